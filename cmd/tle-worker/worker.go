@@ -0,0 +1,114 @@
+//go:build wasip1
+
+// Command tle-worker decrypts a single ciphertext with the beacon handed to it,
+// for sandboxed decryption in untrusted environments. It takes no arguments
+// and reads nothing but its standard input: a decryption ticket written by
+// tle ticket on the first line, or a beacon bundle written by tle
+// fetch-beacon, followed by the ciphertext. The plaintext is written to the
+// standard output.
+//
+//	(tle ticket encrypted_file; cat encrypted_file) | tle-worker > decrypted_file
+//
+// The worker links none of the networks of tlock, nor any relay credentials,
+// yet the drand types it decodes the beacons with link a network stack. It is
+// thus built for WASI only, whose preview 1 offers no way to open a socket,
+// so that it can't reach the network whatever the libraries it links do, and
+// runs in any WebAssembly runtime granting it its standard streams only:
+//
+//	GOOS=wasip1 GOARCH=wasm go build -o tle-worker.wasm ./cmd/tle-worker
+//	wasmtime tle-worker.wasm < job > decrypted_file
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/JonathanLogan/tlock"
+)
+
+const usage = `Usage:
+	tle-worker < JOB > OUTPUT
+
+Decrypts the ciphertext of JOB, read from the standard input, with the
+decryption ticket on its first line, or the beacon bundle it starts with,
+writing the plaintext to the standard output without any network access.`
+
+func main() {
+	log := log.New(os.Stderr, "", 0)
+	if len(os.Args) > 1 {
+		log.Print(usage)
+		os.Exit(2)
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	err := run(w, os.Stdin)
+	if ferr := w.Flush(); err == nil {
+		err = ferr
+	}
+	if err != nil {
+		log.Print(err)
+		os.Exit(1)
+	}
+}
+
+// run decrypts the ciphertext of the job read from src to dst, with the
+// ticket or the beacon bundle it starts with.
+func run(dst io.Writer, src io.Reader) error {
+	br := bufio.NewReader(src)
+	start, err := br.Peek(len(tlock.TicketPrefix))
+	if err != nil {
+		return fmt.Errorf("read job: %w", err)
+	}
+
+	if strings.HasPrefix(string(start), tlock.TicketPrefix) {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("read ticket: %w", err)
+		}
+		ticket, err := tlock.ParseTicket(line)
+		if err != nil {
+			return err
+		}
+		return tlock.New(nil).DecryptWithTicket(dst, br, ticket)
+	}
+
+	if start[0] != '{' {
+		return errors.New("the job starts with neither a ticket nor a beacon bundle")
+	}
+	// The bundle is the first JSON value, which the ciphertext follows.
+	dec := json.NewDecoder(br)
+	var raw json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		return fmt.Errorf("%w: %v", tlock.ErrMalformedBeaconBundle, err)
+	}
+	bundle, err := tlock.ReadBeaconBundle(bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+	network, err := tlock.NewTransportNetwork(context.Background(), bundle)
+	if err != nil {
+		return err
+	}
+
+	// The line break ending the bundle isn't part of the ciphertext.
+	rest := bufio.NewReader(io.MultiReader(dec.Buffered(), br))
+	for {
+		b, err := rest.ReadByte()
+		if err != nil {
+			return fmt.Errorf("read ciphertext: %w", err)
+		}
+		if b != '\n' && b != '\r' && b != ' ' && b != '\t' {
+			_ = rest.UnreadByte()
+			break
+		}
+	}
+	return tlock.New(network).Decrypt(dst, rest)
+}
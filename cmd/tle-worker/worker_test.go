@@ -0,0 +1,44 @@
+//go:build wasip1
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	key := fixedtest.NewKey(nil)
+	key.Genesis = time.Now().Add(-300 * time.Second)
+	bundle := key.Bundle(t, 50)
+	network, err := tlock.NewTransportNetwork(context.Background(), bundle)
+	require.NoError(t, err)
+
+	var ciphertext bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&ciphertext, bytes.NewReader([]byte("the secret")), 50))
+	ticket, err := tlock.New(network).IssueTicket(50)
+	require.NoError(t, err)
+	var beacons bytes.Buffer
+	require.NoError(t, bundle.Write(&beacons))
+
+	for name, head := range map[string]string{
+		"ticket": ticket.String() + "\n",
+		"bundle": beacons.String(),
+	} {
+		var plaintext bytes.Buffer
+		require.NoError(t, run(&plaintext, bytes.NewReader(append([]byte(head), ciphertext.Bytes()...))), name)
+		require.Equal(t, "the secret", plaintext.String(), name)
+	}
+
+	for _, job := range []string{"", "the secret", ticket.String()} {
+		require.Error(t, run(&bytes.Buffer{}, bytes.NewReader([]byte(job))), job)
+	}
+}
@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/JonathanLogan/tlock"
+)
+
+// validateAttestationFlags checks the flags of operations writing or checking
+// an attestation.
+func validateAttestationFlags(f *Flags) error {
+	if f.Attestation == "" {
+		return nil
+	}
+	switch {
+	case f.Metadata:
+		return errors.New("--attestation can't be used with -m/--metadata")
+	case f.OutDir != "":
+		return errors.New("--attestation can't be used with --out-dir")
+	case f.Daemon != "":
+		return errors.New("--attestation can't be used with --daemon")
+	case f.Convergent != "":
+		return errors.New("--attestation can't be used with --convergent")
+	}
+	return nil
+}
+
+// ReadAttestation reads the attestation from the named file.
+func ReadAttestation(name string) (tlock.Attestation, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return tlock.Attestation{}, fmt.Errorf("read attestation: %w", err)
+	}
+	var a tlock.Attestation
+	if err := json.Unmarshal(b, &a); err != nil {
+		return tlock.Attestation{}, fmt.Errorf("parse attestation: %w", err)
+	}
+	return a, nil
+}
+
+// writeAttestation writes the attestation as JSON to the named file.
+func writeAttestation(name string, a tlock.Attestation) error {
+	b, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal attestation: %w", err)
+	}
+	if err := os.WriteFile(name, append(b, '\n'), 0644); err != nil {
+		return fmt.Errorf("write attestation: %w", err)
+	}
+	return nil
+}
+
+// artifactName returns the name of the artifact read from the named input.
+func artifactName(input string) string {
+	if input == "" || input == "-" {
+		return "stdin"
+	}
+	return filepath.Base(input)
+}
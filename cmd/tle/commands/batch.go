@@ -0,0 +1,131 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/JonathanLogan/tlock"
+)
+
+// These are the default name templates of the outputs written to --out-dir.
+const (
+	DefaultEncryptTemplate = "{name}.tle"
+	DefaultDecryptTemplate = "{stem}"
+)
+
+// OutputPath returns the path in the output directory of the result of
+// processing the named input, expanding the name template. {name} is the
+// base name of the input, {stem} the same without a .tle or .age extension
+// and {round} the round the input is or gets encrypted towards.
+func OutputPath(flags Flags, input string, roundNumber uint64) (string, error) {
+	template := flags.NameTemplate
+	if template == "" {
+		template = DefaultEncryptTemplate
+		if flags.Decrypt {
+			template = DefaultDecryptTemplate
+		}
+	}
+
+	name := filepath.Base(input)
+	stem := strings.TrimSuffix(strings.TrimSuffix(name, ".tle"), ".age")
+	out := strings.NewReplacer(
+		"{name}", name,
+		"{stem}", stem,
+		"{round}", strconv.FormatUint(roundNumber, 10),
+	).Replace(template)
+
+	// IsLocal also rejects the names reserved on Windows, and colons which
+	// would address alternate data streams there.
+	if out == "." || strings.ContainsAny(out, `/\`) || !filepath.IsLocal(out) {
+		return "", fmt.Errorf("name template %q expands to the invalid file name %q", template, out)
+	}
+
+	path := filepath.Join(flags.OutDir, out)
+	if sameFile(path, input) {
+		return "", fmt.Errorf("output %q would overwrite its input", path)
+	}
+	return path, nil
+}
+
+// sameFile reports whether both paths name the same file, comparing the
+// files themselves when they exist as paths may differ by case on Windows and
+// macOS.
+func sameFile(a string, b string) bool {
+	if ai, err := os.Stat(a); err == nil {
+		if bi, err := os.Stat(b); err == nil {
+			return os.SameFile(ai, bi)
+		}
+	}
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
+
+// CiphertextRound returns the round the named ciphertext is encrypted
+// towards, removing the decoy whitening with the hint if there is one.
+func CiphertextRound(name string, decoy string) (uint64, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var src io.Reader = f
+	if decoy != "" {
+		if src, err = tlock.NewDecoyReader(src, decoy); err != nil {
+			return 0, err
+		}
+	}
+
+	hdr, err := tlock.ParseHeader(src)
+	if err != nil {
+		return 0, err
+	}
+	roundNumber, _, err := hdr.Round()
+	return roundNumber, err
+}
+
+// CiphertextRounds returns the rounds the named ciphertext depends on, as
+// tlock.Rounds does, removing the decoy whitening with the hint if there is
+// one.
+func CiphertextRounds(name string, decoy string, network tlock.Network) ([]tlock.RoundInfo, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var src io.Reader = f
+	if decoy != "" {
+		if src, err = tlock.NewDecoyReader(src, decoy); err != nil {
+			return nil, err
+		}
+	}
+	return tlock.Rounds(src, nil, network)
+}
+
+// validateBatchFlags checks the flags of batch operations into an output
+// directory.
+func validateBatchFlags(f *Flags) error {
+	if f.OutDir == "" {
+		if f.NameTemplate != "" {
+			return errors.New("--name-template can only be used with --out-dir")
+		}
+		return nil
+	}
+
+	switch {
+	case f.Metadata:
+		return errors.New("--out-dir can't be used with -m/--metadata")
+	case f.Output != "":
+		return errors.New("--out-dir can't be used with -o/--output")
+	case f.Anchor != "":
+		return errors.New("--out-dir can't be used with --anchor")
+	}
+	return nil
+}
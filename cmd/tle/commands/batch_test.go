@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputPath(t *testing.T) {
+	dir := t.TempDir()
+	for _, test := range []struct {
+		flags    Flags
+		input    string
+		expected string
+	}{
+		{Flags{Encrypt: true, OutDir: dir}, "docs/report.pdf", "report.pdf.tle"},
+		{Flags{Encrypt: true, OutDir: dir, NameTemplate: "{stem}.{round}.tle"}, "report.pdf", "report.pdf.1234.tle"},
+		{Flags{Decrypt: true, OutDir: dir}, "locked/report.pdf.tle", "report.pdf"},
+		{Flags{Decrypt: true, OutDir: dir, NameTemplate: "{round}-{stem}"}, "report.pdf.age", "1234-report.pdf"},
+	} {
+		path, err := OutputPath(test.flags, test.input, 1234)
+		require.NoError(t, err)
+		require.Equal(t, filepath.Join(dir, test.expected), path)
+	}
+
+	_, err := OutputPath(Flags{OutDir: dir, NameTemplate: "../{name}"}, "report.pdf", 1234)
+	require.Error(t, err)
+
+	_, err = OutputPath(Flags{OutDir: dir, NameTemplate: `..\{name}`}, "report.pdf", 1234)
+	require.Error(t, err)
+
+	_, err = OutputPath(Flags{OutDir: dir, NameTemplate: "{name}"}, filepath.Join(dir, "report.pdf"), 1234)
+	require.Error(t, err)
+
+	// The input is recognized through another path to it.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "report.pdf"), nil, 0600))
+	link := filepath.Join(t.TempDir(), "link")
+	require.NoError(t, os.Symlink(dir, link))
+	_, err = OutputPath(Flags{OutDir: dir, NameTemplate: "{name}"}, filepath.Join(link, "report.pdf"), 1234)
+	require.Error(t, err)
+}
+
+func TestCiphertextRound(t *testing.T) {
+	roundNumber, err := CiphertextRound("../test.dat.tle", "")
+	require.NoError(t, err)
+	require.NotZero(t, roundNumber)
+}
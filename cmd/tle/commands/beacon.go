@@ -0,0 +1,140 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/http"
+)
+
+// fetchBeaconCommand defines the fetch-beacon subcommand.
+var fetchBeaconCommand = CommandSpec{
+	Name: "fetch-beacon",
+	Synopsis: []string{
+		`tle fetch-beacon [-n NETWORK] [-c CHAIN] [--auth-token TOKEN] [--doh URL] [--ip-family FAMILY] [--no-pin] -o OUTPUT (ROUND | INPUT)...`,
+	},
+	Flags: []FlagSpec{
+		mainFlag("network", func(f *FetchBeaconFlags) any { return &f.Network }),
+		mainFlag("chain", func(f *FetchBeaconFlags) any { return &f.Chain }),
+		mainFlag("auth-token", func(f *FetchBeaconFlags) any { return &f.AuthToken }),
+		mainFlag("doh", func(f *FetchBeaconFlags) any { return &f.DoH }),
+		mainFlag("ip-family", func(f *FetchBeaconFlags) any { return &f.IPFamily }),
+		mainFlag("no-pin", func(f *FetchBeaconFlags) any { return &f.NoPin }),
+		{Name: "output", Short: "o", Arg: "OUTPUT", Description: "Write the beacon bundle to the file at path OUTPUT.", value: field(func(f *FetchBeaconFlags) any { return &f.Output })},
+	},
+	notes: `Fetches and verifies the beacons of the rounds, given as numbers or as the
+ciphertexts INPUT locked to them, and writes them along with the chain
+information to the beacon bundle OUTPUT, a .beacon file. The beacons OUTPUT
+holds already are kept and not fetched again.
+
+The ciphertexts of those rounds then decrypt without calling the network:
+	tle --decrypt --beacons OUTPUT [-o OUTPUT] [INPUT]`,
+	defaults: func() any { return &FetchBeaconFlags{Network: DefaultNetwork, Chain: DefaultChain} },
+}
+
+// FetchBeaconFlags represent the values from the fetch-beacon command line.
+type FetchBeaconFlags struct {
+	Network string
+	Chain   string
+	Output  string
+	Rounds  []string
+
+	NetworkFlags
+}
+
+// ParseFetchBeacon parses the arguments following the fetch-beacon
+// subcommand.
+func ParseFetchBeacon(args []string) (FetchBeaconFlags, error) {
+	f, fs, err := parseCommand[FetchBeaconFlags]("fetch-beacon", args)
+	if err != nil {
+		return FetchBeaconFlags{}, err
+	}
+	if fs.NArg() == 0 || f.Output == "" {
+		return FetchBeaconFlags{}, errors.New(commandUsage("fetch-beacon"))
+	}
+	f.Rounds = fs.Args()
+
+	return f, nil
+}
+
+// FetchBeacon adds the beacons of the rounds of the flags to the beacon bundle
+// of the output, fetching those it lacks from the network.
+func FetchBeacon(flags FetchBeaconFlags, network *http.Network) error {
+	bundle, err := tlock.NewBeaconBundle(network.Info())
+	if err != nil {
+		return err
+	}
+	if f, err := os.Open(flags.Output); err == nil {
+		bundle, err = tlock.ReadBeaconBundle(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", flags.Output, err)
+		}
+		if bundle.ChainHash() != network.ChainHash() {
+			return fmt.Errorf("%s holds the beacons of chain %s, not %s", flags.Output, bundle.ChainHash(), network.ChainHash())
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	for _, arg := range flags.Rounds {
+		roundNumber, err := strconv.ParseUint(arg, 10, 64)
+		if err != nil {
+			if roundNumber, err = CiphertextRound(arg, ""); err != nil {
+				return fmt.Errorf("%s: %w", arg, err)
+			}
+		}
+		if _, err := bundle.Get(context.Background(), roundNumber); err == nil {
+			continue
+		}
+		beacon, err := tlock.New(network).ObtainVerifiedBeacon(roundNumber)
+		if err != nil {
+			return err
+		}
+		if err := bundle.Add(beacon); err != nil {
+			return err
+		}
+	}
+
+	o, err := CreateOutput(flags.Output)
+	if err != nil {
+		return err
+	}
+	return o.Finish(bundle.Write(o))
+}
+
+// OpenBeacons returns the network of the beacon bundle named by --beacons,
+// which decrypts the ciphertexts of its rounds without calling the network.
+func OpenBeacons(name string) (*tlock.TransportNetwork, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("open beacons: %w", err)
+	}
+	defer f.Close()
+
+	bundle, err := tlock.ReadBeaconBundle(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return tlock.NewTransportNetwork(context.Background(), bundle)
+}
+
+// =============================================================================
+
+// validateBeaconsFlags checks the flags decrypting with a beacon bundle.
+func validateBeaconsFlags(f *Flags) error {
+	if f.Beacons == "" {
+		return nil
+	}
+	switch {
+	case !f.Decrypt:
+		return errors.New("--beacons can only be used with -d/--decrypt")
+	case f.Daemon != "":
+		return errors.New("--beacons can't be used with --daemon")
+	}
+	return nil
+}
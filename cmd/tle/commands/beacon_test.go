@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecryptWithBeacons(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	dir := t.TempDir()
+	beacons, input := writeOfflineCiphertext(t, dir, "offline")
+
+	flags := DefaultFlags()
+	flags.Decrypt = true
+	flags.Beacons = beacons
+	flags.Inputs = []string{input}
+	flags.Output = filepath.Join(dir, "data")
+	require.NoError(t, Operation{Flags: flags}.Run(context.Background()))
+	plaintext, err := os.ReadFile(flags.Output)
+	require.NoError(t, err)
+	require.Equal(t, "offline", string(plaintext))
+
+	// Bundles are checked when opened.
+	b, err := os.ReadFile(beacons)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(beacons, bytes.Replace(b, []byte(`"round": 50`), []byte(`"round": 51`), 1), 0o600))
+	require.ErrorIs(t, Operation{Flags: flags}.Run(context.Background()), tlock.ErrMalformedBeaconBundle)
+}
+
+func TestParseFetchBeacon(t *testing.T) {
+	f, err := ParseFetchBeacon([]string{"-o", "rounds.beacon", "1000", "data.tle"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"1000", "data.tle"}, f.Rounds)
+
+	_, err = ParseFetchBeacon([]string{"1000"})
+	require.Error(t, err)
+	_, err = ParseFetchBeacon([]string{"-o", "rounds.beacon"})
+	require.Error(t, err)
+}
+
+// =============================================================================
+
+// writeOfflineCiphertext writes to the directory the plaintext encrypted
+// towards round 50 of a chain of its own, and the beacon bundle decrypting it,
+// returning their names.
+func writeOfflineCiphertext(t *testing.T, dir string, plaintext string) (string, string) {
+	t.Helper()
+	key := fixedtest.NewKey(nil)
+	key.Genesis = time.Now().Add(-300 * time.Second)
+	bundle := key.Bundle(t, 50)
+
+	beacons := filepath.Join(dir, "rounds.beacon")
+	var b bytes.Buffer
+	require.NoError(t, bundle.Write(&b))
+	require.NoError(t, os.WriteFile(beacons, b.Bytes(), 0o600))
+
+	network, err := tlock.NewTransportNetwork(context.Background(), bundle)
+	require.NoError(t, err)
+	input := filepath.Join(dir, "data.tle")
+	var ciphertext bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&ciphertext, bytes.NewReader([]byte(plaintext)), 50))
+	require.NoError(t, os.WriteFile(input, ciphertext.Bytes(), 0o600))
+
+	return beacons, input
+}
@@ -0,0 +1,196 @@
+package commands
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed"
+	chain "github.com/drand/drand/v2/common"
+	"github.com/drand/drand/v2/crypto"
+	"github.com/drand/kyber/util/random"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// benchCommand defines the bench subcommand.
+var benchCommand = CommandSpec{
+	Name: "bench",
+	Synopsis: []string{
+		`tle bench [-n NETWORK] [-c CHAIN] [--size MIB] [--offline]`,
+	},
+	Flags: []FlagSpec{
+		mainFlag("network", func(f *BenchFlags) any { return &f.Network }),
+		mainFlag("chain", func(f *BenchFlags) any { return &f.Chain }),
+		{Name: "size", Arg: "MIB", Description: "The MiB of data processed by each measurement.", value: field(func(f *BenchFlags) any { return &f.Size })},
+		{Name: "offline", Description: "Skip the network measurements.", value: field(func(f *BenchFlags) any { return &f.Offline })},
+	},
+	notes: `Measures the local encryption and decryption throughput of each AEAD for
+several chunk sizes, and of the tlock encryption itself, then the round trip
+latency of fetching beacons from the network unless --offline is given.`,
+	defaults: func() any { return &BenchFlags{Network: DefaultNetwork, Chain: DefaultChain, Size: 64} },
+}
+
+// These are the parameters of the measurements.
+var (
+	benchChunkSizes = []int{16 << 10, 64 << 10, 256 << 10, 1 << 20}
+	benchRoundTrips = 5
+)
+
+// BenchFlags represent the values from the bench command line.
+type BenchFlags struct {
+	Network string
+	Chain   string
+	Size    int
+	Offline bool
+}
+
+// ParseBench parses the arguments following the bench subcommand.
+func ParseBench(args []string) (BenchFlags, error) {
+	f, fs, err := parseCommand[BenchFlags]("bench", args)
+	if err != nil {
+		return BenchFlags{}, err
+	}
+	if f.Size <= 0 || fs.NArg() > 0 {
+		return BenchFlags{}, errors.New(commandUsage("bench"))
+	}
+
+	return f, nil
+}
+
+// Bench runs the measurements and writes the report to dst. The network
+// measurements are skipped when network is nil.
+func Bench(flags BenchFlags, dst io.Writer, network tlock.Network) error {
+	data := make([]byte, flags.Size<<20)
+
+	tw := tabwriter.NewWriter(dst, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "AEAD\tCHUNK\tSEAL\tOPEN")
+	for _, a := range []struct {
+		name string
+		new  func() (cipher.AEAD, error)
+	}{
+		{"chacha20poly1305", func() (cipher.AEAD, error) { return chacha20poly1305.New(make([]byte, chacha20poly1305.KeySize)) }},
+		{"aes-128-gcm", newAES128GCM},
+	} {
+		aead, err := a.new()
+		if err != nil {
+			return err
+		}
+		for _, size := range benchChunkSizes {
+			seal, open := benchAEAD(aead, data, size)
+			fmt.Fprintf(tw, "%s\t%dKiB\t%s\t%s\n", a.name, size>>10, throughput(len(data), seal), throughput(len(data), open))
+		}
+	}
+
+	encrypt, decrypt, err := benchTlock(data)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(tw, "tlock (age)\t%dKiB\t%s\t%s\n", tlock.ChunkSize>>10, throughput(len(data), encrypt), throughput(len(data), decrypt))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if network == nil {
+		return nil
+	}
+
+	latencies, err := benchRoundTrip(network)
+	if err != nil {
+		return err
+	}
+	fastest, total := latencies[0], time.Duration(0)
+	for _, l := range latencies {
+		fastest = min(fastest, l)
+		total += l
+	}
+	_, err = fmt.Fprintf(dst, "\nbeacon round trip over %d rounds: min %s, avg %s\n",
+		len(latencies), fastest.Round(time.Millisecond), (total / time.Duration(len(latencies))).Round(time.Millisecond))
+	return err
+}
+
+// =============================================================================
+
+// benchAEAD seals and opens the data in chunks of the given size.
+func benchAEAD(aead cipher.AEAD, data []byte, size int) (time.Duration, time.Duration) {
+	nonce := make([]byte, aead.NonceSize())
+	sealed := make([][]byte, 0, len(data)/size+1)
+
+	start := time.Now()
+	for i := 0; i < len(data); i += size {
+		sealed = append(sealed, aead.Seal(nil, nonce, data[i:min(i+size, len(data))], nil))
+	}
+	seal := time.Since(start)
+
+	buf := make([]byte, size)
+	start = time.Now()
+	for _, s := range sealed {
+		_, _ = aead.Open(buf[:0], nonce, s, nil)
+	}
+	return seal, time.Since(start)
+}
+
+// benchTlock encrypts and decrypts the data with a local network, keeping
+// the network out of the measurement.
+func benchTlock(data []byte) (time.Duration, time.Duration, error) {
+	sch := crypto.NewPedersenBLSUnchainedG1()
+	secret := sch.KeyGroup.Scalar().Pick(random.New())
+	sig, err := sch.AuthScheme.Sign(secret, sch.DigestBeacon(&chain.Beacon{Round: 1}))
+	if err != nil {
+		return 0, 0, err
+	}
+	network, err := fixed.NewNetwork("bench", sch.KeyGroup.Point().Mul(secret, nil), sch, time.Second, time.Now().Unix(), sig)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var ciphertext bytes.Buffer
+	ciphertext.Grow(len(data) + len(data)/tlock.ChunkSize*16 + 1024)
+	start := time.Now()
+	if err := tlock.New(network).Encrypt(&ciphertext, bytes.NewReader(data), 1); err != nil {
+		return 0, 0, err
+	}
+	encrypt := time.Since(start)
+
+	start = time.Now()
+	if err := tlock.New(network).Decrypt(io.Discard, &ciphertext); err != nil {
+		return 0, 0, err
+	}
+	return encrypt, time.Since(start), nil
+}
+
+// benchRoundTrip measures fetching the signatures of recent rounds.
+func benchRoundTrip(network tlock.Network) ([]time.Duration, error) {
+	current := network.Current(time.Now())
+	latencies := make([]time.Duration, 0, benchRoundTrips)
+	for i := 0; i < benchRoundTrips && uint64(i) < current; i++ {
+		start := time.Now()
+		if _, err := network.Signature(current - uint64(i)); err != nil {
+			return nil, fmt.Errorf("fetch round %d: %w", current-uint64(i), err)
+		}
+		latencies = append(latencies, time.Since(start))
+	}
+	if len(latencies) == 0 {
+		return nil, errors.New("network has no rounds yet")
+	}
+	return latencies, nil
+}
+
+// newAES128GCM returns an AES-128-GCM AEAD with a zero key.
+func newAES128GCM() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// throughput formats the rate at which n bytes were processed.
+func throughput(n int, d time.Duration) string {
+	return fmt.Sprintf("%.0f MB/s", float64(n)/1e6/max(d.Seconds(), 1e-9))
+}
@@ -0,0 +1,29 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBench(t *testing.T) {
+	f, err := ParseBench([]string{"--size", "8", "--offline"})
+	require.NoError(t, err)
+	require.Equal(t, BenchFlags{Network: DefaultNetwork, Chain: DefaultChain, Size: 8, Offline: true}, f)
+
+	_, err = ParseBench([]string{"--size", "0"})
+	require.Error(t, err)
+}
+
+func TestBenchOffline(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	var report bytes.Buffer
+	require.NoError(t, Bench(BenchFlags{Size: 1, Offline: true}, &report, nil))
+	require.Contains(t, report.String(), "chacha20poly1305")
+	require.Contains(t, report.String(), "aes-128-gcm")
+	require.Contains(t, report.String(), "tlock (age)")
+	require.NotContains(t, report.String(), "round trip")
+}
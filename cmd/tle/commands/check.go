@@ -0,0 +1,77 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/JonathanLogan/tlock"
+)
+
+// checkCommand defines the check subcommand.
+var checkCommand = CommandSpec{
+	Name: "check",
+	Synopsis: []string{
+		`tle check [-n NETWORK] [-c CHAIN] [--auth-token TOKEN] [--doh URL] [--ip-family FAMILY] [--no-pin]`,
+	},
+	Flags: []FlagSpec{
+		mainFlag("network", func(f *CheckFlags) any { return &f.Network }),
+		mainFlag("chain", func(f *CheckFlags) any { return &f.Chain }),
+		mainFlag("auth-token", func(f *CheckFlags) any { return &f.AuthToken }),
+		mainFlag("doh", func(f *CheckFlags) any { return &f.DoH }),
+		mainFlag("ip-family", func(f *CheckFlags) any { return &f.IPFamily }),
+		mainFlag("no-pin", func(f *CheckFlags) any { return &f.NoPin }),
+	},
+	notes: `Probes the chain, printing its latest round, the round due from the wall
+clock and whether it is healthy, degraded or halted. The rounds due while it
+isn't healthy unlock late, once it catches up. It exits with status 3 when
+the chain is halted or can't be reached, so that monitoring can alert on it.`,
+	defaults: func() any { return &CheckFlags{Network: DefaultNetwork, Chain: DefaultChain} },
+}
+
+// ErrChainHalted is returned by Check when the chain is halted.
+var ErrChainHalted = errors.New("chain halted")
+
+// CheckFlags represent the values from the check command line.
+type CheckFlags struct {
+	Network string
+	Chain   string
+
+	NetworkFlags
+}
+
+// ParseCheck parses the arguments following the check subcommand.
+func ParseCheck(args []string) (CheckFlags, error) {
+	f, fs, err := parseCommand[CheckFlags]("check", args)
+	if err != nil {
+		return CheckFlags{}, err
+	}
+	if fs.NArg() != 0 {
+		return CheckFlags{}, errors.New(commandUsage("check"))
+	}
+
+	return f, nil
+}
+
+// Check writes the status of the chain of the network to w, failing with
+// ErrChainHalted when it is halted.
+func Check(ctx context.Context, w io.Writer, network tlock.Network) error {
+	status, err := tlock.Status(ctx, network)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "chain:          %s\n", network.ChainHash())
+	fmt.Fprintf(w, "status:         %s\n", status.Health)
+	fmt.Fprintf(w, "latest round:   %d\n", status.LatestRound)
+	fmt.Fprintf(w, "expected round: %d\n", status.ExpectedRound)
+	if status.Health != tlock.Healthy {
+		fmt.Fprintf(w, "behind by:      %s\n", status.Behind)
+	}
+
+	if status.Health == tlock.Halted {
+		return fmt.Errorf("%w at round %d, %s behind", ErrChainHalted, status.LatestRound, status.Behind)
+	}
+	return nil
+}
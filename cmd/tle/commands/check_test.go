@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock/networks/fixed"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+// latestNetwork is a network reporting a fixed latest round.
+type latestNetwork struct {
+	*fixed.Network
+	latest uint64
+}
+
+func (n latestNetwork) LatestRound() (uint64, error) {
+	return n.latest, nil
+}
+
+func TestCheck(t *testing.T) {
+	// The chain started an hour ago.
+	key := fixedtest.NewKey(nil)
+	key.Genesis = time.Now().Add(-time.Hour)
+	network := key.Network(t, nil)
+	current := network.Current(time.Now())
+
+	var out bytes.Buffer
+	require.NoError(t, Check(context.Background(), &out, latestNetwork{Network: network, latest: current}))
+	require.Contains(t, out.String(), "status:         healthy")
+
+	out.Reset()
+	err := Check(context.Background(), &out, latestNetwork{Network: network, latest: current - 1000})
+	require.ErrorIs(t, err, ErrChainHalted)
+	require.Equal(t, ExitNetwork, ExitCode(err))
+	require.Contains(t, out.String(), "status:         halted")
+}
+
+func TestParseCheck(t *testing.T) {
+	_, err := ParseCheck([]string{"-n", "https://api.drand.sh/"})
+	require.NoError(t, err)
+	_, err = ParseCheck([]string{"extra"})
+	require.Error(t, err)
+
+	f, err := ParseCheck([]string{"--auth-token", "secret", "--ip-family", "6", "--no-pin"})
+	require.NoError(t, err)
+	require.Equal(t, Flags{AuthToken: "secret", IPFamily: "6", NoPin: true}, f.Flags())
+}
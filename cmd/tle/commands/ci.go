@@ -0,0 +1,270 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/http"
+)
+
+// ciCommand defines the ci subcommand.
+var ciCommand = CommandSpec{
+	Name: "ci",
+	Synopsis: []string{
+		`tle ci seal (--at TIME | -D DURATION | -r ROUND) [-n NETWORK] [-c CHAIN] [-o OUTPUT] ENVFILE`,
+		`tle ci open [--provider github | gitlab] [-n NETWORK] [-c CHAIN] [-o OUTPUT] INPUT`,
+	},
+	Flags: []FlagSpec{
+		mainFlag("network", func(f *CIFlags) any { return &f.Network }),
+		mainFlag("chain", func(f *CIFlags) any { return &f.Chain }),
+		mainFlag("output", func(f *CIFlags) any { return &f.Output }),
+		{Name: "provider", Arg: "PROVIDER", Description: "The CI provider the variables are exported to, github or gitlab, detected by default.", value: field(func(f *CIFlags) any { return &f.Provider })},
+		{Name: "at", Arg: "TIME", Description: "The time the pipeline runs at.", value: field(func(f *CIFlags) any { return &f.At })},
+		{Name: "duration", Short: "D", Arg: "DURATION", Description: "How long to wait before the pipeline can decrypt.", value: field(func(f *CIFlags) any { return &f.Duration })},
+		{Name: "round", Short: "r", Arg: "ROUND", Description: "The specific round to use.", value: field(func(f *CIFlags) any { return &f.Round })},
+	},
+	notes: `Seals the environment file ENVFILE, made of KEY=VALUE lines, towards the
+scheduled time of a pipeline, writing it armored to OUTPUT, ENVFILE.tle by
+default, and printing the command decrypting it in the pipeline. TIME is in
+RFC 3339 format, such as 2024-06-01T09:00:00Z.
+
+In the pipeline, open decrypts INPUT once it unlocks and exports its
+variables without printing their values. On GitHub Actions, the values are
+masked with ::add-mask:: and appended to $GITHUB_ENV, or OUTPUT if given. On
+GitLab CI, which can't mask values at runtime, they are written as shell
+export statements to OUTPUT, to be sourced by the job. The provider is
+detected from the environment unless given.`,
+	defaults: func() any { return &CIFlags{Network: DefaultNetwork, Chain: DefaultChain} },
+}
+
+// These are the CI providers open exports the variables to.
+const (
+	ProviderGitHub = "github"
+	ProviderGitLab = "gitlab"
+)
+
+// envKey matches the names of the variables of environment files.
+var envKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// CIFlags represent the values from the ci command line.
+type CIFlags struct {
+	Open     bool
+	Network  string
+	Chain    string
+	At       string
+	Duration string
+	Round    uint64
+	Provider string
+	Output   string
+	Input    string
+}
+
+// ParseCI parses the arguments following the ci subcommand.
+func ParseCI(args []string) (CIFlags, error) {
+	if len(args) == 0 || args[0] != "seal" && args[0] != "open" {
+		return CIFlags{}, errors.New(commandUsage("ci"))
+	}
+	f, fs, err := parseCommand[CIFlags]("ci", args[1:])
+	if err != nil {
+		return CIFlags{}, err
+	}
+	if fs.NArg() != 1 {
+		return CIFlags{}, errors.New(commandUsage("ci"))
+	}
+	f.Open = args[0] == "open"
+	f.Input = fs.Arg(0)
+
+	switch {
+	case f.Open && (f.At != "" || f.Duration != "" || f.Round != 0):
+		return CIFlags{}, errors.New("--at, -D/--duration and -r/--round can only be used with ci seal")
+	case !f.Open && f.Provider != "":
+		return CIFlags{}, errors.New("--provider can only be used with ci open")
+	}
+	if !f.Open {
+		set := 0
+		for _, given := range []bool{f.At != "", f.Duration != "", f.Round != 0} {
+			if given {
+				set++
+			}
+		}
+		if set != 1 {
+			return CIFlags{}, errors.New("exactly one of --at, -D/--duration and -r/--round must be given")
+		}
+		if f.Output == "" {
+			f.Output = f.Input + ".tle"
+		}
+	}
+	if f.Provider != "" && f.Provider != ProviderGitHub && f.Provider != ProviderGitLab {
+		return CIFlags{}, fmt.Errorf("--provider: unknown provider %q, expected %s or %s", f.Provider, ProviderGitHub, ProviderGitLab)
+	}
+
+	return f, nil
+}
+
+// CISeal checks that src is an environment file and encrypts it armored to
+// dst towards the round of the flags, which is returned. The armor tells when
+// the file unlocks.
+func CISeal(flags CIFlags, dst io.Writer, src io.Reader, network *http.Network) (uint64, error) {
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := ParseEnv(bytes.NewReader(data)); err != nil {
+		return 0, err
+	}
+
+	roundNumber := flags.Round
+	switch {
+	case flags.At != "":
+		at, err := time.Parse(time.RFC3339, flags.At)
+		if err != nil {
+			return 0, fmt.Errorf("--at: %w", err)
+		}
+		if !at.After(time.Now()) {
+			return 0, fmt.Errorf("--at: %s is in the past", flags.At)
+		}
+		roundNumber = network.RoundNumber(at)
+	case flags.Duration != "":
+		if roundNumber, err = RoundNumber(Flags{Duration: flags.Duration}, network); err != nil {
+			return 0, err
+		}
+	}
+
+	w := tlock.NewHintedArmorWriter(dst, network)
+	if err := tlock.New(network).Encrypt(w, bytes.NewReader(data), roundNumber); err != nil {
+		return 0, err
+	}
+	return roundNumber, w.Close()
+}
+
+// CIOpenCommand returns the command decrypting the output of the flags in a
+// pipeline.
+func CIOpenCommand(flags CIFlags) string {
+	return fmt.Sprintf("tle ci open -n %s -c %s %s", shellQuote(flags.Network), shellQuote(flags.Chain), shellQuote(flags.Output))
+}
+
+// DetectProvider returns the CI provider the environment is the one of, or
+// "" when unknown.
+func DetectProvider(getenv func(string) string) string {
+	switch {
+	case getenv("GITHUB_ACTIONS") == "true":
+		return ProviderGitHub
+	case getenv("GITLAB_CI") == "true":
+		return ProviderGitLab
+	}
+	return ""
+}
+
+// EnvVar is a variable of an environment file.
+type EnvVar struct {
+	Key   string
+	Value string
+}
+
+// ParseEnv parses an environment file, made of KEY=VALUE lines optionally
+// prefixed with export. Values may be quoted with single or double quotes,
+// which are removed. Blank lines and lines starting with # are skipped.
+func ParseEnv(r io.Reader) ([]EnvVar, error) {
+	var vars []EnvVar
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		text = strings.TrimPrefix(text, "export ")
+
+		key, value, ok := strings.Cut(text, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !envKey.MatchString(key) {
+			return nil, fmt.Errorf("environment file line %d: expected KEY=VALUE", line)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		vars = append(vars, EnvVar{Key: key, Value: value})
+	}
+	return vars, s.Err()
+}
+
+// WriteGitHubEnv masks the values of the variables with workflow commands
+// written to log, the standard output of the step, and appends them to env in
+// the format of $GITHUB_ENV. Each line of the values is masked, the runner
+// matching them line by line.
+func WriteGitHubEnv(log io.Writer, env io.Writer, vars []EnvVar) error {
+	escape := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	for _, v := range vars {
+		for _, line := range strings.Split(v.Value, "\n") {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			if _, err := fmt.Fprintf(log, "::add-mask::%s\n", escape.Replace(line)); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, v := range vars {
+		delimiter, err := envDelimiter(v.Value)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(env, "%s<<%s\n%s\n%s\n", v.Key, delimiter, v.Value, delimiter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteShellEnv writes the variables to w as export statements, single
+// quoted.
+func WriteShellEnv(w io.Writer, vars []EnvVar) error {
+	for _, v := range vars {
+		if _, err := fmt.Fprintf(w, "export %s=%s\n", v.Key, shellQuote(v.Value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OpenEnvOutput opens the file the variables are exported to, appending to it
+// and creating it readable by its owner only.
+func OpenEnvOutput(name string) (*os.File, error) {
+	return os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+}
+
+// =============================================================================
+
+// envDelimiter returns a random delimiter of a multiline value of
+// $GITHUB_ENV, which can't occur in the value.
+func envDelimiter(value string) (string, error) {
+	for {
+		var b [16]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return "", err
+		}
+		delimiter := "ghadelimiter_" + hex.EncodeToString(b[:])
+		if !strings.Contains(value, delimiter) {
+			return delimiter, nil
+		}
+	}
+}
+
+// shellQuote quotes a word for POSIX shells.
+func shellQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n\"'\\$`!*?[]{}()<>|&;#~%") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCI(t *testing.T) {
+	f, err := ParseCI([]string{"seal", "--at", "2024-06-01T09:00:00Z", "release.env"})
+	require.NoError(t, err)
+	require.Equal(t, CIFlags{Network: DefaultNetwork, Chain: DefaultChain, At: "2024-06-01T09:00:00Z", Output: "release.env.tle", Input: "release.env"}, f)
+	require.Equal(t, "tle ci open -n https://api.drand.sh/ -c "+DefaultChain+" release.env.tle", CIOpenCommand(f))
+
+	f, err = ParseCI([]string{"open", "--provider", "gitlab", "-o", "secrets.sh", "release.env.tle"})
+	require.NoError(t, err)
+	require.Equal(t, CIFlags{Open: true, Network: DefaultNetwork, Chain: DefaultChain, Provider: ProviderGitLab, Output: "secrets.sh", Input: "release.env.tle"}, f)
+
+	for _, args := range [][]string{
+		nil,
+		{"release.env"},
+		{"seal", "release.env"},
+		{"seal", "-D", "1d", "-r", "1000", "release.env"},
+		{"seal", "--provider", "github", "-D", "1d", "release.env"},
+		{"open", "--at", "2024-06-01T09:00:00Z", "release.env.tle"},
+		{"open", "--provider", "jenkins", "release.env.tle"},
+	} {
+		_, err := ParseCI(args)
+		require.Error(t, err, args)
+	}
+}
+
+func TestParseEnv(t *testing.T) {
+	vars, err := ParseEnv(strings.NewReader("# release secrets\n\nTOKEN=abc=def\nexport NAME = 'it''s'\nQUOTED=\"a b\"\n"))
+	require.NoError(t, err)
+	require.Equal(t, []EnvVar{{"TOKEN", "abc=def"}, {"NAME", "it''s"}, {"QUOTED", "a b"}}, vars)
+
+	_, err = ParseEnv(strings.NewReader("TOKEN=abc\nnot a variable\n"))
+	require.ErrorContains(t, err, "line 2")
+	_, err = ParseEnv(strings.NewReader("1TOKEN=abc\n"))
+	require.Error(t, err)
+}
+
+func TestWriteEnv(t *testing.T) {
+	vars := []EnvVar{{"TOKEN", "100%\rsecret"}, {"EMPTY", ""}}
+
+	var log, env bytes.Buffer
+	require.NoError(t, WriteGitHubEnv(&log, &env, vars))
+	require.Equal(t, "::add-mask::100%25%0Dsecret\n", log.String())
+	delimiter := `ghadelimiter_[0-9a-f]{32}`
+	require.Regexp(t, regexp.MustCompile(`^TOKEN<<`+delimiter+`\n100%\rsecret\n`+delimiter+`\nEMPTY<<`+delimiter+`\n\n`+delimiter+`\n$`), env.String())
+
+	var shell bytes.Buffer
+	require.NoError(t, WriteShellEnv(&shell, []EnvVar{{"TOKEN", "it's $HOME"}, {"PLAIN", "abc"}}))
+	require.Equal(t, "export TOKEN='it'\\''s $HOME'\nexport PLAIN=abc\n", shell.String())
+
+	environ := map[string]string{"GITLAB_CI": "true"}
+	require.Equal(t, ProviderGitLab, DetectProvider(func(key string) string { return environ[key] }))
+	environ["GITHUB_ACTIONS"] = "true"
+	require.Equal(t, ProviderGitHub, DetectProvider(func(key string) string { return environ[key] }))
+	require.Empty(t, DetectProvider(func(string) string { return "" }))
+}
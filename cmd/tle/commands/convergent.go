@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// validateConvergentFlags checks the flags of convergent encryption, which
+// writes a binary ciphertext whose manifest is found by seeking.
+func validateConvergentFlags(f *Flags) error {
+	if f.Convergent == "" {
+		return nil
+	}
+	switch {
+	case !f.Encrypt:
+		return errors.New("--convergent can only be used with -e/--encrypt, decryption detects it")
+	case f.Armor:
+		return errors.New("--convergent can't be used with -a/--armor")
+	case f.Decoy != "":
+		return errors.New("--convergent can't be used with --decoy")
+	case f.Daemon != "":
+		return errors.New("--convergent can't be used with --daemon")
+	}
+	return nil
+}
+
+// validateReproducibleFlags checks the flags of reproducible encryption, which
+// rules out the options drawing randomness of their own.
+func validateReproducibleFlags(f *Flags) error {
+	if f.Reproducible == "" {
+		return nil
+	}
+	switch {
+	case !f.Encrypt:
+		return errors.New("--reproducible can only be used with -e/--encrypt")
+	case f.PassphraseFile != "":
+		return errors.New("--reproducible can't be used with --passphrase-file")
+	case f.Decoy != "":
+		return errors.New("--reproducible can't be used with --decoy")
+	case f.Daemon != "":
+		return errors.New("--reproducible can't be used with --daemon")
+	case f.Format != "" && f.Format != "binary" && f.Format != "age":
+		return fmt.Errorf("--reproducible can't be used with --format %s", f.Format)
+	}
+	return nil
+}
+
+// readConvergentSecret reads the convergent secret from the named file, using
+// its content as is.
+func readConvergentSecret(name string) ([]byte, error) {
+	secret, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("read convergent secret file: %w", err)
+	}
+	return secret, nil
+}
+
+// readReproducibleSeed reads the reproducible seed from the named file, using
+// its content as is.
+func readReproducibleSeed(name string) ([]byte, error) {
+	seed, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("read reproducible seed file: %w", err)
+	}
+	return seed, nil
+}
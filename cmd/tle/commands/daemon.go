@@ -0,0 +1,233 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/policy"
+	"github.com/JonathanLogan/tlock/tlockgrpc"
+	chain "github.com/drand/drand/v2/common"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// daemonCommand defines the daemon subcommand.
+var daemonCommand = CommandSpec{
+	Name: "daemon",
+	Synopsis: []string{
+		`tle daemon [-n NETWORK] [-c CHAIN] [--socket PATH] [--policy POLICY]`,
+	},
+	Flags: []FlagSpec{
+		mainFlag("network", func(f *DaemonFlags) any { return &f.Network }),
+		mainFlag("chain", func(f *DaemonFlags) any { return &f.Chain }),
+		{Name: "socket", Arg: "PATH", Description: "Listen on the unix socket at PATH.", value: field(func(f *DaemonFlags) any { return &f.Socket })},
+		{Name: "policy", Arg: "POLICY", Description: "Check the requests of the tenants against the policy in the file POLICY.", value: field(func(f *DaemonFlags) any { return &f.Policy })},
+	},
+	notes: `Serves encryption, decryption and status requests on the unix socket PATH,
+keeping the connection to the network and its chain information across
+requests. Other invocations of tle go through it when given --daemon PATH or
+when TLE_DAEMON is set. The requests are those of the gRPC service of the
+tlockgrpc package.
+
+PATH defaults to tle.sock in $XDG_RUNTIME_DIR, or in the temporary directory.
+
+POLICY is the JSON file of the rules the encryptions of each tenant must
+follow, as loaded by the policy package. The tenant of an invocation of tle is
+set with --tenant or TLE_TENANT. It is asserted by the invocation rather than
+authenticated: whoever may connect to the socket may claim any tenant, so the
+policy guards against mistakes, and the permissions of the socket against
+unwanted users.`,
+	defaults: func() any { return &DaemonFlags{Network: DefaultNetwork, Chain: DefaultChain, Socket: DefaultSocket()} },
+}
+
+// ErrDaemonRunning is returned when another daemon listens on the socket.
+var ErrDaemonRunning = errors.New("a daemon is already listening on the socket")
+
+// DaemonFlags represent the values from the daemon command line.
+type DaemonFlags struct {
+	Network string
+	Chain   string
+	Socket  string
+	Policy  string
+}
+
+// ParseDaemon parses the arguments following the daemon subcommand.
+func ParseDaemon(args []string) (DaemonFlags, error) {
+	f, fs, err := parseCommand[DaemonFlags]("daemon", args)
+	if err != nil {
+		return DaemonFlags{}, err
+	}
+	if f.Socket == "" || fs.NArg() > 0 {
+		return DaemonFlags{}, errors.New(commandUsage("daemon"))
+	}
+
+	return f, nil
+}
+
+// DefaultSocket returns the default path of the daemon socket.
+func DefaultSocket() string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "tle.sock")
+}
+
+// ListenDaemon listens on the unix socket at path, only accessible to the
+// user. A socket left behind by a daemon which is gone is replaced. On
+// Windows, the permissions are those inherited from the directory.
+func ListenDaemon(path string) (net.Listener, error) {
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("%w: %s", ErrDaemonRunning, path)
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	lis, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		lis.Close()
+		return nil, err
+	}
+
+	return lis, nil
+}
+
+// LoadPolicy loads the policy of the tenants from the named file.
+func LoadPolicy(name string) (*policy.Engine, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return policy.Load(f)
+}
+
+// Daemon serves the requests accepted by the listener until the context is
+// canceled, then lets the pending requests complete. The encryptions are
+// checked against the policy unless it is nil.
+func Daemon(ctx context.Context, lis net.Listener, network tlock.Network, p *policy.Engine) error {
+	srv := tlockgrpc.NewServer(network)
+	if p != nil {
+		srv.SetPolicy(p)
+	}
+	s := grpc.NewServer()
+	tlockgrpc.Register(s, srv)
+
+	go func() {
+		<-ctx.Done()
+		s.GracefulStop()
+	}()
+
+	if err := s.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return nil
+}
+
+// DialDaemon returns a connection to the daemon listening on the unix socket
+// at path. The socket is dialed directly rather than through a unix:// target,
+// which can't hold Windows paths.
+func DialDaemon(path string) (*grpc.ClientConn, error) {
+	return grpc.NewClient("passthrough:///tle-daemon",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+}
+
+// DaemonProcess performs the encryption or decryption of the flags through
+// the daemon.
+func DaemonProcess(ctx context.Context, flags Flags, dst io.Writer, src io.Reader, client tlockgrpc.TlockClient) error {
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return fmt.Errorf("read input: %w", err)
+	}
+	if flags.Tenant != "" {
+		ctx = tlockgrpc.WithTenant(ctx, flags.Tenant)
+	}
+
+	var out []byte
+	if flags.Decrypt {
+		resp, err := client.Decrypt(ctx, &tlockgrpc.DecryptRequest{Ciphertext: data})
+		if status.Code(err) == codes.FailedPrecondition {
+			return fmt.Errorf("%w: %s", tlock.ErrTooEarly, status.Convert(err).Message())
+		}
+		if err != nil {
+			return fmt.Errorf("daemon: %w", err)
+		}
+		out = resp.Plaintext
+	} else {
+		st, err := client.Status(ctx, &tlockgrpc.StatusRequest{})
+		if err != nil {
+			return fmt.Errorf("daemon: %w", err)
+		}
+		if st.Period == 0 {
+			return errors.New("daemon: the network doesn't expose the time of its rounds")
+		}
+		roundNumber, err := RoundNumber(flags, daemonClock{st})
+		if err != nil {
+			return err
+		}
+
+		resp, err := client.Encrypt(ctx, &tlockgrpc.EncryptRequest{Plaintext: data, Round: roundNumber, Armor: flags.Armor})
+		if err != nil {
+			return fmt.Errorf("daemon: %w", err)
+		}
+		out = resp.Ciphertext
+	}
+
+	_, err = io.Copy(dst, bytes.NewReader(out))
+	return err
+}
+
+// =============================================================================
+
+// daemonClock computes the rounds of the chain reported by the daemon.
+type daemonClock struct {
+	status *tlockgrpc.StatusResponse
+}
+
+// RoundNumber returns the round emitted at t.
+func (c daemonClock) RoundNumber(t time.Time) uint64 {
+	return chain.CurrentRound(t.Unix(), time.Duration(c.status.Period)*time.Second, c.status.GenesisTime)
+}
+
+// validateDaemonFlags checks the flags of operations performed through the
+// daemon.
+func validateDaemonFlags(f *Flags) error {
+	if f.Daemon == "" {
+		if f.Tenant != "" {
+			return errors.New("--tenant can only be used with --daemon")
+		}
+		return nil
+	}
+	switch {
+	case f.Metadata:
+		return errors.New("--daemon can't be used with -m/--metadata")
+	case f.OutDir != "":
+		return errors.New("--daemon can't be used with --out-dir")
+	case f.Decoy != "":
+		return errors.New("--daemon can't be used with --decoy")
+	case f.Anchor != "":
+		return errors.New("--daemon can't be used with --anchor")
+	case f.PassphraseFile != "":
+		return errors.New("--daemon can't be used with --passphrase-file")
+	}
+	return nil
+}
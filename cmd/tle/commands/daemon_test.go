@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/JonathanLogan/tlock/tlockgrpc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDaemon(t *testing.T) {
+	f, err := ParseDaemon([]string{"--socket", "/run/tle.sock"})
+	require.NoError(t, err)
+	require.Equal(t, DaemonFlags{Network: DefaultNetwork, Chain: DefaultChain, Socket: "/run/tle.sock"}, f)
+
+	_, err = ParseDaemon([]string{"extra"})
+	require.Error(t, err)
+}
+
+func TestDaemon(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	// Socket paths are limited in length, hence not using t.TempDir.
+	dir, err := os.MkdirTemp("", "tle")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "tle.sock")
+
+	network := earlyNetwork{fixedtest.NewNetwork(t, 1)}
+	lis, err := ListenDaemon(socket)
+	require.NoError(t, err)
+	fi, err := os.Stat(socket)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	_, err = ListenDaemon(socket)
+	require.ErrorIs(t, err, ErrDaemonRunning)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- Daemon(ctx, lis, network, nil) }()
+	defer func() {
+		cancel()
+		require.NoError(t, <-done)
+	}()
+
+	cc, err := DialDaemon(socket)
+	require.NoError(t, err)
+	defer cc.Close()
+	client := tlockgrpc.NewTlockClient(cc)
+
+	var cipherData bytes.Buffer
+	require.NoError(t, DaemonProcess(ctx, Flags{Encrypt: true, Round: 1, Force: true}, &cipherData, bytes.NewReader([]byte("hello")), client))
+
+	var plainData bytes.Buffer
+	require.NoError(t, DaemonProcess(ctx, Flags{Decrypt: true}, &plainData, &cipherData, client))
+	require.Equal(t, "hello", plainData.String())
+
+	cipherData.Reset()
+	require.NoError(t, tlock.New(network).Encrypt(&cipherData, bytes.NewReader([]byte("hello")), 1<<40))
+	err = DaemonProcess(ctx, Flags{Decrypt: true}, &plainData, &cipherData, client)
+	require.ErrorIs(t, err, tlock.ErrTooEarly)
+}
+
+// earlyNetwork only published the first round.
+type earlyNetwork struct {
+	*fixed.Network
+}
+
+func (n earlyNetwork) Signature(roundNumber uint64) ([]byte, error) {
+	if roundNumber > 1 {
+		return nil, errors.New("round not published")
+	}
+	return n.Network.Signature(roundNumber)
+}
@@ -0,0 +1,38 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+
+	"github.com/JonathanLogan/tlock"
+)
+
+// expectDigest returns a writer to dst checking the plaintext against the
+// digest given with --expect-sha256.
+func expectDigest(flags Flags, dst io.Writer) *tlock.DigestWriter {
+	expected, _ := hex.DecodeString(flags.ExpectSHA256)
+	return tlock.NewDigestWriter(dst, expected)
+}
+
+// =============================================================================
+
+// validateDigestFlags checks the flags verifying the digest of the plaintext.
+func validateDigestFlags(f *Flags) error {
+	if f.ExpectSHA256 == "" {
+		return nil
+	}
+	switch {
+	case !f.Decrypt:
+		return errors.New("--expect-sha256 can only be used with -d/--decrypt")
+	case f.OutDir != "":
+		return errors.New("--expect-sha256 can't be used with --out-dir")
+	case f.Daemon != "":
+		return errors.New("--expect-sha256 can't be used with --daemon")
+	}
+	if b, err := hex.DecodeString(f.ExpectSHA256); err != nil || len(b) != sha256.Size {
+		return errors.New("--expect-sha256 must be a hex encoded SHA-256 digest")
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecryptExpectSHA256(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	dir := t.TempDir()
+	beacons, input := writeOfflineCiphertext(t, dir, "committed")
+
+	digest := sha256.Sum256([]byte("committed"))
+	flags := DefaultFlags()
+	flags.Decrypt = true
+	flags.Beacons = beacons
+	flags.Inputs = []string{input}
+	flags.Output = filepath.Join(dir, "data")
+	flags.ExpectSHA256 = hex.EncodeToString(digest[:])
+	require.NoError(t, Operation{Flags: flags}.Run(context.Background()))
+	plaintext, err := os.ReadFile(flags.Output)
+	require.NoError(t, err)
+	require.Equal(t, "committed", string(plaintext))
+
+	// On mismatch, the outputs are removed.
+	digest = sha256.Sum256([]byte("other"))
+	flags.ExpectSHA256 = hex.EncodeToString(digest[:])
+	flags.Output = filepath.Join(dir, "mismatch")
+	flags.Tee = []string{filepath.Join(dir, "copy")}
+	require.ErrorIs(t, Operation{Flags: flags}.Run(context.Background()), tlock.ErrPlaintextMismatch)
+	for _, name := range []string{flags.Output, flags.Output + ".partial", flags.Tee[0], flags.Tee[0] + ".partial"} {
+		require.NoFileExists(t, name)
+	}
+}
@@ -1,14 +1,16 @@
 package commands
 
 import (
+	"crypto/sha256"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"os"
 	"regexp"
 	"strconv"
 	"time"
 
-	"filippo.io/age/armor"
 	"github.com/JonathanLogan/tlock"
 	"github.com/JonathanLogan/tlock/networks/http"
 )
@@ -20,10 +22,39 @@ var ErrInvalidDurationValue = errors.New("the duration you entered is either in
 // of an encoder for reading/writing to disk, a network for making calls to the
 // drand network, and an encrypter for encrypting/decrypting the data.
 func Encrypt(flags Flags, dst io.Writer, src io.Reader, network *http.Network) error {
-	tlock := tlock.New(network)
+	t := tlock.New(network)
+	if flags.Passphrase != "" {
+		params, err := tlock.KDFPreset(flags.KDFPreset)
+		if err != nil {
+			return err
+		}
+		t = t.WithPassphrase(flags.Passphrase, params)
+	}
+	if flags.Reproducible != "" {
+		t = t.WithReproducibleSeed(flags.ReproducibleSeed)
+	}
+	if flags.Entropy != "" {
+		f, err := openEntropy(flags.Entropy)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		t = t.WithRandom(f)
+	}
+
+	if flags.Decoy != "" {
+		d, err := t.NewDecoyWriter(dst, flags.Decoy)
+		if err != nil {
+			return err
+		}
+		dst = d
+	}
 
 	if flags.Armor {
-		a := armor.NewWriter(dst)
+		a := tlock.NewArmorWriter(dst)
+		if flags.Hint {
+			a = tlock.NewHintedArmorWriter(dst, network)
+		}
 		defer func() {
 			if err := a.Close(); err != nil {
 				fmt.Printf("Error while closing: %v", err)
@@ -32,32 +63,151 @@ func Encrypt(flags Flags, dst io.Writer, src io.Reader, network *http.Network) e
 		dst = a
 	}
 
+	roundNumber, err := RoundNumber(flags, network)
+	if err != nil {
+		return err
+	}
+	if flags.OpenFor != "" {
+		notAfter, err := notAfterRound(flags, network, roundNumber)
+		if err != nil {
+			return err
+		}
+		t = t.WithNotAfter(notAfter)
+	}
+
+	if flags.PuzzleFallback {
+		squarings, err := puzzleSquarings(network, roundNumber)
+		if err != nil {
+			return err
+		}
+		t = t.WithPuzzleFallback(squarings)
+	}
+	if flags.Live {
+		return encryptLive(t, flags, dst, src, roundNumber)
+	}
+
+	encrypt := t.Encrypt
+	if flags.Convergent != "" {
+		encrypt = func(dst io.Writer, src io.Reader, roundNumber uint64) error {
+			return t.EncryptConvergent(dst, src, roundNumber, flags.ConvergentSecret)
+		}
+	}
+
+	if flags.Format != "" {
+		enc, _, err := tlock.LookupEncoder(flags.Format)
+		if err != nil {
+			return err
+		}
+		encrypt = func(dst io.Writer, src io.Reader, roundNumber uint64) error {
+			return enc(t, dst, src, roundNumber)
+		}
+	}
+
+	var attestation tlock.Attestation
+	if flags.Attestation != "" {
+		encrypt = func(dst io.Writer, src io.Reader, roundNumber uint64) (err error) {
+			attestation, err = t.EncryptAttested(dst, src, roundNumber, artifactName(flags.Input))
+			return err
+		}
+	}
+
+	// Reproducible encryption reads its input twice, which a pipe only allows
+	// through the spool.
+	if flags.Reproducible != "" && flags.Convergent == "" && flags.Spool != "" {
+		limit, _ := ParseSize(flags.Spool)
+		spool, rr, err := SpoolUnseekable(src, limit)
+		if err != nil {
+			return err
+		}
+		if spool != nil {
+			defer spool.Close()
+		}
+		src = rr
+	}
+
+	h := sha256.New()
+	if flags.Anchor != "" {
+		dst = io.MultiWriter(dst, h)
+	}
+	if err := encrypt(dst, src, roundNumber); err != nil {
+		return err
+	}
+
+	if flags.Attestation != "" {
+		if err := writeAttestation(flags.Attestation, attestation); err != nil {
+			return err
+		}
+	}
+	if flags.Anchor != "" {
+		anchor := tlock.Anchor{Round: roundNumber, ChainHash: network.ChainHash()}
+		h.Sum(anchor.Digest[:0])
+		return writeAnchor(flags.Anchor, anchor)
+	}
+	return nil
+}
+
+// RoundNumber returns the round the flags ask to encrypt towards.
+func RoundNumber(flags Flags, network interface{ RoundNumber(time.Time) uint64 }) (uint64, error) {
 	switch {
 	case flags.Round != 0:
 		lastestAvailableRound := network.RoundNumber(time.Now())
 		if !flags.Force && flags.Round < lastestAvailableRound {
-			return fmt.Errorf("round %d is in the past", flags.Round)
+			return 0, fmt.Errorf("round %d is in the past", flags.Round)
 		}
 
-		return tlock.Encrypt(dst, src, flags.Round)
+		return flags.Round, nil
 
 	case flags.Duration != "":
 		start := time.Now()
 		totalDuration, err := parseDurationsAsSeconds(start, flags.Duration)
 		if err != nil {
-			return err
+			return 0, err
 		}
 
 		decryptionTime := start.Add(totalDuration)
 		if decryptionTime.Before(start) || decryptionTime.Equal(start) {
-			return ErrInvalidDurationValue
+			return 0, ErrInvalidDurationValue
 		}
 
-		roundNumber := network.RoundNumber(decryptionTime)
-		return tlock.Encrypt(dst, src, roundNumber)
+		return network.RoundNumber(decryptionTime), nil
 	default:
-		return errors.New("you must provide either duration or a round flag to encrypt")
+		return 0, errors.New("you must provide either duration or a round flag to encrypt")
+	}
+}
+
+// WarnDrift writes a warning to w when the round the flags encrypt towards is
+// due more than tlock.LongLockThreshold from now, telling how late it may
+// unlock and the assumptions this relies on.
+func WarnDrift(w io.Writer, flags Flags, network *http.Network) {
+	roundNumber, err := RoundNumber(flags, network)
+	if err != nil {
+		return
+	}
+	a := tlock.AnalyzeDrift(network.Info(), roundNumber, time.Now())
+	if !a.Long() {
+		return
+	}
+	loc, err := LoadLocation(flags.TZ)
+	if err != nil {
+		loc = time.Local
+	}
+
+	fmt.Fprintln(w, Message(MsgDriftWarning, a.Round, FormatTime(a.Scheduled, loc), int(a.Duration.Hours()/24), a.Uncertainty().Round(time.Minute)))
+	for _, assumption := range a.Assumptions {
+		fmt.Fprintf(w, "  - %s\n", assumption)
+	}
+}
+
+// writeAnchor writes the anchor of the ciphertext as JSON to the named file.
+func writeAnchor(name string, anchor tlock.Anchor) error {
+	b, err := json.MarshalIndent(anchor, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal anchor: %w", err)
+	}
+	if err := os.WriteFile(name, append(b, '\n'), 0600); err != nil {
+		return fmt.Errorf("write anchor: %w", err)
 	}
+	return nil
 }
 
 var ErrDuplicateDuration = errors.New("you cannot use the same duration unit specifier twice in one duration")
@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/JonathanLogan/tlock"
+)
+
+// openEntropy opens the randomness source named by --entropy, such as the
+// device of a certified generator, once it passes its health check.
+func openEntropy(name string) (*os.File, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("open entropy source: %w", err)
+	}
+	if err := tlock.CheckRandom(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return f, nil
+}
+
+// =============================================================================
+
+// validateEntropyFlags checks the flags drawing randomness from a source.
+func validateEntropyFlags(f *Flags) error {
+	if f.Entropy == "" {
+		return nil
+	}
+	switch {
+	case !f.Encrypt:
+		return errors.New("--entropy can only be used with -e/--encrypt")
+	case f.Reproducible != "":
+		return errors.New("--entropy can't be used with --reproducible")
+	case f.Convergent != "":
+		return errors.New("--entropy can't be used with --convergent")
+	case f.Daemon != "":
+		return errors.New("--entropy can't be used with --daemon")
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"filippo.io/age"
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/http"
+	"github.com/JonathanLogan/tlock/networks/multi"
+)
+
+// These are the exit codes of tle, letting scripts branch on the cause of a
+// failure. Failures of any other cause exit with ExitFailure.
+const (
+	ExitSuccess    = 0
+	ExitFailure    = 1
+	ExitTooEarly   = 2
+	ExitNetwork    = 3
+	ExitFormat     = 4
+	ExitWrongChain = 5
+
+	// ExitInterrupted follows the shell convention for SIGINT.
+	ExitInterrupted = 130
+)
+
+// formatErrors are the errors reporting malformed input.
+var formatErrors = []error{
+	tlock.ErrMalformedHeader,
+	tlock.ErrMalformedPayload,
+	tlock.ErrNoTlockStanza,
+	tlock.ErrInvalidKDFParams,
+	tlock.ErrMalformedPEM,
+	tlock.ErrMalformedEnvelope,
+	tlock.ErrMalformedJWE,
+	tlock.ErrMalformedCompact,
+	tlock.ErrMalformedCMS,
+	tlock.ErrMalformedMessage,
+	tlock.ErrMalformedQRSegment,
+	tlock.ErrIncompleteQRSegments,
+	tlock.ErrMalformedContractCiphertext,
+	tlock.ErrMalformedConvergent,
+	tlock.ErrMalformedBeaconBundle,
+	tlock.ErrMalformedShare,
+	tlock.ErrIncompatibleShares,
+}
+
+// wrongChainErrors are the errors reporting a chain that can't be used.
+var wrongChainErrors = []error{
+	tlock.ErrWrongChainhash,
+	tlock.ErrNotUnchained,
+	http.ErrNotUnchained,
+	http.ErrChainHashMismatch,
+}
+
+// networkErrors are the errors reporting unreachable or inconsistent relays.
+var networkErrors = []error{
+	multi.ErrNoRelay,
+	multi.ErrRelayMismatch,
+	multi.ErrNotEnoughRelays,
+	ErrChainHalted,
+	context.DeadlineExceeded,
+}
+
+// ExitCode returns the exit code reporting the cause of err.
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitSuccess
+	case errors.Is(err, ErrInterrupted):
+		return ExitInterrupted
+	case errors.Is(err, tlock.ErrTooEarly):
+		return ExitTooEarly
+	case isAny(err, wrongChainErrors):
+		return ExitWrongChain
+	case isAny(err, formatErrors):
+		return ExitFormat
+	case isAny(err, networkErrors):
+		return ExitNetwork
+	}
+
+	var noMatch *age.NoIdentityMatchError
+	if errors.As(err, &noMatch) {
+		return ExitFormat
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return ExitNetwork
+	}
+
+	return ExitFailure
+}
+
+// isAny reports whether err matches any of the targets.
+func isAny(err error, targets []error) bool {
+	for _, target := range targets {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/JonathanLogan/tlock/networks/http"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExitCode(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	require.Equal(t, ExitSuccess, ExitCode(nil))
+	require.Equal(t, ExitFailure, ExitCode(errors.New("boom")))
+	require.Equal(t, ExitTooEarly, ExitCode(fmt.Errorf("%w: round 10", tlock.ErrTooEarly)))
+	require.Equal(t, ExitNetwork, ExitCode(&url.Error{Op: "Get", URL: "https://api.drand.sh/", Err: errors.New("connection refused")}))
+	require.Equal(t, ExitWrongChain, ExitCode(fmt.Errorf("wrap: %w", http.ErrChainHashMismatch)))
+	require.Equal(t, ExitWrongChain, ExitCode(fmt.Errorf("%w: ciphertext for another chain", tlock.ErrWrongChainhash)))
+
+	network := fixedtest.NewNetwork(t, 1000)
+	err := tlock.New(network).Decrypt(&bytes.Buffer{}, bytes.NewReader([]byte("not a ciphertext")))
+	require.Equal(t, ExitFormat, ExitCode(err))
+
+}
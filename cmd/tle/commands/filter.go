@@ -0,0 +1,161 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/http"
+)
+
+// filterCommand defines the filter subcommand.
+var filterCommand = CommandSpec{
+	Name: "filter",
+	Synopsis: []string{
+		`tle filter --encrypt (-r round | -D duration) [-n NETWORK] [-c CHAIN] [--auth-token TOKEN] [--doh URL] [--ip-family FAMILY] [--no-pin] [-a] [--passphrase-file FILE [--kdf-preset PRESET]] [--convergent FILE]`,
+		`tle filter --decrypt [-n NETWORK] [-c CHAIN] [--auth-token TOKEN] [--doh URL] [--ip-family FAMILY] [--no-pin] [--passphrase-file FILE] [--spool SIZE]`,
+	},
+	Flags: []FlagSpec{
+		mainFlag("encrypt", func(f *FilterFlags) any { return &f.Encrypt }),
+		mainFlag("decrypt", func(f *FilterFlags) any { return &f.Decrypt }),
+		mainFlag("network", func(f *FilterFlags) any { return &f.Network }),
+		mainFlag("chain", func(f *FilterFlags) any { return &f.Chain }),
+		mainFlag("auth-token", func(f *FilterFlags) any { return &f.AuthToken }),
+		mainFlag("doh", func(f *FilterFlags) any { return &f.DoH }),
+		mainFlag("ip-family", func(f *FilterFlags) any { return &f.IPFamily }),
+		mainFlag("no-pin", func(f *FilterFlags) any { return &f.NoPin }),
+		mainFlag("round", func(f *FilterFlags) any { return &f.Round }),
+		mainFlag("duration", func(f *FilterFlags) any { return &f.Duration }),
+		mainFlag("armor", func(f *FilterFlags) any { return &f.Armor }),
+		mainFlag("passphrase-file", func(f *FilterFlags) any { return &f.PassphraseFile }),
+		mainFlag("kdf-preset", func(f *FilterFlags) any { return &f.KDFPreset }),
+		mainFlag("convergent", func(f *FilterFlags) any { return &f.Convergent }),
+		mainFlag("spool", func(f *FilterFlags) any { return &f.Spool }),
+	},
+	notes: `Encrypts or decrypts the standard input to the standard output, for backup
+tools running external commands on their streams. It never reads from or
+writes to the terminal, writes nothing but errors to the standard error, and
+exits with the status codes of tle: 0 on success, 2 when it is too early to
+decrypt, 3 on network errors, 4 when the input is malformed, 5 when the chain
+is wrong for the ciphertext, and 1 on any other failure, including invalid
+arguments.
+
+With --convergent, repeated data encrypts to the same bytes so that the backup
+tool still deduplicates it, at the cost of the confidentiality trade-offs
+described in the usage of tle. Decrypting such a ciphertext buffers it in a
+temporary file, encrypted with a key kept in memory, of up to SIZE if given.
+
+Examples:
+    $ tar c data | tle filter -e -D 1y | restic backup --stdin --stdin-filename data.tar.tle
+    $ borg create --content-from-command repo::data -- sh -c 'tar c data | tle filter -e -D 1y'
+    $ restic dump latest data.tar.tle | tle filter -d | tar x`,
+	defaults: func() any {
+		return &FilterFlags{Network: DefaultNetwork, Chain: DefaultChain, KDFPreset: DefaultKDFPreset}
+	},
+}
+
+// FilterFlags represent the values from the filter command line.
+type FilterFlags struct {
+	Encrypt        bool
+	Decrypt        bool
+	Network        string
+	Chain          string
+	Round          uint64
+	Duration       string
+	Armor          bool
+	PassphraseFile string
+	KDFPreset      string
+	Passphrase     string
+
+	Convergent       string
+	ConvergentSecret []byte
+	Spool            string
+
+	NetworkFlags
+}
+
+// ParseFilter parses the arguments following the filter subcommand.
+func ParseFilter(args []string) (FilterFlags, error) {
+	f, fs, err := parseCommand[FilterFlags]("filter", args)
+	if err != nil {
+		return FilterFlags{}, err
+	}
+	if fs.NArg() != 0 || f.Encrypt == f.Decrypt {
+		return FilterFlags{}, errors.New(commandUsage("filter"))
+	}
+
+	switch {
+	case f.Encrypt:
+		if f.Duration != "" && f.Round != 0 {
+			return FilterFlags{}, fmt.Errorf("-D/--duration can't be used with -r/--round")
+		}
+		if f.Duration == "" && f.Round == 0 {
+			return FilterFlags{}, fmt.Errorf("-D/--duration or -r/--round must be specified")
+		}
+		if f.Convergent != "" && f.Armor {
+			return FilterFlags{}, fmt.Errorf("--convergent can't be used with -a/--armor")
+		}
+		if f.Spool != "" {
+			return FilterFlags{}, fmt.Errorf("--spool can only be used with -d/--decrypt")
+		}
+		if _, err := tlock.KDFPreset(f.KDFPreset); err != nil {
+			return FilterFlags{}, fmt.Errorf("--kdf-preset: %w", err)
+		}
+	default:
+		if f.Duration != "" || f.Round != 0 || f.Armor || f.Convergent != "" {
+			return FilterFlags{}, fmt.Errorf("--decrypt only accepts -n/--network, -c/--chain, --passphrase-file and --spool")
+		}
+		if f.Spool != "" {
+			if _, err := ParseSize(f.Spool); err != nil {
+				return FilterFlags{}, fmt.Errorf("--spool: %w", err)
+			}
+		}
+	}
+
+	if f.PassphraseFile != "" {
+		if f.Passphrase, err = readPassphrase(f.PassphraseFile); err != nil {
+			return FilterFlags{}, err
+		}
+	}
+	if f.Convergent != "" {
+		if f.ConvergentSecret, err = readConvergentSecret(f.Convergent); err != nil {
+			return FilterFlags{}, err
+		}
+	}
+
+	return f, nil
+}
+
+// Filter encrypts or decrypts src to dst. Unlike the default command, it
+// doesn't check whether dst is a terminal.
+func Filter(flags FilterFlags, dst io.Writer, src io.Reader, network *http.Network) error {
+	if flags.Encrypt {
+		return Encrypt(Flags{
+			Round:            flags.Round,
+			Duration:         flags.Duration,
+			Armor:            flags.Armor,
+			Passphrase:       flags.Passphrase,
+			KDFPreset:        flags.KDFPreset,
+			Convergent:       flags.Convergent,
+			ConvergentSecret: flags.ConvergentSecret,
+		}, dst, src, network)
+	}
+
+	t := tlock.New(network).WithPassphrase(flags.Passphrase, tlock.KDFParams{})
+
+	// Convergent ciphertexts need seeking, which pipes can't do.
+	var limit int64
+	if flags.Spool != "" {
+		limit, _ = ParseSize(flags.Spool)
+	}
+	spool, rr, err := SpoolConvergent(src, limit)
+	if err != nil {
+		return err
+	}
+	if spool == nil {
+		return t.Decrypt(dst, rr)
+	}
+	defer spool.Close()
+	return t.DecryptConvergent(dst, spool)
+}
@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		shouldError bool
+	}{
+		{name: "encrypt with round passes", args: []string{"-e", "-r", "1"}},
+		{name: "encrypt with duration and armor passes", args: []string{"--encrypt", "-D", "1y", "-a"}},
+		{name: "decrypt passes", args: []string{"--decrypt"}},
+		{name: "encrypt without round or duration fails", args: []string{"-e"}, shouldError: true},
+		{name: "encrypt with both round and duration fails", args: []string{"-e", "-r", "1", "-D", "1d"}, shouldError: true},
+		{name: "encrypt with convergent and armor fails", args: []string{"-e", "-r", "1", "-a", "--convergent", "secret.key"}, shouldError: true},
+		{name: "decrypt with round fails", args: []string{"-d", "-r", "1"}, shouldError: true},
+		{name: "missing mode fails", shouldError: true},
+		{name: "both modes fail", args: []string{"-e", "-d", "-r", "1"}, shouldError: true},
+		{name: "input argument fails", args: []string{"-d", "backup.tar"}, shouldError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseFilter(test.args)
+			if test.shouldError {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestFilterMalformedInput(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	var out bytes.Buffer
+	err := Filter(FilterFlags{Decrypt: true}, &out, strings.NewReader("not encrypted"), nil)
+	require.Equal(t, ExitFormat, ExitCode(err))
+	require.Zero(t, out.Len())
+
+	err = Filter(FilterFlags{Decrypt: true}, &out, strings.NewReader("tlock convergent v1\nshort"), nil)
+	require.Equal(t, ExitFormat, ExitCode(err))
+	require.Zero(t, out.Len())
+}
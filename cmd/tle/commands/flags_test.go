@@ -220,6 +220,918 @@ func Test(t *testing.T) {
 			},
 			shouldError: true,
 		},
+		{
+			name: "parsing encrypt with both armor and decoy fails",
+			flags: []KV{
+				{
+					key:   "TLE_ENCRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_ROUND",
+					value: "1",
+				},
+				{
+					key:   "TLE_ARMOR",
+					value: "true",
+				},
+				{
+					key:   "TLE_DECOY",
+					value: "hint",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "parsing decrypt with auth token passes",
+			flags: []KV{
+				{
+					key:   "TLE_DECRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_AUTHTOKEN",
+					value: "secret",
+				},
+			},
+			shouldError: false,
+		},
+		{
+			name: "parsing decrypt with invalid ip family fails",
+			flags: []KV{
+				{
+					key:   "TLE_DECRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_IPFAMILY",
+					value: "5",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "parsing encrypt with spool fails",
+			flags: []KV{
+				{
+					key:   "TLE_ENCRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_ROUND",
+					value: "1",
+				},
+				{
+					key:   "TLE_SPOOL",
+					value: "1G",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "parsing decrypt with jwe format passes",
+			flags: []KV{
+				{
+					key:   "TLE_DECRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_FORMAT",
+					value: "jwe",
+				},
+			},
+			shouldError: false,
+		},
+		{
+			name: "parsing encrypt with unknown format fails",
+			flags: []KV{
+				{
+					key:   "TLE_ENCRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_ROUND",
+					value: "1",
+				},
+				{
+					key:   "TLE_FORMAT",
+					value: "xml",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "parsing encrypt with armor and pem format fails",
+			flags: []KV{
+				{
+					key:   "TLE_ENCRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_ROUND",
+					value: "1",
+				},
+				{
+					key:   "TLE_ARMOR",
+					value: "true",
+				},
+				{
+					key:   "TLE_FORMAT",
+					value: "pem",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "parsing encrypt with armor and unlock hint passes",
+			flags: []KV{
+				{
+					key:   "TLE_ENCRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_ROUND",
+					value: "1",
+				},
+				{
+					key:   "TLE_FORMAT",
+					value: "armor",
+				},
+				{
+					key:   "TLE_HINT",
+					value: "true",
+				},
+			},
+			shouldError: false,
+		},
+		{
+			name: "parsing encrypt with unlock hint and no armor fails",
+			flags: []KV{
+				{
+					key:   "TLE_ENCRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_ROUND",
+					value: "1",
+				},
+				{
+					key:   "TLE_HINT",
+					value: "true",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "parsing decrypt with anchor fails",
+			flags: []KV{
+				{
+					key:   "TLE_DECRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_ANCHOR",
+					value: "anchor.json",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "parsing encrypt with out-dir and output fails",
+			flags: []KV{
+				{
+					key:   "TLE_ENCRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_ROUND",
+					value: "1",
+				},
+				{
+					key:   "TLE_OUTDIR",
+					value: "out",
+				},
+				{
+					key:   "TLE_OUTPUT",
+					value: "out.tle",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "parsing encrypt with name template without out-dir fails",
+			flags: []KV{
+				{
+					key:   "TLE_ENCRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_ROUND",
+					value: "1",
+				},
+				{
+					key:   "TLE_NAMETEMPLATE",
+					value: "{name}.{round}.tle",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "parsing decrypt with an invalid max memory fails",
+			flags: []KV{
+				{
+					key:   "TLE_DECRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_MAXMEMORY",
+					value: "lots",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "parsing decrypt with jobs and max memory passes",
+			flags: []KV{
+				{
+					key:   "TLE_DECRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_JOBS",
+					value: "4",
+				},
+				{
+					key:   "TLE_MAXMEMORY",
+					value: "512M",
+				},
+			},
+			shouldError: false,
+		},
+		{
+			name: "parsing decrypt with convergent fails",
+			flags: []KV{
+				{
+					key:   "TLE_DECRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_CONVERGENT",
+					value: "secret.key",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "parsing encrypt with convergent and armor fails",
+			flags: []KV{
+				{
+					key:   "TLE_ENCRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_DURATION",
+					value: "1d",
+				},
+				{
+					key:   "TLE_ARMOR",
+					value: "true",
+				},
+				{
+					key:   "TLE_CONVERGENT",
+					value: "secret.key",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "parsing encrypt with period and genesis passes",
+			flags: []KV{
+				{
+					key:   "TLE_ENCRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_DURATION",
+					value: "1d",
+				},
+				{
+					key:   "TLE_PERIOD",
+					value: "3s",
+				},
+				{
+					key:   "TLE_GENESIS",
+					value: "1692803367",
+				},
+			},
+			shouldError: false,
+		},
+		{
+			name: "parsing encrypt with period alone fails",
+			flags: []KV{
+				{
+					key:   "TLE_ENCRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_DURATION",
+					value: "1d",
+				},
+				{
+					key:   "TLE_PERIOD",
+					value: "3s",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "parsing encrypt with open-for succeeds",
+			flags: []KV{
+				{
+					key:   "TLE_ENCRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_DURATION",
+					value: "1d",
+				},
+				{
+					key:   "TLE_OPENFOR",
+					value: "2h",
+				},
+			},
+			shouldError: false,
+		},
+		{
+			name: "parsing decrypt with open-for fails",
+			flags: []KV{
+				{
+					key:   "TLE_DECRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_OPENFOR",
+					value: "2h",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "parsing encrypt with enforce-window fails",
+			flags: []KV{
+				{
+					key:   "TLE_ENCRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_DURATION",
+					value: "1d",
+				},
+				{
+					key:   "TLE_ENFORCEWINDOW",
+					value: "true",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "parsing decrypt with enforce-window succeeds",
+			flags: []KV{
+				{
+					key:   "TLE_DECRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_ENFORCEWINDOW",
+					value: "true",
+				},
+			},
+			shouldError: false,
+		},
+		{
+			name: "parsing encrypt with beacons fails",
+			flags: []KV{
+				{
+					key:   "TLE_ENCRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_DURATION",
+					value: "1d",
+				},
+				{
+					key:   "TLE_BEACONS",
+					value: "round.beacon",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "parsing decrypt with beacons succeeds",
+			flags: []KV{
+				{
+					key:   "TLE_DECRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_BEACONS",
+					value: "round.beacon",
+				},
+			},
+			shouldError: false,
+		},
+		{
+			name: "parsing encrypt with entropy succeeds",
+			flags: []KV{
+				{
+					key:   "TLE_ENCRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_DURATION",
+					value: "1d",
+				},
+				{
+					key:   "TLE_ENTROPY",
+					value: "/dev/hwrng",
+				},
+			},
+			shouldError: false,
+		},
+		{
+			name: "parsing decrypt with entropy fails",
+			flags: []KV{
+				{
+					key:   "TLE_DECRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_ENTROPY",
+					value: "/dev/hwrng",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "parsing encrypt with shares succeeds",
+			flags: []KV{
+				{
+					key:   "TLE_ENCRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_DURATION",
+					value: "1d",
+				},
+				{
+					key:   "TLE_OUTPUT",
+					value: "secret.tle",
+				},
+				{
+					key:   "TLE_SHARES",
+					value: "3",
+				},
+				{
+					key:   "TLE_THRESHOLD",
+					value: "2",
+				},
+			},
+			shouldError: false,
+		},
+		{
+			name: "parsing encrypt with shares without output fails",
+			flags: []KV{
+				{
+					key:   "TLE_ENCRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_DURATION",
+					value: "1d",
+				},
+				{
+					key:   "TLE_SHARES",
+					value: "3",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "parsing encrypt with threshold above shares fails",
+			flags: []KV{
+				{
+					key:   "TLE_ENCRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_DURATION",
+					value: "1d",
+				},
+				{
+					key:   "TLE_OUTPUT",
+					value: "secret.tle",
+				},
+				{
+					key:   "TLE_SHARES",
+					value: "3",
+				},
+				{
+					key:   "TLE_THRESHOLD",
+					value: "4",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "parsing decrypt with tee succeeds",
+			flags: []KV{
+				{
+					key:   "TLE_DECRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_OUTPUT",
+					value: "plain.txt",
+				},
+				{
+					key:   "TLE_TEE",
+					value: "copy.txt,-",
+				},
+			},
+			shouldError: false,
+		},
+		{
+			name: "parsing decrypt with tee to the output fails",
+			flags: []KV{
+				{
+					key:   "TLE_DECRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_TEE",
+					value: "-",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "parsing encrypt with tee fails",
+			flags: []KV{
+				{
+					key:   "TLE_ENCRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_DURATION",
+					value: "1d",
+				},
+				{
+					key:   "TLE_TEE",
+					value: "copy.tle",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "parsing decrypt with expect-sha256 succeeds",
+			flags: []KV{
+				{
+					key:   "TLE_DECRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_EXPECTSHA256",
+					value: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+				},
+			},
+			shouldError: false,
+		},
+		{
+			name: "parsing decrypt with a truncated expect-sha256 fails",
+			flags: []KV{
+				{
+					key:   "TLE_DECRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_EXPECTSHA256",
+					value: "e3b0c44298fc1c149afbf4c8996fb924",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "parsing decrypt with puzzle-fallback succeeds",
+			flags: []KV{
+				{
+					key:   "TLE_DECRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_PUZZLEFALLBACK",
+					value: "true",
+				},
+			},
+			shouldError: false,
+		},
+		{
+			name: "parsing decrypt with puzzle-fallback and beacons fails",
+			flags: []KV{
+				{
+					key:   "TLE_DECRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_PUZZLEFALLBACK",
+					value: "true",
+				},
+				{
+					key:   "TLE_BEACONS",
+					value: "rounds.beacon",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "parsing decrypt with puzzle-solution succeeds",
+			flags: []KV{
+				{
+					key:   "TLE_DECRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_PUZZLEFALLBACK",
+					value: "true",
+				},
+				{
+					key:   "TLE_PUZZLESOLUTION",
+					value: "secret.solution",
+				},
+			},
+			shouldError: false,
+		},
+		{
+			name: "parsing decrypt with puzzle-solution without puzzle-fallback fails",
+			flags: []KV{
+				{
+					key:   "TLE_DECRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_PUZZLESOLUTION",
+					value: "secret.solution",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "parsing decrypt with ticket succeeds",
+			flags: []KV{
+				{
+					key:   "TLE_DECRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_TICKET",
+					value: "data.ticket",
+				},
+			},
+			shouldError: false,
+		},
+		{
+			name: "parsing decrypt with ticket and beacons fails",
+			flags: []KV{
+				{
+					key:   "TLE_DECRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_TICKET",
+					value: "data.ticket",
+				},
+				{
+					key:   "TLE_BEACONS",
+					value: "rounds.beacon",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "parsing decrypt with sandbox and beacons succeeds",
+			flags: []KV{
+				{
+					key:   "TLE_DECRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_SANDBOX",
+					value: "true",
+				},
+				{
+					key:   "TLE_BEACONS",
+					value: "rounds.beacon",
+				},
+			},
+			shouldError: false,
+		},
+		{
+			name: "parsing encrypt with sandbox fails",
+			flags: []KV{
+				{
+					key:   "TLE_ENCRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_SANDBOX",
+					value: "true",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "parsing decrypt with strict-decode succeeds",
+			flags: []KV{
+				{
+					key:   "TLE_DECRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_STRICTDECODE",
+					value: "true",
+				},
+			},
+			shouldError: false,
+		},
+		{
+			name: "parsing encrypt with strict-decode fails",
+			flags: []KV{
+				{
+					key:   "TLE_ENCRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_STRICTDECODE",
+					value: "true",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "parsing decrypt with strict-decode and decoy fails",
+			flags: []KV{
+				{
+					key:   "TLE_DECRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_STRICTDECODE",
+					value: "true",
+				},
+				{
+					key:   "TLE_DECOY",
+					value: "hint",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "parsing encrypt with live and chunk-size succeeds",
+			flags: []KV{
+				{
+					key:   "TLE_ENCRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_LIVE",
+					value: "true",
+				},
+				{
+					key:   "TLE_CHUNKSIZE",
+					value: "1K",
+				},
+				{
+					key:   "TLE_DURATION",
+					value: "1d",
+				},
+			},
+			shouldError: false,
+		},
+		{
+			name: "parsing chunk-size without live fails",
+			flags: []KV{
+				{
+					key:   "TLE_ENCRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_CHUNKSIZE",
+					value: "1K",
+				},
+				{
+					key:   "TLE_DURATION",
+					value: "1d",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "parsing live with armor fails",
+			flags: []KV{
+				{
+					key:   "TLE_ENCRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_LIVE",
+					value: "true",
+				},
+				{
+					key:   "TLE_ARMOR",
+					value: "true",
+				},
+				{
+					key:   "TLE_DURATION",
+					value: "1d",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "parsing encrypt with pace fails",
+			flags: []KV{
+				{
+					key:   "TLE_ENCRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_LIVE",
+					value: "true",
+				},
+				{
+					key:   "TLE_PACE",
+					value: "true",
+				},
+				{
+					key:   "TLE_DURATION",
+					value: "1d",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "parsing decrypt with reproducible fails",
+			flags: []KV{
+				{
+					key:   "TLE_DECRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_REPRODUCIBLE",
+					value: "seed.key",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "parsing encrypt with reproducible and decoy fails",
+			flags: []KV{
+				{
+					key:   "TLE_ENCRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_DURATION",
+					value: "1d",
+				},
+				{
+					key:   "TLE_DECOY",
+					value: "hint",
+				},
+				{
+					key:   "TLE_REPRODUCIBLE",
+					value: "seed.key",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "parsing decrypt with attestation and out-dir fails",
+			flags: []KV{
+				{
+					key:   "TLE_DECRYPT",
+					value: "true",
+				},
+				{
+					key:   "TLE_ATTESTATION",
+					value: "release.intoto.json",
+				},
+				{
+					key:   "TLE_OUTDIR",
+					value: "out",
+				},
+			},
+			shouldError: true,
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/JonathanLogan/tlock"
+)
+
+// textFormats are the registered formats whose output is printable.
+var textFormats = map[string]bool{
+	"armor":    true,
+	"pem":      true,
+	"envelope": true,
+	"jwe":      true,
+}
+
+// BinaryOutput tells whether the flags write binary output, which isn't
+// printed to a terminal unless forced.
+func BinaryOutput(flags Flags) bool {
+	return flags.Encrypt && !flags.Armor && !textFormats[flags.Format]
+}
+
+// validateFormatFlags checks that the format is registered and that the other
+// flags don't pick another encoding.
+func validateFormatFlags(f *Flags) error {
+	if f.Format != "" {
+		if err := validateFormat(f); err != nil {
+			return err
+		}
+	}
+	if f.Hint && (!f.Encrypt || !f.Armor) {
+		return errors.New("--unlock-hint requires -e/--encrypt with -a/--armor")
+	}
+	return nil
+}
+
+// validateFormat checks the format of the flags.
+func validateFormat(f *Flags) error {
+	if _, _, err := tlock.LookupEncoder(f.Format); err != nil {
+		return fmt.Errorf("--format: %w, expected one of %s", err, strings.Join(tlock.Formats(), ", "))
+	}
+	switch {
+	case f.Metadata:
+		return errors.New("--format can't be used with -m/--metadata")
+	case f.Armor && f.Format != "armor":
+		return errors.New("-a/--armor can't be used with --format")
+	case f.Decoy != "":
+		return errors.New("--format can't be used with --decoy")
+	case f.Convergent != "":
+		return errors.New("--format can't be used with --convergent")
+	case f.Attestation != "":
+		return errors.New("--format can't be used with --attestation")
+	case f.Daemon != "":
+		return errors.New("--format can't be used with --daemon")
+	}
+
+	// The armor format is the one -a/--armor writes.
+	if f.Format == "armor" && f.Encrypt {
+		f.Format, f.Armor = "", true
+	}
+	return nil
+}
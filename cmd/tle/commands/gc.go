@@ -0,0 +1,222 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// cacheCommand defines the cache subcommand.
+var cacheCommand = CommandSpec{
+	Name: "cache",
+	Synopsis: []string{
+		`tle cache gc [--days N] [--max-size SIZE] [--dry-run] [DIR...]`,
+	},
+	Flags: []FlagSpec{
+		{Name: "days", Arg: "N", Description: "Remove the files older than N days.", value: field(func(f *GCFlags) any { return &f.days })},
+		{Name: "max-size", Arg: "SIZE", Description: "Then remove the oldest files until those kept fit within SIZE.", value: field(func(f *GCFlags) any { return &f.maxSize })},
+		{Name: "dry-run", Description: "List the files without removing them.", value: field(func(f *GCFlags) any { return &f.DryRun })},
+	},
+	notes: `Removes the state tle leaves behind: the spools of the decryptions which
+didn't complete, in the temporary directory, and the partial outputs of the
+interrupted ones along with their resume state, in each DIR or in the current
+directory, without descending into subdirectories. Only the partial outputs
+whose resume state tle wrote for them are removed, other files named
+*.partial or *.resume being left alone. Those older than N days, 7 by
+default, are removed, then the oldest ones until the rest fits within SIZE.
+The files of the runs of tle in progress are kept. With --dry-run, the files
+are listed without being removed.`,
+	defaults: func() any { return &GCFlags{days: 7} },
+}
+
+// GCFlags represent the values from the cache gc command line.
+type GCFlags struct {
+	MaxAge  time.Duration
+	MaxSize int64
+	DryRun  bool
+	Dirs    []string
+
+	// days and maxSize are the values of --days and --max-size.
+	days    int
+	maxSize string
+}
+
+// GCReport lists what CollectGarbage removed, or would remove on a dry run.
+type GCReport struct {
+	Removed []string
+	Freed   int64
+}
+
+// ParseCache parses the arguments following the cache subcommand.
+func ParseCache(args []string) (GCFlags, error) {
+	if len(args) == 0 || args[0] != "gc" {
+		return GCFlags{}, errors.New(commandUsage("cache"))
+	}
+
+	f, fs, err := parseCommand[GCFlags]("cache", args[1:])
+	if err != nil {
+		return GCFlags{}, err
+	}
+	if f.days < 0 {
+		return GCFlags{}, errors.New("--days must not be negative")
+	}
+	f.MaxAge = time.Duration(f.days) * 24 * time.Hour
+	if f.maxSize != "" {
+		size, err := ParseSize(f.maxSize)
+		if err != nil {
+			return GCFlags{}, fmt.Errorf("--max-size: %w", err)
+		}
+		f.MaxSize = size
+	}
+	f.Dirs = fs.Args()
+	if len(f.Dirs) == 0 {
+		f.Dirs = []string{"."}
+	}
+
+	return f, nil
+}
+
+// CollectGarbage removes the orphaned spools and the partial outputs and
+// resume state of the flags older than their age, then the oldest ones until
+// the rest fits within their size, a zero size not bounding them. Those
+// another run of tle holds are kept.
+func CollectGarbage(flags GCFlags) (GCReport, error) {
+	entries, err := gcEntries(flags.Dirs)
+	if err != nil {
+		return GCReport{}, err
+	}
+	slices.SortFunc(entries, func(a, b gcEntry) int {
+		return a.modTime.Compare(b.modTime)
+	})
+
+	var total int64
+	for _, e := range entries {
+		total += e.size
+	}
+
+	var report GCReport
+	expired := time.Now().Add(-flags.MaxAge)
+	for _, e := range entries {
+		if !e.modTime.Before(expired) && (flags.MaxSize <= 0 || total <= flags.MaxSize) {
+			continue
+		}
+		removed, err := e.remove(flags.DryRun)
+		if err != nil {
+			return report, err
+		}
+		if removed {
+			total -= e.size
+			report.Freed += e.size
+			report.Removed = append(report.Removed, e.paths...)
+		}
+	}
+	return report, nil
+}
+
+// =============================================================================
+
+// gcEntry is a set of files removed together, such as a partial output and
+// its resume state.
+type gcEntry struct {
+	paths   []string
+	lock    string
+	size    int64
+	modTime time.Time
+}
+
+// gcEntries lists the spools of the temporary directory and the resume state
+// of the directories along with their partial outputs.
+func gcEntries(dirs []string) ([]gcEntry, error) {
+	var entries []gcEntry
+	spools, err := filepath.Glob(filepath.Join(os.TempDir(), "tle-spool-*"))
+	if err != nil {
+		return nil, err
+	}
+	for _, spool := range spools {
+		if e, ok := newGCEntry(spool, spool); ok {
+			entries = append(entries, e)
+		}
+	}
+
+	for _, dir := range dirs {
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			name := filepath.Join(dir, file.Name())
+			if file.IsDir() || !strings.HasSuffix(name, ".resume") {
+				continue
+			}
+			// The partial output is locked by the run resuming it, if any.
+			output := strings.TrimSuffix(name, ".resume")
+			if _, ok := readResumeState(output); !ok {
+				continue
+			}
+			lock := output + ".partial"
+			if _, err := os.Lstat(lock); errors.Is(err, os.ErrNotExist) {
+				lock = ""
+			}
+			if e, ok := newGCEntry(lock, output+".partial", name); ok {
+				entries = append(entries, e)
+			}
+		}
+	}
+	return entries, nil
+}
+
+// newGCEntry returns the entry of the existing files among the paths, which
+// are held by a run of tle while the lock file is locked.
+func newGCEntry(lock string, paths ...string) (gcEntry, bool) {
+	e := gcEntry{lock: lock}
+	for _, path := range paths {
+		info, err := os.Lstat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		e.paths = append(e.paths, path)
+		e.size += info.Size()
+		if info.ModTime().After(e.modTime) {
+			e.modTime = info.ModTime()
+		}
+	}
+	return e, len(e.paths) > 0
+}
+
+// remove removes the files of the entry, reporting false when a run of tle
+// holds them.
+func (e gcEntry) remove(dryRun bool) (bool, error) {
+	if e.lock != "" {
+		f, err := os.OpenFile(e.lock, os.O_RDWR, 0)
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		defer f.Close()
+		if err := lockFile(f); errors.Is(err, ErrOutputLocked) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		if !renameLocked {
+			// Open files can't be removed on this system.
+			f.Close()
+		}
+	}
+
+	if dryRun {
+		return true, nil
+	}
+	for _, path := range e.paths {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return false, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectGarbage(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	dir := t.TempDir()
+	old := time.Now().Add(-30 * 24 * time.Hour)
+
+	write := func(name string, size int, modTime time.Time) string {
+		require.NoError(t, os.WriteFile(name, make([]byte, size), 0600))
+		require.NoError(t, os.Chtimes(name, modTime, modTime))
+		return name
+	}
+	writeState := func(name string, output string, modTime time.Time) string {
+		b, err := json.Marshal(resumeState{Input: "in.age", Size: 100, ModTime: 1, Output: output})
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(name, b, 0600))
+		require.NoError(t, os.Chtimes(name, modTime, modTime))
+		return name
+	}
+	orphan := write(filepath.Join(tmp, "tle-spool-1"), 100, old)
+	partial := write(filepath.Join(dir, "a.txt.partial"), 100, old)
+	resume := writeState(filepath.Join(dir, "a.txt.resume"), "a.txt", old)
+	lone := writeState(filepath.Join(dir, "b.txt.resume"), "b.txt", old)
+	recent := write(filepath.Join(dir, "c.txt.partial"), 1000, time.Now())
+	recentResume := writeState(filepath.Join(dir, "c.txt.resume"), "c.txt", time.Now())
+	unrelated := write(filepath.Join(dir, "d.txt"), 100, old)
+
+	// The files tle didn't write, or not for that output, are left alone.
+	foreign := []string{
+		write(filepath.Join(dir, "f.txt.partial"), 100, old),
+		write(filepath.Join(dir, "f.txt.resume"), 10, old),
+		write(filepath.Join(dir, "g.txt.partial"), 100, old),
+		write(filepath.Join(dir, "h.txt.partial"), 100, old),
+		writeState(filepath.Join(dir, "h.txt.resume"), "a.txt", old),
+	}
+
+	// The files of the runs in progress are kept, however old.
+	spool, err := NewSpool(bytes.NewReader(make([]byte, 100)), 0)
+	require.NoError(t, err)
+	defer spool.Close()
+	require.NoError(t, os.Chtimes(spool.f.Name(), old, old))
+	held, err := CreateOutput(filepath.Join(dir, "e.txt"))
+	require.NoError(t, err)
+	defer held.Finish(nil)
+	require.NoError(t, os.Chtimes(held.Name(), old, old))
+
+	flags := GCFlags{MaxAge: 7 * 24 * time.Hour, DryRun: true, Dirs: []string{dir}}
+	report, err := CollectGarbage(flags)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{orphan, partial, resume, lone}, report.Removed)
+	require.Equal(t, int64(316), report.Freed)
+	require.FileExists(t, orphan)
+
+	flags.DryRun = false
+	report, err = CollectGarbage(flags)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{orphan, partial, resume, lone}, report.Removed)
+	for _, name := range report.Removed {
+		require.NoFileExists(t, name)
+	}
+	require.FileExists(t, recent)
+	require.FileExists(t, unrelated)
+	for _, name := range foreign {
+		require.FileExists(t, name)
+	}
+	require.FileExists(t, spool.f.Name())
+	require.FileExists(t, held.Name())
+
+	// The size bounds what is kept.
+	flags.MaxSize = 500
+	report, err = CollectGarbage(flags)
+	require.NoError(t, err)
+	require.Equal(t, []string{recent, recentResume}, report.Removed)
+}
+
+func TestParseCache(t *testing.T) {
+	f, err := ParseCache([]string{"gc", "--days", "3", "--max-size", "1G", "out"})
+	require.NoError(t, err)
+	require.Equal(t, 3*24*time.Hour, f.MaxAge)
+	require.Equal(t, int64(1<<30), f.MaxSize)
+	require.Equal(t, []string{"out"}, f.Dirs)
+
+	f, err = ParseCache([]string{"gc"})
+	require.NoError(t, err)
+	require.Equal(t, 7*24*time.Hour, f.MaxAge)
+	require.Equal(t, []string{"."}, f.Dirs)
+
+	_, err = ParseCache([]string{"clear"})
+	require.Error(t, err)
+	_, err = ParseCache([]string{"gc", "--days", "-1"})
+	require.Error(t, err)
+}
@@ -0,0 +1,164 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/http"
+)
+
+// These are the modes of the git filter.
+const (
+	GitFilterClean  = "clean"
+	GitFilterSmudge = "smudge"
+)
+
+// gitFilterCommand defines the git-filter subcommand.
+var gitFilterCommand = CommandSpec{
+	Name: "git-filter",
+	Synopsis: []string{
+		`tle git-filter clean (-r round | -D duration) [-n NETWORK] [-c CHAIN] [--auth-token TOKEN] [--doh URL] [--ip-family FAMILY] [--no-pin] [-a] [PATH]`,
+		`tle git-filter smudge [-n NETWORK] [-c CHAIN] [--auth-token TOKEN] [--doh URL] [--ip-family FAMILY] [--no-pin] [PATH]`,
+	},
+	Flags: []FlagSpec{
+		mainFlag("network", func(f *GitFilterFlags) any { return &f.Network }),
+		mainFlag("chain", func(f *GitFilterFlags) any { return &f.Chain }),
+		mainFlag("auth-token", func(f *GitFilterFlags) any { return &f.AuthToken }),
+		mainFlag("doh", func(f *GitFilterFlags) any { return &f.DoH }),
+		mainFlag("ip-family", func(f *GitFilterFlags) any { return &f.IPFamily }),
+		mainFlag("no-pin", func(f *GitFilterFlags) any { return &f.NoPin }),
+		mainFlag("round", func(f *GitFilterFlags) any { return &f.Round }),
+		mainFlag("duration", func(f *GitFilterFlags) any { return &f.Duration }),
+		mainFlag("armor", func(f *GitFilterFlags) any { return &f.Armor }),
+	},
+	notes: `The clean filter encrypts files as they are staged, the smudge filter decrypts
+them on checkout once their round is reached and leaves them encrypted before.
+PATH is the path of the file in the repository, as passed by git with %f.
+
+Setup:
+    $ git config filter.tle.clean "tle git-filter clean -D 30d %f"
+    $ git config filter.tle.smudge "tle git-filter smudge %f"
+    $ echo "secrets/** filter=tle" >> .gitattributes`,
+	defaults: func() any { return &GitFilterFlags{Network: DefaultNetwork, Chain: DefaultChain} },
+}
+
+// GitFilterFlags represent the values from the git-filter command line.
+type GitFilterFlags struct {
+	Mode     string
+	Path     string
+	Network  string
+	Chain    string
+	Round    uint64
+	Duration string
+	Armor    bool
+
+	NetworkFlags
+}
+
+// ParseGitFilter parses the arguments following the git-filter subcommand.
+func ParseGitFilter(args []string) (GitFilterFlags, error) {
+	if len(args) == 0 {
+		return GitFilterFlags{}, errors.New(commandUsage("git-filter"))
+	}
+	f, fs, err := parseCommand[GitFilterFlags]("git-filter", args[1:])
+	if err != nil {
+		return GitFilterFlags{}, err
+	}
+	f.Mode = args[0]
+	f.Path = fs.Arg(0)
+
+	switch f.Mode {
+	case GitFilterClean:
+		if f.Duration != "" && f.Round != 0 {
+			return GitFilterFlags{}, fmt.Errorf("-D/--duration can't be used with -r/--round")
+		}
+		if f.Duration == "" && f.Round == 0 {
+			return GitFilterFlags{}, fmt.Errorf("-D/--duration or -r/--round must be specified")
+		}
+	case GitFilterSmudge:
+		if f.Duration != "" || f.Round != 0 || f.Armor {
+			return GitFilterFlags{}, fmt.Errorf("smudge only accepts -n/--network and -c/--chain")
+		}
+	default:
+		return GitFilterFlags{}, fmt.Errorf("unknown git-filter mode %q\n\n%s", f.Mode, commandUsage("git-filter"))
+	}
+
+	return f, nil
+}
+
+// GitFilter runs the clean or smudge filter, reading the file content from
+// src and writing what git should store or check out to dst.
+func GitFilter(flags GitFilterFlags, dst io.Writer, src io.Reader, network *http.Network) error {
+	content, err := io.ReadAll(src)
+	if err != nil {
+		return fmt.Errorf("read input: %w", err)
+	}
+
+	switch flags.Mode {
+	case GitFilterClean:
+		return gitClean(flags, dst, content, network)
+	case GitFilterSmudge:
+		return gitSmudge(dst, content, network)
+	default:
+		return fmt.Errorf("unknown git-filter mode %q", flags.Mode)
+	}
+}
+
+// gitClean encrypts the content. Since every encryption yields a different
+// ciphertext, the staged ciphertext is kept when it decrypts to the content,
+// or git would see the file as modified on every status.
+func gitClean(flags GitFilterFlags, dst io.Writer, content []byte, network *http.Network) error {
+	// Content still encrypted, because it was checked out before its round.
+	if _, err := tlock.ParseHeader(bytes.NewReader(content)); err == nil {
+		_, err := dst.Write(content)
+		return err
+	}
+
+	if staged := stagedBlob(flags.Path); staged != nil {
+		var plaintext bytes.Buffer
+		if err := tlock.New(network).Decrypt(&plaintext, bytes.NewReader(staged)); err == nil && bytes.Equal(plaintext.Bytes(), content) {
+			_, err := dst.Write(staged)
+			return err
+		}
+	}
+
+	return Encrypt(Flags{Round: flags.Round, Duration: flags.Duration, Armor: flags.Armor}, dst, bytes.NewReader(content), network)
+}
+
+// gitSmudge decrypts the content, leaving it as is if it isn't a ciphertext
+// or if its round isn't reached yet: failing would abort the checkout.
+func gitSmudge(dst io.Writer, content []byte, network *http.Network) error {
+	if _, err := tlock.ParseHeader(bytes.NewReader(content)); err != nil {
+		_, err := dst.Write(content)
+		return err
+	}
+
+	var plaintext bytes.Buffer
+	if err := tlock.New(network).Decrypt(&plaintext, bytes.NewReader(content)); err != nil {
+		if !errors.Is(err, tlock.ErrTooEarly) {
+			return err
+		}
+		_, err := dst.Write(content)
+		return err
+	}
+
+	_, err := dst.Write(plaintext.Bytes())
+	return err
+}
+
+// stagedBlob returns the content of the file as staged in the git index, or
+// nil if there is none.
+func stagedBlob(path string) []byte {
+	if path == "" {
+		return nil
+	}
+	out, err := exec.Command("git", "cat-file", "blob", ":"+path).Output()
+	if err != nil {
+		return nil
+	}
+	return out
+}
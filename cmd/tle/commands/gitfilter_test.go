@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGitFilter(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		shouldError bool
+	}{
+		{name: "clean with round passes", args: []string{"clean", "-r", "1", "secret.txt"}},
+		{name: "clean with duration passes", args: []string{"clean", "--duration", "30d", "secret.txt"}},
+		{name: "clean without round or duration fails", args: []string{"clean", "secret.txt"}, shouldError: true},
+		{name: "clean with both round and duration fails", args: []string{"clean", "-r", "1", "-D", "1d"}, shouldError: true},
+		{name: "smudge passes", args: []string{"smudge", "secret.txt"}},
+		{name: "smudge with round fails", args: []string{"smudge", "-r", "1"}, shouldError: true},
+		{name: "unknown mode fails", args: []string{"process"}, shouldError: true},
+		{name: "missing mode fails", shouldError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseGitFilter(test.args)
+			if test.shouldError {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+
+	f, err := ParseGitFilter([]string{"clean", "-r", "42", "-a", "dir/secret.txt"})
+	require.NoError(t, err)
+	require.Equal(t, GitFilterFlags{Mode: GitFilterClean, Path: "dir/secret.txt", Network: DefaultNetwork, Chain: DefaultChain, Round: 42, Armor: true}, f)
+}
+
+func TestGitSmudgePassesPlaintextThrough(t *testing.T) {
+	var out bytes.Buffer
+	err := GitFilter(GitFilterFlags{Mode: GitFilterSmudge}, &out, bytes.NewReader([]byte("not encrypted")), nil)
+	require.NoError(t, err)
+	require.Equal(t, "not encrypted", out.String())
+}
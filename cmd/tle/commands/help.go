@@ -0,0 +1,486 @@
+package commands
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Version is the version of tle shown in its usage.
+const Version = "v1.3.0"
+
+// FlagSpec defines a flag of tle, from which both the command line parser and
+// the documentation are built.
+type FlagSpec struct {
+	Name        string `json:"name"`
+	Short       string `json:"short,omitempty"`
+	Arg         string `json:"arg,omitempty"`
+	Description string `json:"description"`
+
+	// ops are the operations whose subcommand takes the flag. The flags
+	// selecting the operation are only taken by the legacy command line.
+	ops op
+	// value returns the field of the flags holding the value of the flag,
+	// given the flags of its command.
+	value func(f any) any
+}
+
+// op is a set of the operations of the encrypt and decrypt subcommands.
+type op int
+
+// These are the operations of the subcommands.
+const (
+	opEncrypt op = 1 << iota
+	opDecrypt
+)
+
+// CommandSpec defines a subcommand of tle, from which both its flag set and
+// its usage are built.
+type CommandSpec struct {
+	Name     string     `json:"name"`
+	Synopsis []string   `json:"synopsis"`
+	Flags    []FlagSpec `json:"-"`
+	Usage    string     `json:"usage"`
+
+	// notes follow the synopsis and the options in the usage.
+	notes string
+	// defaults returns the flags of the subcommand holding their defaults.
+	defaults func() any
+}
+
+// mainSynopsis lists the forms of the command line without subcommand.
+var mainSynopsis = []string{
+	`tle [--encrypt] (-r round)... [--armor [--unlock-hint] | --decoy HINT] [--anchor ANCHOR] [--passphrase-file FILE [--kdf-preset PRESET]] [-o OUTPUT] [INPUT]`,
+	`tle [--encrypt] (-r round)... [OPTIONS] --out-dir DIR [--name-template TEMPLATE] INPUT...`,
+	`tle [--encrypt] (-r round)... --convergent FILE [-o OUTPUT] [INPUT]`,
+	`tle [--encrypt] (-r round)... --reproducible FILE [--spool SIZE] [-a] [-o OUTPUT] [INPUT]`,
+	`tle --decrypt [--decoy HINT] [--passphrase-file FILE] [--spool SIZE] [-o OUTPUT] [INPUT]`,
+	`tle [--encrypt] (-r round)... --open-for DURATION [-a] [-o OUTPUT] [INPUT]`,
+	`tle --decrypt --enforce-window [-o OUTPUT] [INPUT]`,
+	`tle --decrypt --beacons FILE [-o OUTPUT] [INPUT]`,
+	`tle --decrypt --ticket TICKET [-o OUTPUT] [INPUT]`,
+	`tle --decrypt (--tee FILE)... [-o OUTPUT] [INPUT]`,
+	`tle --decrypt --expect-sha256 HEX [-o OUTPUT] [INPUT]`,
+	`tle [--encrypt] (-r round)... --live [--chunk-size SIZE] [-o OUTPUT] [INPUT]`,
+	`tle --decrypt --live [--pace] [-o OUTPUT] [INPUT]`,
+	`tle [--encrypt] (-r round)... --puzzle-fallback [-a] [-o OUTPUT] [INPUT]`,
+	`tle --decrypt --puzzle-fallback [--puzzle-solution SOLUTION] [-o OUTPUT] [INPUT]`,
+	`tle --decrypt --sandbox [OPTIONS] [-o OUTPUT] INPUT...`,
+	`tle --decrypt --strict-decode [-o OUTPUT] [INPUT]`,
+	`tle [--encrypt] (-r round)... --entropy FILE [-a] [-o OUTPUT] [INPUT]`,
+	`tle [--encrypt] (-r round)... --shares N [--threshold K] [-a] -o OUTPUT [INPUT]`,
+	`tle (--encrypt (-r round)... | --decrypt) --format FORMAT [-o OUTPUT] [INPUT]`,
+	`tle (--encrypt (-r round)... | --decrypt) --attestation ATTESTATION [-o OUTPUT] [INPUT]`,
+	`tle --decrypt [OPTIONS] --out-dir DIR [--name-template TEMPLATE] INPUT...`,
+	`tle (--encrypt | --decrypt) [OPTIONS] [--jobs N] [--max-memory SIZE] [--nice] --out-dir DIR INPUT...`,
+	`tle (--encrypt (-r round)... [-a] | --decrypt) --daemon PATH [--tenant TENANT] [-o OUTPUT] [INPUT]`,
+	`tle --metadata`,
+	`tle --help-json`,
+	`tle --manpage`,
+}
+
+// mainFlags lists the flags of the command line without subcommand, in the
+// order of the usage.
+var mainFlags = []FlagSpec{
+	{Name: "metadata", Short: "m", Description: "Displays the metadata of drand network in yaml format.", value: field(func(f *Flags) any { return &f.Metadata })},
+	{Name: "encrypt", Short: "e", Description: "Encrypt the input to the output. Default if omitted.", value: field(func(f *Flags) any { return &f.Encrypt })},
+	{Name: "decrypt", Short: "d", Description: "Decrypt the input to the output.", value: field(func(f *Flags) any { return &f.Decrypt })},
+	{Name: "network", Short: "n", Arg: "NETWORK", Description: "The drand API endpoint to use.", ops: opEncrypt | opDecrypt, value: field(func(f *Flags) any { return &f.Network })},
+	{Name: "chain", Short: "c", Arg: "CHAIN", Description: "The chain to use. Can use either beacon ID name or beacon hash. Use beacon hash in order to ensure public key integrity.", ops: opEncrypt | opDecrypt, value: field(func(f *Flags) any { return &f.Chain })},
+	{Name: "auth-token", Arg: "TOKEN", Description: "The bearer token authenticating the requests to the drand API endpoint.", ops: opEncrypt | opDecrypt, value: field(func(f *Flags) any { return &f.AuthToken })},
+	{Name: "doh", Arg: "URL", Description: "Resolve the drand API endpoint with the DNS over HTTPS server at URL.", ops: opEncrypt | opDecrypt, value: field(func(f *Flags) any { return &f.DoH })},
+	{Name: "ip-family", Arg: "FAMILY", Description: "Connect to the drand API endpoint over IPv4 or IPv6 only, given 4 or 6.", ops: opEncrypt | opDecrypt, value: field(func(f *Flags) any { return &f.IPFamily })},
+	{Name: "no-pin", Description: "Don't check the public key served for mainnet and quicknet against the built in one.", ops: opEncrypt | opDecrypt, value: field(func(f *Flags) any { return &f.NoPin })},
+	{Name: "period", Arg: "PERIOD", Description: "The PERIOD of the chain, given with --genesis to work without calling NETWORK until beacons are needed.", ops: opEncrypt | opDecrypt, value: field(func(f *Flags) any { return &f.Period })},
+	{Name: "genesis", Arg: "GENESIS", Description: "The GENESIS time of the chain as a Unix epoch, given with --period.", ops: opEncrypt | opDecrypt, value: field(func(f *Flags) any { return &f.Genesis })},
+	{Name: "tz", Arg: "ZONE", Description: "Display times in ZONE: Local, UTC, an IANA name such as Europe/Paris or an offset such as +02:00.", ops: opEncrypt | opDecrypt, value: field(func(f *Flags) any { return &f.TZ })},
+	{Name: "round", Short: "r", Arg: "ROUND", Description: "The specific round to use to encrypt the message. Cannot be used with --duration.", ops: opEncrypt, value: field(func(f *Flags) any { return &f.Round })},
+	{Name: "force", Short: "f", Description: "Forces to encrypt against past rounds.", ops: opEncrypt, value: field(func(f *Flags) any { return &f.Force })},
+	{Name: "duration", Short: "D", Arg: "DURATION", Description: "How long to wait before the message can be decrypted.", ops: opEncrypt, value: field(func(f *Flags) any { return &f.Duration })},
+	{Name: "output", Short: "o", Arg: "OUTPUT", Description: "Write the result to the file at path OUTPUT.", ops: opEncrypt | opDecrypt, value: field(func(f *Flags) any { return &f.Output })},
+	{Name: "armor", Short: "a", Description: "Encrypt to a PEM encoded format.", ops: opEncrypt, value: field(func(f *Flags) any { return &f.Armor })},
+	{Name: "unlock-hint", Description: "Precede the armor with a comment line telling when and on which chain the ciphertext unlocks.", ops: opEncrypt, value: field(func(f *Flags) any { return &f.Hint })},
+	{Name: "format", Arg: "FORMAT", Description: "Encrypt to, or decrypt from, FORMAT: binary, armor, age, jwe, pem, cms or envelope.", ops: opEncrypt | opDecrypt, value: field(func(f *Flags) any { return &f.Format })},
+	{Name: "force-tty", Description: "Write binary output to the terminal.", ops: opEncrypt | opDecrypt, value: field(func(f *Flags) any { return &f.ForceTTY })},
+	{Name: "decoy", Arg: "HINT", Description: "Whiten the ciphertext with HINT so it is indistinguishable from random bytes.", ops: opEncrypt | opDecrypt, value: field(func(f *Flags) any { return &f.Decoy })},
+	{Name: "anchor", Arg: "ANCHOR", Description: "Write the blockchain anchoring record of the ciphertext to the file at path ANCHOR.", ops: opEncrypt, value: field(func(f *Flags) any { return &f.Anchor })},
+	{Name: "out-dir", Arg: "DIR", Description: "Write the result of each INPUT to the directory DIR.", ops: opEncrypt | opDecrypt, value: field(func(f *Flags) any { return &f.OutDir })},
+	{Name: "name-template", Arg: "TEMPLATE", Description: "The name of the files written to DIR.", ops: opEncrypt | opDecrypt, value: field(func(f *Flags) any { return &f.NameTemplate })},
+	{Name: "jobs", Arg: "N", Description: "Process up to N INPUT files, or the chunks of a decrypted INPUT, concurrently.", ops: opEncrypt | opDecrypt, value: field(func(f *Flags) any { return &f.Jobs })},
+	{Name: "max-memory", Arg: "SIZE", Description: "Keep the memory of the process within SIZE, lowering --jobs if needed.", ops: opEncrypt | opDecrypt, value: field(func(f *Flags) any { return &f.MaxMemory })},
+	{Name: "nice", Description: "Lower the CPU and I/O priority of the process.", ops: opEncrypt | opDecrypt, value: field(func(f *Flags) any { return &f.Nice })},
+	{Name: "daemon", Arg: "PATH", Description: "Encrypt or decrypt through the daemon listening on the unix socket at PATH.", ops: opEncrypt | opDecrypt, value: field(func(f *Flags) any { return &f.Daemon })},
+	{Name: "tenant", Arg: "TENANT", Description: "The tenant whose policy the daemon checks the encryption against.", ops: opEncrypt, value: field(func(f *Flags) any { return &f.Tenant })},
+	{Name: "passphrase-file", Arg: "FILE", Description: "Additionally require the passphrase read from the first line of FILE to decrypt.", ops: opEncrypt | opDecrypt, value: field(func(f *Flags) any { return &f.PassphraseFile })},
+	{Name: "kdf-preset", Arg: "PRESET", Description: "The argon2id parameters protecting the passphrase: interactive, moderate or paranoid.", ops: opEncrypt, value: field(func(f *Flags) any { return &f.KDFPreset })},
+	{Name: "convergent", Arg: "FILE", Description: "Encrypt deterministically with the secret read from FILE, so backups deduplicate.", ops: opEncrypt, value: field(func(f *Flags) any { return &f.Convergent })},
+	{Name: "reproducible", Arg: "FILE", Description: "Encrypt the same INPUT to the same bytes each time, using the seed read from FILE.", ops: opEncrypt, value: field(func(f *Flags) any { return &f.Reproducible })},
+	{Name: "spool", Arg: "SIZE", Description: "Buffer an INPUT which can't seek in an encrypted temporary file of up to SIZE, when needed.", ops: opEncrypt | opDecrypt, value: field(func(f *Flags) any { return &f.Spool })},
+	{Name: "attestation", Arg: "ATTESTATION", Description: "Write the in-toto attestation of the encrypted INPUT to ATTESTATION, or check the decrypted INPUT against it.", ops: opEncrypt | opDecrypt, value: field(func(f *Flags) any { return &f.Attestation })},
+	{Name: "open-for", Arg: "DURATION", Description: "Record that INPUT is meant to be decrypted within DURATION of the round it unlocks at.", ops: opEncrypt, value: field(func(f *Flags) any { return &f.OpenFor })},
+	{Name: "enforce-window", Description: "Refuse to decrypt INPUT past the window recorded by --open-for.", ops: opDecrypt, value: field(func(f *Flags) any { return &f.EnforceWindow })},
+	{Name: "beacons", Arg: "FILE", Description: "Decrypt INPUT with the beacons of the bundle FILE written by tle fetch-beacon, without calling the network.", ops: opDecrypt, value: field(func(f *Flags) any { return &f.Beacons })},
+	{Name: "ticket", Arg: "TICKET", Description: "Decrypt INPUT with the ticket, or the file holding it, written by tle ticket, without calling the network.", ops: opDecrypt, value: field(func(f *Flags) any { return &f.Ticket })},
+	{Name: "entropy", Arg: "FILE", Description: "Draw the randomness of the encryption from FILE, such as the device of a certified generator, once checked.", ops: opEncrypt, value: field(func(f *Flags) any { return &f.Entropy })},
+	{Name: "shares", Arg: "N", Description: "Split the ciphertext into N shares written to OUTPUT.1 to OUTPUT.N, reassembled by tle combine.", ops: opEncrypt, value: field(func(f *Flags) any { return &f.Shares })},
+	{Name: "threshold", Arg: "K", Description: "The number of the shares reassembling the ciphertext, N by default.", ops: opEncrypt, value: field(func(f *Flags) any { return &f.Threshold })},
+	{Name: "tee", Arg: "FILE", Description: "Also write the decrypted INPUT to FILE, or to the standard output for -. May be repeated.", ops: opDecrypt, value: field(func(f *Flags) any { return &f.Tee })},
+	{Name: "expect-sha256", Arg: "HEX", Description: "Fail, removing OUTPUT, unless the decrypted INPUT has the SHA-256 digest HEX.", ops: opDecrypt, value: field(func(f *Flags) any { return &f.ExpectSHA256 })},
+	{Name: "live", Description: "Encrypt INPUT in small chunks written as they are read, as for live streams, or decrypt such a stream.", ops: opEncrypt | opDecrypt, value: field(func(f *Flags) any { return &f.Live })},
+	{Name: "chunk-size", Arg: "SIZE", Description: "The size of the chunks of --live, 4 KiB by default.", ops: opEncrypt, value: field(func(f *Flags) any { return &f.ChunkSize })},
+	{Name: "pace", Description: "Write the chunks of --live at the times they were recorded at.", ops: opDecrypt, value: field(func(f *Flags) any { return &f.Pace })},
+	{Name: "puzzle-fallback", Description: "Also lock INPUT in a time-lock puzzle solved by computation should the network disappear, or decrypt by solving it.", ops: opEncrypt | opDecrypt, value: field(func(f *Flags) any { return &f.PuzzleFallback })},
+	{Name: "puzzle-solution", Arg: "SOLUTION", Description: "Decrypt with the verified solution of the puzzle of --puzzle-fallback written by tle puzzle solve.", ops: opDecrypt, value: field(func(f *Flags) any { return &f.PuzzleSolution })},
+	{Name: "sandbox", Description: "Confine the decryption, on Linux, to reading INPUT and writing OUTPUT without network access, once the beacons are fetched.", ops: opDecrypt, value: field(func(f *Flags) any { return &f.Sandbox })},
+	{Name: "strict-decode", Description: "Reject INPUT with trailing data, non-canonical encodings or duplicate stanzas.", ops: opDecrypt, value: field(func(f *Flags) any { return &f.StrictDecode })},
+}
+
+// subcommands lists the subcommands in the order of the usage.
+var subcommands = withUsage([]CommandSpec{
+	operationCommand(opEncrypt),
+	operationCommand(opDecrypt),
+	inspectCommand,
+	roundCommand,
+	gitFilterCommand,
+	filterCommand,
+	openEmailCommand,
+	verifyProofCommand,
+	signCommand,
+	verifyCommand,
+	benchCommand,
+	daemonCommand,
+	checkCommand,
+	scheduleCommand,
+	ciCommand,
+	indexCommand,
+	tuiCommand,
+	fetchBeaconCommand,
+	ticketCommand,
+	puzzleCommand,
+	combineCommand,
+	cacheCommand,
+	rewrapCommand,
+	pushCommand,
+	pullCommand,
+})
+
+// usage is the usage of tle, built from the tables above.
+var usage = buildUsage()
+
+// WriteHelpJSON writes the usage of tle as JSON to w, for tools generating
+// completions or documentation.
+func WriteHelpJSON(w io.Writer) error {
+	type flagJSON struct {
+		FlagSpec
+		Type    string `json:"type"`
+		Default string `json:"default,omitempty"`
+		Env     string `json:"env,omitempty"`
+	}
+	type commandJSON struct {
+		CommandSpec
+		Flags []flagJSON `json:"flags,omitempty"`
+	}
+	help := struct {
+		Name        string        `json:"name"`
+		Version     string        `json:"version"`
+		Synopsis    []string      `json:"synopsis"`
+		Flags       []flagJSON    `json:"flags"`
+		Subcommands []commandJSON `json:"subcommands"`
+		Notes       string        `json:"notes"`
+	}{
+		Name:     "tle",
+		Version:  Version,
+		Synopsis: mainSynopsis,
+		Notes:    usageNotes,
+	}
+	defaults := defaultFlags()
+	for _, spec := range mainFlags {
+		typ, def := spec.typeAndDefault(&defaults)
+		help.Flags = append(help.Flags, flagJSON{FlagSpec: spec, Type: typ, Default: def, Env: spec.env()})
+	}
+	// The flags of the encrypt and decrypt subcommands are the only ones also
+	// read from the environment.
+	for _, cmd := range subcommands {
+		c := commandJSON{CommandSpec: cmd}
+		defaults := cmd.defaults()
+		_, env := defaults.(*Flags)
+		for _, spec := range cmd.Flags {
+			typ, def := spec.typeAndDefault(defaults)
+			f := flagJSON{FlagSpec: spec, Type: typ, Default: def}
+			if env {
+				f.Env = spec.env()
+			}
+			c.Flags = append(c.Flags, f)
+		}
+		help.Subcommands = append(help.Subcommands, c)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(help)
+}
+
+// WriteManpage writes the manual page of tle to w, in the roff format of
+// man(7).
+func WriteManpage(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, ".TH TLE 1 \"\" \"tle %s\" \"User Commands\"\n", Version)
+	b.WriteString(".SH NAME\ntle \\- timelock encryption and decryption using drand\n")
+
+	b.WriteString(".SH SYNOPSIS\n")
+	synopsis := mainSynopsis
+	for _, cmd := range subcommands {
+		synopsis = append(synopsis[:len(synopsis):len(synopsis)], cmd.Synopsis...)
+	}
+	for _, line := range synopsis {
+		fmt.Fprintf(&b, ".B %s\n.br\n", roffEscape(line))
+	}
+
+	b.WriteString(".SH OPTIONS\n")
+	for _, spec := range mainFlags {
+		b.WriteString(".TP\n")
+		if spec.Short != "" {
+			fmt.Fprintf(&b, "\\fB\\-%s\\fR, ", spec.Short)
+		}
+		fmt.Fprintf(&b, "\\fB\\-\\-%s\\fR", roffEscape(spec.Name))
+		if spec.Arg != "" {
+			fmt.Fprintf(&b, " \\fI%s\\fR", spec.Arg)
+		}
+		fmt.Fprintf(&b, "\n%s\n", roffEscape(spec.Description))
+	}
+
+	b.WriteString(".SH DESCRIPTION\n")
+	for _, paragraph := range strings.Split(usageNotes, "\n\n") {
+		if strings.Contains(paragraph, "\n    ") || strings.HasPrefix(paragraph, "    ") {
+			fmt.Fprintf(&b, ".PP\n.nf\n%s\n.fi\n", roffEscape(paragraph))
+			continue
+		}
+		fmt.Fprintf(&b, ".PP\n%s\n", roffEscape(paragraph))
+	}
+
+	b.WriteString(".SH COMMANDS\n")
+	for _, cmd := range subcommands {
+		fmt.Fprintf(&b, ".SS %s\n.nf\n%s\n.fi\n", cmd.Name, roffEscape(cmd.Usage))
+	}
+
+	b.WriteString(".SH ENVIRONMENT\n")
+	for _, spec := range mainFlags {
+		fmt.Fprintf(&b, ".TP\n.B %s\nAs \\fB\\-\\-%s\\fR.\n", spec.env(), roffEscape(spec.Name))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// =============================================================================
+
+// buildUsage returns the usage of tle, listing the synopsis of the
+// subcommands and of the legacy command line, then the flags, then the notes.
+func buildUsage() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "tlock %s -- github.com/JonathanLogan/tlock\n\nUsage:\n", Version)
+	for _, cmd := range subcommands {
+		for _, line := range cmd.Synopsis {
+			fmt.Fprintf(&b, "\t%s\n", line)
+		}
+	}
+	for _, line := range mainSynopsis {
+		fmt.Fprintf(&b, "\t%s\n", line)
+	}
+
+	fmt.Fprintf(&b, "\nOptions:\n%s\n", formatOptions(mainFlags, 0))
+	b.WriteString(usageNotes)
+	return b.String()
+}
+
+// operationCommand returns the encrypt or decrypt subcommand, taking the
+// flags of the legacy command line for its operation.
+func operationCommand(o op) CommandSpec {
+	cmd := CommandSpec{
+		Name:     "encrypt",
+		Synopsis: []string{`tle encrypt (-r ROUND | -D DURATION) [OPTIONS] [INPUT...]`},
+		defaults: func() any {
+			f := defaultFlags()
+			return &f
+		},
+	}
+	if o == opDecrypt {
+		cmd.Name, cmd.Synopsis = "decrypt", []string{`tle decrypt [OPTIONS] [INPUT...]`}
+	}
+	for _, spec := range mainFlags {
+		if spec.ops&o != 0 {
+			cmd.Flags = append(cmd.Flags, spec)
+		}
+	}
+	cmd.notes = fmt.Sprintf("The options and INPUT are the ones of the legacy command line, run tle\n--help for their details, except that the operation is the subcommand: use\ntle %s rather than tle --%s.", cmd.Name, cmd.Name)
+	return cmd
+}
+
+// withUsage sets the usage of the subcommands, listing their synopsis, then
+// their flags, then their notes.
+func withUsage(cmds []CommandSpec) []CommandSpec {
+	for i, cmd := range cmds {
+		var b strings.Builder
+		b.WriteString("Usage:\n")
+		for _, line := range cmd.Synopsis {
+			fmt.Fprintf(&b, "\t%s\n", line)
+		}
+		if len(cmd.Flags) > 0 {
+			fmt.Fprintf(&b, "\nOptions:\n%s", formatOptions(cmd.Flags, 0))
+		}
+		fmt.Fprintf(&b, "\n%s", cmd.notes)
+		cmds[i].Usage = b.String()
+	}
+	return cmds
+}
+
+// commandUsage returns the usage of the named subcommand.
+func commandUsage(name string) string {
+	for _, cmd := range subcommands {
+		if cmd.Name == name {
+			return cmd.Usage
+		}
+	}
+	panic("unknown subcommand " + name)
+}
+
+// parseCommand parses the arguments of the named subcommand into its flags,
+// starting from their defaults, and returns the flag set holding the
+// remaining arguments.
+func parseCommand[T any](name string, args []string) (T, *flag.FlagSet, error) {
+	i := slices.IndexFunc(subcommands, func(cmd CommandSpec) bool { return cmd.Name == name })
+	cmd := subcommands[i]
+	f := *cmd.defaults().(*T)
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() { _, _ = io.WriteString(fs.Output(), cmd.Usage+"\n") }
+	for _, spec := range cmd.Flags {
+		spec.define(fs, &f)
+	}
+	if err := fs.Parse(args); err != nil {
+		var zero T
+		return zero, nil, err
+	}
+	return f, fs, nil
+}
+
+// formatOptions lists the flags taken by the operations, all of them when
+// none is given, aligning their descriptions.
+func formatOptions(specs []FlagSpec, o op) string {
+	var options strings.Builder
+	tw := tabwriter.NewWriter(&options, 0, 0, 1, ' ', 0)
+	for _, spec := range specs {
+		if o != 0 && spec.ops&o == 0 {
+			continue
+		}
+		short := "   "
+		if spec.Short != "" {
+			short = "-" + spec.Short + ","
+		}
+		fmt.Fprintf(tw, "%s --%s\t%s\n", short, spec.Name, spec.Description)
+	}
+	tw.Flush()
+
+	lines := strings.SplitAfter(options.String(), "\n")
+	return "\t" + strings.Join(lines[:len(lines)-1], "\t")
+}
+
+// define defines the flag, and its short form, in the flag set, storing its
+// value in the flags of its command.
+func (spec FlagSpec) define(fs *flag.FlagSet, f any) {
+	names := []string{spec.Name}
+	if spec.Short != "" {
+		names = append([]string{spec.Short}, names...)
+	}
+	// Repeated flags accumulate their values, which replace those of the
+	// environment.
+	replaced := false
+	for _, name := range names {
+		switch v := spec.value(f).(type) {
+		case *bool:
+			fs.BoolVar(v, name, *v, spec.Description)
+		case *string:
+			fs.StringVar(v, name, *v, spec.Description)
+		case *int:
+			fs.IntVar(v, name, *v, spec.Description)
+		case *int64:
+			fs.Int64Var(v, name, *v, spec.Description)
+		case *uint64:
+			fs.Uint64Var(v, name, *v, spec.Description)
+		case *time.Duration:
+			fs.DurationVar(v, name, *v, spec.Description)
+		case *[]string:
+			fs.Func(name, spec.Description, func(s string) error {
+				if !replaced {
+					*v, replaced = nil, true
+				}
+				*v = append(*v, s)
+				return nil
+			})
+		default:
+			panic(fmt.Sprintf("flag --%s has unsupported type %T", spec.Name, v))
+		}
+	}
+}
+
+// typeAndDefault returns the type of the value of the flag and its default
+// in the flags of its command holding the defaults, empty when it is the zero
+// value.
+func (spec FlagSpec) typeAndDefault(defaults any) (string, string) {
+	v := reflect.ValueOf(spec.value(defaults)).Elem()
+	typ := v.Kind().String()
+	if _, ok := v.Interface().(time.Duration); ok {
+		typ = "duration"
+	}
+	if v.IsZero() {
+		return typ, ""
+	}
+	return typ, fmt.Sprint(v.Interface())
+}
+
+// field returns the accessor of a flag of a command whose flags are a T.
+func field[T any](value func(f *T) any) func(f any) any {
+	return func(f any) any { return value(f.(*T)) }
+}
+
+// mainFlag returns the flag of the legacy command line of the given name, for
+// a subcommand storing its value with the accessor.
+func mainFlag[T any](name string, value func(f *T) any) FlagSpec {
+	i := slices.IndexFunc(mainFlags, func(spec FlagSpec) bool { return spec.Name == name })
+	spec := mainFlags[i]
+	spec.ops, spec.value = 0, field(value)
+	return spec
+}
+
+// env returns the environment variable envconfig reads the flag from.
+func (spec FlagSpec) env() string {
+	var f Flags
+	ptr := reflect.ValueOf(spec.value(&f)).Pointer()
+	v := reflect.ValueOf(&f).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if v.Field(i).Addr().Pointer() == ptr {
+			return "TLE_" + strings.ToUpper(v.Type().Field(i).Name)
+		}
+	}
+	return ""
+}
+
+// roffEscape escapes the text for roff, in which backslashes start escapes
+// and lines starting with a dot or a quote are requests.
+func roffEscape(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\e")
+	s = strings.ReplaceAll(s, "-", "\\-")
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = "\\&" + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
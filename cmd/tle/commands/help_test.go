@@ -0,0 +1,122 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlagTable(t *testing.T) {
+	// Every flag parsed from the command line is documented. The passphrase
+	// is only read from the environment.
+	documented := make(map[string]bool)
+	for _, spec := range mainFlags {
+		env := spec.env()
+		require.NotEmpty(t, env, spec.Name)
+		require.False(t, documented[env], "%s is defined twice", env)
+		documented[env] = true
+	}
+	typ := reflect.TypeOf(Flags{})
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Tag.Get("ignored") == "true" || field.Name == "Passphrase" {
+			continue
+		}
+		require.True(t, documented["TLE_"+strings.ToUpper(field.Name)], "%s has no flag", field.Name)
+	}
+
+	// The synopsis of the subcommands lists their flags, unless it refers to
+	// the options.
+	for _, cmd := range subcommands {
+		require.True(t, strings.HasPrefix(cmd.Usage, "Usage:\n"), cmd.Name)
+		synopsis := strings.Join(cmd.Synopsis, "\n")
+		for _, line := range cmd.Synopsis {
+			require.True(t, strings.HasPrefix(line, "tle "+cmd.Name), line)
+		}
+		for _, spec := range cmd.Flags {
+			require.Contains(t, cmd.Usage, "--"+spec.Name+" ", cmd.Name)
+			if strings.Contains(synopsis, "[OPTIONS]") {
+				continue
+			}
+			listed := regexp.MustCompile(`(^|[\s\[(|])--` + spec.Name + `\b`).MatchString(synopsis)
+			if spec.Short != "" {
+				listed = listed || regexp.MustCompile(`(^|[\s\[(|])-`+spec.Short+`\b`).MatchString(synopsis)
+			}
+			require.True(t, listed, "the synopsis of %s lacks --%s", cmd.Name, spec.Name)
+		}
+	}
+	require.Contains(t, usage, "\t-m, --metadata ")
+	require.Contains(t, usage, "\t    --passphrase-file Additionally")
+}
+
+func TestWriteHelpJSON(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, WriteHelpJSON(&out))
+
+	var help struct {
+		Version string
+		Flags   []struct {
+			Name, Short, Arg, Type, Default, Env string
+		}
+		Subcommands []struct {
+			Name  string
+			Flags []struct {
+				Name, Short, Type, Default, Env string
+			}
+		}
+	}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &help))
+	require.Equal(t, Version, help.Version)
+	require.Len(t, help.Flags, len(mainFlags))
+	require.Len(t, help.Subcommands, len(subcommands))
+
+	flags := make(map[string]int)
+	for i, f := range help.Flags {
+		flags[f.Name] = i
+	}
+	network := help.Flags[flags["network"]]
+	require.Equal(t, "n", network.Short)
+	require.Equal(t, "NETWORK", network.Arg)
+	require.Equal(t, DefaultNetwork, network.Default)
+	require.Equal(t, "TLE_NETWORK", network.Env)
+	require.Equal(t, "TLE_AUTHTOKEN", help.Flags[flags["auth-token"]].Env)
+	require.Equal(t, "bool", help.Flags[flags["armor"]].Type)
+	require.Equal(t, "uint64", help.Flags[flags["round"]].Type)
+
+	// The flags of the subcommands are described too, only those of encrypt
+	// and decrypt being read from the environment.
+	for _, cmd := range help.Subcommands {
+		switch cmd.Name {
+		case "encrypt":
+			require.Equal(t, "TLE_NETWORK", cmd.Flags[0].Env)
+		case "tui":
+			require.Equal(t, "network", cmd.Flags[0].Name)
+			require.Equal(t, "n", cmd.Flags[0].Short)
+			require.Equal(t, DefaultNetwork, cmd.Flags[0].Default)
+			require.Empty(t, cmd.Flags[0].Env)
+			require.Equal(t, "interval", cmd.Flags[3].Name)
+			require.Equal(t, "duration", cmd.Flags[3].Type)
+			require.Equal(t, "1s", cmd.Flags[3].Default)
+		}
+	}
+}
+
+func TestWriteManpage(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, WriteManpage(&out))
+	page := out.String()
+	require.True(t, strings.HasPrefix(page, ".TH TLE 1 "))
+	require.Contains(t, page, `\fB\-m\fR, \fB\-\-metadata\fR`)
+	require.Contains(t, page, `\fB\-\-kdf\-preset\fR \fIPRESET\fR`)
+	require.Contains(t, page, ".SS rewrap\n")
+	require.Contains(t, page, "\\-n, \\-\\-network")
+	require.Contains(t, page, ".B TLE_PASSPHRASEFILE\n")
+	for _, line := range strings.Split(page, "\n") {
+		require.False(t, strings.HasPrefix(line, "'"), line)
+	}
+}
@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/lockedfs"
+)
+
+// indexCommand defines the index subcommand.
+var indexCommand = CommandSpec{
+	Name: "index",
+	Synopsis: []string{
+		`tle index [-n NETWORK] [-c CHAIN] [--auth-token TOKEN] [--doh URL] [--ip-family FAMILY] [--no-pin] [-o OUTPUT] DIR`,
+	},
+	Flags: []FlagSpec{
+		mainFlag("network", func(f *IndexFlags) any { return &f.Network }),
+		mainFlag("chain", func(f *IndexFlags) any { return &f.Chain }),
+		mainFlag("auth-token", func(f *IndexFlags) any { return &f.AuthToken }),
+		mainFlag("doh", func(f *IndexFlags) any { return &f.DoH }),
+		mainFlag("ip-family", func(f *IndexFlags) any { return &f.IPFamily }),
+		mainFlag("no-pin", func(f *IndexFlags) any { return &f.NoPin }),
+		{Name: "output", Short: "o", Arg: "OUTPUT", Description: "Write the index to the file at path OUTPUT.", value: field(func(f *IndexFlags) any { return &f.Output })},
+	},
+	notes: `Catalogs the .tle ciphertexts under DIR without decrypting them, writing to
+OUTPUT, or the standard output, a JSON index giving for each of them its path
+relative to DIR, its round, the time it unlocks, its size and its chain.
+Dashboards and filesystems built on the lockedfs package read the index
+rather than opening each ciphertext; its entries whose ciphertext changed
+since are ignored.`,
+	defaults: func() any { return &IndexFlags{Network: DefaultNetwork, Chain: DefaultChain} },
+}
+
+// IndexFlags represent the values from the index command line.
+type IndexFlags struct {
+	Network string
+	Chain   string
+	Output  string
+	Dir     string
+
+	NetworkFlags
+}
+
+// ParseIndex parses the arguments following the index subcommand.
+func ParseIndex(args []string) (IndexFlags, error) {
+	f, fs, err := parseCommand[IndexFlags]("index", args)
+	if err != nil {
+		return IndexFlags{}, err
+	}
+	if fs.NArg() != 1 {
+		return IndexFlags{}, errors.New(commandUsage("index"))
+	}
+	f.Dir = fs.Arg(0)
+
+	return f, nil
+}
+
+// Index writes the index of the ciphertexts under the directory of the flags
+// to their output, or to w without one.
+func Index(w io.Writer, flags IndexFlags, network tlock.Network) error {
+	idx, err := lockedfs.New(flags.Dir, network).Index()
+	if err != nil {
+		return fmt.Errorf("index %s: %w", flags.Dir, err)
+	}
+	b, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal index: %w", err)
+	}
+	b = append(b, '\n')
+
+	if flags.Output != "" {
+		return os.WriteFile(flags.Output, b, 0600)
+	}
+	_, err = w.Write(b)
+	return err
+}
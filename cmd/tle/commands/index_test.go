@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/lockedfs"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndex(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewKey(nil).Network(t, nil)
+
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0o700))
+	for _, name := range []string{"a.txt.tle", "sub/b.tle"} {
+		var ciphertext bytes.Buffer
+		require.NoError(t, tlock.New(network).Encrypt(&ciphertext, bytes.NewReader([]byte("content")), 1000))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), ciphertext.Bytes(), 0o600))
+	}
+
+	flags, err := ParseIndex([]string{"-o", filepath.Join(dir, "index.json"), dir})
+	require.NoError(t, err)
+	require.NoError(t, Index(nil, flags, network))
+
+	b, err := os.ReadFile(filepath.Join(dir, "index.json"))
+	require.NoError(t, err)
+	var idx lockedfs.Index
+	require.NoError(t, json.Unmarshal(b, &idx))
+	require.Len(t, idx.Files, 2)
+	require.Equal(t, "a.txt.tle", idx.Files[0].File)
+	require.Equal(t, "sub/b.tle", idx.Files[1].File)
+	require.Equal(t, uint64(1000), idx.Files[1].Round)
+	require.Equal(t, DefaultChain, idx.Files[1].ChainHash)
+	require.NotNil(t, idx.Files[1].Unlocks)
+
+	_, err = ParseIndex(nil)
+	require.Error(t, err)
+}
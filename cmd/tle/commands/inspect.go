@@ -0,0 +1,219 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+)
+
+// inspectCommand defines the inspect subcommand.
+var inspectCommand = CommandSpec{
+	Name: "inspect",
+	Synopsis: []string{
+		`tle inspect [-n NETWORK] [-c CHAIN] [--decoy HINT] [--tz ZONE] INPUT`,
+	},
+	Flags: []FlagSpec{
+		mainFlag("network", func(f *InspectFlags) any { return &f.Network }),
+		mainFlag("chain", func(f *InspectFlags) any { return &f.Chain }),
+		{Name: "decoy", Arg: "HINT", Description: "Read INPUT as whitened with HINT by --decoy.", value: field(func(f *InspectFlags) any { return &f.Decoy })},
+		mainFlag("tz", func(f *InspectFlags) any { return &f.TZ }),
+	},
+	notes: `Prints what the header of the ciphertext INPUT tells without decrypting it:
+its chain, its round and when it unlocks, whether it is unlocked, its
+metadata unless sealed, and the identifier of the file, which correlates its
+copies across encodings. The ciphertexts depending on several rounds, locked
+to more than one or closing their decryption window at a not-after round,
+have each of them listed.`,
+	defaults: func() any { return &InspectFlags{Network: DefaultNetwork, Chain: DefaultChain} },
+}
+
+// roundCommand defines the round subcommand.
+var roundCommand = CommandSpec{
+	Name: "round",
+	Synopsis: []string{
+		`tle round (-D DURATION | --at TIME) [-n NETWORK] [-c CHAIN] [--tz ZONE]`,
+	},
+	Flags: []FlagSpec{
+		mainFlag("network", func(f *RoundFlags) any { return &f.Network }),
+		mainFlag("chain", func(f *RoundFlags) any { return &f.Chain }),
+		{Name: "duration", Short: "D", Arg: "DURATION", Description: "How long from now the round is due.", value: field(func(f *RoundFlags) any { return &f.Duration })},
+		{Name: "at", Arg: "TIME", Description: "The time the round is due at.", value: field(func(f *RoundFlags) any { return &f.At })},
+		mainFlag("tz", func(f *RoundFlags) any { return &f.TZ }),
+	},
+	notes: `Prints the round of the chain due DURATION from now, or at TIME given in the
+RFC 3339 format such as 2026-10-16T09:00:00Z, for scripts passing it to
+tle encrypt -r. When it is due is printed to the standard error.`,
+	defaults: func() any { return &RoundFlags{Network: DefaultNetwork, Chain: DefaultChain} },
+}
+
+// InspectFlags represent the values from the inspect command line.
+type InspectFlags struct {
+	Network string
+	Chain   string
+	Decoy   string
+	TZ      string
+	Input   string
+}
+
+// ParseInspect parses the arguments following the inspect subcommand.
+func ParseInspect(args []string) (InspectFlags, error) {
+	f, fs, err := parseCommand[InspectFlags]("inspect", args)
+	if err != nil {
+		return InspectFlags{}, err
+	}
+	if fs.NArg() != 1 {
+		return InspectFlags{}, errors.New(commandUsage("inspect"))
+	}
+	if _, err := LoadLocation(f.TZ); err != nil {
+		return InspectFlags{}, fmt.Errorf("--tz: %w", err)
+	}
+	f.Input = fs.Arg(0)
+
+	return f, nil
+}
+
+// Inspect writes what the header of the input of the flags tells to w.
+func Inspect(w io.Writer, flags InspectFlags, network tlock.Network) error {
+	f, err := os.Open(flags.Input)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// The input is read twice, for its header then for its identifier.
+	read := func(parse func(io.Reader) error) error {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		var src io.Reader = f
+		if flags.Decoy != "" {
+			if src, err = tlock.NewDecoyReader(src, flags.Decoy); err != nil {
+				return err
+			}
+		}
+		return parse(src)
+	}
+	var hdr *tlock.Header
+	if err := read(func(src io.Reader) (err error) { hdr, err = tlock.ParseHeader(src); return err }); err != nil {
+		return err
+	}
+	var id []byte
+	if err := read(func(src io.Reader) (err error) { id, err = tlock.FileID(src); return err }); err != nil {
+		return err
+	}
+	roundNumber, chainHash, err := hdr.Round()
+	if err != nil {
+		return err
+	}
+	rounds, err := hdr.Rounds(network)
+	if err != nil {
+		return err
+	}
+	m, sealed, err := hdr.Metadata()
+	if err != nil {
+		return err
+	}
+	loc, err := LoadLocation(flags.TZ)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "chain:    %s\n", chainHash)
+	fmt.Fprintf(w, "round:    %d\n", roundNumber)
+	fmt.Fprintf(w, "file id:  %x\n", id)
+	if unlock, ok := tlock.RoundTime(network, roundNumber); ok && chainHash == network.ChainHash() {
+		fmt.Fprintf(w, "unlocks:  %s\n", FormatTime(unlock, loc))
+		fmt.Fprintf(w, "unlocked: %t\n", tlock.IsReadyToDecrypt(network, roundNumber))
+	}
+	if len(rounds) > 1 {
+		fmt.Fprintf(w, "rounds:\n")
+		for _, r := range rounds {
+			event := "unlocks"
+			if r.NotAfter {
+				event = "closes"
+			}
+			fmt.Fprintf(w, "  %d %s", r.Round, event)
+			if !r.Earliest.IsZero() {
+				fmt.Fprintf(w, " at %s", FormatTime(r.Earliest, loc))
+			}
+			fmt.Fprintf(w, "\n")
+		}
+	}
+	switch {
+	case sealed:
+		fmt.Fprintf(w, "metadata: sealed\n")
+	case len(m) > 0:
+		keys := make([]string, 0, len(m))
+		for key := range m {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		fmt.Fprintf(w, "metadata:\n")
+		for _, key := range keys {
+			fmt.Fprintf(w, "  %s: %s\n", key, m[key])
+		}
+	}
+	return nil
+}
+
+// RoundFlags represent the values from the round command line.
+type RoundFlags struct {
+	Network  string
+	Chain    string
+	Duration string
+	At       string
+	TZ       string
+}
+
+// ParseRound parses the arguments following the round subcommand.
+func ParseRound(args []string) (RoundFlags, error) {
+	f, fs, err := parseCommand[RoundFlags]("round", args)
+	if err != nil {
+		return RoundFlags{}, err
+	}
+	if fs.NArg() != 0 || (f.Duration == "") == (f.At == "") {
+		return RoundFlags{}, errors.New(commandUsage("round"))
+	}
+	if _, err := LoadLocation(f.TZ); err != nil {
+		return RoundFlags{}, fmt.Errorf("--tz: %w", err)
+	}
+
+	return f, nil
+}
+
+// Round writes the round of the flags to w, alone on its line so that
+// scripts can read it, and when it is due to info.
+func Round(w io.Writer, info io.Writer, flags RoundFlags, network interface {
+	tlock.Network
+	RoundNumber(time.Time) uint64
+}) error {
+	loc, err := LoadLocation(flags.TZ)
+	if err != nil {
+		return err
+	}
+
+	var roundNumber uint64
+	switch {
+	case flags.At != "":
+		at, err := time.Parse(time.RFC3339, flags.At)
+		if err != nil {
+			return fmt.Errorf("--at: %w", err)
+		}
+		roundNumber = network.RoundNumber(at)
+	default:
+		if roundNumber, err = RoundNumber(Flags{Duration: flags.Duration}, network); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(w, "%d\n", roundNumber)
+	if due, ok := tlock.RoundTime(network, roundNumber); ok {
+		fmt.Fprintf(info, "round %d is due at %s\n", roundNumber, FormatTime(due, loc))
+	}
+	return nil
+}
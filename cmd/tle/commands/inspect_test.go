@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspect(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewKey(nil).Network(t, nil)
+
+	name := filepath.Join(t.TempDir(), "data.tle")
+	var ciphertext bytes.Buffer
+	tl := tlock.New(network).WithMetadata(tlock.UserMetadata{"project": "release"})
+	require.NoError(t, tl.Encrypt(&ciphertext, strings.NewReader("content"), 1000))
+	require.NoError(t, os.WriteFile(name, ciphertext.Bytes(), 0o600))
+
+	flags, err := ParseInspect([]string{"--tz", "UTC", name})
+	require.NoError(t, err)
+	var out bytes.Buffer
+	require.NoError(t, Inspect(&out, flags, network))
+	require.Contains(t, out.String(), "chain:    "+DefaultChain+"\n")
+	require.Contains(t, out.String(), "round:    1000\n")
+	require.Contains(t, out.String(), "unlocked: false\n")
+	require.Contains(t, out.String(), "  project: release\n")
+	id, err := tlock.FileID(bytes.NewReader(ciphertext.Bytes()))
+	require.NoError(t, err)
+	require.Contains(t, out.String(), fmt.Sprintf("file id:  %x\n", id))
+	require.NotContains(t, out.String(), "rounds:")
+
+	// The not-after round is listed along the round.
+	ciphertext.Reset()
+	require.NoError(t, tlock.New(network).WithNotAfter(2000).Encrypt(&ciphertext, strings.NewReader("content"), 1000))
+	require.NoError(t, os.WriteFile(name, ciphertext.Bytes(), 0o600))
+	out.Reset()
+	require.NoError(t, Inspect(&out, flags, network))
+	unlock, _ := tlock.RoundTime(network, 1000)
+	closes, _ := tlock.RoundTime(network, 2000)
+	require.Contains(t, out.String(), "rounds:\n  1000 unlocks at "+FormatTime(unlock, time.UTC)+"\n  2000 closes at "+FormatTime(closes, time.UTC)+"\n")
+
+	_, err = ParseInspect(nil)
+	require.Error(t, err)
+}
+
+func TestRound(t *testing.T) {
+	key := fixedtest.NewKey(nil)
+	genesis := key.Genesis.Unix()
+	network := key.Network(t, nil)
+
+	at := time.Unix(genesis+300, 0).UTC().Format(time.RFC3339)
+	flags, err := ParseRound([]string{"--at", at})
+	require.NoError(t, err)
+	var out, info bytes.Buffer
+	require.NoError(t, Round(&out, &info, flags, network))
+	require.Equal(t, "101\n", out.String())
+	require.Contains(t, info.String(), "round 101 is due at")
+
+	_, err = ParseRound([]string{"--at", at, "-D", "1d"})
+	require.Error(t, err)
+	_, err = ParseRound(nil)
+	require.Error(t, err)
+}
+
+func TestParseOperation(t *testing.T) {
+	t.Setenv("TLE_DECRYPT", "true")
+
+	f, err := ParseOperation("encrypt", []string{"-D", "1d", "-a", "a.txt", "b.txt"})
+	require.NoError(t, err)
+	require.True(t, f.Encrypt)
+	require.False(t, f.Decrypt)
+	require.True(t, f.Armor)
+	require.Equal(t, []string{"a.txt", "b.txt"}, f.Inputs)
+
+	f, err = ParseOperation("decrypt", []string{"--enforce-window", "a.tle"})
+	require.NoError(t, err)
+	require.True(t, f.Decrypt)
+	require.Equal(t, []string{"a.tle"}, f.Inputs)
+
+	// The flags of the other operation, or selecting it, aren't defined.
+	_, err = ParseOperation("decrypt", []string{"-D", "1d"})
+	require.Error(t, err)
+	_, err = ParseOperation("encrypt", []string{"-d", "-D", "1d"})
+	require.Error(t, err)
+	_, err = ParseOperation("encrypt", nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,284 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+)
+
+// binaryIntro is the first line of binary ciphertexts.
+const binaryIntro = "age-encryption.org/v1\n"
+
+// ErrInterrupted is returned when an operation stops on SIGINT or SIGTERM.
+var ErrInterrupted = errors.New("interrupted")
+
+// ErrOutputLocked is returned when another run is writing the same output.
+var ErrOutputLocked = errors.New("output is being written by another process")
+
+// TrapInterrupts returns a context done on the first SIGINT or SIGTERM,
+// letting the operations reading through Interruptible stop cleanly. The
+// default handling is then restored, so that a second signal exits at once,
+// such as when waiting on a terminal.
+func TrapInterrupts() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
+
+// Interruptible returns a reader failing with ErrInterrupted once ctx is
+// done, so that the operation reading it stops between two reads and its
+// outputs are flushed and closed on the way out. Seekers stay seekable.
+func Interruptible(ctx context.Context, r io.Reader) io.Reader {
+	ir := interruptibleReader{ctx: ctx, r: r}
+	if rs, ok := r.(io.ReadSeeker); ok {
+		return interruptibleReadSeeker{interruptibleReader: ir, s: rs}
+	}
+	return ir
+}
+
+// =============================================================================
+
+// Output is a file written as OUTPUT.partial next to its final name, so that
+// failed or interrupted runs never leave a truncated OUTPUT behind. The
+// partial plaintext of an interrupted decryption is kept along with its
+// resume state, OUTPUT.resume, for the next run to pick up where it stopped.
+// The partial file is locked while written, so that concurrent runs writing
+// the same OUTPUT, such as batches or daemon clients, fail rather than
+// interleave their writes.
+type Output struct {
+	*os.File
+	name  string
+	input string
+}
+
+// resumeState records the input an interrupted decryption read, so that it
+// is only resumed from the same one, and the output it wrote, so that the
+// state is known to be that of its partial file.
+type resumeState struct {
+	Input   string `json:"input"`
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"modTime"`
+	Output  string `json:"output"`
+}
+
+// CreateOutput creates the partial file of the named output.
+func CreateOutput(name string) (*Output, error) {
+	f, err := openLocked(name, os.O_CREATE|os.O_RDWR)
+	if errors.Is(err, ErrOutputLocked) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open output file %q: %v", name, err)
+	}
+	if err := f.Truncate(0); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to truncate output file %q: %v", name, err)
+	}
+	_ = os.Remove(name + ".resume")
+	return &Output{File: f, name: name}, nil
+}
+
+// ResumeOutput opens the partial file of the named output left by an
+// interrupted decryption of the named input, returning the number of bytes
+// it holds, or creates it when there is nothing to resume. The output is then
+// resumable, its partial file being kept should the decryption be
+// interrupted again.
+func ResumeOutput(name string, input string) (*Output, int64, error) {
+	f, written, err := openPartial(name, input)
+	if err != nil {
+		return nil, 0, err
+	}
+	if f == nil {
+		o, err := CreateOutput(name)
+		if err != nil {
+			return nil, 0, err
+		}
+		o.input = input
+		return o, 0, nil
+	}
+	return &Output{File: f, name: name, input: input}, written, nil
+}
+
+// Resumable reports whether the decryption of the flags from the named input
+// can be resumed once interrupted, which takes a binary ciphertext read from
+// a file and written to a single output, unverified.
+func Resumable(flags Flags, input string) bool {
+	if !flags.Decrypt || flags.Decoy != "" || flags.Attestation != "" || len(flags.Tee) > 0 || flags.ExpectSHA256 != "" || flags.PuzzleFallback || flags.Ticket != "" || input == "" || input == "-" {
+		return false
+	}
+	f, err := os.Open(input)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	intro := make([]byte, len(binaryIntro))
+	if _, err := io.ReadFull(f, intro); err != nil {
+		return false
+	}
+	return string(intro) == binaryIntro
+}
+
+// Finish completes the output on the outcome of the operation writing it:
+// the partial file is renamed to the output on success and removed on
+// failure, unless the operation was interrupted and the output is
+// resumable, in which case it is kept along with the resume state.
+func (o *Output) Finish(err error) error {
+	serr := o.Sync()
+
+	// The partial file is renamed or removed before it is closed where
+	// possible, so that its lock covers it until then.
+	var cerr error
+	if !renameLocked {
+		cerr = o.Close()
+	}
+	ferr := o.finish(err, serr, cerr)
+	if renameLocked {
+		if cerr = o.Close(); ferr == nil {
+			ferr = cerr
+		}
+	}
+	return ferr
+}
+
+// finish renames, keeps or removes the partial file on the outcome of the
+// operation and of syncing and closing the file.
+func (o *Output) finish(err error, serr error, cerr error) error {
+	partial := o.name + ".partial"
+
+	switch {
+	case err == nil && serr == nil && cerr == nil:
+		_ = os.Remove(o.name + ".resume")
+		return os.Rename(partial, o.name)
+	case errors.Is(err, ErrInterrupted) && o.input != "" && serr == nil && cerr == nil:
+		state, serr := inputState(o.input)
+		if serr == nil {
+			state.Output = filepath.Base(o.name)
+			b, _ := json.Marshal(state)
+			serr = os.WriteFile(o.name+".resume", b, 0600)
+		}
+		if serr == nil {
+			return fmt.Errorf("%w, run the same command again to resume decrypting %s", err, o.name)
+		}
+	}
+
+	_ = os.Remove(partial)
+	_ = os.Remove(o.name + ".resume")
+	return errors.Join(err, serr, cerr)
+}
+
+// =============================================================================
+
+// openPartial opens the partial file of the named output for appending when
+// its resume state matches the named input, returning nil otherwise.
+func openPartial(name string, input string) (*os.File, int64, error) {
+	state, ok := readResumeState(name)
+	current, serr := inputState(input)
+	current.Output = filepath.Base(name)
+	if !ok || serr != nil || state != current {
+		return nil, 0, nil
+	}
+
+	f, err := openLocked(name, os.O_RDWR|os.O_APPEND)
+	if errors.Is(err, ErrOutputLocked) {
+		return nil, 0, err
+	}
+	if err != nil {
+		return nil, 0, nil
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return f, info.Size(), nil
+}
+
+// openLocked opens the partial file of the named output and locks it. The
+// file is opened again when the run which held the lock renamed or removed it
+// in the meantime.
+func openLocked(name string, flag int) (*os.File, error) {
+	partial := name + ".partial"
+	for {
+		f, err := os.OpenFile(partial, flag, 0600)
+		if err != nil {
+			return nil, err
+		}
+		if err := lockFile(f); err != nil {
+			f.Close()
+			if errors.Is(err, ErrOutputLocked) {
+				return nil, fmt.Errorf("%w: %s", ErrOutputLocked, name)
+			}
+			return nil, err
+		}
+
+		info, ierr := f.Stat()
+		current, cerr := os.Stat(partial)
+		if ierr == nil && cerr == nil && os.SameFile(info, current) {
+			return f, nil
+		}
+		f.Close()
+		if ierr != nil {
+			return nil, ierr
+		}
+	}
+}
+
+// readResumeState reads the resume state of the named output, reporting
+// whether there is one in the format tle writes, for that output.
+func readResumeState(name string) (resumeState, bool) {
+	b, err := os.ReadFile(name + ".resume")
+	if err != nil {
+		return resumeState{}, false
+	}
+	var state resumeState
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&state); err != nil || dec.More() {
+		return resumeState{}, false
+	}
+	if state.Input == "" || state.Output != filepath.Base(name) {
+		return resumeState{}, false
+	}
+	return state, true
+}
+
+// inputState returns the resume state of the named input.
+func inputState(input string) (resumeState, error) {
+	info, err := os.Stat(input)
+	if err != nil {
+		return resumeState{}, err
+	}
+	return resumeState{Input: input, Size: info.Size(), ModTime: info.ModTime().UnixNano()}, nil
+}
+
+type interruptibleReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r interruptibleReader) Read(p []byte) (int, error) {
+	if r.ctx.Err() != nil {
+		return 0, ErrInterrupted
+	}
+	return r.r.Read(p)
+}
+
+type interruptibleReadSeeker struct {
+	interruptibleReader
+	s io.Seeker
+}
+
+func (r interruptibleReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	return r.s.Seek(offset, whence)
+}
@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterruptedDecryptionResumes(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+	plaintext := make([]byte, 5*tlock.ChunkSize+123)
+	_, err := rand.Read(plaintext)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	input, output := filepath.Join(dir, "data.tle"), filepath.Join(dir, "data")
+	var ciphertext bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&ciphertext, bytes.NewReader(plaintext), 1000))
+	require.NoError(t, os.WriteFile(input, ciphertext.Bytes(), 0600))
+	require.True(t, Resumable(Flags{Decrypt: true}, input))
+
+	// The first run is interrupted once some plaintext is written.
+	ctx, cancel := context.WithCancel(context.Background())
+	o, written, err := ResumeOutput(output, input)
+	require.NoError(t, err)
+	require.Zero(t, written)
+	f, err := os.Open(input)
+	require.NoError(t, err)
+	err = tlock.New(network).Decrypt(cancelWriter{w: o, cancel: cancel}, Interruptible(ctx, f))
+	f.Close()
+	require.ErrorIs(t, err, ErrInterrupted)
+	err = o.Finish(err)
+	require.ErrorIs(t, err, ErrInterrupted)
+	require.Equal(t, ExitInterrupted, ExitCode(err))
+	_, err = os.Stat(output)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	// The second run picks up where the first stopped.
+	o, written, err = ResumeOutput(output, input)
+	require.NoError(t, err)
+	require.NotZero(t, written)
+	f, err = os.Open(input)
+	require.NoError(t, err)
+	defer f.Close()
+	require.NoError(t, o.Finish(tlock.New(network).DecryptFrom(o, f, written)))
+
+	got, err := os.ReadFile(output)
+	require.NoError(t, err)
+	require.Equal(t, plaintext, got)
+	_, err = os.Stat(output + ".partial")
+	require.ErrorIs(t, err, os.ErrNotExist)
+	_, err = os.Stat(output + ".resume")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestFailedOutputIsRemoved(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "data.tle")
+	o, err := CreateOutput(output)
+	require.NoError(t, err)
+	_, err = o.Write([]byte("partial"))
+	require.NoError(t, err)
+
+	require.ErrorIs(t, o.Finish(ErrInterrupted), ErrInterrupted)
+	entries, err := os.ReadDir(filepath.Dir(output))
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func TestConcurrentOutputIsLocked(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "data")
+	o, err := CreateOutput(output)
+	require.NoError(t, err)
+	_, err = o.Write([]byte("first"))
+	require.NoError(t, err)
+
+	_, err = CreateOutput(output)
+	require.ErrorIs(t, err, ErrOutputLocked)
+
+	// The output is free again once finished.
+	require.NoError(t, o.Finish(nil))
+	o, err = CreateOutput(output)
+	require.NoError(t, err)
+	_, err = o.Write([]byte("second"))
+	require.NoError(t, err)
+	require.NoError(t, o.Finish(nil))
+
+	b, err := os.ReadFile(output)
+	require.NoError(t, err)
+	require.Equal(t, "second", string(b))
+}
+
+// cancelWriter cancels its context on the first write.
+type cancelWriter struct {
+	w      io.Writer
+	cancel context.CancelFunc
+}
+
+func (w cancelWriter) Write(p []byte) (int, error) {
+	w.cancel()
+	return w.w.Write(p)
+}
@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// jobMemory is the memory budgeted to each job when --max-memory is given,
+// covering its chunk buffers along with the allocations of the runtime.
+const jobMemory = 4 << 20
+
+// These are the multipliers of the size suffixes, in powers of 1024.
+var sizeSuffixes = map[string]int64{
+	"":  1,
+	"K": 1 << 10,
+	"M": 1 << 20,
+	"G": 1 << 30,
+	"T": 1 << 40,
+}
+
+// ParseSize parses a size in bytes, optionally followed by one of the
+// suffixes K, M, G or T counting in powers of 1024, such as 512M.
+func ParseSize(s string) (int64, error) {
+	s = strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(s)), "B")
+	digits := strings.TrimRight(s, "KMGT")
+	mult, ok := sizeSuffixes[s[len(digits):]]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil || n <= 0 || n > (1<<62)/mult {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * mult, nil
+}
+
+// Jobs returns the number of jobs to run concurrently, --jobs being lowered
+// so that each job gets its share of --max-memory.
+func Jobs(flags Flags) int {
+	jobs := max(flags.Jobs, 1)
+	if flags.MaxMemory != "" {
+		if limit, err := ParseSize(flags.MaxMemory); err == nil {
+			jobs = min(jobs, max(int(limit/jobMemory), 1))
+		}
+	}
+	return jobs
+}
+
+// ApplyLimits restricts the resources of the process according to the flags,
+// for bulk jobs not to starve interactive workloads. The jobs cap the threads
+// running Go code, the memory limit is the soft limit of the Go runtime and
+// nice lowers the CPU and I/O priorities of the process.
+func ApplyLimits(flags Flags) error {
+	if flags.Jobs > 0 {
+		runtime.GOMAXPROCS(Jobs(flags))
+	}
+	if flags.MaxMemory != "" {
+		limit, err := ParseSize(flags.MaxMemory)
+		if err != nil {
+			return fmt.Errorf("--max-memory: %w", err)
+		}
+		debug.SetMemoryLimit(limit)
+	}
+	if flags.Nice {
+		if err := lowerPriority(); err != nil {
+			return fmt.Errorf("--nice: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateLimitFlags checks the resource limit flags.
+func validateLimitFlags(f *Flags) error {
+	if f.Jobs < 0 {
+		return errors.New("--jobs can't be negative")
+	}
+	if f.MaxMemory != "" {
+		if _, err := ParseSize(f.MaxMemory); err != nil {
+			return fmt.Errorf("--max-memory: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSize(t *testing.T) {
+	for s, want := range map[string]int64{
+		"1":      1,
+		"512K":   512 << 10,
+		"512M":   512 << 20,
+		"512MB":  512 << 20,
+		"2g":     2 << 30,
+		" 1T ":   1 << 40,
+		"100000": 100000,
+	} {
+		got, err := ParseSize(s)
+		require.NoError(t, err, s)
+		require.Equal(t, want, got, s)
+	}
+
+	for _, s := range []string{"", "M", "0", "-1M", "1P", "1.5G", "1KM", "9999999T"} {
+		_, err := ParseSize(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestJobs(t *testing.T) {
+	require.Equal(t, 1, Jobs(Flags{}))
+	require.Equal(t, 8, Jobs(Flags{Jobs: 8}))
+	require.Equal(t, 4, Jobs(Flags{Jobs: 8, MaxMemory: "16M"}))
+	require.Equal(t, 1, Jobs(Flags{Jobs: 8, MaxMemory: "1M"}))
+	require.Equal(t, 8, Jobs(Flags{Jobs: 8, MaxMemory: "1G"}))
+}
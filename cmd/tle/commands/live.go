@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/JonathanLogan/tlock"
+)
+
+// encryptLive encrypts src to dst as a live stream towards the round, in the
+// chunks given with --chunk-size.
+func encryptLive(t tlock.Tlock, flags Flags, dst io.Writer, src io.Reader, roundNumber uint64) error {
+	var chunkSize int64
+	if flags.ChunkSize != "" {
+		chunkSize, _ = ParseSize(flags.ChunkSize)
+	}
+	w, err := t.NewLiveWriter(dst, roundNumber, int(chunkSize))
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, src); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	return w.Close()
+}
+
+// =============================================================================
+
+// validateLiveFlags checks the flags encrypting and decrypting live streams.
+func validateLiveFlags(f *Flags) error {
+	if !f.Live {
+		switch {
+		case f.ChunkSize != "":
+			return errors.New("--chunk-size can only be used with --live")
+		case f.Pace:
+			return errors.New("--pace can only be used with --live")
+		}
+		return nil
+	}
+
+	// The chunks are written as they are sealed, which the encodings and the
+	// options processing the whole ciphertext don't allow.
+	switch {
+	case f.Armor:
+		return errors.New("--live can't be used with -a/--armor")
+	case f.Format != "":
+		return errors.New("--live can't be used with --format")
+	case f.Decoy != "":
+		return errors.New("--live can't be used with --decoy")
+	case f.PassphraseFile != "":
+		return errors.New("--live can't be used with --passphrase-file")
+	case f.Convergent != "":
+		return errors.New("--live can't be used with --convergent")
+	case f.Reproducible != "":
+		return errors.New("--live can't be used with --reproducible")
+	case f.Attestation != "":
+		return errors.New("--live can't be used with --attestation")
+	case f.Anchor != "":
+		return errors.New("--live can't be used with --anchor")
+	case f.OpenFor != "" || f.EnforceWindow:
+		return errors.New("--live can't be used with --open-for or --enforce-window")
+	case f.Shares > 0:
+		return errors.New("--live can't be used with --shares")
+	case f.Spool != "":
+		return errors.New("--live can't be used with --spool")
+	case f.OutDir != "":
+		return errors.New("--live can't be used with --out-dir")
+	case f.Daemon != "":
+		return errors.New("--live can't be used with --daemon")
+	case f.ChunkSize != "" && f.Decrypt:
+		return errors.New("--chunk-size can only be used to encrypt")
+	case f.Pace && !f.Decrypt:
+		return errors.New("--pace can only be used with -d/--decrypt")
+	}
+
+	if f.ChunkSize != "" {
+		size, err := ParseSize(f.ChunkSize)
+		if err != nil {
+			return fmt.Errorf("--chunk-size: %w", err)
+		}
+		if size <= 0 || size > tlock.MaxLiveChunkSize {
+			return fmt.Errorf("--chunk-size must be between 1 and %d bytes", tlock.MaxLiveChunkSize)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecryptLive(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	dir := t.TempDir()
+	beacons, _ := writeOfflineCiphertext(t, dir, "unused")
+
+	f, err := os.Open(beacons)
+	require.NoError(t, err)
+	defer f.Close()
+	bundle, err := tlock.ReadBeaconBundle(f)
+	require.NoError(t, err)
+	network, err := tlock.NewTransportNetwork(context.Background(), bundle)
+	require.NoError(t, err)
+
+	var stream bytes.Buffer
+	err = encryptLive(tlock.New(network), Flags{ChunkSize: "8"}, &stream, bytes.NewReader([]byte("live from the stage")), 50)
+	require.NoError(t, err)
+	input := filepath.Join(dir, "stream.tle")
+	require.NoError(t, os.WriteFile(input, stream.Bytes(), 0o600))
+
+	flags := DefaultFlags()
+	flags.Decrypt = true
+	flags.Live = true
+	flags.Pace = true
+	flags.Beacons = beacons
+	flags.Inputs = []string{input}
+	flags.Output = filepath.Join(dir, "stream")
+	require.NoError(t, Operation{Flags: flags}.Run(context.Background()))
+	plaintext, err := os.ReadFile(flags.Output)
+	require.NoError(t, err)
+	require.Equal(t, "live from the stage", string(plaintext))
+
+	// Without --live, the stream isn't taken for an age file.
+	flags.Live, flags.Pace = false, false
+	flags.Output = filepath.Join(dir, "age")
+	require.Error(t, Operation{Flags: flags}.Run(context.Background()))
+}
@@ -0,0 +1,13 @@
+//go:build !unix && !windows
+
+package commands
+
+import "os"
+
+// renameLocked tells that outputs are renamed while their lock is held.
+const renameLocked = false
+
+// lockFile doesn't lock on this system.
+func lockFile(f *os.File) error {
+	return nil
+}
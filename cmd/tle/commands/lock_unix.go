@@ -0,0 +1,23 @@
+//go:build unix
+
+package commands
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// renameLocked tells that outputs are renamed while their lock is held.
+const renameLocked = true
+
+// lockFile takes an exclusive advisory lock on the file, released once it is
+// closed, failing with ErrOutputLocked when another process holds it.
+func lockFile(f *os.File) error {
+	err := unix.Flock(int(f.Fd()), unix.LOCK_EX|unix.LOCK_NB)
+	if errors.Is(err, unix.EWOULDBLOCK) {
+		return ErrOutputLocked
+	}
+	return err
+}
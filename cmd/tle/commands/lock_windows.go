@@ -0,0 +1,22 @@
+package commands
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// renameLocked tells that outputs are renamed while their lock is held, which
+// Windows doesn't allow for open files.
+const renameLocked = false
+
+// lockFile takes an exclusive lock on the file, released once it is closed,
+// failing with ErrOutputLocked when another process holds it.
+func lockFile(f *os.File) error {
+	err := windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, new(windows.Overlapped))
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return ErrOutputLocked
+	}
+	return err
+}
@@ -0,0 +1,179 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// MessageID identifies a user facing message of the command line, translated
+// by the catalogs registered for it.
+type MessageID string
+
+// These are the messages of the command line which catalogs can translate.
+// Their English text, used when no catalog translates them, is a format of
+// the fmt package, whose arguments translations can reorder with explicit
+// indexes such as %[2]s.
+const (
+	MsgUsage             MessageID = "usage"
+	MsgTooEarly          MessageID = "too-early"
+	MsgTooEarlyUnknown   MessageID = "too-early-unknown"
+	MsgBinaryToTerminal  MessageID = "binary-to-terminal"
+	MsgBinaryOutput      MessageID = "binary-output"
+	MsgDriftWarning      MessageID = "drift-warning"
+	MsgCountdownDue      MessageID = "countdown-due"
+	MsgDashboardChain    MessageID = "dashboard-chain"
+	MsgDashboardHealth   MessageID = "dashboard-health"
+	MsgDashboardBehind   MessageID = "dashboard-behind"
+	MsgDashboardUnknown  MessageID = "dashboard-health-unknown"
+	MsgDashboardRound    MessageID = "dashboard-round"
+	MsgDashboardColumns  MessageID = "dashboard-columns"
+	MsgDashboardSummary  MessageID = "dashboard-summary"
+	MsgDashboardUnlocked MessageID = "dashboard-unlocked"
+	MsgDashboardEvent    MessageID = "dashboard-event"
+	MsgPuzzleProgress    MessageID = "puzzle-progress"
+	MsgPuzzleProofValid  MessageID = "puzzle-proof-valid"
+	MsgAnchorMatch       MessageID = "anchor-match"
+	MsgChunksMatch       MessageID = "chunks-match"
+	MsgSignedBy          MessageID = "signed-by"
+	MsgOCIPushed         MessageID = "oci-pushed"
+	MsgOCIDecrypted      MessageID = "oci-decrypted"
+	MsgScheduledTask     MessageID = "scheduled-task"
+	MsgScheduledTimer    MessageID = "scheduled-timer"
+	MsgScheduledEnable   MessageID = "scheduled-enable"
+	MsgCISealed          MessageID = "ci-sealed"
+	MsgEnvExported       MessageID = "env-exported"
+	MsgCacheWouldFree    MessageID = "cache-would-free"
+	MsgCacheFreed        MessageID = "cache-freed"
+	MsgLegacyDecrypt     MessageID = "legacy-decrypt"
+	MsgLegacyEncrypt     MessageID = "legacy-encrypt"
+)
+
+// Catalog maps messages to their translation in a language.
+type Catalog map[MessageID]string
+
+// english is the catalog of the messages as written in the source.
+var english = Catalog{
+	MsgUsage:             usage,
+	MsgTooEarly:          "too early to decrypt: round %d unlocks at %s",
+	MsgTooEarlyUnknown:   "too early to decrypt: round %d isn't reached yet",
+	MsgBinaryToTerminal:  "did you mean to use -a/--armor? Use --force-tty to write it anyway",
+	MsgBinaryOutput:      "use -o to write it to a file or --force-tty to write it anyway",
+	MsgDriftWarning:      "warning: round %d is due at %s, in %d days, and may unlock up to %s later, assuming that:",
+	MsgCountdownDue:      "due",
+	MsgDashboardChain:    "chain:  %s",
+	MsgDashboardHealth:   "health: %s, latest round %d",
+	MsgDashboardBehind:   ", behind by %s",
+	MsgDashboardUnknown:  "health: unknown, %v",
+	MsgDashboardRound:    "round:  %d at %s",
+	MsgDashboardColumns:  "FILE\tROUND\tUNLOCKS\tIN",
+	MsgDashboardSummary:  "%d locked, %d unlocked",
+	MsgDashboardUnlocked: "recently unlocked:",
+	MsgDashboardEvent:    "%s  %s (round %d)",
+	MsgPuzzleProgress:    "solving the time-lock puzzle: %d%%",
+	MsgPuzzleProofValid:  "the proof of the puzzle solution is valid",
+	MsgAnchorMatch:       "ciphertext matches anchor for round %d of chain %s",
+	MsgChunksMatch:       "%d chunks match their checksum",
+	MsgSignedBy:          "ciphertext signed by %s",
+	MsgOCIPushed:         "pushed %s@%s, unlocking at round %d of chain %s",
+	MsgOCIDecrypted:      "decrypted %s (%s)",
+	MsgScheduledTask:     `registered the scheduled task \tle\%s, unlocking at %s`,
+	MsgScheduledTimer:    "wrote %s, unlocking at %s; enable it with:",
+	MsgScheduledEnable:   "\tsystemctl --user daemon-reload && systemctl --user enable --now %s.timer",
+	MsgCISealed:          "wrote %s, unlocking at %s; decrypt it in the pipeline with:",
+	MsgEnvExported:       "exported %d variables to %s",
+	MsgCacheWouldFree:    "%d bytes would be freed",
+	MsgCacheFreed:        "%d bytes freed",
+	MsgLegacyDecrypt:     "note: tle -d is deprecated, use tle decrypt with the same options",
+	MsgLegacyEncrypt:     "note: tle -e and tle without an operation are deprecated, use tle encrypt with the same options",
+}
+
+var messages = struct {
+	sync.RWMutex
+	catalogs map[string]Catalog
+	language string
+}{
+	catalogs: map[string]Catalog{"en": english},
+	language: "en",
+}
+
+// RegisterCatalog registers the catalog of the language, named as in LANG
+// such as fr or pt_BR, for products embedding tle to translate its messages.
+// The messages the catalog lacks stay in English.
+func RegisterCatalog(language string, c Catalog) {
+	messages.Lock()
+	defer messages.Unlock()
+	messages.catalogs[normalizeLanguage(language)] = c
+}
+
+// SetLanguage selects the language of the messages. A language without a
+// catalog falls back to the one of its base language, fr for fr_CA, then to
+// English.
+func SetLanguage(language string) {
+	messages.Lock()
+	defer messages.Unlock()
+	messages.language = normalizeLanguage(language)
+}
+
+// SetupMessages registers the catalog of the JSON file named by TLE_MESSAGES,
+// an object mapping message identifiers to their translation, and selects the
+// language of TLE_LANG, or else of LC_ALL, LC_MESSAGES or LANG.
+func SetupMessages() error {
+	language := firstNonEmpty(os.Getenv("TLE_LANG"), os.Getenv("LC_ALL"), os.Getenv("LC_MESSAGES"), os.Getenv("LANG"))
+	if name := os.Getenv("TLE_MESSAGES"); name != "" {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			return fmt.Errorf("read message catalog: %w", err)
+		}
+		var c Catalog
+		if err := json.Unmarshal(b, &c); err != nil {
+			return fmt.Errorf("decode message catalog %s: %w", name, err)
+		}
+		RegisterCatalog(language, c)
+	}
+	SetLanguage(language)
+	return nil
+}
+
+// Message returns the message in the selected language, formatted with the
+// arguments.
+func Message(id MessageID, args ...any) string {
+	format := lookupMessage(id)
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// =============================================================================
+
+// lookupMessage returns the translation of the message in the selected
+// language, or its base language, or English.
+func lookupMessage(id MessageID) string {
+	messages.RLock()
+	defer messages.RUnlock()
+
+	language := messages.language
+	base, _, _ := strings.Cut(language, "_")
+	for _, l := range []string{language, base} {
+		if s, ok := messages.catalogs[l][id]; ok {
+			return s
+		}
+	}
+	return english[id]
+}
+
+// normalizeLanguage strips the encoding and modifier of a locale name, as in
+// fr_FR.UTF-8@euro, and maps C and POSIX to English.
+func normalizeLanguage(language string) string {
+	language, _, _ = strings.Cut(language, ".")
+	language, _, _ = strings.Cut(language, "@")
+	language = strings.ReplaceAll(language, "-", "_")
+	switch language {
+	case "", "C", "POSIX":
+		return "en"
+	}
+	return language
+}
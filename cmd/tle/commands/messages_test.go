@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessages(t *testing.T) {
+	defer SetLanguage("en")
+
+	RegisterCatalog("fr", Catalog{
+		MsgTooEarly:     "trop tôt pour déchiffrer : le tour %[1]d est déverrouillé le %[2]s",
+		MsgCountdownDue: "imminent",
+	})
+	SetLanguage("fr_CA.UTF-8")
+
+	unlock := time.Date(2026, 10, 16, 7, 0, 0, 0, time.UTC)
+	err := &TooEarlyError{Round: 42, Unlock: unlock, Location: time.UTC}
+	require.Equal(t, "trop tôt pour déchiffrer : le tour 42 est déverrouillé le Fri, 16 Oct 2026 07:00:00 UTC (2026-10-16 07:00:00 UTC, unix 1792134000)", err.Error())
+	require.Equal(t, "imminent", countdown(0))
+
+	// The messages missing from the catalog stay in English.
+	require.Equal(t, "too early to decrypt: round 42 isn't reached yet", (&TooEarlyError{Round: 42}).Error())
+
+	SetLanguage("C")
+	require.Equal(t, "due", countdown(0))
+}
+
+func TestSetupMessages(t *testing.T) {
+	defer SetLanguage("en")
+
+	name := filepath.Join(t.TempDir(), "de.json")
+	require.NoError(t, os.WriteFile(name, []byte(`{"countdown-due": "fällig"}`), 0o600))
+	t.Setenv("TLE_MESSAGES", name)
+	t.Setenv("TLE_LANG", "de_DE.UTF-8")
+	require.NoError(t, SetupMessages())
+	require.Equal(t, "fällig", countdown(0))
+
+	require.NoError(t, os.WriteFile(name, []byte(`[]`), 0o600))
+	require.Error(t, SetupMessages())
+}
@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/JonathanLogan/tlock"
+	"golang.org/x/crypto/blake2b"
+)
+
+// These are the signature algorithms of minisign: legacy signatures over the
+// file itself, and signatures over its BLAKE2b-512 digest.
+const (
+	minisignLegacy    = "Ed"
+	minisignPrehashed = "ED"
+)
+
+// These are the prefixes of the comment lines of minisign files.
+const (
+	minisignUntrusted = "untrusted comment:"
+	minisignTrusted   = "trusted comment: "
+)
+
+// minisignKeyIDSize is the size of the key ID tying signatures to keys.
+const minisignKeyIDSize = 8
+
+// verifyMinisign checks the minisign signature of the ciphertext read from
+// src, as produced by minisign -S, with the minisign public key file.
+func verifyMinisign(keyFile string, signature []byte, src io.Reader) error {
+	b, err := os.ReadFile(keyFile)
+	if err != nil {
+		return fmt.Errorf("read key: %w", err)
+	}
+	lines := minisignLines(b)
+	if len(lines) != 1 {
+		return fmt.Errorf("key %q is not a minisign public key", keyFile)
+	}
+	key, err := base64.StdEncoding.DecodeString(lines[0])
+	if err != nil || len(key) != 2+minisignKeyIDSize+ed25519.PublicKeySize || string(key[:2]) != minisignLegacy {
+		return fmt.Errorf("key %q is not a minisign public key", keyFile)
+	}
+	keyID, pub := key[2:2+minisignKeyIDSize], ed25519.PublicKey(key[2+minisignKeyIDSize:])
+
+	lines = minisignLines(signature)
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], minisignTrusted) {
+		return fmt.Errorf("%w: malformed minisign signature", tlock.ErrBadSignature)
+	}
+	sig, err := base64.StdEncoding.DecodeString(lines[0])
+	if err != nil || len(sig) != 2+minisignKeyIDSize+ed25519.SignatureSize {
+		return fmt.Errorf("%w: malformed minisign signature", tlock.ErrBadSignature)
+	}
+	globalSig, err := base64.StdEncoding.DecodeString(lines[2])
+	if err != nil || len(globalSig) != ed25519.SignatureSize {
+		return fmt.Errorf("%w: malformed minisign signature", tlock.ErrBadSignature)
+	}
+	if !bytes.Equal(sig[2:2+minisignKeyIDSize], keyID) {
+		return fmt.Errorf("%w: signed by another minisign key", tlock.ErrBadSignature)
+	}
+
+	var msg []byte
+	switch string(sig[:2]) {
+	case minisignLegacy:
+		if msg, err = io.ReadAll(src); err != nil {
+			return fmt.Errorf("read input: %w", err)
+		}
+	case minisignPrehashed:
+		h, _ := blake2b.New512(nil)
+		if _, err := io.Copy(h, src); err != nil {
+			return fmt.Errorf("read input: %w", err)
+		}
+		msg = h.Sum(nil)
+	default:
+		return fmt.Errorf("%w: unsupported minisign algorithm %q", tlock.ErrBadSignature, sig[:2])
+	}
+
+	if !ed25519.Verify(pub, msg, sig[2+minisignKeyIDSize:]) {
+		return tlock.ErrBadSignature
+	}
+	// The trusted comment is authenticated by the global signature.
+	trusted := strings.TrimPrefix(lines[1], minisignTrusted)
+	if !ed25519.Verify(pub, append(sig[2+minisignKeyIDSize:], trusted...), globalSig) {
+		return fmt.Errorf("%w: trusted comment", tlock.ErrBadSignature)
+	}
+	return nil
+}
+
+// minisignLines returns the lines of the minisign file, without the
+// untrusted comment.
+func minisignLines(b []byte) []string {
+	var lines []string
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, minisignUntrusted) {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/JonathanLogan/tlock/networks/http"
+)
+
+// NetworkFlags represent the values reaching the network from the command
+// lines of the subcommands calling it, as the ones of Flags.
+type NetworkFlags struct {
+	AuthToken string
+	DoH       string
+	IPFamily  string
+	NoPin     bool
+}
+
+// Flags returns the flags holding the values, for NewNetwork.
+func (f NetworkFlags) Flags() Flags {
+	return Flags{AuthToken: f.AuthToken, DoH: f.DoH, IPFamily: f.IPFamily, NoPin: f.NoPin}
+}
+
+// NetworkOptions returns the options of the http network set by the flags,
+// or else by the TLE_AUTHTOKEN, TLE_DOH, TLE_IPFAMILY and TLE_NOPIN
+// environment variables, which the subcommands rely on.
+func NetworkOptions(f Flags) ([]http.Option, error) {
+	authToken := firstNonEmpty(f.AuthToken, os.Getenv("TLE_AUTHTOKEN"))
+	doh := firstNonEmpty(f.DoH, os.Getenv("TLE_DOH"))
+	ipFamily := firstNonEmpty(f.IPFamily, os.Getenv("TLE_IPFAMILY"))
+	noPin, _ := strconv.ParseBool(os.Getenv("TLE_NOPIN"))
+
+	opts := []http.Option{http.WithBearerToken(authToken)}
+	if doh != "" {
+		opts = append(opts, http.WithDNSOverHTTPS(doh))
+	}
+	if f.NoPin || noPin {
+		opts = append(opts, http.WithoutPinning())
+	}
+	switch ipFamily {
+	case "":
+	case "4":
+		opts = append(opts, http.WithAddressFamily(http.FamilyIPv4))
+	case "6":
+		opts = append(opts, http.WithAddressFamily(http.FamilyIPv6))
+	default:
+		return nil, fmt.Errorf("invalid --ip-family %q, expected 4 or 6", ipFamily)
+	}
+
+	return opts, nil
+}
+
+// =============================================================================
+
+// firstNonEmpty returns the first of the values which isn't empty.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
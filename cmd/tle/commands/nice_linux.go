@@ -0,0 +1,41 @@
+package commands
+
+import (
+	"os"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// These constants of ioprio_set(2) aren't defined by x/sys.
+const (
+	ioprioWhoProcess = 1
+	ioprioClassIdle  = 3
+	ioprioClassShift = 13
+)
+
+// niceness is the nice value of the lowered priority.
+const niceness = 10
+
+// lowerPriority lowers the CPU priority of the process and moves it to the
+// idle I/O scheduling class. Both are attributes of each thread on Linux, so
+// they are set on every existing thread, the later ones inheriting them.
+func lowerPriority() error {
+	tasks, err := os.ReadDir("/proc/self/task")
+	if err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		tid, err := strconv.Atoi(task.Name())
+		if err != nil {
+			continue
+		}
+		if err := unix.Setpriority(unix.PRIO_PROCESS, tid, niceness); err != nil {
+			return err
+		}
+		if _, _, errno := unix.Syscall(unix.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), ioprioClassIdle<<ioprioClassShift); errno != 0 {
+			return errno
+		}
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package commands
+
+import "errors"
+
+// lowerPriority isn't supported on this system.
+func lowerPriority() error {
+	return errors.New("lowering the priority isn't supported on this system")
+}
@@ -0,0 +1,14 @@
+//go:build unix && !linux
+
+package commands
+
+import "golang.org/x/sys/unix"
+
+// niceness is the nice value of the lowered priority.
+const niceness = 10
+
+// lowerPriority lowers the CPU priority of the process, whose I/O priority
+// can't be set portably on this system.
+func lowerPriority() error {
+	return unix.Setpriority(unix.PRIO_PROCESS, 0, niceness)
+}
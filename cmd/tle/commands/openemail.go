@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/http"
+	"github.com/JonathanLogan/tlock/tlockmail"
+)
+
+// openEmailCommand defines the open-email subcommand.
+var openEmailCommand = CommandSpec{
+	Name: "open-email",
+	Synopsis: []string{
+		`tle open-email [-n NETWORK] [-c CHAIN] [-o OUTPUT] [MESSAGE]`,
+	},
+	Flags: []FlagSpec{
+		mainFlag("network", func(f *OpenEmailFlags) any { return &f.Network }),
+		mainFlag("chain", func(f *OpenEmailFlags) any { return &f.Chain }),
+		mainFlag("output", func(f *OpenEmailFlags) any { return &f.Output }),
+	},
+	notes: `Extracts the timelocked attachment of the email MESSAGE, as saved from a mail
+client in the .eml format, and decrypts it to OUTPUT.`,
+	defaults: func() any { return &OpenEmailFlags{Network: DefaultNetwork, Chain: DefaultChain} },
+}
+
+// OpenEmailFlags represent the values from the open-email command line.
+type OpenEmailFlags struct {
+	Network string
+	Chain   string
+	Output  string
+	Input   string
+}
+
+// ParseOpenEmail parses the arguments following the open-email subcommand.
+func ParseOpenEmail(args []string) (OpenEmailFlags, error) {
+	f, fs, err := parseCommand[OpenEmailFlags]("open-email", args)
+	if err != nil {
+		return OpenEmailFlags{}, err
+	}
+	if fs.NArg() > 1 {
+		return OpenEmailFlags{}, errors.New(commandUsage("open-email"))
+	}
+	f.Input = fs.Arg(0)
+
+	return f, nil
+}
+
+// OpenEmail extracts the timelocked attachment of the message read from src
+// and writes it decrypted to dst.
+func OpenEmail(dst io.Writer, src io.Reader, network *http.Network) error {
+	ciphertext, filename, err := tlockmail.Extract(src)
+	if err != nil {
+		return err
+	}
+
+	if err := tlock.New(network).Decrypt(dst, bytes.NewReader(ciphertext)); err != nil {
+		return fmt.Errorf("decrypt %s: %w", filename, err)
+	}
+	return nil
+}
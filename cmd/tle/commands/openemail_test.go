@@ -0,0 +1,25 @@
+package commands
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/JonathanLogan/tlock/tlockmail"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOpenEmail(t *testing.T) {
+	f, err := ParseOpenEmail([]string{"-o", "letter.txt", "message.eml"})
+	require.NoError(t, err)
+	require.Equal(t, OpenEmailFlags{Network: DefaultNetwork, Chain: DefaultChain, Output: "letter.txt", Input: "message.eml"}, f)
+
+	_, err = ParseOpenEmail([]string{"one.eml", "two.eml"})
+	require.Error(t, err)
+}
+
+func TestOpenEmailWithoutAttachment(t *testing.T) {
+	var out bytes.Buffer
+	err := OpenEmail(&out, strings.NewReader("Subject: hi\r\n\r\nplain message\r\n"), nil)
+	require.ErrorIs(t, err, tlockmail.ErrNoAttachment)
+}
@@ -0,0 +1,430 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/http"
+	"github.com/JonathanLogan/tlock/tlockgrpc"
+)
+
+// Operation is an encryption or decryption run as tle runs it, for the tools
+// embedding tle to get its behaviors, such as the handling of the outputs,
+// the batches written to --out-dir with their name templates or the resumed
+// decryptions, without running the binary. The flags are the ones Parse or
+// ParseOperation return, or are filled in directly from DefaultFlags.
+type Operation struct {
+	Flags Flags
+
+	// Network is the network of the operation, connected to from the flags
+	// when nil. It is unused when the flags run the operation through a
+	// daemon.
+	Network *http.Network
+
+	// Stdin, Stdout and Stderr are read from and written to when no input or
+	// output is given, or "-", and default to the standard streams of the
+	// process when nil. Warnings are written to Stderr.
+	Stdin  io.Reader
+	Stdout *os.File
+	Stderr io.Writer
+
+	// beacons is the network of the beacon bundle of the flags, which
+	// decrypts in place of Network.
+	beacons tlock.Network
+	// ticket is the ticket of the flags, restricting the ciphertexts the
+	// beacons decrypt.
+	ticket *tlock.Ticket
+}
+
+// DefaultFlags returns the flags of the command line when no flag is given,
+// without reading the environment.
+func DefaultFlags() Flags {
+	return defaultFlags()
+}
+
+// NewNetwork connects to the relay at host with the network options of the
+// flags, which the subcommands take from the environment. Given a period and
+// a genesis time, the relay is only called once needed.
+func NewNetwork(host string, chainHash string, flags Flags) (*http.Network, error) {
+	opts, err := NetworkOptions(flags)
+	if err != nil {
+		return nil, err
+	}
+	if flags.Period != "" {
+		period, err := time.ParseDuration(flags.Period)
+		if err != nil {
+			return nil, err
+		}
+		return http.NewOfflineNetwork(host, chainHash, period, flags.Genesis, opts...)
+	}
+	return http.NewNetwork(host, chainHash, opts...)
+}
+
+// Run runs the operation of the flags on their inputs. Once ctx is done, it
+// stops between two reads of the inputs, leaving no truncated output. The
+// process wide limits of the flags aren't applied, which ApplyLimits does,
+// but --sandbox confines the process for the rest of its life.
+func (op Operation) Run(ctx context.Context) error {
+	if op.Stdin == nil {
+		op.Stdin = os.Stdin
+	}
+	if op.Stdout == nil {
+		op.Stdout = os.Stdout
+	}
+	if op.Stderr == nil {
+		op.Stderr = os.Stderr
+	}
+	flags := op.Flags
+
+	if flags.Daemon != "" {
+		return op.processWithDaemon(ctx, op.input(), flags.Output)
+	}
+
+	switch {
+	case flags.Decrypt && flags.PuzzleFallback:
+		// The puzzles are solved without the network.
+	case flags.Ticket != "":
+		ticket, err := OpenTicket(flags.Ticket)
+		if err != nil {
+			return err
+		}
+		network, err := ticket.Network()
+		if err != nil {
+			return err
+		}
+		op.beacons, op.ticket = network, ticket
+	case flags.Beacons != "":
+		network, err := OpenBeacons(flags.Beacons)
+		if err != nil {
+			return err
+		}
+		op.beacons = network
+	case op.Network == nil:
+		network, err := NewNetwork(flags.Network, flags.Chain, flags)
+		if err != nil {
+			return err
+		}
+		op.Network = network
+	}
+
+	if flags.Sandbox {
+		if err := op.confine(ctx); err != nil {
+			return fmt.Errorf("--sandbox: %w", err)
+		}
+	}
+
+	if flags.Encrypt {
+		WarnDrift(op.Stderr, flags, op.Network)
+	}
+
+	if flags.OutDir != "" {
+		return op.runBatch(ctx)
+	}
+
+	return op.process(ctx, flags, op.input(), flags.Output)
+}
+
+// =============================================================================
+
+// input returns the first input of the flags, or the empty string for the
+// standard input.
+func (op Operation) input() string {
+	if len(op.Flags.Inputs) == 0 {
+		return ""
+	}
+	return op.Flags.Inputs[0]
+}
+
+// runBatch processes each input into the output directory.
+func (op Operation) runBatch(ctx context.Context) error {
+	flags, network := op.Flags, op.Network
+	if len(flags.Inputs) == 0 {
+		return errors.New("--out-dir requires INPUT files")
+	}
+
+	// All the inputs are encrypted towards the same round, which their names
+	// may record.
+	if flags.Encrypt {
+		roundNumber, err := RoundNumber(flags, network)
+		if err != nil {
+			return err
+		}
+		flags.Round, flags.Duration, flags.Force = roundNumber, "", true
+	}
+
+	// The inputs may be locked to many rounds, whose beacons are fetched
+	// concurrently ahead. Those failing are fetched again by the inputs,
+	// which then report the error.
+	if flags.Decrypt && op.beacons == nil && !flags.PuzzleFallback {
+		var rounds []uint64
+		for _, input := range flags.Inputs {
+			if roundNumber, err := CiphertextRound(input, flags.Decoy); err == nil {
+				rounds = append(rounds, roundNumber)
+			}
+		}
+		_ = network.Prefetch(ctx, rounds, 0)
+	}
+
+	// The jobs are spent on the inputs, each of them being processed
+	// sequentially.
+	jobs := Jobs(flags)
+	fileFlags := flags
+	fileFlags.Jobs = 1
+
+	var wg sync.WaitGroup
+	var failed atomic.Bool
+	sem := make(chan struct{}, jobs)
+	errs := make([]error, len(flags.Inputs))
+	for i, input := range flags.Inputs {
+		roundNumber := flags.Round
+		if flags.Decrypt {
+			var err error
+			if roundNumber, err = CiphertextRound(input, flags.Decoy); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", input, err)
+				break
+			}
+		}
+
+		output, err := OutputPath(flags, input, roundNumber)
+		if err != nil {
+			errs[i] = err
+			break
+		}
+
+		sem <- struct{}{}
+		if failed.Load() || ctx.Err() != nil {
+			// No input is started after a failure.
+			<-sem
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := op.process(ctx, fileFlags, input, output); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", input, err)
+				failed.Store(true)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// process runs the operation of the flags from the named input to the named
+// output, using the streams of the operation for empty names or "-". Once ctx
+// is done, it stops between two reads of the input.
+func (op Operation) process(ctx context.Context, flags Flags, input string, output string) (err error) {
+	var network tlock.Network = op.Network
+	if op.beacons != nil {
+		network = op.beacons
+	}
+
+	src := op.Stdin
+	var in *os.File
+	if name := input; name != "" && name != "-" {
+		f, err := os.OpenFile(name, os.O_RDONLY, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to open input file %q: %v", name, err)
+		}
+		defer f.Close()
+		src, in = f, f
+
+		// Regular files are mapped, sparing the copies through read buffers.
+		if m, err := tlock.MapFile(f); err == nil {
+			defer m.Close()
+			src = m.Reader()
+		}
+	}
+	src = Interruptible(ctx, src)
+	defer func() { err = ExplainTooEarly(err, flags, input, network) }()
+
+	decrypter := tlock.New(network).WithPassphrase(flags.Passphrase, tlock.KDFParams{})
+	if flags.EnforceWindow {
+		decrypter = decrypter.WithWindowEnforced()
+	}
+	if flags.StrictDecode {
+		decrypter = decrypter.StrictDecode()
+	}
+
+	var dst io.Writer = op.Stdout
+	if flags.Shares > 0 {
+		// The ciphertext is split into shares, written as outputs of their
+		// own, as it is encrypted.
+		var s *shareOutputs
+		if s, err = createShares(output, flags, network); err != nil {
+			return err
+		}
+		defer func() { err = s.finish(err) }()
+		dst = s
+	} else if name := output; name != "" && name != "-" {
+		// The output is written to a partial file, renamed once complete.
+		// Interrupted decryptions keep it to resume from on the next run.
+		var o *Output
+		var written int64
+		if Resumable(flags, input) {
+			o, written, err = ResumeOutput(name, input)
+		} else {
+			o, err = CreateOutput(name)
+		}
+		if err != nil {
+			return err
+		}
+		defer func() { err = o.Finish(err) }()
+		if written > 0 {
+			rs := Interruptible(ctx, in).(io.ReadSeeker)
+			return decrypter.DecryptFrom(o, rs, written)
+		}
+		dst = o
+	} else if flags.Decrypt {
+		w := NewTerminalWriter(op.Stdout, flags.ForceTTY)
+		defer func() {
+			if cerr := w.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		dst = w
+	} else if err := CheckTerminal(op.Stdout, BinaryOutput(flags), flags.ForceTTY); err != nil {
+		return err
+	}
+
+	if len(flags.Tee) > 0 {
+		var t *tees
+		if t, err = op.createTees(flags); err != nil {
+			return err
+		}
+		defer func() { err = t.finish(err) }()
+		dst = tlock.NewTeeWriter(append([]io.Writer{dst}, t.dsts...)...)
+	}
+	if flags.ExpectSHA256 != "" {
+		// A mismatch fails the decryption, whose outputs are removed.
+		dw := expectDigest(flags, dst)
+		defer func() {
+			if err == nil {
+				err = dw.Verify()
+			}
+		}()
+		dst = dw
+	}
+
+	switch {
+	case flags.Metadata:
+		return tlock.New(network).Metadata(dst)
+	case flags.Decrypt:
+		if flags.Live {
+			return decrypter.DecryptLive(ctx, dst, src, flags.Pace)
+		}
+		if flags.PuzzleFallback {
+			if flags.Decoy != "" {
+				if src, err = tlock.NewDecoyReader(src, flags.Decoy); err != nil {
+					return err
+				}
+			}
+			if flags.PuzzleSolution != "" {
+				solution, err := ReadPuzzleSolution(flags.PuzzleSolution)
+				if err != nil {
+					return err
+				}
+				return decrypter.DecryptPuzzleSolution(dst, src, solution)
+			}
+			return decrypter.DecryptPuzzle(ctx, dst, src, puzzleProgress(op.Stderr))
+		}
+		if op.ticket != nil {
+			if flags.Decoy != "" {
+				if src, err = tlock.NewDecoyReader(src, flags.Decoy); err != nil {
+					return err
+				}
+			}
+			return decrypter.DecryptWithTicket(dst, src, op.ticket)
+		}
+		if flags.Decoy != "" {
+			if src, err = tlock.NewDecoyReader(src, flags.Decoy); err != nil {
+				return err
+			}
+		} else if rs, ok := src.(io.ReadSeeker); ok && !flags.StrictDecode && tlock.IsConvergent(rs) {
+			return decrypter.DecryptConvergent(dst, rs)
+		} else if flags.Spool != "" {
+			limit, _ := ParseSize(flags.Spool)
+			spool, rr, err := SpoolConvergent(src, limit)
+			if err != nil {
+				return err
+			}
+			if spool != nil {
+				defer spool.Close()
+				return decrypter.DecryptConvergent(dst, spool)
+			}
+			src = rr
+		}
+		t := decrypter.WithConcurrency(Jobs(flags))
+		if flags.Attestation != "" {
+			attestation, err := ReadAttestation(flags.Attestation)
+			if err != nil {
+				return err
+			}
+			return t.DecryptAttested(dst, src, attestation)
+		}
+		if flags.Format != "" {
+			_, dec, err := tlock.LookupEncoder(flags.Format)
+			if err != nil {
+				return err
+			}
+			return dec(t, dst, src)
+		}
+		return t.Decrypt(dst, src)
+	default:
+		flags.Input = input
+		return Encrypt(flags, dst, src, op.Network)
+	}
+}
+
+// processWithDaemon runs the operation of the flags through the daemon, from
+// the named input to the named output.
+func (op Operation) processWithDaemon(ctx context.Context, input string, output string) (err error) {
+	flags := op.Flags
+
+	src := op.Stdin
+	if name := input; name != "" && name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return fmt.Errorf("failed to open input file %q: %v", name, err)
+		}
+		defer f.Close()
+		src = f
+	}
+
+	var dst io.Writer = op.Stdout
+	if name := output; name != "" && name != "-" {
+		o, err := CreateOutput(name)
+		if err != nil {
+			return err
+		}
+		defer func() { err = o.Finish(err) }()
+		dst = o
+	} else if flags.Decrypt {
+		w := NewTerminalWriter(op.Stdout, flags.ForceTTY)
+		defer func() {
+			if cerr := w.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		dst = w
+	} else if err := CheckTerminal(op.Stdout, !flags.Armor, flags.ForceTTY); err != nil {
+		return err
+	}
+
+	cc, err := DialDaemon(flags.Daemon)
+	if err != nil {
+		return err
+	}
+	defer cc.Close()
+
+	return DaemonProcess(ctx, flags, dst, src, tlockgrpc.NewTlockClient(cc))
+}
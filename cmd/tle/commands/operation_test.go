@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/JonathanLogan/tlock/networks/http"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOperation(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	// Encrypting doesn't call the relay, which is never reached.
+	network, err := http.NewOfflineNetwork("http://127.0.0.1:1", DefaultChain, 3*time.Second, 1692803367)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	var inputs []string
+	for _, name := range []string{"a.txt", "b.txt"} {
+		input := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(input, []byte("content of "+name), 0600))
+		inputs = append(inputs, input)
+	}
+
+	flags := DefaultFlags()
+	flags.Encrypt = true
+	flags.Round = 1 << 40
+	flags.Inputs = inputs
+	flags.OutDir = t.TempDir()
+	flags.NameTemplate = "{round}-{name}.tle"
+	require.NoError(t, Operation{Flags: flags, Network: network}.Run(context.Background()))
+
+	for _, name := range []string{"a.txt", "b.txt"} {
+		roundNumber, err := CiphertextRound(filepath.Join(flags.OutDir, "1099511627776-"+name+".tle"), "")
+		require.NoError(t, err)
+		require.Equal(t, uint64(1<<40), roundNumber)
+	}
+
+	// Without an output, the result is written to the output of the operation.
+	stdout, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+	require.NoError(t, err)
+	defer stdout.Close()
+
+	flags.OutDir, flags.NameTemplate = "", ""
+	flags.Inputs = inputs[:1]
+	flags.Armor = true
+	require.NoError(t, Operation{Flags: flags, Network: network, Stdout: stdout}.Run(context.Background()))
+	b, err := os.ReadFile(stdout.Name())
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(b), "-----BEGIN AGE ENCRYPTED FILE-----"))
+
+	// Randomness sources are checked before use.
+	flags.Entropy = filepath.Join(dir, "zeros")
+	require.NoError(t, os.WriteFile(flags.Entropy, make([]byte, 1024), 0600))
+	require.ErrorIs(t, Operation{Flags: flags, Network: network, Stdout: stdout}.Run(context.Background()), tlock.ErrUnhealthyRandom)
+	flags.Entropy = ""
+
+	// The inputs are checked.
+	flags.Inputs = []string{filepath.Join(dir, "missing.txt")}
+	require.Error(t, Operation{Flags: flags, Network: network, Stdout: stdout}.Run(context.Background()))
+}
@@ -0,0 +1,160 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+)
+
+// puzzleCommand defines the puzzle subcommand.
+var puzzleCommand = CommandSpec{
+	Name: "puzzle",
+	Synopsis: []string{
+		`tle puzzle solve [-o OUTPUT] INPUT`,
+		`tle puzzle verify SOLUTION`,
+	},
+	Flags: []FlagSpec{
+		{Name: "output", Short: "o", Arg: "OUTPUT", Description: "Write the solution to the file at path OUTPUT.", value: field(func(f *PuzzleFlags) any { return &f.Output })},
+	},
+	notes: `Solves the time-lock puzzle of INPUT, encrypted with --puzzle-fallback, and
+writes its solution with a proof of the squarings to OUTPUT, or to the standard
+output, as JSON. Proving the squarings takes as long again as solving the
+puzzle. The proof verifies in a fraction of a second, so that a fast machine,
+or a service, can solve the puzzles of others: verify checks the proof of
+SOLUTION, and decrypting with --puzzle-fallback --puzzle-solution SOLUTION
+verifies it before decrypting INPUT without solving its puzzle again.`,
+	defaults: func() any { return &PuzzleFlags{} },
+}
+
+// PuzzleFlags represent the values from the puzzle command line.
+type PuzzleFlags struct {
+	Verify bool
+	Output string
+	Input  string
+}
+
+// ParsePuzzle parses the arguments of the puzzle subcommand.
+func ParsePuzzle(args []string) (PuzzleFlags, error) {
+	if len(args) == 0 || args[0] != "solve" && args[0] != "verify" {
+		return PuzzleFlags{}, errors.New(commandUsage("puzzle"))
+	}
+	f, fs, err := parseCommand[PuzzleFlags]("puzzle", args[1:])
+	if err != nil {
+		return PuzzleFlags{}, err
+	}
+	if fs.NArg() != 1 {
+		return PuzzleFlags{}, errors.New(commandUsage("puzzle"))
+	}
+	f.Verify = args[0] == "verify"
+	if f.Verify && f.Output != "" {
+		return PuzzleFlags{}, errors.New("-o/--output can only be used with puzzle solve")
+	}
+	f.Input = fs.Arg(0)
+
+	return f, nil
+}
+
+// SolvePuzzle solves the time-lock puzzle of the input of the flags, writing
+// its solution to dst and the progress of the squarings to stderr.
+func SolvePuzzle(ctx context.Context, dst io.Writer, stderr io.Writer, flags PuzzleFlags) error {
+	f, err := os.Open(flags.Input)
+	if err != nil {
+		return fmt.Errorf("failed to open input file %q: %v", flags.Input, err)
+	}
+	defer f.Close()
+
+	solution, err := tlock.SolvePuzzle(ctx, f, puzzleProgress(stderr))
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(dst)
+	enc.SetIndent("", "  ")
+	return enc.Encode(solution)
+}
+
+// ReadPuzzleSolution reads the solution of a time-lock puzzle written by
+// tle puzzle solve, verifying its proof.
+func ReadPuzzleSolution(name string) (tlock.PuzzleSolution, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return tlock.PuzzleSolution{}, fmt.Errorf("failed to read puzzle solution %q: %v", name, err)
+	}
+	var solution tlock.PuzzleSolution
+	if err := json.Unmarshal(data, &solution); err != nil {
+		return tlock.PuzzleSolution{}, fmt.Errorf("parse puzzle solution %q: %w", name, err)
+	}
+	if err := solution.Verify(); err != nil {
+		return tlock.PuzzleSolution{}, fmt.Errorf("puzzle solution %q: %w", name, err)
+	}
+	return solution, nil
+}
+
+// puzzleSquarings returns the squarings of the time-lock puzzle solved on
+// this machine around the time the round unlocks.
+func puzzleSquarings(network tlock.Network, roundNumber uint64) (uint64, error) {
+	unlock, ok := tlock.RoundTime(network, roundNumber)
+	if !ok {
+		return 0, errors.New("the network doesn't expose the time of its rounds")
+	}
+	squarings, err := tlock.PuzzleSquarings(time.Until(unlock))
+	if err != nil {
+		return 0, err
+	}
+	return max(squarings, 1), nil
+}
+
+// puzzleProgress returns the function writing the progress of the squarings
+// to w, whenever another percent of them is done.
+func puzzleProgress(w io.Writer) func(done, total uint64) {
+	last := -1
+	return func(done, total uint64) {
+		percent := int(float64(done) / float64(total) * 100)
+		if percent == last {
+			return
+		}
+		last = percent
+		fmt.Fprintf(w, "\r%s", Message(MsgPuzzleProgress, percent))
+		if done == total {
+			fmt.Fprintln(w)
+		}
+	}
+}
+
+// =============================================================================
+
+// validatePuzzleFlags checks the flags wrapping the file key in a time-lock
+// puzzle, or decrypting by solving it.
+func validatePuzzleFlags(f *Flags) error {
+	if f.PuzzleSolution != "" && (!f.Decrypt || !f.PuzzleFallback) {
+		return errors.New("--puzzle-solution requires --decrypt and --puzzle-fallback")
+	}
+	if !f.PuzzleFallback {
+		return nil
+	}
+
+	// The puzzle is a stanza of the age header, which the other formats and
+	// the deterministic encryptions don't have room for.
+	switch {
+	case f.Format == "jwe":
+		return errors.New("--puzzle-fallback can't be used with --format jwe")
+	case f.Convergent != "":
+		return errors.New("--puzzle-fallback can't be used with --convergent")
+	case f.Reproducible != "":
+		return errors.New("--puzzle-fallback can't be used with --reproducible")
+	case f.Live:
+		return errors.New("--puzzle-fallback can't be used with --live")
+	case f.Daemon != "":
+		return errors.New("--puzzle-fallback can't be used with --daemon")
+	case f.Decrypt && f.Beacons != "":
+		return errors.New("--puzzle-fallback can't be used with --beacons")
+	case f.Decrypt && (f.Attestation != "" || f.Spool != "" || f.EnforceWindow):
+		return errors.New("--puzzle-fallback can't be used with --attestation, --spool or --enforce-window")
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/JonathanLogan/tlock/networks/http"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPuzzleFallback(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network, err := http.NewOfflineNetwork("http://127.0.0.1:1", DefaultChain, 3*time.Second, 1692803367)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "secret.txt")
+	require.NoError(t, os.WriteFile(input, []byte("the secret"), 0600))
+
+	// The round is past, so that the puzzle is solved right away.
+	flags := DefaultFlags()
+	flags.Encrypt = true
+	flags.Round, flags.Force = 1000, true
+	flags.PuzzleFallback = true
+	flags.Inputs = []string{input}
+	flags.Output = filepath.Join(dir, "secret.tle")
+	require.NoError(t, Operation{Flags: flags, Network: network}.Run(context.Background()))
+
+	// Decrypting never connects to the network.
+	flags = DefaultFlags()
+	flags.Decrypt = true
+	flags.Network = "http://127.0.0.1:1"
+	flags.PuzzleFallback = true
+	flags.Inputs = []string{filepath.Join(dir, "secret.tle")}
+	flags.Output = filepath.Join(dir, "decrypted.txt")
+	var stderr bytes.Buffer
+	require.NoError(t, Operation{Flags: flags, Stderr: &stderr}.Run(context.Background()))
+	plaintext, err := os.ReadFile(flags.Output)
+	require.NoError(t, err)
+	require.Equal(t, "the secret", string(plaintext))
+	require.Contains(t, stderr.String(), "solving the time-lock puzzle: 100%\n")
+}
+
+func TestPuzzleSolution(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network, err := http.NewOfflineNetwork("http://127.0.0.1:1", DefaultChain, 3*time.Second, 1692803367)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "secret.txt")
+	require.NoError(t, os.WriteFile(input, []byte("the secret"), 0600))
+
+	flags := DefaultFlags()
+	flags.Encrypt = true
+	flags.Round, flags.Force = 1000, true
+	flags.PuzzleFallback = true
+	flags.Inputs = []string{input}
+	flags.Output = filepath.Join(dir, "secret.tle")
+	require.NoError(t, Operation{Flags: flags, Network: network}.Run(context.Background()))
+
+	pf, err := ParsePuzzle([]string{"solve", "-o", "secret.solution", flags.Output})
+	require.NoError(t, err)
+	require.Equal(t, PuzzleFlags{Output: "secret.solution", Input: flags.Output}, pf)
+	var solution, stderr bytes.Buffer
+	require.NoError(t, SolvePuzzle(context.Background(), &solution, &stderr, pf))
+	require.Contains(t, stderr.String(), "solving the time-lock puzzle: 100%\n")
+	name := filepath.Join(dir, "secret.solution")
+	require.NoError(t, os.WriteFile(name, solution.Bytes(), 0600))
+	_, err = ReadPuzzleSolution(name)
+	require.NoError(t, err)
+
+	// The solution decrypts without solving the puzzle again.
+	flags = DefaultFlags()
+	flags.Decrypt = true
+	flags.PuzzleFallback = true
+	flags.PuzzleSolution = name
+	flags.Inputs = []string{filepath.Join(dir, "secret.tle")}
+	flags.Output = filepath.Join(dir, "decrypted.txt")
+	stderr.Reset()
+	require.NoError(t, Operation{Flags: flags, Stderr: &stderr}.Run(context.Background()))
+	plaintext, err := os.ReadFile(flags.Output)
+	require.NoError(t, err)
+	require.Equal(t, "the secret", string(plaintext))
+	require.Empty(t, stderr.String())
+
+	_, err = ParsePuzzle([]string{"verify", "-o", "out", name})
+	require.Error(t, err)
+	_, err = ParsePuzzle([]string{"prove", name})
+	require.Error(t, err)
+}
@@ -0,0 +1,195 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/oci"
+)
+
+// pushCommand defines the push subcommand.
+var pushCommand = CommandSpec{
+	Name: "push",
+	Synopsis: []string{
+		`tle push [--name NAME] [--plain-http] REFERENCE INPUT`,
+	},
+	Flags: []FlagSpec{
+		{Name: "name", Arg: "NAME", Description: "The file name recorded in the artifact, that of INPUT by default.", value: field(func(f *PushFlags) any { return &f.Name })},
+		{Name: "plain-http", Description: "Talk to the registry over http.", value: field(func(f *PushFlags) any { return &f.PlainHTTP })},
+	},
+	notes: `Pushes the ciphertext INPUT to a container registry as an OCI artifact tagged
+REFERENCE, such as ghcr.io/org/release:v1.0, recording its round and chain
+hash as annotations. NAME is the file name the artifact records, defaulting
+to the one of INPUT.
+
+The credentials of the registry are read from the TLE_REGISTRY_USERNAME and
+TLE_REGISTRY_PASSWORD environment variables, or else from the docker config
+file, as written by docker login.`,
+	defaults: func() any { return &PushFlags{} },
+}
+
+// pullCommand defines the pull subcommand.
+var pullCommand = CommandSpec{
+	Name: "pull",
+	Synopsis: []string{
+		`tle pull [--locked] [-n NETWORK] [-c CHAIN] [--auth-token TOKEN] [--doh URL] [--ip-family FAMILY] [--no-pin] [--plain-http] [--force-tty] [-o OUTPUT] REFERENCE`,
+	},
+	Flags: []FlagSpec{
+		mainFlag("network", func(f *PullFlags) any { return &f.Network }),
+		mainFlag("chain", func(f *PullFlags) any { return &f.Chain }),
+		mainFlag("auth-token", func(f *PullFlags) any { return &f.AuthToken }),
+		mainFlag("doh", func(f *PullFlags) any { return &f.DoH }),
+		mainFlag("ip-family", func(f *PullFlags) any { return &f.IPFamily }),
+		mainFlag("no-pin", func(f *PullFlags) any { return &f.NoPin }),
+		{Name: "locked", Description: "Write the ciphertext rather than decrypting it.", value: field(func(f *PullFlags) any { return &f.Locked })},
+		{Name: "plain-http", Description: "Talk to the registry over http.", value: field(func(f *PullFlags) any { return &f.PlainHTTP })},
+		mainFlag("force-tty", func(f *PullFlags) any { return &f.ForceTTY }),
+		mainFlag("output", func(f *PullFlags) any { return &f.Output }),
+	},
+	notes: `Pulls the artifact REFERENCE pushed with tle push and decrypts it, failing
+without downloading it if its round isn't reached yet. With --locked, the
+ciphertext is written as is, whatever its round.`,
+	defaults: func() any { return &PullFlags{Network: DefaultNetwork, Chain: DefaultChain} },
+}
+
+// PushFlags represent the values from the push command line.
+type PushFlags struct {
+	Reference string
+	Input     string
+	Name      string
+	PlainHTTP bool
+}
+
+// ParsePush parses the arguments following the push subcommand.
+func ParsePush(args []string) (PushFlags, error) {
+	f, fs, err := parseCommand[PushFlags]("push", args)
+	if err != nil {
+		return PushFlags{}, err
+	}
+	if fs.NArg() != 2 {
+		return PushFlags{}, errors.New(commandUsage("push"))
+	}
+	f.Reference, f.Input = fs.Arg(0), fs.Arg(1)
+	if f.Name == "" {
+		f.Name = filepath.Base(f.Input)
+	}
+
+	return f, nil
+}
+
+// PullFlags represent the values from the pull command line.
+type PullFlags struct {
+	Network   string
+	Chain     string
+	Reference string
+	Locked    bool
+	PlainHTTP bool
+	ForceTTY  bool
+	Output    string
+
+	NetworkFlags
+}
+
+// ParsePull parses the arguments following the pull subcommand.
+func ParsePull(args []string) (PullFlags, error) {
+	f, fs, err := parseCommand[PullFlags]("pull", args)
+	if err != nil {
+		return PullFlags{}, err
+	}
+	if fs.NArg() != 1 {
+		return PullFlags{}, errors.New(commandUsage("pull"))
+	}
+	f.Reference = fs.Arg(0)
+
+	return f, nil
+}
+
+// NewRegistryClient returns a client for the registry of the reference, with
+// the credentials found for it.
+func NewRegistryClient(reference string, plainHTTP bool) (*oci.Client, oci.Reference, error) {
+	ref, err := oci.ParseReference(reference)
+	if err != nil {
+		return nil, oci.Reference{}, err
+	}
+	username, password, err := registryCredentials(ref.Registry)
+	if err != nil {
+		return nil, oci.Reference{}, err
+	}
+
+	return &oci.Client{PlainHTTP: plainHTTP, Username: username, Password: password}, ref, nil
+}
+
+// Pull decrypts the artifact of the reference to dst, checking that its round
+// is reached before downloading it.
+func Pull(ctx context.Context, dst io.Writer, client *oci.Client, ref oci.Reference, network tlock.Network) (oci.Artifact, error) {
+	a, err := client.Resolve(ctx, ref)
+	if err != nil {
+		return oci.Artifact{}, err
+	}
+	if a.Round > network.Current(time.Now()) {
+		if eta, ok := tlock.RoundTime(network, a.Round); ok {
+			return oci.Artifact{}, fmt.Errorf("%w: %s unlocks at round %d, expected at %s", tlock.ErrTooEarly, ref, a.Round, FormatTime(eta, nil))
+		}
+		return oci.Artifact{}, fmt.Errorf("%w: %s unlocks at round %d", tlock.ErrTooEarly, ref, a.Round)
+	}
+
+	// The manifest is pinned, should the tag move in the meantime.
+	ref.Tag, ref.Digest = "", a.Digest
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := client.Pull(ctx, ref, pw)
+		pw.CloseWithError(err)
+	}()
+
+	err = tlock.New(network).Decrypt(dst, pr)
+	pr.CloseWithError(err)
+	<-done
+	return a, err
+}
+
+// =============================================================================
+
+// registryCredentials returns the credentials of the registry from the
+// environment or the docker config file, empty when there are none.
+func registryCredentials(registry string) (string, string, error) {
+	if username := os.Getenv("TLE_REGISTRY_USERNAME"); username != "" {
+		return username, os.Getenv("TLE_REGISTRY_PASSWORD"), nil
+	}
+
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", "", nil
+		}
+		dir = filepath.Join(home, ".docker")
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		return "", "", nil
+	}
+
+	var config struct {
+		Auths map[string]struct {
+			Auth string `json:"auth"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(b, &config); err != nil {
+		return "", "", fmt.Errorf("parse docker config: %w", err)
+	}
+	for _, key := range []string{registry, "https://" + registry, "http://" + registry} {
+		if entry, ok := config.Auths[key]; ok && entry.Auth != "" {
+			return oci.BasicAuth(entry.Auth)
+		}
+	}
+	return "", "", nil
+}
@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePushPull(t *testing.T) {
+	tests := []struct {
+		name        string
+		push        bool
+		args        []string
+		shouldError bool
+	}{
+		{name: "push passes", push: true, args: []string{"ghcr.io/org/release:v1", "release.tle"}},
+		{name: "push with name passes", push: true, args: []string{"--name", "release.tar", "localhost:5000/release:v1", "release.tle"}},
+		{name: "push without input fails", push: true, args: []string{"ghcr.io/org/release:v1"}, shouldError: true},
+		{name: "pull passes", args: []string{"ghcr.io/org/release:v1"}},
+		{name: "pull locked with output passes", args: []string{"--locked", "-o", "release.tle", "ghcr.io/org/release:v1"}},
+		{name: "pull without reference fails", shouldError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var err error
+			if test.push {
+				_, err = ParsePush(test.args)
+			} else {
+				_, err = ParsePull(test.args)
+			}
+			if test.shouldError {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRegistryCredentials(t *testing.T) {
+	dir := t.TempDir()
+	config := `{"auths": {"https://ghcr.io": {"auth": "dXNlcjpzZWNyZXQ="}}}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.json"), []byte(config), 0600))
+	t.Setenv("DOCKER_CONFIG", dir)
+	t.Setenv("TLE_REGISTRY_USERNAME", "")
+
+	username, password, err := registryCredentials("ghcr.io")
+	require.NoError(t, err)
+	require.Equal(t, "user", username)
+	require.Equal(t, "secret", password)
+
+	username, _, err = registryCredentials("docker.io")
+	require.NoError(t, err)
+	require.Empty(t, username)
+
+	t.Setenv("TLE_REGISTRY_USERNAME", "env")
+	t.Setenv("TLE_REGISTRY_PASSWORD", "token")
+	username, password, err = registryCredentials("ghcr.io")
+	require.NoError(t, err)
+	require.Equal(t, "env", username)
+	require.Equal(t, "token", password)
+}
@@ -0,0 +1,163 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+)
+
+// rewrapCommand defines the rewrap subcommand.
+var rewrapCommand = CommandSpec{
+	Name: "rewrap",
+	Synopsis: []string{
+		`tle rewrap (--extend DURATION | -r ROUND) [-n NETWORK] [-c CHAIN] [--auth-token TOKEN] [--doh URL] [--ip-family FAMILY] [--no-pin] [--passphrase-file FILE [--kdf-preset PRESET]] [-a] [--force-tty] [-o OUTPUT] [INPUT]`,
+	},
+	Flags: []FlagSpec{
+		mainFlag("network", func(f *RewrapFlags) any { return &f.Network }),
+		mainFlag("chain", func(f *RewrapFlags) any { return &f.Chain }),
+		mainFlag("auth-token", func(f *RewrapFlags) any { return &f.AuthToken }),
+		mainFlag("doh", func(f *RewrapFlags) any { return &f.DoH }),
+		mainFlag("ip-family", func(f *RewrapFlags) any { return &f.IPFamily }),
+		mainFlag("no-pin", func(f *RewrapFlags) any { return &f.NoPin }),
+		{Name: "round", Short: "r", Arg: "ROUND", Description: "The round to rewrap INPUT towards.", value: field(func(f *RewrapFlags) any { return &f.Round })},
+		{Name: "extend", Arg: "DURATION", Description: "Rewrap INPUT towards the round due DURATION after its current one.", value: field(func(f *RewrapFlags) any { return &f.Extend })},
+		mainFlag("passphrase-file", func(f *RewrapFlags) any { return &f.PassphraseFile }),
+		mainFlag("kdf-preset", func(f *RewrapFlags) any { return &f.KDFPreset }),
+		mainFlag("armor", func(f *RewrapFlags) any { return &f.Armor }),
+		mainFlag("force-tty", func(f *RewrapFlags) any { return &f.ForceTTY }),
+		mainFlag("output", func(f *RewrapFlags) any { return &f.Output }),
+	},
+	notes: `Timelocks the ciphertext INPUT again towards a later round, without decrypting
+its payload. The round of INPUT must have been reached. With --extend, the new
+round is the one emitted DURATION after the round recorded in INPUT.
+
+A ciphertext protected by a passphrase requires --passphrase-file, and the
+rewrapped one is protected by the same passphrase, with the argon2id
+parameters of --kdf-preset. The namespace of INPUT is kept.`,
+	defaults: func() any {
+		return &RewrapFlags{Network: DefaultNetwork, Chain: DefaultChain, KDFPreset: DefaultKDFPreset}
+	},
+}
+
+// ErrRewrapRound is returned when the rewrapped round isn't after the round of
+// the ciphertext.
+var ErrRewrapRound = errors.New("the new round must be after the round of the ciphertext")
+
+// RewrapFlags represent the values from the rewrap command line.
+type RewrapFlags struct {
+	Network        string
+	Chain          string
+	Round          uint64
+	Extend         string
+	PassphraseFile string
+	KDFPreset      string
+	Passphrase     string
+	Armor          bool
+	ForceTTY       bool
+	Output         string
+	Input          string
+
+	NetworkFlags
+}
+
+// ParseRewrap parses the arguments following the rewrap subcommand.
+func ParseRewrap(args []string) (RewrapFlags, error) {
+	f, fs, err := parseCommand[RewrapFlags]("rewrap", args)
+	if err != nil {
+		return RewrapFlags{}, err
+	}
+	if (f.Round == 0) == (f.Extend == "") || fs.NArg() > 1 {
+		return RewrapFlags{}, errors.New(commandUsage("rewrap"))
+	}
+	f.Input = fs.Arg(0)
+
+	if _, err := tlock.KDFPreset(f.KDFPreset); err != nil {
+		return RewrapFlags{}, fmt.Errorf("--kdf-preset: %w", err)
+	}
+	if f.PassphraseFile != "" {
+		if f.Passphrase, err = readPassphrase(f.PassphraseFile); err != nil {
+			return RewrapFlags{}, err
+		}
+	}
+
+	return f, nil
+}
+
+// Rewrap rewraps the ciphertext read from src towards the round of the flags
+// and writes it to dst.
+func Rewrap(flags RewrapFlags, dst io.Writer, src io.Reader, network tlock.Network) (err error) {
+	// The header is parsed from a copy of what is read, which is then
+	// replayed ahead of the rest of the ciphertext.
+	var head bytes.Buffer
+	hdr, err := tlock.ParseHeader(io.TeeReader(src, &head))
+	if err != nil {
+		return err
+	}
+	original, _, err := hdr.Round()
+	if err != nil {
+		return err
+	}
+
+	roundNumber, err := RewrapRound(flags, original, network)
+	if err != nil {
+		return err
+	}
+
+	// The rewrapped ciphertext keeps the namespace of the original one, and
+	// its passphrase when there is one.
+	namespace, err := hdr.Namespace()
+	if err != nil {
+		return err
+	}
+	t := tlock.New(network).WithNamespace(namespace)
+	if flags.Passphrase != "" {
+		params, err := tlock.KDFPreset(flags.KDFPreset)
+		if err != nil {
+			return err
+		}
+		t = t.WithPassphrase(flags.Passphrase, params)
+	}
+
+	if flags.Armor {
+		w := tlock.NewArmorWriter(dst)
+		defer func() {
+			if cerr := w.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		dst = w
+	}
+
+	return t.Rewrap(dst, io.MultiReader(&head, src), roundNumber)
+}
+
+// RewrapRound computes the round to rewrap a ciphertext of the original round
+// towards.
+func RewrapRound(flags RewrapFlags, original uint64, network tlock.Network) (uint64, error) {
+	roundNumber := flags.Round
+	if flags.Extend != "" {
+		start, ok := tlock.RoundTime(network, original)
+		n, hasRounds := network.(interface{ RoundNumber(time.Time) uint64 })
+		if !ok || !hasRounds {
+			return 0, errors.New("the network doesn't expose the time of its rounds")
+		}
+
+		extend, err := parseDurationsAsSeconds(start, flags.Extend)
+		if err != nil {
+			return 0, err
+		}
+		if extend <= 0 {
+			return 0, ErrInvalidDurationValue
+		}
+		roundNumber = n.RoundNumber(start.Add(extend))
+	}
+
+	if roundNumber <= original {
+		return 0, fmt.Errorf("%w: %d is not after %d", ErrRewrapRound, roundNumber, original)
+	}
+	return roundNumber, nil
+}
@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRewrap(t *testing.T) {
+	f, err := ParseRewrap([]string{"--extend", "30d", "-o", "new.tle", "old.tle"})
+	require.NoError(t, err)
+	require.Equal(t, RewrapFlags{Network: DefaultNetwork, Chain: DefaultChain, Extend: "30d", KDFPreset: DefaultKDFPreset, Output: "new.tle", Input: "old.tle"}, f)
+
+	_, err = ParseRewrap([]string{"old.tle"})
+	require.Error(t, err)
+	_, err = ParseRewrap([]string{"--extend", "30d", "-r", "10", "old.tle"})
+	require.Error(t, err)
+}
+
+func TestRewrapExtend(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+
+	var cipherData bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&cipherData, bytes.NewReader([]byte("hello")), 1000))
+
+	var rewrapped bytes.Buffer
+	require.NoError(t, Rewrap(RewrapFlags{Extend: "1h"}, &rewrapped, bytes.NewReader(cipherData.Bytes()), network))
+
+	hdr, err := tlock.ParseHeader(bytes.NewReader(rewrapped.Bytes()))
+	require.NoError(t, err)
+	roundNumber, _, err := hdr.Round()
+	require.NoError(t, err)
+	require.Equal(t, uint64(1000+time.Hour/(3*time.Second)), roundNumber)
+
+	err = Rewrap(RewrapFlags{Round: 1000}, &bytes.Buffer{}, bytes.NewReader(cipherData.Bytes()), network)
+	require.ErrorIs(t, err, ErrRewrapRound)
+}
+
+func TestRewrapPassphraseNamespace(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+	params := tlock.KDFParams{Time: 1, Memory: 1024, Threads: 1}
+	original := tlock.New(network).WithNamespace("example.org").WithPassphrase("pass", params)
+
+	var cipherData bytes.Buffer
+	require.NoError(t, original.Encrypt(&cipherData, bytes.NewReader([]byte("hello")), 1000))
+
+	err := Rewrap(RewrapFlags{Round: 1001}, &bytes.Buffer{}, bytes.NewReader(cipherData.Bytes()), network)
+	require.ErrorIs(t, err, tlock.ErrPassphraseRequired)
+
+	var rewrapped bytes.Buffer
+	flags := RewrapFlags{Round: 1001, Passphrase: "pass", KDFPreset: "interactive"}
+	require.NoError(t, Rewrap(flags, &rewrapped, bytes.NewReader(cipherData.Bytes()), network))
+
+	hdr, err := tlock.ParseHeader(bytes.NewReader(rewrapped.Bytes()))
+	require.NoError(t, err)
+	namespace, err := hdr.Namespace()
+	require.NoError(t, err)
+	require.Equal(t, "example.org", namespace)
+	require.Contains(t, rewrapped.String(), " argon2id ")
+}
@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// errSandboxUnsupported is returned when the process can't be confined on
+// this system, or by this build.
+var errSandboxUnsupported = errors.New("confinement isn't supported")
+
+// zoneinfoPaths are the files the time package reads lazily, when the times
+// of the messages are first formatted.
+var zoneinfoPaths = []string{"/etc/localtime", "/usr/share/zoneinfo"}
+
+// confine fetches the beacons of the inputs, then confines the process to the
+// decryption of the flags: without network access, reading nothing but the
+// inputs and writing nothing but the outputs. The ciphertexts are parsed
+// under confinement, save for the headers naming their rounds.
+func (op Operation) confine(ctx context.Context) error {
+	flags := op.Flags
+	if op.beacons == nil && !flags.PuzzleFallback {
+		var rounds []uint64
+		for _, input := range flags.Inputs {
+			infos, err := CiphertextRounds(input, flags.Decoy, op.Network)
+			if err != nil {
+				return fmt.Errorf("%s: %w", input, err)
+			}
+			for _, info := range infos {
+				rounds = append(rounds, info.Round)
+			}
+		}
+		if err := op.Network.Prefetch(ctx, rounds, 0); err != nil {
+			return err
+		}
+	}
+
+	read, write := sandboxPaths(flags)
+	return sandbox(read, write)
+}
+
+// sandboxPaths returns the paths the decryption of the flags reads, and the
+// directories it writes its outputs to.
+func sandboxPaths(flags Flags) ([]string, []string) {
+	read := append([]string{}, zoneinfoPaths...)
+	read = append(read, flags.Inputs...)
+	if flags.PuzzleSolution != "" {
+		read = append(read, flags.PuzzleSolution)
+	}
+	if flags.Attestation != "" {
+		read = append(read, flags.Attestation)
+	}
+
+	var write []string
+	if flags.Output != "" && flags.Output != "-" {
+		write = append(write, filepath.Dir(flags.Output))
+	}
+	if flags.OutDir != "" {
+		write = append(write, flags.OutDir)
+	}
+	for _, name := range flags.Tee {
+		if name != "-" {
+			write = append(write, filepath.Dir(name))
+		}
+	}
+	if flags.Spool != "" {
+		write = append(write, os.TempDir())
+	}
+	return read, write
+}
+
+// =============================================================================
+
+// validateSandboxFlags checks the flags confining the decryption.
+func validateSandboxFlags(f *Flags) error {
+	if !f.Sandbox {
+		return nil
+	}
+	offline := f.Beacons != "" || f.Ticket != "" || f.PuzzleFallback
+	switch {
+	case !f.Decrypt:
+		return errors.New("--sandbox can only be used with -d/--decrypt")
+	case f.Daemon != "":
+		return errors.New("--sandbox can't be used with --daemon")
+	case !offline && (len(f.Inputs) == 0 || f.Live):
+		// The beacons are fetched before the network is cut off, from the
+		// rounds the headers of the inputs name.
+		return errors.New("--sandbox requires INPUT files, or --beacons or --ticket, to fetch the beacons ahead")
+	}
+	return nil
+}
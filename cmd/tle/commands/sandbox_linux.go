@@ -0,0 +1,169 @@
+//go:build linux && (amd64 || arm64)
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// These constants of seccomp(2) and of the x32 ABI aren't defined by x/sys.
+const (
+	seccompSetModeFilter = 1
+	x32SyscallBit        = 0x40000000
+)
+
+// These are the accesses of the Landlock ABIs, by version. The ioctls of the
+// devices aren't restricted, so that the standard streams, opened before,
+// stay usable whatever the kernel.
+var landlockAccesses = []uint64{
+	1: unix.LANDLOCK_ACCESS_FS_EXECUTE | unix.LANDLOCK_ACCESS_FS_WRITE_FILE | unix.LANDLOCK_ACCESS_FS_READ_FILE |
+		unix.LANDLOCK_ACCESS_FS_READ_DIR | unix.LANDLOCK_ACCESS_FS_REMOVE_DIR | unix.LANDLOCK_ACCESS_FS_REMOVE_FILE |
+		unix.LANDLOCK_ACCESS_FS_MAKE_CHAR | unix.LANDLOCK_ACCESS_FS_MAKE_DIR | unix.LANDLOCK_ACCESS_FS_MAKE_REG |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SOCK | unix.LANDLOCK_ACCESS_FS_MAKE_FIFO | unix.LANDLOCK_ACCESS_FS_MAKE_BLOCK |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SYM,
+	2: unix.LANDLOCK_ACCESS_FS_REFER,
+	3: unix.LANDLOCK_ACCESS_FS_TRUNCATE,
+}
+
+// sandboxSyscalls are the system calls the confined process is denied: those
+// of the network, of running other programs, and of io_uring, whose
+// operations open sockets and files without going through the system calls
+// the filter sees.
+var sandboxSyscalls = []uintptr{
+	unix.SYS_SOCKET, unix.SYS_SOCKETPAIR, unix.SYS_CONNECT, unix.SYS_BIND, unix.SYS_LISTEN,
+	unix.SYS_ACCEPT, unix.SYS_ACCEPT4, unix.SYS_SENDTO, unix.SYS_SENDMSG, unix.SYS_SENDMMSG,
+	unix.SYS_EXECVE, unix.SYS_EXECVEAT, unix.SYS_PTRACE,
+	unix.SYS_IO_URING_SETUP, unix.SYS_IO_URING_ENTER, unix.SYS_IO_URING_REGISTER,
+}
+
+// sandbox confines every thread of the process with Landlock, to reading the
+// read paths and the directories beneath them and to writing beneath the
+// write directories, then with a seccomp filter denying the network. It fails
+// when the kernel lacks Landlock, or when tle is built with cgo, whose
+// threads can't all be restricted.
+func sandbox(read []string, write []string) error {
+	// Landlock restricts the threads calling it, which the later threads
+	// inherit: it is called on each of them, as no_new_privs it requires.
+	if _, _, errno := syscall.AllThreadsSyscall(unix.SYS_PRCTL, unix.PR_SET_NO_NEW_PRIVS, 1, 0); errno != 0 {
+		if errno == unix.ENOTSUP {
+			return fmt.Errorf("%w by builds with cgo", errSandboxUnsupported)
+		}
+		return fmt.Errorf("set no_new_privs: %w", errno)
+	}
+
+	abi, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	if errno != 0 {
+		return fmt.Errorf("%w without Landlock: %w", errSandboxUnsupported, errno)
+	}
+	var handled uint64
+	for v := 1; v < len(landlockAccesses) && v <= int(abi); v++ {
+		handled |= landlockAccesses[v]
+	}
+	attr := unix.LandlockRulesetAttr{Access_fs: handled}
+	if abi >= 4 {
+		attr.Access_net = unix.LANDLOCK_ACCESS_NET_BIND_TCP | unix.LANDLOCK_ACCESS_NET_CONNECT_TCP
+	}
+	// The size of the attributes tells the ABI 1 to 3 kernels, which don't
+	// know of the network, to read the filesystem accesses only.
+	size := unsafe.Sizeof(attr)
+	if abi < 4 {
+		size = unsafe.Sizeof(attr.Access_fs)
+	}
+	fd, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, uintptr(unsafe.Pointer(&attr)), size, 0)
+	if errno != 0 {
+		return fmt.Errorf("create ruleset: %w", errno)
+	}
+	defer unix.Close(int(fd))
+
+	readAccess := uint64(unix.LANDLOCK_ACCESS_FS_READ_FILE | unix.LANDLOCK_ACCESS_FS_READ_DIR)
+	writeAccess := readAccess | unix.LANDLOCK_ACCESS_FS_WRITE_FILE | unix.LANDLOCK_ACCESS_FS_MAKE_REG |
+		unix.LANDLOCK_ACCESS_FS_MAKE_DIR | unix.LANDLOCK_ACCESS_FS_REMOVE_FILE | unix.LANDLOCK_ACCESS_FS_TRUNCATE
+	for _, name := range read {
+		if err := landlockAllow(int(fd), name, readAccess&handled); err != nil {
+			return err
+		}
+	}
+	for _, name := range write {
+		if err := landlockAllow(int(fd), name, writeAccess&handled); err != nil {
+			return err
+		}
+	}
+	if _, _, errno := syscall.AllThreadsSyscall(unix.SYS_LANDLOCK_RESTRICT_SELF, fd, 0, 0); errno != 0 {
+		return fmt.Errorf("restrict threads: %w", errno)
+	}
+
+	return seccompDeny(sandboxSyscalls)
+}
+
+// landlockAllow adds the rule granting the access to the path, and beneath it
+// for a directory, to the ruleset. Missing paths are skipped.
+func landlockAllow(ruleset int, name string, access uint64) error {
+	fd, err := unix.Open(name, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if errors.Is(err, unix.ENOENT) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open %s: %w", name, err)
+	}
+	defer unix.Close(fd)
+
+	// Only the accesses to their content apply to files.
+	if info, err := os.Stat(name); err == nil && !info.IsDir() {
+		access &= unix.LANDLOCK_ACCESS_FS_READ_FILE | unix.LANDLOCK_ACCESS_FS_WRITE_FILE | unix.LANDLOCK_ACCESS_FS_TRUNCATE
+	}
+	rule := unix.LandlockPathBeneathAttr{Allowed_access: access, Parent_fd: int32(fd)}
+	if _, _, errno := unix.Syscall6(unix.SYS_LANDLOCK_ADD_RULE, uintptr(ruleset), unix.LANDLOCK_RULE_PATH_BENEATH, uintptr(unsafe.Pointer(&rule)), 0, 0, 0); errno != 0 {
+		return fmt.Errorf("allow %s: %w", name, errno)
+	}
+	return nil
+}
+
+// seccompDeny installs the seccomp filter failing the system calls with
+// EPERM on every thread, and killing the process on the system calls of
+// another architecture.
+func seccompDeny(syscalls []uintptr) error {
+	arch := uint32(unix.AUDIT_ARCH_X86_64)
+	if runtime.GOARCH == "arm64" {
+		arch = unix.AUDIT_ARCH_AARCH64
+	}
+
+	const (
+		ldAbs = unix.BPF_LD | unix.BPF_W | unix.BPF_ABS
+		jeq   = unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K
+		jge   = unix.BPF_JMP | unix.BPF_JGE | unix.BPF_K
+		ret   = unix.BPF_RET | unix.BPF_K
+	)
+	// The offsets of the number and the architecture of the system call in
+	// struct seccomp_data.
+	const nrOffset, archOffset = 0, 4
+
+	n := uint8(len(syscalls))
+	filter := []unix.SockFilter{
+		{Code: ldAbs, K: archOffset},
+		{Code: jeq, Jt: 1, K: arch},
+		{Code: ret, K: unix.SECCOMP_RET_KILL_PROCESS},
+		{Code: ldAbs, K: nrOffset},
+		{Code: jge, Jt: n + 2, K: x32SyscallBit},
+	}
+	for i, nr := range syscalls {
+		filter = append(filter, unix.SockFilter{Code: jeq, Jt: n - uint8(i), K: uint32(nr)})
+	}
+	filter = append(filter,
+		unix.SockFilter{Code: ret, K: unix.SECCOMP_RET_ALLOW},
+		unix.SockFilter{Code: ret, K: unix.SECCOMP_RET_ERRNO | uint32(unix.EPERM)},
+		unix.SockFilter{Code: ret, K: unix.SECCOMP_RET_KILL_PROCESS},
+	)
+
+	prog := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+	if _, _, errno := unix.Syscall(unix.SYS_SECCOMP, seccompSetModeFilter, unix.SECCOMP_FILTER_FLAG_TSYNC, uintptr(unsafe.Pointer(&prog))); errno != 0 {
+		return fmt.Errorf("install seccomp filter: %w", errno)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+//go:build linux && (amd64 || arm64)
+
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"unsafe"
+
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+// TestSandbox decrypts in a child process, the confinement lasting for the
+// rest of the life of the process. It is skipped where the process can't be
+// confined, unless SANDBOX_TEST_REQUIRED is set.
+func TestSandbox(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	if dir := os.Getenv("SANDBOX_TEST_DIR"); dir != "" {
+		if err := confinedDecrypt(dir); err != nil {
+			fmt.Fprint(os.Stderr, err)
+			if errors.Is(err, errSandboxUnsupported) {
+				os.Exit(3)
+			}
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	dir := t.TempDir()
+	writeOfflineCiphertext(t, dir, "the secret")
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "out"), 0o700))
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestSandbox$")
+	cmd.Env = append(os.Environ(), "SANDBOX_TEST_DIR="+dir)
+	out, err := cmd.CombinedOutput()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && exit.ExitCode() == 3 && os.Getenv("SANDBOX_TEST_REQUIRED") == "" {
+		t.Skip(string(out))
+	}
+	require.NoError(t, err, string(out))
+
+	plaintext, err := os.ReadFile(filepath.Join(dir, "out", "data.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "the secret", string(plaintext))
+	require.NoFileExists(t, filepath.Join(dir, "escaped"))
+}
+
+func TestSandboxPaths(t *testing.T) {
+	read, write := sandboxPaths(Flags{
+		Decrypt:     true,
+		Inputs:      []string{"in/data.tle"},
+		Attestation: "in/data.attestation",
+		Output:      "out/data.txt",
+	})
+	require.Subset(t, read, []string{"in/data.tle", "in/data.attestation"})
+	require.Equal(t, []string{"out"}, write)
+}
+
+func TestValidateSandboxFlags(t *testing.T) {
+	require.NoError(t, validateSandboxFlags(&Flags{Decrypt: true, Sandbox: true, Inputs: []string{"data.tle"}}))
+	require.NoError(t, validateSandboxFlags(&Flags{Decrypt: true, Sandbox: true, Beacons: "rounds.beacon"}))
+
+	// The round of the standard input is unknown until it is read.
+	require.Error(t, validateSandboxFlags(&Flags{Decrypt: true, Sandbox: true}))
+	require.Error(t, validateSandboxFlags(&Flags{Encrypt: true, Sandbox: true, Inputs: []string{"data.txt"}}))
+}
+
+// confinedDecrypt decrypts the ciphertext of the directory under --sandbox,
+// then checks that neither the network nor the rest of the filesystem is
+// reachable.
+func confinedDecrypt(dir string) error {
+	flags := DefaultFlags()
+	flags.Decrypt = true
+	flags.Sandbox = true
+	flags.Beacons = filepath.Join(dir, "rounds.beacon")
+	flags.Inputs = []string{filepath.Join(dir, "data.tle")}
+	flags.Output = filepath.Join(dir, "out", "data.txt")
+	if err := (Operation{Flags: flags}).Run(context.Background()); err != nil {
+		return err
+	}
+
+	if conn, err := net.Dial("tcp", "127.0.0.1:1"); err == nil || !errors.Is(err, os.ErrPermission) {
+		if conn != nil {
+			conn.Close()
+		}
+		return fmt.Errorf("dialing isn't denied: %v", err)
+	}
+	var params [120]byte // struct io_uring_params
+	if _, _, errno := unix.Syscall(unix.SYS_IO_URING_SETUP, 1, uintptr(unsafe.Pointer(&params)), 0); errno != unix.EPERM {
+		return fmt.Errorf("io_uring isn't denied: %v", errno)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "escaped"), nil, 0o600); err == nil {
+		return errors.New("writing outside of the output directory isn't denied")
+	}
+	if _, err := os.ReadFile(flags.Beacons); err == nil {
+		return errors.New("reading the beacons once confined isn't denied")
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux || !(amd64 || arm64)
+
+package commands
+
+import "fmt"
+
+// sandbox isn't supported on this system.
+func sandbox(read []string, write []string) error {
+	return fmt.Errorf("%w on this system", errSandboxUnsupported)
+}
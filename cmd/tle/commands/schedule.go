@@ -0,0 +1,332 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/JonathanLogan/tlock"
+)
+
+// scheduleCommand defines the schedule subcommand.
+var scheduleCommand = CommandSpec{
+	Name: "schedule",
+	Synopsis: []string{
+		`tle schedule [-n NETWORK] [-c CHAIN] [-o OUTPUT] [--install-systemd [--unit-dir DIR] | --install-scheduled-task] INPUT`,
+	},
+	Flags: []FlagSpec{
+		mainFlag("network", func(f *ScheduleFlags) any { return &f.Network }),
+		mainFlag("chain", func(f *ScheduleFlags) any { return &f.Chain }),
+		{Name: "output", Short: "o", Arg: "OUTPUT", Description: "Decrypt INPUT to the file at path OUTPUT.", value: field(func(f *ScheduleFlags) any { return &f.Output })},
+		{Name: "install-systemd", Description: "Write the systemd units to DIR instead of printing them.", value: field(func(f *ScheduleFlags) any { return &f.InstallSystemd })},
+		{Name: "unit-dir", Arg: "DIR", Description: "The directory the systemd units are written to.", value: field(func(f *ScheduleFlags) any { return &f.UnitDir })},
+		{Name: "install-scheduled-task", Description: "Register a Windows scheduled task instead of printing it.", value: field(func(f *ScheduleFlags) any { return &f.InstallTask })},
+	},
+	notes: `Computes when the ciphertext INPUT unlocks and prints a systemd service and
+timer decrypting it to OUTPUT at that time, or on Windows the definition of a
+Task Scheduler task doing so. With --install-systemd, the units are written to
+DIR instead, ready to be enabled with systemctl --user. With
+--install-scheduled-task, the task is registered using schtasks.
+
+OUTPUT defaults to INPUT without its .tle or .age extension. DIR defaults to
+the systemd user unit directory, ~/.config/systemd/user.`,
+	defaults: func() any { return &ScheduleFlags{Network: DefaultNetwork, Chain: DefaultChain} },
+}
+
+// unitNameUnsafe matches the characters not kept in unit names.
+var unitNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// ScheduleFlags represent the values from the schedule command line.
+type ScheduleFlags struct {
+	Network        string
+	Chain          string
+	Output         string
+	InstallSystemd bool
+	UnitDir        string
+	InstallTask    bool
+	Input          string
+}
+
+// ParseSchedule parses the arguments following the schedule subcommand.
+func ParseSchedule(args []string) (ScheduleFlags, error) {
+	f, fs, err := parseCommand[ScheduleFlags]("schedule", args)
+	if err != nil {
+		return ScheduleFlags{}, err
+	}
+	if fs.NArg() != 1 || f.InstallSystemd && f.InstallTask {
+		return ScheduleFlags{}, errors.New(commandUsage("schedule"))
+	}
+	if f.UnitDir != "" && !f.InstallSystemd {
+		return ScheduleFlags{}, errors.New("--unit-dir can only be used with --install-systemd")
+	}
+	f.Input = fs.Arg(0)
+
+	return f, nil
+}
+
+// Schedule describes the decryption of a ciphertext once it unlocks.
+type Schedule struct {
+	Name   string
+	Round  uint64
+	Unlock time.Time
+	Input  string
+	Output string
+}
+
+// NewSchedule computes the schedule of the decryption of the input of the
+// flags, the paths of which are made absolute.
+func NewSchedule(flags ScheduleFlags, network tlock.Network) (Schedule, error) {
+	rounds, err := CiphertextRounds(flags.Input, "", network)
+	if err != nil {
+		return Schedule{}, err
+	}
+
+	// Any of the rounds unlocking the ciphertext does, the earliest being
+	// scheduled.
+	i := slices.IndexFunc(rounds, func(r tlock.RoundInfo) bool {
+		return !r.NotAfter && !r.Earliest.IsZero()
+	})
+	if i < 0 {
+		return Schedule{}, errors.New("the network doesn't expose the time of the rounds of the ciphertext")
+	}
+	roundNumber, unlock := rounds[i].Round, rounds[i].Earliest
+
+	output := flags.Output
+	if output == "" {
+		if output, err = OutputPath(Flags{Decrypt: true, OutDir: filepath.Dir(flags.Input)}, flags.Input, roundNumber); err != nil {
+			return Schedule{}, err
+		}
+	}
+
+	s := Schedule{Round: roundNumber, Unlock: unlock}
+	if s.Input, err = filepath.Abs(flags.Input); err != nil {
+		return Schedule{}, err
+	}
+	if s.Output, err = filepath.Abs(output); err != nil {
+		return Schedule{}, err
+	}
+
+	stem := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(flags.Input), ".tle"), ".age")
+	s.Name = fmt.Sprintf("tle-unlock-%s-%d", strings.Trim(unitNameUnsafe.ReplaceAllString(stem, "-"), "-"), roundNumber)
+
+	return s, nil
+}
+
+// Command returns the arguments of the tle invocation decrypting the input.
+func (s Schedule) Command(executable string, flags ScheduleFlags) []string {
+	return []string{executable, "--decrypt", "--network", flags.Network, "--chain", flags.Chain, "--output", s.Output, s.Input}
+}
+
+// SystemdUnits returns the service and timer units decrypting the input once
+// it unlocks. The service is retried while the network lags behind.
+func (s Schedule) SystemdUnits(executable string, flags ScheduleFlags) (service string, timer string) {
+	args := s.Command(executable, flags)
+	for i, arg := range args {
+		args[i] = systemdQuote(arg)
+	}
+
+	service = fmt.Sprintf(`[Unit]
+Description=Decrypt %s once round %d is reached
+
+[Service]
+Type=oneshot
+ExecStart=%s
+Restart=on-failure
+RestartSec=30s
+`, systemdText(s.Input), s.Round, strings.Join(args, " "))
+
+	timer = fmt.Sprintf(`[Unit]
+Description=Decrypt %s at %s
+
+[Timer]
+OnCalendar=%s
+Persistent=true
+AccuracySec=1s
+Unit=%s.service
+
+[Install]
+WantedBy=timers.target
+`, systemdText(s.Input), s.Unlock.UTC().Format(time.RFC3339), s.Unlock.UTC().Format("2006-01-02 15:04:05 UTC"), s.Name)
+
+	return service, timer
+}
+
+// InstallSystemd writes the units of the schedule into the directory and
+// returns the path of the timer.
+func (s Schedule) InstallSystemd(dir string, executable string, flags ScheduleFlags) (string, error) {
+	if dir == "" {
+		config, err := os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(config, "systemd", "user")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	service, timer := s.SystemdUnits(executable, flags)
+	if err := os.WriteFile(filepath.Join(dir, s.Name+".service"), []byte(service), 0644); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, s.Name+".timer")
+	if err := os.WriteFile(path, []byte(timer), 0644); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// TaskXML returns the definition of the Task Scheduler task decrypting the
+// input once it unlocks, encoded in UTF-16 as schtasks expects. The task is
+// retried while the network lags behind.
+func (s Schedule) TaskXML(executable string, flags ScheduleFlags) ([]byte, error) {
+	args := s.Command(executable, flags)
+	for i, arg := range args[1:] {
+		args[i+1] = windowsQuote(arg)
+	}
+
+	task := scheduledTask{
+		Version:     "1.2",
+		Description: fmt.Sprintf("Decrypt %s once round %d is reached", s.Input, s.Round),
+		Trigger:     taskTrigger{StartBoundary: s.Unlock.UTC().Format(time.RFC3339), Enabled: true},
+		Settings: taskSettings{
+			StartWhenAvailable:         true,
+			DisallowStartIfOnBatteries: false,
+			StopIfGoingOnBatteries:     false,
+			MultipleInstancesPolicy:    "IgnoreNew",
+			RestartOnFailure:           taskRestart{Interval: "PT1M", Count: 30},
+		},
+		Actions: taskActions{Context: "Author", Exec: taskExec{Command: args[0], Arguments: strings.Join(args[1:], " ")}},
+	}
+	b, err := xml.MarshalIndent(task, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	doc := `<?xml version="1.0" encoding="UTF-16"?>` + "\n" + string(b) + "\n"
+
+	// The document starts with a byte order mark, in little endian.
+	out := []byte{0xff, 0xfe}
+	for _, c := range utf16.Encode([]rune(doc)) {
+		out = append(out, byte(c), byte(c>>8))
+	}
+	return out, nil
+}
+
+// InstallScheduledTask registers the task of the schedule for the current
+// user, using the schtasks command of Windows.
+func (s Schedule) InstallScheduledTask(executable string, flags ScheduleFlags) error {
+	task, err := s.TaskXML(executable, flags)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp("", s.Name+"-*.xml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(task); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	out, err := exec.Command("schtasks", "/Create", "/TN", `\tle\`+s.Name, "/XML", f.Name()).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("schtasks: %w: %s", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// =============================================================================
+
+// These are the elements of the Task Scheduler task definition used.
+type (
+	scheduledTask struct {
+		XMLName     xml.Name     `xml:"http://schemas.microsoft.com/windows/2004/02/mit/task Task"`
+		Version     string       `xml:"version,attr"`
+		Description string       `xml:"RegistrationInfo>Description"`
+		Trigger     taskTrigger  `xml:"Triggers>TimeTrigger"`
+		Settings    taskSettings `xml:"Settings"`
+		Actions     taskActions  `xml:"Actions"`
+	}
+	taskTrigger struct {
+		StartBoundary string
+		Enabled       bool
+	}
+	taskSettings struct {
+		StartWhenAvailable         bool
+		DisallowStartIfOnBatteries bool
+		StopIfGoingOnBatteries     bool
+		MultipleInstancesPolicy    string
+		RestartOnFailure           taskRestart
+	}
+	taskRestart struct {
+		Interval string
+		Count    int
+	}
+	taskActions struct {
+		Context string   `xml:",attr"`
+		Exec    taskExec `xml:"Exec"`
+	}
+	taskExec struct {
+		Command   string
+		Arguments string
+	}
+)
+
+// windowsQuote quotes a command line argument as parsed by the Windows C
+// runtime, where backslashes are only special ahead of a double quote.
+func windowsQuote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\"") {
+		return arg
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	backslashes := 0
+	for _, c := range arg {
+		switch c {
+		case '\\':
+			backslashes++
+			continue
+		case '"':
+			b.WriteString(strings.Repeat(`\`, 2*backslashes+1))
+		default:
+			b.WriteString(strings.Repeat(`\`, backslashes))
+		}
+		backslashes = 0
+		b.WriteRune(c)
+	}
+	b.WriteString(strings.Repeat(`\`, 2*backslashes))
+	b.WriteByte('"')
+	return b.String()
+}
+
+// systemdQuote quotes a command line argument for ExecStart, escaping the
+// specifier and variable expansions.
+func systemdQuote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n\"'\\%$;") {
+		return arg
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "%", "%%", "$", "$$")
+	return `"` + r.Replace(arg) + `"`
+}
+
+// systemdText escapes the specifier expansions in the text of a setting such
+// as Description, which ends at the first newline.
+func systemdText(text string) string {
+	return strings.NewReplacer("%", "%%", "\n", " ").Replace(text)
+}
@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchedule(t *testing.T) {
+	f, err := ParseSchedule([]string{"--install-systemd", "secret.tle"})
+	require.NoError(t, err)
+	require.Equal(t, ScheduleFlags{Network: DefaultNetwork, Chain: DefaultChain, InstallSystemd: true, Input: "secret.tle"}, f)
+
+	_, err = ParseSchedule(nil)
+	require.Error(t, err)
+	_, err = ParseSchedule([]string{"--unit-dir", "units", "secret.tle"})
+	require.Error(t, err)
+	_, err = ParseSchedule([]string{"--install-systemd", "--install-scheduled-task", "secret.tle"})
+	require.Error(t, err)
+}
+
+func TestSchedule(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+	dir := t.TempDir()
+	input := filepath.Join(dir, "my secret.txt.tle")
+
+	var cipherData bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&cipherData, bytes.NewReader([]byte("hello")), 1000))
+	require.NoError(t, os.WriteFile(input, cipherData.Bytes(), 0600))
+
+	flags := ScheduleFlags{Network: DefaultNetwork, Chain: DefaultChain, Input: input}
+	s, err := NewSchedule(flags, network)
+	require.NoError(t, err)
+	require.Equal(t, "tle-unlock-my-secret.txt-1000", s.Name)
+	require.Equal(t, filepath.Join(dir, "my secret.txt"), s.Output)
+	unlock, _ := tlock.RoundTime(network, 1000)
+	require.Equal(t, unlock, s.Unlock)
+
+	service, timer := s.SystemdUnits("/usr/bin/tle", flags)
+	require.Contains(t, service, `ExecStart=/usr/bin/tle --decrypt --network https://api.drand.sh/ --chain `+DefaultChain+` --output "`+filepath.Join(dir, "my secret.txt")+`" "`+input+`"`)
+	require.Contains(t, timer, "OnCalendar="+unlock.UTC().Format("2006-01-02 15:04:05")+" UTC\n")
+	require.Contains(t, timer, "Unit=tle-unlock-my-secret.txt-1000.service\n")
+
+	units := filepath.Join(dir, "units")
+	path, err := s.InstallSystemd(units, "/usr/bin/tle", flags)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(units, s.Name+".timer"), path)
+	b, err := os.ReadFile(filepath.Join(units, s.Name+".service"))
+	require.NoError(t, err)
+	require.Equal(t, service, string(b))
+}
+
+func TestTaskXML(t *testing.T) {
+	s := Schedule{
+		Name:   "tle-unlock-report-1000",
+		Round:  1000,
+		Unlock: time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC),
+		Input:  `C:\Users\me\report & notes.tle`,
+		Output: `C:\Users\me\report & notes`,
+	}
+	b, err := s.TaskXML(`C:\Program Files\tle\tle.exe`, ScheduleFlags{Network: DefaultNetwork, Chain: DefaultChain})
+	require.NoError(t, err)
+	require.Equal(t, []byte{0xff, 0xfe}, b[:2])
+
+	u := make([]uint16, 0, len(b)/2)
+	for i := 2; i < len(b); i += 2 {
+		u = append(u, uint16(b[i])|uint16(b[i+1])<<8)
+	}
+	task := string(utf16.Decode(u))
+	require.True(t, strings.HasPrefix(task, `<?xml version="1.0" encoding="UTF-16"?>`))
+	require.Contains(t, task, "<StartBoundary>2030-01-02T03:04:05Z</StartBoundary>")
+	require.Contains(t, task, `<Command>C:\Program Files\tle\tle.exe</Command>`)
+	require.Contains(t, task, `--output &#34;C:\Users\me\report &amp; notes&#34; &#34;C:\Users\me\report &amp; notes.tle&#34;</Arguments>`)
+}
+
+func TestWindowsQuote(t *testing.T) {
+	require.Equal(t, `C:\dir\a.tle`, windowsQuote(`C:\dir\a.tle`))
+	require.Equal(t, `"C:\my dir\\"`, windowsQuote(`C:\my dir\`))
+	require.Equal(t, `"say \"hi\\\""`, windowsQuote(`say "hi\"`))
+	require.Equal(t, `""`, windowsQuote(""))
+}
+
+func TestSystemdQuote(t *testing.T) {
+	require.Equal(t, "/tmp/a.tle", systemdQuote("/tmp/a.tle"))
+	require.Equal(t, `"100%% \"sure\" $$HOME"`, systemdQuote(`100% "sure" $HOME`))
+	require.Equal(t, `""`, systemdQuote(""))
+	require.Equal(t, "100%% sure .tle", systemdText("100% sure\n.tle"))
+
+	s := Schedule{Name: "tle-unlock-100-1000", Round: 1000, Input: "/tmp/100%.tle", Output: "/tmp/100%"}
+	service, timer := s.SystemdUnits("/usr/bin/tle", ScheduleFlags{Network: DefaultNetwork, Chain: DefaultChain})
+	require.Contains(t, service, "Description=Decrypt /tmp/100%%.tle once round 1000 is reached\n")
+	require.Contains(t, timer, "Description=Decrypt /tmp/100%%.tle at ")
+}
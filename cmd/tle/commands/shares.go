@@ -0,0 +1,170 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/JonathanLogan/tlock"
+)
+
+// combineCommand defines the combine subcommand.
+var combineCommand = CommandSpec{
+	Name: "combine",
+	Synopsis: []string{
+		`tle combine [-o OUTPUT] SHARE...`,
+	},
+	Flags: []FlagSpec{
+		{Name: "output", Short: "o", Arg: "OUTPUT", Description: "Write the ciphertext to the file at path OUTPUT.", value: field(func(f *CombineFlags) any { return &f.Output })},
+	},
+	notes: `Reassembles the ciphertext split with --shares from enough of its shares, the
+files OUTPUT.1 to OUTPUT.N written by the encryption, given in any order. The
+ciphertext written to OUTPUT then decrypts as any other:
+	tle --decrypt [-o OUTPUT] [INPUT]`,
+	defaults: func() any { return &CombineFlags{} },
+}
+
+// CombineFlags represent the values from the combine command line.
+type CombineFlags struct {
+	Output string
+	Shares []string
+}
+
+// ParseCombine parses the arguments following the combine subcommand.
+func ParseCombine(args []string) (CombineFlags, error) {
+	f, fs, err := parseCommand[CombineFlags]("combine", args)
+	if err != nil {
+		return CombineFlags{}, err
+	}
+	if fs.NArg() == 0 {
+		return CombineFlags{}, errors.New(commandUsage("combine"))
+	}
+	f.Shares = fs.Args()
+
+	return f, nil
+}
+
+// Combine writes the ciphertext reassembled from the shares of the flags to
+// their output, or to stdout.
+func Combine(stdout *os.File, flags CombineFlags) (err error) {
+	srcs := make([]io.Reader, len(flags.Shares))
+	for i, name := range flags.Shares {
+		f, err := os.Open(name)
+		if err != nil {
+			return fmt.Errorf("failed to open share %q: %v", name, err)
+		}
+		defer f.Close()
+		srcs[i] = f
+	}
+	src, err := tlock.NewShareReader(srcs)
+	if err != nil {
+		return err
+	}
+
+	var dst io.Writer = stdout
+	if flags.Output != "" && flags.Output != "-" {
+		o, err := CreateOutput(flags.Output)
+		if err != nil {
+			return err
+		}
+		defer func() { err = o.Finish(err) }()
+		dst = o
+	} else if err := CheckTerminal(stdout, true, false); err != nil {
+		return err
+	}
+
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+// =============================================================================
+
+// shareOutputs are the outputs of the shares of a ciphertext, split as it is
+// written.
+type shareOutputs struct {
+	io.WriteCloser
+	outputs []*Output
+	entropy *os.File // Source of the randomness of --entropy, if any.
+}
+
+// createShares creates the outputs name.1 to name.n of the shares of the
+// ciphertext of --shares, any --threshold of which reassemble it. The shares
+// draw their randomness from the source of --entropy, if any.
+func createShares(name string, flags Flags, network tlock.Network) (*shareOutputs, error) {
+	s := shareOutputs{}
+	t := tlock.New(network)
+	if flags.Entropy != "" {
+		f, err := openEntropy(flags.Entropy)
+		if err != nil {
+			return nil, err
+		}
+		s.entropy = f
+		t = t.WithRandom(f)
+	}
+
+	n, threshold := flags.Shares, flags.Threshold
+	dsts := make([]io.Writer, n)
+	for i := range n {
+		o, err := CreateOutput(name + "." + strconv.Itoa(i+1))
+		if err != nil {
+			s.finish(err)
+			return nil, err
+		}
+		s.outputs = append(s.outputs, o)
+		dsts[i] = o
+	}
+
+	w, err := t.NewShareWriter(dsts, threshold)
+	if err != nil {
+		s.finish(err)
+		return nil, err
+	}
+	s.WriteCloser = w
+	return &s, nil
+}
+
+// finish closes the shares, writing their checksums, and finishes their
+// outputs, which are all removed on error.
+func (s *shareOutputs) finish(err error) error {
+	if err == nil && s.WriteCloser != nil {
+		err = s.Close()
+	}
+	errs := []error{err}
+	for _, o := range s.outputs {
+		errs = append(errs, o.Finish(err))
+	}
+	if s.entropy != nil {
+		s.entropy.Close()
+	}
+	return errors.Join(errs...)
+}
+
+// validateSharesFlags checks the flags splitting the ciphertext into shares.
+func validateSharesFlags(f *Flags) error {
+	if f.Threshold != 0 && f.Shares == 0 {
+		return errors.New("--threshold can only be used with --shares")
+	}
+	if f.Shares == 0 {
+		return nil
+	}
+	if f.Threshold == 0 {
+		f.Threshold = f.Shares
+	}
+	switch {
+	case !f.Encrypt:
+		return errors.New("--shares can only be used with -e/--encrypt")
+	case f.Shares < 2 || f.Shares > tlock.MaxShares:
+		return fmt.Errorf("--shares must be between 2 and %d", tlock.MaxShares)
+	case f.Threshold < 2 || f.Threshold > f.Shares:
+		return errors.New("--threshold must be between 2 and --shares")
+	case f.Output == "" || f.Output == "-":
+		return errors.New("--shares requires -o/--output, the name of the shares")
+	case f.OutDir != "":
+		return errors.New("--shares can't be used with --out-dir")
+	case f.Daemon != "":
+		return errors.New("--shares can't be used with --daemon")
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/JonathanLogan/tlock/networks/http"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShares(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	// Encrypting doesn't call the relay, which is never reached.
+	network, err := http.NewOfflineNetwork("http://127.0.0.1:1", DefaultChain, 3*time.Second, 1692803367)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "secret.txt")
+	require.NoError(t, os.WriteFile(input, []byte("the secret"), 0600))
+
+	flags := DefaultFlags()
+	flags.Encrypt = true
+	flags.Round = 1 << 40
+	flags.Inputs = []string{input}
+	flags.Output = filepath.Join(dir, "secret.tle")
+	flags.Shares, flags.Threshold = 3, 2
+	require.NoError(t, Operation{Flags: flags, Network: network}.Run(context.Background()))
+	require.NoFileExists(t, flags.Output)
+
+	// The shares of failed encryptions are removed.
+	failed := flags
+	failed.Round, failed.Output = 1, filepath.Join(dir, "failed.tle")
+	require.Error(t, Operation{Flags: failed, Network: network}.Run(context.Background()))
+	for i := 1; i <= 3; i++ {
+		require.NoFileExists(t, failed.Output+"."+strconv.Itoa(i))
+		require.NoFileExists(t, failed.Output+"."+strconv.Itoa(i)+".partial")
+	}
+
+	// Any two shares reassemble the ciphertext.
+	combined := filepath.Join(dir, "combined.tle")
+	require.NoError(t, Combine(nil, CombineFlags{Output: combined, Shares: []string{flags.Output + ".3", flags.Output + ".1"}}))
+	roundNumber, err := CiphertextRound(combined, "")
+	require.NoError(t, err)
+	require.Equal(t, uint64(1<<40), roundNumber)
+
+	err = Combine(nil, CombineFlags{Output: combined, Shares: []string{flags.Output + ".2"}})
+	require.ErrorIs(t, err, tlock.ErrIncompatibleShares)
+}
+
+func TestParseCombine(t *testing.T) {
+	f, err := ParseCombine([]string{"-o", "secret.tle", "secret.tle.1", "secret.tle.2"})
+	require.NoError(t, err)
+	require.Equal(t, "secret.tle", f.Output)
+	require.Equal(t, []string{"secret.tle.1", "secret.tle.2"}, f.Shares)
+
+	_, err = ParseCombine(nil)
+	require.Error(t, err)
+}
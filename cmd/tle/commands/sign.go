@@ -0,0 +1,201 @@
+package commands
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/JonathanLogan/tlock"
+)
+
+// signCommand defines the sign subcommand.
+var signCommand = CommandSpec{
+	Name: "sign",
+	Synopsis: []string{
+		`tle sign --key KEY [-o OUTPUT] [INPUT]`,
+	},
+	Flags: []FlagSpec{
+		{Name: "key", Arg: "KEY", Description: "Sign with the Ed25519 private key in the PEM file KEY.", value: field(func(f *SignFlags) any { return &f.Key })},
+		{Name: "output", Short: "o", Arg: "OUTPUT", Description: "Write the signature to the file at path OUTPUT, INPUT.sig by default.", value: field(func(f *SignFlags) any { return &f.Output })},
+	},
+	notes: `Signs the ciphertext INPUT with the Ed25519 private key in the PEM file KEY,
+writing the detached signature to OUTPUT, which defaults to INPUT.sig. The
+signature covers the header and every chunk of the ciphertext, and can be
+checked with tle verify before the ciphertext unlocks. A key pair can be
+generated with:
+    $ openssl genpkey -algorithm ed25519 -out key.pem
+    $ openssl pkey -in key.pem -pubout -out key.pub`,
+	defaults: func() any { return &SignFlags{} },
+}
+
+// verifyCommand defines the verify subcommand.
+var verifyCommand = CommandSpec{
+	Name: "verify",
+	Synopsis: []string{
+		`tle verify --signer KEY [--signature SIGNATURE] [--principal PRINCIPAL] [--namespace NAMESPACE] [INPUT]`,
+		`tle verify --fast [INPUT]`,
+	},
+	Flags: []FlagSpec{
+		{Name: "signer", Arg: "KEY", Description: "The public key of the author, or the allowed signers file.", value: field(func(f *VerifyFlags) any { return &f.Signer })},
+		{Name: "signature", Arg: "SIGNATURE", Description: "Read the signature from the file at path SIGNATURE, INPUT.sig by default.", value: field(func(f *VerifyFlags) any { return &f.Signature })},
+		{Name: "principal", Arg: "PRINCIPAL", Description: "The principal the ssh key must be allowed for.", value: field(func(f *VerifyFlags) any { return &f.Principal })},
+		{Name: "namespace", Arg: "NAMESPACE", Description: "The namespace of the ssh signature.", value: field(func(f *VerifyFlags) any { return &f.Namespace })},
+		{Name: "fast", Description: "Check the chunk checksums rather than a signature.", value: field(func(f *VerifyFlags) any { return &f.Fast })},
+	},
+	notes: `Checks that the ciphertext INPUT was signed by its author, SIGNATURE defaulting
+to INPUT.sig. The kind of SIGNATURE tells what KEY holds:
+  - for tle sign, the Ed25519 public key of the author in a PEM file;
+  - for minisign -S, the minisign public key file of the author;
+  - for ssh-keygen -Y sign, an allowed signers file as read by ssh-keygen,
+    which must allow the key for PRINCIPAL when given. NAMESPACE defaults to
+    file, the one ssh-keygen is told with -n.
+
+With --fast, the chunks of the convergent ciphertext INPUT are rather checked
+against the CRC32C recorded next to them, finding corruption in large
+archives without the network or any decryption. This doesn't authenticate
+them, and ciphertexts without checksums, such as age ones, fail.`,
+	defaults: func() any { return &VerifyFlags{Namespace: DefaultSSHNamespace} },
+}
+
+// SignFlags represent the values from the sign command line.
+type SignFlags struct {
+	Key    string
+	Output string
+	Input  string
+}
+
+// ParseSign parses the arguments following the sign subcommand.
+func ParseSign(args []string) (SignFlags, error) {
+	f, fs, err := parseCommand[SignFlags]("sign", args)
+	if err != nil {
+		return SignFlags{}, err
+	}
+	if f.Key == "" || fs.NArg() > 1 {
+		return SignFlags{}, errors.New(commandUsage("sign"))
+	}
+	f.Input = fs.Arg(0)
+	if f.Output == "" && f.Input != "" && f.Input != "-" {
+		f.Output = f.Input + ".sig"
+	}
+
+	return f, nil
+}
+
+// Sign returns the detached signature of the ciphertext read from src.
+func Sign(flags SignFlags, src io.Reader) ([]byte, error) {
+	key, err := loadSigningKey(flags.Key)
+	if err != nil {
+		return nil, err
+	}
+	return tlock.Sign(src, key)
+}
+
+// VerifyFlags represent the values from the verify command line.
+type VerifyFlags struct {
+	Signer    string
+	Signature string
+	Principal string
+	Namespace string
+	Fast      bool
+	Input     string
+}
+
+// ParseVerify parses the arguments following the verify subcommand.
+func ParseVerify(args []string) (VerifyFlags, error) {
+	f, fs, err := parseCommand[VerifyFlags]("verify", args)
+	if err != nil {
+		return VerifyFlags{}, err
+	}
+	if (f.Signer == "" && !f.Fast) || (f.Signer != "" && f.Fast) || fs.NArg() > 1 {
+		return VerifyFlags{}, errors.New(commandUsage("verify"))
+	}
+	f.Input = fs.Arg(0)
+	if f.Fast {
+		return f, nil
+	}
+	if f.Signature == "" {
+		if f.Input == "" || f.Input == "-" {
+			return VerifyFlags{}, errors.New("--signature is required when reading the standard input")
+		}
+		f.Signature = f.Input + ".sig"
+	}
+
+	return f, nil
+}
+
+// Verify checks the signature of the ciphertext read from src, which may be a
+// tle, minisign or SSH signature. It returns the signer, which is the
+// principals of the key for SSH signatures and the key file otherwise.
+func Verify(flags VerifyFlags, src io.Reader) (string, error) {
+	signature, err := os.ReadFile(flags.Signature)
+	if err != nil {
+		return "", fmt.Errorf("read signature: %w", err)
+	}
+
+	switch trimmed := bytes.TrimSpace(signature); {
+	case bytes.HasPrefix(trimmed, []byte("-----BEGIN "+sshsigPEMType+"-----")):
+		return verifySSH(flags.Signer, flags.Principal, flags.Namespace, signature, src)
+	case bytes.HasPrefix(trimmed, []byte(minisignUntrusted)):
+		return flags.Signer, verifyMinisign(flags.Signer, signature, src)
+	}
+
+	key, err := loadSignerKey(flags.Signer)
+	if err != nil {
+		return "", err
+	}
+	return flags.Signer, tlock.VerifySignature(src, signature, key)
+}
+
+// =============================================================================
+
+// loadSigningKey reads the Ed25519 private key from the PKCS #8 PEM file.
+func loadSigningKey(path string) (ed25519.PrivateKey, error) {
+	der, err := readPEM(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("parse key %q: %w", path, err)
+	}
+	ed, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("key %q is not an Ed25519 key", path)
+	}
+	return ed, nil
+}
+
+// loadSignerKey reads the Ed25519 public key from the PKIX PEM file.
+func loadSignerKey(path string) (ed25519.PublicKey, error) {
+	der, err := readPEM(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("parse key %q: %w", path, err)
+	}
+	ed, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("key %q is not an Ed25519 key", path)
+	}
+	return ed, nil
+}
+
+// readPEM returns the contents of the PEM block of the type in the file.
+func readPEM(path, blockType string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read key: %w", err)
+	}
+	block, _ := pem.Decode(b)
+	if block == nil || block.Type != blockType {
+		return nil, fmt.Errorf("key %q is not a PEM encoded %s", path, blockType)
+	}
+	return block.Bytes, nil
+}
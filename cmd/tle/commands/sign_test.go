@@ -0,0 +1,183 @@
+package commands
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSignVerify(t *testing.T) {
+	ciphertext, err := os.ReadFile("../test.dat.tle")
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	keyFile, pubFile := writeKeyPair(t, dir, "author")
+	_, otherFile := writeKeyPair(t, dir, "other")
+
+	input := filepath.Join(dir, "test.dat.tle")
+	require.NoError(t, os.WriteFile(input, ciphertext, 0600))
+
+	sign, err := ParseSign([]string{"--key", keyFile, input})
+	require.NoError(t, err)
+	require.Equal(t, input+".sig", sign.Output)
+	signature, err := Sign(sign, bytes.NewReader(ciphertext))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(sign.Output, signature, 0600))
+
+	verify, err := ParseVerify([]string{"--signer", pubFile, input})
+	require.NoError(t, err)
+	require.Equal(t, input+".sig", verify.Signature)
+	signer, err := Verify(verify, bytes.NewReader(ciphertext))
+	require.NoError(t, err)
+	require.Equal(t, pubFile, signer)
+	_, err = Verify(verify, bytes.NewReader(append(ciphertext, 0)))
+	require.ErrorIs(t, err, tlock.ErrBadSignature)
+
+	verify, err = ParseVerify([]string{"--signer", otherFile, input})
+	require.NoError(t, err)
+	_, err = Verify(verify, bytes.NewReader(ciphertext))
+	require.ErrorIs(t, err, tlock.ErrBadSignature)
+
+	// The keys must be of the right kind.
+	_, err = Sign(SignFlags{Key: pubFile}, bytes.NewReader(ciphertext))
+	require.Error(t, err)
+
+	_, err = ParseVerify([]string{"--signer", pubFile})
+	require.Error(t, err)
+	_, err = ParseVerify([]string{"--fast", "--signer", pubFile, input})
+	require.Error(t, err)
+	verify, err = ParseVerify([]string{"--fast", input})
+	require.NoError(t, err)
+	require.True(t, verify.Fast)
+	_, err = ParseSign([]string{input})
+	require.Error(t, err)
+}
+
+// writeKeyPair writes an Ed25519 key pair in the PEM files generated by
+// openssl, returning their paths.
+func writeKeyPair(t *testing.T, dir, name string) (string, string) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+
+	keyFile := filepath.Join(dir, name+".pem")
+	pubFile := filepath.Join(dir, name+".pub")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}), 0600))
+	require.NoError(t, os.WriteFile(pubFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0600))
+	return keyFile, pubFile
+}
+
+func TestVerifyMinisign(t *testing.T) {
+	ciphertext, err := os.ReadFile("../test.dat.tle")
+	require.NoError(t, err)
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	keyID := []byte("12345678")
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "minisign.pub")
+	key := base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), keyID...), pub...))
+	require.NoError(t, os.WriteFile(keyFile, []byte("untrusted comment: minisign public key 3837363534333231\n"+key+"\n"), 0600))
+
+	digest := blake2b.Sum512(ciphertext)
+	for algorithm, msg := range map[string][]byte{"ED": digest[:], "Ed": ciphertext} {
+		sig := append(append([]byte(algorithm), keyID...), ed25519.Sign(priv, msg)...)
+		trusted := "timestamp:1700000000\tfile:test.dat.tle"
+		globalSig := ed25519.Sign(priv, append(sig[len(sig)-ed25519.SignatureSize:], trusted...))
+		sigFile := filepath.Join(dir, algorithm+".minisig")
+		require.NoError(t, os.WriteFile(sigFile, []byte(fmt.Sprintf("untrusted comment: signature from minisign secret key\n%s\ntrusted comment: %s\n%s\n",
+			base64.StdEncoding.EncodeToString(sig), trusted, base64.StdEncoding.EncodeToString(globalSig))), 0600))
+
+		flags, err := ParseVerify([]string{"--signer", keyFile, "--signature", sigFile, "-"})
+		require.NoError(t, err)
+		_, err = Verify(flags, bytes.NewReader(ciphertext))
+		require.NoError(t, err, algorithm)
+		_, err = Verify(flags, bytes.NewReader(append(ciphertext, 0)))
+		require.ErrorIs(t, err, tlock.ErrBadSignature, algorithm)
+
+		// The trusted comment can't be altered.
+		require.NoError(t, os.WriteFile(sigFile, []byte(fmt.Sprintf("untrusted comment: signature from minisign secret key\n%s\ntrusted comment: %s\n%s\n",
+			base64.StdEncoding.EncodeToString(sig), "forged", base64.StdEncoding.EncodeToString(globalSig))), 0600))
+		_, err = Verify(flags, bytes.NewReader(ciphertext))
+		require.ErrorIs(t, err, tlock.ErrBadSignature, algorithm)
+	}
+}
+
+func TestVerifySSH(t *testing.T) {
+	ciphertext, err := os.ReadFile("../test.dat.tle")
+	require.NoError(t, err)
+
+	_, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(priv)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	sigFile := filepath.Join(dir, "test.dat.tle.sig")
+	require.NoError(t, os.WriteFile(sigFile, sshSign(t, signer, "file", ciphertext), 0600))
+	signersFile := filepath.Join(dir, "allowed_signers")
+	key := string(bytes.TrimSpace(ssh.MarshalAuthorizedKey(signer.PublicKey())))
+	require.NoError(t, os.WriteFile(signersFile, []byte("# release keys\nalice@example.com,*@release.example.com namespaces=\"file\" "+key+" alice\n"), 0600))
+
+	flags, err := ParseVerify([]string{"--signer", signersFile, "--signature", sigFile, "--principal", "ci@release.example.com", "-"})
+	require.NoError(t, err)
+	principals, err := Verify(flags, bytes.NewReader(ciphertext))
+	require.NoError(t, err)
+	require.Equal(t, "alice@example.com,*@release.example.com", principals)
+	_, err = Verify(flags, bytes.NewReader(append(ciphertext, 0)))
+	require.ErrorIs(t, err, tlock.ErrBadSignature)
+
+	flags.Principal = "mallory@example.com"
+	_, err = Verify(flags, bytes.NewReader(ciphertext))
+	require.ErrorIs(t, err, tlock.ErrBadSignature)
+
+	// The namespace separates the signatures of files from the ones of other
+	// uses of the key.
+	flags.Principal = ""
+	require.NoError(t, os.WriteFile(sigFile, sshSign(t, signer, "git", ciphertext), 0600))
+	_, err = Verify(flags, bytes.NewReader(ciphertext))
+	require.ErrorIs(t, err, tlock.ErrBadSignature)
+	flags.Namespace = "git"
+	_, err = Verify(flags, bytes.NewReader(ciphertext))
+	require.ErrorIs(t, err, tlock.ErrBadSignature, "namespace not allowed for the key")
+}
+
+// sshSign signs the message as ssh-keygen -Y sign does.
+func sshSign(t *testing.T, signer ssh.Signer, namespace string, message []byte) []byte {
+	digest := sha512.Sum512(message)
+	signed := append([]byte("SSHSIG"), ssh.Marshal(struct {
+		Namespace     string
+		Reserved      []byte
+		HashAlgorithm string
+		Hash          []byte
+	}{namespace, nil, "sha512", digest[:]})...)
+	sig, err := signer.Sign(rand.Reader, signed)
+	require.NoError(t, err)
+
+	blob := append([]byte("SSHSIG"), ssh.Marshal(struct {
+		Version       uint32
+		PublicKey     []byte
+		Namespace     string
+		Reserved      []byte
+		HashAlgorithm string
+		Signature     []byte
+	}{1, signer.PublicKey().Marshal(), namespace, nil, "sha512", ssh.Marshal(sig)})...)
+	return pem.EncodeToMemory(&pem.Block{Type: "SSH SIGNATURE", Bytes: blob})
+}
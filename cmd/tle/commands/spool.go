@@ -0,0 +1,168 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/JonathanLogan/tlock"
+	"golang.org/x/crypto/chacha20"
+)
+
+// maxSpoolSize is the most the keystream of a spool covers, 64 bytes per
+// block for each value of the 32 bits counter of chacha20.
+const maxSpoolSize = 64 << 32
+
+// ErrSpoolFull is returned when an input exceeds the size of the spool.
+var ErrSpoolFull = errors.New("input exceeds the spool size")
+
+// Spool holds a copy of a non seekable input in a temporary file, for the
+// operations reading their input twice or out of order. The file is
+// encrypted with an ephemeral key only kept in memory, so that what remains
+// on disk after a crash can't be read back, and is removed on Close.
+type Spool struct {
+	*io.SectionReader
+	f     *os.File
+	key   [chacha20.KeySize]byte
+	nonce [chacha20.NonceSize]byte
+}
+
+// NewSpool copies src to a spool, failing with ErrSpoolFull once more than
+// max bytes are read. A max of 0 only caps the spool to 256 GiB.
+func NewSpool(src io.Reader, max int64) (*Spool, error) {
+	if max <= 0 || max > maxSpoolSize {
+		max = maxSpoolSize
+	}
+
+	s := Spool{}
+	if _, err := rand.Read(s.key[:]); err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp("", "tle-spool-*")
+	if err != nil {
+		return nil, fmt.Errorf("create spool: %w", err)
+	}
+	s.f = f
+	// The spool is locked while in use, so that tle cache gc only removes
+	// those left behind.
+	if err := lockFile(f); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("lock spool: %w", err)
+	}
+
+	size, err := io.Copy(&spoolWriter{s: &s}, io.LimitReader(src, max+1))
+	switch {
+	case err != nil:
+		s.Close()
+		return nil, fmt.Errorf("spool input: %w", err)
+	case size > max:
+		s.Close()
+		return nil, fmt.Errorf("%w of %d bytes", ErrSpoolFull, max)
+	}
+
+	s.SectionReader = io.NewSectionReader(&s, 0, size)
+	return &s, nil
+}
+
+// ReadAt implements the io.ReaderAt interface, decrypting what is read.
+func (s *Spool) ReadAt(p []byte, off int64) (int, error) {
+	n, err := s.f.ReadAt(p, off)
+	if xerr := s.xor(p[:n], off); xerr != nil {
+		return 0, xerr
+	}
+	return n, err
+}
+
+// Close removes the spool.
+func (s *Spool) Close() error {
+	err := s.f.Close()
+	if rerr := os.Remove(s.f.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// SpoolConvergent peeks at src and, when it holds a convergent ciphertext
+// which can't seek, copies it to a spool of up to max bytes for
+// DecryptConvergent. Otherwise the spool is nil and the returned reader
+// replays src.
+func SpoolConvergent(src io.Reader, max int64) (*Spool, io.Reader, error) {
+	rr := bufio.NewReader(src)
+	if head, _ := rr.Peek(64); !tlock.IsConvergent(bytes.NewReader(head)) {
+		return nil, rr, nil
+	}
+	s, err := NewSpool(rr, max)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s, s, nil
+}
+
+// SpoolUnseekable copies src to a spool of up to max bytes when it can't
+// seek, for the encryptions reading their input twice. Otherwise the spool is
+// nil and src is returned as is.
+func SpoolUnseekable(src io.Reader, max int64) (*Spool, io.Reader, error) {
+	if rs, ok := src.(io.ReadSeeker); ok {
+		if _, err := rs.Seek(0, io.SeekCurrent); err == nil {
+			return nil, src, nil
+		}
+	}
+	s, err := NewSpool(src, max)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s, s, nil
+}
+
+// =============================================================================
+
+// validateSpoolFlags checks the size of the spool, which decryption and
+// reproducible encryption use.
+func validateSpoolFlags(f *Flags) error {
+	if f.Spool == "" {
+		return nil
+	}
+	if !f.Decrypt && f.Reproducible == "" {
+		return errors.New("--spool can only be used with -d/--decrypt or --reproducible")
+	}
+	if _, err := ParseSize(f.Spool); err != nil {
+		return fmt.Errorf("--spool: %w", err)
+	}
+	return nil
+}
+
+// spoolWriter appends to the file of a spool, encrypting what is written.
+type spoolWriter struct {
+	s   *Spool
+	off int64
+	buf []byte
+}
+
+func (w *spoolWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf[:0], p...)
+	if err := w.s.xor(w.buf, w.off); err != nil {
+		return 0, err
+	}
+	n, err := w.s.f.Write(w.buf)
+	w.off += int64(n)
+	return n, err
+}
+
+// xor applies the keystream at the offset to p.
+func (s *Spool) xor(p []byte, off int64) error {
+	c, err := chacha20.NewUnauthenticatedCipher(s.key[:], s.nonce[:])
+	if err != nil {
+		return err
+	}
+	c.SetCounter(uint32(off / 64))
+	if skip := off % 64; skip != 0 {
+		var block [64]byte
+		c.XORKeyStream(block[:skip], block[:skip])
+	}
+	c.XORKeyStream(p, p)
+	return nil
+}
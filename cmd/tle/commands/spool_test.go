@@ -0,0 +1,95 @@
+package commands
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpool(t *testing.T) {
+	data := make([]byte, 100_000)
+	_, err := rand.Read(data)
+	require.NoError(t, err)
+
+	s, err := NewSpool(bytes.NewReader(data), 0)
+	require.NoError(t, err)
+	name := s.f.Name()
+
+	// The file holds the data encrypted, read back decrypted.
+	raw, err := os.ReadFile(name)
+	require.NoError(t, err)
+	require.Len(t, raw, len(data))
+	require.NotEqual(t, data, raw)
+
+	got, err := io.ReadAll(s)
+	require.NoError(t, err)
+	require.Equal(t, data, got)
+
+	_, err = s.Seek(12_345, io.SeekStart)
+	require.NoError(t, err)
+	got = make([]byte, 1000)
+	_, err = io.ReadFull(s, got)
+	require.NoError(t, err)
+	require.Equal(t, data[12_345:13_345], got)
+
+	require.NoError(t, s.Close())
+	_, err = os.Stat(name)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestSpoolFull(t *testing.T) {
+	_, err := NewSpool(bytes.NewReader(make([]byte, 1025)), 1024)
+	require.ErrorIs(t, err, ErrSpoolFull)
+
+	s, err := NewSpool(bytes.NewReader(make([]byte, 1024)), 1024)
+	require.NoError(t, err)
+	require.Equal(t, int64(1024), s.Size())
+	require.NoError(t, s.Close())
+}
+
+func TestSpoolConvergent(t *testing.T) {
+	s, rr, err := SpoolConvergent(strings.NewReader("not convergent"), 0)
+	require.NoError(t, err)
+	require.Nil(t, s)
+	b, err := io.ReadAll(rr)
+	require.NoError(t, err)
+	require.Equal(t, "not convergent", string(b))
+
+	s, rr, err = SpoolConvergent(strings.NewReader("tlock convergent v1\nrest"), 0)
+	require.NoError(t, err)
+	require.NotNil(t, s)
+	defer s.Close()
+	b, err = io.ReadAll(rr)
+	require.NoError(t, err)
+	require.Equal(t, "tlock convergent v1\nrest", string(b))
+}
+
+func TestSpoolUnseekable(t *testing.T) {
+	s, rr, err := SpoolUnseekable(strings.NewReader("seekable"), 0)
+	require.NoError(t, err)
+	require.Nil(t, s)
+	b, err := io.ReadAll(rr)
+	require.NoError(t, err)
+	require.Equal(t, "seekable", string(b))
+
+	// A pipe is an *os.File, which can't seek nonetheless.
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+	go func() {
+		w.Write([]byte("piped"))
+		w.Close()
+	}()
+	s, rr, err = SpoolUnseekable(r, 0)
+	require.NoError(t, err)
+	require.NotNil(t, s)
+	defer s.Close()
+	b, err = io.ReadAll(rr)
+	require.NoError(t, err)
+	require.Equal(t, "piped", string(b))
+}
@@ -0,0 +1,159 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/pem"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/JonathanLogan/tlock"
+	"golang.org/x/crypto/ssh"
+)
+
+// These constants define the SSH signature format of ssh-keygen -Y sign.
+const (
+	sshsigPEMType = "SSH SIGNATURE"
+	sshsigMagic   = "SSHSIG"
+	sshsigVersion = 1
+)
+
+// DefaultSSHNamespace is the namespace ssh-keygen signs files in.
+const DefaultSSHNamespace = "file"
+
+// sshsig is the blob of an SSH signature, following its magic preamble.
+type sshsig struct {
+	Version       uint32
+	PublicKey     []byte
+	Namespace     string
+	Reserved      []byte
+	HashAlgorithm string
+	Signature     []byte
+}
+
+// sshsigSigned is the data signed, following the magic preamble.
+type sshsigSigned struct {
+	Namespace     string
+	Reserved      []byte
+	HashAlgorithm string
+	Hash          []byte
+}
+
+// verifySSH checks the SSH signature of the ciphertext read from src, as
+// produced by ssh-keygen -Y sign, against the allowed signers file. When
+// principal is set, the key must be allowed for it. It returns the principals
+// the key is allowed for.
+func verifySSH(signersFile, principal, namespace string, signature []byte, src io.Reader) (string, error) {
+	block, _ := pem.Decode(signature)
+	if block == nil || block.Type != sshsigPEMType || !bytes.HasPrefix(block.Bytes, []byte(sshsigMagic)) {
+		return "", fmt.Errorf("%w: malformed ssh signature", tlock.ErrBadSignature)
+	}
+	var sig sshsig
+	if err := ssh.Unmarshal(block.Bytes[len(sshsigMagic):], &sig); err != nil || sig.Version != sshsigVersion {
+		return "", fmt.Errorf("%w: malformed ssh signature", tlock.ErrBadSignature)
+	}
+	if sig.Namespace != namespace {
+		return "", fmt.Errorf("%w: signed in namespace %q, not %q", tlock.ErrBadSignature, sig.Namespace, namespace)
+	}
+	pub, err := ssh.ParsePublicKey(sig.PublicKey)
+	if err != nil {
+		return "", fmt.Errorf("%w: signature key: %w", tlock.ErrBadSignature, err)
+	}
+
+	principals, err := allowedPrincipals(signersFile, pub, principal, namespace)
+	if err != nil {
+		return "", err
+	}
+
+	var h hash.Hash
+	switch sig.HashAlgorithm {
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return "", fmt.Errorf("%w: unsupported hash algorithm %q", tlock.ErrBadSignature, sig.HashAlgorithm)
+	}
+	if _, err := io.Copy(h, src); err != nil {
+		return "", fmt.Errorf("read input: %w", err)
+	}
+	signed := append([]byte(sshsigMagic), ssh.Marshal(sshsigSigned{
+		Namespace:     sig.Namespace,
+		Reserved:      sig.Reserved,
+		HashAlgorithm: sig.HashAlgorithm,
+		Hash:          h.Sum(nil),
+	})...)
+
+	var s ssh.Signature
+	if err := ssh.Unmarshal(sig.Signature, &s); err != nil {
+		return "", fmt.Errorf("%w: malformed ssh signature", tlock.ErrBadSignature)
+	}
+	if err := pub.Verify(signed, &s); err != nil {
+		return "", fmt.Errorf("%w: %w", tlock.ErrBadSignature, err)
+	}
+	return principals, nil
+}
+
+// allowedPrincipals returns the principals of the first line of the allowed
+// signers file allowing the key, for the principal when set, to sign in the
+// namespace. Certificate authorities are not supported.
+func allowedPrincipals(signersFile string, key ssh.PublicKey, principal, namespace string) (string, error) {
+	f, err := os.Open(signersFile)
+	if err != nil {
+		return "", fmt.Errorf("read allowed signers: %w", err)
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		principals, rest, _ := strings.Cut(line, " ")
+		allowed, _, options, _, err := ssh.ParseAuthorizedKey([]byte(rest))
+		if err != nil {
+			return "", fmt.Errorf("parse allowed signers: %w", err)
+		}
+		if !bytes.Equal(allowed.Marshal(), key.Marshal()) || !allowsNamespace(options, namespace) || slices.Contains(options, "cert-authority") {
+			continue
+		}
+		if principal == "" || matchesPrincipal(principals, principal) {
+			return principals, nil
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return "", fmt.Errorf("read allowed signers: %w", err)
+	}
+
+	return "", fmt.Errorf("%w: key %s not allowed", tlock.ErrBadSignature, ssh.FingerprintSHA256(key))
+}
+
+// allowsNamespace reports whether the options of an allowed signer allow the
+// namespace, which they do unless restricted by a namespaces option.
+func allowsNamespace(options []string, namespace string) bool {
+	for _, option := range options {
+		if value, ok := strings.CutPrefix(option, "namespaces="); ok {
+			return slices.Contains(strings.Split(strings.Trim(value, `"`), ","), namespace)
+		}
+	}
+	return true
+}
+
+// matchesPrincipal reports whether one of the comma separated patterns
+// matches the principal.
+func matchesPrincipal(patterns, principal string) bool {
+	for _, pattern := range strings.Split(patterns, ",") {
+		if ok, _ := path.Match(pattern, principal); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,21 @@
+package commands
+
+import "errors"
+
+// validateStrictDecodeFlags checks the flags decoding the INPUT strictly.
+// The live, decoy and spooled convergent formats have encodings of their
+// own, which aren't checked.
+func validateStrictDecodeFlags(f *Flags) error {
+	if !f.StrictDecode {
+		return nil
+	}
+	switch {
+	case !f.Decrypt:
+		return errors.New("--strict-decode can only be used with -d/--decrypt")
+	case f.Daemon != "":
+		return errors.New("--strict-decode can't be used with --daemon")
+	case f.Live || f.Decoy != "" || f.Spool != "":
+		return errors.New("--strict-decode can't be used with --live, --decoy or --spool")
+	}
+	return nil
+}
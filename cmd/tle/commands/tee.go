@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// tees are the additional outputs of a decryption given with --tee.
+type tees struct {
+	dsts    []io.Writer
+	outputs []*Output
+	closers []io.Closer
+}
+
+// createTees creates the outputs of the names, the standard output of the
+// operation for -.
+func (op Operation) createTees(flags Flags) (*tees, error) {
+	t := tees{}
+	for _, name := range flags.Tee {
+		if name == "-" {
+			w := NewTerminalWriter(op.Stdout, flags.ForceTTY)
+			t.closers = append(t.closers, w)
+			t.dsts = append(t.dsts, w)
+			continue
+		}
+		o, err := CreateOutput(name)
+		if err != nil {
+			t.finish(err)
+			return nil, err
+		}
+		t.outputs = append(t.outputs, o)
+		t.dsts = append(t.dsts, o)
+	}
+	return &t, nil
+}
+
+// finish completes the outputs on the outcome of the decryption, as
+// Output.Finish does.
+func (t *tees) finish(err error) error {
+	errs := []error{err}
+	for _, c := range t.closers {
+		if cerr := c.Close(); err == nil {
+			errs = append(errs, cerr)
+		}
+	}
+	for _, o := range t.outputs {
+		errs = append(errs, o.Finish(err))
+	}
+	return errors.Join(errs...)
+}
+
+// =============================================================================
+
+// validateTeeFlags checks the flags writing the decryption to several outputs.
+func validateTeeFlags(f *Flags) error {
+	if len(f.Tee) == 0 {
+		return nil
+	}
+	switch {
+	case !f.Decrypt:
+		return errors.New("--tee can only be used with -d/--decrypt")
+	case f.OutDir != "":
+		return errors.New("--tee can't be used with --out-dir")
+	case f.Daemon != "":
+		return errors.New("--tee can't be used with --daemon")
+	}
+
+	names := []string{f.Output}
+	if f.Output == "" {
+		names[0] = "-"
+	}
+	for _, name := range f.Tee {
+		if name == "" || slices.Contains(names, name) {
+			return fmt.Errorf("--tee %q: the outputs must be distinct", name)
+		}
+		names = append(names, name)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecryptWithTee(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	dir := t.TempDir()
+	beacons, input := writeOfflineCiphertext(t, dir, "teed")
+
+	stdout, err := os.Create(filepath.Join(dir, "stdout"))
+	require.NoError(t, err)
+	defer stdout.Close()
+
+	flags := DefaultFlags()
+	flags.Decrypt = true
+	flags.Beacons = beacons
+	flags.Inputs = []string{input}
+	flags.Output = filepath.Join(dir, "data")
+	flags.Tee = []string{filepath.Join(dir, "copy"), "-"}
+	require.NoError(t, Operation{Flags: flags, Stdout: stdout}.Run(context.Background()))
+	for _, name := range []string{flags.Output, flags.Tee[0], stdout.Name()} {
+		plaintext, err := os.ReadFile(name)
+		require.NoError(t, err)
+		require.Equal(t, "teed", string(plaintext))
+	}
+	require.False(t, Resumable(flags, input))
+
+	// The outputs of failed decryptions are removed.
+	ciphertext, err := os.ReadFile(input)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(input, ciphertext[:len(ciphertext)-1], 0o600))
+	flags.Output = filepath.Join(dir, "truncated")
+	flags.Tee = []string{filepath.Join(dir, "truncated-copy")}
+	require.Error(t, Operation{Flags: flags, Stdout: stdout}.Run(context.Background()))
+	require.NoFileExists(t, flags.Output)
+	require.NoFileExists(t, flags.Tee[0])
+	require.NoFileExists(t, flags.Tee[0]+".partial")
+}
+
+func TestParseTee(t *testing.T) {
+	t.Setenv("TLE_TEE", "env.txt")
+	f, err := ParseOperation("decrypt", []string{"-o", "plain.txt", "--tee", "a.txt", "--tee", "-"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a.txt", "-"}, f.Tee)
+
+	_, err = ParseOperation("decrypt", []string{"-o", "plain.txt", "--tee", "a.txt", "--tee", "a.txt"})
+	require.Error(t, err)
+}
@@ -0,0 +1,150 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/JonathanLogan/tlock"
+)
+
+// ticketCommand defines the ticket subcommand.
+var ticketCommand = CommandSpec{
+	Name: "ticket",
+	Synopsis: []string{
+		`tle ticket [-n NETWORK] [-c CHAIN] [--auth-token TOKEN] [--doh URL] [--ip-family FAMILY] [--no-pin] [-o OUTPUT] (ROUND | INPUT...)`,
+	},
+	Flags: []FlagSpec{
+		mainFlag("network", func(f *TicketFlags) any { return &f.Network }),
+		mainFlag("chain", func(f *TicketFlags) any { return &f.Chain }),
+		mainFlag("auth-token", func(f *TicketFlags) any { return &f.AuthToken }),
+		mainFlag("doh", func(f *TicketFlags) any { return &f.DoH }),
+		mainFlag("ip-family", func(f *TicketFlags) any { return &f.IPFamily }),
+		mainFlag("no-pin", func(f *TicketFlags) any { return &f.NoPin }),
+		{Name: "output", Short: "o", Arg: "OUTPUT", Description: "Write the ticket to the file at path OUTPUT.", value: field(func(f *TicketFlags) any { return &f.Output })},
+	},
+	notes: `Fetches and verifies the beacon of the round, reached by the network, and
+writes a decryption ticket holding it along with the chain information to
+OUTPUT, or to the standard output: a line of text to hand to the workers
+decrypting without network access or relay credentials. Given the
+ciphertexts INPUT, all locked to the same round, the ticket decrypts those
+only; given the ROUND, all the ciphertexts locked to it.
+
+The workers decrypt with the ticket, or the file holding it, verifying its
+beacon first:
+	TLE_TICKET=tlock-ticket/v1:... tle --decrypt [-o OUTPUT] [INPUT]
+
+The ticket is no secret: the beacon it holds is published by the network.
+Restricting it to INPUT keeps the workers from decrypting other ciphertexts
+by mistake, not from obtaining the beacon elsewhere.`,
+	defaults: func() any { return &TicketFlags{Network: DefaultNetwork, Chain: DefaultChain} },
+}
+
+// TicketFlags represent the values from the ticket command line.
+type TicketFlags struct {
+	Network string
+	Chain   string
+	Output  string
+	Round   uint64
+	Inputs  []string
+
+	NetworkFlags
+}
+
+// ParseTicket parses the arguments following the ticket subcommand.
+func ParseTicket(args []string) (TicketFlags, error) {
+	f, fs, err := parseCommand[TicketFlags]("ticket", args)
+	if err != nil {
+		return TicketFlags{}, err
+	}
+	if fs.NArg() == 0 {
+		return TicketFlags{}, errors.New(commandUsage("ticket"))
+	}
+	if roundNumber, err := strconv.ParseUint(fs.Arg(0), 10, 64); err == nil {
+		if fs.NArg() != 1 {
+			return TicketFlags{}, errors.New(commandUsage("ticket"))
+		}
+		f.Round = roundNumber
+	} else {
+		f.Inputs = fs.Args()
+	}
+
+	return f, nil
+}
+
+// IssueTicket writes to dst the ticket of the round of the flags, or the one
+// restricted to their inputs.
+func IssueTicket(dst io.Writer, flags TicketFlags, network tlock.Network) error {
+	roundNumber := flags.Round
+	var ids [][]byte
+	for _, input := range flags.Inputs {
+		r, err := CiphertextRound(input, "")
+		if err != nil {
+			return fmt.Errorf("%s: %w", input, err)
+		}
+		if roundNumber != 0 && r != roundNumber {
+			return fmt.Errorf("%s: locked to round %d, not %d: issue a ticket per round", input, r, roundNumber)
+		}
+		roundNumber = r
+
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		id, err := tlock.FileID(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", input, err)
+		}
+		ids = append(ids, id)
+	}
+
+	ticket, err := tlock.New(network).IssueTicket(roundNumber, ids...)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(dst, ticket)
+	return err
+}
+
+// OpenTicket parses the ticket named by --ticket, given as such or as the
+// file holding it.
+func OpenTicket(value string) (*tlock.Ticket, error) {
+	if strings.HasPrefix(value, tlock.TicketPrefix) {
+		return tlock.ParseTicket(value)
+	}
+	data, err := os.ReadFile(value)
+	if err != nil {
+		return nil, fmt.Errorf("open ticket: %w", err)
+	}
+	ticket, err := tlock.ParseTicket(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", value, err)
+	}
+	return ticket, nil
+}
+
+// =============================================================================
+
+// validateTicketFlags checks the flags decrypting with a ticket.
+func validateTicketFlags(f *Flags) error {
+	if f.Ticket == "" {
+		return nil
+	}
+	switch {
+	case !f.Decrypt:
+		return errors.New("--ticket can only be used with -d/--decrypt")
+	case f.Beacons != "":
+		return errors.New("--ticket can't be used with --beacons")
+	case f.Daemon != "":
+		return errors.New("--ticket can't be used with --daemon")
+	case f.PuzzleFallback || f.Live:
+		return errors.New("--ticket can't be used with --puzzle-fallback or --live")
+	case f.Spool != "":
+		return errors.New("--ticket can't be used with --spool")
+	}
+	return nil
+}
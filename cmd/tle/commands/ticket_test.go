@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTicket(t *testing.T) {
+	f, err := ParseTicket([]string{"-o", "round.ticket", "1000"})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1000), f.Round)
+	require.Equal(t, "round.ticket", f.Output)
+
+	f, err = ParseTicket([]string{"a.tle", "b.tle"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a.tle", "b.tle"}, f.Inputs)
+
+	for _, args := range [][]string{{}, {"1000", "a.tle"}} {
+		_, err := ParseTicket(args)
+		require.Error(t, err, args)
+	}
+}
+
+func TestDecryptWithTicket(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	dir := t.TempDir()
+	beacons, input := writeOfflineCiphertext(t, dir, "the secret")
+	network, err := OpenBeacons(beacons)
+	require.NoError(t, err)
+
+	var ticket bytes.Buffer
+	require.NoError(t, IssueTicket(&ticket, TicketFlags{Inputs: []string{input}}, network))
+	name := filepath.Join(dir, "data.ticket")
+	require.NoError(t, os.WriteFile(name, ticket.Bytes(), 0o600))
+
+	// The ticket decrypts without the network, given as such or as a file.
+	for _, value := range []string{string(bytes.TrimSpace(ticket.Bytes())), name} {
+		flags := DefaultFlags()
+		flags.Decrypt = true
+		flags.Network = "http://127.0.0.1:1"
+		flags.Ticket = value
+		flags.Inputs = []string{input}
+		flags.Output = filepath.Join(dir, "data.txt")
+		require.NoError(t, Operation{Flags: flags}.Run(context.Background()))
+		plaintext, err := os.ReadFile(flags.Output)
+		require.NoError(t, err)
+		require.Equal(t, "the secret", string(plaintext))
+	}
+
+	// Other ciphertexts of the round aren't covered.
+	other := filepath.Join(dir, "other.tle")
+	var ciphertext bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&ciphertext, bytes.NewReader([]byte("another secret")), 50))
+	require.NoError(t, os.WriteFile(other, ciphertext.Bytes(), 0o600))
+	flags := DefaultFlags()
+	flags.Decrypt = true
+	flags.Ticket = name
+	flags.Inputs = []string{other}
+	flags.Output = filepath.Join(dir, "other.txt")
+	require.ErrorIs(t, Operation{Flags: flags}.Run(context.Background()), tlock.ErrNotInTicket)
+	require.NoFileExists(t, flags.Output)
+}
@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+)
+
+// TooEarlyError is returned when decrypting before the round of the
+// ciphertext, telling when it unlocks. It matches tlock.ErrTooEarly.
+type TooEarlyError struct {
+	Round    uint64
+	Unlock   time.Time // Zero when the network can't tell it.
+	Location *time.Location
+}
+
+// NewTooEarlyError returns the TooEarlyError of the round, whose unlock time
+// is displayed in the location.
+func NewTooEarlyError(network tlock.Network, roundNumber uint64, loc *time.Location) *TooEarlyError {
+	unlock, _ := tlock.RoundTime(network, roundNumber)
+	return &TooEarlyError{Round: roundNumber, Unlock: unlock, Location: loc}
+}
+
+// Error implements the error interface.
+func (e *TooEarlyError) Error() string {
+	if e.Unlock.IsZero() {
+		return Message(MsgTooEarlyUnknown, e.Round)
+	}
+	return Message(MsgTooEarly, e.Round, FormatTime(e.Unlock, e.Location))
+}
+
+// Unwrap returns tlock.ErrTooEarly.
+func (e *TooEarlyError) Unwrap() error {
+	return tlock.ErrTooEarly
+}
+
+// ExplainTooEarly replaces a tlock.ErrTooEarly failure of the decryption of
+// the named input by a TooEarlyError telling when it unlocks in the time
+// zone of --tz. Other errors are returned as is.
+func ExplainTooEarly(err error, flags Flags, input string, network tlock.Network) error {
+	if !errors.Is(err, tlock.ErrTooEarly) || input == "" || input == "-" {
+		return err
+	}
+	roundNumber, rerr := CiphertextRound(input, flags.Decoy)
+	if rerr != nil {
+		return err
+	}
+	loc, lerr := LoadLocation(flags.TZ)
+	if lerr != nil {
+		return err
+	}
+	return NewTooEarlyError(network, roundNumber, loc)
+}
+
+// LoadLocation returns the time zone named by --tz: Local or an empty name
+// for the one of the system, UTC, an IANA name such as Europe/Paris, or a
+// fixed offset such as +02:00 or -0700.
+func LoadLocation(name string) (*time.Location, error) {
+	switch name {
+	case "", "Local", "local":
+		return time.Local, nil
+	case "UTC", "utc", "Z":
+		return time.UTC, nil
+	}
+
+	if name[0] == '+' || name[0] == '-' {
+		offset := strings.ReplaceAll(name[1:], ":", "")
+		if len(offset) == 2 {
+			offset += "00"
+		}
+		n, err := strconv.Atoi(offset)
+		if len(offset) == 4 && err == nil && n/100 <= 14 && n%100 < 60 {
+			seconds := (n/100*60 + n%100) * 60
+			if name[0] == '-' {
+				seconds = -seconds
+			}
+			return time.FixedZone("UTC"+name[:1]+offset[:2]+":"+offset[2:], seconds), nil
+		}
+		return nil, fmt.Errorf("invalid time zone offset %q", name)
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q: %w", name, err)
+	}
+	return loc, nil
+}
+
+// FormatTime formats t in the location, nil meaning the local one, followed
+// by the same in UTC and as a Unix timestamp, so that the day it designates
+// can't be mistaken:
+//
+//	Fri, 16 Oct 2026 09:00:00 CEST (2026-10-16 07:00:00 UTC, unix 1792134000)
+func FormatTime(t time.Time, loc *time.Location) string {
+	if loc == nil {
+		loc = time.Local
+	}
+	return fmt.Sprintf("%s (%s, unix %d)", t.In(loc).Format(time.RFC1123), t.UTC().Format("2006-01-02 15:04:05 UTC"), t.Unix())
+}
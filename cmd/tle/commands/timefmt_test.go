@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadLocation(t *testing.T) {
+	tests := []struct {
+		name        string
+		offset      int
+		shouldError bool
+	}{
+		{name: "UTC"},
+		{name: "+02:00", offset: 2 * 3600},
+		{name: "-0730", offset: -(7*3600 + 30*60)},
+		{name: "+05", offset: 5 * 3600},
+		{name: "+25:00", shouldError: true},
+		{name: "Mars/Olympus_Mons", shouldError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			loc, err := LoadLocation(test.name)
+			if test.shouldError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			_, offset := time.Date(2026, 1, 1, 0, 0, 0, 0, loc).Zone()
+			require.Equal(t, test.offset, offset)
+		})
+	}
+
+	loc, err := LoadLocation("")
+	require.NoError(t, err)
+	require.Equal(t, time.Local, loc)
+}
+
+func TestFormatTime(t *testing.T) {
+	loc, err := LoadLocation("+02:00")
+	require.NoError(t, err)
+	unlock := time.Date(2026, 10, 16, 7, 0, 0, 0, time.UTC)
+	require.Equal(t, "Fri, 16 Oct 2026 09:00:00 UTC+02:00 (2026-10-16 07:00:00 UTC, unix 1792134000)", FormatTime(unlock, loc))
+
+	err = fmt.Errorf("data.tle: %w", &TooEarlyError{Round: 42, Unlock: unlock, Location: time.UTC})
+	require.True(t, errors.Is(err, tlock.ErrTooEarly))
+	require.Equal(t, ExitTooEarly, ExitCode(err))
+	require.Equal(t, "data.tle: too early to decrypt: round 42 unlocks at Fri, 16 Oct 2026 07:00:00 UTC (2026-10-16 07:00:00 UTC, unix 1792134000)", err.Error())
+}
@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"errors"
+	"io"
+	"os"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrBinaryToTerminal is returned when binary output would be written to a
+// terminal without --force-tty.
+var ErrBinaryToTerminal = errors.New("refusing to output binary to the terminal")
+
+// ttyCheckSize is the amount of decrypted output inspected before writing to a
+// terminal.
+const ttyCheckSize = 1024
+
+// CheckTerminal returns an error when binary output is about to be written to
+// the terminal f without being forced, suggesting the armor instead.
+func CheckTerminal(f *os.File, binary bool, force bool) error {
+	if !binary || force || !isTerminal(f) {
+		return nil
+	}
+	return errors.Join(ErrBinaryToTerminal, errors.New(Message(MsgBinaryToTerminal)))
+}
+
+// NewTerminalWriter returns a writer to f which, when f is a terminal and the
+// output isn't forced, refuses decrypted output that isn't printable text. It
+// must be closed to write short outputs, but never closes f.
+func NewTerminalWriter(f *os.File, force bool) io.WriteCloser {
+	if force || !isTerminal(f) {
+		return nopCloser{f}
+	}
+	return &ttyWriter{dst: f}
+}
+
+// =============================================================================
+
+// ttyWriter inspects the start of the output before writing it.
+type ttyWriter struct {
+	dst     io.Writer
+	buf     []byte
+	checked bool
+}
+
+func (w *ttyWriter) Write(p []byte) (int, error) {
+	if w.checked {
+		return w.dst.Write(p)
+	}
+
+	w.buf = append(w.buf, p...)
+	if len(w.buf) < ttyCheckSize {
+		return len(p), nil
+	}
+	if err := w.flush(); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close writes what is still buffered, once checked.
+func (w *ttyWriter) Close() error {
+	if w.checked {
+		return nil
+	}
+	return w.flush()
+}
+
+// flush checks the buffered output and writes it.
+func (w *ttyWriter) flush() error {
+	w.checked = true
+	if !printable(w.buf[:min(len(w.buf), ttyCheckSize)]) {
+		return errors.Join(ErrBinaryToTerminal, errors.New(Message(MsgBinaryOutput)))
+	}
+	_, err := w.dst.Write(w.buf)
+	w.buf = nil
+	return err
+}
+
+// printable reports whether b looks like text, ignoring a rune cut off at the
+// end.
+func printable(b []byte) bool {
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		if r == utf8.RuneError && size == 1 {
+			return len(b) < utf8.UTFMax && !utf8.FullRune(b)
+		}
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+		b = b[size:]
+	}
+	return true
+}
+
+// isTerminal reports whether f is a terminal. The null device is a character
+// device too, but output to it is discarded.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	if null, err := os.Stat(os.DevNull); err == nil && os.SameFile(fi, null) {
+		return false
+	}
+	return true
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTerminalWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := &ttyWriter{dst: &out}
+	_, err := w.Write([]byte("hello, wörld\n"))
+	require.NoError(t, err)
+	require.Zero(t, out.Len())
+	require.NoError(t, w.Close())
+	require.Equal(t, "hello, wörld\n", out.String())
+
+	out.Reset()
+	w = &ttyWriter{dst: &out}
+	_, err = w.Write(append(bytes.Repeat([]byte("a"), ttyCheckSize-1), 0xe2, 0x82))
+	require.NoError(t, err)
+	_, err = w.Write([]byte{0xac})
+	require.NoError(t, err)
+	require.Equal(t, ttyCheckSize+2, out.Len())
+
+	out.Reset()
+	w = &ttyWriter{dst: &out}
+	_, err = w.Write([]byte{0x00, 0x01, 0xff})
+	require.NoError(t, err)
+	require.ErrorIs(t, w.Close(), ErrBinaryToTerminal)
+	require.Zero(t, out.Len())
+}
+
+func TestCheckTerminalNotATerminal(t *testing.T) {
+	f, err := os.Create(t.TempDir() + "/out")
+	require.NoError(t, err)
+	defer f.Close()
+	require.NoError(t, CheckTerminal(f, true, false))
+
+	null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	require.NoError(t, err)
+	defer null.Close()
+	require.NoError(t, CheckTerminal(null, true, false))
+	require.IsType(t, nopCloser{}, NewTerminalWriter(null, false))
+}
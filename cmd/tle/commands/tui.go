@@ -0,0 +1,278 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/lockedfs"
+	"github.com/JonathanLogan/tlock/watcher"
+)
+
+// tuiCommand defines the tui subcommand.
+var tuiCommand = CommandSpec{
+	Name: "tui",
+	Synopsis: []string{
+		`tle tui [-n NETWORK] [-c CHAIN] [--index INDEX] [--interval INTERVAL] [--tz ZONE] DIR`,
+	},
+	Flags: []FlagSpec{
+		mainFlag("network", func(f *TUIFlags) any { return &f.Network }),
+		mainFlag("chain", func(f *TUIFlags) any { return &f.Chain }),
+		{Name: "index", Arg: "INDEX", Description: "Read the ciphertexts from the index INDEX written by tle index.", value: field(func(f *TUIFlags) any { return &f.Index })},
+		{Name: "interval", Arg: "INTERVAL", Description: "How often the dashboard is refreshed.", value: field(func(f *TUIFlags) any { return &f.Interval })},
+		mainFlag("tz", func(f *TUIFlags) any { return &f.TZ }),
+	},
+	notes: `Shows a live dashboard of the .tle ciphertexts under DIR: the health of the
+chain, a table of the files still locked with the countdown to their unlock,
+and the files which unlocked while it runs. The table is refreshed every
+INTERVAL, 1s by default, until interrupted.
+
+The files are listed from the index of the directory, kept in memory and
+seeded from the INDEX written by tle index when given, so that only the
+ciphertexts which changed are opened again.`,
+	defaults: func() any { return &TUIFlags{Network: DefaultNetwork, Chain: DefaultChain, Interval: time.Second} },
+}
+
+// maxUnlockEvents is the number of unlock events the dashboard shows.
+const maxUnlockEvents = 10
+
+// TUIFlags represent the values from the tui command line.
+type TUIFlags struct {
+	Network  string
+	Chain    string
+	Index    string
+	Interval time.Duration
+	TZ       string
+	Dir      string
+}
+
+// ParseTUI parses the arguments following the tui subcommand.
+func ParseTUI(args []string) (TUIFlags, error) {
+	f, fs, err := parseCommand[TUIFlags]("tui", args)
+	if err != nil {
+		return TUIFlags{}, err
+	}
+	if fs.NArg() != 1 {
+		return TUIFlags{}, errors.New(commandUsage("tui"))
+	}
+	if f.Interval <= 0 {
+		return TUIFlags{}, errors.New("--interval must be positive")
+	}
+	if _, err := LoadLocation(f.TZ); err != nil {
+		return TUIFlags{}, fmt.Errorf("--tz: %w", err)
+	}
+	f.Dir = fs.Arg(0)
+
+	return f, nil
+}
+
+// UnlockEvent records a file of the dashboard unlocking.
+type UnlockEvent struct {
+	File  string
+	Round uint64
+	At    time.Time
+}
+
+// Dashboard tracks the locked files of a directory, watching their rounds.
+type Dashboard struct {
+	network tlock.Network
+	watcher *watcher.Watcher
+	loc     *time.Location
+
+	mu      sync.Mutex
+	fsys    *lockedfs.FS
+	files   []lockedfs.IndexEntry
+	watched map[string]uint64
+	events  []UnlockEvent
+}
+
+// NewDashboard constructs the dashboard of the directory of the flags.
+func NewDashboard(flags TUIFlags, network tlock.Network) (*Dashboard, error) {
+	loc, err := LoadLocation(flags.TZ)
+	if err != nil {
+		return nil, err
+	}
+
+	fsys := lockedfs.New(flags.Dir, network)
+	if flags.Index != "" {
+		f, err := os.Open(flags.Index)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		idx, err := lockedfs.ReadIndex(f)
+		if err != nil {
+			return nil, err
+		}
+		fsys = fsys.WithIndex(idx)
+	}
+
+	return &Dashboard{
+		network: network,
+		watcher: watcher.New(network),
+		loc:     loc,
+		fsys:    fsys,
+		watched: make(map[string]uint64),
+	}, nil
+}
+
+// Run refreshes the dashboard and renders it to w, clearing the terminal,
+// every interval until the context is done.
+func (d *Dashboard) Run(ctx context.Context, w io.Writer, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.Refresh(ctx); err != nil {
+			return err
+		}
+		io.WriteString(w, "\x1b[H\x1b[2J")
+		d.Render(ctx, w, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh lists the files of the directory again and watches the rounds of
+// those not watched yet until the context is done.
+func (d *Dashboard) Refresh(ctx context.Context) error {
+	d.mu.Lock()
+	fsys := d.fsys
+	d.mu.Unlock()
+
+	idx, err := fsys.Index()
+	if err != nil {
+		return err
+	}
+
+	latest := latestRound(d.network, time.Now())
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.fsys = fsys.WithIndex(idx)
+	d.files = idx.Files
+	for _, e := range idx.Files {
+		if roundNumber, ok := d.watched[e.File]; ok && roundNumber == e.Round {
+			continue
+		}
+		d.watched[e.File] = e.Round
+		if e.Round <= latest {
+			continue
+		}
+		go d.watch(ctx, e)
+	}
+	return nil
+}
+
+// Events returns the unlock events recorded, the latest first.
+func (d *Dashboard) Events() []UnlockEvent {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]UnlockEvent(nil), d.events...)
+}
+
+// Render writes the chain health, the table of the locked files and the
+// latest unlock events to w.
+func (d *Dashboard) Render(ctx context.Context, w io.Writer, now time.Time) {
+	d.mu.Lock()
+	files := append([]lockedfs.IndexEntry(nil), d.files...)
+	events := append([]UnlockEvent(nil), d.events...)
+	d.mu.Unlock()
+
+	// The latest round is fetched once, the files being compared to it.
+	latest := d.network.Current(now)
+	fmt.Fprintln(w, Message(MsgDashboardChain, d.network.ChainHash()))
+	if status, err := tlock.Status(ctx, d.network); err == nil {
+		latest = status.LatestRound
+		io.WriteString(w, Message(MsgDashboardHealth, status.Health, status.LatestRound))
+		if status.Health != tlock.Healthy {
+			io.WriteString(w, Message(MsgDashboardBehind, status.Behind))
+		}
+		fmt.Fprintln(w)
+	} else {
+		fmt.Fprintln(w, Message(MsgDashboardUnknown, err))
+	}
+	fmt.Fprintf(w, "%s\n\n", Message(MsgDashboardRound, d.network.Current(now), now.In(d.loc).Format(time.DateTime)))
+
+	sort.SliceStable(files, func(i, j int) bool { return files[i].Round < files[j].Round })
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, Message(MsgDashboardColumns))
+	locked := 0
+	for _, e := range files {
+		if e.Round <= latest {
+			continue
+		}
+		locked++
+		unlocks, in := "-", "-"
+		if e.Unlocks != nil {
+			unlocks = e.Unlocks.In(d.loc).Format(time.DateTime)
+			in = countdown(e.Unlocks.Sub(now))
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", e.File, e.Round, unlocks, in)
+	}
+	tw.Flush()
+	fmt.Fprintln(w, Message(MsgDashboardSummary, locked, len(files)-locked))
+
+	if len(events) > 0 {
+		fmt.Fprintf(w, "\n%s\n", Message(MsgDashboardUnlocked))
+		for _, e := range events {
+			fmt.Fprintf(w, "  %s\n", Message(MsgDashboardEvent, e.At.In(d.loc).Format(time.DateTime), e.File, e.Round))
+		}
+	}
+}
+
+// =============================================================================
+
+// watch records an unlock event once the round of the file is reached.
+func (d *Dashboard) watch(ctx context.Context, e lockedfs.IndexEntry) {
+	if _, ok := <-d.watcher.Subscribe(ctx, e.Round); !ok {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.events = append([]UnlockEvent{{File: e.File, Round: e.Round, At: time.Now()}}, d.events...)
+	if len(d.events) > maxUnlockEvents {
+		d.events = d.events[:maxUnlockEvents]
+	}
+}
+
+// latestRound returns the latest round the network published, or its current
+// round when it doesn't tell.
+func latestRound(network tlock.Network, now time.Time) uint64 {
+	if n, ok := network.(interface{ LatestRound() (uint64, error) }); ok {
+		if latest, err := n.LatestRound(); err == nil {
+			return latest
+		}
+	}
+	return network.Current(now)
+}
+
+// countdown formats the duration left before an unlock, down to the second.
+func countdown(left time.Duration) string {
+	if left <= 0 {
+		return Message(MsgCountdownDue)
+	}
+	left = left.Round(time.Second)
+	days := left / (24 * time.Hour)
+	left -= days * 24 * time.Hour
+
+	var b strings.Builder
+	if days > 0 {
+		fmt.Fprintf(&b, "%dd", days)
+	}
+	b.WriteString(left.String())
+	return b.String()
+}
@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDashboard(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	// Round 2 is due within a second.
+	key := fixedtest.NewKey(nil)
+	key.Genesis = time.Now().Add(-2 * time.Second)
+	network := key.Network(t, nil)
+
+	dir := t.TempDir()
+	for name, roundNumber := range map[string]uint64{"soon.tle": 2, "later.tle": 1000, "open.tle": 1} {
+		var ciphertext bytes.Buffer
+		require.NoError(t, tlock.New(network).Encrypt(&ciphertext, bytes.NewReader([]byte("content")), roundNumber))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), ciphertext.Bytes(), 0o600))
+	}
+
+	flags, err := ParseTUI([]string{"--tz", "UTC", dir})
+	require.NoError(t, err)
+	d, err := NewDashboard(flags, network)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, d.Refresh(ctx))
+
+	var out bytes.Buffer
+	d.Render(ctx, &out, time.Now())
+	require.Contains(t, out.String(), "later.tle")
+	require.Contains(t, out.String(), "soon.tle")
+	require.NotContains(t, out.String(), "open.tle")
+	require.Contains(t, out.String(), "2 locked, 1 unlocked")
+
+	require.Eventually(t, func() bool { return len(d.Events()) == 1 }, 10*time.Second, 50*time.Millisecond)
+	require.Equal(t, "soon.tle", d.Events()[0].File)
+
+	out.Reset()
+	d.Render(ctx, &out, time.Now())
+	require.Contains(t, out.String(), "1 locked, 2 unlocked")
+	require.Contains(t, out.String(), "recently unlocked:")
+
+	_, err = ParseTUI([]string{"--interval", "0s", dir})
+	require.Error(t, err)
+}
+
+func TestDashboardFetchesLatestRoundOnce(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	// The files are all unlocked, so that no watcher fetches the round.
+	key := fixedtest.NewKey(nil)
+	key.Genesis = time.Now().Add(-30 * time.Second)
+	fixedNetwork := key.Network(t, nil)
+	network := &countingNetwork{Network: fixedNetwork}
+
+	dir := t.TempDir()
+	for i := range 10 {
+		var ciphertext bytes.Buffer
+		require.NoError(t, tlock.New(network).Encrypt(&ciphertext, bytes.NewReader([]byte("content")), uint64(i+1)))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("%d.tle", i)), ciphertext.Bytes(), 0o600))
+	}
+
+	flags, err := ParseTUI([]string{"--tz", "UTC", dir})
+	require.NoError(t, err)
+	d, err := NewDashboard(flags, network)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// The round is fetched once by the listing, and once by the dashboard.
+	require.NoError(t, d.Refresh(ctx))
+	require.Equal(t, int32(2), network.calls.Swap(0))
+
+	var out bytes.Buffer
+	d.Render(ctx, &out, time.Now())
+	require.Equal(t, int32(1), network.calls.Load())
+	require.Contains(t, out.String(), "0 locked, 10 unlocked")
+}
+
+// countingNetwork counts the calls for its latest round, reaching round 10.
+type countingNetwork struct {
+	*fixed.Network
+	calls atomic.Int32
+}
+
+func (n *countingNetwork) LatestRound() (uint64, error) {
+	n.calls.Add(1)
+	return 10, nil
+}
+
+func TestCountdown(t *testing.T) {
+	require.Equal(t, "due", countdown(-time.Second))
+	require.Equal(t, "1m30s", countdown(90*time.Second))
+	require.Equal(t, "2d1h0m0s", countdown(49*time.Hour))
+}
@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/JonathanLogan/tlock"
+)
+
+// verifyProofCommand defines the verify-proof subcommand.
+var verifyProofCommand = CommandSpec{
+	Name: "verify-proof",
+	Synopsis: []string{
+		`tle verify-proof --anchor ANCHOR [INPUT]`,
+	},
+	Flags: []FlagSpec{
+		{Name: "anchor", Arg: "ANCHOR", Description: "The anchoring record to verify INPUT against.", value: field(func(f *VerifyProofFlags) any { return &f.Anchor })},
+	},
+	notes: `Checks that the ciphertext INPUT matches ANCHOR, the JSON record written by
+--anchor or the hex encoded calldata of the anchoring transaction. Checking
+that the anchor itself is included in a blockchain is left to its tools.`,
+	defaults: func() any { return &VerifyProofFlags{} },
+}
+
+// VerifyProofFlags represent the values from the verify-proof command line.
+type VerifyProofFlags struct {
+	Anchor string
+	Input  string
+}
+
+// ParseVerifyProof parses the arguments following the verify-proof
+// subcommand.
+func ParseVerifyProof(args []string) (VerifyProofFlags, error) {
+	f, fs, err := parseCommand[VerifyProofFlags]("verify-proof", args)
+	if err != nil {
+		return VerifyProofFlags{}, err
+	}
+	if f.Anchor == "" || fs.NArg() > 1 {
+		return VerifyProofFlags{}, errors.New(commandUsage("verify-proof"))
+	}
+	f.Input = fs.Arg(0)
+
+	return f, nil
+}
+
+// VerifyProof checks the ciphertext read from src against the anchor file.
+func VerifyProof(flags VerifyProofFlags, src io.Reader) (tlock.Anchor, error) {
+	b, err := os.ReadFile(flags.Anchor)
+	if err != nil {
+		return tlock.Anchor{}, fmt.Errorf("read anchor: %w", err)
+	}
+
+	var anchor tlock.Anchor
+	if b = bytes.TrimSpace(b); len(b) > 0 && b[0] == '{' {
+		if err := json.Unmarshal(b, &anchor); err != nil {
+			return tlock.Anchor{}, fmt.Errorf("parse anchor: %w", err)
+		}
+	} else {
+		calldata, err := hex.DecodeString(strings.TrimPrefix(string(b), "0x"))
+		if err != nil {
+			return tlock.Anchor{}, fmt.Errorf("parse calldata: %w", err)
+		}
+		if anchor, err = tlock.ParseCalldata(calldata); err != nil {
+			return tlock.Anchor{}, fmt.Errorf("parse calldata: %w", err)
+		}
+	}
+
+	return anchor, anchor.Verify(src)
+}
@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyProof(t *testing.T) {
+	ciphertext, err := os.ReadFile("../test.dat.tle")
+	require.NoError(t, err)
+	anchor, err := tlock.NewAnchor(bytes.NewReader(ciphertext))
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	anchorFile := filepath.Join(dir, "anchor.json")
+	b, err := json.Marshal(anchor)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(anchorFile, b, 0600))
+
+	calldataFile := filepath.Join(dir, "calldata.hex")
+	calldata, err := anchor.Calldata()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(calldataFile, []byte("0x"+hex.EncodeToString(calldata)+"\n"), 0600))
+
+	for _, name := range []string{anchorFile, calldataFile} {
+		flags, err := ParseVerifyProof([]string{"--anchor", name, "../test.dat.tle"})
+		require.NoError(t, err)
+		_, err = VerifyProof(flags, bytes.NewReader(ciphertext))
+		require.NoError(t, err)
+
+		_, err = VerifyProof(flags, bytes.NewReader(append(ciphertext, 0)))
+		require.Error(t, err)
+	}
+
+	_, err = ParseVerifyProof([]string{"../test.dat.tle"})
+	require.Error(t, err)
+}
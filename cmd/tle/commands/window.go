@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JonathanLogan/tlock/networks/http"
+)
+
+// validateWindowFlags checks the flags of decryption windows, whose not-after
+// round is recorded in the metadata of the age ciphertext.
+func validateWindowFlags(f *Flags) error {
+	if f.OpenFor != "" {
+		switch {
+		case !f.Encrypt:
+			return errors.New("--open-for can only be used with -e/--encrypt")
+		case f.Convergent != "":
+			return errors.New("--open-for can't be used with --convergent")
+		case f.Daemon != "":
+			return errors.New("--open-for can't be used with --daemon")
+		case f.Format != "" && f.Format != "binary" && f.Format != "age" && f.Format != "armor" && f.Format != "pem" && f.Format != "envelope":
+			return fmt.Errorf("--open-for can't be used with --format %s", f.Format)
+		}
+		if _, err := parseDurationsAsSeconds(time.Now(), f.OpenFor); err != nil {
+			return fmt.Errorf("--open-for: %w", err)
+		}
+	}
+	if f.EnforceWindow {
+		switch {
+		case !f.Decrypt:
+			return errors.New("--enforce-window can only be used with -d/--decrypt")
+		case f.Daemon != "":
+			return errors.New("--enforce-window can't be used with --daemon")
+		}
+	}
+	return nil
+}
+
+// notAfterRound returns the last round of the window opening at the round and
+// lasting the duration of --open-for.
+func notAfterRound(flags Flags, network *http.Network, roundNumber uint64) (uint64, error) {
+	opens := time.Unix(network.Info().GenesisTime, 0).Add(time.Duration(roundNumber-1) * network.Info().Period)
+	duration, err := parseDurationsAsSeconds(opens, flags.OpenFor)
+	if err != nil {
+		return 0, err
+	}
+	if duration <= 0 {
+		return 0, ErrInvalidDurationValue
+	}
+	return network.RoundNumber(opens.Add(duration)), nil
+}
@@ -1,16 +1,21 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"errors"
-	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
 
 	"github.com/JonathanLogan/tlock"
 	"github.com/JonathanLogan/tlock/cmd/tle/commands"
 	"github.com/JonathanLogan/tlock/networks/http"
+	"github.com/JonathanLogan/tlock/policy"
 )
 
 func main() {
@@ -21,35 +26,165 @@ func main() {
 		return
 	}
 
-	if err := run(); err != nil {
+	if err := commands.SetupMessages(); err != nil {
+		log.Print(err)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "--help-json":
+		err = commands.WriteHelpJSON(os.Stdout)
+	case "--manpage":
+		err = commands.WriteManpage(os.Stdout)
+	case "encrypt", "decrypt":
+		err = runOperation(os.Args[1])
+	case "inspect":
+		err = runInspect()
+	case "round":
+		err = runRound()
+	case "git-filter":
+		err = runGitFilter()
+	case "filter":
+		err = runFilter()
+	case "open-email":
+		err = runOpenEmail()
+	case "verify-proof":
+		err = runVerifyProof(log)
+	case "sign":
+		err = runSign()
+	case "verify":
+		err = runVerify(log)
+	case "bench":
+		err = runBench()
+	case "rewrap":
+		err = runRewrap()
+	case "push":
+		err = runPush(log)
+	case "pull":
+		err = runPull(log)
+	case "check":
+		err = runCheck()
+	case "daemon":
+		err = runDaemon()
+	case "schedule":
+		err = runSchedule(log)
+	case "ci":
+		err = runCI(log)
+	case "index":
+		err = runIndex()
+	case "tui":
+		err = runTUI()
+	case "fetch-beacon":
+		err = runFetchBeacon()
+	case "ticket":
+		err = runTicket()
+	case "puzzle":
+		err = runPuzzle()
+	case "combine":
+		err = runCombine()
+	case "cache":
+		err = runCache()
+	default:
+		err = run()
+	}
+
+	if err != nil {
+		var tooEarly *commands.TooEarlyError
 		switch {
+		case errors.As(err, &tooEarly):
+			log.Print(err)
 		case errors.Is(err, tlock.ErrTooEarly):
-			log.Fatal(errors.Unwrap(err))
+			log.Print(errors.Unwrap(err))
 		case errors.Is(err, http.ErrNotUnchained):
-			log.Fatal(http.ErrNotUnchained)
+			log.Print(http.ErrNotUnchained)
+		case errors.Is(err, tlock.ErrNotUnchained):
+			log.Print(tlock.ErrNotUnchained)
 		default:
-			log.Fatal(err)
+			log.Print(err)
 		}
 	}
+	os.Exit(commands.ExitCode(err))
 }
 
+// run runs the legacy command line, whose operation is given by a flag.
 func run() error {
-	var err error
-
 	flags, err := commands.Parse()
 	if err != nil {
 		return fmt.Errorf("parse commands: %v", err)
 	}
+	commands.WarnLegacy(os.Stderr, flags)
+
+	return runFlags(flags)
+}
+
+// runOperation runs the encrypt or decrypt subcommand.
+func runOperation(name string) error {
+	flags, err := commands.ParseOperation(name, os.Args[2:])
+	if err != nil {
+		return err
+	}
+
+	return runFlags(flags)
+}
+
+// runFlags runs the operation of the flags on their inputs.
+func runFlags(flags commands.Flags) error {
+	if err := commands.ApplyLimits(flags); err != nil {
+		return err
+	}
+
+	// Interrupted runs stop between two reads, leaving no truncated output.
+	ctx, stop := commands.TrapInterrupts()
+	defer stop()
+
+	return commands.Operation{Flags: flags}.Run(ctx)
+}
+
+func runGitFilter() error {
+	flags, err := commands.ParseGitFilter(os.Args[2:])
+	if err != nil {
+		return err
+	}
+
+	network, err := commands.NewNetwork(flags.Network, flags.Chain, flags.Flags())
+	if err != nil {
+		return err
+	}
+
+	return commands.GitFilter(flags, os.Stdout, os.Stdin, network)
+}
+
+func runFilter() error {
+	// A backup tool closing the pipe early fails the write, which is reported
+	// through the exit code rather than the signal.
+	signal.Ignore(syscall.SIGPIPE)
+
+	flags, err := commands.ParseFilter(os.Args[2:])
+	if err != nil {
+		return err
+	}
+
+	network, err := commands.NewNetwork(flags.Network, flags.Chain, flags.Flags())
+	if err != nil {
+		return err
+	}
+
+	return commands.Filter(flags, os.Stdout, os.Stdin, network)
+}
+
+func runOpenEmail() (err error) {
+	flags, err := commands.ParseOpenEmail(os.Args[2:])
+	if err != nil {
+		return err
+	}
 
 	var src io.Reader = os.Stdin
-	if name := flag.Arg(0); name != "" && name != "-" {
-		f, err := os.OpenFile(name, os.O_RDONLY, 0600)
+	if name := flags.Input; name != "" && name != "-" {
+		f, err := os.Open(name)
 		if err != nil {
 			return fmt.Errorf("failed to open input file %q: %v", name, err)
 		}
-		defer func(f *os.File) {
-			err = f.Close()
-		}(f)
+		defer f.Close()
 		src = f
 	}
 
@@ -59,25 +194,585 @@ func run() error {
 		if err != nil {
 			return fmt.Errorf("failed to open output file %q: %v", name, err)
 		}
-		defer func(f *os.File) {
-			err = f.Close()
-		}(f)
+		defer func() {
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}()
 		dst = f
 	}
 
-	network, err := http.NewNetwork(flags.Network, flags.Chain)
+	network, err := commands.NewNetwork(flags.Network, flags.Chain, commands.Flags{})
 	if err != nil {
 		return err
 	}
 
-	switch {
-	case flags.Metadata:
-		err = tlock.New(network).Metadata(dst)
-	case flags.Decrypt:
-		err = tlock.New(network).Decrypt(dst, src)
-	default:
-		err = commands.Encrypt(flags, dst, src, network)
+	return commands.OpenEmail(dst, src, network)
+}
+
+func runVerifyProof(log *log.Logger) error {
+	flags, err := commands.ParseVerifyProof(os.Args[2:])
+	if err != nil {
+		return err
+	}
+
+	var src io.Reader = os.Stdin
+	if name := flags.Input; name != "" && name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return fmt.Errorf("failed to open input file %q: %v", name, err)
+		}
+		defer f.Close()
+		src = f
+	}
+
+	anchor, err := commands.VerifyProof(flags, src)
+	if err != nil {
+		return err
+	}
+
+	log.Print(commands.Message(commands.MsgAnchorMatch, anchor.Round, anchor.ChainHash))
+	return nil
+}
+
+func runSign() error {
+	flags, err := commands.ParseSign(os.Args[2:])
+	if err != nil {
+		return err
+	}
+
+	var src io.Reader = os.Stdin
+	if name := flags.Input; name != "" && name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return fmt.Errorf("failed to open input file %q: %v", name, err)
+		}
+		defer f.Close()
+		src = f
+	}
+
+	signature, err := commands.Sign(flags, src)
+	if err != nil {
+		return err
 	}
 
+	if name := flags.Output; name != "" && name != "-" {
+		return os.WriteFile(name, signature, 0644)
+	}
+	_, err = os.Stdout.Write(signature)
 	return err
 }
+
+func runVerify(log *log.Logger) error {
+	flags, err := commands.ParseVerify(os.Args[2:])
+	if err != nil {
+		return err
+	}
+
+	var src io.Reader = os.Stdin
+	if name := flags.Input; name != "" && name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return fmt.Errorf("failed to open input file %q: %v", name, err)
+		}
+		defer f.Close()
+		src = f
+	}
+
+	if flags.Fast {
+		chunks, err := tlock.VerifyChecksums(src)
+		if err != nil {
+			return err
+		}
+		log.Print(commands.Message(commands.MsgChunksMatch, chunks))
+		return nil
+	}
+
+	signer, err := commands.Verify(flags, src)
+	if err != nil {
+		return err
+	}
+
+	log.Print(commands.Message(commands.MsgSignedBy, signer))
+	return nil
+}
+
+func runBench() error {
+	flags, err := commands.ParseBench(os.Args[2:])
+	if err != nil {
+		return err
+	}
+
+	var network tlock.Network
+	if !flags.Offline {
+		if network, err = commands.NewNetwork(flags.Network, flags.Chain, commands.Flags{}); err != nil {
+			return err
+		}
+	}
+
+	return commands.Bench(flags, os.Stdout, network)
+}
+
+func runRewrap() (err error) {
+	flags, err := commands.ParseRewrap(os.Args[2:])
+	if err != nil {
+		return err
+	}
+
+	var src io.Reader = os.Stdin
+	if name := flags.Input; name != "" && name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return fmt.Errorf("failed to open input file %q: %v", name, err)
+		}
+		defer f.Close()
+		src = f
+	}
+
+	var dst io.Writer = os.Stdout
+	if name := flags.Output; name != "" && name != "-" {
+		f, err := os.OpenFile(name, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to open output file %q: %v", name, err)
+		}
+		defer func() {
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		dst = f
+	} else if err := commands.CheckTerminal(os.Stdout, !flags.Armor, flags.ForceTTY); err != nil {
+		return err
+	}
+
+	network, err := commands.NewNetwork(flags.Network, flags.Chain, flags.Flags())
+	if err != nil {
+		return err
+	}
+
+	return commands.Rewrap(flags, dst, src, network)
+}
+
+func runPush(log *log.Logger) error {
+	flags, err := commands.ParsePush(os.Args[2:])
+	if err != nil {
+		return err
+	}
+
+	client, ref, err := commands.NewRegistryClient(flags.Reference, flags.PlainHTTP)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(flags.Input)
+	if err != nil {
+		return fmt.Errorf("failed to open input file %q: %v", flags.Input, err)
+	}
+	defer f.Close()
+
+	a, err := client.Push(context.Background(), ref, flags.Name, f)
+	if err != nil {
+		return err
+	}
+
+	log.Print(commands.Message(commands.MsgOCIPushed, ref.Registry+"/"+ref.Repository, a.Digest, a.Round, a.ChainHash))
+	return nil
+}
+
+func runPull(log *log.Logger) (err error) {
+	flags, err := commands.ParsePull(os.Args[2:])
+	if err != nil {
+		return err
+	}
+
+	client, ref, err := commands.NewRegistryClient(flags.Reference, flags.PlainHTTP)
+	if err != nil {
+		return err
+	}
+
+	var dst io.Writer = os.Stdout
+	if name := flags.Output; name != "" && name != "-" {
+		f, err := os.OpenFile(name, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to open output file %q: %v", name, err)
+		}
+		defer func() {
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		dst = f
+	} else if flags.Locked {
+		if err := commands.CheckTerminal(os.Stdout, true, flags.ForceTTY); err != nil {
+			return err
+		}
+	} else {
+		w := commands.NewTerminalWriter(os.Stdout, flags.ForceTTY)
+		defer func() {
+			if cerr := w.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		dst = w
+	}
+
+	if flags.Locked {
+		_, err := client.Pull(context.Background(), ref, dst)
+		return err
+	}
+
+	network, err := commands.NewNetwork(flags.Network, flags.Chain, flags.Flags())
+	if err != nil {
+		return err
+	}
+
+	a, err := commands.Pull(context.Background(), dst, client, ref, network)
+	if err != nil {
+		return err
+	}
+	log.Print(commands.Message(commands.MsgOCIDecrypted, a.Name, a.Digest))
+	return nil
+}
+
+func runCheck() error {
+	flags, err := commands.ParseCheck(os.Args[2:])
+	if err != nil {
+		return err
+	}
+
+	network, err := commands.NewNetwork(flags.Network, flags.Chain, flags.Flags())
+	if err != nil {
+		return err
+	}
+
+	return commands.Check(context.Background(), os.Stdout, network)
+}
+
+func runIndex() error {
+	flags, err := commands.ParseIndex(os.Args[2:])
+	if err != nil {
+		return err
+	}
+
+	network, err := commands.NewNetwork(flags.Network, flags.Chain, flags.Flags())
+	if err != nil {
+		return err
+	}
+
+	return commands.Index(os.Stdout, flags, network)
+}
+
+func runFetchBeacon() error {
+	flags, err := commands.ParseFetchBeacon(os.Args[2:])
+	if err != nil {
+		return err
+	}
+
+	network, err := commands.NewNetwork(flags.Network, flags.Chain, flags.Flags())
+	if err != nil {
+		return err
+	}
+
+	return commands.FetchBeacon(flags, network)
+}
+
+func runPuzzle() (err error) {
+	flags, err := commands.ParsePuzzle(os.Args[2:])
+	if err != nil {
+		return err
+	}
+
+	if flags.Verify {
+		if _, err := commands.ReadPuzzleSolution(flags.Input); err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, commands.Message(commands.MsgPuzzleProofValid))
+		return nil
+	}
+
+	ctx, stop := commands.TrapInterrupts()
+	defer stop()
+
+	var dst io.Writer = os.Stdout
+	if flags.Output != "" {
+		f, err := os.OpenFile(flags.Output, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open output file %q: %v", flags.Output, err)
+		}
+		defer func() {
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				os.Remove(flags.Output)
+			}
+		}()
+		dst = f
+	}
+
+	return commands.SolvePuzzle(ctx, dst, os.Stderr, flags)
+}
+
+func runTicket() error {
+	flags, err := commands.ParseTicket(os.Args[2:])
+	if err != nil {
+		return err
+	}
+
+	network, err := commands.NewNetwork(flags.Network, flags.Chain, flags.Flags())
+	if err != nil {
+		return err
+	}
+
+	if flags.Output == "" || flags.Output == "-" {
+		return commands.IssueTicket(os.Stdout, flags, network)
+	}
+	o, err := commands.CreateOutput(flags.Output)
+	if err != nil {
+		return err
+	}
+	return o.Finish(commands.IssueTicket(o, flags, network))
+}
+
+func runCombine() error {
+	flags, err := commands.ParseCombine(os.Args[2:])
+	if err != nil {
+		return err
+	}
+
+	return commands.Combine(os.Stdout, flags)
+}
+
+func runCache() error {
+	flags, err := commands.ParseCache(os.Args[2:])
+	if err != nil {
+		return err
+	}
+
+	report, err := commands.CollectGarbage(flags)
+	for _, name := range report.Removed {
+		fmt.Println(name)
+	}
+	if err != nil {
+		return err
+	}
+	if flags.DryRun {
+		fmt.Fprintln(os.Stderr, commands.Message(commands.MsgCacheWouldFree, report.Freed))
+	} else {
+		fmt.Fprintln(os.Stderr, commands.Message(commands.MsgCacheFreed, report.Freed))
+	}
+	return nil
+}
+
+func runInspect() error {
+	flags, err := commands.ParseInspect(os.Args[2:])
+	if err != nil {
+		return err
+	}
+
+	network, err := commands.NewNetwork(flags.Network, flags.Chain, commands.Flags{})
+	if err != nil {
+		return err
+	}
+
+	return commands.Inspect(os.Stdout, flags, network)
+}
+
+func runRound() error {
+	flags, err := commands.ParseRound(os.Args[2:])
+	if err != nil {
+		return err
+	}
+
+	network, err := commands.NewNetwork(flags.Network, flags.Chain, commands.Flags{})
+	if err != nil {
+		return err
+	}
+
+	return commands.Round(os.Stdout, os.Stderr, flags, network)
+}
+
+func runTUI() error {
+	flags, err := commands.ParseTUI(os.Args[2:])
+	if err != nil {
+		return err
+	}
+
+	network, err := commands.NewNetwork(flags.Network, flags.Chain, commands.Flags{})
+	if err != nil {
+		return err
+	}
+
+	dashboard, err := commands.NewDashboard(flags, network)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return dashboard.Run(ctx, os.Stdout, flags.Interval)
+}
+
+func runDaemon() error {
+	flags, err := commands.ParseDaemon(os.Args[2:])
+	if err != nil {
+		return err
+	}
+
+	network, err := commands.NewNetwork(flags.Network, flags.Chain, commands.Flags{})
+	if err != nil {
+		return err
+	}
+
+	var p *policy.Engine
+	if flags.Policy != "" {
+		if p, err = commands.LoadPolicy(flags.Policy); err != nil {
+			return err
+		}
+	}
+
+	lis, err := commands.ListenDaemon(flags.Socket)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(flags.Socket)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return commands.Daemon(ctx, lis, network, p)
+}
+
+func runSchedule(log *log.Logger) error {
+	flags, err := commands.ParseSchedule(os.Args[2:])
+	if err != nil {
+		return err
+	}
+
+	network, err := commands.NewNetwork(flags.Network, flags.Chain, commands.Flags{})
+	if err != nil {
+		return err
+	}
+
+	schedule, err := commands.NewSchedule(flags, network)
+	if err != nil {
+		return err
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case flags.InstallTask:
+		if err := schedule.InstallScheduledTask(executable, flags); err != nil {
+			return err
+		}
+		log.Print(commands.Message(commands.MsgScheduledTask, schedule.Name, commands.FormatTime(schedule.Unlock, nil)))
+		return nil
+	case !flags.InstallSystemd && runtime.GOOS == "windows":
+		task, err := schedule.TaskXML(executable, flags)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(task)
+		return err
+	case !flags.InstallSystemd:
+		service, timer := schedule.SystemdUnits(executable, flags)
+		fmt.Printf("# %s.service\n%s\n# %s.timer\n%s", schedule.Name, service, schedule.Name, timer)
+		return nil
+	}
+
+	path, err := schedule.InstallSystemd(flags.UnitDir, executable, flags)
+	if err != nil {
+		return err
+	}
+	log.Print(commands.Message(commands.MsgScheduledTimer, path, commands.FormatTime(schedule.Unlock, nil)))
+	log.Print(commands.Message(commands.MsgScheduledEnable, schedule.Name))
+	return nil
+}
+
+func runCI(log *log.Logger) (err error) {
+	flags, err := commands.ParseCI(os.Args[2:])
+	if err != nil {
+		return err
+	}
+
+	network, err := commands.NewNetwork(flags.Network, flags.Chain, commands.Flags{})
+	if err != nil {
+		return err
+	}
+
+	var src io.Reader = os.Stdin
+	if flags.Input != "-" {
+		f, err := os.Open(flags.Input)
+		if err != nil {
+			return fmt.Errorf("failed to open input file %q: %v", flags.Input, err)
+		}
+		defer f.Close()
+		src = f
+	}
+
+	if !flags.Open {
+		f, err := os.OpenFile(flags.Output, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open output file %q: %v", flags.Output, err)
+		}
+		roundNumber, err := commands.CISeal(flags, f, src, network)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(flags.Output)
+			return err
+		}
+		unlock, _ := tlock.RoundTime(network, roundNumber)
+		log.Print(commands.Message(commands.MsgCISealed, flags.Output, commands.FormatTime(unlock, nil)))
+		fmt.Println(commands.CIOpenCommand(flags))
+		return nil
+	}
+
+	provider := flags.Provider
+	if provider == "" {
+		provider = commands.DetectProvider(os.Getenv)
+	}
+	output := flags.Output
+	switch {
+	case output == "" && provider == commands.ProviderGitHub:
+		if output = os.Getenv("GITHUB_ENV"); output == "" {
+			return errors.New("GITHUB_ENV is not set, give -o OUTPUT")
+		}
+	case output == "":
+		return errors.New("-o OUTPUT is required outside of GitHub Actions, the values not being masked")
+	}
+
+	var plaintext bytes.Buffer
+	if err := tlock.New(network).Decrypt(&plaintext, src); err != nil {
+		return err
+	}
+	vars, err := commands.ParseEnv(&plaintext)
+	if err != nil {
+		return err
+	}
+
+	f, err := commands.OpenEnvOutput(output)
+	if err != nil {
+		return fmt.Errorf("failed to open output file %q: %v", output, err)
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	if provider == commands.ProviderGitHub {
+		err = commands.WriteGitHubEnv(os.Stdout, f, vars)
+	} else {
+		err = commands.WriteShellEnv(f, vars)
+	}
+	if err != nil {
+		return err
+	}
+	log.Print(commands.Message(commands.MsgEnvExported, len(vars), output))
+	return nil
+}
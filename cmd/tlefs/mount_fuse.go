@@ -0,0 +1,187 @@
+//go:build linux || darwin
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/lockedfs"
+	fusefs "github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// attrTimeout is how long the kernel caches the attributes and entries, and
+// so how late after their rounds the files become readable.
+const attrTimeout = time.Second
+
+// mount serves the filesystem at the mountpoint, read-only.
+func mount(fsys *lockedfs.FS, dir string, mountpoint string, opts ...func(*fuse.MountOptions)) (mounted, error) {
+	timeout := attrTimeout
+	options := fusefs.Options{
+		MountOptions: fuse.MountOptions{
+			FsName:  dir,
+			Name:    "tlefs",
+			Options: []string{"ro"},
+		},
+		EntryTimeout: &timeout,
+		AttrTimeout:  &timeout,
+		// Locked files have no permissions.
+		NullPermissions: true,
+	}
+	for _, opt := range opts {
+		opt(&options.MountOptions)
+	}
+	return fusefs.Mount(mountpoint, &node{fsys: fsys, name: "."}, &options)
+}
+
+// node is a file or directory of the filesystem, named by its path in it.
+type node struct {
+	fusefs.Inode
+	fsys *lockedfs.FS
+	name string
+}
+
+var (
+	_ fusefs.NodeLookuper  = (*node)(nil)
+	_ fusefs.NodeReaddirer = (*node)(nil)
+	_ fusefs.NodeGetattrer = (*node)(nil)
+	_ fusefs.NodeOpener    = (*node)(nil)
+	_ fusefs.NodeReader    = (*node)(nil)
+	_ fusefs.NodeReleaser  = (*node)(nil)
+)
+
+func (n *node) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fusefs.Inode, syscall.Errno) {
+	child := path.Join(n.name, name)
+	info, err := n.fsys.Stat(child)
+	if err != nil {
+		return nil, toErrno(err)
+	}
+	setAttr(&out.Attr, info)
+	return n.NewInode(ctx, &node{fsys: n.fsys, name: child}, fusefs.StableAttr{Mode: fileType(info)}), 0
+}
+
+func (n *node) Readdir(context.Context) (fusefs.DirStream, syscall.Errno) {
+	entries, err := n.fsys.ReadDir(n.name)
+	if err != nil {
+		return nil, toErrno(err)
+	}
+	list := make([]fuse.DirEntry, 0, len(entries))
+	for _, entry := range entries {
+		mode := uint32(syscall.S_IFREG)
+		if entry.IsDir() {
+			mode = syscall.S_IFDIR
+		}
+		list = append(list, fuse.DirEntry{Name: entry.Name(), Mode: mode})
+	}
+	return fusefs.NewListDirStream(list), 0
+}
+
+// Getattr stats the file again, its permissions and size changing once its
+// round has passed.
+func (n *node) Getattr(_ context.Context, _ fusefs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	info, err := n.fsys.Stat(n.name)
+	if err != nil {
+		return toErrno(err)
+	}
+	setAttr(&out.Attr, info)
+	return 0
+}
+
+// Open decrypts the file as it is read. Encoded ciphertexts can only be read
+// in sequence, bypassing the page cache.
+func (n *node) Open(_ context.Context, flags uint32) (fusefs.FileHandle, uint32, syscall.Errno) {
+	if flags&(syscall.O_WRONLY|syscall.O_RDWR) != 0 {
+		return nil, 0, syscall.EROFS
+	}
+	f, err := n.fsys.Open(n.name)
+	if err != nil {
+		return nil, 0, toErrno(err)
+	}
+	h := &handle{f: f}
+	if _, ok := f.(io.ReaderAt); !ok {
+		return h, fuse.FOPEN_DIRECT_IO, 0
+	}
+	return h, fuse.FOPEN_KEEP_CACHE, 0
+}
+
+func (n *node) Read(_ context.Context, fh fusefs.FileHandle, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	m, err := fh.(*handle).readAt(dest, off)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, toErrno(err)
+	}
+	return fuse.ReadResultData(dest[:m]), 0
+}
+
+func (n *node) Release(_ context.Context, fh fusefs.FileHandle) syscall.Errno {
+	return toErrno(fh.(*handle).f.Close())
+}
+
+// handle is an open file, read at any offset when it implements io.ReaderAt
+// and in sequence otherwise.
+type handle struct {
+	f fs.File
+
+	mu  sync.Mutex
+	pos int64
+}
+
+func (h *handle) readAt(p []byte, off int64) (int, error) {
+	if r, ok := h.f.(io.ReaderAt); ok {
+		return r.ReadAt(p, off)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if off < h.pos {
+		return 0, syscall.ESPIPE
+	}
+	if _, err := io.CopyN(io.Discard, h.f, off-h.pos); err != nil {
+		return 0, err
+	}
+	n, err := io.ReadFull(h.f, p)
+	h.pos = off + int64(n)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// setAttr sets the attributes of the file, without the write permissions.
+func setAttr(out *fuse.Attr, info fs.FileInfo) {
+	out.Mode = fileType(info) | uint32(info.Mode().Perm()&^0o222)
+	out.Size = uint64(info.Size())
+	mtime := info.ModTime()
+	out.SetTimes(nil, &mtime, nil)
+}
+
+// fileType returns the type bits of the mode of the file.
+func fileType(info fs.FileInfo) uint32 {
+	if info.IsDir() {
+		return syscall.S_IFDIR
+	}
+	return syscall.S_IFREG
+}
+
+// toErrno returns the error number of the error, files locked until their
+// round being denied.
+func toErrno(err error) syscall.Errno {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, tlock.ErrTooEarly):
+		return syscall.EACCES
+	case errors.Is(err, fs.ErrNotExist):
+		return syscall.ENOENT
+	case errors.Is(err, fs.ErrPermission):
+		return syscall.EACCES
+	}
+	return fusefs.ToErrno(err)
+}
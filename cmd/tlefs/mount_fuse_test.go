@@ -0,0 +1,56 @@
+//go:build linux || darwin
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/lockedfs"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMount(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1)
+	dir := t.TempDir()
+	plaintext := []byte("released content")
+	for name, roundNumber := range map[string]uint64{"released.txt.tle": 1, "later.txt.tle": 1000} {
+		var ciphertext bytes.Buffer
+		require.NoError(t, tlock.New(network).Encrypt(&ciphertext, bytes.NewReader(plaintext), roundNumber))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), ciphertext.Bytes(), 0o600))
+	}
+
+	mountpoint := t.TempDir()
+	m, err := mount(lockedfs.New(dir, network), dir, mountpoint, func(o *fuse.MountOptions) {
+		o.DirectMount = true
+	})
+	if err != nil {
+		t.Skipf("FUSE can't be mounted: %v", err)
+	}
+	defer m.Wait()
+	defer m.Unmount()
+
+	entries, err := os.ReadDir(mountpoint)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	data, err := os.ReadFile(filepath.Join(mountpoint, "released.txt"))
+	require.NoError(t, err)
+	require.Equal(t, plaintext, data)
+
+	// Locked files are stat'ed, without permissions, but can't be read.
+	info, err := os.Stat(filepath.Join(mountpoint, "later.txt"))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0), info.Mode().Perm())
+	require.WithinDuration(t, time.Now(), info.ModTime(), time.Hour)
+	_, err = os.ReadFile(filepath.Join(mountpoint, "later.txt"))
+	require.ErrorIs(t, err, os.ErrPermission)
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin
+
+package main
+
+import (
+	"errors"
+
+	"github.com/JonathanLogan/tlock/lockedfs"
+)
+
+// mount isn't supported on this system, which lacks FUSE.
+func mount(fsys *lockedfs.FS, dir string, mountpoint string) (mounted, error) {
+	return nil, errors.New("mounting requires FUSE, on Linux or macOS")
+}
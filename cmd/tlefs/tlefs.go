@@ -0,0 +1,134 @@
+// Command tlefs mounts a directory of tlock ciphertexts as a read-only
+// filesystem, in which each FILE.tle appears as FILE. Before its round, a
+// file can be listed and stat'ed, from its header or the index, but has no
+// permissions and can't be opened. Once its round has passed, it becomes
+// readable without remounting, decrypted as it is read.
+//
+//	tlefs [-n NETWORK] [-c CHAIN] [--index FILE] DIR MOUNTPOINT
+//
+// The filesystem is served until it is unmounted, or tlefs is interrupted.
+// It requires FUSE, on Linux or macOS.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/JonathanLogan/tlock/cmd/tle/commands"
+	"github.com/JonathanLogan/tlock/lockedfs"
+	"github.com/JonathanLogan/tlock/networks/http"
+)
+
+const usage = `Usage:
+	tlefs [-n NETWORK] [-c CHAIN] [--index FILE] DIR MOUNTPOINT
+
+Mounts the ciphertexts of DIR as a read-only filesystem at MOUNTPOINT, each
+FILE.tle appearing as FILE, readable once its round has passed.
+
+Flags:
+	-n, --network NETWORK  The drand relay to fetch the beacons from.
+	-c, --chain CHAIN      The chain hash of the network.
+	--index FILE           Read the rounds of the files from the index written
+	                       by lockedfs, rather than from their headers.
+	--auth-token TOKEN     The bearer token of a private relay, also read from
+	                       TLE_AUTHTOKEN.`
+
+// flags are the options of the command.
+type flags struct {
+	Network    string
+	Chain      string
+	Index      string
+	AuthToken  string
+	Dir        string
+	Mountpoint string
+}
+
+func main() {
+	log := log.New(os.Stderr, "", 0)
+
+	f, err := parse(os.Args[1:])
+	if err != nil {
+		log.Print(err)
+		os.Exit(2)
+	}
+	if err := run(f); err != nil {
+		log.Print(err)
+		os.Exit(1)
+	}
+}
+
+// parse parses the arguments of the command.
+func parse(args []string) (flags, error) {
+	f := flags{AuthToken: os.Getenv("TLE_AUTHTOKEN")}
+	fs := flag.NewFlagSet("tlefs", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {}
+	fs.StringVar(&f.Network, "n", commands.DefaultNetwork, "")
+	fs.StringVar(&f.Network, "network", commands.DefaultNetwork, "")
+	fs.StringVar(&f.Chain, "c", commands.DefaultChain, "")
+	fs.StringVar(&f.Chain, "chain", commands.DefaultChain, "")
+	fs.StringVar(&f.Index, "index", "", "")
+	fs.StringVar(&f.AuthToken, "auth-token", f.AuthToken, "")
+	if err := fs.Parse(args); err != nil {
+		return flags{}, fmt.Errorf("%w\n\n%s", err, usage)
+	}
+	if fs.NArg() != 2 {
+		return flags{}, errors.New(usage)
+	}
+	f.Dir, f.Mountpoint = fs.Arg(0), fs.Arg(1)
+	return f, nil
+}
+
+// run serves the filesystem of the flags until it is unmounted.
+func run(f flags) error {
+	var opts []http.Option
+	if f.AuthToken != "" {
+		opts = append(opts, http.WithBearerToken(f.AuthToken))
+	}
+	network, err := http.NewNetwork(f.Network, f.Chain, opts...)
+	if err != nil {
+		return err
+	}
+
+	fsys := lockedfs.New(f.Dir, network)
+	if f.Index != "" {
+		r, err := os.Open(f.Index)
+		if err != nil {
+			return err
+		}
+		idx, err := lockedfs.ReadIndex(r)
+		r.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Index, err)
+		}
+		fsys = fsys.WithIndex(idx)
+	}
+
+	m, err := mount(fsys, f.Dir, f.Mountpoint)
+	if err != nil {
+		return err
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		if err := m.Unmount(); err != nil {
+			fmt.Fprintf(os.Stderr, "unmount %s: %v\n", f.Mountpoint, err)
+		}
+	}()
+	m.Wait()
+	return nil
+}
+
+// mounted is a filesystem being served.
+type mounted interface {
+	Unmount() error
+	Wait()
+}
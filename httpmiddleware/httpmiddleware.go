@@ -0,0 +1,126 @@
+// Package httpmiddleware provides an http.Handler middleware serving timelocked
+// payloads, answering 425 Too Early until their round is reached and the
+// decrypted content afterwards. It is meant for embargoed download servers.
+package httpmiddleware
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+)
+
+// Handler wraps a handler serving tlock ciphertexts.
+type Handler struct {
+	network tlock.Network
+	next    http.Handler
+}
+
+// New constructs a handler decrypting the successful responses of next with
+// the network. Responses which aren't tlock ciphertexts are passed through.
+func New(network tlock.Network, next http.Handler) *Handler {
+	return &Handler{
+		network: network,
+		next:    next,
+	}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec := recorder{header: make(http.Header), status: http.StatusOK}
+	h.next.ServeHTTP(&rec, r)
+
+	if rec.status != http.StatusOK {
+		rec.writeTo(w)
+		return
+	}
+
+	hdr, err := tlock.ReadHeader(bufio.NewReader(bytes.NewReader(rec.body.Bytes())))
+	if err != nil {
+		rec.writeTo(w)
+		return
+	}
+	roundNumber, _, err := hdr.Round()
+	if err != nil {
+		rec.writeTo(w)
+		return
+	}
+
+	if h.network.Current(time.Now()) < roundNumber {
+		h.tooEarly(w, roundNumber)
+		return
+	}
+
+	copyHeader(w.Header(), rec.header)
+	w.Header().Del("Content-Length")
+	dst := lazyWriter{w: w}
+	// The network outlives the response, whose body mustn't switch its chain.
+	if err := tlock.New(h.network).Strict().Decrypt(&dst, &rec.body); err != nil && !dst.written {
+		if errors.Is(err, tlock.ErrTooEarly) {
+			h.tooEarly(w, roundNumber)
+			return
+		}
+		http.Error(w, "decrypt: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// tooEarly answers that the content is not available yet, with the time
+// until the round is reached if the network can tell it.
+func (h *Handler) tooEarly(w http.ResponseWriter, roundNumber uint64) {
+	if eta, ok := tlock.RoundTime(h.network, roundNumber); ok {
+		seconds := math.Ceil(time.Until(eta).Seconds())
+		w.Header().Set("Retry-After", strconv.Itoa(max(1, int(seconds))))
+	}
+	http.Error(w, "content is timelocked until round "+strconv.FormatUint(roundNumber, 10), http.StatusTooEarly)
+}
+
+// =============================================================================
+
+// recorder captures the response of the wrapped handler.
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) Header() http.Header {
+	return r.header
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	return r.body.Write(p)
+}
+
+func (r *recorder) WriteHeader(status int) {
+	r.status = status
+}
+
+// writeTo writes the captured response unchanged.
+func (r *recorder) writeTo(w http.ResponseWriter) {
+	copyHeader(w.Header(), r.header)
+	w.WriteHeader(r.status)
+	_, _ = w.Write(r.body.Bytes())
+}
+
+// lazyWriter tracks whether the response has been started, after which the
+// status can't be changed anymore.
+type lazyWriter struct {
+	w       http.ResponseWriter
+	written bool
+}
+
+func (l *lazyWriter) Write(p []byte) (int, error) {
+	l.written = true
+	return l.w.Write(p)
+}
+
+func copyHeader(dst, src http.Header) {
+	for k, v := range src {
+		dst[k] = v
+	}
+}
@@ -0,0 +1,62 @@
+package httpmiddleware_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/httpmiddleware"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1)
+	plaintext := []byte("embargoed content")
+
+	var unlocked, locked bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&unlocked, bytes.NewReader(plaintext), 1))
+	require.NoError(t, tlock.New(network).Encrypt(&locked, bytes.NewReader(plaintext), 100))
+
+	files := map[string][]byte{
+		"/unlocked": unlocked.Bytes(),
+		"/locked":   locked.Bytes(),
+		"/plain":    plaintext,
+	}
+	h := httpmiddleware.New(network, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		_, _ = w.Write(data)
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/unlocked", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, plaintext, rec.Body.Bytes())
+	require.Empty(t, rec.Header().Get("Content-Length"))
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/locked", nil))
+	require.Equal(t, http.StatusTooEarly, rec.Code)
+	retryAfter, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	require.InDelta(t, 99*3, retryAfter, 3)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/plain", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, plaintext, rec.Body.Bytes())
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
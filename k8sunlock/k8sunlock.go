@@ -0,0 +1,265 @@
+// Package k8sunlock provides a reusable controller materializing Kubernetes
+// Secrets from LockedSecret custom resources once the rounds of their
+// timelocked data are reached, for staged credential releases. It doesn't
+// depend on a Kubernetes client library: the API access is abstracted by the
+// Client interface, to be implemented with the client the platform uses. The
+// matching custom resource definition is in crd.yaml.
+package k8sunlock
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/watcher"
+)
+
+// These are the phases of a LockedSecret.
+const (
+	PhasePending  = "Pending"
+	PhaseUnlocked = "Unlocked"
+	PhaseFailed   = "Failed"
+)
+
+// LockedSecret is the custom resource referencing timelocked data.
+type LockedSecret struct {
+	Namespace string
+	Name      string
+	Spec      LockedSecretSpec
+	Status    LockedSecretStatus
+}
+
+// LockedSecretSpec is the desired state of a LockedSecret.
+type LockedSecretSpec struct {
+	// SecretName is the name of the Secret to materialize, in the namespace
+	// of the LockedSecret.
+	SecretName string
+	// Data maps the Secret keys to their tlock ciphertexts, armored or not.
+	Data map[string][]byte
+}
+
+// LockedSecretStatus is the observed state of a LockedSecret.
+type LockedSecretStatus struct {
+	Phase      string
+	Round      uint64
+	UnlockTime time.Time
+	Message    string
+}
+
+// EventType is the type of a watch event.
+type EventType int
+
+// These are the types of watch events.
+const (
+	Added EventType = iota
+	Modified
+	Deleted
+)
+
+// Event is a change of a LockedSecret.
+type Event struct {
+	Type   EventType
+	Object LockedSecret
+}
+
+// Client abstracts the Kubernetes API calls needed by the controller.
+type Client interface {
+	// ListLockedSecrets lists the LockedSecrets of all watched namespaces.
+	ListLockedSecrets(ctx context.Context) ([]LockedSecret, error)
+	// WatchLockedSecrets streams the changes of LockedSecrets until the
+	// context is done.
+	WatchLockedSecrets(ctx context.Context) (<-chan Event, error)
+	// ApplySecret creates or updates the Secret with the data.
+	ApplySecret(ctx context.Context, namespace, name string, data map[string][]byte) error
+	// UpdateStatus records the status of the LockedSecret.
+	UpdateStatus(ctx context.Context, namespace, name string, status LockedSecretStatus) error
+}
+
+// Controller reconciles LockedSecrets.
+type Controller struct {
+	client  Client
+	network tlock.Network
+	watcher *watcher.Watcher
+	resync  time.Duration
+}
+
+// New constructs a controller decrypting with the network. All resources are
+// reconciled again every resync period, in addition to the watch events.
+func New(client Client, network tlock.Network, resync time.Duration) *Controller {
+	return &Controller{
+		client:  client,
+		network: network,
+		watcher: watcher.New(network),
+		resync:  resync,
+	}
+}
+
+// Run reconciles the LockedSecrets until the context is done. Pending ones are
+// reconciled again as soon as their round is reached.
+func (c *Controller) Run(ctx context.Context) error {
+	events, err := c.client.WatchLockedSecrets(ctx)
+	if err != nil {
+		return fmt.Errorf("watch locked secrets: %w", err)
+	}
+
+	// A single waiter per LockedSecret requeues it once its round is reached,
+	// replaced when the round changes and cancelled when it is deleted or
+	// no longer pending.
+	waiting := make(map[string]*waiter)
+	stop := func(key string) {
+		if w, ok := waiting[key]; ok {
+			w.cancel()
+			delete(waiting, key)
+		}
+	}
+	requeue := make(chan *waiter)
+	reconcile := func(ls LockedSecret) {
+		key := ls.Namespace + "/" + ls.Name
+		status := c.Reconcile(ctx, ls)
+		if status.Phase != PhasePending {
+			stop(key)
+			return
+		}
+		if w, ok := waiting[key]; ok && w.round == status.Round {
+			w.ls = ls
+			return
+		}
+		stop(key)
+		wctx, cancel := context.WithCancel(ctx)
+		w := &waiter{ls: ls, round: status.Round, cancel: cancel}
+		waiting[key] = w
+		go func() {
+			if _, ok := <-c.watcher.Subscribe(wctx, w.round); ok {
+				select {
+				case requeue <- w:
+				case <-wctx.Done():
+				}
+			}
+		}()
+	}
+	defer func() {
+		for key := range waiting {
+			stop(key)
+		}
+	}()
+
+	resync := func() error {
+		list, err := c.client.ListLockedSecrets(ctx)
+		if err != nil {
+			return fmt.Errorf("list locked secrets: %w", err)
+		}
+		listed := make(map[string]bool, len(list))
+		for _, ls := range list {
+			listed[ls.Namespace+"/"+ls.Name] = true
+			reconcile(ls)
+		}
+		// The deletions missed by the watch are caught up with.
+		for key := range waiting {
+			if !listed[key] {
+				stop(key)
+			}
+		}
+		return nil
+	}
+	if err := resync(); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(c.resync)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := resync(); err != nil {
+				return err
+			}
+		case w := <-requeue:
+			key := w.ls.Namespace + "/" + w.ls.Name
+			if waiting[key] != w {
+				continue
+			}
+			delete(waiting, key)
+			reconcile(w.ls)
+		case ev, ok := <-events:
+			if !ok {
+				return fmt.Errorf("watch locked secrets: channel closed")
+			}
+			if ev.Type == Deleted {
+				stop(ev.Object.Namespace + "/" + ev.Object.Name)
+				continue
+			}
+			reconcile(ev.Object)
+		}
+	}
+}
+
+// waiter waits for the round of a pending LockedSecret.
+type waiter struct {
+	ls     LockedSecret
+	round  uint64
+	cancel context.CancelFunc
+}
+
+// Reconcile materializes the Secret of the LockedSecret if all its data can
+// be decrypted, and records and returns the resulting status.
+func (c *Controller) Reconcile(ctx context.Context, ls LockedSecret) LockedSecretStatus {
+	status := c.reconcile(ctx, ls)
+	if status != ls.Status {
+		if err := c.client.UpdateStatus(ctx, ls.Namespace, ls.Name, status); err != nil {
+			status.Phase = PhaseFailed
+			status.Message = fmt.Sprintf("update status: %v", err)
+		}
+	}
+	return status
+}
+
+func (c *Controller) reconcile(ctx context.Context, ls LockedSecret) LockedSecretStatus {
+	if ls.Status.Phase == PhaseUnlocked {
+		return ls.Status
+	}
+
+	// The Secret is only materialized once every key can be decrypted.
+	var status LockedSecretStatus
+	for key, ciphertext := range ls.Spec.Data {
+		hdr, err := tlock.ParseHeader(bytes.NewReader(ciphertext))
+		if err != nil {
+			return LockedSecretStatus{Phase: PhaseFailed, Message: fmt.Sprintf("key %q: %v", key, err)}
+		}
+		roundNumber, _, err := hdr.Round()
+		if err != nil {
+			return LockedSecretStatus{Phase: PhaseFailed, Message: fmt.Sprintf("key %q: %v", key, err)}
+		}
+		status.Round = max(status.Round, roundNumber)
+	}
+	if eta, ok := tlock.RoundTime(c.network, status.Round); ok {
+		status.UnlockTime = eta.UTC()
+	}
+
+	if !c.watcher.Reached(status.Round) {
+		status.Phase = PhasePending
+		return status
+	}
+
+	// The network is shared by the resources of the cluster, which mustn't
+	// switch its chain.
+	data := make(map[string][]byte, len(ls.Spec.Data))
+	for key, ciphertext := range ls.Spec.Data {
+		var plaintext bytes.Buffer
+		if err := tlock.New(c.network).Strict().Decrypt(&plaintext, bytes.NewReader(ciphertext)); err != nil {
+			return LockedSecretStatus{Phase: PhaseFailed, Round: status.Round, Message: fmt.Sprintf("key %q: %v", key, err)}
+		}
+		data[key] = plaintext.Bytes()
+	}
+
+	if err := c.client.ApplySecret(ctx, ls.Namespace, ls.Spec.SecretName, data); err != nil {
+		return LockedSecretStatus{Phase: PhaseFailed, Round: status.Round, Message: fmt.Sprintf("apply secret: %v", err)}
+	}
+
+	status.Phase = PhaseUnlocked
+	return status
+}
@@ -0,0 +1,153 @@
+package k8sunlock_test
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/k8sunlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestController(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	key := fixedtest.NewKey(nil)
+	key.Period = time.Second
+	network := key.SigningNetwork(t)
+
+	client := fakeClient{
+		lockedSecrets: []k8sunlock.LockedSecret{
+			lockedSecret(t, network, "now", 1),
+			lockedSecret(t, network, "soon", 2),
+			lockedSecret(t, network, "later", 1000),
+		},
+		secrets:  make(map[string]map[string][]byte),
+		statuses: make(map[string]k8sunlock.LockedSecretStatus),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go func() { _ = k8sunlock.New(&client, network, time.Minute).Run(ctx) }()
+
+	require.Eventually(t, func() bool {
+		client.mu.Lock()
+		defer client.mu.Unlock()
+		return len(client.secrets) == 2
+	}, 4*time.Second, 50*time.Millisecond)
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	require.Equal(t, []byte("password for now"), client.secrets["default/now-secret"]["password"])
+	require.Equal(t, []byte("password for soon"), client.secrets["default/soon-secret"]["password"])
+	require.Equal(t, k8sunlock.PhaseUnlocked, client.statuses["default/soon"].Phase)
+	require.Equal(t, k8sunlock.PhasePending, client.statuses["default/later"].Phase)
+	require.Equal(t, uint64(1000), client.statuses["default/later"].Round)
+}
+
+func TestControllerWaitsOncePerResource(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	key := fixedtest.NewKey(nil)
+	key.Period = time.Second
+	network := key.SigningNetwork(t)
+
+	later := lockedSecret(t, network, "later", 1000)
+	client := fakeClient{
+		lockedSecrets: []k8sunlock.LockedSecret{later},
+		events:        make(chan k8sunlock.Event),
+		secrets:       make(map[string]map[string][]byte),
+		statuses:      make(map[string]k8sunlock.LockedSecretStatus),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	before := runtime.NumGoroutine()
+	go func() { _ = k8sunlock.New(&client, network, 5*time.Millisecond).Run(ctx) }()
+
+	// Resyncing every few milliseconds mustn't pile up waiters.
+	time.Sleep(200 * time.Millisecond)
+	require.Less(t, runtime.NumGoroutine(), before+5)
+
+	client.mu.Lock()
+	client.lockedSecrets = nil
+	client.mu.Unlock()
+	client.events <- k8sunlock.Event{Type: k8sunlock.Deleted, Object: later}
+	// Only the controller remains, along with the condition of Eventually.
+	require.Eventually(t, func() bool {
+		return runtime.NumGoroutine() <= before+2
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestReconcileInvalidCiphertext(t *testing.T) {
+	key := fixedtest.NewKey(nil)
+	key.Period = time.Second
+	network := key.SigningNetwork(t)
+	client := fakeClient{statuses: make(map[string]k8sunlock.LockedSecretStatus)}
+
+	status := k8sunlock.New(&client, network, time.Minute).Reconcile(context.Background(), k8sunlock.LockedSecret{
+		Namespace: "default",
+		Name:      "broken",
+		Spec:      k8sunlock.LockedSecretSpec{SecretName: "broken", Data: map[string][]byte{"key": []byte("garbage")}},
+	})
+	require.Equal(t, k8sunlock.PhaseFailed, status.Phase)
+	require.Equal(t, status, client.statuses["default/broken"])
+}
+
+func lockedSecret(t *testing.T, network tlock.Network, name string, roundNumber uint64) k8sunlock.LockedSecret {
+	t.Helper()
+
+	var ciphertext bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&ciphertext, bytes.NewReader([]byte("password for "+name)), roundNumber))
+
+	return k8sunlock.LockedSecret{
+		Namespace: "default",
+		Name:      name,
+		Spec: k8sunlock.LockedSecretSpec{
+			SecretName: name + "-secret",
+			Data:       map[string][]byte{"password": ciphertext.Bytes()},
+		},
+	}
+}
+
+// =============================================================================
+
+type fakeClient struct {
+	mu            sync.Mutex
+	lockedSecrets []k8sunlock.LockedSecret
+	events        chan k8sunlock.Event
+	secrets       map[string]map[string][]byte
+	statuses      map[string]k8sunlock.LockedSecretStatus
+}
+
+func (c *fakeClient) ListLockedSecrets(context.Context) ([]k8sunlock.LockedSecret, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lockedSecrets, nil
+}
+
+func (c *fakeClient) WatchLockedSecrets(context.Context) (<-chan k8sunlock.Event, error) {
+	if c.events != nil {
+		return c.events, nil
+	}
+	return make(chan k8sunlock.Event), nil
+}
+
+func (c *fakeClient) ApplySecret(_ context.Context, namespace, name string, data map[string][]byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.secrets[namespace+"/"+name] = data
+	return nil
+}
+
+func (c *fakeClient) UpdateStatus(_ context.Context, namespace, name string, status k8sunlock.LockedSecretStatus) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.statuses[namespace+"/"+name] = status
+	return nil
+}
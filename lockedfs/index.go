@@ -0,0 +1,104 @@
+package lockedfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+)
+
+// Index catalogs the ciphertexts of a directory, so that dashboards and
+// filesystems can list them without opening each of them.
+type Index struct {
+	Files []IndexEntry `json:"files"`
+}
+
+// IndexEntry describes a ciphertext of an index. The size and modification
+// time are the ones of the ciphertext, telling when the entry is stale.
+type IndexEntry struct {
+	File      string     `json:"file"`
+	Round     uint64     `json:"round"`
+	Unlocks   *time.Time `json:"unlocks,omitempty"`
+	Size      int64      `json:"size"`
+	Modified  time.Time  `json:"modified"`
+	ChainHash string     `json:"chain"`
+}
+
+// Index walks the directory and returns the index of the ciphertexts under
+// it, whose files are named relative to the directory, suffix included.
+// Ciphertexts whose header can't be parsed are left out.
+func (f *FS) Index() (Index, error) {
+	idx := Index{Files: []IndexEntry{}}
+	err := fs.WalkDir(f, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		ci, err := os.Stat(f.path(name) + Suffix)
+		if err != nil {
+			return err
+		}
+
+		e := IndexEntry{
+			File:      name + Suffix,
+			Round:     info.Sys().(*Info).Round,
+			Size:      ci.Size(),
+			Modified:  ci.ModTime().UTC(),
+			ChainHash: info.Sys().(*Info).ChainHash,
+		}
+		if eta, ok := tlock.RoundTime(f.network, e.Round); ok {
+			eta = eta.UTC()
+			e.Unlocks = &eta
+		}
+		idx.Files = append(idx.Files, e)
+		return nil
+	})
+	if err != nil {
+		return Index{}, err
+	}
+
+	return idx, nil
+}
+
+// WithIndex returns a filesystem serving the metadata of the ciphertexts from
+// the index rather than from their header, as long as their size and
+// modification time are the ones indexed.
+func (f *FS) WithIndex(idx Index) *FS {
+	entries := make(map[string]IndexEntry, len(idx.Files))
+	for _, e := range idx.Files {
+		entries[path.Clean(e.File)] = e
+	}
+
+	cp := *f
+	cp.index = entries
+	return &cp
+}
+
+// ReadIndex decodes an index written as JSON.
+func ReadIndex(r io.Reader) (Index, error) {
+	var idx Index
+	if err := json.NewDecoder(r).Decode(&idx); err != nil {
+		return Index{}, fmt.Errorf("decode index: %w", err)
+	}
+	return idx, nil
+}
+
+// =============================================================================
+
+// indexed returns the entry of the named ciphertext, if it is indexed and its
+// size and modification time are the indexed ones.
+func (f *FS) indexed(name string, ci fs.FileInfo) (IndexEntry, bool) {
+	e, ok := f.index[name+Suffix]
+	if !ok || e.Size != ci.Size() || !e.Modified.Equal(ci.ModTime()) {
+		return IndexEntry{}, false
+	}
+	return e, true
+}
@@ -0,0 +1,288 @@
+// Package lockedfs exposes a directory of tlock ciphertexts as a read-only
+// fs.FS in which files become readable once their round has been reached.
+// Before that their metadata can be listed and stat'ed but opening them fails
+// with tlock.ErrTooEarly. It is the filesystem layer of the tlefs command,
+// which mounts it with FUSE, and can be served by any io/fs consumer.
+package lockedfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+)
+
+// binaryIntro starts the binary ciphertexts, which are read at random offsets.
+// The others, armored or otherwise encoded, are decrypted as a stream.
+const binaryIntro = "age-encryption.org/v1\n"
+
+// Suffix is the file name suffix of the ciphertexts, which is removed from
+// the names exposed by the filesystem.
+const Suffix = ".tle"
+
+// FS is a read-only filesystem over a directory of ciphertexts.
+type FS struct {
+	dir     string
+	network tlock.Network
+	index   map[string]IndexEntry
+}
+
+// New constructs a filesystem exposing the ciphertexts of the directory,
+// decrypted with the network.
+func New(dir string, network tlock.Network) *FS {
+	return &FS{
+		dir:     dir,
+		network: network,
+	}
+}
+
+// Info is returned by the Sys method of the FileInfo of ciphertexts.
+type Info struct {
+	Round     uint64
+	ChainHash string
+	Unlocked  bool
+}
+
+// Open opens the named file, decrypting it if its round has been reached. The
+// file is decrypted as it is read, binary ciphertexts at any offset, the
+// returned file then implementing io.ReaderAt and io.Seeker.
+func (f *FS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if fi, err := os.Stat(f.path(name)); err == nil && fi.IsDir() {
+		entries, err := f.ReadDir(name)
+		if err != nil {
+			return nil, err
+		}
+		return &dir{info: fi, entries: entries}, nil
+	}
+
+	info, err := f.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Sys().(*Info).Unlocked {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: tlock.ErrTooEarly}
+	}
+
+	src, err := os.Open(f.path(name) + Suffix)
+	if err != nil {
+		return nil, err
+	}
+
+	// The network is shared by the files, which mustn't switch its chain.
+	t := tlock.New(f.network).Strict()
+	fi := info.(*fileInfo)
+	intro := make([]byte, len(binaryIntro))
+	if _, err := src.ReadAt(intro, 0); err != nil || string(intro) != binaryIntro {
+		return newStreamFile(t, src, fi), nil
+	}
+
+	ci, err := src.Stat()
+	if err != nil {
+		src.Close()
+		return nil, err
+	}
+	r, err := t.NewRandomAccessDecrypter(src, ci.Size())
+	if err != nil {
+		src.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	fi.size = r.Size()
+	return &file{SectionReader: io.NewSectionReader(r, 0, r.Size()), info: fi, src: src}, nil
+}
+
+// Stat returns the metadata of the named file without decrypting it, reading
+// its round from the index when it has one. Until the round is reached the
+// file has no permissions and its size is the one of the ciphertext. Its
+// modification time is the time the round is expected.
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	return f.stat(name, f.latestRound())
+}
+
+// ReadDir lists the ciphertexts and subdirectories of the named directory.
+// Other files are hidden.
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	entries, err := os.ReadDir(f.path(name))
+	if err != nil {
+		return nil, err
+	}
+
+	// The latest round is fetched once for all the files.
+	latest := f.latestRound()
+	var result []fs.DirEntry
+	for _, entry := range entries {
+		switch {
+		case entry.IsDir():
+			result = append(result, entry)
+		case entry.Type().IsRegular() && strings.HasSuffix(entry.Name(), Suffix):
+			info, err := f.stat(path.Join(name, strings.TrimSuffix(entry.Name(), Suffix)), latest)
+			if err != nil {
+				continue
+			}
+			result = append(result, fs.FileInfoToDirEntry(info))
+		}
+	}
+
+	return result, nil
+}
+
+// stat returns the metadata of the named file as Stat does, the file being
+// unlocked once the latest round reaches its round.
+func (f *FS) stat(name string, latest func() uint64) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if fi, err := os.Stat(f.path(name)); err == nil && fi.IsDir() {
+		return fi, nil
+	}
+
+	ci, err := os.Stat(f.path(name) + Suffix)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: errors.Unwrap(err)}
+	}
+
+	e, ok := f.indexed(name, ci)
+	if !ok {
+		src, err := os.Open(f.path(name) + Suffix)
+		if err != nil {
+			return nil, &fs.PathError{Op: "stat", Path: name, Err: errors.Unwrap(err)}
+		}
+		defer src.Close()
+
+		hdr, err := tlock.ParseHeader(src)
+		if err != nil {
+			return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+		}
+		if e.Round, e.ChainHash, err = hdr.Round(); err != nil {
+			return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+		}
+	}
+
+	fi := fileInfo{
+		name:    path.Base(name),
+		size:    ci.Size(),
+		modTime: ci.ModTime(),
+		info: Info{
+			Round:     e.Round,
+			ChainHash: e.ChainHash,
+			Unlocked:  latest() >= e.Round,
+		},
+	}
+	if eta, ok := tlock.RoundTime(f.network, e.Round); ok {
+		fi.modTime = eta
+	}
+
+	return &fi, nil
+}
+
+// latestRound returns a function returning the latest round the network
+// published, or its current round when it doesn't tell, fetched on the first
+// call only.
+func (f *FS) latestRound() func() uint64 {
+	return sync.OnceValue(func() uint64 {
+		if n, ok := f.network.(interface{ LatestRound() (uint64, error) }); ok {
+			if latest, err := n.LatestRound(); err == nil {
+				return latest
+			}
+		}
+		return f.network.Current(time.Now())
+	})
+}
+
+// path returns the path of the named file in the directory, without suffix.
+func (f *FS) path(name string) string {
+	return filepath.Join(f.dir, filepath.FromSlash(name))
+}
+
+// =============================================================================
+
+type fileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	info    Info
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.size }
+func (fi *fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *fileInfo) IsDir() bool        { return false }
+func (fi *fileInfo) Sys() any           { return &fi.info }
+
+func (fi *fileInfo) Mode() fs.FileMode {
+	if fi.info.Unlocked {
+		return 0o444
+	}
+	return 0
+}
+
+// file reads the plaintext of a binary ciphertext, decrypting the chunks
+// holding the offsets read.
+type file struct {
+	*io.SectionReader
+	info fs.FileInfo
+	src  *os.File
+}
+
+func (f *file) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *file) Close() error               { return f.src.Close() }
+
+// streamFile reads the plaintext of an encoded ciphertext, decrypted as it is
+// read. Its size is the one of the ciphertext, as the plaintext is smaller.
+type streamFile struct {
+	*io.PipeReader
+	info fs.FileInfo
+}
+
+func newStreamFile(t tlock.Tlock, src *os.File, info fs.FileInfo) *streamFile {
+	pr, pw := io.Pipe()
+	go func() {
+		defer src.Close()
+		pw.CloseWithError(t.Decrypt(pw, src))
+	}()
+	return &streamFile{PipeReader: pr, info: info}
+}
+
+func (f *streamFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+type dir struct {
+	info    fs.FileInfo
+	entries []fs.DirEntry
+}
+
+func (d *dir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *dir) Close() error               { return nil }
+
+func (d *dir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: fs.ErrInvalid}
+}
+
+func (d *dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(d.entries))
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
@@ -0,0 +1,150 @@
+package lockedfs_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/lockedfs"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFS(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1)
+	dir := t.TempDir()
+	plaintext := []byte("released content")
+
+	writeCiphertext(t, network, filepath.Join(dir, "released.txt.tle"), plaintext, 1)
+	writeCiphertext(t, network, filepath.Join(dir, "later.txt.tle"), plaintext, 1000)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ignored.txt"), plaintext, 0o600))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0o700))
+	writeCiphertext(t, network, filepath.Join(dir, "sub", "nested.tle"), plaintext, 1)
+
+	fsys := lockedfs.New(dir, network)
+
+	entries, err := fs.ReadDir(fsys, ".")
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	require.ElementsMatch(t, []string{"released.txt", "later.txt", "sub"}, names)
+
+	data, err := fs.ReadFile(fsys, "released.txt")
+	require.NoError(t, err)
+	require.Equal(t, plaintext, data)
+
+	data, err = fs.ReadFile(fsys, "sub/nested")
+	require.NoError(t, err)
+	require.Equal(t, plaintext, data)
+
+	info, err := fs.Stat(fsys, "later.txt")
+	require.NoError(t, err)
+	require.Equal(t, fs.FileMode(0), info.Mode())
+	require.Equal(t, uint64(1000), info.Sys().(*lockedfs.Info).Round)
+	require.False(t, info.Sys().(*lockedfs.Info).Unlocked)
+
+	_, err = fsys.Open("later.txt")
+	require.ErrorIs(t, err, tlock.ErrTooEarly)
+
+	_, err = fsys.Open("missing")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	f, err := fsys.Open("released.txt")
+	require.NoError(t, err)
+	defer f.Close()
+	info, err = f.Stat()
+	require.NoError(t, err)
+	require.Equal(t, int64(len(plaintext)), info.Size())
+	data, err = io.ReadAll(f)
+	require.NoError(t, err)
+	require.Equal(t, plaintext, data)
+}
+
+func TestFSStreams(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1)
+	dir := t.TempDir()
+	plaintext := bytes.Repeat([]byte("large content "), 2*tlock.ChunkSize/14)
+
+	writeCiphertext(t, network, filepath.Join(dir, "large.bin.tle"), plaintext, 1)
+	var armored bytes.Buffer
+	w := tlock.NewArmorWriter(&armored)
+	require.NoError(t, tlock.New(network).Encrypt(w, bytes.NewReader(plaintext), 1))
+	require.NoError(t, w.Close())
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "large.asc.tle"), armored.Bytes(), 0o600))
+
+	fsys := lockedfs.New(dir, network)
+
+	// Binary ciphertexts are read at any offset, decrypting only the chunks
+	// holding it.
+	f, err := fsys.Open("large.bin")
+	require.NoError(t, err)
+	defer f.Close()
+	info, err := f.Stat()
+	require.NoError(t, err)
+	require.Equal(t, int64(len(plaintext)), info.Size())
+	buf := make([]byte, 100)
+	off := int64(tlock.ChunkSize + 10)
+	_, err = f.(io.ReaderAt).ReadAt(buf, off)
+	require.NoError(t, err)
+	require.Equal(t, plaintext[off:off+100], buf)
+
+	// Armored ones are decrypted as they are read.
+	data, err := fs.ReadFile(fsys, "large.asc")
+	require.NoError(t, err)
+	require.Equal(t, plaintext, data)
+}
+
+func writeCiphertext(t *testing.T, network tlock.Network, name string, plaintext []byte, roundNumber uint64) {
+	t.Helper()
+
+	var ciphertext bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&ciphertext, bytes.NewReader(plaintext), roundNumber))
+	require.NoError(t, os.WriteFile(name, ciphertext.Bytes(), 0o600))
+}
+
+func TestIndex(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1)
+	dir := t.TempDir()
+	name := filepath.Join(dir, "later.txt.tle")
+	writeCiphertext(t, network, name, []byte("content"), 1000)
+
+	idx, err := lockedfs.New(dir, network).Index()
+	require.NoError(t, err)
+	require.Len(t, idx.Files, 1)
+	require.Equal(t, "later.txt.tle", idx.Files[0].File)
+	require.Equal(t, uint64(1000), idx.Files[0].Round)
+
+	var b bytes.Buffer
+	require.NoError(t, json.NewEncoder(&b).Encode(idx))
+	idx, err = lockedfs.ReadIndex(&b)
+	require.NoError(t, err)
+	fsys := lockedfs.New(dir, network).WithIndex(idx)
+
+	// The header isn't read while the ciphertext is unchanged.
+	fi, err := os.Stat(name)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(name, make([]byte, fi.Size()), 0o600))
+	require.NoError(t, os.Chtimes(name, fi.ModTime(), fi.ModTime()))
+	info, err := fs.Stat(fsys, "later.txt")
+	require.NoError(t, err)
+	require.Equal(t, uint64(1000), info.Sys().(*lockedfs.Info).Round)
+
+	// Changed ciphertexts are read again.
+	writeCiphertext(t, network, name, []byte("content"), 2000)
+	info, err = fs.Stat(fsys, "later.txt")
+	require.NoError(t, err)
+	require.Equal(t, uint64(2000), info.Sys().(*lockedfs.Info).Round)
+}
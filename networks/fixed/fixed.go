@@ -8,6 +8,7 @@ import (
 	chain "github.com/drand/drand/v2/common"
 	"github.com/drand/drand/v2/crypto"
 
+	dchain "github.com/drand/drand/v2/common/chain"
 	"github.com/drand/kyber"
 )
 
@@ -65,6 +66,16 @@ func (n *Network) Scheme() crypto.Scheme {
 	return *n.scheme
 }
 
+// Info returns the chain information of the network.
+func (n *Network) Info() *dchain.Info {
+	return &dchain.Info{
+		PublicKey:   n.publicKey,
+		Period:      n.period,
+		Scheme:      n.scheme.Name,
+		GenesisTime: n.genesis,
+	}
+}
+
 // Signature only returns a fixed signature if set with the fixed network
 func (n *Network) Signature(_ uint64) ([]byte, error) {
 	return n.fixedSig, nil
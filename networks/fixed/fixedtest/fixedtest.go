@@ -0,0 +1,166 @@
+// Package fixedtest provides the fixed networks the tests of the packages
+// built on tlock decrypt with, and the helpers these tests share.
+package fixedtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed"
+	chain "github.com/drand/drand/v2/common"
+	dchain "github.com/drand/drand/v2/common/chain"
+	"github.com/drand/drand/v2/crypto"
+	"github.com/drand/kyber"
+	"github.com/drand/kyber/util/random"
+	"github.com/stretchr/testify/require"
+)
+
+// ChainHash is the chain hash of the networks, the one of quicknet.
+const ChainHash = "52db9ba70e0cc0f6eaf7803dd07447a1f5477735fd3f661792ba94600c84e971"
+
+// Period is the default period of the networks.
+const Period = 3 * time.Second
+
+// NewNetwork returns a network with a random key, holding the beacon of the
+// round, whose genesis is now.
+func NewNetwork(t testing.TB, roundNumber uint64) *fixed.Network {
+	t.Helper()
+
+	key := NewKey(nil)
+	return key.Network(t, key.SignRound(t, roundNumber))
+}
+
+// SkipInFIPSMode skips the test in FIPS mode, in which the age payload of
+// ciphertexts, sealed with ChaCha20-Poly1305, isn't approved.
+func SkipInFIPSMode(t testing.TB) {
+	t.Helper()
+
+	if tlock.FIPSMode {
+		t.Skip("the age payload isn't approved in FIPS mode")
+	}
+}
+
+// =============================================================================
+
+// Key is the random key of a test network, along with the genesis and the
+// period of the network, which tests may change before using it.
+type Key struct {
+	Scheme    *crypto.Scheme
+	PublicKey kyber.Point
+	Genesis   time.Time
+	Period    time.Duration
+	secret    kyber.Scalar
+}
+
+// NewKey returns a random key of the scheme, the one of quicknet when nil, for
+// a network whose genesis is now.
+func NewKey(sch *crypto.Scheme) *Key {
+	if sch == nil {
+		sch = crypto.NewPedersenBLSUnchainedSwapped()
+	}
+	secret := sch.KeyGroup.Scalar().Pick(random.New())
+	return &Key{
+		Scheme:    sch,
+		PublicKey: sch.KeyGroup.Point().Mul(secret, nil),
+		Genesis:   time.Now(),
+		Period:    Period,
+		secret:    secret,
+	}
+}
+
+// Sign signs the message, such as the identity of a ciphertext.
+func (k *Key) Sign(t testing.TB, msg []byte) []byte {
+	t.Helper()
+
+	sig, err := k.Scheme.AuthScheme.Sign(k.secret, msg)
+	require.NoError(t, err)
+	return sig
+}
+
+// Signature returns the signature of the beacon of the round.
+func (k *Key) Signature(roundNumber uint64) ([]byte, error) {
+	return k.Scheme.AuthScheme.Sign(k.secret, k.Scheme.DigestBeacon(&chain.Beacon{Round: roundNumber}))
+}
+
+// SignRound returns the signature of the beacon of the round.
+func (k *Key) SignRound(t testing.TB, roundNumber uint64) []byte {
+	t.Helper()
+
+	sig, err := k.Signature(roundNumber)
+	require.NoError(t, err)
+	return sig
+}
+
+// Network returns the fixed network of the key, returning sig as the
+// signature of every round.
+func (k *Key) Network(t testing.TB, sig []byte) *fixed.Network {
+	t.Helper()
+
+	network, err := fixed.NewNetwork(ChainHash, k.PublicKey, k.Scheme, k.Period, k.Genesis.Unix(), sig)
+	require.NoError(t, err)
+	return network
+}
+
+// SigningNetwork returns a network of the key signing the beacon of any
+// round, standing in for a live network.
+func (k *Key) SigningNetwork(t testing.TB) *SigningNetwork {
+	t.Helper()
+
+	return &SigningNetwork{Network: k.Network(t, nil), key: k}
+}
+
+// Info returns the chain information of the network of the key.
+func (k *Key) Info() *dchain.Info {
+	return &dchain.Info{
+		PublicKey:   k.PublicKey,
+		ID:          "test",
+		Period:      k.Period,
+		Scheme:      k.Scheme.Name,
+		GenesisTime: k.Genesis.Unix(),
+		GenesisSeed: []byte("seed"),
+	}
+}
+
+// Beacon returns the verified beacon of the round.
+func (k *Key) Beacon(t testing.TB, roundNumber uint64) tlock.VerifiedBeacon {
+	t.Helper()
+
+	return tlock.VerifiedBeacon{Round: roundNumber, ChainHash: k.Info().HashString(), Signature: k.SignRound(t, roundNumber)}
+}
+
+// Bundle returns the beacon bundle of the rounds.
+func (k *Key) Bundle(t testing.TB, rounds ...uint64) *tlock.BeaconBundle {
+	t.Helper()
+
+	bundle, err := tlock.NewBeaconBundle(k.Info())
+	require.NoError(t, err)
+	for _, roundNumber := range rounds {
+		require.NoError(t, bundle.Add(k.Beacon(t, roundNumber)))
+	}
+	return bundle
+}
+
+// BundleNetwork returns the network serving the beacon bundle of the rounds
+// offline.
+func (k *Key) BundleNetwork(t testing.TB, rounds ...uint64) *tlock.TransportNetwork {
+	t.Helper()
+
+	network, err := tlock.NewTransportNetwork(context.Background(), k.Bundle(t, rounds...))
+	require.NoError(t, err)
+	return network
+}
+
+// =============================================================================
+
+// SigningNetwork is a fixed network signing the beacon of any round.
+type SigningNetwork struct {
+	*fixed.Network
+	key *Key
+}
+
+// Signature returns the signature of the beacon of the round.
+func (n *SigningNetwork) Signature(roundNumber uint64) ([]byte, error) {
+	return n.key.Signature(roundNumber)
+}
@@ -0,0 +1,105 @@
+package http_test
+
+import (
+	"encoding/hex"
+	nhttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock/networks/http"
+	dchain "github.com/drand/drand/v2/common/chain"
+	"github.com/drand/drand/v2/crypto"
+	"github.com/drand/kyber/util/random"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewNetworkVerifiesChainHash(t *testing.T) {
+	sch := crypto.NewPedersenBLSUnchainedG1()
+	info := &dchain.Info{
+		PublicKey:   sch.KeyGroup.Point().Pick(random.New()),
+		ID:          "quicknet",
+		Period:      3 * time.Second,
+		Scheme:      sch.Name,
+		GenesisTime: time.Now().Unix(),
+		GenesisSeed: []byte("seed"),
+	}
+
+	// The relay serves the same chain information whatever the chain hash.
+	relay := httptest.NewServer(nhttp.HandlerFunc(func(w nhttp.ResponseWriter, r *nhttp.Request) {
+		_ = info.ToJSON(w, nil)
+	}))
+	defer relay.Close()
+
+	network, err := http.NewNetwork(relay.URL, hex.EncodeToString(info.Hash()))
+	require.NoError(t, err)
+	require.True(t, network.PublicKey().Equal(info.PublicKey))
+
+	_, err = http.NewNetwork(relay.URL, "52db9ba70e0cc0f6eaf7803dd07447a1f5477735fd3f661792ba94600c84e971")
+	require.ErrorIs(t, err, http.ErrChainHashMismatch)
+}
+
+func TestNewNetworkSendsCredentials(t *testing.T) {
+	sch := crypto.NewPedersenBLSUnchainedG1()
+	info := &dchain.Info{
+		PublicKey:   sch.KeyGroup.Point().Pick(random.New()),
+		ID:          "quicknet",
+		Period:      3 * time.Second,
+		Scheme:      sch.Name,
+		GenesisTime: time.Now().Unix(),
+		GenesisSeed: []byte("seed"),
+	}
+	chainHash := hex.EncodeToString(info.Hash())
+
+	var auth string
+	relay := httptest.NewServer(nhttp.HandlerFunc(func(w nhttp.ResponseWriter, r *nhttp.Request) {
+		if r.Header.Get("Authorization") != auth {
+			w.WriteHeader(nhttp.StatusUnauthorized)
+			return
+		}
+		_ = info.ToJSON(w, nil)
+	}))
+	defer relay.Close()
+
+	auth = "Bearer secret"
+	_, err := http.NewNetwork(relay.URL, chainHash)
+	require.Error(t, err)
+	network, err := http.NewNetwork(relay.URL, chainHash, http.WithBearerToken("secret"))
+	require.NoError(t, err)
+	require.True(t, network.PublicKey().Equal(info.PublicKey))
+
+	auth = "Basic dXNlcjpwYXNz"
+	_, err = http.NewNetwork(relay.URL, chainHash, http.WithBasicAuth("user", "pass"))
+	require.NoError(t, err)
+	network, err = http.NewNetwork(strings.Replace(relay.URL, "://", "://user:pass@", 1), chainHash)
+	require.NoError(t, err)
+
+	// The credentials of the URL survive switching the chain.
+	require.NoError(t, network.SwitchChainHash(chainHash))
+}
+
+func TestCheckPinnedKey(t *testing.T) {
+	const quicknet = "52db9ba70e0cc0f6eaf7803dd07447a1f5477735fd3f661792ba94600c84e971"
+	sch := crypto.NewPedersenBLSUnchainedG1()
+	info := &dchain.Info{
+		PublicKey:   sch.KeyGroup.Point().Pick(random.New()),
+		ID:          "quicknet",
+		Period:      3 * time.Second,
+		Scheme:      sch.Name,
+		GenesisTime: 1692803367,
+		GenesisSeed: []byte("seed"),
+	}
+
+	// Chains which aren't well-known aren't checked.
+	require.NoError(t, http.CheckPinnedKey(hex.EncodeToString(info.Hash()), info))
+	require.ErrorIs(t, http.CheckPinnedKey(quicknet, info), http.ErrPinMismatch)
+
+	key, err := hex.DecodeString("83cf0f2896adee7eb8b5f01fcad3912212c437e0073e911fb90022d3e760183c8c4b450b6a0a6c3ac6a5776a2d1064510d1fec758c921cc22b0e17e63aaf4bcb5ed66304de9cf809bd274ca73bab4af5a6e9c76a4bc09e76eae8991ef5ece45a")
+	require.NoError(t, err)
+	require.NoError(t, info.PublicKey.UnmarshalBinary(key))
+	info.GenesisSeed, err = hex.DecodeString("f477d5c89f21a17c863a7f937c6a6d15859414d2be09cd448d4279af331c5d3e")
+	require.NoError(t, err)
+	require.Equal(t, quicknet, hex.EncodeToString(info.Hash()))
+	require.NoError(t, http.CheckPinnedKey(quicknet, info))
+}
@@ -0,0 +1,128 @@
+package http
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	dchain "github.com/drand/drand/v2/common/chain"
+	"github.com/drand/drand/v2/crypto"
+	dclient "github.com/drand/go-clients/drand"
+)
+
+// ErrNoOfflineInfo is returned when constructing an offline network for a
+// chain whose public key isn't built in.
+var ErrNoOfflineInfo = errors.New("chain information isn't built in")
+
+// ErrOfflineMismatch is returned when the chain information given to an
+// offline network doesn't match the chain hash, or the one the relay serves
+// once it is reached.
+var ErrOfflineMismatch = errors.New("chain period or genesis mismatch")
+
+// offlineChains complete the pinned keys of the chains usable offline with
+// the rest of their chain information, which their chain hash commits to.
+var offlineChains = map[string]struct {
+	id          string
+	scheme      string
+	genesisSeed string
+}{
+	// quicknet.
+	"52db9ba70e0cc0f6eaf7803dd07447a1f5477735fd3f661792ba94600c84e971": {
+		id:          "quicknet",
+		scheme:      crypto.SigsOnG1ID,
+		genesisSeed: "f477d5c89f21a17c863a7f937c6a6d15859414d2be09cd448d4279af331c5d3e",
+	},
+}
+
+// lazyClient connects an offline network to its relay when first needed.
+type lazyClient struct {
+	once   sync.Once
+	client dclient.Client
+	err    error
+}
+
+// NewOfflineNetwork constructs a network for a League of Entropy chain without
+// calling the relay, using the built in public key and the given period and
+// genesis time, so that durations and times convert to rounds and data can be
+// encrypted while the relay is unavailable. The period and genesis time are
+// checked against the chain hash, which commits to them. The relay at host is
+// only called once beacons are needed, as when decrypting, its chain
+// information being checked against the one assumed.
+func NewOfflineNetwork(host string, chainHash string, period time.Duration, genesis int64, opts ...Option) (*Network, error) {
+	chainHash = strings.ToLower(chainHash)
+	c, ok := offlineChains[chainHash]
+	if !ok {
+		return nil, fmt.Errorf("%w: chain %s", ErrNoOfflineInfo, chainHash)
+	}
+
+	sch, err := crypto.SchemeFromName(c.scheme)
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(pinnedKeys[chainHash])
+	if err != nil {
+		return nil, err
+	}
+	publicKey := sch.KeyGroup.Point()
+	if err := publicKey.UnmarshalBinary(key); err != nil {
+		return nil, fmt.Errorf("unmarshal public key: %w", err)
+	}
+	seed, err := hex.DecodeString(c.genesisSeed)
+	if err != nil {
+		return nil, err
+	}
+
+	info := dchain.Info{
+		PublicKey:   publicKey,
+		ID:          c.id,
+		Period:      period,
+		Scheme:      sch.Name,
+		GenesisTime: genesis,
+		GenesisSeed: seed,
+	}
+	if info.HashString() != chainHash {
+		return nil, fmt.Errorf("%w: period %s and genesis %d aren't the ones of chain %s", ErrOfflineMismatch, period, genesis, chainHash)
+	}
+
+	network := Network{
+		chainHash: chainHash,
+		host:      host,
+		opts:      opts,
+		publicKey: publicKey,
+		scheme:    *sch,
+		period:    period,
+		genesis:   genesis,
+		info:      &info,
+		cache:     newBeaconCache(),
+		lazy:      &lazyClient{},
+	}
+
+	return &network, nil
+}
+
+// =============================================================================
+
+// relay returns the client of the network, connecting an offline network to
+// its relay on the first call.
+func (n *Network) relay() (dclient.Client, error) {
+	if n.lazy == nil {
+		return n.client, nil
+	}
+
+	n.lazy.once.Do(func() {
+		online, err := NewNetwork(n.host, n.chainHash, n.opts...)
+		if err != nil {
+			n.lazy.err = err
+			return
+		}
+		if !online.info.Equal(n.info) {
+			n.lazy.err = fmt.Errorf("%w: the relay serves period %s and genesis %d, %s and %d were assumed", ErrOfflineMismatch, online.period, online.genesis, n.period, n.genesis)
+			return
+		}
+		n.lazy.client = online.client
+	})
+	return n.lazy.client, n.lazy.err
+}
@@ -0,0 +1,43 @@
+package http_test
+
+import (
+	nhttp "net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock/networks/http"
+	"github.com/stretchr/testify/require"
+)
+
+const quicknet = "52db9ba70e0cc0f6eaf7803dd07447a1f5477735fd3f661792ba94600c84e971"
+
+func TestOfflineNetwork(t *testing.T) {
+	var requests atomic.Int32
+	relay := httptest.NewServer(nhttp.HandlerFunc(func(w nhttp.ResponseWriter, r *nhttp.Request) {
+		requests.Add(1)
+		w.WriteHeader(nhttp.StatusServiceUnavailable)
+	}))
+	defer relay.Close()
+
+	network, err := http.NewOfflineNetwork(relay.URL, quicknet, 3*time.Second, 1692803367)
+	require.NoError(t, err)
+	require.Equal(t, quicknet, network.ChainHash())
+	require.Equal(t, uint64(1), network.RoundNumber(time.Unix(1692803367, 0)))
+	require.Equal(t, uint64(21), network.RoundNumber(time.Unix(1692803367+60, 0)))
+	require.Equal(t, 3*time.Second, network.Info().Period)
+	require.Zero(t, requests.Load())
+
+	// The relay is only called for beacons.
+	_, err = network.Signature(1)
+	require.Error(t, err)
+	require.NotZero(t, requests.Load())
+
+	_, err = http.NewOfflineNetwork(relay.URL, quicknet, 30*time.Second, 1692803367)
+	require.ErrorIs(t, err, http.ErrOfflineMismatch)
+	_, err = http.NewOfflineNetwork(relay.URL, quicknet, 3*time.Second, 1692803366)
+	require.ErrorIs(t, err, http.ErrOfflineMismatch)
+	_, err = http.NewOfflineNetwork(relay.URL, "dbd506d6ef76e5f386f41c651dcb808c5bcbd75471cc4eafa3f4df7ad4e4c493", 3*time.Second, 1692803367)
+	require.ErrorIs(t, err, http.ErrNoOfflineInfo)
+}
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	dchain "github.com/drand/drand/v2/common/chain"
+)
+
+// ErrPinMismatch represents an error when a relay serves a public key other
+// than the one pinned for a well-known chain.
+var ErrPinMismatch = errors.New("public key does not match the pinned one")
+
+// pinnedKeys are the public keys of the League of Entropy chains, by chain
+// hash. They are fixed for the life of the chains.
+var pinnedKeys = map[string]string{
+	// mainnet, whose beacon ID is "default".
+	"8990e7a9aaed2ffed73dbd7092123d6f289930540d7651336225dc172e51b2ce": "868f005eb8e6e4ca0a47c8a77ceaa5309a47978a7c71bc5cce96366b5d7a569937c529eeda66c7293784a9402801af31",
+	// quicknet.
+	"52db9ba70e0cc0f6eaf7803dd07447a1f5477735fd3f661792ba94600c84e971": "83cf0f2896adee7eb8b5f01fcad3912212c437e0073e911fb90022d3e760183c8c4b450b6a0a6c3ac6a5776a2d1064510d1fec758c921cc22b0e17e63aaf4bcb5ed66304de9cf809bd274ca73bab4af5a6e9c76a4bc09e76eae8991ef5ece45a",
+}
+
+// WithoutPinning disables checking the public key of the well-known chains
+// against the one built in. The chain information is still checked against
+// the chain hash.
+func WithoutPinning() Option {
+	return func(o *options) {
+		o.noPin = true
+	}
+}
+
+// CheckPinnedKey checks the public key of the chain information against the
+// one built in when the chain hash is the one of a League of Entropy chain,
+// so that their public key is trusted without trusting the relay. Other
+// chains aren't checked.
+func CheckPinnedKey(chainHash string, info *dchain.Info) error {
+	pinned, ok := pinnedKeys[strings.ToLower(chainHash)]
+	if !ok {
+		return nil
+	}
+	if info.PublicKey == nil {
+		return fmt.Errorf("%w: no public key", ErrPinMismatch)
+	}
+	key, err := info.PublicKey.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("marshal public key: %w", err)
+	}
+	if hex.EncodeToString(key) != pinned {
+		return fmt.Errorf("%w: chain %s has public key %x", ErrPinMismatch, chainHash, key)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package http_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	nhttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/JonathanLogan/tlock/networks/http"
+	"github.com/drand/drand/v2/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrefetch(t *testing.T) {
+	key := fixedtest.NewKey(crypto.NewPedersenBLSUnchainedG1())
+	key.Genesis = time.Now().Add(-time.Hour)
+	info := key.Info()
+
+	// The relay signs the beacons, counting the requests for each round.
+	var requests [100]atomic.Int32
+	relay := httptest.NewServer(nhttp.HandlerFunc(func(w nhttp.ResponseWriter, r *nhttp.Request) {
+		if strings.HasSuffix(r.URL.Path, "/info") {
+			_ = info.ToJSON(w, nil)
+			return
+		}
+		var roundNumber uint64
+		if _, err := fmt.Sscanf(r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:], "%d", &roundNumber); err != nil || roundNumber >= 100 {
+			w.WriteHeader(nhttp.StatusNotFound)
+			return
+		}
+		requests[roundNumber].Add(1)
+		signature, err := key.Signature(roundNumber)
+		require.NoError(t, err)
+		if roundNumber == 13 {
+			signature[0] ^= 1
+		}
+		randomness := sha256.Sum256(signature)
+		fmt.Fprintf(w, `{"round":%d,"randomness":"%x","signature":"%x"}`, roundNumber, randomness, signature)
+	}))
+	defer relay.Close()
+
+	network, err := http.NewNetwork(relay.URL, hex.EncodeToString(info.Hash()))
+	require.NoError(t, err)
+
+	// Rounds which aren't reached yet are skipped, and forged beacons are
+	// reported but not cached.
+	err = network.Prefetch(context.Background(), []uint64{3, 1, 2, 3, 13, 1 << 40}, 2)
+	require.ErrorContains(t, err, "round 13: verify beacon")
+	for _, roundNumber := range []uint64{1, 2, 3} {
+		require.Equal(t, int32(1), requests[roundNumber].Load())
+	}
+
+	for _, roundNumber := range []uint64{1, 2, 3, 13} {
+		_, err := network.Signature(roundNumber)
+		require.NoError(t, err)
+	}
+	for _, roundNumber := range []uint64{1, 2, 3} {
+		require.Equal(t, int32(1), requests[roundNumber].Load())
+	}
+	require.Equal(t, int32(2), requests[13].Load())
+
+	// Pruning bounds the cache by its entries, then by their age, the pruned
+	// rounds being fetched again.
+	require.Equal(t, 1, network.PruneCache(0, 2))
+	require.Equal(t, 0, network.PruneCache(time.Hour, 2))
+	time.Sleep(time.Millisecond)
+	require.Equal(t, 2, network.PruneCache(time.Nanosecond, 0))
+	_, err = network.Signature(1)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), requests[1].Load())
+}
@@ -0,0 +1,150 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// These are the address families a network can be restricted to.
+const (
+	FamilyAny  = "tcp"
+	FamilyIPv4 = "tcp4"
+	FamilyIPv6 = "tcp6"
+)
+
+// ErrInvalidFamily is returned when an address family isn't one of FamilyAny,
+// FamilyIPv4 or FamilyIPv6.
+var ErrInvalidFamily = errors.New("invalid address family")
+
+// WithResolver resolves the host of the relay with the resolver rather than
+// the one of the system.
+func WithResolver(resolver *net.Resolver) Option {
+	return func(o *options) {
+		o.resolver = resolver
+	}
+}
+
+// WithDNSOverHTTPS resolves the host of the relay with the DNS over HTTPS
+// server at the URL, such as https://1.1.1.1/dns-query, so that the lookups
+// can't be seen or tampered with on the local network. The host of the URL
+// is best an IP address, as it is resolved by the system.
+func WithDNSOverHTTPS(url string) Option {
+	return WithResolver(NewDoHResolver(url))
+}
+
+// WithAddressFamily restricts the connections to the relay to the family,
+// FamilyIPv4 or FamilyIPv6, as on IPv6 only hosts. Other values fail the
+// connections with ErrInvalidFamily.
+func WithAddressFamily(family string) Option {
+	return func(o *options) {
+		o.family = family
+	}
+}
+
+// NewDoHResolver returns a resolver sending its queries to the DNS over HTTPS
+// server at the URL, following RFC 8484.
+func NewDoHResolver(url string) *net.Resolver {
+	client := &http.Client{Timeout: timeout}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return &dohConn{ctx: ctx, client: client, url: url}, nil
+		},
+	}
+}
+
+// =============================================================================
+
+// dialContext returns the dial function of the dialer restricted to the
+// family.
+func dialContext(d *net.Dialer, family string) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		switch family {
+		case "", FamilyAny:
+		case FamilyIPv4, FamilyIPv6:
+			network = family
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrInvalidFamily, family)
+		}
+		return d.DialContext(ctx, network, address)
+	}
+}
+
+// dohConn carries the DNS messages the resolver exchanges over HTTPS. It
+// implements net.PacketConn for the resolver to write whole queries and read
+// whole answers.
+type dohConn struct {
+	ctx      context.Context
+	client   *http.Client
+	url      string
+	deadline time.Time
+	answer   bytes.Buffer
+}
+
+// Write sends the query and keeps the answer for Read.
+func (c *dohConn) Write(b []byte) (int, error) {
+	ctx := c.ctx
+	if !c.deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, c.deadline)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(b))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("dns over https: %s", resp.Status)
+	}
+
+	c.answer.Reset()
+	if _, err := io.Copy(&c.answer, io.LimitReader(resp.Body, 65535)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Read returns the answer of the last query.
+func (c *dohConn) Read(b []byte) (int, error) {
+	if c.answer.Len() == 0 {
+		return 0, os.ErrDeadlineExceeded
+	}
+	n := copy(b, c.answer.Bytes())
+	c.answer.Reset()
+	return n, nil
+}
+
+func (c *dohConn) WriteTo(b []byte, _ net.Addr) (int, error) { return c.Write(b) }
+
+func (c *dohConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	n, err := c.Read(b)
+	return n, nil, err
+}
+
+func (c *dohConn) Close() error                       { return nil }
+func (c *dohConn) LocalAddr() net.Addr                { return dohAddr(c.url) }
+func (c *dohConn) RemoteAddr() net.Addr               { return dohAddr(c.url) }
+func (c *dohConn) SetDeadline(t time.Time) error      { c.deadline = t; return nil }
+func (c *dohConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *dohConn) SetWriteDeadline(t time.Time) error { c.deadline = t; return nil }
+
+// dohAddr is the address of a DNS over HTTPS server.
+type dohAddr string
+
+func (a dohAddr) Network() string { return "https" }
+func (a dohAddr) String() string  { return string(a) }
@@ -0,0 +1,81 @@
+package http_test
+
+import (
+	"encoding/hex"
+	"io"
+	nhttp "net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock/networks/http"
+	dchain "github.com/drand/drand/v2/common/chain"
+	"github.com/drand/drand/v2/crypto"
+	"github.com/drand/kyber/util/random"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestDNSOverHTTPS(t *testing.T) {
+	sch := crypto.NewPedersenBLSUnchainedG1()
+	info := &dchain.Info{
+		PublicKey:   sch.KeyGroup.Point().Pick(random.New()),
+		ID:          "quicknet",
+		Period:      3 * time.Second,
+		Scheme:      sch.Name,
+		GenesisTime: time.Now().Unix(),
+		GenesisSeed: []byte("seed"),
+	}
+	chainHash := hex.EncodeToString(info.Hash())
+
+	relay := httptest.NewServer(nhttp.HandlerFunc(func(w nhttp.ResponseWriter, r *nhttp.Request) {
+		_ = info.ToJSON(w, nil)
+	}))
+	defer relay.Close()
+
+	// The DNS over HTTPS server resolves relay.example to the loopback
+	// address of the relay.
+	var queries int
+	doh := httptest.NewServer(nhttp.HandlerFunc(func(w nhttp.ResponseWriter, r *nhttp.Request) {
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		var query dnsmessage.Message
+		require.NoError(t, query.Unpack(b))
+
+		answer := dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: query.ID, Response: true, Authoritative: true},
+			Questions: query.Questions,
+		}
+		q := query.Questions[0]
+		if q.Type == dnsmessage.TypeA && q.Name.String() == "relay.example." {
+			queries++
+			answer.Answers = []dnsmessage.Resource{{
+				Header: dnsmessage.ResourceHeader{Name: q.Name, Type: q.Type, Class: q.Class, TTL: 60},
+				Body:   &dnsmessage.AResource{A: [4]byte{127, 0, 0, 1}},
+			}}
+		}
+		b, err = answer.Pack()
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", "application/dns-message")
+		_, _ = w.Write(b)
+	}))
+	defer doh.Close()
+
+	u, err := url.Parse(relay.URL)
+	require.NoError(t, err)
+	host := "http://relay.example:" + u.Port()
+
+	network, err := http.NewNetwork(host, chainHash, http.WithDNSOverHTTPS(doh.URL))
+	require.NoError(t, err)
+	require.True(t, network.PublicKey().Equal(info.PublicKey))
+	require.NotZero(t, queries)
+
+	_, err = http.NewNetwork(host, chainHash, http.WithDNSOverHTTPS(doh.URL), http.WithAddressFamily(http.FamilyIPv6))
+	require.Error(t, err)
+
+	_, err = http.NewNetwork(relay.URL, chainHash, http.WithAddressFamily("udp"))
+	require.ErrorIs(t, err, http.ErrInvalidFamily)
+	require.True(t, strings.Contains(err.Error(), "udp"))
+}
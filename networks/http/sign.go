@@ -0,0 +1,99 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// These are the headers carrying the signature of a request.
+const (
+	HeaderKeyID     = "X-Tlock-Key-Id"
+	HeaderTimestamp = "X-Tlock-Timestamp"
+	HeaderSignature = "X-Tlock-Signature"
+)
+
+// ErrInvalidSignature is returned by VerifyRequest when a request isn't
+// signed with a known key, or its signature is stale.
+var ErrInvalidSignature = errors.New("invalid request signature")
+
+// WithRequestSigning signs each request with the key, so that relay
+// operators can authenticate and meter their clients. It is a shorthand for
+// WithRoundTripper(SigningTransport(keyID, key)).
+func WithRequestSigning(keyID string, key []byte) Option {
+	return WithRoundTripper(SigningTransport(keyID, key))
+}
+
+// SigningTransport returns a middleware signing the requests with the key.
+// The signature is the hex encoded HMAC-SHA256 of the method, the request URI
+// and the unix timestamp of the request, each followed by a newline. It is
+// sent in the X-Tlock-Signature header, along with the X-Tlock-Key-Id and
+// X-Tlock-Timestamp headers. The requests of a network have no body, hence
+// it isn't signed.
+func SigningTransport(keyID string, key []byte) func(http.RoundTripper) http.RoundTripper {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return signingTransport{keyID: keyID, key: key, next: next}
+	}
+}
+
+// VerifyRequest checks the signature of a request, for relays or the proxies
+// in front of them, and returns the id of the key it was signed with. The
+// key function returns the key of an id, and false for unknown ids. Requests
+// whose timestamp is more than skew away from now are rejected.
+func VerifyRequest(req *http.Request, key func(keyID string) ([]byte, bool), skew time.Duration) (string, error) {
+	keyID := req.Header.Get(HeaderKeyID)
+	k, ok := key(keyID)
+	if !ok {
+		return "", fmt.Errorf("%w: unknown key %q", ErrInvalidSignature, keyID)
+	}
+
+	timestamp := req.Header.Get(HeaderTimestamp)
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("%w: malformed timestamp %q", ErrInvalidSignature, timestamp)
+	}
+	if d := time.Since(time.Unix(unix, 0)); d > skew || d < -skew {
+		return "", fmt.Errorf("%w: timestamp off by %s", ErrInvalidSignature, d.Round(time.Second))
+	}
+
+	signature, err := hex.DecodeString(req.Header.Get(HeaderSignature))
+	if err != nil || !hmac.Equal(signature, sign(k, req.Method, req.URL.RequestURI(), timestamp)) {
+		return "", fmt.Errorf("%w: signature mismatch", ErrInvalidSignature)
+	}
+	return keyID, nil
+}
+
+// =============================================================================
+
+// signingTransport signs the requests it forwards.
+type signingTransport struct {
+	keyID string
+	key   []byte
+	next  http.RoundTripper
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t signingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req = req.Clone(req.Context())
+	req.Header.Set(HeaderKeyID, t.keyID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, hex.EncodeToString(sign(t.key, req.Method, req.URL.RequestURI(), timestamp)))
+	return t.next.RoundTrip(req)
+}
+
+// sign computes the signature of a request.
+func sign(key []byte, method string, uri string, timestamp string) []byte {
+	mac := hmac.New(sha256.New, key)
+	for _, s := range []string{method, uri, timestamp} {
+		mac.Write([]byte(s))
+		mac.Write([]byte{'\n'})
+	}
+	return mac.Sum(nil)
+}
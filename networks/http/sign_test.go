@@ -0,0 +1,82 @@
+package http_test
+
+import (
+	"encoding/hex"
+	nhttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock/networks/http"
+	dchain "github.com/drand/drand/v2/common/chain"
+	"github.com/drand/drand/v2/crypto"
+	"github.com/drand/kyber/util/random"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestSigning(t *testing.T) {
+	sch := crypto.NewPedersenBLSUnchainedG1()
+	info := &dchain.Info{
+		PublicKey:   sch.KeyGroup.Point().Pick(random.New()),
+		ID:          "quicknet",
+		Period:      3 * time.Second,
+		Scheme:      sch.Name,
+		GenesisTime: time.Now().Unix(),
+		GenesisSeed: []byte("seed"),
+	}
+	chainHash := hex.EncodeToString(info.Hash())
+
+	keys := map[string][]byte{"client": []byte("secret")}
+	var metered []string
+	relay := httptest.NewServer(nhttp.HandlerFunc(func(w nhttp.ResponseWriter, r *nhttp.Request) {
+		keyID, err := http.VerifyRequest(r, func(id string) ([]byte, bool) {
+			key, ok := keys[id]
+			return key, ok
+		}, time.Minute)
+		if err != nil {
+			w.WriteHeader(nhttp.StatusUnauthorized)
+			return
+		}
+		metered = append(metered, keyID)
+		_ = info.ToJSON(w, nil)
+	}))
+	defer relay.Close()
+
+	_, err := http.NewNetwork(relay.URL, chainHash)
+	require.Error(t, err)
+	_, err = http.NewNetwork(relay.URL, chainHash, http.WithRequestSigning("client", []byte("wrong")))
+	require.Error(t, err)
+	_, err = http.NewNetwork(relay.URL, chainHash, http.WithRequestSigning("unknown", []byte("secret")))
+	require.Error(t, err)
+
+	network, err := http.NewNetwork(relay.URL, chainHash, http.WithRequestSigning("client", []byte("secret")))
+	require.NoError(t, err)
+	require.True(t, network.PublicKey().Equal(info.PublicKey))
+	require.Equal(t, []string{"client"}, metered)
+}
+
+func TestVerifyRequestRejectsStaleTimestamp(t *testing.T) {
+	var req *nhttp.Request
+	rt := http.SigningTransport("client", []byte("secret"))(roundTripFunc(func(r *nhttp.Request) (*nhttp.Response, error) {
+		req = r
+		return &nhttp.Response{StatusCode: nhttp.StatusOK, Body: nhttp.NoBody}, nil
+	}))
+	r, err := nhttp.NewRequest(nhttp.MethodGet, "https://relay.example/public/1", nhttp.NoBody)
+	require.NoError(t, err)
+	_, err = rt.RoundTrip(r)
+	require.NoError(t, err)
+
+	key := func(string) ([]byte, bool) { return []byte("secret"), true }
+	_, err = http.VerifyRequest(req, key, time.Minute)
+	require.NoError(t, err)
+
+	req.Header.Set(http.HeaderTimestamp, "1")
+	_, err = http.VerifyRequest(req, key, time.Minute)
+	require.ErrorIs(t, err, http.ErrInvalidSignature)
+}
+
+type roundTripFunc func(*nhttp.Request) (*nhttp.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *nhttp.Request) (*nhttp.Response, error) {
+	return f(r)
+}
@@ -0,0 +1,176 @@
+// Package multi implements the Network interface for the tlock package over
+// several relays serving the same chain.
+package multi
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/http"
+	dchain "github.com/drand/drand/v2/common/chain"
+	"github.com/drand/drand/v2/crypto"
+	"github.com/drand/kyber"
+)
+
+// ErrNoRelay represents an error when no relay could be used.
+var ErrNoRelay = errors.New("no relay available")
+
+// ErrRelayMismatch represents an error when cross-checking relays returned
+// different signatures for the same round.
+var ErrRelayMismatch = errors.New("relays returned different signatures")
+
+// ErrNotEnoughRelays represents an error when cross-checking couldn't get the
+// signature from two relays.
+var ErrNotEnoughRelays = errors.New("not enough relays to cross-check the signature")
+
+// =============================================================================
+
+// Network represents the network support over several relays, failing over
+// to the next relay when one can't provide a signature.
+type Network struct {
+	networks   []tlock.Network
+	crossCheck bool
+}
+
+// NewNetwork constructs a network using the http client for each host. Hosts
+// which can't be reached are skipped, as long as one of them can. The options
+// apply to every host.
+func NewNetwork(hosts []string, chainHash string, opts ...http.Option) (*Network, error) {
+	var networks []tlock.Network
+	var errs []error
+	for _, host := range hosts {
+		network, err := http.NewNetwork(host, chainHash, opts...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", host, err))
+			continue
+		}
+		networks = append(networks, network)
+	}
+	if len(networks) == 0 {
+		return nil, fmt.Errorf("%w: %w", ErrNoRelay, errors.Join(errs...))
+	}
+
+	return New(networks...)
+}
+
+// New constructs a network over the given networks, which must serve the
+// same chain. They are tried in order.
+func New(networks ...tlock.Network) (*Network, error) {
+	if len(networks) == 0 {
+		return nil, ErrNoRelay
+	}
+	for _, network := range networks[1:] {
+		if network.ChainHash() != networks[0].ChainHash() || !network.PublicKey().Equal(networks[0].PublicKey()) {
+			return nil, fmt.Errorf("networks serve different chains: %s and %s", networks[0].ChainHash(), network.ChainHash())
+		}
+	}
+
+	return &Network{networks: networks}, nil
+}
+
+// SetCrossCheck sets whether signatures must be fetched from two relays and
+// be byte for byte equal before being used, defending against a single
+// compromised relay on top of the signature verification.
+func (n *Network) SetCrossCheck(crossCheck bool) {
+	n.crossCheck = crossCheck
+}
+
+// ChainHash returns the chain hash for this network.
+func (n *Network) ChainHash() string {
+	return n.networks[0].ChainHash()
+}
+
+// Current returns the current round for that network at the given date.
+func (n *Network) Current(date time.Time) uint64 {
+	return n.networks[0].Current(date)
+}
+
+// PublicKey returns the kyber point needed for encryption and decryption.
+func (n *Network) PublicKey() kyber.Point {
+	return n.networks[0].PublicKey()
+}
+
+// Scheme returns the drand crypto Scheme used by the network.
+func (n *Network) Scheme() crypto.Scheme {
+	return n.networks[0].Scheme()
+}
+
+// Info returns the chain information of the network, if any of the networks
+// exposes it.
+func (n *Network) Info() *dchain.Info {
+	for _, network := range n.networks {
+		if i, ok := network.(interface{ Info() *dchain.Info }); ok && i.Info() != nil {
+			return i.Info()
+		}
+	}
+	return nil
+}
+
+// Signature retrieves the signature for the specified round number from the
+// first relay providing it, or from the first two when cross-checking.
+func (n *Network) Signature(roundNumber uint64) ([]byte, error) {
+	var sigs [][]byte
+	var errs []error
+	for _, network := range n.networks {
+		sig, err := network.Signature(roundNumber)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !n.crossCheck {
+			return sig, nil
+		}
+
+		if sigs = append(sigs, sig); len(sigs) == 2 {
+			if !bytes.Equal(sigs[0], sigs[1]) {
+				return nil, fmt.Errorf("%w: round %d", ErrRelayMismatch, roundNumber)
+			}
+			return sig, nil
+		}
+	}
+
+	if n.crossCheck && len(sigs) == 1 {
+		return nil, fmt.Errorf("%w: %w", ErrNotEnoughRelays, errors.Join(errs...))
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Status returns the status of the latest round a majority of the relays
+// answering have reached, so that neither a relay lagging behind nor one
+// claiming rounds it doesn't serve misleads the callers about the unlocks.
+func (n *Network) Status(ctx context.Context) (tlock.ChainStatus, error) {
+	var statuses []tlock.ChainStatus
+	var errs []error
+	for _, network := range n.networks {
+		s, err := tlock.Status(ctx, network)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		statuses = append(statuses, s)
+	}
+
+	if len(statuses) == 0 {
+		return tlock.ChainStatus{}, fmt.Errorf("%w: %w", ErrNoRelay, errors.Join(errs...))
+	}
+	slices.SortFunc(statuses, func(a, b tlock.ChainStatus) int {
+		return cmp.Compare(b.LatestRound, a.LatestRound)
+	})
+	return statuses[len(statuses)/2], nil
+}
+
+// SwitchChainHash switches every relay to the chain hash.
+func (n *Network) SwitchChainHash(chainHash string) error {
+	for _, network := range n.networks {
+		if err := network.SwitchChainHash(chainHash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
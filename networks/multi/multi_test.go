@@ -0,0 +1,97 @@
+package multi_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/JonathanLogan/tlock/networks/multi"
+	"github.com/stretchr/testify/require"
+)
+
+// unreachableNetwork is a relay that can't be reached.
+type unreachableNetwork struct {
+	*fixed.Network
+}
+
+func (unreachableNetwork) Signature(uint64) ([]byte, error) {
+	return nil, errors.New("unreachable")
+}
+
+func TestNetwork(t *testing.T) {
+	key := fixedtest.NewKey(nil)
+	good, other := key.Network(t, []byte("good")), key.Network(t, []byte("forged"))
+	down := unreachableNetwork{good}
+
+	network, err := multi.New(down, good, other)
+	require.NoError(t, err)
+	sig, err := network.Signature(10)
+	require.NoError(t, err)
+	require.Equal(t, []byte("good"), sig)
+
+	network.SetCrossCheck(true)
+	_, err = network.Signature(10)
+	require.ErrorIs(t, err, multi.ErrRelayMismatch)
+
+	network, err = multi.New(good, down, good)
+	require.NoError(t, err)
+	network.SetCrossCheck(true)
+	sig, err = network.Signature(10)
+	require.NoError(t, err)
+	require.Equal(t, []byte("good"), sig)
+
+	network, err = multi.New(down, good)
+	require.NoError(t, err)
+	network.SetCrossCheck(true)
+	_, err = network.Signature(10)
+	require.ErrorIs(t, err, multi.ErrNotEnoughRelays)
+
+	_, err = multi.New(good, fixedtest.NewKey(nil).Network(t, nil))
+	require.Error(t, err)
+}
+
+// statusNetwork is a relay reporting the latest round given.
+type statusNetwork struct {
+	*fixed.Network
+	latest uint64
+	err    error
+}
+
+func (n statusNetwork) Status(context.Context) (tlock.ChainStatus, error) {
+	return tlock.ChainStatus{LatestRound: n.latest, Health: tlock.Healthy}, n.err
+}
+
+func TestStatus(t *testing.T) {
+	fixedNetwork := fixedtest.NewKey(nil).Network(t, nil)
+	relay := func(latest uint64) statusNetwork {
+		return statusNetwork{Network: fixedNetwork, latest: latest}
+	}
+	down := statusNetwork{Network: fixedNetwork, err: errors.New("unreachable")}
+
+	// A single relay claiming a later round is outvoted, as is a single one
+	// lagging behind.
+	for _, test := range []struct {
+		relays []tlock.Network
+		latest uint64
+	}{
+		{[]tlock.Network{relay(100)}, 100},
+		{[]tlock.Network{relay(100), relay(1_000_000)}, 100},
+		{[]tlock.Network{relay(1_000_000), relay(100), relay(100)}, 100},
+		{[]tlock.Network{relay(90), relay(100), relay(100)}, 100},
+		{[]tlock.Network{down, relay(100), relay(1_000_000)}, 100},
+	} {
+		network, err := multi.New(test.relays...)
+		require.NoError(t, err)
+		s, err := network.Status(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, test.latest, s.LatestRound)
+	}
+
+	network, err := multi.New(down, down)
+	require.NoError(t, err)
+	_, err = network.Status(context.Background())
+	require.ErrorIs(t, err, multi.ErrNoRelay)
+}
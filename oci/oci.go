@@ -0,0 +1,538 @@
+// Package oci stores timelocked ciphertexts as OCI artifacts in container
+// registries, so embargoed releases can be distributed through the existing
+// registry infrastructure and decrypted once their round is reached. The
+// round and chain hash are recorded as annotations of the manifest, letting
+// clients tell whether an artifact can be decrypted before downloading it.
+// It speaks the OCI distribution API directly, with anonymous, basic or bearer
+// token authentication.
+package oci
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/JonathanLogan/tlock"
+)
+
+// These constants identify the tlock artifacts and their annotations.
+const (
+	ArtifactType        = "application/vnd.tlock.ciphertext.v1"
+	LayerMediaType      = "application/vnd.tlock.ciphertext.v1.age"
+	AnnotationRound     = "dev.tlock.round"
+	AnnotationChainHash = "dev.tlock.chainhash"
+	AnnotationTitle     = "org.opencontainers.image.title"
+)
+
+// These constants are defined by the OCI image specification.
+const (
+	manifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	emptyMediaType    = "application/vnd.oci.empty.v1+json"
+)
+
+// emptyConfig is the content of the empty config blob of artifacts.
+var emptyConfig = []byte("{}")
+
+// These errors are returned by the client.
+var (
+	ErrInvalidReference = errors.New("invalid artifact reference")
+	ErrNotTlockArtifact = errors.New("not a tlock artifact")
+	ErrDigestMismatch   = errors.New("content doesn't match its digest")
+)
+
+// Reference identifies an artifact in a registry, by tag or by digest.
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseReference parses a reference of the form REGISTRY/REPOSITORY[:TAG] or
+// REGISTRY/REPOSITORY@DIGEST. The tag defaults to latest.
+func ParseReference(s string) (Reference, error) {
+	registry, rest, ok := strings.Cut(s, "/")
+	if !ok || rest == "" || !(strings.ContainsAny(registry, ".:") || registry == "localhost") {
+		return Reference{}, fmt.Errorf("%w: %q must start with a registry host", ErrInvalidReference, s)
+	}
+
+	ref := Reference{Registry: registry, Tag: "latest"}
+	if repo, digest, ok := strings.Cut(rest, "@"); ok {
+		if !strings.HasPrefix(digest, "sha256:") || len(digest) != len("sha256:")+2*sha256.Size {
+			return Reference{}, fmt.Errorf("%w: unsupported digest %q", ErrInvalidReference, digest)
+		}
+		ref.Repository, ref.Tag, ref.Digest = repo, "", digest
+	} else if i := strings.LastIndex(rest, ":"); i > strings.LastIndex(rest, "/") {
+		ref.Repository, ref.Tag = rest[:i], rest[i+1:]
+	} else {
+		ref.Repository = rest
+	}
+
+	if ref.Repository == "" || ref.Repository != strings.ToLower(ref.Repository) || (ref.Digest == "" && ref.Tag == "") {
+		return Reference{}, fmt.Errorf("%w: %q", ErrInvalidReference, s)
+	}
+	return ref, nil
+}
+
+// String returns the reference in the form ParseReference accepts.
+func (r Reference) String() string {
+	if r.Digest != "" {
+		return r.Registry + "/" + r.Repository + "@" + r.Digest
+	}
+	return r.Registry + "/" + r.Repository + ":" + r.Tag
+}
+
+// Artifact describes a tlock artifact.
+type Artifact struct {
+	Name      string // The title of the ciphertext layer.
+	Round     uint64
+	ChainHash string
+	Digest    string // The digest of the manifest.
+	Size      int64  // The size of the ciphertext.
+}
+
+// Client pushes and pulls tlock artifacts.
+type Client struct {
+	// HTTPClient is used for the requests, http.DefaultClient if nil.
+	HTTPClient *http.Client
+	// PlainHTTP makes the client use http rather than https.
+	PlainHTTP bool
+	// Username and Password are the credentials of the registry, if any.
+	Username string
+	Password string
+
+	token string
+	basic bool
+}
+
+// Push uploads the ciphertext in src as an artifact named name, tagged with
+// the tag of the reference. It returns the artifact with the digest of its
+// manifest.
+func (c *Client) Push(ctx context.Context, ref Reference, name string, src io.ReadSeeker) (Artifact, error) {
+	if ref.Tag == "" {
+		return Artifact{}, fmt.Errorf("%w: pushing requires a tag", ErrInvalidReference)
+	}
+
+	hdr, err := tlock.ParseHeader(src)
+	if err != nil {
+		return Artifact{}, err
+	}
+	roundNumber, chainHash, err := hdr.Round()
+	if err != nil {
+		return Artifact{}, err
+	}
+
+	h := sha256.New()
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return Artifact{}, err
+	}
+	size, err := io.Copy(h, src)
+	if err != nil {
+		return Artifact{}, fmt.Errorf("read ciphertext: %w", err)
+	}
+	layerDigest := digestOf(h)
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return Artifact{}, err
+	}
+
+	configDigest := "sha256:" + hex.EncodeToString(sha256Sum(emptyConfig))
+	if err := c.uploadBlob(ctx, ref, configDigest, int64(len(emptyConfig)), bytes.NewReader(emptyConfig)); err != nil {
+		return Artifact{}, fmt.Errorf("upload config: %w", err)
+	}
+	if err := c.uploadBlob(ctx, ref, layerDigest, size, src); err != nil {
+		return Artifact{}, fmt.Errorf("upload ciphertext: %w", err)
+	}
+
+	m := manifest{
+		SchemaVersion: 2,
+		MediaType:     manifestMediaType,
+		ArtifactType:  ArtifactType,
+		Config:        descriptor{MediaType: emptyMediaType, Digest: configDigest, Size: int64(len(emptyConfig))},
+		Layers: []descriptor{{
+			MediaType:   LayerMediaType,
+			Digest:      layerDigest,
+			Size:        size,
+			Annotations: map[string]string{AnnotationTitle: name},
+		}},
+		Annotations: map[string]string{
+			AnnotationRound:     strconv.FormatUint(roundNumber, 10),
+			AnnotationChainHash: chainHash,
+		},
+	}
+	body, err := json.Marshal(m)
+	if err != nil {
+		return Artifact{}, err
+	}
+
+	resp, err := c.do(ctx, http.MethodPut, c.url(ref, "manifests/"+ref.Tag), bytes.NewReader(body), manifestMediaType)
+	if err != nil {
+		return Artifact{}, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return Artifact{}, statusError("push manifest", resp)
+	}
+
+	return Artifact{
+		Name:      name,
+		Round:     roundNumber,
+		ChainHash: chainHash,
+		Digest:    "sha256:" + hex.EncodeToString(sha256Sum(body)),
+		Size:      size,
+	}, nil
+}
+
+// Resolve fetches the manifest of the artifact and returns its description,
+// without downloading the ciphertext.
+func (c *Client) Resolve(ctx context.Context, ref Reference) (Artifact, error) {
+	a, _, err := c.resolve(ctx, ref)
+	return a, err
+}
+
+// Pull writes the ciphertext of the artifact to dst, checking its digest.
+func (c *Client) Pull(ctx context.Context, ref Reference, dst io.Writer) (Artifact, error) {
+	a, layer, err := c.resolve(ctx, ref)
+	if err != nil {
+		return Artifact{}, err
+	}
+
+	resp, err := c.do(ctx, http.MethodGet, c.url(ref, "blobs/"+layer.Digest), nil, "")
+	if err != nil {
+		return Artifact{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Artifact{}, statusError("pull ciphertext", resp)
+	}
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(dst, h), io.LimitReader(resp.Body, layer.Size+1))
+	if err != nil {
+		return Artifact{}, fmt.Errorf("pull ciphertext: %w", err)
+	}
+	if n != layer.Size || digestOf(h) != layer.Digest {
+		return Artifact{}, fmt.Errorf("%w: ciphertext %s", ErrDigestMismatch, layer.Digest)
+	}
+
+	return a, nil
+}
+
+// BasicAuth returns the username and password of a base64 encoded
+// "username:password" pair, as found in the auths of docker config files.
+func BasicAuth(auth string) (string, string, error) {
+	b, err := base64.StdEncoding.DecodeString(auth)
+	if err != nil {
+		return "", "", fmt.Errorf("decode auth: %w", err)
+	}
+	username, password, ok := strings.Cut(string(b), ":")
+	if !ok {
+		return "", "", errors.New("decode auth: missing password")
+	}
+	return username, password, nil
+}
+
+// =============================================================================
+
+type descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type manifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType,omitempty"`
+	Config        descriptor        `json:"config"`
+	Layers        []descriptor      `json:"layers"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// maxManifestSize bounds the size of the manifests read from registries.
+const maxManifestSize = 4 * 1024 * 1024
+
+// resolve fetches the manifest of the artifact, returning the artifact and
+// its ciphertext layer.
+func (c *Client) resolve(ctx context.Context, ref Reference) (Artifact, descriptor, error) {
+	target := ref.Tag
+	if ref.Digest != "" {
+		target = ref.Digest
+	}
+	resp, err := c.do(ctx, http.MethodGet, c.url(ref, "manifests/"+target), nil, "")
+	if err != nil {
+		return Artifact{}, descriptor{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Artifact{}, descriptor{}, statusError("pull manifest", resp)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize))
+	if err != nil {
+		return Artifact{}, descriptor{}, fmt.Errorf("pull manifest: %w", err)
+	}
+	digest := "sha256:" + hex.EncodeToString(sha256Sum(body))
+	if ref.Digest != "" && digest != ref.Digest {
+		return Artifact{}, descriptor{}, fmt.Errorf("%w: manifest %s", ErrDigestMismatch, ref.Digest)
+	}
+
+	var m manifest
+	if err := json.Unmarshal(body, &m); err != nil {
+		return Artifact{}, descriptor{}, fmt.Errorf("%w: %w", ErrNotTlockArtifact, err)
+	}
+	if m.ArtifactType != ArtifactType || len(m.Layers) != 1 || m.Layers[0].MediaType != LayerMediaType {
+		return Artifact{}, descriptor{}, fmt.Errorf("%w: artifact type %q", ErrNotTlockArtifact, m.ArtifactType)
+	}
+	roundNumber, err := strconv.ParseUint(m.Annotations[AnnotationRound], 10, 64)
+	if err != nil {
+		return Artifact{}, descriptor{}, fmt.Errorf("%w: round annotation: %w", ErrNotTlockArtifact, err)
+	}
+
+	layer := m.Layers[0]
+	return Artifact{
+		Name:      layer.Annotations[AnnotationTitle],
+		Round:     roundNumber,
+		ChainHash: m.Annotations[AnnotationChainHash],
+		Digest:    digest,
+		Size:      layer.Size,
+	}, layer, nil
+}
+
+// uploadBlob uploads the blob unless the registry already has it.
+func (c *Client) uploadBlob(ctx context.Context, ref Reference, digest string, size int64, body io.Reader) error {
+	resp, err := c.do(ctx, http.MethodHead, c.url(ref, "blobs/"+digest), nil, "")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	resp, err = c.do(ctx, http.MethodPost, c.url(ref, "blobs/uploads/"), nil, "")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return statusError("start upload", resp)
+	}
+	location, err := resp.Location()
+	if err != nil {
+		return fmt.Errorf("start upload: %w", err)
+	}
+	q := location.Query()
+	q.Set("digest", digest)
+	location.RawQuery = q.Encode()
+
+	req, err := c.newRequest(ctx, http.MethodPut, location.String(), io.NopCloser(body), "application/octet-stream")
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	resp, err = c.send(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return statusError("upload", resp)
+	}
+	return nil
+}
+
+// url returns the URL of the API path in the repository of the reference.
+func (c *Client) url(ref Reference, path string) string {
+	scheme := "https"
+	if c.PlainHTTP {
+		scheme = "http"
+	}
+	return scheme + "://" + ref.Registry + "/v2/" + ref.Repository + "/" + path
+}
+
+// do sends a request whose body, if any, can be sent again once
+// authenticated.
+func (c *Client) do(ctx context.Context, method string, u string, body *bytes.Reader, contentType string) (*http.Response, error) {
+	var rc io.Reader
+	if body != nil {
+		rc = body
+	}
+	req, err := c.newRequest(ctx, method, u, rc, contentType)
+	if err != nil {
+		return nil, err
+	}
+	return c.send(req)
+}
+
+func (c *Client) newRequest(ctx context.Context, method string, u string, body io.Reader, contentType string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if method == http.MethodGet || method == http.MethodHead {
+		req.Header.Set("Accept", manifestMediaType)
+	}
+	return req, nil
+}
+
+// send sends the request, authenticating and sending it again when the
+// registry requires it and the body can be replayed.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	c.authorize(req)
+	resp, err := c.httpClient().Do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	resp.Body.Close()
+
+	if err := c.authenticate(req.Context(), resp.Header.Get("WWW-Authenticate")); err != nil {
+		return nil, err
+	}
+	if req.Body != nil && req.GetBody == nil {
+		return nil, fmt.Errorf("%s %s: unauthorized", req.Method, req.URL.Redacted())
+	}
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	c.authorize(retry)
+	return c.httpClient().Do(retry)
+}
+
+// authorize sets the credentials of the request.
+func (c *Client) authorize(req *http.Request) {
+	switch {
+	case c.token != "":
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	case c.basic:
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+}
+
+// authenticate answers the challenge of the registry, fetching a bearer token
+// or falling back to basic authentication.
+func (c *Client) authenticate(ctx context.Context, challenge string) error {
+	scheme, params := parseChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if c.Username == "" {
+			return errors.New("the registry requires credentials")
+		}
+		c.basic = true
+		return nil
+	case "bearer":
+	default:
+		return fmt.Errorf("unsupported authentication challenge %q", challenge)
+	}
+
+	u, err := url.Parse(params["realm"])
+	if err != nil || u.Scheme == "" {
+		return fmt.Errorf("invalid token realm %q", params["realm"])
+	}
+	q := u.Query()
+	if params["service"] != "" {
+		q.Set("service", params["service"])
+	}
+	if params["scope"] != "" {
+		q.Set("scope", params["scope"])
+	}
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return statusError("fetch token", resp)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return fmt.Errorf("fetch token: %w", err)
+	}
+	c.token = token.Token
+	if c.token == "" {
+		c.token = token.AccessToken
+	}
+	if c.token == "" {
+		return errors.New("fetch token: empty token")
+	}
+	return nil
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// parseChallenge parses a WWW-Authenticate header into its scheme and
+// parameters.
+func parseChallenge(challenge string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	params := make(map[string]string)
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		params[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+	return scheme, params
+}
+
+// statusError reports an unexpected response, with the error message of the
+// registry if there is one.
+func statusError(op string, resp *http.Response) error {
+	var body struct {
+		Errors []struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&body); err == nil && len(body.Errors) > 0 {
+		return fmt.Errorf("%s: %s: %s %s", op, resp.Status, body.Errors[0].Code, body.Errors[0].Message)
+	}
+	return fmt.Errorf("%s: %s", op, resp.Status)
+}
+
+func digestOf(h hash.Hash) string {
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+func sha256Sum(b []byte) []byte {
+	sum := sha256.Sum256(b)
+	return sum[:]
+}
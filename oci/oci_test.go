@@ -0,0 +1,174 @@
+package oci_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/JonathanLogan/tlock/oci"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReference(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("ab", 32)
+	tests := []struct {
+		in   string
+		want oci.Reference
+		str  string
+	}{
+		{in: "ghcr.io/org/release:v1.0", want: oci.Reference{Registry: "ghcr.io", Repository: "org/release", Tag: "v1.0"}, str: "ghcr.io/org/release:v1.0"},
+		{in: "localhost:5000/release", want: oci.Reference{Registry: "localhost:5000", Repository: "release", Tag: "latest"}, str: "localhost:5000/release:latest"},
+		{in: "ghcr.io/org/release@" + digest, want: oci.Reference{Registry: "ghcr.io", Repository: "org/release", Digest: digest}, str: "ghcr.io/org/release@" + digest},
+		{in: "org/release:v1.0"},
+		{in: "ghcr.io/Org/release"},
+		{in: "ghcr.io/org/release@md5:00"},
+		{in: "ghcr.io/org/release:"},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			ref, err := oci.ParseReference(test.in)
+			if test.want == (oci.Reference{}) {
+				require.ErrorIs(t, err, oci.ErrInvalidReference)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.want, ref)
+			require.Equal(t, test.str, ref.String())
+		})
+	}
+}
+
+func TestPushPull(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+	var ciphertext bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&ciphertext, strings.NewReader("release 1.0"), 1000))
+
+	registry := newRegistry(t)
+	ref, err := oci.ParseReference(strings.TrimPrefix(registry.URL, "http://") + "/org/release:v1.0")
+	require.NoError(t, err)
+	client := &oci.Client{PlainHTTP: true, Username: "ci", Password: "secret"}
+
+	pushed, err := client.Push(context.Background(), ref, "release.tar.gz.tle", bytes.NewReader(ciphertext.Bytes()))
+	require.NoError(t, err)
+	require.EqualValues(t, 1000, pushed.Round)
+	require.Equal(t, network.ChainHash(), pushed.ChainHash)
+
+	resolved, err := (&oci.Client{PlainHTTP: true}).Resolve(context.Background(), ref)
+	require.NoError(t, err)
+	require.Equal(t, pushed, resolved)
+
+	ref.Tag, ref.Digest = "", pushed.Digest
+	var pulled bytes.Buffer
+	a, err := (&oci.Client{PlainHTTP: true}).Pull(context.Background(), ref, &pulled)
+	require.NoError(t, err)
+	require.Equal(t, pushed, a)
+	require.Equal(t, ciphertext.Bytes(), pulled.Bytes())
+
+	// Pushing without credentials is refused by the token server.
+	_, err = (&oci.Client{PlainHTTP: true}).Push(context.Background(), oci.Reference{Registry: ref.Registry, Repository: "org/release", Tag: "v2"}, "release.tle", bytes.NewReader(ciphertext.Bytes()))
+	require.Error(t, err)
+
+	// A corrupted blob is detected.
+	registry.corrupt()
+	_, err = (&oci.Client{PlainHTTP: true}).Pull(context.Background(), ref, io.Discard)
+	require.ErrorIs(t, err, oci.ErrDigestMismatch)
+}
+
+// registry is a minimal in-memory OCI registry, handing out bearer tokens,
+// and requiring credentials to push.
+type registry struct {
+	*httptest.Server
+	mu        sync.Mutex
+	blobs     map[string][]byte
+	manifests map[string][]byte
+}
+
+func newRegistry(t *testing.T) *registry {
+	r := &registry{blobs: make(map[string][]byte), manifests: make(map[string][]byte)}
+	r.Server = httptest.NewServer(http.HandlerFunc(r.serve))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *registry) corrupt() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for digest, b := range r.blobs {
+		if len(b) > 2 {
+			r.blobs[digest] = append(bytes.Clone(b[:len(b)-1]), b[len(b)-1]^1)
+		}
+	}
+}
+
+func (r *registry) serve(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if req.URL.Path == "/token" {
+		token := "pull"
+		if user, pass, ok := req.BasicAuth(); ok && user == "ci" && pass == "secret" {
+			token = "push"
+		}
+		_, _ = io.WriteString(w, `{"token":"`+token+`"}`)
+		return
+	}
+
+	auth := req.Header.Get("Authorization")
+	write := req.Method == http.MethodPost || req.Method == http.MethodPut
+	if auth != "Bearer push" && (write || auth != "Bearer pull") {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="`+r.URL+`/token",service="test",scope="repository:org/release:pull,push"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	path := strings.TrimPrefix(req.URL.Path, "/v2/org/release/")
+	switch {
+	case req.Method == http.MethodPost && path == "blobs/uploads/":
+		w.Header().Set("Location", "/v2/org/release/blobs/uploads/1?state=x")
+		w.WriteHeader(http.StatusAccepted)
+	case req.Method == http.MethodPut && strings.HasPrefix(path, "blobs/uploads/"):
+		b, _ := io.ReadAll(req.Body)
+		digest := req.URL.Query().Get("digest")
+		sum := sha256.Sum256(b)
+		if digest != "sha256:"+hex.EncodeToString(sum[:]) || req.URL.Query().Get("state") != "x" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		r.blobs[digest] = b
+		w.WriteHeader(http.StatusCreated)
+	case strings.HasPrefix(path, "blobs/"):
+		b, ok := r.blobs[strings.TrimPrefix(path, "blobs/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(b)
+	case req.Method == http.MethodPut && strings.HasPrefix(path, "manifests/"):
+		b, _ := io.ReadAll(req.Body)
+		sum := sha256.Sum256(b)
+		r.manifests[strings.TrimPrefix(path, "manifests/")] = b
+		r.manifests["sha256:"+hex.EncodeToString(sum[:])] = b
+		w.WriteHeader(http.StatusCreated)
+	case req.Method == http.MethodGet && strings.HasPrefix(path, "manifests/"):
+		b, ok := r.manifests[strings.TrimPrefix(path, "manifests/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"errors":[{"code":"MANIFEST_UNKNOWN","message":"manifest unknown"}]}`)
+			return
+		}
+		_, _ = w.Write(b)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
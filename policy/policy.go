@@ -0,0 +1,142 @@
+// Package policy implements the rules an organization sets on what its tenants
+// may timelock, evaluated by services before encrypting: which chains can be
+// used and how far in the future ciphertexts can be locked. The rules are only
+// as strong as the identification of the tenants by the service.
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+)
+
+// ErrDenied is returned when an encryption is denied by the policy.
+var ErrDenied = errors.New("denied by policy")
+
+// Rules are the rules applying to the encryptions of a tenant. Empty lists
+// and a zero duration don't restrict anything.
+type Rules struct {
+	// Chains are the chain hashes which can be encrypted towards.
+	Chains []string
+	// MaxLockDuration is how far in the future a round can be.
+	MaxLockDuration time.Duration
+}
+
+// Engine evaluates the rules of each tenant. Tenants without rules of their
+// own get the default rules, or are denied when there are none.
+type Engine struct {
+	mu       sync.RWMutex
+	tenants  map[string]Rules
+	defaults *Rules
+}
+
+// New constructs an engine without any rules, denying every tenant.
+func New() *Engine {
+	return &Engine{
+		tenants: make(map[string]Rules),
+	}
+}
+
+// Load constructs an engine from its JSON configuration, as in:
+//
+//	{
+//	  "default": {"chains": ["52db9b..."], "max_lock_duration": "720h"},
+//	  "tenants": {"acme": {"max_lock_duration": "8760h"}}
+//	}
+func Load(r io.Reader) (*Engine, error) {
+	var config struct {
+		Default *Rules           `json:"default"`
+		Tenants map[string]Rules `json:"tenants"`
+	}
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&config); err != nil {
+		return nil, fmt.Errorf("parse policy: %w", err)
+	}
+
+	e := New()
+	if config.Default != nil {
+		e.SetDefault(*config.Default)
+	}
+	for tenant, rules := range config.Tenants {
+		e.SetTenant(tenant, rules)
+	}
+	return e, nil
+}
+
+// SetDefault sets the rules of the tenants without rules of their own.
+func (e *Engine) SetDefault(rules Rules) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.defaults = &rules
+}
+
+// SetTenant sets the rules of the tenant.
+func (e *Engine) SetTenant(tenant string, rules Rules) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.tenants[tenant] = rules
+}
+
+// Check returns an error wrapping ErrDenied unless the tenant may encrypt
+// towards the round of the network.
+func (e *Engine) Check(tenant string, network tlock.Network, roundNumber uint64) error {
+	e.mu.RLock()
+	rules, ok := e.tenants[tenant]
+	if !ok && e.defaults != nil {
+		rules, ok = *e.defaults, true
+	}
+	e.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: unknown tenant %q", ErrDenied, tenant)
+	}
+
+	if len(rules.Chains) > 0 && !slices.Contains(rules.Chains, network.ChainHash()) {
+		return fmt.Errorf("%w: chain %s is not allowed", ErrDenied, network.ChainHash())
+	}
+
+	if rules.MaxLockDuration > 0 {
+		unlock, ok := tlock.RoundTime(network, roundNumber)
+		if !ok {
+			return fmt.Errorf("%w: the time of round %d is unknown", ErrDenied, roundNumber)
+		}
+		if lock := time.Until(unlock); lock > rules.MaxLockDuration {
+			return fmt.Errorf("%w: round %d is locked for %s, more than %s", ErrDenied, roundNumber, lock.Round(time.Second), rules.MaxLockDuration)
+		}
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// UnmarshalJSON implements json.Unmarshaler, the duration being written as
+// parsed by time.ParseDuration.
+func (r *Rules) UnmarshalJSON(b []byte) error {
+	var rules struct {
+		Chains          []string `json:"chains"`
+		MaxLockDuration string   `json:"max_lock_duration"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&rules); err != nil {
+		return err
+	}
+
+	*r = Rules{Chains: rules.Chains}
+	if rules.MaxLockDuration != "" {
+		d, err := time.ParseDuration(rules.MaxLockDuration)
+		if err != nil {
+			return fmt.Errorf("max_lock_duration: %w", err)
+		}
+		r.MaxLockDuration = d
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package policy_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/JonathanLogan/tlock/policy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	network := fixedtest.NewNetwork(t, 1)
+	now := network.Current(time.Now())
+	day := uint64(24 * time.Hour / (3 * time.Second))
+
+	p, err := policy.Load(strings.NewReader(`{
+		"default": {"chains": ["` + fixedtest.ChainHash + `"], "max_lock_duration": "48h"},
+		"tenants": {"acme": {"max_lock_duration": "720h"}}
+	}`))
+	require.NoError(t, err)
+
+	require.NoError(t, p.Check("", network, now+day))
+	require.ErrorIs(t, p.Check("", network, now+3*day), policy.ErrDenied)
+	require.NoError(t, p.Check("acme", network, now+3*day))
+	require.ErrorIs(t, p.Check("acme", network, now+31*day), policy.ErrDenied)
+
+	require.NoError(t, network.SwitchChainHash("dbd506d6ef76e5f386f41c651dcb808c5bcbd75471cc4eafa3f4df7ad4e4c493"))
+	require.ErrorIs(t, p.Check("", network, now+day), policy.ErrDenied)
+	require.NoError(t, p.Check("acme", network, now+day))
+
+	require.ErrorIs(t, policy.New().Check("acme", network, now), policy.ErrDenied)
+
+	_, err = policy.Load(strings.NewReader(`{"default": {"max_lock": "48h"}}`))
+	require.Error(t, err)
+	_, err = policy.Load(strings.NewReader(`{"default": {"recipients": ["legal"]}}`))
+	require.Error(t, err)
+}
@@ -0,0 +1,304 @@
+// Package sqlcontainer stores ciphertexts in a SQLite database rather than in
+// flat files, one row per chunk along with its index and offset. Appends run
+// in a transaction, so readers never see a ciphertext partly written, and
+// reads fetch only the chunks holding the range asked for, which suits the
+// random access of tlock.Tlock.DecryptFrom.
+//
+// The package uses database/sql and leaves the choice of the driver to the
+// application, which imports one such as modernc.org/sqlite or
+// github.com/mattn/go-sqlite3 and opens the database itself:
+//
+//	db, err := sql.Open("sqlite", "ciphertexts.db")
+//	c, err := sqlcontainer.New(ctx, db)
+//	w, err := c.Append(ctx, "2024-06-01.log")
+//	err = tlock.New(network).Encrypt(w, src, roundNumber)
+//	err = w.Close()
+//
+// The statements are portable enough to run on MySQL as well.
+package sqlcontainer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DefaultChunkSize is the size of the rows the writers append, unless given
+// another one with WithChunkSize.
+const DefaultChunkSize = 64 << 10
+
+// ErrNotFound is returned when opening a ciphertext the container doesn't
+// hold.
+var ErrNotFound = errors.New("ciphertext not found")
+
+// schema creates the table of the container. The offset of a chunk is the
+// one of its first byte in the ciphertext, letting reads select the chunks
+// they need.
+const schema = `
+CREATE TABLE IF NOT EXISTS tlock_chunks (
+	name TEXT NOT NULL,
+	idx  INTEGER NOT NULL,
+	off  INTEGER NOT NULL,
+	data BLOB NOT NULL,
+	PRIMARY KEY (name, idx)
+)`
+
+// Container holds ciphertexts in a database.
+type Container struct {
+	db        *sql.DB
+	chunkSize int
+}
+
+// Option configures a container.
+type Option func(c *Container)
+
+// WithChunkSize sets the size of the rows the writers append.
+func WithChunkSize(size int) Option {
+	return func(c *Container) {
+		if size > 0 {
+			c.chunkSize = size
+		}
+	}
+}
+
+// New constructs a container using the database, creating its table if
+// needed.
+func New(ctx context.Context, db *sql.DB, opts ...Option) (*Container, error) {
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		return nil, err
+	}
+
+	c := Container{db: db, chunkSize: DefaultChunkSize}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return &c, nil
+}
+
+// Append returns a writer appending to the named ciphertext, which is created
+// if needed. The chunks written are committed by Close, all at once, and
+// discarded by Abort.
+func (c *Container) Append(ctx context.Context, name string) (*Writer, error) {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	w := Writer{ctx: ctx, tx: tx, name: name, buf: make([]byte, 0, c.chunkSize)}
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(MAX(idx) + 1, 0), COALESCE(MAX(off + LENGTH(data)), 0)
+		FROM tlock_chunks WHERE name = ?`, name).Scan(&w.index, &w.offset)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("find end of %s: %w", name, err)
+	}
+	return &w, nil
+}
+
+// Open returns a reader of the named ciphertext as committed when opening it.
+func (c *Container) Open(ctx context.Context, name string) (*Reader, error) {
+	var size int64
+	var chunks int
+	err := c.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(MAX(off + LENGTH(data)), 0)
+		FROM tlock_chunks WHERE name = ?`, name).Scan(&chunks, &size)
+	if err != nil {
+		return nil, err
+	}
+	if chunks == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return &Reader{ctx: ctx, db: c.db, name: name, size: size}, nil
+}
+
+// Delete removes the named ciphertext.
+func (c *Container) Delete(ctx context.Context, name string) error {
+	_, err := c.db.ExecContext(ctx, "DELETE FROM tlock_chunks WHERE name = ?", name)
+	return err
+}
+
+// Names returns the names of the ciphertexts of the container, sorted.
+func (c *Container) Names(ctx context.Context) ([]string, error) {
+	rows, err := c.db.QueryContext(ctx, "SELECT DISTINCT name FROM tlock_chunks ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// =============================================================================
+
+// Writer appends chunks to a ciphertext within a transaction.
+type Writer struct {
+	ctx    context.Context
+	tx     *sql.Tx
+	name   string
+	buf    []byte
+	index  int64
+	offset int64
+	err    error
+}
+
+// Write implements io.Writer, inserting a row each time a chunk fills up.
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	var n int
+	for len(p) > 0 {
+		m := min(len(p), cap(w.buf)-len(w.buf))
+		w.buf = append(w.buf, p[:m]...)
+		p = p[m:]
+		n += m
+		if len(w.buf) == cap(w.buf) {
+			if err := w.flush(); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Close inserts the last chunk and commits the transaction. The ciphertext is
+// left as it was if an error occurred.
+func (w *Writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if err := w.flush(); err != nil {
+		return err
+	}
+
+	w.err = errors.New("writer closed")
+	return w.tx.Commit()
+}
+
+// Abort rolls the transaction back, discarding the chunks written. It has no
+// effect once Close has been called.
+func (w *Writer) Abort() error {
+	if w.err != nil {
+		return nil
+	}
+	w.err = errors.New("writer aborted")
+	return w.tx.Rollback()
+}
+
+// flush inserts the buffered chunk, rolling back on error.
+func (w *Writer) flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	_, err := w.tx.ExecContext(w.ctx, "INSERT INTO tlock_chunks (name, idx, off, data) VALUES (?, ?, ?, ?)",
+		w.name, w.index, w.offset, w.buf)
+	if err != nil {
+		w.tx.Rollback()
+		w.err = fmt.Errorf("insert chunk %d of %s: %w", w.index, w.name, err)
+		return w.err
+	}
+
+	w.index++
+	w.offset += int64(len(w.buf))
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// =============================================================================
+
+// Reader reads a ciphertext of a container, fetching the chunks on demand.
+// It implements io.ReadSeeker and io.ReaderAt.
+type Reader struct {
+	ctx  context.Context
+	db   *sql.DB
+	name string
+	size int64
+	pos  int64
+}
+
+// Size returns the size of the ciphertext.
+func (r *Reader) Size() int64 {
+	return r.size
+}
+
+// Read implements io.Reader.
+func (r *Reader) Read(p []byte) (int, error) {
+	n, err := r.ReadAt(p, r.pos)
+	r.pos += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// Seek implements io.Seeker.
+func (r *Reader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.pos
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, errors.New("seek: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("seek: negative position")
+	}
+	r.pos = offset
+	return offset, nil
+}
+
+// ReadAt implements io.ReaderAt, selecting the chunks overlapping the range.
+func (r *Reader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("read: negative offset")
+	}
+	if off >= r.size {
+		return 0, io.EOF
+	}
+	end := min(off+int64(len(p)), r.size)
+
+	rows, err := r.db.QueryContext(r.ctx, `
+		SELECT off, data FROM tlock_chunks
+		WHERE name = ? AND off < ? AND off + LENGTH(data) > ?
+		ORDER BY idx`, r.name, end, off)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var n int
+	for rows.Next() {
+		var start int64
+		var data []byte
+		if err := rows.Scan(&start, &data); err != nil {
+			return n, err
+		}
+		pos := off + int64(n)
+		if start > pos || start+int64(len(data)) <= pos {
+			return n, fmt.Errorf("read %s: chunk at %d, expected one holding %d", r.name, start, pos)
+		}
+		n += copy(p[n:end-off], data[pos-start:])
+	}
+	if err := rows.Err(); err != nil {
+		return n, err
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
@@ -0,0 +1,66 @@
+package sqlcontainer_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/JonathanLogan/tlock/sqlcontainer"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// TestContainer runs against modernc.org/sqlite, which only the tests link,
+// the package leaving the choice of the driver to the application.
+func TestContainer(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "tlock.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	c, err := sqlcontainer.New(ctx, db, sqlcontainer.WithChunkSize(10))
+	require.NoError(t, err)
+
+	_, err = c.Open(ctx, "log")
+	require.ErrorIs(t, err, sqlcontainer.ErrNotFound)
+
+	w, err := c.Append(ctx, "log")
+	require.NoError(t, err)
+	_, err = w.Write([]byte("discarded"))
+	require.NoError(t, err)
+	require.NoError(t, w.Abort())
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), 5)
+	for _, part := range [][]byte{data[:33], data[33:]} {
+		w, err := c.Append(ctx, "log")
+		require.NoError(t, err)
+		_, err = w.Write(part)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+	}
+
+	r, err := c.Open(ctx, "log")
+	require.NoError(t, err)
+	require.Equal(t, int64(len(data)), r.Size())
+	got, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, data, got)
+
+	buf := make([]byte, 15)
+	n, err := r.ReadAt(buf, 28)
+	require.NoError(t, err)
+	require.Equal(t, data[28:28+n], buf)
+	n, err = r.ReadAt(buf, int64(len(data))-5)
+	require.ErrorIs(t, err, io.EOF)
+	require.Equal(t, data[len(data)-5:], buf[:n])
+
+	names, err := c.Names(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"log"}, names)
+	require.NoError(t, c.Delete(ctx, "log"))
+	_, err = c.Open(ctx, "log")
+	require.ErrorIs(t, err, sqlcontainer.ErrNotFound)
+}
@@ -0,0 +1,188 @@
+// Package sqlvalue provides column values which are timelock encrypted when
+// written to a database and decrypted when read back once their round is
+// reached, for applications storing embargoed records in Postgres, MySQL or
+// any other database/sql driver. The values are stored as binary ciphertexts,
+// hence in bytea, BLOB or VARBINARY columns.
+//
+//	codec := sqlvalue.New(network)
+//	_, err := db.Exec("INSERT INTO reports (id, body) VALUES ($1, $2)", id, codec.String(body, roundNumber))
+//
+//	body := codec.String("", 0)
+//	err := db.QueryRow("SELECT body FROM reports WHERE id = $1", id).Scan(&body)
+//	var tooEarly *sqlvalue.TooEarlyError
+//	if errors.As(err, &tooEarly) {
+//		// The report is still embargoed until tooEarly.UnlockTime.
+//	}
+//
+// Scanning stops at the first column failing, so locked columns are best
+// scanned last, or in their own query.
+package sqlvalue
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+)
+
+// TooEarlyError is returned when scanning a value whose round isn't reached
+// yet. It matches tlock.ErrTooEarly.
+type TooEarlyError struct {
+	Round      uint64
+	UnlockTime time.Time // Zero when the network can't tell it.
+}
+
+// Error implements the error interface.
+func (e *TooEarlyError) Error() string {
+	if e.UnlockTime.IsZero() {
+		return fmt.Sprintf("value locked until round %d", e.Round)
+	}
+	return fmt.Sprintf("value locked until round %d, expected at %s", e.Round, e.UnlockTime.UTC().Format(time.RFC3339))
+}
+
+// Unwrap returns tlock.ErrTooEarly.
+func (e *TooEarlyError) Unwrap() error {
+	return tlock.ErrTooEarly
+}
+
+// Codec encrypts and decrypts the values with a network.
+type Codec struct {
+	network tlock.Network
+	tlock   tlock.Tlock
+}
+
+// New constructs a codec for the network. The codec lives as long as the
+// connection pool and its values are scanned concurrently, so a row of another
+// chain doesn't switch the network.
+func New(network tlock.Network) *Codec {
+	return &Codec{
+		network: network,
+		tlock:   tlock.New(network).Strict(),
+	}
+}
+
+// WithTlock returns a codec using t, carrying its options such as a
+// passphrase or a namespace, to encrypt and decrypt the values.
+func (c *Codec) WithTlock(t tlock.Tlock) *Codec {
+	return &Codec{network: c.network, tlock: t}
+}
+
+// Bytes returns a value encrypting data towards the round. A nil data is
+// written as NULL.
+func (c *Codec) Bytes(data []byte, roundNumber uint64) Bytes {
+	return Bytes{Codec: c, Round: roundNumber, Data: data}
+}
+
+// String returns a value encrypting s towards the round.
+func (c *Codec) String(s string, roundNumber uint64) String {
+	return String{Codec: c, Round: roundNumber, String: s, Valid: true}
+}
+
+// =============================================================================
+
+// Bytes is a byte slice column value. Scanning a NULL sets Data to nil.
+type Bytes struct {
+	Codec *Codec
+	Round uint64 // The round to encrypt towards, set by Scan to the one of the ciphertext.
+	Data  []byte
+}
+
+// Value implements the driver.Valuer interface.
+func (b Bytes) Value() (driver.Value, error) {
+	if b.Data == nil {
+		return nil, nil
+	}
+	return b.Codec.encrypt(b.Data, b.Round)
+}
+
+// Scan implements the sql.Scanner interface. It returns a *TooEarlyError when
+// the round of the value isn't reached yet.
+func (b *Bytes) Scan(src any) error {
+	data, roundNumber, err := b.Codec.decrypt(src)
+	b.Data, b.Round = data, roundNumber
+	return err
+}
+
+// String is a string column value. Valid is false for NULL.
+type String struct {
+	Codec  *Codec
+	Round  uint64 // The round to encrypt towards, set by Scan to the one of the ciphertext.
+	String string
+	Valid  bool
+}
+
+// Value implements the driver.Valuer interface.
+func (s String) Value() (driver.Value, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	return s.Codec.encrypt([]byte(s.String), s.Round)
+}
+
+// Scan implements the sql.Scanner interface. It returns a *TooEarlyError when
+// the round of the value isn't reached yet.
+func (s *String) Scan(src any) error {
+	data, roundNumber, err := s.Codec.decrypt(src)
+	s.String, s.Round, s.Valid = string(data), roundNumber, data != nil
+	return err
+}
+
+// =============================================================================
+
+// encrypt returns the binary ciphertext of the data.
+func (c *Codec) encrypt(data []byte, roundNumber uint64) ([]byte, error) {
+	if c == nil {
+		return nil, errors.New("sqlvalue: value without codec")
+	}
+	if roundNumber == 0 {
+		return nil, errors.New("sqlvalue: value without round")
+	}
+
+	var buf bytes.Buffer
+	if err := c.tlock.Encrypt(&buf, bytes.NewReader(data), roundNumber); err != nil {
+		return nil, fmt.Errorf("sqlvalue: encrypt: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// decrypt decrypts the ciphertext read from the database, returning nil for
+// NULL and a non nil slice otherwise.
+func (c *Codec) decrypt(src any) ([]byte, uint64, error) {
+	if c == nil {
+		return nil, 0, errors.New("sqlvalue: value without codec")
+	}
+
+	var ciphertext []byte
+	switch v := src.(type) {
+	case nil:
+		return nil, 0, nil
+	case []byte:
+		ciphertext = v
+	case string:
+		ciphertext = []byte(v)
+	default:
+		return nil, 0, fmt.Errorf("sqlvalue: unsupported type %T", src)
+	}
+
+	hdr, err := tlock.ParseHeader(bytes.NewReader(ciphertext))
+	if err != nil {
+		return nil, 0, fmt.Errorf("sqlvalue: %w", err)
+	}
+	roundNumber, _, err := hdr.Round()
+	if err != nil {
+		return nil, 0, fmt.Errorf("sqlvalue: %w", err)
+	}
+
+	plaintext := bytes.NewBuffer([]byte{})
+	if err := c.tlock.Decrypt(plaintext, bytes.NewReader(ciphertext)); err != nil {
+		if errors.Is(err, tlock.ErrTooEarly) {
+			unlock, _ := tlock.RoundTime(c.network, roundNumber)
+			return nil, roundNumber, &TooEarlyError{Round: roundNumber, UnlockTime: unlock}
+		}
+		return nil, roundNumber, fmt.Errorf("sqlvalue: decrypt: %w", err)
+	}
+	return plaintext.Bytes(), roundNumber, nil
+}
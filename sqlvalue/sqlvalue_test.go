@@ -0,0 +1,100 @@
+package sqlvalue_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/JonathanLogan/tlock/sqlvalue"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ driver.Valuer = sqlvalue.Bytes{}
+	_ driver.Valuer = sqlvalue.String{}
+	_ sql.Scanner   = (*sqlvalue.Bytes)(nil)
+	_ sql.Scanner   = (*sqlvalue.String)(nil)
+)
+
+func TestRoundTrip(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	codec := sqlvalue.New(fixedtest.NewNetwork(t, 1000))
+
+	v, err := codec.String("embargoed report", 1000).Value()
+	require.NoError(t, err)
+	require.IsType(t, []byte{}, v)
+
+	s := codec.String("", 0)
+	require.NoError(t, s.Scan(v))
+	require.Equal(t, sqlvalue.String{Codec: codec, Round: 1000, String: "embargoed report", Valid: true}, s)
+
+	v, err = codec.Bytes([]byte{}, 1000).Value()
+	require.NoError(t, err)
+	b := codec.Bytes(nil, 0)
+	require.NoError(t, b.Scan(string(v.([]byte))))
+	require.NotNil(t, b.Data)
+	require.Empty(t, b.Data)
+}
+
+func TestNull(t *testing.T) {
+	codec := sqlvalue.New(fixedtest.NewNetwork(t, 1000))
+
+	v, err := codec.Bytes(nil, 1000).Value()
+	require.NoError(t, err)
+	require.Nil(t, v)
+	v, err = sqlvalue.String{Codec: codec, Round: 1000}.Value()
+	require.NoError(t, err)
+	require.Nil(t, v)
+
+	s := codec.String("stale", 0)
+	require.NoError(t, s.Scan(nil))
+	require.False(t, s.Valid)
+	b := codec.Bytes([]byte("stale"), 0)
+	require.NoError(t, b.Scan(nil))
+	require.Nil(t, b.Data)
+}
+
+func TestTooEarly(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+	v, err := sqlvalue.New(network).Bytes([]byte("secret"), 5000).Value()
+	require.NoError(t, err)
+
+	b := sqlvalue.New(lockedNetwork{network}).Bytes(nil, 0)
+	err = b.Scan(v)
+	var tooEarly *sqlvalue.TooEarlyError
+	require.ErrorAs(t, err, &tooEarly)
+	require.ErrorIs(t, err, tlock.ErrTooEarly)
+	require.EqualValues(t, 5000, tooEarly.Round)
+	require.False(t, tooEarly.UnlockTime.IsZero())
+	require.EqualValues(t, 5000, b.Round)
+	require.Nil(t, b.Data)
+}
+
+func TestInvalid(t *testing.T) {
+	codec := sqlvalue.New(fixedtest.NewNetwork(t, 1000))
+
+	_, err := codec.String("no round", 0).Value()
+	require.Error(t, err)
+	_, err = sqlvalue.Bytes{Data: []byte("no codec"), Round: 1000}.Value()
+	require.Error(t, err)
+
+	b := codec.Bytes(nil, 0)
+	require.ErrorIs(t, b.Scan([]byte("plaintext")), tlock.ErrMalformedHeader)
+	require.Error(t, b.Scan(int64(1)))
+}
+
+// lockedNetwork is a network which hasn't emitted any round yet.
+type lockedNetwork struct {
+	*fixed.Network
+}
+
+func (lockedNetwork) Signature(uint64) ([]byte, error) {
+	return nil, errors.New("round not reached")
+}
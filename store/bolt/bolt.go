@@ -0,0 +1,219 @@
+// Package bolt implements store.Store in a bbolt database file, for services
+// running on a single host.
+package bolt
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/JonathanLogan/tlock/store"
+	bbolt "go.etcd.io/bbolt"
+)
+
+// These are the buckets of the database. The pending bucket indexes the items
+// by round, its keys being the big endian round followed by the ID.
+var (
+	itemsBucket   = []byte("items")
+	pendingBucket = []byte("pending")
+	beaconsBucket = []byte("beacons")
+	leasesBucket  = []byte("leases")
+)
+
+// Store is a store.Store backed by a bbolt database.
+type Store struct {
+	db *bbolt.DB
+}
+
+// Open opens the database file at path, creating it if needed.
+func Open(path string) (*Store, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range [][]byte{itemsBucket, pendingBucket, beaconsBucket, leasesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Put implements store.Store.
+func (s *Store) Put(_ context.Context, item store.Item) error {
+	value, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		items := tx.Bucket(itemsBucket)
+		if old := items.Get([]byte(item.ID)); old != nil {
+			var prev store.Item
+			if err := json.Unmarshal(old, &prev); err != nil {
+				return err
+			}
+			if err := tx.Bucket(pendingBucket).Delete(pendingKey(prev)); err != nil {
+				return err
+			}
+		}
+		if err := items.Put([]byte(item.ID), value); err != nil {
+			return err
+		}
+		return tx.Bucket(pendingBucket).Put(pendingKey(item), nil)
+	})
+}
+
+// Get implements store.Store.
+func (s *Store) Get(_ context.Context, id string) (store.Item, error) {
+	var item store.Item
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		value := tx.Bucket(itemsBucket).Get([]byte(id))
+		if value == nil {
+			return store.ErrNotFound
+		}
+		return json.Unmarshal(value, &item)
+	})
+	return item, err
+}
+
+// Delete implements store.Store.
+func (s *Store) Delete(_ context.Context, id string) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		items := tx.Bucket(itemsBucket)
+		value := items.Get([]byte(id))
+		if value == nil {
+			return nil
+		}
+		var item store.Item
+		if err := json.Unmarshal(value, &item); err != nil {
+			return err
+		}
+		if err := tx.Bucket(pendingBucket).Delete(pendingKey(item)); err != nil {
+			return err
+		}
+		return items.Delete([]byte(id))
+	})
+}
+
+// List implements store.Store.
+func (s *Store) List(_ context.Context, maxRound uint64) ([]store.Item, error) {
+	var items []store.Item
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		all := tx.Bucket(itemsBucket)
+		c := tx.Bucket(pendingBucket).Cursor()
+		for k, _ := c.First(); k != nil && binary.BigEndian.Uint64(k) <= maxRound; k, _ = c.Next() {
+			value := all.Get(k[8:])
+			if value == nil {
+				return errors.New("pending index refers to a missing item")
+			}
+			var item store.Item
+			if err := json.Unmarshal(value, &item); err != nil {
+				return err
+			}
+			items = append(items, item)
+		}
+		return nil
+	})
+	return items, err
+}
+
+// PutBeacon implements store.Store.
+func (s *Store) PutBeacon(_ context.Context, chainHash string, roundNumber uint64, signature []byte) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(beaconsBucket).Put(beaconKey(chainHash, roundNumber), signature)
+	})
+}
+
+// GetBeacon implements store.Store.
+func (s *Store) GetBeacon(_ context.Context, chainHash string, roundNumber uint64) ([]byte, error) {
+	var signature []byte
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		value := tx.Bucket(beaconsBucket).Get(beaconKey(chainHash, roundNumber))
+		if value == nil {
+			return store.ErrNotFound
+		}
+		// Values are only valid during the transaction.
+		signature = append([]byte(nil), value...)
+		return nil
+	})
+	return signature, err
+}
+
+// Acquire implements store.Leaser.
+func (s *Store) Acquire(_ context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	acquired := false
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		leases := tx.Bucket(leasesBucket)
+		now := time.Now()
+		if value := leases.Get([]byte(key)); value != nil {
+			var l lease
+			if err := json.Unmarshal(value, &l); err != nil {
+				return err
+			}
+			if l.Owner != owner && now.Before(l.Expires) {
+				return nil
+			}
+		}
+		value, err := json.Marshal(lease{Owner: owner, Expires: now.Add(ttl)})
+		if err != nil {
+			return err
+		}
+		acquired = true
+		return leases.Put([]byte(key), value)
+	})
+	return acquired && err == nil, err
+}
+
+// Release implements store.Leaser.
+func (s *Store) Release(_ context.Context, key, owner string) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		leases := tx.Bucket(leasesBucket)
+		value := leases.Get([]byte(key))
+		if value == nil {
+			return nil
+		}
+		var l lease
+		if err := json.Unmarshal(value, &l); err != nil {
+			return err
+		}
+		if l.Owner != owner {
+			return nil
+		}
+		return leases.Delete([]byte(key))
+	})
+}
+
+// =============================================================================
+
+// lease is the value of a lease in the leases bucket.
+type lease struct {
+	Owner   string
+	Expires time.Time
+}
+
+// pendingKey returns the key of the item in the pending bucket.
+func pendingKey(item store.Item) []byte {
+	return append(binary.BigEndian.AppendUint64(nil, item.Round), item.ID...)
+}
+
+// beaconKey returns the key of the beacon in the beacons bucket.
+func beaconKey(chainHash string, roundNumber uint64) []byte {
+	return binary.BigEndian.AppendUint64([]byte(chainHash+"/"), roundNumber)
+}
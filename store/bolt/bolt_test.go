@@ -0,0 +1,26 @@
+package bolt_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/JonathanLogan/tlock/store/bolt"
+	"github.com/JonathanLogan/tlock/store/storetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	s, err := bolt.Open(filepath.Join(t.TempDir(), "tlock.db"))
+	require.NoError(t, err)
+	defer s.Close()
+
+	storetest.Run(t, s)
+}
+
+func TestLeaser(t *testing.T) {
+	s, err := bolt.Open(filepath.Join(t.TempDir(), "tlock.db"))
+	require.NoError(t, err)
+	defer s.Close()
+
+	storetest.RunLeaser(t, s)
+}
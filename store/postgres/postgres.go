@@ -0,0 +1,178 @@
+// Package postgres implements store.Store in a PostgreSQL database, letting
+// replicas of a service share their state.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/JonathanLogan/tlock/store"
+	// The driver is registered for Open.
+	_ "github.com/lib/pq"
+)
+
+// schema creates the tables of the store. Rounds are stored as BIGINT, which
+// holds any round a chain will reach.
+const schema = `
+CREATE TABLE IF NOT EXISTS tlock_items (
+	id         TEXT PRIMARY KEY,
+	round      BIGINT NOT NULL,
+	chain_hash TEXT NOT NULL,
+	ciphertext BYTEA NOT NULL,
+	created    TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS tlock_items_round ON tlock_items (round, id);
+CREATE TABLE IF NOT EXISTS tlock_beacons (
+	chain_hash TEXT NOT NULL,
+	round      BIGINT NOT NULL,
+	signature  BYTEA NOT NULL,
+	PRIMARY KEY (chain_hash, round)
+);
+CREATE TABLE IF NOT EXISTS tlock_leases (
+	key     TEXT PRIMARY KEY,
+	owner   TEXT NOT NULL,
+	expires TIMESTAMPTZ NOT NULL
+);`
+
+// Store is a store.Store backed by a PostgreSQL database.
+type Store struct {
+	db *sql.DB
+}
+
+// Open connects to the database of the connection string, as accepted by the
+// lib/pq driver, and creates the tables of the store if needed.
+func Open(ctx context.Context, dsn string) (*Store, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	s, err := New(ctx, db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// New constructs a store using the database, creating its tables if needed.
+func New(ctx context.Context, db *sql.DB) (*Store, error) {
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Put implements store.Store.
+func (s *Store) Put(ctx context.Context, item store.Item) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO tlock_items (id, round, chain_hash, ciphertext, created) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (id) DO UPDATE SET round = $2, chain_hash = $3, ciphertext = $4, created = $5`,
+		item.ID, roundParam(item.Round), item.ChainHash, item.Ciphertext, item.Created)
+	return err
+}
+
+// Get implements store.Store.
+func (s *Store) Get(ctx context.Context, id string) (store.Item, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT id, round, chain_hash, ciphertext, created FROM tlock_items WHERE id = $1`, id)
+	item, err := scanItem(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.Item{}, store.ErrNotFound
+	}
+	return item, err
+}
+
+// Delete implements store.Store.
+func (s *Store) Delete(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM tlock_items WHERE id = $1`, id)
+	return err
+}
+
+// List implements store.Store.
+func (s *Store) List(ctx context.Context, maxRound uint64) ([]store.Item, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, round, chain_hash, ciphertext, created FROM tlock_items
+		WHERE round <= $1 ORDER BY round, id`, roundParam(maxRound))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []store.Item
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// PutBeacon implements store.Store.
+func (s *Store) PutBeacon(ctx context.Context, chainHash string, roundNumber uint64, signature []byte) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO tlock_beacons (chain_hash, round, signature) VALUES ($1, $2, $3)
+		ON CONFLICT (chain_hash, round) DO UPDATE SET signature = $3`,
+		chainHash, roundParam(roundNumber), signature)
+	return err
+}
+
+// GetBeacon implements store.Store.
+func (s *Store) GetBeacon(ctx context.Context, chainHash string, roundNumber uint64) ([]byte, error) {
+	var signature []byte
+	err := s.db.QueryRowContext(ctx, `SELECT signature FROM tlock_beacons WHERE chain_hash = $1 AND round = $2`,
+		chainHash, roundParam(roundNumber)).Scan(&signature)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	}
+	return signature, err
+}
+
+// Acquire implements store.Leaser. Expiry uses the clock of the database,
+// which all the replicas share.
+func (s *Store) Acquire(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	// The update only applies when the owner holds the lease or it expired,
+	// returning no row otherwise.
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO tlock_leases (key, owner, expires) VALUES ($1, $2, now() + $3 * interval '1 microsecond')
+		ON CONFLICT (key) DO UPDATE SET owner = $2, expires = now() + $3 * interval '1 microsecond'
+		WHERE tlock_leases.owner = $2 OR tlock_leases.expires <= now()
+		RETURNING owner`, key, owner, ttl.Microseconds()).Scan(new(string))
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Release implements store.Leaser.
+func (s *Store) Release(ctx context.Context, key, owner string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM tlock_leases WHERE key = $1 AND owner = $2`, key, owner)
+	return err
+}
+
+// =============================================================================
+
+// roundParam converts a round to a BIGINT, saturating the rounds past its
+// range such as the maximum round given to List.
+func roundParam(roundNumber uint64) int64 {
+	return int64(min(roundNumber, math.MaxInt64))
+}
+
+// scanItem scans an item from a row of the items table.
+func scanItem(row interface{ Scan(...any) error }) (store.Item, error) {
+	var item store.Item
+	var roundNumber int64
+	if err := row.Scan(&item.ID, &roundNumber, &item.ChainHash, &item.Ciphertext, &item.Created); err != nil {
+		return store.Item{}, err
+	}
+	item.Round = uint64(roundNumber)
+	return item, nil
+}
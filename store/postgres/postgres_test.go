@@ -0,0 +1,34 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/JonathanLogan/tlock/store/postgres"
+	"github.com/JonathanLogan/tlock/store/storetest"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStore runs against the database of the TLOCK_POSTGRES_DSN connection
+// string, emptying the tables of the store first.
+func TestStore(t *testing.T) {
+	dsn := os.Getenv("TLOCK_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TLOCK_POSTGRES_DSN is not set")
+	}
+
+	ctx := context.Background()
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+
+	s, err := postgres.New(ctx, db)
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, "TRUNCATE tlock_items, tlock_beacons, tlock_leases")
+	require.NoError(t, err)
+
+	storetest.Run(t, s)
+	storetest.RunLeaser(t, s)
+}
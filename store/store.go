@@ -0,0 +1,177 @@
+// Package store defines the storage of the state of services processing
+// ciphertexts at unlock time: the items pending until their round and a cache
+// of the beacons fetched. Sharing a store lets such services run replicated.
+// The bolt and postgres subpackages implement it on disk and in a database.
+package store
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned when an item or a beacon isn't in the store.
+var ErrNotFound = errors.New("not found in store")
+
+// Item is a ciphertext pending until its round.
+type Item struct {
+	ID         string
+	Round      uint64
+	ChainHash  string
+	Ciphertext []byte
+	Created    time.Time
+}
+
+// Store stores the items and beacons of a service. Implementations are safe
+// for concurrent use.
+type Store interface {
+	// Put stores the item, replacing the one with the same ID.
+	Put(ctx context.Context, item Item) error
+	// Get returns the item with the ID, or ErrNotFound.
+	Get(ctx context.Context, id string) (Item, error)
+	// Delete removes the item with the ID, once processed.
+	Delete(ctx context.Context, id string) error
+	// List returns the items whose round is at most maxRound, ordered by
+	// round then ID.
+	List(ctx context.Context, maxRound uint64) ([]Item, error)
+
+	// PutBeacon caches the signature of the round of the chain.
+	PutBeacon(ctx context.Context, chainHash string, roundNumber uint64, signature []byte) error
+	// GetBeacon returns the cached signature of the round of the chain, or
+	// ErrNotFound.
+	GetBeacon(ctx context.Context, chainHash string, roundNumber uint64) ([]byte, error)
+}
+
+// Leaser grants leases expiring after a time to live, coordinating the
+// replicas sharing a store so that a single one processes an item, or acts as
+// the leader when the key is shared by all.
+type Leaser interface {
+	// Acquire takes the lease on the key for the owner, renewing it when the
+	// owner holds it already. It reports false when another owner holds a
+	// lease which hasn't expired.
+	Acquire(ctx context.Context, key, owner string, ttl time.Duration) (bool, error)
+	// Release gives up the lease on the key if the owner holds it.
+	Release(ctx context.Context, key, owner string) error
+}
+
+// =============================================================================
+
+// Memory is a Store keeping its state in memory, for a single instance and
+// tests.
+type Memory struct {
+	mu      sync.RWMutex
+	items   map[string]Item
+	beacons map[beaconKey][]byte
+	leases  map[string]lease
+}
+
+type lease struct {
+	owner   string
+	expires time.Time
+}
+
+type beaconKey struct {
+	chainHash string
+	round     uint64
+}
+
+// NewMemory constructs an empty memory store.
+func NewMemory() *Memory {
+	return &Memory{
+		items:   make(map[string]Item),
+		beacons: make(map[beaconKey][]byte),
+		leases:  make(map[string]lease),
+	}
+}
+
+// Put implements Store.
+func (m *Memory) Put(_ context.Context, item Item) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item.Ciphertext = slices.Clone(item.Ciphertext)
+	m.items[item.ID] = item
+	return nil
+}
+
+// Get implements Store.
+func (m *Memory) Get(_ context.Context, id string) (Item, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	item, ok := m.items[id]
+	if !ok {
+		return Item{}, ErrNotFound
+	}
+	item.Ciphertext = slices.Clone(item.Ciphertext)
+	return item, nil
+}
+
+// Delete implements Store.
+func (m *Memory) Delete(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.items, id)
+	return nil
+}
+
+// List implements Store.
+func (m *Memory) List(_ context.Context, maxRound uint64) ([]Item, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var items []Item
+	for _, id := range slices.Sorted(maps.Keys(m.items)) {
+		if item := m.items[id]; item.Round <= maxRound {
+			item.Ciphertext = slices.Clone(item.Ciphertext)
+			items = append(items, item)
+		}
+	}
+	slices.SortStableFunc(items, func(a, b Item) int {
+		return cmp.Compare(a.Round, b.Round)
+	})
+	return items, nil
+}
+
+// PutBeacon implements Store.
+func (m *Memory) PutBeacon(_ context.Context, chainHash string, roundNumber uint64, signature []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.beacons[beaconKey{chainHash, roundNumber}] = slices.Clone(signature)
+	return nil
+}
+
+// GetBeacon implements Store.
+func (m *Memory) GetBeacon(_ context.Context, chainHash string, roundNumber uint64) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	signature, ok := m.beacons[beaconKey{chainHash, roundNumber}]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return slices.Clone(signature), nil
+}
+
+// Acquire implements Leaser.
+func (m *Memory) Acquire(_ context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	if l, ok := m.leases[key]; ok && l.owner != owner && now.Before(l.expires) {
+		return false, nil
+	}
+	m.leases[key] = lease{owner: owner, expires: now.Add(ttl)}
+	return true, nil
+}
+
+// Release implements Leaser.
+func (m *Memory) Release(_ context.Context, key, owner string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if l, ok := m.leases[key]; ok && l.owner == owner {
+		delete(m.leases, key)
+	}
+	return nil
+}
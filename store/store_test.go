@@ -0,0 +1,16 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/JonathanLogan/tlock/store"
+	"github.com/JonathanLogan/tlock/store/storetest"
+)
+
+func TestMemory(t *testing.T) {
+	storetest.Run(t, store.NewMemory())
+}
+
+func TestMemoryLeaser(t *testing.T) {
+	storetest.RunLeaser(t, store.NewMemory())
+}
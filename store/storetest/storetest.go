@@ -0,0 +1,111 @@
+// Package storetest checks implementations of the store.Store interface.
+package storetest
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock/store"
+	"github.com/stretchr/testify/require"
+)
+
+// Run checks the behavior of the empty store s.
+func Run(t *testing.T, s store.Store) {
+	t.Helper()
+	ctx := context.Background()
+
+	_, err := s.Get(ctx, "missing")
+	require.ErrorIs(t, err, store.ErrNotFound)
+
+	created := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, item := range []store.Item{
+		{ID: "c", Round: 20, ChainHash: "chain", Ciphertext: []byte("third"), Created: created},
+		{ID: "b", Round: 10, ChainHash: "chain", Ciphertext: []byte("second"), Created: created},
+		{ID: "a", Round: 10, ChainHash: "chain", Ciphertext: []byte("first"), Created: created},
+		{ID: "d", Round: 30, ChainHash: "chain", Ciphertext: []byte("stale"), Created: created},
+	} {
+		require.NoError(t, s.Put(ctx, item))
+	}
+	require.NoError(t, s.Put(ctx, store.Item{ID: "d", Round: 40, ChainHash: "chain", Ciphertext: []byte("fourth"), Created: created}))
+
+	item, err := s.Get(ctx, "b")
+	require.NoError(t, err)
+	require.Equal(t, store.Item{ID: "b", Round: 10, ChainHash: "chain", Ciphertext: []byte("second"), Created: created}, normalize(item))
+
+	items, err := s.List(ctx, 20)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b", "c"}, ids(items))
+
+	items, err = s.List(ctx, math.MaxUint64)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b", "c", "d"}, ids(items))
+	require.Equal(t, uint64(40), items[3].Round)
+
+	require.NoError(t, s.Delete(ctx, "a"))
+	require.NoError(t, s.Delete(ctx, "missing"))
+	items, err = s.List(ctx, 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{"b"}, ids(items))
+
+	_, err = s.GetBeacon(ctx, "chain", 10)
+	require.ErrorIs(t, err, store.ErrNotFound)
+	require.NoError(t, s.PutBeacon(ctx, "chain", 10, []byte("signature")))
+	signature, err := s.GetBeacon(ctx, "chain", 10)
+	require.NoError(t, err)
+	require.Equal(t, []byte("signature"), signature)
+	_, err = s.GetBeacon(ctx, "other", 10)
+	require.ErrorIs(t, err, store.ErrNotFound)
+}
+
+// RunLeaser checks the behavior of the leaser l holding no lease.
+func RunLeaser(t *testing.T, l store.Leaser) {
+	t.Helper()
+	ctx := context.Background()
+
+	ok, err := l.Acquire(ctx, "item/a", "one", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = l.Acquire(ctx, "item/a", "two", time.Minute)
+	require.NoError(t, err)
+	require.False(t, ok, "lease held by another owner")
+	ok, err = l.Acquire(ctx, "item/a", "one", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok, "lease renewed by its owner")
+	ok, err = l.Acquire(ctx, "item/b", "two", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok, "lease on another key")
+
+	require.NoError(t, l.Release(ctx, "item/a", "two"))
+	ok, err = l.Acquire(ctx, "item/a", "two", time.Minute)
+	require.NoError(t, err)
+	require.False(t, ok, "lease released by another owner")
+	require.NoError(t, l.Release(ctx, "item/a", "one"))
+	ok, err = l.Acquire(ctx, "item/a", "two", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok, "lease released by its owner")
+
+	ok, err = l.Acquire(ctx, "item/c", "one", 50*time.Millisecond)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Eventually(t, func() bool {
+		ok, err := l.Acquire(ctx, "item/c", "two", time.Minute)
+		return err == nil && ok
+	}, 5*time.Second, 20*time.Millisecond, "lease expired")
+}
+
+// normalize drops the location and monotonic clock of the creation time,
+// which stores may not keep.
+func normalize(item store.Item) store.Item {
+	item.Created = item.Created.UTC()
+	return item
+}
+
+func ids(items []store.Item) []string {
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
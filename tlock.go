@@ -4,7 +4,6 @@
 package tlock
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
 	"io"
@@ -12,11 +11,10 @@ import (
 	"time"
 
 	"filippo.io/age"
-	"filippo.io/age/armor"
 	chain "github.com/drand/drand/v2/common"
+	dchain "github.com/drand/drand/v2/common/chain"
 	"github.com/drand/drand/v2/crypto"
 	"github.com/drand/kyber"
-	bls "github.com/drand/kyber-bls12381"
 	"github.com/drand/kyber/encrypt/ibe"
 	"gopkg.in/yaml.v3"
 )
@@ -25,10 +23,15 @@ import (
 var ErrTooEarly = errors.New("too early to decrypt")
 var ErrInvalidPublicKey = errors.New("the public key received from the network to encrypt this was infinity and thus insecure")
 
+// ErrNotUnchained represents an error when encrypting towards a network whose
+// scheme doesn't support timelock encryption, such as a chained one.
+var ErrNotUnchained = errors.New("not an unchained network")
+
 // =============================================================================
 
 // Network represents a system that provides support for encrypting/decrypting
-// a DEK based on a future time.
+// a DEK based on a future time. New transports implement the smaller
+// Transport interface instead, which NewTransportNetwork turns into a Network.
 type Network interface {
 	ChainHash() string
 	Current(time.Time) uint64
@@ -44,6 +47,21 @@ type Network interface {
 type Tlock struct {
 	network        Network
 	trustChainhash bool
+	passphrase     string
+	kdf            KDFParams
+	namespace      string
+	metadata       UserMetadata
+	sealMetadata   bool
+	cipher         byte
+	seed           []byte
+	notAfter       uint64
+	enforceWindow  bool
+	random         io.Reader
+	puzzle         uint64
+	strictDecode   bool
+
+	maxPlaintextSize int64
+	workers          int
 }
 
 // New constructs a tlock for the specified network which can encrypt data that
@@ -65,14 +83,44 @@ func (t Tlock) Strict() Tlock {
 // Encrypt will encrypt the source and write that to the destination. The encrypted
 // data will not be decryptable until the specified round is reached by the network.
 func (t Tlock) Encrypt(dst io.Writer, src io.Reader, roundNumber uint64) (err error) {
-	w, err := age.Encrypt(dst, &Recipient{network: t.network, roundNumber: roundNumber})
+	if err := checkUnchained(t.network); err != nil {
+		return err
+	}
+	if err := approved("ChaCha20-Poly1305", "the age payload"); err != nil {
+		return err
+	}
+
+	if t.metadata, err = t.windowMetadata(roundNumber); err != nil {
+		return err
+	}
+
+	if t.passphrase != "" {
+		if err := t.kdf.validate(); err != nil {
+			return err
+		}
+	}
+
+	if t.metadata != nil {
+		if _, err := marshalMetadata(t.metadata); err != nil {
+			return err
+		}
+	}
+
+	if t.random != nil {
+		return t.encryptWithRandom(dst, src, roundNumber)
+	}
+	if t.seed != nil {
+		return t.encryptReproducible(dst, src, roundNumber)
+	}
+
+	w, err := age.Encrypt(dst, &Recipient{network: t.network, roundNumber: roundNumber, passphrase: t.passphrase, kdf: t.kdf, namespace: t.namespace, metadata: t.metadata, sealMetadata: t.sealMetadata, puzzle: t.puzzle})
 	if err != nil {
 		return fmt.Errorf("hybrid encrypt: %w", err)
 	}
 
 	defer func() {
-		if err = w.Close(); err != nil {
-			err = fmt.Errorf("close: %w", err)
+		if cerr := w.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("close: %w", cerr)
 		}
 	}()
 
@@ -85,23 +133,39 @@ func (t Tlock) Encrypt(dst io.Writer, src io.Reader, roundNumber uint64) (err er
 
 // Decrypt will decrypt the source and write that to the destination. The decrypted
 // data will not be decryptable unless the specified round from the encrypt call
-// is reached by the network.
+// is reached by the network. Failures past the header are reported as a
+// PartialDecryptionError.
 func (t Tlock) Decrypt(dst io.Writer, src io.Reader) error {
-	rr := bufio.NewReader(src)
-
-	if start, _ := rr.Peek(len(armor.Header)); string(start) == armor.Header {
-		src = armor.NewReader(rr)
-	} else {
-		src = rr
+	if err := approved("ChaCha20-Poly1305", "the age payload"); err != nil {
+		return err
+	}
+	rr, err := t.dearmor(src)
+	if err != nil {
+		return fmt.Errorf("hybrid decrypt: %w", err)
+	}
+	intro, err := rr.Peek(len(headerIntro))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("hybrid decrypt: %w", err)
+	}
+	if string(intro) != headerIntro {
+		return fmt.Errorf("%w: not an age ciphertext", ErrMalformedHeader)
+	}
+	if t.workers > 1 {
+		return t.decryptParallel(dst, rr)
 	}
 
-	r, err := age.Decrypt(src, &Identity{network: t.network, trustChainhash: t.trustChainhash})
+	r, err := age.Decrypt(rr, &Identity{network: t.network, trustChainhash: t.trustChainhash, passphrase: t.passphrase, namespace: t.namespace, enforceWindow: t.enforceWindow, strict: t.strictDecode})
 	if err != nil {
 		return fmt.Errorf("hybrid decrypt: %w", err)
 	}
 
-	if _, err := io.Copy(dst, r); err != nil {
-		return fmt.Errorf("write: %w", err)
+	cw := countingWriter{w: t.limitPlaintext(dst)}
+	if _, err := io.Copy(&cw, r); err != nil {
+		return &PartialDecryptionError{
+			Chunks: cw.n / ChunkSize,
+			Bytes:  cw.n,
+			Err:    fmt.Errorf("write: %w", err),
+		}
 	}
 
 	return nil
@@ -132,6 +196,30 @@ func (t Tlock) Metadata(dst io.Writer) (err error) {
 	return nil
 }
 
+// IsReadyToDecrypt reports whether the network published the round. Networks
+// able to report their latest round are asked for it, as a chain catching up
+// publishes rounds later than their time, then in a burst. Otherwise, the
+// round is computed from the time.
+func IsReadyToDecrypt(network Network, roundNumber uint64) bool {
+	if n, ok := network.(interface{ LatestRound() (uint64, error) }); ok {
+		if latest, err := n.LatestRound(); err == nil {
+			return latest >= roundNumber
+		}
+	}
+	return network.Current(time.Now()) >= roundNumber
+}
+
+// RoundTime returns the time at which the network emits the specified round.
+// It reports false when the network doesn't expose its chain information.
+func RoundTime(network Network, roundNumber uint64) (time.Time, bool) {
+	n, ok := network.(interface{ Info() *dchain.Info })
+	if !ok || n.Info() == nil {
+		return time.Time{}, false
+	}
+	info := n.Info()
+	return time.Unix(chain.TimeOfRound(info.Period, info.GenesisTime, roundNumber), 0), true
+}
+
 // =============================================================================
 
 // TimeLock encrypts the specified data for the given round number. The data
@@ -147,25 +235,8 @@ func TimeLock(scheme crypto.Scheme, publicKey kyber.Point, roundNumber uint64, d
 	log.Printf("Round number: %d\n", roundNumber)
 	log.Printf("Network Public key: %s\n", publicKey.String())
 	log.Printf("ID %x\n", id)
-	var cipherText *ibe.Ciphertext
-	var err error
-	switch scheme.Name {
-	case crypto.ShortSigSchemeID:
-		// the ShortSigSchemeID uses the wrong DST for G1, so we keep it for retro-compatibility
-		cipherText, err = ibe.EncryptCCAonG2(bls.NewBLS12381SuiteWithDST(bls.DefaultDomainG2(), bls.DefaultDomainG2()), publicKey, id, data)
-	case crypto.UnchainedSchemeID:
-		cipherText, err = ibe.EncryptCCAonG1(bls.NewBLS12381Suite(), publicKey, id, data)
-	case crypto.SigsOnG1ID:
-		cipherText, err = ibe.EncryptCCAonG2(bls.NewBLS12381Suite(), publicKey, id, data)
-	default:
-		return nil, fmt.Errorf("unsupported drand scheme '%s'", scheme.Name)
-	}
-
-	if err != nil {
-		return nil, fmt.Errorf("encrypt data: %w", err)
-	}
 
-	return cipherText, nil
+	return EncryptToIdentity(scheme, publicKey, id, data)
 }
 
 // TimeUnlock decrypts the specified ciphertext for the given beacon. The
@@ -175,32 +246,11 @@ func TimeUnlock(scheme crypto.Scheme, publicKey kyber.Point, beacon chain.Beacon
 		return nil, fmt.Errorf("verify beacon: %w", err)
 	}
 
-	var data []byte
-	var err error
-	switch scheme.Name {
-	case crypto.ShortSigSchemeID:
-		var signature bls.KyberG1
-		if err := signature.UnmarshalBinary(beacon.Signature); err != nil {
-			return nil, fmt.Errorf("unmarshal kyber G1: %w", err)
-		}
-		// the ShortSigSchemeID uses the wrong DST for G1, so we keep it for retro-compatibility
-		data, err = ibe.DecryptCCAonG2(bls.NewBLS12381SuiteWithDST(bls.DefaultDomainG2(), bls.DefaultDomainG2()), &signature, ciphertext)
-	case crypto.UnchainedSchemeID:
-		var signature bls.KyberG2
-		if err := signature.UnmarshalBinary(beacon.Signature); err != nil {
-			return nil, fmt.Errorf("unmarshal kyber G2: %w", err)
-		}
-		data, err = ibe.DecryptCCAonG1(bls.NewBLS12381Suite(), &signature, ciphertext)
-	case crypto.SigsOnG1ID:
-		var signature bls.KyberG1
-		if err := signature.UnmarshalBinary(beacon.Signature); err != nil {
-			return nil, fmt.Errorf("unmarshal kyber G1: %w", err)
-		}
-		data, err = ibe.DecryptCCAonG2(bls.NewBLS12381Suite(), &signature, ciphertext)
-	default:
-		return nil, fmt.Errorf("unsupported drand scheme '%s'", scheme.Name)
+	suite, err := suiteFor(scheme.Name)
+	if err != nil {
+		return nil, err
 	}
-
+	data, err := suite.Decrypt(beacon.Signature, ciphertext)
 	if err != nil {
 		return nil, fmt.Errorf("decrypt dek: %w", err)
 	}
@@ -208,6 +258,22 @@ func TimeUnlock(scheme crypto.Scheme, publicKey kyber.Point, beacon chain.Beacon
 	return data, nil
 }
 
+// checkUnchained verifies that the network, according to its chain information
+// when exposed, uses a scheme timelock encryption can be performed with.
+func checkUnchained(network Network) error {
+	schemes := []string{network.Scheme().Name}
+	if n, ok := network.(interface{ Info() *dchain.Info }); ok && n.Info() != nil {
+		schemes = append(schemes, n.Info().Scheme)
+	}
+
+	for _, scheme := range schemes {
+		if _, err := suiteFor(scheme); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // =============================================================================
 
 // These constants define the size of the different CipherDEK fields.
@@ -4,13 +4,14 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
-	"time"
 
 	"filippo.io/age"
-	chain "github.com/drand/drand/v2/common"
+	"github.com/drand/kyber/encrypt/ibe"
+	"golang.org/x/crypto/argon2"
 )
 
 var ErrWrongChainhash = errors.New("invalid chainhash")
@@ -20,6 +21,17 @@ var ErrWrongChainhash = errors.New("invalid chainhash")
 type Recipient struct {
 	network     Network
 	roundNumber uint64
+	passphrase  string
+	kdf         KDFParams
+	namespace   string
+
+	metadata     UserMetadata
+	sealMetadata bool
+
+	puzzle uint64 // Squarings of the time-lock puzzle, none when zero.
+
+	sigma  []byte    // Random element of the encryption, drawn when nil.
+	random io.Reader // Source of the salt of the passphrase and of the puzzle, crypto/rand when nil.
 }
 
 func NewRecipient(network Network, roundNumber uint64) *Recipient {
@@ -40,11 +52,51 @@ func (t *Recipient) SetRound(round uint64) {
 	t.roundNumber = round
 }
 
+// SetPassphrase additionally protects the wrapped filekeys with the passphrase,
+// using argon2id with the given parameters to derive a mask for them.
+func (t *Recipient) SetPassphrase(passphrase string, params KDFParams) {
+	t.passphrase = passphrase
+	t.kdf = params
+}
+
+// SetNamespace labels the stanzas with the application namespace.
+func (t *Recipient) SetNamespace(namespace string) {
+	t.namespace = namespace
+}
+
+// SetMetadata attaches the metadata to the header, sealed with the file key
+// when sealed is set.
+func (t *Recipient) SetMetadata(m UserMetadata, sealed bool) {
+	t.metadata = m
+	t.sealMetadata = sealed
+}
+
 // Wrap is called by the age Encrypt API and is provided the DEK generated by
 // age that is used for encrypting/decrypting data. Inside of Wrap we encrypt
 // the DEK using timelock encryption.
 func (t *Recipient) Wrap(fileKey []byte) ([]*age.Stanza, error) {
-	ciphertext, err := TimeLock(t.network.Scheme(), t.network.PublicKey(), t.roundNumber, fileKey)
+	data := make([]byte, len(fileKey))
+	copy(data, fileKey)
+	var extra []string
+	if t.passphrase != "" {
+		mask, args, err := passphraseMask(t.passphrase, t.kdf, len(fileKey), randomReader(t.random))
+		if err != nil {
+			return nil, fmt.Errorf("passphrase: %w", err)
+		}
+		xorInPlace(data, mask)
+		extra = args
+	}
+	if t.namespace != "" {
+		extra = append(extra, namespaceArgs(t.namespace)...)
+	}
+
+	var ciphertext *ibe.Ciphertext
+	var err error
+	if t.sigma != nil {
+		ciphertext, err = timeLockWithSigma(t.network.Scheme(), t.network.PublicKey(), t.roundNumber, data, t.sigma)
+	} else {
+		ciphertext, err = TimeLock(t.network.Scheme(), t.network.PublicKey(), t.roundNumber, data)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("encrypt dek: %w", err)
 	}
@@ -56,11 +108,27 @@ func (t *Recipient) Wrap(fileKey []byte) ([]*age.Stanza, error) {
 
 	stanza := age.Stanza{
 		Type: "tlock",
-		Args: []string{strconv.FormatUint(t.roundNumber, 10), t.network.ChainHash()},
+		Args: append([]string{strconv.FormatUint(t.roundNumber, 10), t.network.ChainHash()}, extra...),
 		Body: body,
 	}
 
-	return []*age.Stanza{&stanza}, nil
+	stanzas := []*age.Stanza{&stanza}
+	if t.puzzle > 0 {
+		puzzle, err := wrapPuzzle(data, t.puzzle, extra, randomReader(t.random))
+		if err != nil {
+			return nil, fmt.Errorf("puzzle: %w", err)
+		}
+		stanzas = append(stanzas, puzzle)
+	}
+	if t.metadata != nil {
+		meta, err := wrapMetadata(t.metadata, t.sealMetadata, fileKey)
+		if err != nil {
+			return nil, fmt.Errorf("metadata: %w", err)
+		}
+		stanzas = append(stanzas, meta)
+	}
+
+	return stanzas, nil
 }
 
 func (t *Recipient) String() string {
@@ -85,6 +153,10 @@ func (t *Recipient) String() string {
 type Identity struct {
 	network        Network
 	trustChainhash bool
+	passphrase     string
+	namespace      string
+	enforceWindow  bool
+	strict         bool
 }
 
 func NewIdentity(network Network, trustChainhash bool) *Identity {
@@ -102,6 +174,16 @@ func (t *Identity) SetTrust(trust bool) {
 	t.trustChainhash = trust
 }
 
+// SetPassphrase sets the passphrase used to unwrap passphrase protected stanzas.
+func (t *Identity) SetPassphrase(passphrase string) {
+	t.passphrase = passphrase
+}
+
+// SetNamespace sets the application namespace stanzas must be labelled with.
+func (t *Identity) SetNamespace(namespace string) {
+	t.namespace = namespace
+}
+
 // Unwrap is called by the age Decrypt API and is provided the DEK that was time
 // lock encrypted by the Wrap function via the Stanza. Inside of Unwrap we decrypt
 // the DEK and provide back to age. If the ciphertext uses a chainhash different
@@ -110,6 +192,11 @@ func (t *Identity) Unwrap(stanzas []*age.Stanza) ([]byte, error) {
 	if len(stanzas) < 1 {
 		return nil, errors.New("check stanzas length: should be at least one")
 	}
+	if t.strict {
+		if err := checkStanzas(stanzas); err != nil {
+			return nil, err
+		}
+	}
 
 	invalid := ""
 	for _, stanza := range stanzas {
@@ -117,7 +204,7 @@ func (t *Identity) Unwrap(stanzas []*age.Stanza) ([]byte, error) {
 			continue
 		}
 
-		if len(stanza.Args) != 2 {
+		if len(stanza.Args) < 2 {
 			continue
 		}
 
@@ -126,54 +213,135 @@ func (t *Identity) Unwrap(stanzas []*age.Stanza) ([]byte, error) {
 			return nil, fmt.Errorf("parse block round: %w", err)
 		}
 
-		if t.network.ChainHash() != stanza.Args[1] {
+		if !t.useChainHash(stanza.Args[1]) {
 			invalid = stanza.Args[1]
-			if t.trustChainhash {
-				fmt.Fprintf(os.Stderr, "WARN: stanza using different chainhash '%s', trying to use it instead.\n", invalid)
-				err = t.network.SwitchChainHash(invalid)
-				if err != nil {
-					continue
-				}
-			} else {
-				continue
-			}
+			continue
 		}
 
-		ciphertext, err := BytesToCiphertext(t.network.Scheme(), stanza.Body)
+		unmask, err := t.unmasker(stanza.Args[2:])
 		if err != nil {
-			return nil, fmt.Errorf("parse cipher dek: %w", err)
+			return nil, err
 		}
 
-		signature, err := t.network.Signature(roundNumber)
+		ciphertext, err := BytesToCiphertext(t.network.Scheme(), stanza.Body)
 		if err != nil {
-			return nil, fmt.Errorf(
-				"%w: expected round %d > %d current round",
-				ErrTooEarly,
-				roundNumber,
-				t.network.Current(time.Now()))
-		}
-
-		beacon := chain.Beacon{
-			Round:     roundNumber,
-			Signature: signature,
+			return nil, fmt.Errorf("parse cipher dek: %w", err)
 		}
 
-		fileKey, err := TimeUnlock(t.network.Scheme(), t.network.PublicKey(), beacon, ciphertext)
+		fileKey, err := t.unlock(roundNumber, ciphertext)
 		if err != nil {
-			return nil, fmt.Errorf("decrypt dek: %w", err)
+			return nil, err
 		}
+		fileKey = unmask(fileKey)
 
+		if t.enforceWindow {
+			if err := t.checkWindow(stanzas, fileKey); err != nil {
+				return nil, err
+			}
+		}
 		return fileKey, nil
 	}
 
 	if len(invalid) > 0 {
-		return nil, fmt.Errorf("%w: current network uses %s != %s the ciphertext requires.\n"+
-			"Note that is might have been encrypted using our testnet instead", ErrWrongChainhash, t.network.ChainHash(), invalid)
+		return nil, t.wrongChainHash(invalid)
 	}
 
 	return nil, fmt.Errorf("check stanza type: wrong type: %w", age.ErrIncorrectIdentity)
 }
 
+// useChainHash reports whether the network is using the chainhash, switching
+// to it first if the identity trusts chainhashes found in ciphertexts.
+func (t *Identity) useChainHash(chainHash string) bool {
+	if t.network.ChainHash() == chainHash {
+		return true
+	}
+	if !t.trustChainhash {
+		return false
+	}
+
+	fmt.Fprintf(os.Stderr, "WARN: stanza using different chainhash '%s', trying to use it instead.\n", chainHash)
+	return t.network.SwitchChainHash(chainHash) == nil
+}
+
+// wrongChainHash returns the error reported when a ciphertext requires a
+// chainhash the network can't use.
+func (t *Identity) wrongChainHash(chainHash string) error {
+	return fmt.Errorf("%w: current network uses %s != %s the ciphertext requires.\n"+
+		"Note that is might have been encrypted using our testnet instead", ErrWrongChainhash, t.network.ChainHash(), chainHash)
+}
+
+// unlock retrieves and verifies the beacon of the round from the network and
+// uses it to decrypt the ciphertext.
+func (t *Identity) unlock(roundNumber uint64, ciphertext *ibe.Ciphertext) ([]byte, error) {
+	b, err := Tlock{network: t.network}.ObtainVerifiedBeacon(roundNumber)
+	if err != nil {
+		return nil, err
+	}
+	return b.decrypt(t.network.Scheme(), ciphertext)
+}
+
+// unmasker parses the extension arguments following the round and chainhash
+// of a stanza and returns the function removing the masks they describe from
+// the decrypted filekey. Parsing happens before any beacon is fetched so that
+// missing secrets are reported early.
+func (t *Identity) unmasker(args []string) (func([]byte) []byte, error) {
+	var masks []func(size int) []byte
+	var namespaced bool
+	seen := make(map[string]bool)
+	for len(args) > 0 {
+		if t.strict && seen[args[0]] {
+			return nil, fmt.Errorf("%w: repeated stanza argument %q", ErrNonCanonical, args[0])
+		}
+		seen[args[0]] = true
+		switch args[0] {
+		case kdfArg:
+			if len(args) < 5 {
+				return nil, fmt.Errorf("%w: expected 4 arguments, got %d", ErrInvalidKDFParams, len(args)-1)
+			}
+			salt, params, err := parsePassphraseArgs(args[1:5])
+			if err != nil {
+				return nil, err
+			}
+			if err := approved("argon2id", "the passphrase"); err != nil {
+				return nil, err
+			}
+			if t.passphrase == "" {
+				return nil, ErrPassphraseRequired
+			}
+			passphrase := t.passphrase
+			masks = append(masks, func(size int) []byte {
+				return argon2.IDKey([]byte(passphrase), salt, params.Time, params.Memory, params.Threads, uint32(size))
+			})
+			args = args[5:]
+		case nsArg:
+			if len(args) < 2 {
+				return nil, fmt.Errorf("%w: missing namespace", ErrNamespaceMismatch)
+			}
+			namespace, err := parseNamespaceArg(args[1])
+			if err != nil {
+				return nil, err
+			}
+			if namespace != t.namespace {
+				return nil, fmt.Errorf("%w: ciphertext uses %q", ErrNamespaceMismatch, namespace)
+			}
+			namespaced = true
+			args = args[2:]
+		default:
+			return nil, fmt.Errorf("unsupported stanza argument %q", args[0])
+		}
+	}
+	if t.namespace != "" && !namespaced {
+		return nil, fmt.Errorf("%w: ciphertext has no namespace", ErrNamespaceMismatch)
+	}
+
+	return func(fileKey []byte) []byte {
+		for _, mask := range masks {
+			xorInPlace(fileKey, mask(len(fileKey)))
+		}
+		return fileKey
+	}, nil
+}
+
 func (t *Identity) String() string {
 	sb := strings.Builder{}
 
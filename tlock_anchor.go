@@ -0,0 +1,165 @@
+package tlock
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// ErrAnchorMismatch is returned when a ciphertext doesn't match an anchor.
+var ErrAnchorMismatch = errors.New("ciphertext doesn't match anchor")
+
+// anchorDomain separates the anchor commitment from other uses of SHA-256.
+const anchorDomain = "tlock anchor v1"
+
+// anchorSignature is the Solidity signature of the calldata function.
+const anchorSignature = "anchor(uint256,bytes32,bytes32)"
+
+// Anchor is a record of a ciphertext meant to be anchored in a blockchain, to
+// prove the ciphertext existed before its round. It commits to the round, the
+// chain hash and the SHA-256 digest of the binary ciphertext, so armoring
+// doesn't change it.
+type Anchor struct {
+	Round     uint64
+	ChainHash string
+	Digest    [sha256.Size]byte
+}
+
+// NewAnchor computes the anchor of the ciphertext in src, which may be binary,
+// age armored, PEM encoded or in a JSON envelope.
+func NewAnchor(src io.Reader) (Anchor, error) {
+	h := sha256.New()
+	rr := bufio.NewReader(io.TeeReader(dearmor(src), h))
+	hdr, err := ReadHeader(rr)
+	if err != nil {
+		return Anchor{}, err
+	}
+	if _, err := io.Copy(io.Discard, rr); err != nil {
+		return Anchor{}, fmt.Errorf("read payload: %w", err)
+	}
+	roundNumber, chainHash, err := hdr.Round()
+	if err != nil {
+		return Anchor{}, err
+	}
+
+	a := Anchor{Round: roundNumber, ChainHash: chainHash}
+	h.Sum(a.Digest[:0])
+	return a, nil
+}
+
+// Commitment returns the single digest committing to the anchor, which is to
+// be stamped with OpenTimestamps.
+func (a Anchor) Commitment() [sha256.Size]byte {
+	h := sha256.New()
+	h.Write([]byte(anchorDomain))
+	h.Write(binary.BigEndian.AppendUint64(nil, a.Round))
+	h.Write([]byte(a.ChainHash))
+	h.Write(a.Digest[:])
+
+	var c [sha256.Size]byte
+	h.Sum(c[:0])
+	return c
+}
+
+// Calldata returns the anchor ABI encoded as a call to
+// anchor(uint256 round, bytes32 chainHash, bytes32 digest), to be sent as the
+// data of an Ethereum transaction.
+func (a Anchor) Calldata() ([]byte, error) {
+	chainHash, err := hex.DecodeString(a.ChainHash)
+	if err != nil || len(chainHash) != 32 {
+		return nil, fmt.Errorf("invalid chain hash %q", a.ChainHash)
+	}
+
+	data := anchorSelector()
+	data = append(data, make([]byte, 24)...)
+	data = binary.BigEndian.AppendUint64(data, a.Round)
+	data = append(data, chainHash...)
+	return append(data, a.Digest[:]...), nil
+}
+
+// ParseCalldata parses the anchor from calldata produced by Calldata.
+func ParseCalldata(data []byte) (Anchor, error) {
+	if len(data) != 4+3*32 || !bytes.Equal(data[:4], anchorSelector()) {
+		return Anchor{}, errors.New("not anchor calldata")
+	}
+	if !bytes.Equal(data[4:28], make([]byte, 24)) {
+		return Anchor{}, errors.New("round out of range")
+	}
+
+	a := Anchor{
+		Round:     binary.BigEndian.Uint64(data[28:36]),
+		ChainHash: hex.EncodeToString(data[36:68]),
+	}
+	copy(a.Digest[:], data[68:])
+	return a, nil
+}
+
+// Verify checks that the ciphertext in src is the one of the anchor.
+func (a Anchor) Verify(src io.Reader) error {
+	b, err := NewAnchor(src)
+	if err != nil {
+		return err
+	}
+	if b != a {
+		return ErrAnchorMismatch
+	}
+	return nil
+}
+
+// anchorJSON is the JSON representation of an anchor. The commitment and
+// calldata are provided for convenience and ignored when parsing.
+type anchorJSON struct {
+	Round      uint64 `json:"round"`
+	ChainHash  string `json:"chain_hash"`
+	Digest     string `json:"digest"`
+	Commitment string `json:"commitment,omitempty"`
+	Calldata   string `json:"calldata,omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (a Anchor) MarshalJSON() ([]byte, error) {
+	c := a.Commitment()
+	j := anchorJSON{
+		Round:      a.Round,
+		ChainHash:  a.ChainHash,
+		Digest:     hex.EncodeToString(a.Digest[:]),
+		Commitment: hex.EncodeToString(c[:]),
+	}
+	if calldata, err := a.Calldata(); err == nil {
+		j.Calldata = "0x" + hex.EncodeToString(calldata)
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (a *Anchor) UnmarshalJSON(b []byte) error {
+	var j anchorJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	digest, err := hex.DecodeString(j.Digest)
+	if err != nil || len(digest) != sha256.Size {
+		return fmt.Errorf("invalid digest %q", j.Digest)
+	}
+
+	*a = Anchor{Round: j.Round, ChainHash: j.ChainHash}
+	copy(a.Digest[:], digest)
+	return nil
+}
+
+// =============================================================================
+
+// anchorSelector returns the function selector of the calldata.
+func anchorSelector() []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write([]byte(anchorSignature))
+	return h.Sum(nil)[:4]
+}
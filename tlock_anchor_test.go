@@ -0,0 +1,53 @@
+package tlock_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"testing"
+
+	"filippo.io/age/armor"
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnchor(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+
+	var cipherData bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&cipherData, bytes.NewReader(dataFile), 1000))
+
+	anchor, err := tlock.NewAnchor(bytes.NewReader(cipherData.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, uint64(1000), anchor.Round)
+	require.Equal(t, network.ChainHash(), anchor.ChainHash)
+	require.Equal(t, sha256.Sum256(cipherData.Bytes()), anchor.Digest)
+
+	// Armoring doesn't change the anchor.
+	var armored bytes.Buffer
+	a := armor.NewWriter(&armored)
+	_, err = a.Write(cipherData.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, a.Close())
+	require.NoError(t, anchor.Verify(&armored))
+
+	calldata, err := anchor.Calldata()
+	require.NoError(t, err)
+	require.Len(t, calldata, 100)
+	parsed, err := tlock.ParseCalldata(calldata)
+	require.NoError(t, err)
+	require.Equal(t, anchor, parsed)
+
+	b, err := json.Marshal(anchor)
+	require.NoError(t, err)
+	var decoded tlock.Anchor
+	require.NoError(t, json.Unmarshal(b, &decoded))
+	require.Equal(t, anchor, decoded)
+
+	var other bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&other, bytes.NewReader(dataFile), 1000))
+	require.ErrorIs(t, anchor.Verify(&other), tlock.ErrAnchorMismatch)
+}
@@ -0,0 +1,34 @@
+package tlock
+
+import (
+	"crypto/cipher"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// ErrNotApproved is returned in FIPS mode when an operation requires a
+// symmetric algorithm which isn't FIPS approved.
+var ErrNotApproved = errors.New("algorithm not approved in FIPS mode")
+
+// =============================================================================
+
+// approved returns ErrNotApproved in FIPS mode for the algorithms outside of
+// AES-GCM, SHA-256 and HKDF, the only ones the symmetric layer then selects.
+// The algorithm names the operation requiring it in the error.
+func approved(algorithm string, operation string) error {
+	if !FIPSMode {
+		return nil
+	}
+	return fmt.Errorf("%w: %s requires %s, build without the fips tag to use it", ErrNotApproved, operation, algorithm)
+}
+
+// newChaCha20Poly1305 returns the ChaCha20-Poly1305 AEAD sealing the age
+// payload and the other ChaCha20-Poly1305 payloads, unless in FIPS mode.
+func newChaCha20Poly1305(key []byte, operation string) (cipher.AEAD, error) {
+	if err := approved("ChaCha20-Poly1305", operation); err != nil {
+		return nil, err
+	}
+	return chacha20poly1305.New(key)
+}
@@ -0,0 +1,370 @@
+package tlock
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"filippo.io/age/armor"
+)
+
+// These constants define the layout of the age armor.
+const (
+	armorColumns      = 64
+	armorLineBytes    = armorColumns / 4 * 3
+	armorBatchLines   = 1024
+	armorMaxWhitspace = 1024
+)
+
+var armorEncoding = base64.StdEncoding.Strict()
+
+// ArmorHintPrefix starts the comment line NewHintedArmorWriter writes ahead
+// of the armor, which the readers of this package skip.
+const ArmorHintPrefix = "Unlocks: "
+
+// NewArmorWriter returns a writer producing the same output as the age armor
+// writer. Input is encoded in batches of whole lines and written with a single
+// call per batch, rather than going through a base64 encoder and a line
+// wrapper in small writes. The output is only complete after Close.
+func NewArmorWriter(dst io.Writer) io.WriteCloser {
+	return &armorWriter{dst: dst}
+}
+
+// NewHintedArmorWriter returns an armor writer preceding the armor with a
+// comment line telling recipients of pasted blocks when to try decrypting,
+// such as
+//
+//	Unlocks: 2026-01-01T00:00:00Z, chain 52db9b…
+//
+// built from the header of the ciphertext, with the round rather than the
+// time when the network can't tell it. The line isn't authenticated and other
+// age implementations need it removed.
+func NewHintedArmorWriter(dst io.Writer, network Network) io.WriteCloser {
+	return &hintedArmorWriter{dst: dst, network: network}
+}
+
+// NewArmorReader returns a reader decoding the age armor as strictly as the
+// age armor reader, decoding as many lines as fit in each read directly into
+// the caller's buffer. A leading hint line is skipped.
+func NewArmorReader(src io.Reader) io.Reader {
+	return &armorReader{r: bufio.NewReaderSize(src, armorBatchLines*(armorColumns+1))}
+}
+
+// =============================================================================
+
+type armorWriter struct {
+	dst     io.Writer
+	started bool
+	closed  bool
+	pending []byte // Input not filling a line yet.
+	out     []byte
+}
+
+func (w *armorWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("armor writer already closed")
+	}
+	if !w.started {
+		w.started = true
+		w.out = append(w.out[:0], armor.Header+"\n"...)
+	}
+
+	n := len(p)
+	if len(w.pending) > 0 {
+		c := min(armorLineBytes-len(w.pending), len(p))
+		w.pending = append(w.pending, p[:c]...)
+		p = p[c:]
+		if len(w.pending) < armorLineBytes {
+			return n, w.flush()
+		}
+		w.out = appendArmorLines(w.out, w.pending)
+		w.pending = w.pending[:0]
+	}
+
+	for len(p) >= armorLineBytes {
+		batch := min(len(p), armorBatchLines*armorLineBytes) / armorLineBytes * armorLineBytes
+		w.out = appendArmorLines(w.out, p[:batch])
+		p = p[batch:]
+		if err := w.flush(); err != nil {
+			return 0, err
+		}
+	}
+	w.pending = append(w.pending, p...)
+
+	return n, w.flush()
+}
+
+func (w *armorWriter) Close() error {
+	if w.closed {
+		return errors.New("armor writer already closed")
+	}
+	w.closed = true
+	if !w.started {
+		w.out = append(w.out[:0], armor.Header+"\n"...)
+	}
+
+	if len(w.pending) > 0 {
+		w.out = armorEncoding.AppendEncode(w.out, w.pending)
+		w.out = append(w.out, '\n')
+	}
+	w.out = append(w.out, armor.Footer+"\n"...)
+
+	return w.flush()
+}
+
+// flush writes the encoded output.
+func (w *armorWriter) flush() error {
+	if len(w.out) == 0 {
+		return nil
+	}
+	_, err := w.dst.Write(w.out)
+	w.out = w.out[:0]
+	return err
+}
+
+// appendArmorLines appends the encoding of the whole lines in p to out. The
+// lines are encoded with a single call, then spread out to insert the line
+// feeds in place.
+func appendArmorLines(out, p []byte) []byte {
+	lines := len(p) / armorLineBytes
+	start := len(out)
+	out = armorEncoding.AppendEncode(out, p)
+	out = append(out, make([]byte, lines)...)
+
+	enc := out[start:]
+	for i := lines - 1; i >= 0; i-- {
+		copy(enc[i*(armorColumns+1):], enc[i*armorColumns:(i+1)*armorColumns])
+		enc[i*(armorColumns+1)+armorColumns] = '\n'
+	}
+	return out
+}
+
+// =============================================================================
+
+// hintedArmorWriter buffers the ciphertext until its header is complete, then
+// writes the hint and streams the rest through an armor writer.
+type hintedArmorWriter struct {
+	dst     io.Writer
+	network Network
+	head    []byte
+	armor   io.WriteCloser
+}
+
+func (w *hintedArmorWriter) Write(p []byte) (int, error) {
+	if w.armor != nil {
+		return w.armor.Write(p)
+	}
+
+	// The header ends with the line of its MAC.
+	w.head = append(w.head, p...)
+	if i := bytes.Index(w.head, []byte("\n---")); i < 0 || bytes.IndexByte(w.head[i+1:], '\n') < 0 {
+		return len(p), nil
+	}
+	if err := w.start(); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *hintedArmorWriter) Close() error {
+	if w.armor == nil {
+		if err := w.start(); err != nil {
+			return err
+		}
+	}
+	return w.armor.Close()
+}
+
+// start writes the hint and the buffered ciphertext.
+func (w *hintedArmorWriter) start() error {
+	hdr, err := ReadHeader(bufio.NewReader(bytes.NewReader(w.head)))
+	if err != nil {
+		return err
+	}
+	roundNumber, chainHash, err := hdr.Round()
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w.dst, armorHint(w.network, roundNumber, chainHash)+"\n"); err != nil {
+		return err
+	}
+	w.armor = NewArmorWriter(w.dst)
+	_, err = w.armor.Write(w.head)
+	w.head = nil
+	return err
+}
+
+// armorHint returns the hint line of a ciphertext.
+func armorHint(network Network, roundNumber uint64, chainHash string) string {
+	unlock := fmt.Sprintf("round %d", roundNumber)
+	if eta, ok := RoundTime(network, roundNumber); ok {
+		unlock = eta.UTC().Format(time.RFC3339)
+	}
+	return fmt.Sprintf("%s%s, chain %s…", ArmorHintPrefix, unlock, chainHash[:min(6, len(chainHash))])
+}
+
+// =============================================================================
+
+type armorReader struct {
+	r       *bufio.Reader
+	started bool
+	unread  []byte // Backed by buf.
+	buf     [armorLineBytes]byte
+	err     error
+
+	// strict rejects what the armor writer doesn't write: leading lines,
+	// carriage returns and trailing whitespace.
+	strict bool
+}
+
+func (r *armorReader) Read(p []byte) (int, error) {
+	if len(r.unread) > 0 {
+		n := copy(p, r.unread)
+		r.unread = r.unread[n:]
+		return n, nil
+	}
+	if r.err != nil {
+		return 0, r.err
+	}
+	if !r.started {
+		if err := r.readHeader(); err != nil {
+			return 0, r.setErr(err)
+		}
+		r.started = true
+	}
+
+	var n int
+	for r.err == nil && (n == 0 || len(p)-n >= armorLineBytes) {
+		line, err := r.line()
+		if err != nil {
+			r.setErr(err)
+			break
+		}
+		if string(line) == armor.Footer {
+			r.setErr(r.drainTrailing())
+			break
+		}
+		if len(line) > armorColumns {
+			r.setErr(errors.New("column limit exceeded"))
+			break
+		}
+		if r.strict && len(line) == 0 {
+			r.setErr(fmt.Errorf("%w: empty line", ErrNonCanonical))
+			break
+		}
+
+		// Lines are decoded in place, unless the caller's buffer is too small.
+		dst := p[n:]
+		small := len(dst) < armorLineBytes
+		if small {
+			dst = r.buf[:]
+		}
+		m, err := armorEncoding.Decode(dst, line)
+		if err != nil {
+			r.setErr(err)
+			break
+		}
+		if m < armorLineBytes {
+			r.setErr(r.readFooter())
+		}
+		if small {
+			c := copy(p[n:], dst[:m])
+			r.unread = dst[c:m]
+			n += c
+			break
+		}
+		n += m
+	}
+
+	if n > 0 {
+		return n, nil
+	}
+	return 0, r.err
+}
+
+// readHeader skips leading whitespace and reads the armor header line.
+func (r *armorReader) readHeader() error {
+	var removed int
+	for {
+		line, err := r.line()
+		if err != nil {
+			return err
+		}
+		if r.strict && string(line) != armor.Header {
+			return fmt.Errorf("%w: first line %q", ErrNonCanonical, line)
+		}
+		if len(bytes.TrimSpace(line)) == 0 || bytes.HasPrefix(line, []byte(ArmorHintPrefix)) {
+			if removed += len(line) + 1; removed > armorMaxWhitspace {
+				return errors.New("too much leading whitespace")
+			}
+			continue
+		}
+		if string(line) != armor.Header {
+			return fmt.Errorf("invalid first line: %q", line)
+		}
+		return nil
+	}
+}
+
+// readFooter reads the footer following a short line.
+func (r *armorReader) readFooter() error {
+	line, err := r.line()
+	if err != nil {
+		return err
+	}
+	if string(line) != armor.Footer {
+		return fmt.Errorf("invalid closing line: %q", line)
+	}
+	return r.drainTrailing()
+}
+
+// line returns the next line without its line ending. It is only valid until
+// the next read.
+func (r *armorReader) line() ([]byte, error) {
+	line, err := r.r.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		return nil, errors.New("column limit exceeded")
+	}
+	if err == io.EOF && len(line) == 0 {
+		return nil, io.ErrUnexpectedEOF
+	} else if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if r.strict && (err == io.EOF || bytes.HasSuffix(line, []byte("\r\n"))) {
+		return nil, fmt.Errorf("%w: line ending", ErrNonCanonical)
+	}
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	return bytes.TrimSuffix(line, []byte("\r")), nil
+}
+
+// drainTrailing checks that only whitespace follows the footer.
+func (r *armorReader) drainTrailing() error {
+	buf, err := io.ReadAll(io.LimitReader(r.r, armorMaxWhitspace))
+	if err != nil {
+		return err
+	}
+	if r.strict && len(buf) != 0 {
+		return fmt.Errorf("%w: trailing data after armored file", ErrNonCanonical)
+	}
+	if len(bytes.TrimSpace(buf)) != 0 {
+		return errors.New("trailing data after armored file")
+	}
+	if len(buf) == armorMaxWhitspace {
+		return errors.New("too much trailing whitespace")
+	}
+	return io.EOF
+}
+
+// setErr records the error, wrapping it unless it is the end of the input.
+func (r *armorReader) setErr(err error) error {
+	if err != io.EOF {
+		err = fmt.Errorf("invalid armor: %w", err)
+	}
+	r.err = err
+	return err
+}
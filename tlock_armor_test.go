@@ -0,0 +1,131 @@
+package tlock_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"filippo.io/age/armor"
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArmorMatchesAge(t *testing.T) {
+	for _, size := range []int{0, 1, 47, 48, 49, 96, 1000, 100_000} {
+		data := make([]byte, size)
+		_, err := rand.Read(data)
+		require.NoError(t, err)
+
+		var want bytes.Buffer
+		a := armor.NewWriter(&want)
+		_, err = a.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, a.Close())
+
+		// Write in uneven pieces to exercise the pending line.
+		var got bytes.Buffer
+		w := tlock.NewArmorWriter(&got)
+		for rest := data; len(rest) > 0; {
+			n := min(len(rest), 37)
+			_, err := w.Write(rest[:n])
+			require.NoError(t, err)
+			rest = rest[n:]
+		}
+		require.NoError(t, w.Close())
+		require.Equal(t, want.String(), got.String(), "size %d", size)
+
+		decoded, err := io.ReadAll(tlock.NewArmorReader(&want))
+		require.NoError(t, err)
+		require.Equal(t, data, decoded)
+
+		decoded, err = io.ReadAll(iotest.OneByteReader(tlock.NewArmorReader(&got)))
+		require.NoError(t, err)
+		require.Equal(t, data, decoded)
+	}
+}
+
+func TestArmorReaderStrict(t *testing.T) {
+	valid := armor.Header + "\n" + "aGVsbG8=\n" + armor.Footer + "\n"
+	decoded, err := io.ReadAll(tlock.NewArmorReader(strings.NewReader("\n  \n" + valid + "\n")))
+	require.NoError(t, err)
+	require.Equal(t, []byte("hello"), decoded)
+
+	for name, armored := range map[string]string{
+		"trailing data":  valid + "x",
+		"missing footer": armor.Header + "\naGVsbG8=\n",
+		"bad padding":    armor.Header + "\naGVsbG8\n" + armor.Footer + "\n",
+		"long line":      armor.Header + "\n" + strings.Repeat("A", 68) + "\n" + armor.Footer + "\n",
+		"bad header":     "-----BEGIN SOMETHING-----\naGVsbG8=\n" + armor.Footer + "\n",
+		"data after end": armor.Header + "\naGVsbG8=\naGVsbG8=\n" + armor.Footer + "\n",
+	} {
+		_, err := io.ReadAll(tlock.NewArmorReader(strings.NewReader(armored)))
+		require.Error(t, err, name)
+
+		_, err = io.ReadAll(armor.NewReader(strings.NewReader(armored)))
+		require.Error(t, err, name)
+	}
+}
+
+func BenchmarkArmor(b *testing.B) {
+	data := make([]byte, 1<<20)
+	var armored bytes.Buffer
+	w := armor.NewWriter(&armored)
+	_, _ = w.Write(data)
+	_ = w.Close()
+
+	for name, newWriter := range map[string]func(io.Writer) io.WriteCloser{
+		"age":   armor.NewWriter,
+		"tlock": tlock.NewArmorWriter,
+	} {
+		b.Run("encode/"+name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				w := newWriter(io.Discard)
+				_, _ = w.Write(data)
+				_ = w.Close()
+			}
+		})
+	}
+
+	for name, newReader := range map[string]func(io.Reader) io.Reader{
+		"age":   armor.NewReader,
+		"tlock": tlock.NewArmorReader,
+	} {
+		b.Run("decode/"+name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				_, _ = io.Copy(io.Discard, newReader(bytes.NewReader(armored.Bytes())))
+			}
+		})
+	}
+}
+
+func TestHintedArmor(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+
+	var armored bytes.Buffer
+	w := tlock.NewHintedArmorWriter(&armored, network)
+	require.NoError(t, tlock.New(network).Encrypt(w, bytes.NewReader(loremBytes), 1000))
+	require.NoError(t, w.Close())
+
+	hint, rest, _ := strings.Cut(armored.String(), "\n")
+	require.True(t, strings.HasPrefix(hint, tlock.ArmorHintPrefix), hint)
+	require.True(t, strings.HasSuffix(hint, ", chain "+network.ChainHash()[:6]+"…"), hint)
+	require.True(t, strings.HasPrefix(rest, armor.Header))
+
+	var plainData bytes.Buffer
+	require.NoError(t, tlock.New(network).Decrypt(&plainData, bytes.NewReader(armored.Bytes())))
+	require.Equal(t, loremBytes, plainData.Bytes())
+
+	binary, err := io.ReadAll(tlock.NewArmorReader(bytes.NewReader(armored.Bytes())))
+	require.NoError(t, err)
+	plainData.Reset()
+	require.NoError(t, tlock.New(network).Decrypt(&plainData, bytes.NewReader(binary)))
+	require.Equal(t, loremBytes, plainData.Bytes())
+}
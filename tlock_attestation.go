@@ -0,0 +1,181 @@
+package tlock
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ErrAttestationMismatch is returned when a ciphertext or its plaintext
+// doesn't match an attestation.
+var ErrAttestationMismatch = errors.New("ciphertext doesn't match attestation")
+
+// ErrMalformedAttestation is returned when an attestation can't be parsed.
+var ErrMalformedAttestation = errors.New("malformed attestation")
+
+// These constants identify the in-toto statement and its predicate.
+const (
+	InTotoStatementType      = "https://in-toto.io/Statement/v1"
+	AttestationPredicateType = "https://github.com/JonathanLogan/tlock/attestation/v1"
+)
+
+// Attestation binds a release artifact to its timelocked ciphertext, the
+// round and chain it is locked to and the time it was locked at. It is
+// encoded as an in-toto statement whose subject is the artifact, ready to be
+// signed with the tools of the supply chain, such as cosign attest-blob.
+//
+// The digest of the artifact is public, hence the attestation lets anyone
+// confirm a guess of the artifact before the round is reached.
+type Attestation struct {
+	Name       string
+	Digest     [sha256.Size]byte // Of the plaintext artifact.
+	Ciphertext [sha256.Size]byte // Of the binary ciphertext.
+	Round      uint64
+	ChainHash  string
+	Created    time.Time
+}
+
+// EncryptAttested encrypts src like Encrypt and returns the attestation of
+// the artifact of the given name.
+func (t Tlock) EncryptAttested(dst io.Writer, src io.Reader, roundNumber uint64, name string) (Attestation, error) {
+	a := Attestation{
+		Name:      name,
+		Round:     roundNumber,
+		ChainHash: t.network.ChainHash(),
+		Created:   time.Now().UTC().Truncate(time.Second),
+	}
+
+	plaintext, ciphertext := sha256.New(), sha256.New()
+	if err := t.Encrypt(io.MultiWriter(dst, ciphertext), io.TeeReader(src, plaintext), roundNumber); err != nil {
+		return Attestation{}, err
+	}
+	plaintext.Sum(a.Digest[:0])
+	ciphertext.Sum(a.Ciphertext[:0])
+
+	return a, nil
+}
+
+// DecryptAttested decrypts src like Decrypt and checks the ciphertext and the
+// plaintext against the attestation. The round and chain hash are checked
+// before decrypting, the digests once everything is written to dst, which
+// must be discarded on ErrAttestationMismatch.
+func (t Tlock) DecryptAttested(dst io.Writer, src io.Reader, a Attestation) error {
+	ciphertext := sha256.New()
+	bin := io.TeeReader(dearmor(src), ciphertext)
+
+	// The header is parsed from a copy of what is read, which is then
+	// replayed ahead of the rest of the ciphertext.
+	var head bytes.Buffer
+	hdr, err := ReadHeader(bufio.NewReader(io.TeeReader(bin, &head)))
+	if err != nil {
+		return err
+	}
+	roundNumber, chainHash, err := hdr.Round()
+	if err != nil {
+		return err
+	}
+	if roundNumber != a.Round || chainHash != a.ChainHash {
+		return fmt.Errorf("%w: locked to round %d of chain %s, not round %d of chain %s", ErrAttestationMismatch, roundNumber, chainHash, a.Round, a.ChainHash)
+	}
+
+	plaintext := sha256.New()
+	rr := io.MultiReader(&head, bin)
+	if err := t.Decrypt(io.MultiWriter(dst, plaintext), rr); err != nil {
+		return err
+	}
+	if _, err := io.Copy(io.Discard, rr); err != nil {
+		return err
+	}
+
+	if !bytes.Equal(ciphertext.Sum(nil), a.Ciphertext[:]) {
+		return fmt.Errorf("%w: ciphertext digest", ErrAttestationMismatch)
+	}
+	if !bytes.Equal(plaintext.Sum(nil), a.Digest[:]) {
+		return fmt.Errorf("%w: artifact digest", ErrAttestationMismatch)
+	}
+	return nil
+}
+
+// inTotoStatement is the in-toto statement of an attestation.
+type inTotoStatement struct {
+	Type          string               `json:"_type"`
+	Subject       []inTotoSubject      `json:"subject"`
+	PredicateType string               `json:"predicateType"`
+	Predicate     attestationPredicate `json:"predicate"`
+}
+
+type inTotoSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+type attestationPredicate struct {
+	Ciphertext inTotoSubject `json:"ciphertext"`
+	Round      uint64        `json:"round"`
+	ChainHash  string        `json:"chainHash"`
+	Created    time.Time     `json:"created"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (a Attestation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(inTotoStatement{
+		Type: InTotoStatementType,
+		Subject: []inTotoSubject{{
+			Name:   a.Name,
+			Digest: map[string]string{"sha256": hex.EncodeToString(a.Digest[:])},
+		}},
+		PredicateType: AttestationPredicateType,
+		Predicate: attestationPredicate{
+			Ciphertext: inTotoSubject{
+				Name:   a.Name + ".tle",
+				Digest: map[string]string{"sha256": hex.EncodeToString(a.Ciphertext[:])},
+			},
+			Round:     a.Round,
+			ChainHash: a.ChainHash,
+			Created:   a.Created,
+		},
+	})
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (a *Attestation) UnmarshalJSON(b []byte) error {
+	var s inTotoStatement
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("%w: %w", ErrMalformedAttestation, err)
+	}
+	if s.Type != InTotoStatementType || s.PredicateType != AttestationPredicateType {
+		return fmt.Errorf("%w: unsupported statement %q with predicate %q", ErrMalformedAttestation, s.Type, s.PredicateType)
+	}
+	if len(s.Subject) != 1 {
+		return fmt.Errorf("%w: expected 1 subject, got %d", ErrMalformedAttestation, len(s.Subject))
+	}
+
+	*a = Attestation{
+		Name:      s.Subject[0].Name,
+		Round:     s.Predicate.Round,
+		ChainHash: s.Predicate.ChainHash,
+		Created:   s.Predicate.Created,
+	}
+	if err := decodeSHA256(a.Digest[:], s.Subject[0].Digest); err != nil {
+		return err
+	}
+	return decodeSHA256(a.Ciphertext[:], s.Predicate.Ciphertext.Digest)
+}
+
+// =============================================================================
+
+// decodeSHA256 decodes the sha256 entry of an in-toto digest set into dst.
+func decodeSHA256(dst []byte, digests map[string]string) error {
+	digest, err := hex.DecodeString(digests["sha256"])
+	if err != nil || len(digest) != sha256.Size {
+		return fmt.Errorf("%w: invalid sha256 digest %q", ErrMalformedAttestation, digests["sha256"])
+	}
+	copy(dst, digest)
+	return nil
+}
@@ -0,0 +1,65 @@
+package tlock_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttestation(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+	artifact := []byte("release 1.0")
+
+	var cipherData bytes.Buffer
+	a, err := tlock.New(network).EncryptAttested(&cipherData, bytes.NewReader(artifact), 1000, "release.tar.gz")
+	require.NoError(t, err)
+	require.Equal(t, network.ChainHash(), a.ChainHash)
+	require.False(t, a.Created.IsZero())
+
+	b, err := json.Marshal(a)
+	require.NoError(t, err)
+	require.Contains(t, string(b), `"_type":"https://in-toto.io/Statement/v1"`)
+	require.Contains(t, string(b), `"name":"release.tar.gz"`)
+
+	var parsed tlock.Attestation
+	require.NoError(t, json.Unmarshal(b, &parsed))
+	require.Equal(t, a, parsed)
+
+	var out bytes.Buffer
+	require.NoError(t, tlock.New(network).DecryptAttested(&out, bytes.NewReader(cipherData.Bytes()), parsed))
+	require.Equal(t, artifact, out.Bytes())
+
+	// The armored ciphertext has the same attestation.
+	var armored bytes.Buffer
+	w := tlock.NewArmorWriter(&armored)
+	_, err = w.Write(cipherData.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, tlock.New(network).DecryptAttested(&out, &armored, parsed))
+
+	// Another ciphertext of the same artifact doesn't match.
+	var other bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&other, bytes.NewReader(artifact), 1000))
+	err = tlock.New(network).DecryptAttested(&out, &other, parsed)
+	require.ErrorIs(t, err, tlock.ErrAttestationMismatch)
+
+	wrongRound := parsed
+	wrongRound.Round = 999
+	err = tlock.New(network).DecryptAttested(&out, bytes.NewReader(cipherData.Bytes()), wrongRound)
+	require.ErrorIs(t, err, tlock.ErrAttestationMismatch)
+
+	wrongDigest := parsed
+	wrongDigest.Digest[0] ^= 1
+	err = tlock.New(network).DecryptAttested(&out, bytes.NewReader(cipherData.Bytes()), wrongDigest)
+	require.ErrorIs(t, err, tlock.ErrAttestationMismatch)
+
+	err = json.Unmarshal([]byte(strings.Replace(string(b), "Statement/v1", "Statement/v0.1", 1)), &parsed)
+	require.ErrorIs(t, err, tlock.ErrMalformedAttestation)
+}
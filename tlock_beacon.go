@@ -0,0 +1,94 @@
+package tlock
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	chain "github.com/drand/drand/v2/common"
+	"github.com/drand/drand/v2/crypto"
+	"github.com/drand/kyber/encrypt/ibe"
+)
+
+// ErrBeaconMismatch is returned when a beacon is used for a ciphertext of
+// another round or chain.
+var ErrBeaconMismatch = errors.New("beacon doesn't match the ciphertext")
+
+// VerifiedBeacon is the signature of a round, verified against the public key
+// of the chain when obtained. It serializes to JSON, letting callers persist
+// the beacons of past rounds and unwrap keys without the network.
+type VerifiedBeacon struct {
+	Round     uint64 `json:"round"`
+	ChainHash string `json:"chain_hash"`
+	Signature []byte `json:"signature"`
+}
+
+// ObtainVerifiedBeacon retrieves the signature of the round from the network
+// and verifies it, so that any number of keys wrapped towards the round can be
+// unwrapped with UnwrapWithBeacon. The round must have been reached by the
+// network.
+func (t Tlock) ObtainVerifiedBeacon(roundNumber uint64) (VerifiedBeacon, error) {
+	signature, err := t.network.Signature(roundNumber)
+	if err != nil {
+		return VerifiedBeacon{}, fmt.Errorf(
+			"%w: expected round %d > %d current round",
+			ErrTooEarly,
+			roundNumber,
+			t.network.Current(time.Now()))
+	}
+
+	b := VerifiedBeacon{
+		Round:     roundNumber,
+		ChainHash: t.network.ChainHash(),
+		Signature: signature,
+	}
+	if err := b.Verify(t.network); err != nil {
+		return VerifiedBeacon{}, err
+	}
+	return b, nil
+}
+
+// Verify verifies the signature of the beacon against the public key of the
+// network, as beacons read back from untrusted storage should be.
+func (b VerifiedBeacon) Verify(network Network) error {
+	if b.ChainHash != network.ChainHash() {
+		return fmt.Errorf("%w: beacon of chain %s, network is %s", ErrBeaconMismatch, b.ChainHash, network.ChainHash())
+	}
+	scheme := network.Scheme()
+	beacon := chain.Beacon{Round: b.Round, Signature: b.Signature}
+	if err := scheme.VerifyBeacon(&beacon, network.PublicKey()); err != nil {
+		return fmt.Errorf("verify beacon: %w", err)
+	}
+	return nil
+}
+
+// UnwrapWithBeacon decrypts the data encryption key with the beacon of its
+// round, without calling the network.
+func (t Tlock) UnwrapWithBeacon(w WrappedDEK, b VerifiedBeacon) ([]byte, error) {
+	if w.Round != b.Round || w.ChainHash != b.ChainHash {
+		return nil, fmt.Errorf("%w: key of round %d of chain %s, beacon of round %d of chain %s", ErrBeaconMismatch, w.Round, w.ChainHash, b.Round, b.ChainHash)
+	}
+
+	ciphertext, err := bytesToDirectCiphertext(t.network.Scheme(), w.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("parse wrapped dek: %w", err)
+	}
+
+	return b.decrypt(t.network.Scheme(), ciphertext)
+}
+
+// =============================================================================
+
+// decrypt decrypts the ciphertext with the signature of the beacon.
+func (b VerifiedBeacon) decrypt(scheme crypto.Scheme, ciphertext *ibe.Ciphertext) ([]byte, error) {
+	suite, err := suiteFor(scheme.Name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := suite.Decrypt(b.Signature, ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt dek: %w", err)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,202 @@
+package tlock
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+
+	chain "github.com/drand/drand/v2/common"
+	dchain "github.com/drand/drand/v2/common/chain"
+	"github.com/drand/drand/v2/crypto"
+)
+
+// BeaconBundleFormat identifies the version of the format of beacon bundles.
+const BeaconBundleFormat = "tlock-beacon/v1"
+
+// ErrMalformedBeaconBundle is returned when reading a beacon bundle which
+// isn't valid: of another format, with chain information not hashing to its
+// chain hash, or with a beacon whose signature doesn't verify.
+var ErrMalformedBeaconBundle = errors.New("malformed beacon bundle")
+
+// ErrBeaconNotInBundle is returned when a beacon bundle lacks the beacon of a
+// round.
+var ErrBeaconNotInBundle = errors.New("beacon not in bundle")
+
+// BeaconBundle holds verified beacons of a chain along with its chain
+// information, so that the ciphertexts locked to their rounds decrypt without
+// calling the network. It is the content of the .beacon files, JSON objects
+// such as:
+//
+//	{
+//	  "format": "tlock-beacon/v1",
+//	  "chain_hash": "52db9ba7...",
+//	  "info": {"public_key": "83cf0f28...", "period": 3, ...},
+//	  "beacons": [{"round": 1000, "signature": "b44679b9..."}]
+//	}
+//
+// where the info is the chain information as served by drand relays, and the
+// signatures are hex encoded. A bundle is a Transport, turned into the
+// network of its chain by NewTransportNetwork.
+type BeaconBundle struct {
+	info    *dchain.Info
+	scheme  crypto.Scheme
+	beacons map[uint64][]byte
+}
+
+// NewBeaconBundle constructs an empty bundle for the chain of the information.
+func NewBeaconBundle(info *dchain.Info) (*BeaconBundle, error) {
+	if _, err := suiteFor(info.Scheme); err != nil {
+		return nil, err
+	}
+	sch, err := crypto.SchemeFromName(info.Scheme)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotUnchained, err)
+	}
+
+	return &BeaconBundle{
+		info:    info,
+		scheme:  *sch,
+		beacons: make(map[uint64][]byte),
+	}, nil
+}
+
+// ReadBeaconBundle reads a bundle written by Write, verifying that its chain
+// information hashes to its chain hash and that the signatures of all its
+// beacons verify against the public key of the chain. Bundles with unknown
+// fields, duplicate rounds or trailing data are rejected as malformed.
+func ReadBeaconBundle(r io.Reader) (*BeaconBundle, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var f beaconBundleFile
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedBeaconBundle, err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("%w: trailing data", ErrMalformedBeaconBundle)
+	}
+	if f.Format != BeaconBundleFormat {
+		return nil, fmt.Errorf("%w: format %q", ErrMalformedBeaconBundle, f.Format)
+	}
+
+	info, err := dchain.InfoFromJSON(bytes.NewReader(f.Info))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedBeaconBundle, err)
+	}
+	if info.HashString() != f.ChainHash {
+		return nil, fmt.Errorf("%w: chain information doesn't hash to chain %s", ErrMalformedBeaconBundle, f.ChainHash)
+	}
+	b, err := NewBeaconBundle(info)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, fb := range f.Beacons {
+		if _, ok := b.beacons[fb.Round]; ok {
+			return nil, fmt.Errorf("%w: duplicate round %d", ErrMalformedBeaconBundle, fb.Round)
+		}
+		signature, err := hex.DecodeString(fb.Signature)
+		if err != nil {
+			return nil, fmt.Errorf("%w: signature of round %d: %v", ErrMalformedBeaconBundle, fb.Round, err)
+		}
+		if err := b.Add(VerifiedBeacon{Round: fb.Round, ChainHash: f.ChainHash, Signature: signature}); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrMalformedBeaconBundle, err)
+		}
+	}
+
+	return b, nil
+}
+
+// ChainHash returns the chain hash of the chain of the bundle.
+func (b *BeaconBundle) ChainHash() string {
+	return b.info.HashString()
+}
+
+// Add verifies the beacon against the chain of the bundle and adds it,
+// replacing the one of the same round.
+func (b *BeaconBundle) Add(beacon VerifiedBeacon) error {
+	if beacon.ChainHash != b.ChainHash() {
+		return fmt.Errorf("%w: beacon of chain %s, bundle of chain %s", ErrBeaconMismatch, beacon.ChainHash, b.ChainHash())
+	}
+	cb := chain.Beacon{Round: beacon.Round, Signature: beacon.Signature}
+	if err := b.scheme.VerifyBeacon(&cb, b.info.PublicKey); err != nil {
+		return fmt.Errorf("verify beacon of round %d: %w", beacon.Round, err)
+	}
+	b.beacons[beacon.Round] = beacon.Signature
+	return nil
+}
+
+// Beacons returns the beacons of the bundle ordered by round.
+func (b *BeaconBundle) Beacons() []VerifiedBeacon {
+	rounds := slices.Sorted(maps.Keys(b.beacons))
+	beacons := make([]VerifiedBeacon, len(rounds))
+	for i, roundNumber := range rounds {
+		beacons[i] = VerifiedBeacon{Round: roundNumber, ChainHash: b.ChainHash(), Signature: b.beacons[roundNumber]}
+	}
+	return beacons
+}
+
+// Write writes the bundle in the format ReadBeaconBundle reads.
+func (b *BeaconBundle) Write(w io.Writer) error {
+	var info bytes.Buffer
+	if err := b.info.ToJSON(&info, nil); err != nil {
+		return fmt.Errorf("marshal chain info: %w", err)
+	}
+
+	f := beaconBundleFile{
+		Format:    BeaconBundleFormat,
+		ChainHash: b.ChainHash(),
+		Info:      bytes.TrimSpace(info.Bytes()),
+		Beacons:   []beaconBundleEntry{},
+	}
+	for _, beacon := range b.Beacons() {
+		f.Beacons = append(f.Beacons, beaconBundleEntry{Round: beacon.Round, Signature: hex.EncodeToString(beacon.Signature)})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(f)
+}
+
+// Latest returns the beacon of the latest round of the bundle.
+func (b *BeaconBundle) Latest(ctx context.Context) (*chain.Beacon, error) {
+	if len(b.beacons) == 0 {
+		return nil, fmt.Errorf("%w: empty bundle", ErrBeaconNotInBundle)
+	}
+	return b.Get(ctx, slices.Max(slices.Collect(maps.Keys(b.beacons))))
+}
+
+// Get returns the beacon of the round from the bundle.
+func (b *BeaconBundle) Get(_ context.Context, roundNumber uint64) (*chain.Beacon, error) {
+	signature, ok := b.beacons[roundNumber]
+	if !ok {
+		return nil, fmt.Errorf("%w: round %d", ErrBeaconNotInBundle, roundNumber)
+	}
+	return &chain.Beacon{Round: roundNumber, Signature: signature}, nil
+}
+
+// ChainInfo returns the chain information of the bundle.
+func (b *BeaconBundle) ChainInfo(_ context.Context) (*dchain.Info, error) {
+	return b.info, nil
+}
+
+// =============================================================================
+
+// beaconBundleFile is the JSON encoding of a beacon bundle.
+type beaconBundleFile struct {
+	Format    string              `json:"format"`
+	ChainHash string              `json:"chain_hash"`
+	Info      json.RawMessage     `json:"info"`
+	Beacons   []beaconBundleEntry `json:"beacons"`
+}
+
+// beaconBundleEntry is the JSON encoding of a beacon of a bundle.
+type beaconBundleEntry struct {
+	Round     uint64 `json:"round"`
+	Signature string `json:"signature"`
+}
@@ -0,0 +1,68 @@
+package tlock_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBeaconBundle(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	key := fixedtest.NewKey(nil)
+	key.Genesis = time.Now().Add(-300 * time.Second)
+	info := key.Info()
+
+	bundle := key.Bundle(t, 50, 20)
+	forged := key.Beacon(t, 30)
+	forged.Signature = key.SignRound(t, 31)
+	require.Error(t, bundle.Add(forged))
+
+	var file bytes.Buffer
+	require.NoError(t, bundle.Write(&file))
+	read, err := tlock.ReadBeaconBundle(bytes.NewReader(file.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, bundle.Beacons(), read.Beacons())
+	require.Equal(t, []uint64{20, 50}, []uint64{read.Beacons()[0].Round, read.Beacons()[1].Round})
+
+	// The bundle decrypts the ciphertexts of its rounds offline.
+	network, err := tlock.NewTransportNetwork(context.Background(), read)
+	require.NoError(t, err)
+	require.Equal(t, info.HashString(), network.ChainHash())
+	for roundNumber, ok := range map[uint64]bool{50: true, 40: false} {
+		var cipherData bytes.Buffer
+		require.NoError(t, tlock.New(network).Encrypt(&cipherData, bytes.NewReader(loremBytes), roundNumber))
+		var plainData bytes.Buffer
+		err := tlock.New(network).Decrypt(&plainData, &cipherData)
+		if ok {
+			require.NoError(t, err)
+			require.Equal(t, loremBytes, plainData.Bytes())
+		} else {
+			require.ErrorIs(t, err, tlock.ErrTooEarly)
+		}
+	}
+
+	// Bundles are checked strictly when read.
+	signature20 := bundle.Beacons()[0].Signature
+	signature50 := bundle.Beacons()[1].Signature
+	for name, tamper := range map[string]func(string) string{
+		"format":     func(s string) string { return strings.Replace(s, tlock.BeaconBundleFormat, "tlock-beacon/v2", 1) },
+		"chain hash": func(s string) string { return strings.Replace(s, `"chain_hash": "`, `"chain_hash": "00`, 1) },
+		"signature": func(s string) string {
+			return strings.Replace(s, hex.EncodeToString(signature20), hex.EncodeToString(signature50), 1)
+		},
+		"duplicate": func(s string) string { return strings.Replace(s, `"round": 50`, `"round": 20`, 1) },
+		"unknown":   func(s string) string { return strings.Replace(s, `"format"`, `"extra": 1, "format"`, 1) },
+		"trailing":  func(s string) string { return s + "{}" },
+	} {
+		_, err := tlock.ReadBeaconBundle(strings.NewReader(tamper(file.String())))
+		require.ErrorIs(t, err, tlock.ErrMalformedBeaconBundle, name)
+	}
+}
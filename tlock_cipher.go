@@ -0,0 +1,104 @@
+package tlock
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// ErrUnknownCipher is returned when a payload records a cipher which isn't
+// registered.
+var ErrUnknownCipher = errors.New("unknown cipher")
+
+// CipherKeySize is the size of the keys the registered ciphers are given.
+const CipherKeySize = 32
+
+// These are the IDs of the ciphers registered by default. IDs from 0x80 are
+// left for third parties.
+const (
+	CipherChaCha20Poly1305 byte = 0x01
+	CipherAES256GCM        byte = 0x02
+)
+
+// NewAEAD returns the AEAD of a cipher keyed with a CipherKeySize bytes key.
+type NewAEAD func(key []byte) (cipher.AEAD, error)
+
+var (
+	ciphersMu sync.RWMutex
+	ciphers   = map[byte]NewAEAD{
+		CipherChaCha20Poly1305: chacha20poly1305.New,
+		CipherAES256GCM:        newAESGCM,
+	}
+)
+
+// RegisterCipher registers the AEAD implementation recorded with the given ID
+// in the payloads encrypting with it, such as messages. Decryption looks the
+// cipher up by the ID, so payloads sealed with a third party cipher open
+// wherever it is registered. The age payload of ciphertexts isn't affected,
+// age fixing its cipher. It panics if the ID is below 0x80, which are
+// reserved for tlock, if it is already registered or if newAEAD is nil.
+func RegisterCipher(id byte, newAEAD NewAEAD) {
+	ciphersMu.Lock()
+	defer ciphersMu.Unlock()
+	if id < 0x80 {
+		panic(fmt.Sprintf("tlock: RegisterCipher of reserved cipher 0x%02x", id))
+	}
+	if newAEAD == nil {
+		panic("tlock: RegisterCipher is nil")
+	}
+	if _, dup := ciphers[id]; dup {
+		panic(fmt.Sprintf("tlock: RegisterCipher called twice for cipher 0x%02x", id))
+	}
+	ciphers[id] = newAEAD
+}
+
+// WithCipher returns a tlock sealing payloads with the cipher registered with
+// the given ID rather than ChaCha20-Poly1305, or AES-256-GCM in FIPS mode.
+func (t Tlock) WithCipher(id byte) Tlock {
+	t.cipher = id
+	return t
+}
+
+// =============================================================================
+
+// cipherID returns the ID of the cipher the tlock seals with, AES-256-GCM by
+// default in FIPS mode.
+func (t Tlock) cipherID() byte {
+	if t.cipher == 0 && FIPSMode {
+		return CipherAES256GCM
+	}
+	if t.cipher == 0 {
+		return CipherChaCha20Poly1305
+	}
+	return t.cipher
+}
+
+// newCipher returns the AEAD of the cipher registered with the ID. Only
+// AES-256-GCM is approved in FIPS mode.
+func newCipher(id byte, key []byte) (cipher.AEAD, error) {
+	if id != CipherAES256GCM {
+		if err := approved(fmt.Sprintf("cipher 0x%02x", id), "the payload"); err != nil {
+			return nil, err
+		}
+	}
+	ciphersMu.RLock()
+	newAEAD, ok := ciphers[id]
+	ciphersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: 0x%02x", ErrUnknownCipher, id)
+	}
+	return newAEAD(key)
+}
+
+// newAESGCM returns the AES-256-GCM AEAD.
+func newAESGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,237 @@
+package tlock
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+
+	"filippo.io/age"
+)
+
+// ErrMalformedCMS is returned when a CMS structure can't be converted back
+// into a tlock ciphertext.
+var ErrMalformedCMS = errors.New("malformed tlock cms structure")
+
+// These object identifiers are defined by RFC 5652.
+var (
+	oidData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidEnvelopedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 3}
+)
+
+// These object identifiers live under the UUID arc 2.25 and identify the
+// tlock specific parts of the structure. They are kept in their DER encoding
+// since their arcs don't fit into an asn1.ObjectIdentifier.
+var (
+	// oidTlockStanza identifies an OtherRecipientInfo carrying an age stanza.
+	oidTlockStanza = []byte{0x69, 0x82, 0xad, 0x93, 0x80, 0x9f, 0xb5, 0xf2, 0xb2, 0x9b, 0xed, 0xbc, 0xdb, 0xb1, 0xf8, 0xb8, 0xe5, 0xfd, 0xd0, 0x07, 0x01}
+	// oidTlockPayload identifies the age payload encryption, the header MAC
+	// being its parameter.
+	oidTlockPayload = []byte{0x69, 0x82, 0xad, 0x93, 0x80, 0x9f, 0xb5, 0xf2, 0xb2, 0x9b, 0xed, 0xbc, 0xdb, 0xb1, 0xf8, 0xb8, 0xe5, 0xfd, 0xd0, 0x07, 0x02}
+)
+
+// cmsEnvelopedDataVersion is the version required by RFC 5652 when
+// OtherRecipientInfo structures are present.
+const cmsEnvelopedDataVersion = 3
+
+// oriTag is the context specific tag of the ori RecipientInfo choice.
+const oriTag = 4
+
+type cmsContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,tag:0"`
+}
+
+type cmsEnvelopedData struct {
+	Version              int
+	RecipientInfos       []asn1.RawValue `asn1:"set"`
+	EncryptedContentInfo cmsEncryptedContentInfo
+}
+
+type cmsOtherRecipientInfo struct {
+	OriType  asn1.RawValue
+	OriValue cmsStanza
+}
+
+// cmsStanza is an age stanza. DER sorts the elements of the RecipientInfos
+// SET OF, so each stanza records its position in the header, which the header
+// MAC covers. The first one omits it.
+type cmsStanza struct {
+	Type  string
+	Args  []string
+	Body  []byte
+	Index int `asn1:"optional"`
+}
+
+type cmsEncryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm cmsAlgorithmIdentifier
+	EncryptedContent           []byte `asn1:"tag:0"`
+}
+
+type cmsAlgorithmIdentifier struct {
+	Algorithm  asn1.RawValue
+	Parameters cmsPayloadParameters
+}
+
+type cmsPayloadParameters struct {
+	HeaderMAC []byte
+}
+
+// =============================================================================
+
+// NewCMSWriter returns a writer converting the binary ciphertext written to it
+// into a DER encoded CMS (RFC 5652) ContentInfo holding an EnvelopedData. Each
+// stanza of the age header, including the tlock one, is carried as an
+// OtherRecipientInfo and the age payload is the encrypted content. DER needs
+// all lengths up front, so the ciphertext is buffered and converted on Close.
+func NewCMSWriter(dst io.Writer) io.WriteCloser {
+	return &cmsWriter{dst: dst}
+}
+
+// NewCMSReader returns a reader converting the CMS structure produced by
+// NewCMSWriter back into the binary ciphertext.
+func NewCMSReader(src io.Reader) io.Reader {
+	return &cmsReader{src: src}
+}
+
+type cmsWriter struct {
+	dst io.Writer
+	buf bytes.Buffer
+}
+
+func (w *cmsWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func (w *cmsWriter) Close() error {
+	rr := bufio.NewReader(&w.buf)
+	hdr, err := ReadHeader(rr)
+	if err != nil {
+		return err
+	}
+	payload, err := io.ReadAll(rr)
+	if err != nil {
+		return err
+	}
+
+	der, err := marshalCMS(hdr, payload)
+	if err != nil {
+		return fmt.Errorf("marshal cms: %w", err)
+	}
+
+	_, err = w.dst.Write(der)
+	return err
+}
+
+type cmsReader struct {
+	src io.Reader
+	r   io.Reader
+}
+
+func (r *cmsReader) Read(p []byte) (int, error) {
+	if r.r == nil {
+		der, err := io.ReadAll(r.src)
+		if err != nil {
+			return 0, err
+		}
+		ciphertext, err := unmarshalCMS(der)
+		if err != nil {
+			return 0, err
+		}
+		r.r = bytes.NewReader(ciphertext)
+	}
+	return r.r.Read(p)
+}
+
+// =============================================================================
+
+// marshalCMS encodes the header and payload of a ciphertext as ContentInfo.
+func marshalCMS(hdr *Header, payload []byte) ([]byte, error) {
+	recipients := make([]asn1.RawValue, 0, len(hdr.Stanzas))
+	for i, s := range hdr.Stanzas {
+		ori := cmsOtherRecipientInfo{
+			OriType:  asn1.RawValue{Tag: asn1.TagOID, Bytes: oidTlockStanza},
+			OriValue: cmsStanza{Type: s.Type, Args: s.Args, Body: s.Body, Index: i},
+		}
+		b, err := asn1.MarshalWithParams(ori, fmt.Sprintf("tag:%d", oriTag))
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, asn1.RawValue{FullBytes: b})
+	}
+
+	enveloped, err := asn1.Marshal(cmsEnvelopedData{
+		Version:        cmsEnvelopedDataVersion,
+		RecipientInfos: recipients,
+		EncryptedContentInfo: cmsEncryptedContentInfo{
+			ContentType: oidData,
+			ContentEncryptionAlgorithm: cmsAlgorithmIdentifier{
+				Algorithm:  asn1.RawValue{Tag: asn1.TagOID, Bytes: oidTlockPayload},
+				Parameters: cmsPayloadParameters{HeaderMAC: hdr.MAC},
+			},
+			EncryptedContent: payload,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The explicit tag is spelled out, as it isn't applied to raw values.
+	return asn1.Marshal(struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue
+	}{
+		ContentType: oidEnvelopedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: enveloped},
+	})
+}
+
+// unmarshalCMS decodes a ContentInfo back into a binary ciphertext.
+func unmarshalCMS(der []byte) ([]byte, error) {
+	var ci cmsContentInfo
+	if rest, err := asn1.Unmarshal(der, &ci); err != nil || len(rest) != 0 {
+		return nil, fmt.Errorf("%w: content info", ErrMalformedCMS)
+	}
+	if !ci.ContentType.Equal(oidEnvelopedData) {
+		return nil, fmt.Errorf("%w: unexpected content type %s", ErrMalformedCMS, ci.ContentType)
+	}
+
+	var ed cmsEnvelopedData
+	if rest, err := asn1.Unmarshal(ci.Content.Bytes, &ed); err != nil || len(rest) != 0 {
+		return nil, fmt.Errorf("%w: enveloped data", ErrMalformedCMS)
+	}
+	eci := ed.EncryptedContentInfo
+	if ed.Version != cmsEnvelopedDataVersion || !bytes.Equal(eci.ContentEncryptionAlgorithm.Algorithm.Bytes, oidTlockPayload) {
+		return nil, fmt.Errorf("%w: not a tlock enveloped data", ErrMalformedCMS)
+	}
+
+	hdr := Header{
+		Stanzas: make([]*age.Stanza, len(ed.RecipientInfos)),
+		MAC:     eci.ContentEncryptionAlgorithm.Parameters.HeaderMAC,
+	}
+	for _, rv := range ed.RecipientInfos {
+		var ori cmsOtherRecipientInfo
+		if rest, err := asn1.UnmarshalWithParams(rv.FullBytes, &ori, fmt.Sprintf("tag:%d", oriTag)); err != nil || len(rest) != 0 {
+			return nil, fmt.Errorf("%w: recipient info", ErrMalformedCMS)
+		}
+		if !bytes.Equal(ori.OriType.Bytes, oidTlockStanza) {
+			return nil, fmt.Errorf("%w: unsupported recipient info", ErrMalformedCMS)
+		}
+		i := ori.OriValue.Index
+		if i < 0 || i >= len(hdr.Stanzas) || hdr.Stanzas[i] != nil {
+			return nil, fmt.Errorf("%w: invalid stanza index %d", ErrMalformedCMS, i)
+		}
+		hdr.Stanzas[i] = &age.Stanza{Type: ori.OriValue.Type, Args: ori.OriValue.Args, Body: ori.OriValue.Body}
+	}
+
+	var buf bytes.Buffer
+	if err := hdr.Marshal(&buf); err != nil {
+		return nil, err
+	}
+	buf.Write(eci.EncryptedContent)
+
+	return buf.Bytes(), nil
+}
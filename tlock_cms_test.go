@@ -0,0 +1,56 @@
+package tlock_test
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCMSRoundTrip(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+
+	var der bytes.Buffer
+	w := tlock.NewCMSWriter(&der)
+	require.NoError(t, tlock.New(network).Encrypt(w, bytes.NewReader(loremBytes), 1000))
+	require.NoError(t, w.Close())
+
+	var contentInfo struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue `asn1:"explicit,tag:0"`
+	}
+	rest, err := asn1.Unmarshal(der.Bytes(), &contentInfo)
+	require.NoError(t, err)
+	require.Empty(t, rest)
+	require.Equal(t, "1.2.840.113549.1.7.3", contentInfo.ContentType.String())
+
+	var plainData bytes.Buffer
+	err = tlock.New(network).Decrypt(&plainData, tlock.NewCMSReader(&der))
+	require.NoError(t, err)
+	require.Equal(t, loremBytes, plainData.Bytes())
+
+	err = tlock.New(network).Decrypt(&plainData, tlock.NewCMSReader(bytes.NewReader(loremBytes)))
+	require.ErrorIs(t, err, tlock.ErrMalformedCMS)
+}
+
+func TestCMSRoundTripKeepsStanzaOrder(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+
+	// The tlock stanza sorts after the metadata stanza in DER.
+	var der bytes.Buffer
+	w := tlock.NewCMSWriter(&der)
+	encrypter := tlock.New(network).WithMetadata(tlock.UserMetadata{"filename": "lorem.txt"})
+	require.NoError(t, encrypter.Encrypt(w, bytes.NewReader(loremBytes), 1000))
+	require.NoError(t, w.Close())
+
+	var plainData bytes.Buffer
+	require.NoError(t, tlock.New(network).Decrypt(&plainData, tlock.NewCMSReader(&der)))
+	require.Equal(t, loremBytes, plainData.Bytes())
+}
@@ -0,0 +1,219 @@
+package tlock
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/drand/drand/v2/crypto"
+	"github.com/drand/kyber/encrypt/ibe"
+)
+
+// ErrMalformedCompact is returned when a compact ciphertext can't be decoded.
+var ErrMalformedCompact = errors.New("malformed compact ciphertext")
+
+// MaxCompactSize is the largest plaintext which can be encrypted directly with
+// the IBE scheme, bounded by the output size of its hash function.
+const MaxCompactSize = 32
+
+// compactHRPPrefix starts the human readable part of compact ciphertexts,
+// which continues with the first bytes of the chain hash.
+const compactHRPPrefix = "tlock"
+
+// compactChainHashLen is the number of chain hash characters in the human
+// readable part.
+const compactChainHashLen = 8
+
+// bech32Charset is the bech32 alphabet for 5 bit groups.
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// bech32mConst is the checksum constant of BIP 350.
+const bech32mConst = 0x2bc830a3
+
+// EncryptCompact encrypts a plaintext of at most MaxCompactSize bytes directly
+// with the IBE scheme, without age, and returns it as a single line bech32m
+// string suitable for URLs and chat messages. The human readable part of the
+// string identifies the chain it can be decrypted with.
+func (t Tlock) EncryptCompact(plaintext []byte, roundNumber uint64) (string, error) {
+	if len(plaintext) == 0 || len(plaintext) > MaxCompactSize {
+		return "", fmt.Errorf("plaintext must be between 1 and %d bytes, got %d", MaxCompactSize, len(plaintext))
+	}
+
+	ciphertext, err := TimeLock(t.network.Scheme(), t.network.PublicKey(), roundNumber, plaintext)
+	if err != nil {
+		return "", fmt.Errorf("encrypt: %w", err)
+	}
+	b, err := directCiphertextToBytes(ciphertext)
+	if err != nil {
+		return "", err
+	}
+
+	data := binary.BigEndian.AppendUint64(nil, roundNumber)
+	data = append(data, b...)
+
+	return encodeBech32m(compactHRP(t.network.ChainHash()), data), nil
+}
+
+// DecryptCompact decrypts a string produced by EncryptCompact. The round must
+// have been reached by the network.
+func (t Tlock) DecryptCompact(s string) ([]byte, error) {
+	hrp, data, err := decodeBech32m(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedCompact, err)
+	}
+	if hrp != compactHRP(t.network.ChainHash()) {
+		return nil, fmt.Errorf("%w: ciphertext for %q, network is %q", ErrWrongChainhash, hrp, compactHRP(t.network.ChainHash()))
+	}
+
+	if len(data) < 8 {
+		return nil, fmt.Errorf("%w: invalid length %d", ErrMalformedCompact, len(data))
+	}
+	ciphertext, err := bytesToDirectCiphertext(t.network.Scheme(), data[8:])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedCompact, err)
+	}
+
+	id := Identity{network: t.network}
+	return id.unlock(binary.BigEndian.Uint64(data[:8]), ciphertext)
+}
+
+// directCiphertextToBytes converts a ciphertext of a plaintext encrypted
+// directly with the IBE scheme to bytes. Unlike CiphertextToBytes, the sizes
+// of V and W are the size of the plaintext.
+func directCiphertextToBytes(ciphertext *ibe.Ciphertext) ([]byte, error) {
+	u, err := ciphertext.U.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("marshal kyber point: %w", err)
+	}
+
+	b := append(u, ciphertext.V...)
+	return append(b, ciphertext.W...), nil
+}
+
+// bytesToDirectCiphertext converts bytes produced by directCiphertextToBytes
+// back to a ciphertext.
+func bytesToDirectCiphertext(scheme crypto.Scheme, b []byte) (*ibe.Ciphertext, error) {
+	pointLen := scheme.KeyGroup.PointLen()
+	size := len(b) - pointLen
+	if size < 2 || size%2 != 0 || size > 2*MaxCompactSize {
+		return nil, fmt.Errorf("invalid ciphertext length %d", len(b))
+	}
+
+	u := scheme.KeyGroup.Point()
+	if err := u.UnmarshalBinary(b[:pointLen]); err != nil {
+		return nil, fmt.Errorf("unmarshal kyber point: %w", err)
+	}
+	rest := b[pointLen:]
+
+	return &ibe.Ciphertext{U: u, V: rest[:size/2], W: rest[size/2:]}, nil
+}
+
+// =============================================================================
+
+// compactHRP returns the human readable part for the chain hash.
+func compactHRP(chainHash string) string {
+	return compactHRPPrefix + strings.ToLower(chainHash[:min(compactChainHashLen, len(chainHash))])
+}
+
+// encodeBech32m encodes data with the human readable part following BIP 350.
+// The 90 characters limit of BIP 173 isn't applied, ciphertexts being longer.
+func encodeBech32m(hrp string, data []byte) string {
+	values := convertBits(data, 8, 5, true)
+	checksum := bech32Polymod(append(bech32HRPExpand(hrp), append(values, 0, 0, 0, 0, 0, 0)...)) ^ bech32mConst
+
+	var sb strings.Builder
+	sb.WriteString(hrp + "1")
+	for _, v := range values {
+		sb.WriteByte(bech32Charset[v])
+	}
+	for i := 0; i < 6; i++ {
+		sb.WriteByte(bech32Charset[(checksum>>(5*(5-i)))&31])
+	}
+	return sb.String()
+}
+
+// decodeBech32m decodes a BIP 350 string into its human readable part and
+// data.
+func decodeBech32m(s string) (string, []byte, error) {
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return "", nil, errors.New("mixed case")
+	}
+	s = strings.ToLower(s)
+
+	sep := strings.LastIndexByte(s, '1')
+	if sep < 1 || sep+7 > len(s) {
+		return "", nil, errors.New("missing separator or checksum")
+	}
+	hrp := s[:sep]
+
+	values := make([]byte, 0, len(s)-sep-1)
+	for _, c := range s[sep+1:] {
+		v := strings.IndexRune(bech32Charset, c)
+		if v < 0 {
+			return "", nil, fmt.Errorf("invalid character %q", c)
+		}
+		values = append(values, byte(v))
+	}
+	if bech32Polymod(append(bech32HRPExpand(hrp), values...)) != bech32mConst {
+		return "", nil, errors.New("invalid checksum")
+	}
+
+	data := convertBits(values[:len(values)-6], 5, 8, false)
+	if data == nil {
+		return "", nil, errors.New("invalid padding")
+	}
+	return hrp, data, nil
+}
+
+// bech32Polymod computes the bech32 checksum of the values.
+func bech32Polymod(values []byte) uint32 {
+	gen := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
+
+// bech32HRPExpand expands the human readable part for the checksum.
+func bech32HRPExpand(hrp string) []byte {
+	values := make([]byte, 0, 2*len(hrp)+1)
+	for i := 0; i < len(hrp); i++ {
+		values = append(values, hrp[i]>>5)
+	}
+	values = append(values, 0)
+	for i := 0; i < len(hrp); i++ {
+		values = append(values, hrp[i]&31)
+	}
+	return values
+}
+
+// convertBits regroups data from groups of from bits into groups of to bits.
+// It returns nil if the padding is invalid when not padding.
+func convertBits(data []byte, from, to uint, pad bool) []byte {
+	var acc, bits uint
+	maxv := uint(1)<<to - 1
+	out := make([]byte, 0, len(data)*int(from)/int(to)+1)
+	for _, v := range data {
+		acc = acc<<from | uint(v)
+		bits += from
+		for bits >= to {
+			bits -= to
+			out = append(out, byte((acc>>bits)&maxv))
+		}
+	}
+	switch {
+	case pad && bits > 0:
+		out = append(out, byte((acc<<(to-bits))&maxv))
+	case !pad && (bits >= from || (acc<<(to-bits))&maxv != 0):
+		return nil
+	}
+	return out
+}
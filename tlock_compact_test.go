@@ -0,0 +1,57 @@
+package tlock_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompactRoundTrip(t *testing.T) {
+	network := fixedtest.NewNetwork(t, 1000)
+	secret := []byte("correct horse battery staple")
+
+	compact, err := tlock.New(network).EncryptCompact(secret, 1000)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(compact, "tlock"+network.ChainHash()[:8]+"1"))
+	require.NotContains(t, compact, "\n")
+
+	plaintext, err := tlock.New(network).DecryptCompact(compact)
+	require.NoError(t, err)
+	require.Equal(t, secret, plaintext)
+
+	// Upper case strings are valid too, making for denser QR codes.
+	plaintext, err = tlock.New(network).DecryptCompact(strings.ToUpper(compact))
+	require.NoError(t, err)
+	require.Equal(t, secret, plaintext)
+
+	tampered := []byte(compact)
+	if tampered[20] == 'q' {
+		tampered[20] = 'p'
+	} else {
+		tampered[20] = 'q'
+	}
+	_, err = tlock.New(network).DecryptCompact(string(tampered))
+	require.ErrorIs(t, err, tlock.ErrMalformedCompact)
+
+	_, err = tlock.New(network).EncryptCompact(make([]byte, tlock.MaxCompactSize+1), 1000)
+	require.Error(t, err)
+}
+
+func TestCompactWrongChain(t *testing.T) {
+	network := fixedtest.NewNetwork(t, 1000)
+
+	compact, err := tlock.New(network).EncryptCompact([]byte("secret"), 1000)
+	require.NoError(t, err)
+
+	sch := network.Scheme()
+	other, err := fixed.NewNetwork(testnetQuicknetT, network.PublicKey(), &sch, 3*time.Second, time.Now().Unix(), nil)
+	require.NoError(t, err)
+
+	_, err = tlock.New(other).DecryptCompact(compact)
+	require.ErrorIs(t, err, tlock.ErrWrongChainhash)
+}
@@ -0,0 +1,133 @@
+package tlock
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/drand/drand/v2/crypto"
+	"github.com/drand/kyber/encrypt/ibe"
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+// ErrMalformedContractCiphertext is returned when a contract ciphertext can't
+// be decoded.
+var ErrMalformedContractCiphertext = errors.New("malformed contract ciphertext")
+
+// These constants define the sizes of the EIP-2537 encodings used by on-chain
+// BLS12-381 verifiers: field elements are left padded to 64 bytes, and points
+// are uncompressed with the Fp2 coordinates ordered c0 then c1.
+const (
+	fpSize         = 48
+	eip2537FpSize  = 64
+	eip2537G1Size  = 2 * eip2537FpSize
+	eip2537G2Size  = 4 * eip2537FpSize
+	compressedG1   = fpSize
+	compressedG2   = 2 * fpSize
+	eip2537Padding = eip2537FpSize - fpSize
+)
+
+// ContractCiphertext converts the ciphertext wrapping a DEK, as carried by the
+// tlock stanza, to the layout expected by Solidity verifiers built on the
+// EIP-2537 precompiles: the point U in its EIP-2537 encoding, 128 bytes on G1
+// or 256 bytes on G2, followed by V and W.
+func ContractCiphertext(scheme crypto.Scheme, ciphertext *ibe.Ciphertext) ([]byte, error) {
+	u, err := ciphertext.U.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("marshal kyber point: %w", err)
+	}
+
+	var b []byte
+	switch scheme.KeyGroup.PointLen() {
+	case compressedG1:
+		p, err := bls12381.NewG1().FromCompressed(u)
+		if err != nil {
+			return nil, fmt.Errorf("decompress G1 point: %w", err)
+		}
+		raw := bls12381.NewG1().ToUncompressed(p)
+		b = appendEIP2537Fp(b, raw[:fpSize], raw[fpSize:])
+	case compressedG2:
+		p, err := bls12381.NewG2().FromCompressed(u)
+		if err != nil {
+			return nil, fmt.Errorf("decompress G2 point: %w", err)
+		}
+		// The uncompressed encoding orders the Fp2 coordinates c1 then c0.
+		raw := bls12381.NewG2().ToUncompressed(p)
+		b = appendEIP2537Fp(b, raw[fpSize:2*fpSize], raw[:fpSize], raw[3*fpSize:], raw[2*fpSize:3*fpSize])
+	default:
+		return nil, fmt.Errorf("unsupported point length %d", scheme.KeyGroup.PointLen())
+	}
+
+	b = append(b, ciphertext.V...)
+	return append(b, ciphertext.W...), nil
+}
+
+// ContractCiphertextToCiphertext converts bytes in the layout produced by
+// ContractCiphertext back to a ciphertext.
+func ContractCiphertextToCiphertext(scheme crypto.Scheme, b []byte) (*ibe.Ciphertext, error) {
+	var u []byte
+	var rest []byte
+	switch scheme.KeyGroup.PointLen() {
+	case compressedG1:
+		if len(b) != eip2537G1Size+cipherVLen+cipherWLen {
+			return nil, fmt.Errorf("%w: incorrect length %d", ErrMalformedContractCiphertext, len(b))
+		}
+		raw, err := stripEIP2537Fp(b[:eip2537G1Size])
+		if err != nil {
+			return nil, err
+		}
+		p, err := bls12381.NewG1().FromUncompressed(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrMalformedContractCiphertext, err)
+		}
+		u, rest = bls12381.NewG1().ToCompressed(p), b[eip2537G1Size:]
+	case compressedG2:
+		if len(b) != eip2537G2Size+cipherVLen+cipherWLen {
+			return nil, fmt.Errorf("%w: incorrect length %d", ErrMalformedContractCiphertext, len(b))
+		}
+		raw, err := stripEIP2537Fp(b[:eip2537G2Size])
+		if err != nil {
+			return nil, err
+		}
+		swapped := make([]byte, 0, len(raw))
+		swapped = append(swapped, raw[fpSize:2*fpSize]...)
+		swapped = append(swapped, raw[:fpSize]...)
+		swapped = append(swapped, raw[3*fpSize:]...)
+		swapped = append(swapped, raw[2*fpSize:3*fpSize]...)
+		p, err := bls12381.NewG2().FromUncompressed(swapped)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrMalformedContractCiphertext, err)
+		}
+		u, rest = bls12381.NewG2().ToCompressed(p), b[eip2537G2Size:]
+	default:
+		return nil, fmt.Errorf("unsupported point length %d", scheme.KeyGroup.PointLen())
+	}
+
+	compressed := append(u, rest...)
+	return BytesToCiphertext(scheme, compressed)
+}
+
+// =============================================================================
+
+// appendEIP2537Fp appends the field elements left padded to 64 bytes.
+func appendEIP2537Fp(b []byte, elements ...[]byte) []byte {
+	for _, e := range elements {
+		b = append(b, make([]byte, eip2537Padding)...)
+		b = append(b, e...)
+	}
+	return b
+}
+
+// stripEIP2537Fp removes the padding of the field elements, which must be
+// zero.
+func stripEIP2537Fp(b []byte) ([]byte, error) {
+	out := make([]byte, 0, len(b)/eip2537FpSize*fpSize)
+	for ; len(b) > 0; b = b[eip2537FpSize:] {
+		for _, c := range b[:eip2537Padding] {
+			if c != 0 {
+				return nil, fmt.Errorf("%w: non zero padding", ErrMalformedContractCiphertext)
+			}
+		}
+		out = append(out, b[eip2537Padding:eip2537FpSize]...)
+	}
+	return out, nil
+}
@@ -0,0 +1,41 @@
+package tlock_test
+
+import (
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/drand/drand/v2/crypto"
+	"github.com/drand/kyber/util/random"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContractCiphertext(t *testing.T) {
+	for size, sch := range map[int]*crypto.Scheme{
+		128 + 32: crypto.NewPedersenBLSUnchained(),
+		256 + 32: crypto.NewPedersenBLSUnchainedSwapped(),
+	} {
+		t.Run(sch.Name, func(t *testing.T) {
+			publicKey := sch.KeyGroup.Point().Mul(sch.KeyGroup.Scalar().Pick(random.New()), nil)
+
+			ciphertext, err := tlock.TimeLock(*sch, publicKey, 1000, []byte("0123456789abcdef"))
+			require.NoError(t, err)
+
+			b, err := tlock.ContractCiphertext(*sch, ciphertext)
+			require.NoError(t, err)
+			require.Len(t, b, size)
+			for i := 0; i < size-32; i += 64 {
+				require.Equal(t, make([]byte, 16), b[i:i+16])
+			}
+
+			decoded, err := tlock.ContractCiphertextToCiphertext(*sch, b)
+			require.NoError(t, err)
+			require.True(t, ciphertext.U.Equal(decoded.U))
+			require.Equal(t, ciphertext.V, decoded.V)
+			require.Equal(t, ciphertext.W, decoded.W)
+
+			b[0] = 1
+			_, err = tlock.ContractCiphertextToCiphertext(*sch, b)
+			require.ErrorIs(t, err, tlock.ErrMalformedContractCiphertext)
+		})
+	}
+}
@@ -0,0 +1,348 @@
+package tlock
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// ErrMalformedConvergent is returned when a ciphertext isn't a valid
+// convergent ciphertext.
+var ErrMalformedConvergent = errors.New("malformed convergent ciphertext")
+
+// ErrConvergentSecret is returned when the convergent secret is too short.
+var ErrConvergentSecret = errors.New("convergent secret too short")
+
+// ErrNoChecksums is returned when scanning the checksums of a ciphertext
+// whose chunks have none.
+var ErrNoChecksums = errors.New("ciphertext has no chunk checksums")
+
+// ErrChecksumMismatch is returned when a chunk doesn't match its checksum.
+var ErrChecksumMismatch = errors.New("chunk checksum mismatch")
+
+// These magics start convergent ciphertexts. Since v2, each frame carries the
+// CRC32C of its sealed chunk, outside of the AEAD.
+const (
+	convergentMagic   = "tlock convergent v2\n"
+	convergentMagicV1 = "tlock convergent v1\n"
+)
+
+// castagnoli is the table of the CRC32C of the chunks.
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// These constants define the content defined chunking, which cuts chunks of
+// 256 KiB on average.
+const (
+	minConvergentChunk  = 64 * 1024
+	maxConvergentChunk  = 1024 * 1024
+	convergentChunkBits = 18
+)
+
+// These constants define the sizes of the convergent secret, the chunk keys
+// and the trailer recording the offset of the manifest.
+const (
+	minConvergentSecretSize = 16
+	convergentKeySize       = chacha20poly1305.KeySize
+	convergentTrailerSize   = 8
+)
+
+// These labels separate the uses of the convergent secret.
+const (
+	convergentGearLabel = "tlock convergent gear"
+	convergentKeyLabel  = "tlock convergent key"
+)
+
+// EncryptConvergent encrypts src so that backup systems can deduplicate the
+// ciphertexts of repeated data across runs. The plaintext is cut into chunks
+// at content defined boundaries, each chunk being encrypted with a key derived
+// from the secret and the hash of its content, so that equal chunks always
+// encrypt to equal bytes. Only the manifest listing the chunk keys is timelock
+// encrypted, using the options of the tlock.
+//
+// This trades confidentiality for deduplication: anyone holding ciphertexts
+// encrypted with the same secret learns which chunks they share, the chunk
+// sizes leak, and anyone holding the secret can confirm a guess of a chunk's
+// content before the round is reached. The secret must be kept as confidential
+// as the plaintext and shouldn't be shared between unrelated datasets.
+func (t Tlock) EncryptConvergent(dst io.Writer, src io.Reader, roundNumber uint64, secret []byte) error {
+	if len(secret) < minConvergentSecretSize {
+		return fmt.Errorf("%w: %d bytes, at least %d are required", ErrConvergentSecret, len(secret), minConvergentSecretSize)
+	}
+
+	bw := bufio.NewWriter(dst)
+	bw.WriteString(convergentMagic)
+	offset := int64(len(convergentMagic))
+
+	var manifest bytes.Buffer
+	c := newChunker(src, secret)
+	for {
+		chunk, err := c.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read chunk: %w", err)
+		}
+
+		key := convergentKey(secret, chunk)
+		aead, err := newChaCha20Poly1305(key, "convergent encryption")
+		if err != nil {
+			return err
+		}
+		sealed := aead.Seal(nil, make([]byte, chacha20poly1305.NonceSize), chunk, nil)
+
+		frame := binary.AppendUvarint(nil, uint64(len(sealed)))
+		frame = binary.BigEndian.AppendUint32(frame, crc32.Checksum(sealed, castagnoli))
+		bw.Write(frame)
+		if _, err := bw.Write(sealed); err != nil {
+			return fmt.Errorf("write chunk: %w", err)
+		}
+		offset += int64(len(frame) + len(sealed))
+		manifest.Write(key)
+	}
+	bw.WriteByte(0)
+	offset++
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("write chunk: %w", err)
+	}
+
+	if err := t.Encrypt(dst, &manifest, roundNumber); err != nil {
+		return fmt.Errorf("encrypt manifest: %w", err)
+	}
+
+	_, err := dst.Write(binary.BigEndian.AppendUint64(nil, uint64(offset)))
+	return err
+}
+
+// DecryptConvergent decrypts a ciphertext produced by EncryptConvergent. The
+// manifest is at the end of the ciphertext, hence src has to be seekable. The
+// secret isn't needed, the chunk keys being recorded in the manifest.
+func (t Tlock) DecryptConvergent(dst io.Writer, src io.ReadSeeker) error {
+	size, err := src.Seek(-convergentTrailerSize, io.SeekEnd)
+	if err != nil {
+		return fmt.Errorf("%w: seek trailer: %w", ErrMalformedConvergent, err)
+	}
+	trailer := make([]byte, convergentTrailerSize)
+	if _, err := io.ReadFull(src, trailer); err != nil {
+		return fmt.Errorf("%w: read trailer: %w", ErrMalformedConvergent, err)
+	}
+	offset := binary.BigEndian.Uint64(trailer)
+	if offset <= uint64(len(convergentMagic)) || offset >= uint64(size) {
+		return fmt.Errorf("%w: invalid manifest offset %d", ErrMalformedConvergent, offset)
+	}
+
+	if _, err := src.Seek(int64(offset), io.SeekStart); err != nil {
+		return err
+	}
+	var manifest bytes.Buffer
+	if err := t.Decrypt(&manifest, io.LimitReader(src, size-int64(offset))); err != nil {
+		if errors.Is(err, ErrMalformedHeader) {
+			return fmt.Errorf("%w: decrypt manifest: %w", ErrMalformedConvergent, err)
+		}
+		return fmt.Errorf("decrypt manifest: %w", err)
+	}
+	if manifest.Len()%convergentKeySize != 0 {
+		return fmt.Errorf("%w: manifest of %d bytes", ErrMalformedConvergent, manifest.Len())
+	}
+
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return t.openConvergentChunks(t.limitPlaintext(dst), bufio.NewReader(io.LimitReader(src, int64(offset))), manifest.Bytes())
+}
+
+// IsConvergent reports whether src starts like a convergent ciphertext. It
+// leaves src positioned at its start, and doesn't read from it when it can't
+// seek, such as a pipe.
+func IsConvergent(src io.ReadSeeker) bool {
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return false
+	}
+	magic := make([]byte, len(convergentMagic))
+	_, err := io.ReadFull(src, magic)
+	if _, serr := src.Seek(0, io.SeekStart); err != nil || serr != nil {
+		return false
+	}
+	return string(magic) == convergentMagic || string(magic) == convergentMagicV1
+}
+
+// VerifyChecksums scans the chunks of a convergent ciphertext and checks them
+// against the CRC32C their frame carries, finding corrupted chunks in large
+// archives quickly: neither the network nor the manifest is needed and
+// nothing is decrypted. It returns the number of chunks checked. The checksums
+// only catch accidental corruption, decrypting is what authenticates the
+// data. Ciphertexts without checksums fail with ErrNoChecksums.
+func VerifyChecksums(src io.Reader) (int, error) {
+	r := bufio.NewReader(src)
+	magic := make([]byte, len(convergentMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != convergentMagic {
+		return 0, ErrNoChecksums
+	}
+
+	buf := make([]byte, maxConvergentChunk+chacha20poly1305.Overhead)
+	for index := 0; ; index++ {
+		n, err := readConvergentFrame(r, buf, index, true)
+		if err != nil {
+			return index, err
+		}
+		if n == 0 {
+			return index, nil
+		}
+	}
+}
+
+// =============================================================================
+
+// openConvergentChunks decrypts the chunks in r with the keys of the manifest.
+func (t Tlock) openConvergentChunks(dst io.Writer, r *bufio.Reader, keys []byte) error {
+	magic := make([]byte, len(convergentMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || (string(magic) != convergentMagic && string(magic) != convergentMagicV1) {
+		return fmt.Errorf("%w: not a convergent ciphertext", ErrMalformedConvergent)
+	}
+
+	buf := make([]byte, maxConvergentChunk+chacha20poly1305.Overhead)
+	for index := 0; ; index++ {
+		n, err := readConvergentFrame(r, buf, index, string(magic) == convergentMagic)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			break
+		}
+		if len(keys) < convergentKeySize {
+			return fmt.Errorf("%w: more chunks than in the manifest", ErrMalformedConvergent)
+		}
+
+		aead, err := newChaCha20Poly1305(keys[:convergentKeySize], "convergent encryption")
+		if err != nil {
+			return err
+		}
+		keys = keys[convergentKeySize:]
+		plaintext, err := aead.Open(buf[:0], make([]byte, chacha20poly1305.NonceSize), buf[:n], nil)
+		if err != nil {
+			return fmt.Errorf("decrypt chunk %d: %w", index, err)
+		}
+		if _, err := dst.Write(plaintext); err != nil {
+			return err
+		}
+	}
+
+	if len(keys) != 0 {
+		return fmt.Errorf("%w: %d chunks missing", ErrMalformedConvergent, len(keys)/convergentKeySize)
+	}
+	if _, err := r.ReadByte(); !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%w: trailing data before the manifest", ErrMalformedConvergent)
+	}
+	return nil
+}
+
+// readConvergentFrame reads the frame of the chunk with the given index into
+// buf, checking its CRC32C when it has one, and returns the size of the sealed
+// chunk, 0 for the end of the chunks.
+func readConvergentFrame(r *bufio.Reader, buf []byte, index int, checksum bool) (uint64, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, fmt.Errorf("%w: read frame: %w", ErrMalformedConvergent, err)
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	if n <= chacha20poly1305.Overhead || n > uint64(len(buf)) {
+		return 0, fmt.Errorf("%w: chunk %d has invalid size %d", ErrMalformedConvergent, index, n)
+	}
+
+	var sum [4]byte
+	if checksum {
+		if _, err := io.ReadFull(r, sum[:]); err != nil {
+			return 0, fmt.Errorf("%w: read frame: %w", ErrMalformedConvergent, err)
+		}
+	}
+	if _, err := io.ReadFull(r, buf[:n]); err != nil {
+		return 0, fmt.Errorf("%w: read chunk %d: %w", ErrMalformedConvergent, index, err)
+	}
+	if checksum && crc32.Checksum(buf[:n], castagnoli) != binary.BigEndian.Uint32(sum[:]) {
+		return 0, fmt.Errorf("%w: chunk %d", ErrChecksumMismatch, index)
+	}
+	return n, nil
+}
+
+// convergentKey derives the key of a chunk from the secret and its content.
+func convergentKey(secret []byte, chunk []byte) []byte {
+	sum := sha256.Sum256(chunk)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(convergentKeyLabel))
+	mac.Write(sum[:])
+	return mac.Sum(nil)
+}
+
+// chunker cuts a stream in chunks using a gear rolling hash, whose table is
+// derived from the secret so that the boundaries don't reveal the content to
+// those not holding it.
+type chunker struct {
+	r    io.Reader
+	gear [256]uint64
+	buf  []byte
+	n    int
+	cut  int
+	eof  bool
+}
+
+func newChunker(r io.Reader, secret []byte) *chunker {
+	c := chunker{r: r, buf: make([]byte, maxConvergentChunk)}
+	for i := range c.gear {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(convergentGearLabel))
+		mac.Write([]byte{byte(i)})
+		c.gear[i] = binary.BigEndian.Uint64(mac.Sum(nil))
+	}
+	return &c
+}
+
+// next returns the next chunk, which is only valid until the following call.
+func (c *chunker) next() ([]byte, error) {
+	c.n = copy(c.buf, c.buf[c.cut:c.n])
+	c.cut = 0
+
+	if !c.eof {
+		n, err := io.ReadFull(c.r, c.buf[c.n:])
+		c.n += n
+		switch {
+		case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+			c.eof = true
+		case err != nil:
+			return nil, err
+		}
+	}
+	if c.n == 0 {
+		return nil, io.EOF
+	}
+
+	c.cut = c.boundary(c.buf[:c.n])
+	return c.buf[:c.cut], nil
+}
+
+// boundary returns the size of the chunk starting data.
+func (c *chunker) boundary(data []byte) int {
+	if len(data) <= minConvergentChunk {
+		return len(data)
+	}
+
+	var h uint64
+	for i := minConvergentChunk; i < len(data); i++ {
+		h = h<<1 + c.gear[data[i]]
+		// The top bits depend on the last 64 bytes, the bottom ones on far
+		// fewer.
+		if h>>(64-convergentChunkBits) == 0 {
+			return i + 1
+		}
+	}
+	return len(data)
+}
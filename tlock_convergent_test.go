@@ -0,0 +1,162 @@
+package tlock_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptConvergent(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	plaintext := make([]byte, 3*1024*1024)
+	rand.New(rand.NewSource(1)).Read(plaintext)
+
+	for _, size := range []int{0, 10, len(plaintext)} {
+		var cipherData bytes.Buffer
+		require.NoError(t, tlock.New(network).EncryptConvergent(&cipherData, bytes.NewReader(plaintext[:size]), 1000, secret))
+		require.True(t, tlock.IsConvergent(bytes.NewReader(cipherData.Bytes())))
+
+		var out bytes.Buffer
+		require.NoError(t, tlock.New(network).DecryptConvergent(&out, bytes.NewReader(cipherData.Bytes())))
+		require.True(t, bytes.Equal(plaintext[:size], out.Bytes()))
+	}
+
+	err := tlock.New(network).EncryptConvergent(io.Discard, bytes.NewReader(plaintext), 1000, []byte("short"))
+	require.ErrorIs(t, err, tlock.ErrConvergentSecret)
+
+	var cipherData bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&cipherData, bytes.NewReader(plaintext[:10]), 1000))
+	require.False(t, tlock.IsConvergent(bytes.NewReader(cipherData.Bytes())))
+}
+
+func TestEncryptConvergentDeduplicates(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	plaintext := make([]byte, 4*1024*1024)
+	rand.New(rand.NewSource(2)).Read(plaintext)
+
+	var first, second bytes.Buffer
+	require.NoError(t, tlock.New(network).EncryptConvergent(&first, bytes.NewReader(plaintext), 1000, secret))
+	require.NoError(t, tlock.New(network).EncryptConvergent(&second, bytes.NewReader(plaintext), 1000, secret))
+	require.Equal(t, convergentChunks(t, first.Bytes()), convergentChunks(t, second.Bytes()))
+
+	// Inserting data only changes the chunks around the insertion.
+	edited := append(append(append([]byte{}, plaintext[:2*1024*1024]...), "inserted"...), plaintext[2*1024*1024:]...)
+	var third bytes.Buffer
+	require.NoError(t, tlock.New(network).EncryptConvergent(&third, bytes.NewReader(edited), 1000, secret))
+
+	before := convergentChunks(t, first.Bytes())
+	seen := make(map[string]bool)
+	for _, chunk := range before {
+		seen[string(chunk)] = true
+	}
+	var shared int
+	for _, chunk := range convergentChunks(t, third.Bytes()) {
+		if seen[string(chunk)] {
+			shared++
+		}
+	}
+	require.Greater(t, len(before), 4)
+	require.GreaterOrEqual(t, shared, len(before)-2)
+
+	// A different secret doesn't share anything.
+	var other bytes.Buffer
+	require.NoError(t, tlock.New(network).EncryptConvergent(&other, bytes.NewReader(plaintext), 1000, []byte("fedcba9876543210fedcba9876543210")))
+	for _, chunk := range convergentChunks(t, other.Bytes()) {
+		require.False(t, seen[string(chunk)])
+	}
+}
+
+func TestDecryptConvergentTampered(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	plaintext := make([]byte, 1024*1024)
+	rand.New(rand.NewSource(3)).Read(plaintext)
+
+	var cipherData bytes.Buffer
+	require.NoError(t, tlock.New(network).EncryptConvergent(&cipherData, bytes.NewReader(plaintext), 1000, secret))
+
+	tampered := bytes.Clone(cipherData.Bytes())
+	tampered[100] ^= 1
+	err := tlock.New(network).DecryptConvergent(io.Discard, bytes.NewReader(tampered))
+	require.Error(t, err)
+
+	truncated := bytes.Clone(cipherData.Bytes())
+	truncated = truncated[:len(truncated)-1]
+	err = tlock.New(network).DecryptConvergent(io.Discard, bytes.NewReader(truncated))
+	require.ErrorIs(t, err, tlock.ErrMalformedConvergent)
+
+	// Dropping a chunk is caught by the manifest.
+	chunks := convergentChunks(t, cipherData.Bytes())
+	frame := len(binary.AppendUvarint(nil, uint64(len(chunks[0])))) + 4 + len(chunks[0])
+	dropped := bytes.Clone(cipherData.Bytes())
+	dropped = append(dropped[:len("tlock convergent v2\n")], dropped[len("tlock convergent v2\n")+frame:]...)
+	err = tlock.New(network).DecryptConvergent(io.Discard, bytes.NewReader(dropped))
+	require.Error(t, err)
+}
+
+func TestVerifyChecksums(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	plaintext := make([]byte, 1024*1024)
+	rand.New(rand.NewSource(4)).Read(plaintext)
+
+	var cipherData bytes.Buffer
+	require.NoError(t, tlock.New(network).EncryptConvergent(&cipherData, bytes.NewReader(plaintext), 1000, secret))
+
+	chunks, err := tlock.VerifyChecksums(bytes.NewReader(cipherData.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, len(convergentChunks(t, cipherData.Bytes())), chunks)
+
+	// Corruption is found without decrypting, and caught again when
+	// decrypting.
+	tampered := bytes.Clone(cipherData.Bytes())
+	tampered[100] ^= 1
+	_, err = tlock.VerifyChecksums(bytes.NewReader(tampered))
+	require.ErrorIs(t, err, tlock.ErrChecksumMismatch)
+	err = tlock.New(network).DecryptConvergent(io.Discard, bytes.NewReader(tampered))
+	require.ErrorIs(t, err, tlock.ErrChecksumMismatch)
+
+	var ageData bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&ageData, bytes.NewReader(plaintext), 1000))
+	_, err = tlock.VerifyChecksums(&ageData)
+	require.ErrorIs(t, err, tlock.ErrNoChecksums)
+}
+
+// convergentChunks returns the encrypted chunks of a convergent ciphertext.
+func convergentChunks(t *testing.T, ciphertext []byte) [][]byte {
+	r := bufio.NewReader(bytes.NewReader(ciphertext))
+	_, err := r.Discard(len("tlock convergent v2\n"))
+	require.NoError(t, err)
+
+	var chunks [][]byte
+	for {
+		n, err := binary.ReadUvarint(r)
+		require.NoError(t, err)
+		if n == 0 {
+			return chunks
+		}
+		_, err = r.Discard(4)
+		require.NoError(t, err)
+		chunk := make([]byte, n)
+		_, err = io.ReadFull(r, chunk)
+		require.NoError(t, err)
+		chunks = append(chunks, chunk)
+	}
+}
@@ -0,0 +1,122 @@
+package tlock
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/hkdf"
+)
+
+// ErrEmptyHint is returned when a decoy writer or reader is created without
+// a hint to derive its keystream from.
+var ErrEmptyHint = errors.New("decoy mode requires a non-empty hint")
+
+// decoySaltSize is the size of the random salt prefixed to decoy output.
+const decoySaltSize = 16
+
+// decoyInfo is the HKDF info string used to derive the decoy keystream key.
+const decoyInfo = "tlock decoy v1"
+
+// NewDecoyWriter returns a writer that whitens everything written to it with a
+// keystream derived from the hint and a random salt. The resulting output has
+// no magic, no armor and no cleartext header, so without the hint it cannot be
+// told apart from random bytes. The whitening provides no confidentiality of
+// its own: anybody guessing the hint can strip it, so it should be something
+// only the intended recipients know.
+func NewDecoyWriter(dst io.Writer, hint string) (io.Writer, error) {
+	return newDecoyWriter(dst, hint, rand.Reader)
+}
+
+// NewDecoyWriter returns a decoy writer as the function of the same name does,
+// drawing the salt from the source of WithRandom.
+func (t Tlock) NewDecoyWriter(dst io.Writer, hint string) (io.Writer, error) {
+	return newDecoyWriter(dst, hint, randomReader(t.random))
+}
+
+// newDecoyWriter returns the decoy writer, drawing the salt from random.
+func newDecoyWriter(dst io.Writer, hint string, random io.Reader) (io.Writer, error) {
+	if hint == "" {
+		return nil, ErrEmptyHint
+	}
+
+	salt := make([]byte, decoySaltSize)
+	if _, err := io.ReadFull(random, salt); err != nil {
+		return nil, fmt.Errorf("read salt: %w", err)
+	}
+
+	stream, err := decoyStream(hint, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := dst.Write(salt); err != nil {
+		return nil, fmt.Errorf("write salt: %w", err)
+	}
+
+	return &decoyWriter{dst: dst, stream: stream}, nil
+}
+
+// NewDecoyReader returns a reader removing the whitening applied by
+// NewDecoyWriter. A wrong hint is not detected here; it results in garbage
+// which the subsequent decryption will reject.
+func NewDecoyReader(src io.Reader, hint string) (io.Reader, error) {
+	if hint == "" {
+		return nil, ErrEmptyHint
+	}
+
+	salt := make([]byte, decoySaltSize)
+	if _, err := io.ReadFull(src, salt); err != nil {
+		return nil, fmt.Errorf("read salt: %w", err)
+	}
+
+	stream, err := decoyStream(hint, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decoyReader{src: src, stream: stream}, nil
+}
+
+// decoyStream derives the keystream for the given hint and salt.
+func decoyStream(hint string, salt []byte) (*chacha20.Cipher, error) {
+	key := make([]byte, chacha20.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, []byte(hint), salt, []byte(decoyInfo)), key); err != nil {
+		return nil, fmt.Errorf("derive key: %w", err)
+	}
+
+	// The key is unique per salt, so a fixed nonce is safe here.
+	stream, err := chacha20.NewUnauthenticatedCipher(key, make([]byte, chacha20.NonceSize))
+	if err != nil {
+		return nil, fmt.Errorf("keystream: %w", err)
+	}
+
+	return stream, nil
+}
+
+// =============================================================================
+
+type decoyWriter struct {
+	dst    io.Writer
+	stream *chacha20.Cipher
+}
+
+func (w *decoyWriter) Write(p []byte) (int, error) {
+	buf := make([]byte, len(p))
+	w.stream.XORKeyStream(buf, p)
+	return w.dst.Write(buf)
+}
+
+type decoyReader struct {
+	src    io.Reader
+	stream *chacha20.Cipher
+}
+
+func (r *decoyReader) Read(p []byte) (int, error) {
+	n, err := r.src.Read(p)
+	r.stream.XORKeyStream(p[:n], p[:n])
+	return n, err
+}
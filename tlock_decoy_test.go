@@ -0,0 +1,46 @@
+package tlock_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecoyRoundTrip(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+
+	var cipherData bytes.Buffer
+	w, err := tlock.NewDecoyWriter(&cipherData, "meet me at round 1000")
+	require.NoError(t, err)
+	require.NoError(t, tlock.New(network).Encrypt(w, bytes.NewReader(loremBytes), 1000))
+
+	require.NotContains(t, cipherData.String(), "age-encryption.org")
+	require.NotContains(t, cipherData.String(), "tlock")
+
+	t.Run("with the right hint", func(t *testing.T) {
+		r, err := tlock.NewDecoyReader(bytes.NewReader(cipherData.Bytes()), "meet me at round 1000")
+		require.NoError(t, err)
+
+		var plainData bytes.Buffer
+		require.NoError(t, tlock.New(network).Decrypt(&plainData, r))
+		require.Equal(t, loremBytes, plainData.Bytes())
+	})
+
+	t.Run("with the wrong hint", func(t *testing.T) {
+		r, err := tlock.NewDecoyReader(bytes.NewReader(cipherData.Bytes()), "wrong")
+		require.NoError(t, err)
+
+		var plainData bytes.Buffer
+		require.Error(t, tlock.New(network).Decrypt(&plainData, r))
+	})
+
+	t.Run("without hint", func(t *testing.T) {
+		_, err := tlock.NewDecoyWriter(&bytes.Buffer{}, "")
+		require.ErrorIs(t, err, tlock.ErrEmptyHint)
+	})
+}
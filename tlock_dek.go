@@ -0,0 +1,54 @@
+package tlock
+
+import (
+	"fmt"
+)
+
+// WrappedDEK is a data encryption key timelocked towards a round. It holds
+// everything needed to unwrap it besides the network, and serializes to JSON
+// for stateless wrapping services.
+type WrappedDEK struct {
+	Round      uint64 `json:"round"`
+	ChainHash  string `json:"chain_hash"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// WrapDEK timelock encrypts the data encryption key, of at most MaxCompactSize
+// bytes, directly with the IBE scheme towards the round.
+func (t Tlock) WrapDEK(dek []byte, roundNumber uint64) (WrappedDEK, error) {
+	if len(dek) == 0 || len(dek) > MaxCompactSize {
+		return WrappedDEK{}, fmt.Errorf("dek must be between 1 and %d bytes, got %d", MaxCompactSize, len(dek))
+	}
+
+	ciphertext, err := TimeLock(t.network.Scheme(), t.network.PublicKey(), roundNumber, dek)
+	if err != nil {
+		return WrappedDEK{}, fmt.Errorf("encrypt dek: %w", err)
+	}
+	b, err := directCiphertextToBytes(ciphertext)
+	if err != nil {
+		return WrappedDEK{}, err
+	}
+
+	return WrappedDEK{
+		Round:      roundNumber,
+		ChainHash:  t.network.ChainHash(),
+		Ciphertext: b,
+	}, nil
+}
+
+// UnwrapDEK decrypts the data encryption key. The round must have been
+// reached by the network. Unwrapping many keys of the same round is best done
+// with ObtainVerifiedBeacon and UnwrapWithBeacon, fetching the beacon once.
+func (t Tlock) UnwrapDEK(w WrappedDEK) ([]byte, error) {
+	id := Identity{network: t.network, trustChainhash: t.trustChainhash}
+	if !id.useChainHash(w.ChainHash) {
+		return nil, id.wrongChainHash(w.ChainHash)
+	}
+
+	ciphertext, err := bytesToDirectCiphertext(t.network.Scheme(), w.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("parse wrapped dek: %w", err)
+	}
+
+	return id.unlock(w.Round, ciphertext)
+}
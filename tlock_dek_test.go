@@ -0,0 +1,73 @@
+package tlock_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrapUnwrapDEK(t *testing.T) {
+	network := fixedtest.NewNetwork(t, 1000)
+	dek := []byte("0123456789abcdef0123456789abcdef")
+
+	wrapped, err := tlock.New(network).WrapDEK(dek, 1000)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1000), wrapped.Round)
+	require.Equal(t, network.ChainHash(), wrapped.ChainHash)
+
+	// The wrapped key survives a JSON round trip.
+	b, err := json.Marshal(wrapped)
+	require.NoError(t, err)
+	var decoded tlock.WrappedDEK
+	require.NoError(t, json.Unmarshal(b, &decoded))
+
+	unwrapped, err := tlock.New(network).UnwrapDEK(decoded)
+	require.NoError(t, err)
+	require.Equal(t, dek, unwrapped)
+
+	decoded.Ciphertext = decoded.Ciphertext[:10]
+	_, err = tlock.New(network).UnwrapDEK(decoded)
+	require.Error(t, err)
+
+	_, err = tlock.New(network).WrapDEK(make([]byte, tlock.MaxCompactSize+1), 1000)
+	require.Error(t, err)
+}
+
+func TestUnwrapWithBeacon(t *testing.T) {
+	network := fixedtest.NewNetwork(t, 1000)
+	tl := tlock.New(network)
+
+	deks := [][]byte{[]byte("first data encryption key"), []byte("second data encryption key")}
+	var wrapped []tlock.WrappedDEK
+	for _, dek := range deks {
+		w, err := tl.WrapDEK(dek, 1000)
+		require.NoError(t, err)
+		wrapped = append(wrapped, w)
+	}
+
+	// The beacon is fetched and verified once, and survives a JSON round trip.
+	beacon, err := tl.ObtainVerifiedBeacon(1000)
+	require.NoError(t, err)
+	b, err := json.Marshal(beacon)
+	require.NoError(t, err)
+	var decoded tlock.VerifiedBeacon
+	require.NoError(t, json.Unmarshal(b, &decoded))
+	require.NoError(t, decoded.Verify(network))
+
+	for i, w := range wrapped {
+		unwrapped, err := tl.UnwrapWithBeacon(w, decoded)
+		require.NoError(t, err)
+		require.Equal(t, deks[i], unwrapped)
+	}
+
+	other, err := tl.WrapDEK(deks[0], 1001)
+	require.NoError(t, err)
+	_, err = tl.UnwrapWithBeacon(other, decoded)
+	require.ErrorIs(t, err, tlock.ErrBeaconMismatch)
+
+	decoded.Signature[0] ^= 1
+	require.Error(t, decoded.Verify(network))
+}
@@ -0,0 +1,59 @@
+package tlock
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// ErrPlaintextMismatch is returned when a plaintext doesn't match the digest
+// it was expected to have.
+var ErrPlaintextMismatch = errors.New("plaintext doesn't match the expected digest")
+
+// DigestWriter hashes the plaintext written through it with SHA-256, for the
+// consumers given the digest of a plaintext ahead of its round to check it
+// while it is decrypted, without reading it again:
+//
+//	dw := tlock.NewDigestWriter(dst, expected)
+//	if err := tlock.New(network).Decrypt(dw, src); err != nil {
+//		return err
+//	}
+//	return dw.Verify()
+//
+// The plaintext is written to dst before it is verified, the callers
+// discarding it on mismatch.
+type DigestWriter struct {
+	w        io.Writer
+	h        hash.Hash
+	expected []byte
+}
+
+// NewDigestWriter returns a writer to dst checking that what is written has
+// the expected SHA-256 digest.
+func NewDigestWriter(dst io.Writer, expected []byte) *DigestWriter {
+	return &DigestWriter{
+		w:        dst,
+		h:        sha256.New(),
+		expected: expected,
+	}
+}
+
+// Write writes p to the destination, hashing what was written.
+func (w *DigestWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.h.Write(p[:n])
+	return n, err
+}
+
+// Verify returns ErrPlaintextMismatch unless what was written has the
+// expected digest.
+func (w *DigestWriter) Verify() error {
+	sum := w.h.Sum(nil)
+	if subtle.ConstantTimeCompare(sum, w.expected) != 1 {
+		return fmt.Errorf("%w: sha256 %x, expected %x", ErrPlaintextMismatch, sum, w.expected)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package tlock_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDigestWriter(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+
+	var cipherData bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&cipherData, bytes.NewReader(dataFile), 1000))
+
+	expected := sha256.Sum256(dataFile)
+	var plainData bytes.Buffer
+	dw := tlock.NewDigestWriter(&plainData, expected[:])
+	require.NoError(t, tlock.New(network).Decrypt(dw, bytes.NewReader(cipherData.Bytes())))
+	require.NoError(t, dw.Verify())
+	require.Equal(t, dataFile, plainData.Bytes())
+
+	other := sha256.Sum256([]byte("other"))
+	dw = tlock.NewDigestWriter(&plainData, other[:])
+	require.NoError(t, tlock.New(network).Decrypt(dw, bytes.NewReader(cipherData.Bytes())))
+	require.ErrorIs(t, dw.Verify(), tlock.ErrPlaintextMismatch)
+}
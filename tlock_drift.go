@@ -0,0 +1,67 @@
+package tlock
+
+import (
+	"fmt"
+	"time"
+
+	chain "github.com/drand/drand/v2/common"
+	dchain "github.com/drand/drand/v2/common/chain"
+)
+
+// LongLockThreshold is the lock duration beyond which the drift of the unlock
+// time is worth warning about.
+const LongLockThreshold = 365 * 24 * time.Hour
+
+// AssumedAvailability is the share of the time the chain is assumed to emit
+// its rounds over long durations, its outages being caught up once it
+// recovers.
+const AssumedAvailability = 0.999
+
+// DriftAnalysis describes the uncertainty on the time a round far in the
+// future becomes decryptable. The round to time mapping of a chain is fixed,
+// hence rounds are never emitted early, but the chain may be halted when the
+// round is due, in which case the round is only emitted once it catches up.
+type DriftAnalysis struct {
+	Round     uint64
+	Scheduled time.Time     // The time the round is due at.
+	Duration  time.Duration // From the time of the analysis to Scheduled.
+	Latest    time.Time     // The latest time the round is expected at.
+
+	// Assumptions lists what the analysis relies on, for display.
+	Assumptions []string
+}
+
+// AnalyzeDrift returns the drift analysis of the round of the chain, from
+// now. The latest unlock time allows for the outages the chain may suffer in
+// the meantime, given AssumedAvailability, to all happen when the round is
+// due, on top of the margin of EstimateUnlock.
+func AnalyzeDrift(info *dchain.Info, roundNumber uint64, now time.Time) DriftAnalysis {
+	_, _, latest := EstimateUnlock(info, roundNumber)
+	scheduled := time.Unix(chain.TimeOfRound(info.Period, info.GenesisTime, roundNumber), 0)
+	duration := scheduled.Sub(now)
+	if duration > 0 {
+		latest = latest.Add(time.Duration(float64(duration) * (1 - AssumedAvailability)).Round(time.Second))
+	}
+
+	return DriftAnalysis{
+		Round:     roundNumber,
+		Scheduled: scheduled,
+		Duration:  duration,
+		Latest:    latest,
+		Assumptions: []string{
+			fmt.Sprintf("the chain keeps its genesis time and its period of %s", info.Period),
+			fmt.Sprintf("the chain emits its rounds %.1f%% of the time and catches up after its outages", AssumedAvailability*100),
+			"the chain and a relay serving it still run when the round is due",
+		},
+	}
+}
+
+// Long reports whether the duration exceeds LongLockThreshold.
+func (a DriftAnalysis) Long() bool {
+	return a.Duration > LongLockThreshold
+}
+
+// Uncertainty returns how late after its scheduled time the round may unlock.
+func (a DriftAnalysis) Uncertainty() time.Duration {
+	return a.Latest.Sub(a.Scheduled)
+}
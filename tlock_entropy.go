@@ -0,0 +1,158 @@
+package tlock
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+
+	"github.com/drand/kyber/encrypt/ibe"
+)
+
+// ErrUnhealthyRandom is returned by CheckRandom when a randomness source fails
+// its health check.
+var ErrUnhealthyRandom = errors.New("randomness source failed its health check")
+
+// These constants define the samples CheckRandom reads from a source.
+const (
+	healthSamples    = 8
+	healthSampleSize = 32
+)
+
+// WithRandom returns a tlock whose Encrypt draws its randomness from r rather
+// than from crypto/rand, for the environments mandating a certified random
+// bit generator: the file key, the payload nonce, the random element of the
+// identity based encryption and the salt of the passphrase. SealMessage,
+// EncryptJWE and the NewDecoyWriter and NewShareWriter methods draw theirs
+// from r too. Sources should pass CheckRandom once, when the program starts.
+// The payload is written as age writes it, streamed rather than held in
+// memory.
+func (t Tlock) WithRandom(r io.Reader) Tlock {
+	t.random = r
+	return t
+}
+
+// CheckRandom reads samples from the randomness source, failing with
+// ErrUnhealthyRandom when it can't be read, repeats a sample, is stuck on a
+// byte value or is grossly biased. It catches broken sources, such as a
+// device returning zeros or a generator reseeded with a constant, rather than
+// certifying the quality of their output.
+func CheckRandom(r io.Reader) error {
+	seen := make(map[[healthSampleSize]byte]bool, healthSamples)
+	ones := 0
+	for range healthSamples {
+		var sample [healthSampleSize]byte
+		if _, err := io.ReadFull(r, sample[:]); err != nil {
+			return fmt.Errorf("%w: read: %v", ErrUnhealthyRandom, err)
+		}
+		if seen[sample] {
+			return fmt.Errorf("%w: repeated output", ErrUnhealthyRandom)
+		}
+		seen[sample] = true
+		if bytes.Count(sample[:], sample[:1]) == healthSampleSize {
+			return fmt.Errorf("%w: output stuck at %#x", ErrUnhealthyRandom, sample[0])
+		}
+		for _, b := range sample {
+			ones += bits.OnesCount8(b)
+		}
+	}
+
+	// The ones of unbiased output are within 9 standard deviations of half the
+	// bits, which fails healthy sources with negligible probability.
+	total := healthSamples * healthSampleSize * 8
+	if ones < total*2/5 || ones > total*3/5 {
+		return fmt.Errorf("%w: %d bits of %d set", ErrUnhealthyRandom, ones, total)
+	}
+	return nil
+}
+
+// =============================================================================
+
+// encryptWithRandom encrypts src as Encrypt does, drawing the randomness from
+// the source of the tlock.
+func (t Tlock) encryptWithRandom(dst io.Writer, src io.Reader, roundNumber uint64) error {
+	if t.seed != nil {
+		return fmt.Errorf("%w: a randomness source can't be used along a reproducible seed", ErrNotReproducible)
+	}
+
+	r := Recipient{network: t.network, roundNumber: roundNumber, passphrase: t.passphrase, kdf: t.kdf, namespace: t.namespace, metadata: t.metadata, sealMetadata: t.sealMetadata, random: t.random, puzzle: t.puzzle}
+	fileKey := make([]byte, fileKeySize)
+	nonce := make([]byte, streamNonceSize)
+	r.sigma = make([]byte, fileKeySize)
+	for _, b := range [][]byte{fileKey, nonce, r.sigma} {
+		if _, err := io.ReadFull(t.random, b); err != nil {
+			return fmt.Errorf("read random: %w", err)
+		}
+	}
+
+	stanzas, err := r.Wrap(fileKey)
+	if err != nil {
+		return fmt.Errorf("hybrid encrypt: %w", err)
+	}
+	hdr := Header{Stanzas: stanzas}
+	hdr.MAC = headerMAC(fileKey, &hdr)
+
+	bw := bufio.NewWriter(dst)
+	if err := hdr.Marshal(bw); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+	if _, err := bw.Write(nonce); err != nil {
+		return fmt.Errorf("write nonce: %w", err)
+	}
+
+	aead, err := newChaCha20Poly1305(hkdfKey(fileKey, nonce, "payload"), "the age payload")
+	if err != nil {
+		return err
+	}
+	br := bufio.NewReader(src)
+	chunk := make([]byte, ChunkSize)
+	for index := int64(0); ; index++ {
+		n, err := io.ReadFull(br, chunk)
+		var last bool
+		switch {
+		case err == nil:
+			// A full chunk is the last one when nothing follows it.
+			if _, err := br.Peek(1); errors.Is(err, io.EOF) {
+				last = true
+			} else if err != nil {
+				return fmt.Errorf("read: %w", err)
+			}
+		case errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF):
+			last = true
+		default:
+			return fmt.Errorf("read: %w", err)
+		}
+		if _, err := bw.Write(sealChunk(aead, chunk[:n], index, last)); err != nil {
+			return fmt.Errorf("write: %w", err)
+		}
+		if last {
+			break
+		}
+	}
+
+	return bw.Flush()
+}
+
+// timeLock encrypts the data towards the round as TimeLock does, drawing the
+// random element of the encryption from the source of the tlock, if any.
+func (t Tlock) timeLock(roundNumber uint64, data []byte) (*ibe.Ciphertext, error) {
+	if t.random == nil {
+		return TimeLock(t.network.Scheme(), t.network.PublicKey(), roundNumber, data)
+	}
+	sigma := make([]byte, len(data))
+	if _, err := io.ReadFull(t.random, sigma); err != nil {
+		return nil, fmt.Errorf("read random: %w", err)
+	}
+	return timeLockWithSigma(t.network.Scheme(), t.network.PublicKey(), roundNumber, data, sigma)
+}
+
+// randomReader returns the source of randomness, crypto/rand when nil.
+func randomReader(r io.Reader) io.Reader {
+	if r == nil {
+		return rand.Reader
+	}
+	return r
+}
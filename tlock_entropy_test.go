@@ -0,0 +1,122 @@
+package tlock_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+// counterRandom is a deterministic source standing for a certified generator.
+type counterRandom struct {
+	seed    string
+	counter uint64
+	buf     []byte
+}
+
+func (r *counterRandom) Read(p []byte) (int, error) {
+	for len(r.buf) < len(p) {
+		h := sha256.Sum256(binary.BigEndian.AppendUint64([]byte(r.seed), r.counter))
+		r.counter++
+		r.buf = append(r.buf, h[:]...)
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func TestWithRandom(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 50)
+
+	for _, size := range []int{0, 100, tlock.ChunkSize, 2*tlock.ChunkSize + 1} {
+		plaintext := make([]byte, size)
+		_, err := rand.Read(plaintext)
+		require.NoError(t, err)
+
+		// All the randomness is drawn from the source.
+		encrypt := func() []byte {
+			tl := tlock.New(network).WithRandom(&counterRandom{seed: "drbg"}).WithPassphrase("correct horse", tlock.KDFParams{Time: 1, Memory: 64, Threads: 1})
+			var cipherData bytes.Buffer
+			require.NoError(t, tl.Encrypt(&cipherData, bytes.NewReader(plaintext), 50))
+			return cipherData.Bytes()
+		}
+		cipherData := encrypt()
+		require.Equal(t, cipherData, encrypt())
+
+		var plainData bytes.Buffer
+		require.NoError(t, tlock.New(network).WithPassphrase("correct horse", tlock.KDFParams{}).Decrypt(&plainData, bytes.NewReader(cipherData)))
+		require.True(t, bytes.Equal(plaintext, plainData.Bytes()))
+	}
+
+	var cipherData bytes.Buffer
+	err := tlock.New(network).WithRandom(io.LimitReader(rand.Reader, 20)).Encrypt(&cipherData, bytes.NewReader(loremBytes), 50)
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestWithRandomPayloads(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 50)
+	tl := func() tlock.Tlock { return tlock.New(network).WithRandom(&counterRandom{seed: "drbg"}) }
+
+	// Each payload draws all its randomness from the source.
+	seal := func() []byte {
+		payload, err := tl().SealMessage([]byte("hello"), 50)
+		require.NoError(t, err)
+		return payload
+	}
+	require.Equal(t, seal(), seal())
+
+	jwe := func() string {
+		s, err := tl().EncryptJWE([]byte("hello"), 50)
+		require.NoError(t, err)
+		return s
+	}
+	require.Equal(t, jwe(), jwe())
+
+	decoy := func() []byte {
+		var out bytes.Buffer
+		w, err := tl().NewDecoyWriter(&out, "hint")
+		require.NoError(t, err)
+		_, err = w.Write(loremBytes)
+		require.NoError(t, err)
+		return out.Bytes()
+	}
+	require.Equal(t, decoy(), decoy())
+
+	shares := func() [][]byte {
+		outs := make([]bytes.Buffer, 3)
+		w, err := tl().NewShareWriter([]io.Writer{&outs[0], &outs[1], &outs[2]}, 2)
+		require.NoError(t, err)
+		_, err = w.Write(loremBytes)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		return [][]byte{outs[0].Bytes(), outs[1].Bytes(), outs[2].Bytes()}
+	}
+	require.Equal(t, shares(), shares())
+
+	_, err := tlock.New(network).WithRandom(io.LimitReader(rand.Reader, 20)).SealMessage([]byte("hello"), 50)
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestCheckRandom(t *testing.T) {
+	require.NoError(t, tlock.CheckRandom(rand.Reader))
+	require.NoError(t, tlock.CheckRandom(&counterRandom{seed: "drbg"}))
+
+	for name, r := range map[string]io.Reader{
+		"zeros":    bytes.NewReader(make([]byte, 1024)),
+		"short":    io.LimitReader(rand.Reader, 100),
+		"repeated": bytes.NewReader(bytes.Repeat(sha256.New().Sum(nil), 32)),
+		"biased":   bytes.NewReader(bytes.Repeat([]byte{0xff, 0xfe, 0xfd, 0xfb, 0xf7, 0xef, 0xdf, 0xbf, 0x7f}, 128)),
+	} {
+		require.ErrorIs(t, tlock.CheckRandom(r), tlock.ErrUnhealthyRandom, name)
+	}
+}
@@ -0,0 +1,85 @@
+package tlock
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrMalformedEnvelope is returned when a JSON envelope can't be decoded.
+var ErrMalformedEnvelope = errors.New("malformed tlock json envelope")
+
+// EnvelopeVersion is the schema version of the JSON envelopes produced.
+const EnvelopeVersion = 1
+
+// Envelope is the JSON representation of a ciphertext, meant to be stored as
+// a value by infrastructure as code tooling. The round and chain hash are
+// informative only, the ciphertext being authoritative.
+type Envelope struct {
+	Version    int    `json:"version"`
+	Round      uint64 `json:"round"`
+	ChainHash  string `json:"chain_hash"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// NewEnvelopeWriter returns a writer encoding the binary ciphertext written to
+// it as a JSON envelope, with the ciphertext base64 encoded. The ciphertext is
+// buffered and encoded on Close.
+func NewEnvelopeWriter(dst io.Writer) io.WriteCloser {
+	return &envelopeWriter{dst: dst}
+}
+
+// NewEnvelopeReader returns a reader over the binary ciphertext held by the
+// JSON envelope in src.
+func NewEnvelopeReader(src io.Reader) io.Reader {
+	return &envelopeReader{src: src}
+}
+
+type envelopeWriter struct {
+	dst io.Writer
+	buf bytes.Buffer
+}
+
+func (w *envelopeWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func (w *envelopeWriter) Close() error {
+	hdr, err := ReadHeader(bufio.NewReader(bytes.NewReader(w.buf.Bytes())))
+	if err != nil {
+		return err
+	}
+	roundNumber, chainHash, err := hdr.Round()
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(w.dst).Encode(Envelope{
+		Version:    EnvelopeVersion,
+		Round:      roundNumber,
+		ChainHash:  chainHash,
+		Ciphertext: w.buf.Bytes(),
+	})
+}
+
+type envelopeReader struct {
+	src io.Reader
+	r   io.Reader
+}
+
+func (r *envelopeReader) Read(p []byte) (int, error) {
+	if r.r == nil {
+		var env Envelope
+		if err := json.NewDecoder(r.src).Decode(&env); err != nil {
+			return 0, fmt.Errorf("%w: %w", ErrMalformedEnvelope, err)
+		}
+		if env.Version != EnvelopeVersion {
+			return 0, fmt.Errorf("%w: unsupported version %d", ErrMalformedEnvelope, env.Version)
+		}
+		r.r = bytes.NewReader(env.Ciphertext)
+	}
+	return r.r.Read(p)
+}
@@ -0,0 +1,42 @@
+package tlock_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+
+	var envelope bytes.Buffer
+	w := tlock.NewEnvelopeWriter(&envelope)
+	require.NoError(t, tlock.New(network).Encrypt(w, bytes.NewReader(dataFile), 1000))
+	require.NoError(t, w.Close())
+
+	var env tlock.Envelope
+	require.NoError(t, json.Unmarshal(envelope.Bytes(), &env))
+	require.Equal(t, tlock.EnvelopeVersion, env.Version)
+	require.Equal(t, uint64(1000), env.Round)
+	require.Equal(t, network.ChainHash(), env.ChainHash)
+
+	var plainData bytes.Buffer
+	require.NoError(t, tlock.New(network).Decrypt(&plainData, tlock.NewEnvelopeReader(bytes.NewReader(envelope.Bytes()))))
+	require.Equal(t, dataFile, plainData.Bytes())
+
+	// Decrypt detects the envelope on its own.
+	plainData.Reset()
+	require.NoError(t, tlock.New(network).Decrypt(&plainData, bytes.NewReader(envelope.Bytes())))
+	require.Equal(t, dataFile, plainData.Bytes())
+
+	future := strings.Replace(envelope.String(), `"version":1`, `"version":2`, 1)
+	err := tlock.New(network).Decrypt(&plainData, strings.NewReader(future))
+	require.ErrorIs(t, err, tlock.ErrMalformedEnvelope)
+}
@@ -0,0 +1,28 @@
+package tlock
+
+import (
+	"time"
+
+	chain "github.com/drand/drand/v2/common"
+	dchain "github.com/drand/drand/v2/common/chain"
+)
+
+// These constants define the lag between the scheduled time of a round and
+// its beacon being available from the relays.
+const (
+	expectedPublicationLag = time.Second
+	maxPublicationLag      = 5 * time.Second
+)
+
+// EstimateUnlock returns the window in which the round is expected to become
+// decryptable. The earliest time is the one the round is scheduled at, no
+// beacon being ever emitted before. The expected time accounts for the usual
+// relay publication lag. The latest time additionally allows for the round to
+// be emitted one period late, as happens when the network catches up after
+// missing rounds. Longer network outages aren't accounted for.
+func EstimateUnlock(info *dchain.Info, roundNumber uint64) (earliest, expected, latest time.Time) {
+	earliest = time.Unix(chain.TimeOfRound(info.Period, info.GenesisTime, roundNumber), 0)
+	expected = earliest.Add(expectedPublicationLag)
+	latest = earliest.Add(info.Period + maxPublicationLag)
+	return earliest, expected, latest
+}
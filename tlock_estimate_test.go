@@ -0,0 +1,37 @@
+package tlock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	dchain "github.com/drand/drand/v2/common/chain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateUnlock(t *testing.T) {
+	info := &dchain.Info{Period: 3 * time.Second, GenesisTime: 1692803367}
+
+	earliest, expected, latest := tlock.EstimateUnlock(info, 11)
+	require.Equal(t, time.Unix(1692803367+30, 0), earliest)
+	require.True(t, earliest.Before(expected))
+	require.True(t, expected.Before(latest))
+	require.GreaterOrEqual(t, latest.Sub(earliest), info.Period)
+}
+
+func TestAnalyzeDrift(t *testing.T) {
+	info := &dchain.Info{Period: 3 * time.Second, GenesisTime: 1692803367}
+	now := time.Unix(1692803367, 0)
+
+	short := tlock.AnalyzeDrift(info, 20*60*24+1, now)
+	require.Equal(t, 24*time.Hour, short.Duration)
+	require.False(t, short.Long())
+	require.Less(t, short.Uncertainty(), 2*time.Minute)
+
+	// Two years of 3 seconds rounds.
+	long := tlock.AnalyzeDrift(info, 2*365*24*60*20+1, now)
+	require.True(t, long.Long())
+	require.Equal(t, now.Add(long.Duration), long.Scheduled)
+	require.Greater(t, long.Uncertainty(), 17*time.Hour)
+	require.NotEmpty(t, long.Assumptions)
+}
@@ -0,0 +1,28 @@
+package tlock
+
+import (
+	"fmt"
+	"io"
+)
+
+// FileIDSize is the size of the identifiers of ciphertexts.
+const FileIDSize = streamNonceSize
+
+// FileID returns the identifier of the ciphertext in src, which may be
+// binary, age armored, PEM encoded or in a JSON envelope, without decrypting
+// it. The identifier is the random nonce following the header, drawn for each
+// file. The key of the chunks of the payload is derived from it and the file
+// key, binding every chunk to its file, so that chunks can't be spliced
+// between two files even when locked to the same round. It is public, and the
+// same for all the encodings of a ciphertext, correlating their copies.
+func FileID(src io.Reader) ([]byte, error) {
+	br := dearmor(src)
+	if _, err := ReadHeader(br); err != nil {
+		return nil, err
+	}
+	id := make([]byte, FileIDSize)
+	if _, err := io.ReadFull(br, id); err != nil {
+		return nil, fmt.Errorf("%w: read nonce: %w", ErrMalformedPayload, err)
+	}
+	return id, nil
+}
@@ -0,0 +1,48 @@
+package tlock_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileID(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+	plaintext := bytes.Repeat([]byte("tlock"), 2*tlock.ChunkSize/5+1)
+
+	var a, b, armored bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&a, bytes.NewReader(plaintext), 1000))
+	require.NoError(t, tlock.New(network).Encrypt(&b, bytes.NewReader(plaintext), 1000))
+	w := tlock.NewArmorWriter(&armored)
+	_, err := w.Write(a.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	idA, err := tlock.FileID(bytes.NewReader(a.Bytes()))
+	require.NoError(t, err)
+	require.Len(t, idA, tlock.FileIDSize)
+	idB, err := tlock.FileID(bytes.NewReader(b.Bytes()))
+	require.NoError(t, err)
+	require.NotEqual(t, idA, idB)
+	idArmored, err := tlock.FileID(&armored)
+	require.NoError(t, err)
+	require.Equal(t, idA, idArmored)
+
+	// The chunks of files locked to the same round can't be spliced, their
+	// keys being derived from the identifiers of the files.
+	payloadSize := len(plaintext) + 16*(len(plaintext)/tlock.ChunkSize+1)
+	firstChunk := func(ciphertext []byte) []byte {
+		return ciphertext[len(ciphertext)-payloadSize:][:tlock.ChunkSize+16]
+	}
+	spliced := bytes.Clone(a.Bytes())
+	copy(firstChunk(spliced), firstChunk(b.Bytes()))
+	require.Error(t, tlock.New(network).Decrypt(&bytes.Buffer{}, bytes.NewReader(spliced)))
+
+	_, err = tlock.FileID(bytes.NewReader([]byte("garbage")))
+	require.ErrorIs(t, err, tlock.ErrMalformedHeader)
+}
@@ -0,0 +1,10 @@
+//go:build fips
+
+package tlock
+
+// FIPSMode reports whether the package is built with the fips tag, in which
+// its symmetric layer only selects FIPS approved algorithms: AES-GCM, SHA-256
+// and HKDF. The operations requiring others, such as the age payload sealed
+// with ChaCha20-Poly1305 or passphrases stretched with argon2id, fail with
+// ErrNotApproved, while messages are sealed with AES-256-GCM by default.
+const FIPSMode = true
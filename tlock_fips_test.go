@@ -0,0 +1,38 @@
+//go:build fips
+
+package tlock_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFIPSMode(t *testing.T) {
+	require.True(t, tlock.FIPSMode)
+
+	network := fixedtest.NewNetwork(t, 50)
+
+	// Messages are sealed with AES-256-GCM.
+	payload, err := tlock.New(network).SealMessage(loremBytes[:100], 50)
+	require.NoError(t, err)
+	plaintext, err := tlock.New(network).OpenMessage(payload)
+	require.NoError(t, err)
+	require.Equal(t, loremBytes[:100], plaintext)
+
+	jwe, err := tlock.New(network).EncryptJWE(loremBytes[:100], 50)
+	require.NoError(t, err)
+	plaintext, err = tlock.New(network).DecryptJWE([]byte(jwe))
+	require.NoError(t, err)
+	require.Equal(t, loremBytes[:100], plaintext)
+
+	// The algorithms which aren't approved fail clearly.
+	_, err = tlock.New(network).WithCipher(tlock.CipherChaCha20Poly1305).SealMessage(loremBytes[:100], 50)
+	require.ErrorIs(t, err, tlock.ErrNotApproved)
+	var cipherData bytes.Buffer
+	require.ErrorIs(t, tlock.New(network).Encrypt(&cipherData, bytes.NewReader(loremBytes), 50), tlock.ErrNotApproved)
+	require.ErrorIs(t, tlock.New(network).Decrypt(&cipherData, bytes.NewReader([]byte("age-encryption.org/v1\n"))), tlock.ErrNotApproved)
+}
@@ -0,0 +1,125 @@
+package tlock
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// Encoder encrypts src to dst towards the round number, encoding the result in
+// the format it is registered for.
+type Encoder func(t Tlock, dst io.Writer, src io.Reader, roundNumber uint64) error
+
+// Decoder decrypts src, encoded in the format it is registered for, to dst.
+type Decoder func(t Tlock, dst io.Writer, src io.Reader) error
+
+type format struct {
+	enc Encoder
+	dec Decoder
+}
+
+var (
+	formatsMu sync.RWMutex
+	formats   = map[string]format{
+		"binary":   {encodeWith(nil), decodeWith(nil)},
+		"age":      {encodeWith(nil), decodeWith(nil)},
+		"armor":    {encodeWith(func(dst io.Writer, _ Network) io.WriteCloser { return NewArmorWriter(dst) }), decodeWith(NewArmorReader)},
+		"pem":      {encodeWith(NewPEMWriter), decodeWith(NewPEMReader)},
+		"cms":      {encodeWith(func(dst io.Writer, _ Network) io.WriteCloser { return NewCMSWriter(dst) }), decodeWith(NewCMSReader)},
+		"envelope": {encodeWith(func(dst io.Writer, _ Network) io.WriteCloser { return NewEnvelopeWriter(dst) }), decodeWith(NewEnvelopeReader)},
+		"jwe":      {encodeJWE, decodeJWE},
+	}
+)
+
+// RegisterEncoder registers the encoder and decoder of the format with the
+// given name, replacing any previously registered one. The binary format, and
+// its age alias since tlock ciphertexts are age files, armor, pem, cms,
+// envelope and jwe are registered by default.
+func RegisterEncoder(name string, enc Encoder, dec Decoder) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	formats[name] = format{enc: enc, dec: dec}
+}
+
+// LookupEncoder returns the encoder and decoder registered for the format.
+func LookupEncoder(name string) (Encoder, Decoder, error) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+
+	f, ok := formats[name]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown format %q", name)
+	}
+	return f.enc, f.dec, nil
+}
+
+// Formats returns the sorted names of the registered formats.
+func Formats() []string {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// =============================================================================
+
+// encodeWith returns the encoder writing the binary ciphertext through the
+// writer returned by wrap, or as is when wrap is nil.
+func encodeWith(wrap func(dst io.Writer, network Network) io.WriteCloser) Encoder {
+	return func(t Tlock, dst io.Writer, src io.Reader, roundNumber uint64) error {
+		if wrap == nil {
+			return t.Encrypt(dst, src, roundNumber)
+		}
+		w := wrap(dst, t.network)
+		if err := t.Encrypt(w, src, roundNumber); err != nil {
+			return err
+		}
+		return w.Close()
+	}
+}
+
+// decodeWith returns the decoder reading the binary ciphertext through the
+// reader returned by unwrap, or as is when unwrap is nil.
+func decodeWith(unwrap func(src io.Reader) io.Reader) Decoder {
+	return func(t Tlock, dst io.Writer, src io.Reader) error {
+		if unwrap != nil {
+			src = unwrap(src)
+		}
+		return t.Decrypt(dst, src)
+	}
+}
+
+// encodeJWE encrypts src into a JWE in the compact serialization, on a line.
+func encodeJWE(t Tlock, dst io.Writer, src io.Reader, roundNumber uint64) error {
+	plaintext, err := io.ReadAll(src)
+	if err != nil {
+		return err
+	}
+	jwe, err := t.EncryptJWE(plaintext, roundNumber)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(dst, jwe+"\n")
+	return err
+}
+
+// decodeJWE decrypts a JWE in either serialization.
+func decodeJWE(t Tlock, dst io.Writer, src io.Reader) error {
+	jwe, err := io.ReadAll(src)
+	if err != nil {
+		return err
+	}
+	plaintext, err := t.DecryptJWE(bytes.TrimSpace(jwe))
+	if err != nil {
+		return err
+	}
+	_, err = dst.Write(plaintext)
+	return err
+}
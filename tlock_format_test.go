@@ -0,0 +1,81 @@
+package tlock_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatsRoundTrip(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+
+	for _, name := range []string{"binary", "age", "armor", "pem", "cms", "envelope", "jwe"} {
+		t.Run(name, func(t *testing.T) {
+			enc, dec, err := tlock.LookupEncoder(name)
+			require.NoError(t, err)
+
+			var ciphertext bytes.Buffer
+			require.NoError(t, enc(tlock.New(network), &ciphertext, bytes.NewReader(loremBytes), 1000))
+
+			var plainData bytes.Buffer
+			require.NoError(t, dec(tlock.New(network), &plainData, &ciphertext))
+			require.Equal(t, loremBytes, plainData.Bytes())
+		})
+	}
+}
+
+func TestRegisterEncoder(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	_, _, err := tlock.LookupEncoder("rot13")
+	require.Error(t, err)
+
+	rot13 := func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return 'a' + (r-'a'+13)%26
+		case r >= 'A' && r <= 'Z':
+			return 'A' + (r-'A'+13)%26
+		}
+		return r
+	}
+	armor, dearmor, err := tlock.LookupEncoder("armor")
+	require.NoError(t, err)
+	tlock.RegisterEncoder("rot13",
+		func(tl tlock.Tlock, dst io.Writer, src io.Reader, roundNumber uint64) error {
+			var buf bytes.Buffer
+			if err := armor(tl, &buf, src, roundNumber); err != nil {
+				return err
+			}
+			_, err := io.WriteString(dst, strings.Map(rot13, buf.String()))
+			return err
+		},
+		func(tl tlock.Tlock, dst io.Writer, src io.Reader) error {
+			b, err := io.ReadAll(src)
+			if err != nil {
+				return err
+			}
+			return dearmor(tl, dst, strings.NewReader(strings.Map(rot13, string(b))))
+		},
+	)
+	require.Contains(t, tlock.Formats(), "rot13")
+
+	network := fixedtest.NewNetwork(t, 1000)
+	enc, dec, err := tlock.LookupEncoder("rot13")
+	require.NoError(t, err)
+
+	var ciphertext bytes.Buffer
+	require.NoError(t, enc(tlock.New(network), &ciphertext, bytes.NewReader(loremBytes), 1000))
+	require.True(t, strings.HasPrefix(ciphertext.String(), "-----ORTVA NTR RAPELCGRQ SVYR-----"))
+
+	var plainData bytes.Buffer
+	require.NoError(t, dec(tlock.New(network), &plainData, &ciphertext))
+	require.Equal(t, loremBytes, plainData.Bytes())
+}
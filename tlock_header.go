@@ -0,0 +1,208 @@
+package tlock
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// ErrMalformedHeader is returned when a ciphertext header can't be parsed.
+var ErrMalformedHeader = errors.New("malformed age header")
+
+// ErrNoTlockStanza is returned when a header has no tlock stanza.
+var ErrNoTlockStanza = errors.New("no tlock stanza in header")
+
+// These constants define the textual elements of an age header.
+const (
+	headerIntro    = "age-encryption.org/v1\n"
+	stanzaPrefix   = "->"
+	footerPrefix   = "---"
+	columnsPerLine = 64
+	bytesPerLine   = columnsPerLine / 4 * 3
+	headerMACSize  = 32
+)
+
+var b64 = base64.RawStdEncoding.Strict()
+
+// Header represents the header of an age encrypted tlock ciphertext, which is
+// everything up to the start of the encrypted payload.
+type Header struct {
+	Stanzas []*age.Stanza
+	MAC     []byte
+}
+
+// ReadHeader parses the header at the start of the binary ciphertext in src,
+// leaving src positioned at the start of the payload.
+func ReadHeader(src *bufio.Reader) (*Header, error) {
+	line, err := src.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("%w: read intro: %w", ErrMalformedHeader, err)
+	}
+	if line != headerIntro {
+		return nil, fmt.Errorf("%w: unexpected intro %q", ErrMalformedHeader, line)
+	}
+
+	var h Header
+	for {
+		line, err := src.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("%w: read line: %w", ErrMalformedHeader, err)
+		}
+		prefix, args := splitHeaderLine(line)
+
+		switch prefix {
+		case footerPrefix:
+			if len(args) != 1 {
+				return nil, fmt.Errorf("%w: malformed closing line %q", ErrMalformedHeader, line)
+			}
+			mac, err := b64.DecodeString(args[0])
+			if err != nil || len(mac) != headerMACSize {
+				return nil, fmt.Errorf("%w: malformed mac %q", ErrMalformedHeader, args[0])
+			}
+			h.MAC = mac
+			return &h, nil
+
+		case stanzaPrefix:
+			if len(args) < 1 {
+				return nil, fmt.Errorf("%w: malformed stanza %q", ErrMalformedHeader, line)
+			}
+			for _, arg := range args {
+				if !validHeaderArg(arg) {
+					return nil, fmt.Errorf("%w: malformed stanza %q", ErrMalformedHeader, line)
+				}
+			}
+			body, err := readStanzaBody(src)
+			if err != nil {
+				return nil, err
+			}
+			h.Stanzas = append(h.Stanzas, &age.Stanza{Type: args[0], Args: args[1:], Body: body})
+
+		default:
+			return nil, fmt.Errorf("%w: unexpected line %q", ErrMalformedHeader, line)
+		}
+	}
+}
+
+// ParseHeader parses the header of the ciphertext in src, which may be binary,
+// age armored, PEM encoded or in a JSON envelope.
+func ParseHeader(src io.Reader) (*Header, error) {
+	return ReadHeader(dearmor(src))
+}
+
+// MarshalWithoutMAC writes the header up to and including the footer prefix,
+// which is the part of the header covered by the MAC.
+func (h *Header) MarshalWithoutMAC(w io.Writer) error {
+	var buf bytes.Buffer
+	buf.WriteString(headerIntro)
+	for _, s := range h.Stanzas {
+		buf.WriteString(stanzaPrefix)
+		for _, arg := range append([]string{s.Type}, s.Args...) {
+			buf.WriteString(" " + arg)
+		}
+		buf.WriteString("\n")
+
+		body := b64.EncodeToString(s.Body)
+		for len(body) >= columnsPerLine {
+			buf.WriteString(body[:columnsPerLine] + "\n")
+			body = body[columnsPerLine:]
+		}
+		buf.WriteString(body + "\n")
+	}
+	buf.WriteString(footerPrefix)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Marshal writes the header in its canonical encoding.
+func (h *Header) Marshal(w io.Writer) error {
+	if err := h.MarshalWithoutMAC(w); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, " "+b64.EncodeToString(h.MAC)+"\n")
+	return err
+}
+
+// Round returns the round number and chainhash of the first tlock stanza.
+func (h *Header) Round() (uint64, string, error) {
+	for _, s := range h.Stanzas {
+		if s.Type != "tlock" || len(s.Args) < 2 {
+			continue
+		}
+		roundNumber, err := strconv.ParseUint(s.Args[0], 10, 64)
+		if err != nil {
+			return 0, "", fmt.Errorf("parse block round: %w", err)
+		}
+		return roundNumber, s.Args[1], nil
+	}
+	return 0, "", ErrNoTlockStanza
+}
+
+// =============================================================================
+
+// readStanzaBody reads the base64 body lines of a stanza, which always end
+// with a short line.
+func readStanzaBody(src *bufio.Reader) ([]byte, error) {
+	var body []byte
+	for {
+		line, err := src.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("%w: read body: %w", ErrMalformedHeader, err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if strings.ContainsRune(line, '\r') || len(line) > columnsPerLine {
+			return nil, fmt.Errorf("%w: malformed body line %q", ErrMalformedHeader, line)
+		}
+		b, err := b64.DecodeString(line)
+		if err != nil {
+			return nil, fmt.Errorf("%w: malformed body line %q", ErrMalformedHeader, line)
+		}
+		body = append(body, b...)
+		if len(b) < bytesPerLine {
+			return body, nil
+		}
+	}
+}
+
+// splitHeaderLine splits a header line into its prefix and arguments.
+func splitHeaderLine(line string) (string, []string) {
+	parts := strings.Split(strings.TrimSuffix(line, "\n"), " ")
+	return parts[0], parts[1:]
+}
+
+// validHeaderArg reports whether s is a valid stanza argument.
+func validHeaderArg(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for _, c := range s {
+		if c < 33 || c > 126 {
+			return false
+		}
+	}
+	return true
+}
+
+// dearmor returns a reader over the binary ciphertext in src, removing the
+// age armor, the tlock PEM encoding or the JSON envelope if there is any.
+func dearmor(src io.Reader) *bufio.Reader {
+	rr := bufio.NewReader(src)
+	if start, _ := rr.Peek(len(armor.Header)); string(start) == armor.Header || strings.HasPrefix(string(start), ArmorHintPrefix) {
+		return bufio.NewReader(NewArmorReader(rr))
+	}
+	if start, _ := rr.Peek(len(pemHeader)); string(start) == pemHeader {
+		return bufio.NewReader(NewPEMReader(rr))
+	}
+	if start, _ := rr.Peek(1); string(start) == "{" {
+		return bufio.NewReader(NewEnvelopeReader(rr))
+	}
+	return rr
+}
@@ -0,0 +1,39 @@
+package tlock_test
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"testing"
+
+	"filippo.io/age/armor"
+	"github.com/JonathanLogan/tlock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadHeader(t *testing.T) {
+	f, err := os.Open("testdata/lorem-tle-testnet-quicknet-t-2024-01-17-15-28.tle")
+	require.NoError(t, err)
+	defer f.Close()
+
+	var ciphertext bytes.Buffer
+	_, err = ciphertext.ReadFrom(armor.NewReader(f))
+	require.NoError(t, err)
+
+	rr := bufio.NewReader(bytes.NewReader(ciphertext.Bytes()))
+	hdr, err := tlock.ReadHeader(rr)
+	require.NoError(t, err)
+
+	roundNumber, chainHash, err := hdr.Round()
+	require.NoError(t, err)
+	require.NotZero(t, roundNumber)
+	require.Equal(t, "cc9c398442737cbd141526600919edd69f1d6f9b4adb67e4d912fbc64341a9a5", chainHash)
+
+	var marshaled bytes.Buffer
+	require.NoError(t, hdr.Marshal(&marshaled))
+	require.True(t, bytes.HasPrefix(ciphertext.Bytes(), marshaled.Bytes()))
+	require.Equal(t, ciphertext.Len()-marshaled.Len(), rr.Buffered())
+
+	_, err = tlock.ReadHeader(bufio.NewReader(bytes.NewReader(loremBytes)))
+	require.ErrorIs(t, err, tlock.ErrMalformedHeader)
+}
@@ -0,0 +1,203 @@
+package tlock
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrMalformedJWE is returned when a JWE can't be parsed or isn't using the
+// tlock algorithm.
+var ErrMalformedJWE = errors.New("malformed tlock jwe")
+
+// These constants define the JOSE algorithms used by tlock JWEs. The content
+// encryption key is timelock encrypted, its size being the one of age file
+// keys, hence AES-128-GCM for the content.
+const (
+	JWEAlgorithm  = "TLOCK-BLS12381"
+	JWEEncryption = "A128GCM"
+)
+
+// These constants define the sizes used by the A128GCM content encryption.
+const (
+	jweKeySize   = 16
+	jweNonceSize = 12
+	jweTagSize   = 16
+)
+
+var b64url = base64.RawURLEncoding
+
+// jweHeader is the JOSE protected header of a tlock JWE. The round and chain
+// hash are private header parameters.
+type jweHeader struct {
+	Algorithm  string `json:"alg"`
+	Encryption string `json:"enc"`
+	Round      uint64 `json:"tlock_round"`
+	ChainHash  string `json:"tlock_chain"`
+}
+
+// jweJSON is the flattened JWE JSON serialization.
+type jweJSON struct {
+	Protected    string `json:"protected"`
+	EncryptedKey string `json:"encrypted_key"`
+	IV           string `json:"iv"`
+	Ciphertext   string `json:"ciphertext"`
+	Tag          string `json:"tag"`
+}
+
+// EncryptJWE encrypts the plaintext into a JWE using the compact serialization.
+// The content encryption key is timelock encrypted towards the round number.
+func (t Tlock) EncryptJWE(plaintext []byte, roundNumber uint64) (string, error) {
+	j, err := t.sealJWE(plaintext, roundNumber)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join([]string{j.Protected, j.EncryptedKey, j.IV, j.Ciphertext, j.Tag}, "."), nil
+}
+
+// EncryptJWEJSON encrypts the plaintext into a JWE using the flattened JSON
+// serialization.
+func (t Tlock) EncryptJWEJSON(plaintext []byte, roundNumber uint64) ([]byte, error) {
+	j, err := t.sealJWE(plaintext, roundNumber)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(j)
+}
+
+// DecryptJWE decrypts a tlock JWE in either the compact or the flattened JSON
+// serialization. The round must have been reached by the network.
+func (t Tlock) DecryptJWE(jwe []byte) ([]byte, error) {
+	var j jweJSON
+	if trimmed := bytes.TrimSpace(jwe); len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, &j); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrMalformedJWE, err)
+		}
+	} else {
+		parts := strings.Split(string(trimmed), ".")
+		if len(parts) != 5 {
+			return nil, fmt.Errorf("%w: expected 5 parts, got %d", ErrMalformedJWE, len(parts))
+		}
+		j = jweJSON{Protected: parts[0], EncryptedKey: parts[1], IV: parts[2], Ciphertext: parts[3], Tag: parts[4]}
+	}
+
+	return t.openJWE(j)
+}
+
+// =============================================================================
+
+// sealJWE performs the encryption and returns the encoded JWE parts.
+func (t Tlock) sealJWE(plaintext []byte, roundNumber uint64) (jweJSON, error) {
+	cek := make([]byte, jweKeySize)
+	if _, err := io.ReadFull(randomReader(t.random), cek); err != nil {
+		return jweJSON{}, fmt.Errorf("read cek: %w", err)
+	}
+
+	ciphertext, err := t.timeLock(roundNumber, cek)
+	if err != nil {
+		return jweJSON{}, fmt.Errorf("encrypt cek: %w", err)
+	}
+	encryptedKey, err := CiphertextToBytes(t.network.Scheme(), ciphertext)
+	if err != nil {
+		return jweJSON{}, fmt.Errorf("bytes: %w", err)
+	}
+
+	header, err := json.Marshal(jweHeader{
+		Algorithm:  JWEAlgorithm,
+		Encryption: JWEEncryption,
+		Round:      roundNumber,
+		ChainHash:  t.network.ChainHash(),
+	})
+	if err != nil {
+		return jweJSON{}, fmt.Errorf("marshal header: %w", err)
+	}
+	protected := b64url.EncodeToString(header)
+
+	aead, err := newJWEAEAD(cek)
+	if err != nil {
+		return jweJSON{}, err
+	}
+	iv := make([]byte, jweNonceSize)
+	if _, err := io.ReadFull(randomReader(t.random), iv); err != nil {
+		return jweJSON{}, fmt.Errorf("read iv: %w", err)
+	}
+	sealed := aead.Seal(nil, iv, plaintext, []byte(protected))
+	body, tag := sealed[:len(sealed)-jweTagSize], sealed[len(sealed)-jweTagSize:]
+
+	return jweJSON{
+		Protected:    protected,
+		EncryptedKey: b64url.EncodeToString(encryptedKey),
+		IV:           b64url.EncodeToString(iv),
+		Ciphertext:   b64url.EncodeToString(body),
+		Tag:          b64url.EncodeToString(tag),
+	}, nil
+}
+
+// openJWE performs the decryption of the encoded JWE parts.
+func (t Tlock) openJWE(j jweJSON) ([]byte, error) {
+	rawHeader, err := b64url.DecodeString(j.Protected)
+	if err != nil {
+		return nil, fmt.Errorf("%w: protected header: %w", ErrMalformedJWE, err)
+	}
+	var header jweHeader
+	if err := json.Unmarshal(rawHeader, &header); err != nil {
+		return nil, fmt.Errorf("%w: protected header: %w", ErrMalformedJWE, err)
+	}
+	if header.Algorithm != JWEAlgorithm || header.Encryption != JWEEncryption {
+		return nil, fmt.Errorf("%w: unsupported alg %q and enc %q", ErrMalformedJWE, header.Algorithm, header.Encryption)
+	}
+
+	var parts [4][]byte
+	for i, s := range []string{j.EncryptedKey, j.IV, j.Ciphertext, j.Tag} {
+		if parts[i], err = b64url.DecodeString(s); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrMalformedJWE, err)
+		}
+	}
+	encryptedKey, iv, body, tag := parts[0], parts[1], parts[2], parts[3]
+	if len(iv) != jweNonceSize || len(tag) != jweTagSize {
+		return nil, fmt.Errorf("%w: invalid iv or tag size", ErrMalformedJWE)
+	}
+	if err := t.checkPlaintextSize(int64(len(body))); err != nil {
+		return nil, err
+	}
+
+	id := Identity{network: t.network, trustChainhash: t.trustChainhash}
+	if !id.useChainHash(header.ChainHash) {
+		return nil, id.wrongChainHash(header.ChainHash)
+	}
+
+	ciphertext, err := BytesToCiphertext(t.network.Scheme(), encryptedKey)
+	if err != nil {
+		return nil, fmt.Errorf("parse cipher cek: %w", err)
+	}
+	cek, err := id.unlock(header.Round, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newJWEAEAD(cek)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, iv, append(body, tag...), []byte(j.Protected))
+	if err != nil {
+		return nil, fmt.Errorf("decrypt content: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+// newJWEAEAD returns the A128GCM AEAD for the content encryption key.
+func newJWEAEAD(cek []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, fmt.Errorf("aes: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,61 @@
+package tlock_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWERoundTrip(t *testing.T) {
+	network := fixedtest.NewNetwork(t, 1000)
+	plaintext := []byte("hello world")
+
+	t.Run("compact serialization", func(t *testing.T) {
+		token, err := tlock.New(network).EncryptJWE(plaintext, 1000)
+		require.NoError(t, err)
+
+		parts := strings.Split(token, ".")
+		require.Len(t, parts, 5)
+		header, err := base64.RawURLEncoding.DecodeString(parts[0])
+		require.NoError(t, err)
+		require.Contains(t, string(header), `"alg":"TLOCK-BLS12381"`)
+		require.Contains(t, string(header), `"tlock_round":1000`)
+
+		decrypted, err := tlock.New(network).DecryptJWE([]byte(token))
+		require.NoError(t, err)
+		require.Equal(t, plaintext, decrypted)
+	})
+
+	t.Run("json serialization", func(t *testing.T) {
+		token, err := tlock.New(network).EncryptJWEJSON(plaintext, 1000)
+		require.NoError(t, err)
+		require.True(t, json.Valid(token))
+
+		decrypted, err := tlock.New(network).DecryptJWE(token)
+		require.NoError(t, err)
+		require.Equal(t, plaintext, decrypted)
+	})
+
+	t.Run("tampered protected header", func(t *testing.T) {
+		token, err := tlock.New(network).EncryptJWE(plaintext, 1000)
+		require.NoError(t, err)
+
+		parts := strings.Split(token, ".")
+		header, err := base64.RawURLEncoding.DecodeString(parts[0])
+		require.NoError(t, err)
+		parts[0] = base64.RawURLEncoding.EncodeToString([]byte(strings.Replace(string(header), `"enc"`, ` "enc"`, 1)))
+
+		_, err = tlock.New(network).DecryptJWE([]byte(strings.Join(parts, ".")))
+		require.Error(t, err)
+	})
+
+	t.Run("wrong algorithm", func(t *testing.T) {
+		_, err := tlock.New(network).DecryptJWE([]byte("eyJhbGciOiJub25lIn0.a.b.c.d"))
+		require.ErrorIs(t, err, tlock.ErrMalformedJWE)
+	})
+}
@@ -0,0 +1,68 @@
+package tlock
+
+import (
+	"fmt"
+
+	"github.com/drand/drand/v2/crypto"
+	"github.com/drand/kyber"
+	"github.com/drand/kyber/encrypt/ibe"
+)
+
+// SignatureOracle provides the signature of the master key of a drand-style
+// network over an identity, which only becomes available once the condition
+// the identity stands for is met. Drand networks are oracles for the digests
+// of their rounds.
+type SignatureOracle interface {
+	Signature(identity []byte) ([]byte, error)
+}
+
+// SignatureOracleFunc adapts a function to the SignatureOracle interface.
+type SignatureOracleFunc func(identity []byte) ([]byte, error)
+
+// Signature calls f(identity).
+func (f SignatureOracleFunc) Signature(identity []byte) ([]byte, error) {
+	return f(identity)
+}
+
+// EncryptToIdentity encrypts the data to an arbitrary identity against the
+// master public key. It generalizes TimeLock, whose identity is the digest of
+// a round, to conditions other than round numbers.
+func EncryptToIdentity(scheme crypto.Scheme, publicKey kyber.Point, identity []byte, data []byte) (*ibe.Ciphertext, error) {
+	if publicKey.Equal(publicKey.Null()) {
+		return nil, ErrInvalidPublicKey
+	}
+
+	suite, err := suiteFor(scheme.Name)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := suite.Encrypt(publicKey, identity, data)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt data: %w", err)
+	}
+
+	return ciphertext, nil
+}
+
+// DecryptWithOracle decrypts the ciphertext encrypted to the identity, using
+// the signature over the identity retrieved from the oracle once verified.
+func DecryptWithOracle(scheme crypto.Scheme, publicKey kyber.Point, identity []byte, ciphertext *ibe.Ciphertext, oracle SignatureOracle) ([]byte, error) {
+	signature, err := oracle.Signature(identity)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTooEarly, err)
+	}
+	if err := scheme.ThresholdScheme.VerifyRecovered(publicKey, identity, signature); err != nil {
+		return nil, fmt.Errorf("verify signature: %w", err)
+	}
+
+	suite, err := suiteFor(scheme.Name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := suite.Decrypt(signature, ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt data: %w", err)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,42 @@
+package tlock_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/drand/drand/v2/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptToIdentity(t *testing.T) {
+	for _, sch := range []*crypto.Scheme{crypto.NewPedersenBLSUnchainedG1(), crypto.NewPedersenBLSUnchained()} {
+		t.Run(sch.Name, func(t *testing.T) {
+			key := fixedtest.NewKey(sch)
+			publicKey := key.PublicKey
+			identity := []byte("election 2028 results published")
+			data := []byte("0123456789abcdef")
+
+			ciphertext, err := tlock.EncryptToIdentity(*sch, publicKey, identity, data)
+			require.NoError(t, err)
+
+			oracle := tlock.SignatureOracleFunc(func(identity []byte) ([]byte, error) {
+				return key.Sign(t, identity), nil
+
+			})
+			plaintext, err := tlock.DecryptWithOracle(*sch, publicKey, identity, ciphertext, oracle)
+			require.NoError(t, err)
+			require.Equal(t, data, plaintext)
+
+			_, err = tlock.DecryptWithOracle(*sch, publicKey, []byte("another identity"), ciphertext, oracle)
+			require.Error(t, err)
+
+			notYet := tlock.SignatureOracleFunc(func([]byte) ([]byte, error) {
+				return nil, errors.New("condition not met")
+			})
+			_, err = tlock.DecryptWithOracle(*sch, publicKey, identity, ciphertext, notYet)
+			require.ErrorIs(t, err, tlock.ErrTooEarly)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package tlock
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrPlaintextTooLarge is returned when a plaintext exceeds the maximum size
+// set with WithMaxPlaintextSize.
+var ErrPlaintextTooLarge = errors.New("plaintext too large")
+
+// WithMaxPlaintextSize returns a tlock aborting decryptions whose plaintext
+// exceeds the size in bytes, protecting services decrypting untrusted
+// ciphertexts into memory. Decrypt writes up to the maximum size before
+// failing, the other decryptions fail before writing anything when the size
+// of the plaintext is known upfront. A size of zero removes the limit.
+func (t Tlock) WithMaxPlaintextSize(size int64) Tlock {
+	t.maxPlaintextSize = size
+	return t
+}
+
+// =============================================================================
+
+// checkPlaintextSize returns an error when the size exceeds the maximum size.
+func (t Tlock) checkPlaintextSize(size int64) error {
+	if t.maxPlaintextSize > 0 && size > t.maxPlaintextSize {
+		return fmt.Errorf("%w: %d bytes, the limit is %d", ErrPlaintextTooLarge, size, t.maxPlaintextSize)
+	}
+	return nil
+}
+
+// limitWriter fails writes past the maximum size, writing what fits.
+type limitWriter struct {
+	w         io.Writer
+	remaining int64
+	max       int64
+}
+
+// limitPlaintext returns a writer to dst enforcing the maximum size.
+func (t Tlock) limitPlaintext(dst io.Writer) io.Writer {
+	if t.maxPlaintextSize <= 0 {
+		return dst
+	}
+	return &limitWriter{w: dst, remaining: t.maxPlaintextSize, max: t.maxPlaintextSize}
+}
+
+func (l *limitWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) <= l.remaining {
+		n, err := l.w.Write(p)
+		l.remaining -= int64(n)
+		return n, err
+	}
+
+	n, err := l.w.Write(p[:l.remaining])
+	l.remaining -= int64(n)
+	if err != nil {
+		return n, err
+	}
+	return n, fmt.Errorf("%w: the limit is %d bytes", ErrPlaintextTooLarge, l.max)
+}
@@ -0,0 +1,43 @@
+package tlock_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxPlaintextSize(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+	plaintext := bytes.Repeat([]byte("a"), 2*tlock.ChunkSize+10)
+
+	var cipherData bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&cipherData, bytes.NewReader(plaintext), 1000))
+	ciphertext := cipherData.Bytes()
+
+	var out bytes.Buffer
+	err := tlock.New(network).WithMaxPlaintextSize(tlock.ChunkSize).Decrypt(&out, bytes.NewReader(ciphertext))
+	require.ErrorIs(t, err, tlock.ErrPlaintextTooLarge)
+	require.Equal(t, tlock.ChunkSize, out.Len())
+
+	out.Reset()
+	require.NoError(t, tlock.New(network).WithMaxPlaintextSize(int64(len(plaintext))).Decrypt(&out, bytes.NewReader(ciphertext)))
+	require.Equal(t, plaintext, out.Bytes())
+
+	out.Reset()
+	err = tlock.New(network).WithMaxPlaintextSize(tlock.ChunkSize).DecryptFrom(&out, bytes.NewReader(ciphertext), 0)
+	require.ErrorIs(t, err, tlock.ErrPlaintextTooLarge)
+	require.Zero(t, out.Len())
+
+	_, err = tlock.New(network).WithMaxPlaintextSize(tlock.ChunkSize).NewRandomAccessDecrypter(bytes.NewReader(ciphertext), int64(len(ciphertext)))
+	require.ErrorIs(t, err, tlock.ErrPlaintextTooLarge)
+
+	jwe, err := tlock.New(network).EncryptJWE(plaintext, 1000)
+	require.NoError(t, err)
+	_, err = tlock.New(network).WithMaxPlaintextSize(100).DecryptJWE([]byte(jwe))
+	require.ErrorIs(t, err, tlock.ErrPlaintextTooLarge)
+}
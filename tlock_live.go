@@ -0,0 +1,323 @@
+package tlock
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// ErrMalformedLive is returned when a live stream can't be parsed.
+var ErrMalformedLive = errors.New("malformed tlock live stream")
+
+// These constants bound the size of the chunks of live streams.
+const (
+	DefaultLiveChunkSize = 4096
+	MaxLiveChunkSize     = ChunkSize
+)
+
+// These constants define the layout of live streams. The header is made of
+// the magic, the ID of the cipher, the round, the start of the chain hash, the
+// chunk size and the IBE ciphertext of the stream key. Each frame then starts
+// with a flags byte, the size of its chunk and its offset from the start of
+// the stream in nanoseconds, authenticated along the sealed chunk. The frames
+// are sealed with a key derived from the stream key and the header, and with
+// their index as nonce, the last one being flagged.
+const (
+	liveMagic         = "tlock-live/v1\n"
+	liveFieldsSize    = 1 + 8 + messageChainHashSize + 4
+	liveFrameSize     = 1 + 4 + 8
+	liveLastFrameFlag = 0x01
+)
+
+// LiveChunk is a chunk of a live stream, with the time it was written at
+// relative to the start of the stream.
+type LiveChunk struct {
+	Offset time.Duration
+	Data   []byte
+}
+
+// NewLiveWriter returns a writer encrypting a live stream towards the round,
+// such as a recording, in chunks of at most chunkSize bytes,
+// DefaultLiveChunkSize when zero. Unlike age, whose chunks are 64 KiB, each
+// write is sealed and written to dst right away, then flushed if dst has a
+// Flush method, so that the stream is readable as it is produced. The
+// writes aren't buffered either: they block until dst accepts them, slowing
+// the producer down to the pace of the consumer. The time of each chunk is
+// recorded for DecryptLive to replay the stream at its original pace. Close
+// must be called to mark the end of the stream, which otherwise reads as
+// truncated.
+func (t Tlock) NewLiveWriter(dst io.Writer, roundNumber uint64, chunkSize int) (io.WriteCloser, error) {
+	if chunkSize == 0 {
+		chunkSize = DefaultLiveChunkSize
+	}
+	if chunkSize < 0 || chunkSize > MaxLiveChunkSize {
+		return nil, fmt.Errorf("invalid live chunk size %d, maximum is %d", chunkSize, MaxLiveChunkSize)
+	}
+	if err := checkUnchained(t.network); err != nil {
+		return nil, err
+	}
+
+	chainHash, err := hex.DecodeString(t.network.ChainHash())
+	if err != nil || len(chainHash) < messageChainHashSize {
+		return nil, fmt.Errorf("invalid chain hash %q", t.network.ChainHash())
+	}
+
+	key := make([]byte, messageKeySize)
+	if _, err := io.ReadFull(randomReader(t.random), key); err != nil {
+		return nil, fmt.Errorf("read key: %w", err)
+	}
+	ciphertext, err := TimeLock(t.network.Scheme(), t.network.PublicKey(), roundNumber, key)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt key: %w", err)
+	}
+	encryptedKey, err := directCiphertextToBytes(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	header := append([]byte(liveMagic), t.cipherID())
+	header = binary.BigEndian.AppendUint64(header, roundNumber)
+	header = append(header, chainHash[:messageChainHashSize]...)
+	header = binary.BigEndian.AppendUint32(header, uint32(chunkSize))
+	header = append(header, encryptedKey...)
+
+	aead, err := liveCipher(t.cipherID(), key, header)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &liveWriter{dst: dst, aead: aead, chunkSize: chunkSize, start: time.Now()}
+	if _, err := dst.Write(header); err != nil {
+		return nil, fmt.Errorf("write: %w", err)
+	}
+	if err := flushLive(dst); err != nil {
+		return nil, fmt.Errorf("flush: %w", err)
+	}
+	return w, nil
+}
+
+// LiveReader reads the chunks of a live stream.
+type LiveReader struct {
+	src       *bufio.Reader
+	aead      cipher.AEAD
+	chunkSize int
+	index     uint64
+	done      bool
+}
+
+// NewLiveReader reads the header of the live stream in src and unlocks its
+// key, which requires its round to have been reached by the network.
+func (t Tlock) NewLiveReader(src io.Reader) (*LiveReader, error) {
+	rr := bufio.NewReader(src)
+	header := make([]byte, len(liveMagic)+liveFieldsSize)
+	if _, err := io.ReadFull(rr, header); err != nil {
+		return nil, fmt.Errorf("%w: truncated header", ErrMalformedLive)
+	}
+	if string(header[:len(liveMagic)]) != liveMagic {
+		return nil, fmt.Errorf("%w: not a live stream", ErrMalformedLive)
+	}
+	fields := header[len(liveMagic):]
+	cipherID := fields[0]
+	roundNumber := binary.BigEndian.Uint64(fields[1:9])
+	streamChainHash := fields[9 : 9+messageChainHashSize]
+	chunkSize := int(binary.BigEndian.Uint32(fields[9+messageChainHashSize:]))
+	if chunkSize == 0 || chunkSize > MaxLiveChunkSize {
+		return nil, fmt.Errorf("%w: chunk size %d", ErrMalformedLive, chunkSize)
+	}
+
+	chainHash, err := hex.DecodeString(t.network.ChainHash())
+	if err != nil || len(chainHash) < messageChainHashSize {
+		return nil, fmt.Errorf("invalid chain hash %q", t.network.ChainHash())
+	}
+	if !bytes.Equal(streamChainHash, chainHash[:messageChainHashSize]) {
+		return nil, fmt.Errorf("%w: live stream for chain %x, network is %s", ErrWrongChainhash, streamChainHash, t.network.ChainHash())
+	}
+
+	encryptedKey := make([]byte, t.network.Scheme().KeyGroup.PointLen()+2*messageKeySize)
+	if _, err := io.ReadFull(rr, encryptedKey); err != nil {
+		return nil, fmt.Errorf("%w: truncated header", ErrMalformedLive)
+	}
+	ciphertext, err := bytesToDirectCiphertext(t.network.Scheme(), encryptedKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedLive, err)
+	}
+	id := Identity{network: t.network}
+	key, err := id.unlock(roundNumber, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := liveCipher(cipherID, key, append(header, encryptedKey...))
+	if err != nil {
+		return nil, err
+	}
+	return &LiveReader{src: rr, aead: aead, chunkSize: chunkSize}, nil
+}
+
+// Next returns the next chunk of the stream, or io.EOF after the last one. A
+// stream ending before its last chunk is reported as malformed.
+func (r *LiveReader) Next() (LiveChunk, error) {
+	for !r.done {
+		prefix := make([]byte, liveFrameSize)
+		if _, err := io.ReadFull(r.src, prefix); err != nil {
+			return LiveChunk{}, fmt.Errorf("%w: truncated at chunk %d", ErrMalformedLive, r.index)
+		}
+		flags := prefix[0]
+		size := int(binary.BigEndian.Uint32(prefix[1:5]))
+		offset := int64(binary.BigEndian.Uint64(prefix[5:]))
+		if flags&^liveLastFrameFlag != 0 || size > r.chunkSize || offset < 0 {
+			return LiveChunk{}, fmt.Errorf("%w: invalid chunk %d", ErrMalformedLive, r.index)
+		}
+
+		sealed := make([]byte, size+r.aead.Overhead())
+		if _, err := io.ReadFull(r.src, sealed); err != nil {
+			return LiveChunk{}, fmt.Errorf("%w: truncated at chunk %d", ErrMalformedLive, r.index)
+		}
+		data, err := r.aead.Open(sealed[:0], liveNonce(r.aead, r.index), sealed, prefix)
+		if err != nil {
+			return LiveChunk{}, fmt.Errorf("%w: chunk %d: %w", ErrMalformedLive, r.index, err)
+		}
+		r.index++
+		r.done = flags&liveLastFrameFlag != 0
+
+		// The last chunk may be empty, marking the end of the stream only.
+		if len(data) > 0 {
+			return LiveChunk{Offset: time.Duration(offset), Data: data}, nil
+		}
+	}
+	return LiveChunk{}, io.EOF
+}
+
+// DecryptLive decrypts the live stream in src to dst, flushing each chunk as
+// NewLiveWriter does. When paced, each chunk is written at its offset from
+// the start of the decryption, replaying the stream at the pace it was
+// recorded at, until the context is done.
+func (t Tlock) DecryptLive(ctx context.Context, dst io.Writer, src io.Reader, paced bool) error {
+	r, err := t.NewLiveReader(src)
+	if err != nil {
+		return err
+	}
+
+	w := t.limitPlaintext(dst)
+	start := time.Now()
+	for {
+		c, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if wait := time.Until(start.Add(c.Offset)); paced && wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+		if _, err := w.Write(c.Data); err != nil {
+			return fmt.Errorf("write: %w", err)
+		}
+		if err := flushLive(dst); err != nil {
+			return fmt.Errorf("flush: %w", err)
+		}
+	}
+}
+
+// =============================================================================
+
+// liveWriter seals the chunks of a live stream.
+type liveWriter struct {
+	dst       io.Writer
+	aead      cipher.AEAD
+	chunkSize int
+	start     time.Time
+	index     uint64
+	err       error
+}
+
+func (w *liveWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	var n int
+	for len(p) > 0 {
+		chunk := p[:min(len(p), w.chunkSize)]
+		if err := w.writeFrame(chunk, 0); err != nil {
+			w.err = err
+			return n, err
+		}
+		n += len(chunk)
+		p = p[len(chunk):]
+	}
+	return n, nil
+}
+
+// Close writes the last frame, empty, marking the end of the stream.
+func (w *liveWriter) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	err := w.writeFrame(nil, liveLastFrameFlag)
+	w.err = errors.New("live stream closed")
+	return err
+}
+
+// writeFrame seals the chunk and writes it to the destination, flushing it.
+func (w *liveWriter) writeFrame(chunk []byte, flags byte) error {
+	frame := []byte{flags}
+	frame = binary.BigEndian.AppendUint32(frame, uint32(len(chunk)))
+	frame = binary.BigEndian.AppendUint64(frame, uint64(time.Since(w.start)))
+	frame = w.aead.Seal(frame, liveNonce(w.aead, w.index), chunk, frame[:liveFrameSize])
+	w.index++
+
+	if _, err := w.dst.Write(frame); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	if err := flushLive(w.dst); err != nil {
+		return fmt.Errorf("flush: %w", err)
+	}
+	return nil
+}
+
+// liveCipher returns the AEAD of the cipher keyed for the stream of the
+// header.
+func liveCipher(id byte, key []byte, header []byte) (cipher.AEAD, error) {
+	streamKey := make([]byte, CipherKeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key, header, []byte("tlock live")), streamKey); err != nil {
+		return nil, err
+	}
+	return newCipher(id, streamKey)
+}
+
+// liveNonce returns the nonce of the frame of the index.
+func liveNonce(aead cipher.AEAD, index uint64) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], index)
+	return nonce
+}
+
+// flushLive flushes the writer if it buffers its writes, as bufio.Writer and
+// http.ResponseWriter do.
+func flushLive(w io.Writer) error {
+	switch f := w.(type) {
+	case interface{ Flush() error }:
+		return f.Flush()
+	case interface{ Flush() }:
+		f.Flush()
+	}
+	return nil
+}
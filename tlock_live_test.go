@@ -0,0 +1,104 @@
+package tlock_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+// flushCounter records the writes and flushes of a live stream.
+type flushCounter struct {
+	bytes.Buffer
+	flushed int
+}
+
+func (f *flushCounter) Flush() error {
+	f.flushed++
+	return nil
+}
+
+func TestLive(t *testing.T) {
+	network := fixedtest.NewNetwork(t, 1000)
+
+	var stream flushCounter
+	w, err := tlock.New(network).NewLiveWriter(&stream, 1000, 16)
+	require.NoError(t, err)
+	require.Equal(t, 1, stream.flushed)
+
+	// Each write is written out right away, in chunks of at most 16 bytes.
+	_, err = w.Write([]byte("first"))
+	require.NoError(t, err)
+	require.Equal(t, 2, stream.flushed)
+	_, err = w.Write(loremBytes[:40])
+	require.NoError(t, err)
+	require.Equal(t, 5, stream.flushed)
+	require.NoError(t, w.Close())
+	require.Equal(t, 6, stream.flushed)
+	_, err = w.Write([]byte("late"))
+	require.Error(t, err)
+
+	r, err := tlock.New(network).NewLiveReader(bytes.NewReader(stream.Bytes()))
+	require.NoError(t, err)
+	var chunks []tlock.LiveChunk
+	for {
+		c, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		chunks = append(chunks, c)
+	}
+	require.Len(t, chunks, 4)
+	require.Equal(t, []byte("first"), chunks[0].Data)
+	require.Equal(t, loremBytes[:16], chunks[1].Data)
+	for i := 1; i < len(chunks); i++ {
+		require.GreaterOrEqual(t, chunks[i].Offset, chunks[i-1].Offset)
+	}
+
+	var plainData bytes.Buffer
+	require.NoError(t, tlock.New(network).DecryptLive(context.Background(), &plainData, bytes.NewReader(stream.Bytes()), false))
+	require.Equal(t, append([]byte("first"), loremBytes[:40]...), plainData.Bytes())
+
+	// Truncated and tampered streams are refused.
+	err = tlock.New(network).DecryptLive(context.Background(), io.Discard, bytes.NewReader(stream.Bytes()[:stream.Len()-1]), false)
+	require.ErrorIs(t, err, tlock.ErrMalformedLive)
+	tampered := bytes.Clone(stream.Bytes())
+	tampered[len(tampered)-20] ^= 1
+	err = tlock.New(network).DecryptLive(context.Background(), io.Discard, bytes.NewReader(tampered), false)
+	require.ErrorIs(t, err, tlock.ErrMalformedLive)
+
+	_, err = tlock.New(network).NewLiveWriter(io.Discard, 1000, tlock.MaxLiveChunkSize+1)
+	require.Error(t, err)
+}
+
+func TestLivePacing(t *testing.T) {
+	network := fixedtest.NewNetwork(t, 1000)
+
+	var stream bytes.Buffer
+	w, err := tlock.New(network).NewLiveWriter(&stream, 1000, 0)
+	require.NoError(t, err)
+	_, err = w.Write([]byte("tick"))
+	require.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+	_, err = w.Write([]byte("tock"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	start := time.Now()
+	var plainData bytes.Buffer
+	require.NoError(t, tlock.New(network).DecryptLive(context.Background(), &plainData, bytes.NewReader(stream.Bytes()), true))
+	require.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	require.Equal(t, "ticktock", plainData.String())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = tlock.New(network).DecryptLive(ctx, io.Discard, bytes.NewReader(stream.Bytes()), true)
+	require.ErrorIs(t, err, context.Canceled)
+}
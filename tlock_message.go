@@ -0,0 +1,168 @@
+package tlock
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrMalformedMessage is returned when a message payload can't be parsed.
+var ErrMalformedMessage = errors.New("malformed tlock message payload")
+
+// ErrMessageTooLarge is returned when a message exceeds MaxMessageSize.
+var ErrMessageTooLarge = errors.New("message too large")
+
+// MaxMessageSize is the largest plaintext of a message payload, keeping
+// payloads small enough for messaging apps.
+const MaxMessageSize = 4096
+
+// These constants define the layout of message payloads: a version byte, the
+// ID of the cipher, the round, the start of the chain hash, the IBE ciphertext
+// of the message key and the sealed message. Version 1 payloads have no cipher
+// ID and are sealed with ChaCha20-Poly1305.
+const (
+	messageVersion       = 2
+	messageChainHashSize = 8
+	messageKeySize       = CipherKeySize
+)
+
+// SealMessage encrypts the plaintext, of at most MaxMessageSize bytes, into a
+// compact binary payload made of a single chunk, for self-revealing messages.
+// The message key is encrypted directly with the IBE scheme, without age, and
+// the message is sealed with the cipher of WithCipher.
+func (t Tlock) SealMessage(plaintext []byte, roundNumber uint64) ([]byte, error) {
+	if len(plaintext) > MaxMessageSize {
+		return nil, fmt.Errorf("%w: %d bytes, maximum is %d", ErrMessageTooLarge, len(plaintext), MaxMessageSize)
+	}
+
+	chainHash, err := hex.DecodeString(t.network.ChainHash())
+	if err != nil || len(chainHash) < messageChainHashSize {
+		return nil, fmt.Errorf("invalid chain hash %q", t.network.ChainHash())
+	}
+
+	key := make([]byte, messageKeySize)
+	if _, err := io.ReadFull(randomReader(t.random), key); err != nil {
+		return nil, fmt.Errorf("read key: %w", err)
+	}
+	ciphertext, err := t.timeLock(roundNumber, key)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt key: %w", err)
+	}
+	encryptedKey, err := directCiphertextToBytes(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newCipher(t.cipherID(), key)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := []byte{messageVersion, t.cipherID()}
+	payload = binary.BigEndian.AppendUint64(payload, roundNumber)
+	payload = append(payload, chainHash[:messageChainHashSize]...)
+	payload = append(payload, encryptedKey...)
+	// The key is used once, so the nonce can be fixed.
+	return aead.Seal(payload, make([]byte, aead.NonceSize()), plaintext, payload), nil
+}
+
+// OpenMessage decrypts a payload produced by SealMessage. The round must have
+// been reached by the network.
+func (t Tlock) OpenMessage(payload []byte) ([]byte, error) {
+	h, err := parseMessageHeader(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	chainHash, err := hex.DecodeString(t.network.ChainHash())
+	if err != nil || len(chainHash) < messageChainHashSize {
+		return nil, fmt.Errorf("invalid chain hash %q", t.network.ChainHash())
+	}
+	if !bytes.Equal(h.chainHash, chainHash[:messageChainHashSize]) {
+		return nil, fmt.Errorf("%w: message for chain %x, network is %s", ErrWrongChainhash, h.chainHash, t.network.ChainHash())
+	}
+
+	keyLen := t.network.Scheme().KeyGroup.PointLen() + 2*messageKeySize
+	if len(payload) < h.size+keyLen {
+		return nil, fmt.Errorf("%w: truncated", ErrMalformedMessage)
+	}
+	header, sealed := payload[:h.size+keyLen], payload[h.size+keyLen:]
+
+	ciphertext, err := bytesToDirectCiphertext(t.network.Scheme(), header[h.size:])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedMessage, err)
+	}
+	id := Identity{network: t.network}
+	key, err := id.unlock(h.round, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newCipher(h.cipher, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.Overhead() {
+		return nil, fmt.Errorf("%w: truncated", ErrMalformedMessage)
+	}
+	if len(sealed) > aead.Overhead()+MaxMessageSize {
+		return nil, fmt.Errorf("%w: %d bytes payload", ErrMessageTooLarge, len(payload))
+	}
+	plaintext, err := aead.Open(nil, make([]byte, aead.NonceSize()), sealed, header)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedMessage, err)
+	}
+
+	return plaintext, nil
+}
+
+// MessageRound returns the round a message payload is locked to, letting apps
+// display when it reveals itself without a network.
+func MessageRound(payload []byte) (uint64, error) {
+	h, err := parseMessageHeader(payload)
+	if err != nil {
+		return 0, err
+	}
+	return h.round, nil
+}
+
+// =============================================================================
+
+// messageHeader is the fixed size header of a message payload.
+type messageHeader struct {
+	cipher    byte
+	round     uint64
+	chainHash []byte
+	size      int
+}
+
+// parseMessageHeader parses the header of a message payload of any version.
+func parseMessageHeader(payload []byte) (messageHeader, error) {
+	if len(payload) == 0 {
+		return messageHeader{}, fmt.Errorf("%w: truncated", ErrMalformedMessage)
+	}
+
+	h := messageHeader{cipher: CipherChaCha20Poly1305}
+	fields := payload[1:]
+	switch payload[0] {
+	case 1:
+	case messageVersion:
+		if len(fields) == 0 {
+			return messageHeader{}, fmt.Errorf("%w: truncated", ErrMalformedMessage)
+		}
+		h.cipher, fields = fields[0], fields[1:]
+	default:
+		return messageHeader{}, fmt.Errorf("%w: unsupported version %d", ErrMalformedMessage, payload[0])
+	}
+	if len(fields) < 8+messageChainHashSize {
+		return messageHeader{}, fmt.Errorf("%w: truncated", ErrMalformedMessage)
+	}
+
+	h.round = binary.BigEndian.Uint64(fields[:8])
+	h.chainHash = fields[8 : 8+messageChainHashSize]
+	h.size = len(payload) - len(fields) + 8 + messageChainHashSize
+	return h, nil
+}
@@ -0,0 +1,92 @@
+package tlock_test
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+func TestSealOpenMessage(t *testing.T) {
+	network := fixedtest.NewNetwork(t, 1000)
+	message := []byte("see you on the other side")
+
+	payload, err := tlock.New(network).SealMessage(message, 1000)
+	require.NoError(t, err)
+	require.Less(t, len(payload), 256)
+
+	roundNumber, err := tlock.MessageRound(payload)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1000), roundNumber)
+
+	opened, err := tlock.New(network).OpenMessage(payload)
+	require.NoError(t, err)
+	require.Equal(t, message, opened)
+
+	tampered := bytes.Clone(payload)
+	tampered[len(tampered)-1] ^= 1
+	_, err = tlock.New(network).OpenMessage(tampered)
+	require.ErrorIs(t, err, tlock.ErrMalformedMessage)
+
+	_, err = tlock.New(network).OpenMessage(payload[:20])
+	require.ErrorIs(t, err, tlock.ErrMalformedMessage)
+
+	_, err = tlock.New(network).SealMessage(make([]byte, tlock.MaxMessageSize+1), 1000)
+	require.ErrorIs(t, err, tlock.ErrMessageTooLarge)
+
+	payload, err = tlock.New(network).SealMessage(make([]byte, tlock.MaxMessageSize), 1000)
+	require.NoError(t, err)
+	_, err = tlock.New(network).OpenMessage(append(payload, 0))
+	require.ErrorIs(t, err, tlock.ErrMessageTooLarge)
+}
+
+func TestMessageCiphers(t *testing.T) {
+	network := fixedtest.NewNetwork(t, 1000)
+	message := []byte("see you on the other side")
+
+	registerXChaCha20Poly1305()
+	for _, id := range []byte{tlock.CipherChaCha20Poly1305, tlock.CipherAES256GCM, 0x80} {
+		if tlock.FIPSMode && id != tlock.CipherAES256GCM {
+			// TestFIPSMode checks that the other ciphers are rejected.
+			continue
+		}
+		payload, err := tlock.New(network).WithCipher(id).SealMessage(message, 1000)
+		require.NoError(t, err)
+		require.Equal(t, id, payload[1])
+
+		// The cipher is picked from the payload.
+		opened, err := tlock.New(network).OpenMessage(payload)
+		require.NoError(t, err)
+		require.Equal(t, message, opened)
+	}
+
+	// Only AES-256-GCM is known in FIPS mode.
+	errUnknown := tlock.ErrUnknownCipher
+	if tlock.FIPSMode {
+		errUnknown = tlock.ErrNotApproved
+	}
+	_, err := tlock.New(network).WithCipher(0x81).SealMessage(message, 1000)
+	require.ErrorIs(t, err, errUnknown)
+
+	payload, err := tlock.New(network).SealMessage(message, 1000)
+	require.NoError(t, err)
+	payload[1] = 0x81
+	_, err = tlock.New(network).OpenMessage(payload)
+	require.ErrorIs(t, err, errUnknown)
+
+	// The built-in ciphers and the registered ones can't be replaced.
+	for _, id := range []byte{tlock.CipherChaCha20Poly1305, tlock.CipherAES256GCM, 0x7f, 0x80} {
+		require.Panics(t, func() { tlock.RegisterCipher(id, chacha20poly1305.NewX) }, "0x%02x", id)
+	}
+	require.Panics(t, func() { tlock.RegisterCipher(0x81, nil) })
+}
+
+// registerXChaCha20Poly1305 registers XChaCha20-Poly1305 as the third party
+// cipher 0x80, once per test binary.
+var registerXChaCha20Poly1305 = sync.OnceFunc(func() {
+	tlock.RegisterCipher(0x80, chacha20poly1305.NewX)
+})
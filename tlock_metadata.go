@@ -0,0 +1,192 @@
+package tlock
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"unicode"
+
+	"filippo.io/age"
+)
+
+// ErrMalformedMetadata is returned when the metadata of a ciphertext can't be
+// parsed or opened.
+var ErrMalformedMetadata = errors.New("malformed metadata")
+
+// ErrInvalidMetadata is returned when metadata to encrypt has an invalid key or
+// exceeds maxMetadataSize once encoded.
+var ErrInvalidMetadata = errors.New("invalid metadata")
+
+// These are the keys of the well known metadata fields.
+const (
+	MetadataContentType = "content-type"
+	MetadataDescription = "description"
+	MetadataCreator     = "creator"
+)
+
+// metadataStanza is the type of the stanza carrying the metadata, whose
+// single argument tells whether its body is sealed.
+const metadataStanza = "tlock-meta"
+
+// These are the arguments of the metadata stanza.
+const (
+	metadataPlain  = "plain"
+	metadataSealed = "sealed"
+)
+
+// maxMetadataSize bounds the encoded metadata, which lives in the header.
+const maxMetadataSize = 4096
+
+// UserMetadata holds small key/value fields describing the payload, such as
+// its content type. The fields are carried in the header, authenticated by its
+// MAC, either in the clear so they can be read before the round is reached or
+// sealed with the file key.
+type UserMetadata map[string]string
+
+// WithMetadata returns a tlock attaching the metadata in the clear to the
+// ciphertexts it encrypts, readable with Header.Metadata at any time. Their
+// integrity is only established once the ciphertext is decrypted.
+func (t Tlock) WithMetadata(m UserMetadata) Tlock {
+	t.metadata = m
+	t.sealMetadata = false
+	return t
+}
+
+// WithSealedMetadata returns a tlock attaching the metadata encrypted with the
+// file key to the ciphertexts it encrypts, readable with DecryptMetadata once
+// the round is reached.
+func (t Tlock) WithSealedMetadata(m UserMetadata) Tlock {
+	t.metadata = m
+	t.sealMetadata = true
+	return t
+}
+
+// Metadata returns the metadata carried in the clear by the header, nil when
+// there is none. It reports whether the header carries sealed metadata, which
+// only DecryptMetadata can read.
+func (h *Header) Metadata() (UserMetadata, bool, error) {
+	s := h.metadataStanza()
+	if s == nil {
+		return nil, false, nil
+	}
+	if s.Args[0] == metadataSealed {
+		return nil, true, nil
+	}
+	m, err := unmarshalMetadata(s.Body)
+	return m, false, err
+}
+
+// DecryptMetadata returns the metadata of the ciphertext in src, opening it
+// when sealed. The round must have been reached by the network, as the file
+// key authenticates the header.
+func (t Tlock) DecryptMetadata(src io.Reader) (UserMetadata, error) {
+	hdr, err := ParseHeader(src)
+	if err != nil {
+		return nil, err
+	}
+
+	id := Identity{network: t.network, trustChainhash: t.trustChainhash, passphrase: t.passphrase, namespace: t.namespace}
+	fileKey, err := id.Unwrap(hdr.Stanzas)
+	if err != nil {
+		return nil, fmt.Errorf("unwrap: %w", err)
+	}
+	if !hmac.Equal(headerMAC(fileKey, hdr), hdr.MAC) {
+		return nil, errors.New("bad header MAC")
+	}
+
+	s := hdr.metadataStanza()
+	if s == nil {
+		return nil, nil
+	}
+	return openMetadata(s, fileKey)
+}
+
+// =============================================================================
+
+// metadataStanza returns the metadata stanza of the header, nil when there is
+// none or it is malformed.
+func (h *Header) metadataStanza() *age.Stanza {
+	for _, s := range h.Stanzas {
+		if s.Type == metadataStanza && len(s.Args) == 1 && (s.Args[0] == metadataPlain || s.Args[0] == metadataSealed) {
+			return s
+		}
+	}
+	return nil
+}
+
+// wrapMetadata returns the stanza carrying the metadata for the file key.
+func wrapMetadata(m UserMetadata, sealed bool, fileKey []byte) (*age.Stanza, error) {
+	body, err := marshalMetadata(m)
+	if err != nil {
+		return nil, err
+	}
+	if !sealed {
+		return &age.Stanza{Type: metadataStanza, Args: []string{metadataPlain}, Body: body}, nil
+	}
+
+	aead, err := newChaCha20Poly1305(hkdfKey(fileKey, nil, "metadata"), "sealed metadata")
+	if err != nil {
+		return nil, err
+	}
+	body = aead.Seal(nil, make([]byte, aead.NonceSize()), body, nil)
+	return &age.Stanza{Type: metadataStanza, Args: []string{metadataSealed}, Body: body}, nil
+}
+
+// openMetadata decodes the metadata of the stanza, opening it with the file
+// key when sealed.
+func openMetadata(s *age.Stanza, fileKey []byte) (UserMetadata, error) {
+	body := s.Body
+	if s.Args[0] == metadataSealed {
+		aead, err := newChaCha20Poly1305(hkdfKey(fileKey, nil, "metadata"), "sealed metadata")
+		if err != nil {
+			return nil, err
+		}
+		// The key is unique to the file, so is the single message sealed
+		// with it.
+		if body, err = aead.Open(nil, make([]byte, aead.NonceSize()), body, nil); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrMalformedMetadata, err)
+		}
+	}
+	return unmarshalMetadata(body)
+}
+
+// marshalMetadata validates and encodes the metadata.
+func marshalMetadata(m UserMetadata) ([]byte, error) {
+	for k := range m {
+		if k == "" || !isPrintable(k) {
+			return nil, fmt.Errorf("%w: key %q", ErrInvalidMetadata, k)
+		}
+	}
+	body, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxMetadataSize {
+		return nil, fmt.Errorf("%w: %d bytes encoded, at most %d", ErrInvalidMetadata, len(body), maxMetadataSize)
+	}
+	return body, nil
+}
+
+// unmarshalMetadata decodes the metadata.
+func unmarshalMetadata(body []byte) (UserMetadata, error) {
+	if len(body) > maxMetadataSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrMalformedMetadata, len(body))
+	}
+	var m UserMetadata
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedMetadata, err)
+	}
+	return m, nil
+}
+
+// isPrintable reports whether s only holds printable characters.
+func isPrintable(s string) bool {
+	for _, r := range s {
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
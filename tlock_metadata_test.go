@@ -0,0 +1,95 @@
+package tlock_test
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadata(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+	metadata := tlock.UserMetadata{
+		tlock.MetadataContentType: "application/pdf",
+		tlock.MetadataDescription: "quarterly results",
+	}
+
+	var cipherData bytes.Buffer
+	require.NoError(t, tlock.New(network).WithMetadata(metadata).Encrypt(&cipherData, strings.NewReader("embargoed"), 1000))
+	ciphertext := cipherData.Bytes()
+
+	hdr, err := tlock.ParseHeader(bytes.NewReader(ciphertext))
+	require.NoError(t, err)
+	got, sealed, err := hdr.Metadata()
+	require.NoError(t, err)
+	require.False(t, sealed)
+	require.Equal(t, metadata, got)
+
+	var out bytes.Buffer
+	require.NoError(t, tlock.New(network).Decrypt(&out, bytes.NewReader(ciphertext)))
+	require.Equal(t, "embargoed", out.String())
+	got, err = tlock.New(network).DecryptMetadata(bytes.NewReader(ciphertext))
+	require.NoError(t, err)
+	require.Equal(t, metadata, got)
+
+	// The metadata is kept when rewrapping.
+	var rewrapped bytes.Buffer
+	require.NoError(t, tlock.New(network).Rewrap(&rewrapped, bytes.NewReader(ciphertext), 1000))
+	got, err = tlock.New(network).DecryptMetadata(&rewrapped)
+	require.NoError(t, err)
+	require.Equal(t, metadata, got)
+
+	// The metadata is authenticated by the header MAC.
+	rr := bufio.NewReader(bytes.NewReader(ciphertext))
+	hdr, err = tlock.ReadHeader(rr)
+	require.NoError(t, err)
+	hdr.Stanzas[1].Body = []byte(`{"content-type":"text/plain"}`)
+	var tampered bytes.Buffer
+	require.NoError(t, hdr.Marshal(&tampered))
+	_, err = io.Copy(&tampered, rr)
+	require.NoError(t, err)
+	require.Error(t, tlock.New(network).Decrypt(io.Discard, bytes.NewReader(tampered.Bytes())))
+	_, err = tlock.New(network).DecryptMetadata(bytes.NewReader(tampered.Bytes()))
+	require.Error(t, err)
+}
+
+func TestSealedMetadata(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+	metadata := tlock.UserMetadata{tlock.MetadataCreator: "newsroom"}
+
+	var cipherData bytes.Buffer
+	require.NoError(t, tlock.New(network).WithSealedMetadata(metadata).Encrypt(&cipherData, strings.NewReader("embargoed"), 1000))
+	require.NotContains(t, cipherData.String(), "newsroom")
+
+	hdr, err := tlock.ParseHeader(bytes.NewReader(cipherData.Bytes()))
+	require.NoError(t, err)
+	got, sealed, err := hdr.Metadata()
+	require.NoError(t, err)
+	require.True(t, sealed)
+	require.Nil(t, got)
+
+	got, err = tlock.New(network).DecryptMetadata(bytes.NewReader(cipherData.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, metadata, got)
+}
+
+func TestInvalidMetadata(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+
+	err := tlock.New(network).WithMetadata(tlock.UserMetadata{"": "empty"}).Encrypt(io.Discard, strings.NewReader("data"), 1000)
+	require.ErrorIs(t, err, tlock.ErrInvalidMetadata)
+
+	err = tlock.New(network).WithMetadata(tlock.UserMetadata{"description": strings.Repeat("a", 5000)}).Encrypt(io.Discard, strings.NewReader("data"), 1000)
+	require.ErrorIs(t, err, tlock.ErrInvalidMetadata)
+}
@@ -0,0 +1,50 @@
+package tlock
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// ErrMmapUnsupported is returned when memory mapping files isn't supported on
+// the platform, callers falling back to reading them.
+var ErrMmapUnsupported = errors.New("memory mapping not supported")
+
+// MappedFile is a read only memory mapping of a file. Its contents can be
+// handed to the encryption and decryption without being copied through
+// intermediate buffers, and sliced at chunk boundaries.
+type MappedFile struct {
+	data  []byte
+	unmap func() error
+}
+
+// Bytes returns the contents of the file. They must not be used after Close.
+func (m *MappedFile) Bytes() []byte {
+	return m.data
+}
+
+// Size returns the size of the file.
+func (m *MappedFile) Size() int64 {
+	return int64(len(m.data))
+}
+
+// ReadAt implements io.ReaderAt.
+func (m *MappedFile) ReadAt(p []byte, off int64) (int, error) {
+	return bytes.NewReader(m.data).ReadAt(p, off)
+}
+
+// Reader returns a reader over the contents of the file. Its WriteTo method
+// hands the whole mapping to the destination in one call.
+func (m *MappedFile) Reader() io.Reader {
+	return bytes.NewReader(m.data)
+}
+
+// Close unmaps the file.
+func (m *MappedFile) Close() error {
+	if m.unmap == nil {
+		return nil
+	}
+	err := m.unmap()
+	m.data, m.unmap = nil, nil
+	return err
+}
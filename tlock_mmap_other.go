@@ -0,0 +1,10 @@
+//go:build !unix
+
+package tlock
+
+import "os"
+
+// MapFile maps the regular file into memory.
+func MapFile(f *os.File) (*MappedFile, error) {
+	return nil, ErrMmapUnsupported
+}
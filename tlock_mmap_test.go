@@ -0,0 +1,45 @@
+package tlock_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMapFile(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+	plaintext := bytes.Repeat([]byte("mapped "), tlock.ChunkSize/3)
+
+	name := filepath.Join(t.TempDir(), "data.tle")
+	var cipherData bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&cipherData, bytes.NewReader(plaintext), 1000))
+	require.NoError(t, os.WriteFile(name, cipherData.Bytes(), 0600))
+
+	f, err := os.Open(name)
+	require.NoError(t, err)
+	defer f.Close()
+
+	m, err := tlock.MapFile(f)
+	if errors.Is(err, tlock.ErrMmapUnsupported) {
+		t.Skip(err)
+	}
+	require.NoError(t, err)
+	defer m.Close()
+	require.Equal(t, cipherData.Bytes(), m.Bytes())
+
+	var out bytes.Buffer
+	require.NoError(t, tlock.New(network).Decrypt(&out, m.Reader()))
+	require.Equal(t, plaintext, out.Bytes())
+
+	d, err := tlock.New(network).NewRandomAccessDecrypter(m, m.Size())
+	require.NoError(t, err)
+	require.Equal(t, int64(len(plaintext)), d.Size())
+}
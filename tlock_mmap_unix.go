@@ -0,0 +1,31 @@
+//go:build unix
+
+package tlock
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// MapFile maps the regular file into memory.
+func MapFile(f *os.File) (*MappedFile, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !fi.Mode().IsRegular() {
+		return nil, fmt.Errorf("%w: %s is not a regular file", ErrMmapUnsupported, f.Name())
+	}
+	if fi.Size() == 0 {
+		return &MappedFile{data: []byte{}}, nil
+	}
+
+	data, err := unix.Mmap(int(f.Fd()), 0, int(fi.Size()), unix.PROT_READ, unix.MAP_SHARED)
+	if err != nil {
+		return nil, fmt.Errorf("mmap: %w", err)
+	}
+
+	return &MappedFile{data: data, unmap: func() error { return unix.Munmap(data) }}, nil
+}
@@ -0,0 +1,207 @@
+package tlock
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrMalformedMulti is returned when a decrypted plaintext isn't a valid
+// multi part plaintext.
+var ErrMalformedMulti = errors.New("malformed multi part plaintext")
+
+// ErrInvalidPartName is returned when a part to encrypt has an invalid name.
+var ErrInvalidPartName = errors.New("invalid part name")
+
+// multiMagic starts the plaintext of multi part ciphertexts.
+const multiMagic = "tlock multi v1\n"
+
+// These are the markers preceding each part and the end of the parts.
+const (
+	multiEnd  = 0
+	multiPart = 1
+)
+
+// maxPartNameSize bounds the size of part names.
+const maxPartNameSize = 4096
+
+// NamedReader is a part of a multi part plaintext.
+type NamedReader struct {
+	Name string
+	io.Reader
+}
+
+// EncryptMulti encrypts the concatenation of the parts, recording their names
+// and boundaries in the encrypted payload so that DecryptMulti can write them
+// to separate outputs. Names are kept confidential until the round is reached.
+// The parts are read in sequence, the context being checked between reads.
+func (t Tlock) EncryptMulti(ctx context.Context, dst io.Writer, parts []NamedReader, roundNumber uint64) error {
+	for _, part := range parts {
+		if part.Name == "" || len(part.Name) > maxPartNameSize || strings.ContainsRune(part.Name, 0) {
+			return fmt.Errorf("%w: %q", ErrInvalidPartName, part.Name)
+		}
+	}
+
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pw.CloseWithError(writeParts(ctx, pw, parts))
+	}()
+
+	err := t.Encrypt(dst, pr, roundNumber)
+	pr.CloseWithError(err)
+	<-done
+	return err
+}
+
+// DecryptMulti decrypts a ciphertext produced by EncryptMulti, writing each
+// part to the writer open returns for its name. Writers implementing io.Closer
+// are closed at the end of their part.
+func (t Tlock) DecryptMulti(ctx context.Context, src io.Reader, open func(name string) (io.Writer, error)) error {
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pw.CloseWithError(t.Decrypt(pw, src))
+	}()
+
+	err := readParts(ctx, bufio.NewReader(pr), open)
+	pr.CloseWithError(err)
+	<-done
+	return err
+}
+
+// =============================================================================
+
+// writeParts writes the framed parts to w. The data of each part is split in
+// frames prefixed with their length, an empty frame ending the part.
+func writeParts(ctx context.Context, w io.Writer, parts []NamedReader) error {
+	bw := bufio.NewWriterSize(w, ChunkSize)
+	bw.WriteString(multiMagic)
+
+	buf := make([]byte, ChunkSize)
+	for _, part := range parts {
+		bw.WriteByte(multiPart)
+		bw.Write(binary.AppendUvarint(nil, uint64(len(part.Name))))
+		bw.WriteString(part.Name)
+
+		for {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			n, err := part.Read(buf)
+			if n > 0 {
+				bw.Write(binary.AppendUvarint(nil, uint64(n)))
+				if _, err := bw.Write(buf[:n]); err != nil {
+					// The encryption failed.
+					return err
+				}
+			}
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("read part %q: %w", part.Name, err)
+			}
+		}
+		bw.WriteByte(0)
+	}
+	bw.WriteByte(multiEnd)
+
+	return bw.Flush()
+}
+
+// readParts reads the framed parts from r, writing them to the writers open
+// returns.
+func readParts(ctx context.Context, r *bufio.Reader, open func(name string) (io.Writer, error)) error {
+	magic := make([]byte, len(multiMagic))
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return multiReadError(err)
+	}
+	if string(magic) != multiMagic {
+		return fmt.Errorf("%w: not a multi part plaintext", ErrMalformedMulti)
+	}
+
+	for {
+		marker, err := r.ReadByte()
+		if err != nil {
+			return multiReadError(err)
+		}
+		switch marker {
+		case multiEnd:
+			// Reading to the end lets decryption authenticate the last chunk.
+			switch _, err := r.ReadByte(); {
+			case err == nil:
+				return fmt.Errorf("%w: trailing data", ErrMalformedMulti)
+			case errors.Is(err, io.EOF):
+				return nil
+			default:
+				return err
+			}
+		case multiPart:
+		default:
+			return fmt.Errorf("%w: unexpected marker %d", ErrMalformedMulti, marker)
+		}
+
+		size, err := binary.ReadUvarint(r)
+		if err != nil {
+			return multiReadError(err)
+		}
+		if size == 0 || size > maxPartNameSize {
+			return fmt.Errorf("%w: name of %d bytes", ErrMalformedMulti, size)
+		}
+		name := make([]byte, size)
+		if _, err := io.ReadFull(r, name); err != nil {
+			return multiReadError(err)
+		}
+
+		w, err := open(string(name))
+		if err != nil {
+			return fmt.Errorf("open part %q: %w", name, err)
+		}
+		if err := readPart(ctx, r, w); err != nil {
+			return err
+		}
+		if c, ok := w.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				return fmt.Errorf("close part %q: %w", name, err)
+			}
+		}
+	}
+}
+
+// readPart copies the frames of a part from r to w.
+func readPart(ctx context.Context, r *bufio.Reader, w io.Writer) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		size, err := binary.ReadUvarint(r)
+		if err != nil {
+			return multiReadError(err)
+		}
+		if size == 0 {
+			return nil
+		}
+		if size > ChunkSize {
+			return fmt.Errorf("%w: frame of %d bytes", ErrMalformedMulti, size)
+		}
+		if _, err := io.CopyN(w, r, int64(size)); err != nil {
+			return multiReadError(err)
+		}
+	}
+}
+
+// multiReadError reports a truncated plaintext as malformed, passing the
+// errors of the decryption through.
+func multiReadError(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: truncated", ErrMalformedMulti)
+	}
+	return err
+}
@@ -0,0 +1,101 @@
+package tlock_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+// namedBuffer is a buffer closed at the end of its part.
+type namedBuffer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *namedBuffer) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestEncryptMulti(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+	large := bytes.Repeat([]byte("large"), tlock.ChunkSize)
+	parts := []tlock.NamedReader{
+		{Name: "notes.txt", Reader: strings.NewReader("release notes")},
+		{Name: "empty", Reader: strings.NewReader("")},
+		{Name: "dir/large.bin", Reader: bytes.NewReader(large)},
+	}
+
+	var cipherData bytes.Buffer
+	require.NoError(t, tlock.New(network).EncryptMulti(context.Background(), &cipherData, parts, 1000))
+
+	var names []string
+	outputs := make(map[string]*namedBuffer)
+	err := tlock.New(network).DecryptMulti(context.Background(), bytes.NewReader(cipherData.Bytes()), func(name string) (io.Writer, error) {
+		names = append(names, name)
+		outputs[name] = &namedBuffer{}
+		return outputs[name], nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"notes.txt", "empty", "dir/large.bin"}, names)
+	require.Equal(t, "release notes", outputs["notes.txt"].String())
+	require.Zero(t, outputs["empty"].Len())
+	require.Equal(t, large, outputs["dir/large.bin"].Bytes())
+	for _, out := range outputs {
+		require.True(t, out.closed)
+	}
+
+	// The writer of a part can stop decryption.
+	errFull := errors.New("disk full")
+	err = tlock.New(network).DecryptMulti(context.Background(), bytes.NewReader(cipherData.Bytes()), func(name string) (io.Writer, error) {
+		if name == "empty" {
+			return nil, errFull
+		}
+		return io.Discard, nil
+	})
+	require.ErrorIs(t, err, errFull)
+
+	// Truncated ciphertexts fail to authenticate.
+	truncated := cipherData.Bytes()[:cipherData.Len()-100]
+	err = tlock.New(network).DecryptMulti(context.Background(), bytes.NewReader(truncated), func(string) (io.Writer, error) {
+		return io.Discard, nil
+	})
+	require.Error(t, err)
+}
+
+func TestDecryptMultiNotMulti(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+
+	var cipherData bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&cipherData, strings.NewReader("a single plaintext"), 1000))
+
+	err := tlock.New(network).DecryptMulti(context.Background(), &cipherData, func(string) (io.Writer, error) {
+		return io.Discard, nil
+	})
+	require.ErrorIs(t, err, tlock.ErrMalformedMulti)
+}
+
+func TestEncryptMultiErrors(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+
+	err := tlock.New(network).EncryptMulti(context.Background(), io.Discard, []tlock.NamedReader{{Name: "", Reader: strings.NewReader("data")}}, 1000)
+	require.ErrorIs(t, err, tlock.ErrInvalidPartName)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = tlock.New(network).EncryptMulti(ctx, io.Discard, []tlock.NamedReader{{Name: "a", Reader: strings.NewReader("data")}}, 1000)
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,71 @@
+package tlock
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// ErrNamespaceMismatch is returned when a ciphertext was encrypted for another
+// application namespace than the one decrypting it.
+var ErrNamespaceMismatch = errors.New("ciphertext namespace mismatch")
+
+// nsArg is the stanza argument introducing the application namespace of a
+// tlock stanza.
+const nsArg = "ns"
+
+// WithNamespace returns a tlock labelling its ciphertexts with the application
+// namespace, so that an application doesn't mistake the ciphertexts of
+// another for its own. The namespace is recorded in the clear in the
+// ciphertext header, whose MAC authenticates it once decrypted.
+//
+// The namespace is a label checked on decryption, not a domain separation:
+// drand only signs the round, which is thus the identity the filekeys are
+// encrypted to, and whoever gets the beacon of the round can decrypt the
+// ciphertexts of every namespace, by ignoring the label.
+//
+// Decryption requires the ciphertext namespace to match. A tlock without a
+// namespace, the default, only decrypts ciphertexts without one.
+func (t Tlock) WithNamespace(namespace string) Tlock {
+	t.namespace = namespace
+	return t
+}
+
+// Namespace returns the application namespace recorded by the first tlock
+// stanza of the header, or an empty string when it has none.
+func (h *Header) Namespace() (string, error) {
+	for _, s := range h.Stanzas {
+		if s.Type != "tlock" || len(s.Args) < 2 {
+			continue
+		}
+		args := s.Args[2:]
+		for len(args) > 1 {
+			switch args[0] {
+			case kdfArg:
+				args = args[min(5, len(args)):]
+			case nsArg:
+				return parseNamespaceArg(args[1])
+			default:
+				return "", fmt.Errorf("unsupported stanza argument %q", args[0])
+			}
+		}
+		return "", nil
+	}
+	return "", ErrNoTlockStanza
+}
+
+// =============================================================================
+
+// namespaceArgs returns the stanza arguments recording the namespace.
+func namespaceArgs(namespace string) []string {
+	return []string{nsArg, base64.RawStdEncoding.EncodeToString([]byte(namespace))}
+}
+
+// parseNamespaceArg parses the namespace following the ns stanza argument.
+func parseNamespaceArg(arg string) (string, error) {
+	namespace, err := base64.RawStdEncoding.Strict().DecodeString(arg)
+	if err != nil || len(namespace) == 0 {
+		return "", fmt.Errorf("%w: malformed namespace %q", ErrNamespaceMismatch, arg)
+	}
+	return string(namespace), nil
+}
@@ -0,0 +1,62 @@
+package tlock_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespaceRoundTrip(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+
+	var cipherData bytes.Buffer
+	err := tlock.New(network).WithNamespace("example.org/sealed-bids").Encrypt(&cipherData, bytes.NewReader(loremBytes), 1000)
+	require.NoError(t, err)
+	require.Contains(t, cipherData.String(), " ns ")
+
+	t.Run("with the same namespace", func(t *testing.T) {
+		var plainData bytes.Buffer
+		err := tlock.New(network).WithNamespace("example.org/sealed-bids").Decrypt(&plainData, bytes.NewReader(cipherData.Bytes()))
+		require.NoError(t, err)
+		require.Equal(t, loremBytes, plainData.Bytes())
+	})
+
+	t.Run("with another namespace", func(t *testing.T) {
+		err := tlock.New(network).WithNamespace("example.org/other").Decrypt(&bytes.Buffer{}, bytes.NewReader(cipherData.Bytes()))
+		require.ErrorIs(t, err, tlock.ErrNamespaceMismatch)
+	})
+
+	t.Run("without namespace", func(t *testing.T) {
+		err := tlock.New(network).Decrypt(&bytes.Buffer{}, bytes.NewReader(cipherData.Bytes()))
+		require.ErrorIs(t, err, tlock.ErrNamespaceMismatch)
+	})
+
+	t.Run("with a passphrase", func(t *testing.T) {
+		params := tlock.KDFParams{Time: 1, Memory: 1024, Threads: 1}
+		var cipherData, plainData bytes.Buffer
+		require.NoError(t, tlock.New(network).WithNamespace("ns").WithPassphrase("pass", params).Encrypt(&cipherData, bytes.NewReader(loremBytes), 1000))
+		require.NoError(t, tlock.New(network).WithNamespace("ns").WithPassphrase("pass", tlock.KDFParams{}).Decrypt(&plainData, &cipherData))
+		require.Equal(t, loremBytes, plainData.Bytes())
+	})
+}
+
+func TestNamespaceCompatibility(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+
+	var cipherData bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&cipherData, bytes.NewReader(loremBytes), 1000))
+
+	err := tlock.New(network).WithNamespace("example.org/sealed-bids").Decrypt(&bytes.Buffer{}, bytes.NewReader(cipherData.Bytes()))
+	require.ErrorIs(t, err, tlock.ErrNamespaceMismatch)
+
+	var plainData bytes.Buffer
+	require.NoError(t, tlock.New(network).Decrypt(&plainData, &cipherData))
+	require.Equal(t, loremBytes, plainData.Bytes())
+}
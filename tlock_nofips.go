@@ -0,0 +1,7 @@
+//go:build !fips
+
+package tlock
+
+// FIPSMode reports whether the package is built with the fips tag, restricting
+// its symmetric layer to FIPS approved algorithms.
+const FIPSMode = false
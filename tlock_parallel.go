@@ -0,0 +1,143 @@
+package tlock
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// WithConcurrency returns a tlock whose Decrypt opens the chunks of the
+// payload with up to workers goroutines, writing them in order. Once the
+// beacon is cached, opening the chunks is the bottleneck of decryption. At
+// most twice as many chunks as workers are held in memory. A value of one or
+// less decrypts sequentially.
+func (t Tlock) WithConcurrency(workers int) Tlock {
+	t.workers = workers
+	return t
+}
+
+// =============================================================================
+
+// parallelChunk is a chunk of the payload going through the pipeline.
+type parallelChunk struct {
+	index int64
+	last  bool
+	buf   []byte
+
+	plaintext []byte
+	err       error
+	done      chan struct{}
+}
+
+// decryptParallel decrypts the binary ciphertext in src with a pipeline of
+// workers, reporting failures past the header as Decrypt does.
+func (t Tlock) decryptParallel(dst io.Writer, src *bufio.Reader) error {
+	hdr, err := ReadHeader(src)
+	if err != nil {
+		return fmt.Errorf("hybrid decrypt: %w", err)
+	}
+	fileKey, err := t.unwrapFileKey(hdr)
+	if err != nil {
+		return fmt.Errorf("hybrid decrypt: %w", err)
+	}
+	nonce := make([]byte, streamNonceSize)
+	if _, err := io.ReadFull(src, nonce); err != nil {
+		return fmt.Errorf("hybrid decrypt: %w: read nonce: %w", ErrMalformedPayload, err)
+	}
+	aead, err := newChaCha20Poly1305(hkdfKey(fileKey, nonce, "payload"), "the age payload")
+	if err != nil {
+		return err
+	}
+
+	jobs := make(chan *parallelChunk)
+	ordered := make(chan *parallelChunk, 2*t.workers)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for range t.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range jobs {
+				c.plaintext, c.err = openChunk(aead, c.buf, c.index, c.last)
+				close(c.done)
+			}
+		}()
+	}
+	go func() {
+		defer close(ordered)
+		defer close(jobs)
+		readChunks(src, jobs, ordered, stop)
+	}()
+
+	cw := countingWriter{w: t.limitPlaintext(dst)}
+	for c := range ordered {
+		<-c.done
+		err := c.err
+		if err == nil {
+			_, err = cw.Write(c.plaintext)
+		}
+		if err != nil {
+			close(stop)
+			for range ordered {
+			}
+			wg.Wait()
+			return &PartialDecryptionError{
+				Chunks: cw.n / ChunkSize,
+				Bytes:  cw.n,
+				Err:    fmt.Errorf("write: %w", err),
+			}
+		}
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// readChunks reads the encrypted chunks from src, handing each of them to the
+// workers through jobs and to the writer through ordered, until the end of
+// the payload or until stop is closed. Malformed payloads are reported as a
+// failed chunk.
+func readChunks(src *bufio.Reader, jobs, ordered chan<- *parallelChunk, stop <-chan struct{}) {
+	for index := int64(0); ; index++ {
+		c := parallelChunk{index: index, buf: make([]byte, encChunkSize), done: make(chan struct{})}
+		n, err := io.ReadFull(src, c.buf)
+		switch {
+		case err == nil:
+			// A full chunk is the last one when nothing follows it.
+			if _, err := src.Peek(1); errors.Is(err, io.EOF) {
+				c.last = true
+			} else if err != nil {
+				c.err = err
+			}
+		case errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF):
+			c.last = true
+			if n < streamTagSize || (index > 0 && n == streamTagSize) {
+				c.err = ErrMalformedPayload
+			}
+		default:
+			c.err = err
+		}
+		c.buf = c.buf[:n]
+
+		select {
+		case ordered <- &c:
+		case <-stop:
+			return
+		}
+		if c.err != nil {
+			close(c.done)
+			return
+		}
+		select {
+		case jobs <- &c:
+		case <-stop:
+			close(c.done)
+			return
+		}
+		if c.last {
+			return
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package tlock_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecryptParallel(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+
+	for _, size := range []int{0, 1, tlock.ChunkSize, tlock.ChunkSize + 1, 5 * tlock.ChunkSize, 10*tlock.ChunkSize + 7} {
+		plaintext := make([]byte, size)
+		_, err := rand.Read(plaintext)
+		require.NoError(t, err)
+
+		var cipherData bytes.Buffer
+		require.NoError(t, tlock.New(network).Encrypt(&cipherData, bytes.NewReader(plaintext), 1000))
+
+		for _, workers := range []int{2, 4, 16} {
+			var out bytes.Buffer
+			require.NoError(t, tlock.New(network).WithConcurrency(workers).Decrypt(&out, bytes.NewReader(cipherData.Bytes())), "size %d", size)
+			require.True(t, bytes.Equal(plaintext, out.Bytes()), "size %d", size)
+		}
+	}
+}
+
+func TestDecryptParallelFailures(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+	plaintext := bytes.Repeat([]byte("a"), 8*tlock.ChunkSize+10)
+
+	var cipherData bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&cipherData, bytes.NewReader(plaintext), 1000))
+	ciphertext := cipherData.Bytes()
+	payloadStart := len(ciphertext) - (len(plaintext) + 9*16) - 16
+
+	// The chunks before a corrupted one are written.
+	tampered := bytes.Clone(ciphertext)
+	tampered[payloadStart+16+3*(tlock.ChunkSize+16)+5] ^= 1
+	var out bytes.Buffer
+	err := tlock.New(network).WithConcurrency(4).Decrypt(&out, bytes.NewReader(tampered))
+	var partial *tlock.PartialDecryptionError
+	require.True(t, errors.As(err, &partial))
+	require.Equal(t, int64(3), partial.Chunks)
+	require.Equal(t, plaintext[:3*tlock.ChunkSize], out.Bytes())
+
+	// Truncating the payload at a chunk boundary loses the last chunk flag.
+	truncated := ciphertext[:payloadStart+16+4*(tlock.ChunkSize+16)]
+	out.Reset()
+	err = tlock.New(network).WithConcurrency(4).Decrypt(&out, bytes.NewReader(truncated))
+	require.True(t, errors.As(err, &partial))
+
+	out.Reset()
+	err = tlock.New(network).WithConcurrency(4).WithMaxPlaintextSize(tlock.ChunkSize).Decrypt(&out, bytes.NewReader(ciphertext))
+	require.ErrorIs(t, err, tlock.ErrPlaintextTooLarge)
+	require.Equal(t, tlock.ChunkSize, out.Len())
+}
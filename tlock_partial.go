@@ -0,0 +1,42 @@
+package tlock
+
+import (
+	"fmt"
+	"io"
+)
+
+// ChunkSize is the size of the plaintext chunks of the age payload, each of
+// them being authenticated on its own.
+const ChunkSize = 64 * 1024
+
+// PartialDecryptionError is returned when decryption fails once the header was
+// decrypted, reporting how much plaintext was written to the destination. The
+// chunks written were authenticated, so callers can decide whether to resume
+// or to discard the output.
+type PartialDecryptionError struct {
+	Chunks int64 // Number of complete chunks written.
+	Bytes  int64 // Number of plaintext bytes written.
+	Err    error
+}
+
+func (e *PartialDecryptionError) Error() string {
+	return fmt.Sprintf("%v (%d bytes in %d complete chunks written)", e.Err, e.Bytes, e.Chunks)
+}
+
+func (e *PartialDecryptionError) Unwrap() error {
+	return e.Err
+}
+
+// =============================================================================
+
+// countingWriter counts the bytes written to the underlying writer.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,34 @@
+package tlock_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartialDecryption(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+	plaintext := bytes.Repeat([]byte("x"), 3*tlock.ChunkSize+100)
+
+	var cipherData bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&cipherData, bytes.NewReader(plaintext), 1000))
+
+	// Flip the last byte of the third chunk, which precedes its 16 bytes tag
+	// and the last chunk holding 100 bytes and its tag.
+	ciphertext := cipherData.Bytes()
+	ciphertext[len(ciphertext)-(100+16)-16-1] ^= 1
+
+	var out bytes.Buffer
+	err := tlock.New(network).Decrypt(&out, bytes.NewReader(ciphertext))
+	var partial *tlock.PartialDecryptionError
+	require.True(t, errors.As(err, &partial))
+	require.Equal(t, int64(2), partial.Chunks)
+	require.Equal(t, int64(2*tlock.ChunkSize), partial.Bytes)
+	require.Equal(t, plaintext[:partial.Bytes], out.Bytes())
+}
@@ -0,0 +1,156 @@
+package tlock
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// ErrPassphraseRequired is returned when a ciphertext is additionally protected
+// by a passphrase but none was provided for decryption.
+var ErrPassphraseRequired = errors.New("ciphertext requires a passphrase")
+
+// ErrInvalidKDFParams is returned when argon2id parameters are out of the
+// accepted bounds, either when encrypting or when read from a header.
+var ErrInvalidKDFParams = errors.New("invalid argon2id parameters")
+
+// kdfArg is the stanza argument introducing the argon2id parameters of a
+// passphrase protected tlock stanza.
+const kdfArg = "argon2id"
+
+// kdfSaltSize is the size of the random argon2id salt.
+const kdfSaltSize = 16
+
+// These bounds keep a malicious header from making decryption arbitrarily
+// expensive. The paranoid preset uses the most memory allowed.
+const (
+	maxKDFTime   = 16
+	maxKDFMemory = 1024 * 1024
+)
+
+// KDFParams holds the argon2id parameters used to derive the passphrase mask of
+// the file key. They are recorded in the ciphertext header.
+type KDFParams struct {
+	Time    uint32 // Number of passes over the memory.
+	Memory  uint32 // Memory in KiB.
+	Threads uint8  // Degree of parallelism.
+}
+
+// These are the presets for the argon2id parameters.
+var (
+	KDFInteractive = KDFParams{Time: 2, Memory: 64 * 1024, Threads: 4}
+	KDFModerate    = KDFParams{Time: 3, Memory: 256 * 1024, Threads: 4}
+	KDFParanoid    = KDFParams{Time: 4, Memory: 1024 * 1024, Threads: 4}
+)
+
+// KDFPreset returns the argon2id parameters for the preset with the given name,
+// one of interactive, moderate or paranoid.
+func KDFPreset(name string) (KDFParams, error) {
+	switch name {
+	case "interactive":
+		return KDFInteractive, nil
+	case "moderate":
+		return KDFModerate, nil
+	case "paranoid":
+		return KDFParanoid, nil
+	default:
+		return KDFParams{}, fmt.Errorf("unknown kdf preset %q", name)
+	}
+}
+
+// validate checks that the parameters are within the accepted bounds.
+func (p KDFParams) validate() error {
+	if p.Time == 0 || p.Time > maxKDFTime {
+		return fmt.Errorf("%w: time %d", ErrInvalidKDFParams, p.Time)
+	}
+	if p.Memory < 8*uint32(p.Threads) || p.Memory > maxKDFMemory {
+		return fmt.Errorf("%w: memory %d KiB", ErrInvalidKDFParams, p.Memory)
+	}
+	if p.Threads == 0 {
+		return fmt.Errorf("%w: threads %d", ErrInvalidKDFParams, p.Threads)
+	}
+	return nil
+}
+
+// WithPassphrase returns a tlock which additionally requires the passphrase to
+// decrypt. The params are only used for encryption, decryption reads them
+// from the ciphertext header.
+func (t Tlock) WithPassphrase(passphrase string, params KDFParams) Tlock {
+	t.passphrase = passphrase
+	t.kdf = params
+	return t
+}
+
+// =============================================================================
+
+// passphraseMask derives a salt read from random and the mask to apply to a
+// file key of the given size. It returns the stanza arguments recording the
+// parameters.
+func passphraseMask(passphrase string, params KDFParams, size int, random io.Reader) ([]byte, []string, error) {
+	if err := approved("argon2id", "the passphrase"); err != nil {
+		return nil, nil, err
+	}
+	if err := params.validate(); err != nil {
+		return nil, nil, err
+	}
+
+	salt := make([]byte, kdfSaltSize)
+	if _, err := io.ReadFull(random, salt); err != nil {
+		return nil, nil, fmt.Errorf("read salt: %w", err)
+	}
+
+	mask := argon2.IDKey([]byte(passphrase), salt, params.Time, params.Memory, params.Threads, uint32(size))
+	args := []string{
+		kdfArg,
+		base64.RawStdEncoding.EncodeToString(salt),
+		strconv.FormatUint(uint64(params.Time), 10),
+		strconv.FormatUint(uint64(params.Memory), 10),
+		strconv.FormatUint(uint64(params.Threads), 10),
+	}
+
+	return mask, args, nil
+}
+
+// parsePassphraseArgs parses the salt and parameters following the argon2id
+// stanza argument.
+func parsePassphraseArgs(args []string) ([]byte, KDFParams, error) {
+	if len(args) != 4 {
+		return nil, KDFParams{}, fmt.Errorf("%w: expected 4 arguments, got %d", ErrInvalidKDFParams, len(args))
+	}
+
+	salt, err := base64.RawStdEncoding.Strict().DecodeString(args[0])
+	if err != nil || len(salt) != kdfSaltSize {
+		return nil, KDFParams{}, fmt.Errorf("%w: malformed salt", ErrInvalidKDFParams)
+	}
+
+	tm, err := strconv.ParseUint(args[1], 10, 32)
+	if err != nil {
+		return nil, KDFParams{}, fmt.Errorf("%w: time: %w", ErrInvalidKDFParams, err)
+	}
+	mem, err := strconv.ParseUint(args[2], 10, 32)
+	if err != nil {
+		return nil, KDFParams{}, fmt.Errorf("%w: memory: %w", ErrInvalidKDFParams, err)
+	}
+	threads, err := strconv.ParseUint(args[3], 10, 8)
+	if err != nil {
+		return nil, KDFParams{}, fmt.Errorf("%w: threads: %w", ErrInvalidKDFParams, err)
+	}
+
+	params := KDFParams{Time: uint32(tm), Memory: uint32(mem), Threads: uint8(threads)}
+	if err := params.validate(); err != nil {
+		return nil, KDFParams{}, err
+	}
+
+	return salt, params, nil
+}
+
+// xorInPlace xors mask into b.
+func xorInPlace(b, mask []byte) {
+	for i := range b {
+		b[i] ^= mask[i]
+	}
+}
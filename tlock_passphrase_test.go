@@ -0,0 +1,68 @@
+package tlock_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPassphraseRoundTrip(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+	params := tlock.KDFParams{Time: 1, Memory: 1024, Threads: 1}
+
+	var cipherData bytes.Buffer
+	err := tlock.New(network).WithPassphrase("correct horse", params).Encrypt(&cipherData, bytes.NewReader(loremBytes), 1000)
+	require.NoError(t, err)
+	require.Contains(t, cipherData.String(), " argon2id ")
+
+	t.Run("with the right passphrase", func(t *testing.T) {
+		var plainData bytes.Buffer
+		err := tlock.New(network).WithPassphrase("correct horse", tlock.KDFParams{}).Decrypt(&plainData, bytes.NewReader(cipherData.Bytes()))
+		require.NoError(t, err)
+		require.Equal(t, loremBytes, plainData.Bytes())
+	})
+
+	t.Run("with the wrong passphrase", func(t *testing.T) {
+		var plainData bytes.Buffer
+		err := tlock.New(network).WithPassphrase("battery staple", tlock.KDFParams{}).Decrypt(&plainData, bytes.NewReader(cipherData.Bytes()))
+		require.Error(t, err)
+	})
+
+	t.Run("without passphrase", func(t *testing.T) {
+		var plainData bytes.Buffer
+		err := tlock.New(network).Decrypt(&plainData, bytes.NewReader(cipherData.Bytes()))
+		require.ErrorIs(t, err, tlock.ErrPassphraseRequired)
+	})
+}
+
+func TestKDFPreset(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	for name, expected := range map[string]tlock.KDFParams{
+		"interactive": tlock.KDFInteractive,
+		"moderate":    tlock.KDFModerate,
+		"paranoid":    tlock.KDFParanoid,
+	} {
+		params, err := tlock.KDFPreset(name)
+		require.NoError(t, err)
+		require.Equal(t, expected, params)
+	}
+
+	_, err := tlock.KDFPreset("lax")
+	require.Error(t, err)
+
+	network := fixedtest.NewNetwork(t, 1000)
+	for _, params := range []tlock.KDFParams{
+		{},
+		{Time: 17, Memory: 64 * 1024, Threads: 4},
+		{Time: 1, Memory: 1024*1024 + 1, Threads: 4},
+	} {
+		err = tlock.New(network).WithPassphrase("pass", params).Encrypt(&bytes.Buffer{}, bytes.NewReader(loremBytes), 1000)
+		require.ErrorIs(t, err, tlock.ErrInvalidKDFParams, params)
+	}
+}
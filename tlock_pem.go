@@ -0,0 +1,93 @@
+package tlock
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/pem"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+)
+
+// ErrMalformedPEM is returned when no tlock PEM block can be decoded.
+var ErrMalformedPEM = errors.New("malformed tlock pem block")
+
+// PEMType is the type of the PEM blocks holding tlock ciphertexts.
+const PEMType = "TLOCK MESSAGE"
+
+// pemHeader is the opening line of tlock PEM blocks.
+const pemHeader = "-----BEGIN " + PEMType + "-----"
+
+// These are the headers set on tlock PEM blocks. They are informative only
+// and not authenticated, the ciphertext itself being authoritative.
+const (
+	PEMHeaderRound     = "Round"
+	PEMHeaderChainHash = "Chain-Hash"
+	PEMHeaderUnlockETA = "Unlock-ETA"
+)
+
+// NewPEMWriter returns a writer encoding the binary ciphertext written to it
+// as a PEM block, whose headers carry the round, chain hash and, when the
+// network exposes its chain information, the expected unlock time. The
+// ciphertext is buffered and encoded on Close.
+func NewPEMWriter(dst io.Writer, network Network) io.WriteCloser {
+	return &pemWriter{dst: dst, network: network}
+}
+
+// NewPEMReader returns a reader over the binary ciphertext held by the tlock
+// PEM block in src. Text surrounding the block is ignored.
+func NewPEMReader(src io.Reader) io.Reader {
+	return &pemReader{src: src}
+}
+
+type pemWriter struct {
+	dst     io.Writer
+	network Network
+	buf     bytes.Buffer
+}
+
+func (w *pemWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func (w *pemWriter) Close() error {
+	hdr, err := ReadHeader(bufio.NewReader(bytes.NewReader(w.buf.Bytes())))
+	if err != nil {
+		return err
+	}
+	roundNumber, chainHash, err := hdr.Round()
+	if err != nil {
+		return err
+	}
+
+	headers := map[string]string{
+		PEMHeaderRound:     strconv.FormatUint(roundNumber, 10),
+		PEMHeaderChainHash: chainHash,
+	}
+	if eta, ok := RoundTime(w.network, roundNumber); ok {
+		headers[PEMHeaderUnlockETA] = eta.UTC().Format(time.RFC3339)
+	}
+
+	return pem.Encode(w.dst, &pem.Block{Type: PEMType, Headers: headers, Bytes: w.buf.Bytes()})
+}
+
+type pemReader struct {
+	src io.Reader
+	r   io.Reader
+}
+
+func (r *pemReader) Read(p []byte) (int, error) {
+	if r.r == nil {
+		data, err := io.ReadAll(r.src)
+		if err != nil {
+			return 0, err
+		}
+		block, _ := pem.Decode(data)
+		if block == nil || block.Type != PEMType {
+			return 0, ErrMalformedPEM
+		}
+		r.r = bytes.NewReader(block.Bytes)
+	}
+	return r.r.Read(p)
+}
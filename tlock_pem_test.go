@@ -0,0 +1,52 @@
+package tlock_test
+
+import (
+	"bytes"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPEMRoundTrip(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+
+	var cipherData bytes.Buffer
+	w := tlock.NewPEMWriter(&cipherData, network)
+	require.NoError(t, tlock.New(network).Encrypt(w, bytes.NewReader(loremBytes), 1000))
+	require.NoError(t, w.Close())
+
+	block, _ := pem.Decode(cipherData.Bytes())
+	require.NotNil(t, block)
+	require.Equal(t, tlock.PEMType, block.Type)
+	require.Equal(t, "1000", block.Headers[tlock.PEMHeaderRound])
+	require.Equal(t, network.ChainHash(), block.Headers[tlock.PEMHeaderChainHash])
+	eta, err := time.Parse(time.RFC3339, block.Headers[tlock.PEMHeaderUnlockETA])
+	require.NoError(t, err)
+	expected, ok := tlock.RoundTime(network, 1000)
+	require.True(t, ok)
+	require.True(t, eta.Equal(expected))
+
+	// Embedded in a config file.
+	config := "key: value\nsecret: |\n" + cipherData.String() + "other: value\n"
+
+	var plainData bytes.Buffer
+	err = tlock.New(network).Decrypt(&plainData, tlock.NewPEMReader(strings.NewReader(config)))
+	require.NoError(t, err)
+	require.Equal(t, loremBytes, plainData.Bytes())
+
+	// Decrypt detects the PEM encoding on its own.
+	plainData.Reset()
+	err = tlock.New(network).Decrypt(&plainData, bytes.NewReader(cipherData.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, loremBytes, plainData.Bytes())
+
+	err = tlock.New(network).Decrypt(&plainData, tlock.NewPEMReader(strings.NewReader(config[:20])))
+	require.ErrorIs(t, err, tlock.ErrMalformedPEM)
+}
@@ -0,0 +1,249 @@
+package tlock
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"time"
+
+	"filippo.io/age"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// ErrNoPuzzle is returned when solving the time-lock puzzle of a ciphertext
+// encrypted without one.
+var ErrNoPuzzle = errors.New("no time-lock puzzle in header")
+
+// These constants define the time-lock puzzles wrapping file keys: the file
+// key, masked as in the tlock stanza, is sealed with a key derived from
+// 2^(2^T) modulo an RSA modulus, which takes T sequential squarings to compute
+// without the factors of the modulus.
+const (
+	puzzleStanzaType  = "tlock-puzzle"
+	puzzleModulusBits = 2048
+	puzzleModulusSize = puzzleModulusBits / 8
+	puzzleCheckEvery  = 1 << 16
+)
+
+// WithPuzzleFallback returns a tlock additionally wrapping the file keys of
+// the ciphertexts it encrypts in a time-lock puzzle taking the given number
+// of sequential squarings to solve, as PuzzleSquarings estimates them, so
+// that they remain recoverable with DecryptPuzzle should the drand networks
+// disappear, at the cost of the computation. Anyone holding a ciphertext can
+// start solving its puzzle right away, and faster hardware solves it sooner:
+// the ciphertexts are then only locked for as long as the squarings take.
+func (t Tlock) WithPuzzleFallback(squarings uint64) Tlock {
+	t.puzzle = squarings
+	return t
+}
+
+// SetPuzzle additionally wraps the filekeys in a time-lock puzzle taking the
+// given number of sequential squarings to solve.
+func (t *Recipient) SetPuzzle(squarings uint64) {
+	t.puzzle = squarings
+}
+
+// PuzzleSquarings measures the speed of the squarings of time-lock puzzles
+// on this machine, returning the number of them it performs in d.
+func PuzzleSquarings(d time.Duration) (uint64, error) {
+	if d <= 0 {
+		return 0, nil
+	}
+	n, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), puzzleModulusBits))
+	if err != nil {
+		return 0, err
+	}
+	n.SetBit(n, puzzleModulusBits-1, 1).SetBit(n, 0, 1)
+
+	b := big.NewInt(2)
+	var done uint64
+	start := time.Now()
+	for time.Since(start) < 100*time.Millisecond {
+		for range 1024 {
+			b.Mul(b, b).Mod(b, n)
+		}
+		done += 1024
+	}
+	return uint64(float64(done) * float64(d) / float64(time.Since(start))), nil
+}
+
+// DecryptPuzzle decrypts the source to the destination without the network,
+// solving the time-lock puzzle of the ciphertext, which takes as long as it
+// was set up to, until the context is done. The progress of the squarings is
+// reported to progress, when not nil.
+func (t Tlock) DecryptPuzzle(ctx context.Context, dst io.Writer, src io.Reader, progress func(done, total uint64)) error {
+	if err := approved("ChaCha20-Poly1305", "the age payload"); err != nil {
+		return err
+	}
+	return t.decryptPuzzle(dst, src, &puzzleIdentity{ctx: ctx, progress: progress})
+}
+
+// =============================================================================
+
+// decryptPuzzle decrypts the source to the destination, unwrapping the file
+// key with the puzzle identity.
+func (t Tlock) decryptPuzzle(dst io.Writer, src io.Reader, id *puzzleIdentity) error {
+	id.unmask = Identity{passphrase: t.passphrase, namespace: t.namespace, strict: t.strictDecode}
+	rr, err := t.dearmor(src)
+	if err != nil {
+		return fmt.Errorf("hybrid decrypt: %w", err)
+	}
+	r, err := age.Decrypt(rr, id)
+	if err != nil {
+		return fmt.Errorf("hybrid decrypt: %w", err)
+	}
+
+	cw := countingWriter{w: t.limitPlaintext(dst)}
+	if _, err := io.Copy(&cw, r); err != nil {
+		return &PartialDecryptionError{
+			Chunks: cw.n / ChunkSize,
+			Bytes:  cw.n,
+			Err:    fmt.Errorf("write: %w", err),
+		}
+	}
+	return nil
+}
+
+// wrapPuzzle returns the stanza of the time-lock puzzle sealing the masked
+// file key, the arguments of the masks following the number of squarings.
+func wrapPuzzle(data []byte, squarings uint64, extra []string, random io.Reader) (*age.Stanza, error) {
+	var p, q *big.Int
+	for p == nil || p.Cmp(q) == 0 {
+		var err error
+		if p, err = rand.Prime(random, puzzleModulusBits/2); err != nil {
+			return nil, err
+		}
+		if q, err = rand.Prime(random, puzzleModulusBits/2); err != nil {
+			return nil, err
+		}
+	}
+	one := big.NewInt(1)
+	n := new(big.Int).Mul(p, q)
+	phi := new(big.Int).Mul(new(big.Int).Sub(p, one), new(big.Int).Sub(q, one))
+
+	// The factors shortcut the squarings, reducing the exponent modulo phi.
+	e := new(big.Int).Exp(big.NewInt(2), new(big.Int).SetUint64(squarings), phi)
+	b := new(big.Int).Exp(big.NewInt(2), e, n)
+
+	aead, err := puzzleCipher(n, b)
+	if err != nil {
+		return nil, err
+	}
+	body := n.FillBytes(make([]byte, puzzleModulusSize))
+	body = aead.Seal(body, make([]byte, aead.NonceSize()), data, nil)
+
+	return &age.Stanza{
+		Type: puzzleStanzaType,
+		Args: append([]string{strconv.FormatUint(squarings, 10)}, extra...),
+		Body: body,
+	}, nil
+}
+
+// puzzleIdentity unwraps file keys by solving their time-lock puzzle, or with
+// the solution computed by someone else.
+type puzzleIdentity struct {
+	ctx      context.Context
+	progress func(done, total uint64)
+	solution *PuzzleSolution
+	unmask   Identity
+}
+
+func (p *puzzleIdentity) Unwrap(stanzas []*age.Stanza) ([]byte, error) {
+	if p.unmask.strict {
+		if err := checkStanzas(stanzas); err != nil {
+			return nil, err
+		}
+	}
+	s, squarings, n, err := findPuzzle(stanzas)
+	if err != nil {
+		return nil, err
+	}
+	unmask, err := p.unmask.unmasker(s.Args[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	var b *big.Int
+	if p.solution != nil {
+		if p.solution.Squarings != squarings || !bytes.Equal(p.solution.Modulus, s.Body[:puzzleModulusSize]) {
+			return nil, fmt.Errorf("%w: solution of another puzzle", ErrInvalidPuzzleProof)
+		}
+		b = new(big.Int).SetBytes(p.solution.Solution)
+	} else if b, err = square(p.ctx, n, squarings, 0, squarings, p.progress); err != nil {
+		return nil, err
+	}
+
+	aead, err := puzzleCipher(n, b)
+	if err != nil {
+		return nil, err
+	}
+	data, err := aead.Open(nil, make([]byte, aead.NonceSize()), s.Body[puzzleModulusSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("open puzzle: %w", err)
+	}
+	return unmask(data), nil
+}
+
+// findPuzzle returns the puzzle stanza among the stanzas, with its number of
+// squarings and its modulus.
+func findPuzzle(stanzas []*age.Stanza) (*age.Stanza, uint64, *big.Int, error) {
+	for _, s := range stanzas {
+		if s.Type != puzzleStanzaType || len(s.Args) < 1 {
+			continue
+		}
+		squarings, err := strconv.ParseUint(s.Args[0], 10, 64)
+		if err != nil {
+			return nil, 0, nil, fmt.Errorf("parse squarings: %w", err)
+		}
+		if len(s.Body) != puzzleModulusSize+fileKeySize+chacha20poly1305.Overhead {
+			return nil, 0, nil, fmt.Errorf("%w: invalid puzzle size %d", ErrMalformedHeader, len(s.Body))
+		}
+		n := new(big.Int).SetBytes(s.Body[:puzzleModulusSize])
+		if n.Bit(0) == 0 || n.BitLen() < puzzleModulusBits-1 {
+			return nil, 0, nil, fmt.Errorf("%w: invalid puzzle modulus", ErrMalformedHeader)
+		}
+		return s, squarings, n, nil
+	}
+	return nil, 0, nil, ErrNoPuzzle
+}
+
+// square computes 2^(2^squarings) modulo n, one squaring after the other,
+// reporting the progress from done out of total squarings.
+func square(ctx context.Context, n *big.Int, squarings uint64, done uint64, total uint64, progress func(done, total uint64)) (*big.Int, error) {
+	b := big.NewInt(2)
+	for i := uint64(0); i < squarings; i++ {
+		if i%puzzleCheckEvery == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if progress != nil {
+				progress(done+i, total)
+			}
+		}
+		b.Mul(b, b).Mod(b, n)
+	}
+	if progress != nil {
+		progress(done+squarings, total)
+	}
+	return b, nil
+}
+
+// puzzleCipher returns the AEAD keyed with the solution b of the puzzle
+// modulo n.
+func puzzleCipher(n *big.Int, b *big.Int) (cipher.AEAD, error) {
+	key := make([]byte, chacha20poly1305.KeySize)
+	solution := b.FillBytes(make([]byte, puzzleModulusSize))
+	modulus := n.FillBytes(make([]byte, puzzleModulusSize))
+	if _, err := io.ReadFull(hkdf.New(sha256.New, solution, modulus, []byte(puzzleStanzaType)), key); err != nil {
+		return nil, err
+	}
+	return newChaCha20Poly1305(key, "the time-lock puzzle")
+}
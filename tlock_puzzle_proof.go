@@ -0,0 +1,147 @@
+package tlock
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// ErrInvalidPuzzleProof is returned when the solution of a time-lock puzzle
+// doesn't come with a valid proof of the squarings, or belongs to another
+// puzzle.
+var ErrInvalidPuzzleProof = errors.New("invalid time-lock puzzle proof")
+
+// puzzleProofDomain separates the hashes deriving the primes of the proofs.
+const puzzleProofDomain = "tlock-puzzle-proof/v1"
+
+// PuzzleSolution is the solution of the time-lock puzzle of a ciphertext,
+// with the Wesolowski proof that it is 2^(2^Squarings) modulo Modulus. The
+// proof verifies in a few exponentiations, letting anyone check the work of
+// whoever solved the puzzle instead of repeating it. It serializes to JSON,
+// so that the solving can be delegated.
+type PuzzleSolution struct {
+	Squarings uint64 `json:"squarings"`
+	Modulus   []byte `json:"modulus"`
+	Solution  []byte `json:"solution"`
+	Proof     []byte `json:"proof"`
+}
+
+// SolvePuzzle solves the time-lock puzzle of the ciphertext read from the
+// source and proves the squarings, which takes twice as long as solving it
+// with DecryptPuzzle, until the context is done. The progress of the
+// squarings is reported to progress, when not nil. The solution decrypts the
+// ciphertext with DecryptPuzzleSolution.
+func SolvePuzzle(ctx context.Context, src io.Reader, progress func(done, total uint64)) (PuzzleSolution, error) {
+	h, err := ParseHeader(src)
+	if err != nil {
+		return PuzzleSolution{}, err
+	}
+	_, squarings, n, err := findPuzzle(h.Stanzas)
+	if err != nil {
+		return PuzzleSolution{}, err
+	}
+
+	y, err := square(ctx, n, squarings, 0, 2*squarings, progress)
+	if err != nil {
+		return PuzzleSolution{}, err
+	}
+	l := puzzleProofPrime(n, y, squarings)
+
+	// The proof is 2^floor(2^T/l), computed one bit of the quotient at a time.
+	x := big.NewInt(2)
+	pi := big.NewInt(1)
+	r := big.NewInt(1)
+	two := big.NewInt(2)
+	for i := uint64(0); i < squarings; i++ {
+		if i%puzzleCheckEvery == 0 {
+			if err := ctx.Err(); err != nil {
+				return PuzzleSolution{}, err
+			}
+			if progress != nil {
+				progress(squarings+i, 2*squarings)
+			}
+		}
+		r.Mul(r, two)
+		pi.Mul(pi, pi)
+		if r.Cmp(l) >= 0 {
+			r.Sub(r, l)
+			pi.Mul(pi, x)
+		}
+		pi.Mod(pi, n)
+	}
+	if progress != nil {
+		progress(2*squarings, 2*squarings)
+	}
+
+	return PuzzleSolution{
+		Squarings: squarings,
+		Modulus:   n.FillBytes(make([]byte, puzzleModulusSize)),
+		Solution:  y.FillBytes(make([]byte, puzzleModulusSize)),
+		Proof:     pi.FillBytes(make([]byte, puzzleModulusSize)),
+	}, nil
+}
+
+// Verify verifies the proof of the solution, as solutions received from
+// whoever solved the puzzle should be.
+func (s PuzzleSolution) Verify() error {
+	if len(s.Modulus) != puzzleModulusSize || len(s.Solution) != puzzleModulusSize || len(s.Proof) != puzzleModulusSize {
+		return fmt.Errorf("%w: invalid size", ErrInvalidPuzzleProof)
+	}
+	n := new(big.Int).SetBytes(s.Modulus)
+	y := new(big.Int).SetBytes(s.Solution)
+	pi := new(big.Int).SetBytes(s.Proof)
+	if n.Bit(0) == 0 || n.BitLen() < puzzleModulusBits-1 {
+		return fmt.Errorf("%w: invalid modulus", ErrInvalidPuzzleProof)
+	}
+	one := big.NewInt(1)
+	if y.Cmp(one) <= 0 || y.Cmp(n) >= 0 || pi.Sign() <= 0 || pi.Cmp(n) >= 0 {
+		return fmt.Errorf("%w: value out of range", ErrInvalidPuzzleProof)
+	}
+
+	// The proof holds when pi^l * 2^(2^T mod l) is the solution.
+	l := puzzleProofPrime(n, y, s.Squarings)
+	r := new(big.Int).Exp(big.NewInt(2), new(big.Int).SetUint64(s.Squarings), l)
+	lhs := new(big.Int).Exp(pi, l, n)
+	lhs.Mul(lhs, new(big.Int).Exp(big.NewInt(2), r, n)).Mod(lhs, n)
+	if lhs.Cmp(y) != 0 {
+		return ErrInvalidPuzzleProof
+	}
+	return nil
+}
+
+// DecryptPuzzleSolution decrypts the source to the destination without the
+// network, with the solution of the time-lock puzzle of the ciphertext, which
+// is verified first.
+func (t Tlock) DecryptPuzzleSolution(dst io.Writer, src io.Reader, s PuzzleSolution) error {
+	if err := approved("ChaCha20-Poly1305", "the age payload"); err != nil {
+		return err
+	}
+	if err := s.Verify(); err != nil {
+		return err
+	}
+	return t.decryptPuzzle(dst, src, &puzzleIdentity{solution: &s})
+}
+
+// =============================================================================
+
+// puzzleProofPrime returns the prime of the proof of the solution y of the
+// puzzle modulo n, hashed from the puzzle and its solution so that the prover
+// can't choose it.
+func puzzleProofPrime(n *big.Int, y *big.Int, squarings uint64) *big.Int {
+	h := sha256.New()
+	h.Write([]byte(puzzleProofDomain))
+	h.Write(n.FillBytes(make([]byte, puzzleModulusSize)))
+	h.Write(y.FillBytes(make([]byte, puzzleModulusSize)))
+	h.Write(binary.BigEndian.AppendUint64(nil, squarings))
+
+	l := new(big.Int).SetBytes(h.Sum(nil))
+	l.SetBit(l, 0, 1)
+	for !l.ProbablyPrime(20) {
+		l.Add(l, big.NewInt(2))
+	}
+	return l
+}
@@ -0,0 +1,112 @@
+package tlock_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPuzzleFallback(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+
+	var cipherData bytes.Buffer
+	require.NoError(t, tlock.New(network).WithPuzzleFallback(100000).Encrypt(&cipherData, bytes.NewReader(loremBytes), 1000))
+
+	// The beacon still decrypts the ciphertext.
+	var plainData bytes.Buffer
+	require.NoError(t, tlock.New(network).Decrypt(&plainData, bytes.NewReader(cipherData.Bytes())))
+	require.Equal(t, loremBytes, plainData.Bytes())
+
+	// So does solving the puzzle, without any network.
+	plainData.Reset()
+	var done, total uint64
+	progress := func(d, t uint64) { done, total = d, t }
+	require.NoError(t, tlock.New(nil).DecryptPuzzle(context.Background(), &plainData, bytes.NewReader(cipherData.Bytes()), progress))
+	require.Equal(t, loremBytes, plainData.Bytes())
+	require.Equal(t, uint64(100000), done)
+	require.Equal(t, uint64(100000), total)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := tlock.New(nil).DecryptPuzzle(ctx, io.Discard, bytes.NewReader(cipherData.Bytes()), nil)
+	require.ErrorIs(t, err, context.Canceled)
+
+	cipherData.Reset()
+	require.NoError(t, tlock.New(network).Encrypt(&cipherData, bytes.NewReader(loremBytes), 1000))
+	err = tlock.New(nil).DecryptPuzzle(context.Background(), io.Discard, bytes.NewReader(cipherData.Bytes()), nil)
+	require.ErrorIs(t, err, tlock.ErrNoPuzzle)
+}
+
+func TestPuzzlePassphrase(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+
+	var cipherData bytes.Buffer
+	tl := tlock.New(network).WithPassphrase("correct horse", tlock.KDFParams{Time: 1, Memory: 1024, Threads: 1}).WithPuzzleFallback(1000)
+	require.NoError(t, tl.Encrypt(&cipherData, bytes.NewReader(loremBytes), 1000))
+
+	// The puzzle reveals the file key masked by the passphrase only.
+	err := tlock.New(nil).DecryptPuzzle(context.Background(), io.Discard, bytes.NewReader(cipherData.Bytes()), nil)
+	require.ErrorIs(t, err, tlock.ErrPassphraseRequired)
+
+	var plainData bytes.Buffer
+	tl = tlock.New(nil).WithPassphrase("correct horse", tlock.KDFParams{})
+	require.NoError(t, tl.DecryptPuzzle(context.Background(), &plainData, bytes.NewReader(cipherData.Bytes()), nil))
+	require.Equal(t, loremBytes, plainData.Bytes())
+}
+
+func TestPuzzleSquarings(t *testing.T) {
+	squarings, err := tlock.PuzzleSquarings(time.Second)
+	require.NoError(t, err)
+	require.Greater(t, squarings, uint64(1000))
+	squarings, err = tlock.PuzzleSquarings(-time.Second)
+	require.NoError(t, err)
+	require.Zero(t, squarings)
+}
+
+func TestPuzzleSolution(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+
+	var cipherData bytes.Buffer
+	require.NoError(t, tlock.New(network).WithPuzzleFallback(10000).Encrypt(&cipherData, bytes.NewReader(loremBytes), 1000))
+
+	var done, total uint64
+	progress := func(d, t uint64) { done, total = d, t }
+	solution, err := tlock.SolvePuzzle(context.Background(), bytes.NewReader(cipherData.Bytes()), progress)
+	require.NoError(t, err)
+	require.Equal(t, uint64(10000), solution.Squarings)
+	require.Equal(t, uint64(20000), done)
+	require.Equal(t, uint64(20000), total)
+	require.NoError(t, solution.Verify())
+
+	// The solution decrypts the ciphertext without solving the puzzle again.
+	var plainData bytes.Buffer
+	require.NoError(t, tlock.New(nil).DecryptPuzzleSolution(&plainData, bytes.NewReader(cipherData.Bytes()), solution))
+	require.Equal(t, loremBytes, plainData.Bytes())
+
+	// Claiming fewer squarings, or another solution, doesn't verify.
+	forged := solution
+	forged.Squarings--
+	require.ErrorIs(t, forged.Verify(), tlock.ErrInvalidPuzzleProof)
+	forged = solution
+	forged.Solution = bytes.Clone(solution.Solution)
+	forged.Solution[len(forged.Solution)-1] ^= 1
+	require.ErrorIs(t, forged.Verify(), tlock.ErrInvalidPuzzleProof)
+
+	// Nor does the solution decrypt the ciphertexts of other puzzles.
+	cipherData.Reset()
+	require.NoError(t, tlock.New(network).WithPuzzleFallback(10000).Encrypt(&cipherData, bytes.NewReader(loremBytes), 1000))
+	err = tlock.New(nil).DecryptPuzzleSolution(io.Discard, bytes.NewReader(cipherData.Bytes()), solution)
+	require.ErrorIs(t, err, tlock.ErrInvalidPuzzleProof)
+}
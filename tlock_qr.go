@@ -0,0 +1,188 @@
+package tlock
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrMalformedQRSegment is returned when a QR segment can't be parsed.
+var ErrMalformedQRSegment = errors.New("malformed qr segment")
+
+// ErrIncompleteQRSegments is returned when the segments of a ciphertext are
+// missing, duplicated or belong to different ciphertexts.
+var ErrIncompleteQRSegments = errors.New("incomplete qr segments")
+
+// QRSegmentSize is a segment size, in characters, which keeps the QR codes
+// readable by common phone cameras.
+const QRSegmentSize = 1000
+
+// qrPrefix starts every QR segment.
+const qrPrefix = "TLE"
+
+// qrIDSize is the number of bytes of the ciphertext hash identifying the
+// segments belonging together.
+const qrIDSize = 4
+
+// base45Alphabet is the alphabet of RFC 9285, which is the set of characters
+// of the QR code alphanumeric mode.
+const base45Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+// SplitQR splits the ciphertext into segments of at most maxChars characters,
+// each to be rendered as its own QR code. The segments only use characters of
+// the QR alphanumeric mode, which encodes them most compactly, and have the
+// form TLE:<id>:<n>/<total>:<base45 data>.
+func SplitQR(ciphertext []byte, maxChars int) ([]string, error) {
+	if len(ciphertext) == 0 {
+		return nil, errors.New("empty ciphertext")
+	}
+
+	sum := sha256.Sum256(ciphertext)
+	id := strings.ToUpper(hex.EncodeToString(sum[:qrIDSize]))
+
+	// The prefix length depends on the number of segments, so grow the
+	// number of digits until the segments fit.
+	for digits := 1; digits <= 6; digits++ {
+		prefixLen := len(qrPrefix) + 1 + len(id) + 1 + 2*digits + 1 + 1
+		chunk := (maxChars - prefixLen) / 3 * 2
+		if chunk <= 0 {
+			return nil, fmt.Errorf("segment size %d too small", maxChars)
+		}
+		total := (len(ciphertext) + chunk - 1) / chunk
+		if len(strconv.Itoa(total)) > digits {
+			continue
+		}
+
+		segments := make([]string, 0, total)
+		for n := 0; n < total; n++ {
+			data := ciphertext[n*chunk : min((n+1)*chunk, len(ciphertext))]
+			segments = append(segments, fmt.Sprintf("%s:%s:%d/%d:%s", qrPrefix, id, n+1, total, encodeBase45(data)))
+		}
+		return segments, nil
+	}
+
+	return nil, fmt.Errorf("segment size %d too small", maxChars)
+}
+
+// JoinQR reassembles the ciphertext from its segments, which may be provided
+// in any order.
+func JoinQR(segments []string) ([]byte, error) {
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("%w: no segments", ErrIncompleteQRSegments)
+	}
+
+	var id string
+	var parts [][]byte
+	for _, segment := range segments {
+		fields := strings.SplitN(strings.TrimSpace(segment), ":", 4)
+		if len(fields) != 4 || fields[0] != qrPrefix {
+			return nil, fmt.Errorf("%w: %.20q", ErrMalformedQRSegment, segment)
+		}
+
+		position, count, ok := strings.Cut(fields[2], "/")
+		if !ok {
+			return nil, fmt.Errorf("%w: position %q", ErrMalformedQRSegment, fields[2])
+		}
+		n, err := strconv.Atoi(position)
+		if err != nil {
+			return nil, fmt.Errorf("%w: position %q", ErrMalformedQRSegment, fields[2])
+		}
+		total, err := strconv.Atoi(count)
+		if err != nil || total < 1 || n < 1 || n > total {
+			return nil, fmt.Errorf("%w: position %q", ErrMalformedQRSegment, fields[2])
+		}
+
+		// The total is untrusted, and only completes when given as many
+		// segments, bounding what is allocated for them.
+		if total > len(segments) {
+			return nil, fmt.Errorf("%w: %d segments of %d", ErrIncompleteQRSegments, len(segments), total)
+		}
+		if parts == nil {
+			id = fields[1]
+			parts = make([][]byte, total)
+		}
+		if fields[1] != id || total != len(parts) {
+			return nil, fmt.Errorf("%w: segments of different ciphertexts", ErrIncompleteQRSegments)
+		}
+		if parts[n-1] != nil {
+			return nil, fmt.Errorf("%w: duplicate segment %d", ErrIncompleteQRSegments, n)
+		}
+
+		data, err := decodeBase45(fields[3])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrMalformedQRSegment, err)
+		}
+		parts[n-1] = data
+	}
+
+	var ciphertext []byte
+	for i, part := range parts {
+		if part == nil {
+			return nil, fmt.Errorf("%w: missing segment %d of %d", ErrIncompleteQRSegments, i+1, len(parts))
+		}
+		ciphertext = append(ciphertext, part...)
+	}
+
+	sum := sha256.Sum256(ciphertext)
+	if strings.ToUpper(hex.EncodeToString(sum[:qrIDSize])) != id {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrIncompleteQRSegments)
+	}
+
+	return ciphertext, nil
+}
+
+// =============================================================================
+
+// encodeBase45 encodes data using the RFC 9285 base45 encoding.
+func encodeBase45(data []byte) string {
+	var sb strings.Builder
+	for i := 0; i+1 < len(data); i += 2 {
+		v := int(data[i])<<8 | int(data[i+1])
+		sb.WriteByte(base45Alphabet[v%45])
+		sb.WriteByte(base45Alphabet[v/45%45])
+		sb.WriteByte(base45Alphabet[v/45/45])
+	}
+	if len(data)%2 == 1 {
+		v := int(data[len(data)-1])
+		sb.WriteByte(base45Alphabet[v%45])
+		sb.WriteByte(base45Alphabet[v/45])
+	}
+	return sb.String()
+}
+
+// decodeBase45 decodes the RFC 9285 base45 encoded string s.
+func decodeBase45(s string) ([]byte, error) {
+	if len(s)%3 == 1 {
+		return nil, errors.New("invalid base45 length")
+	}
+
+	digits := make([]int, len(s))
+	for i := range s {
+		digits[i] = strings.IndexByte(base45Alphabet, s[i])
+		if digits[i] < 0 {
+			return nil, fmt.Errorf("invalid base45 character %q", s[i])
+		}
+	}
+
+	data := make([]byte, 0, len(s)/3*2+1)
+	for i := 0; i < len(digits); i += 3 {
+		if i+2 >= len(digits) {
+			v := digits[i] + digits[i+1]*45
+			if v > 0xff {
+				return nil, errors.New("invalid base45 value")
+			}
+			data = append(data, byte(v))
+			break
+		}
+		v := digits[i] + digits[i+1]*45 + digits[i+2]*45*45
+		if v > 0xffff {
+			return nil, errors.New("invalid base45 value")
+		}
+		data = append(data, byte(v>>8), byte(v))
+	}
+
+	return data, nil
+}
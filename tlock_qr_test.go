@@ -0,0 +1,66 @@
+package tlock_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQRSplitJoin(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+
+	var cipherData bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&cipherData, bytes.NewReader(dataFile), 1000))
+
+	segments, err := tlock.SplitQR(cipherData.Bytes(), 120)
+	require.NoError(t, err)
+	require.Greater(t, len(segments), 1)
+	for _, segment := range segments {
+		require.LessOrEqual(t, len(segment), 120)
+		require.Empty(t, strings.Trim(segment, "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"))
+	}
+
+	// Segments may be scanned in any order.
+	reversed := make([]string, len(segments))
+	for i, segment := range segments {
+		reversed[len(segments)-1-i] = segment
+	}
+	ciphertext, err := tlock.JoinQR(reversed)
+	require.NoError(t, err)
+	require.Equal(t, cipherData.Bytes(), ciphertext)
+
+	var plainData bytes.Buffer
+	require.NoError(t, tlock.New(network).Decrypt(&plainData, bytes.NewReader(ciphertext)))
+	require.Equal(t, dataFile, plainData.Bytes())
+
+	_, err = tlock.JoinQR(segments[1:])
+	require.ErrorIs(t, err, tlock.ErrIncompleteQRSegments)
+
+	_, err = tlock.JoinQR(append(segments, segments[0]))
+	require.ErrorIs(t, err, tlock.ErrIncompleteQRSegments)
+
+	_, err = tlock.JoinQR([]string{"TLE:garbage"})
+	require.ErrorIs(t, err, tlock.ErrMalformedQRSegment)
+
+	// A total beyond the segments given is refused before allocating.
+	_, err = tlock.JoinQR([]string{"TLE:00000000:1/2000000000:00"})
+	require.ErrorIs(t, err, tlock.ErrIncompleteQRSegments)
+}
+
+func TestQRSplitOddLengths(t *testing.T) {
+	for size := 1; size < 10; size++ {
+		data := bytes.Repeat([]byte{0xff}, size)
+		segments, err := tlock.SplitQR(data, 24)
+		require.NoError(t, err)
+
+		joined, err := tlock.JoinQR(segments)
+		require.NoError(t, err)
+		require.Equal(t, data, joined)
+	}
+}
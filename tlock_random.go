@@ -0,0 +1,78 @@
+package tlock
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// RandomAccessDecrypter provides random access to the plaintext of a binary
+// ciphertext, decrypting only the chunks being read. The chunks having a fixed
+// size, their offsets are computed rather than read from an index, so any
+// ciphertext can be accessed this way.
+type RandomAccessDecrypter struct {
+	stream *stream
+
+	mu    sync.Mutex
+	index int64 // Index of the cached chunk, -1 for none.
+	chunk []byte
+}
+
+// NewRandomAccessDecrypter unwraps the file key of the ciphertext of the given
+// size, which requires its round to be reached, and returns the decrypter
+// over its plaintext.
+func (t Tlock) NewRandomAccessDecrypter(src io.ReaderAt, size int64) (*RandomAccessDecrypter, error) {
+	s, err := t.openStream(src, size)
+	if err != nil {
+		return nil, fmt.Errorf("hybrid decrypt: %w", err)
+	}
+	if err := t.checkPlaintextSize(s.plaintextSize()); err != nil {
+		return nil, err
+	}
+	return &RandomAccessDecrypter{stream: s, index: -1}, nil
+}
+
+// Size returns the size of the plaintext.
+func (d *RandomAccessDecrypter) Size() int64 {
+	return d.stream.plaintextSize()
+}
+
+// ReadAt implements io.ReaderAt over the plaintext. Each chunk read is
+// authenticated before any of its bytes are returned.
+func (d *RandomAccessDecrypter) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+
+	var n int
+	for n < len(p) {
+		if off >= d.Size() {
+			return n, io.EOF
+		}
+		chunk, err := d.load(off / ChunkSize)
+		if err != nil {
+			return n, err
+		}
+		c := copy(p[n:], chunk[off%ChunkSize:])
+		n += c
+		off += int64(c)
+	}
+	return n, nil
+}
+
+// load returns the decrypted chunk with the given index, keeping the last one
+// around as reads are mostly sequential.
+func (d *RandomAccessDecrypter) load(index int64) ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.index != index {
+		chunk, err := d.stream.chunk(index)
+		if err != nil {
+			return nil, err
+		}
+		d.index, d.chunk = index, chunk
+	}
+	return d.chunk, nil
+}
@@ -0,0 +1,50 @@
+package tlock_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRandomAccessDecrypter(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+	plaintext := make([]byte, 2*tlock.ChunkSize+100)
+	for i := range plaintext {
+		plaintext[i] = byte(i * 7)
+	}
+
+	var cipherData bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&cipherData, bytes.NewReader(plaintext), 1000))
+
+	d, err := tlock.New(network).NewRandomAccessDecrypter(bytes.NewReader(cipherData.Bytes()), int64(cipherData.Len()))
+	require.NoError(t, err)
+	require.Equal(t, int64(len(plaintext)), d.Size())
+
+	for _, r := range []struct{ off, size int }{
+		{0, 10},
+		{tlock.ChunkSize - 5, 10},
+		{2 * tlock.ChunkSize, 100},
+		{10, 2*tlock.ChunkSize + 90},
+	} {
+		buf := make([]byte, r.size)
+		n, err := d.ReadAt(buf, int64(r.off))
+		require.NoError(t, err)
+		require.Equal(t, r.size, n)
+		require.Equal(t, plaintext[r.off:r.off+r.size], buf)
+	}
+
+	buf := make([]byte, 10)
+	n, err := d.ReadAt(buf, int64(len(plaintext))-5)
+	require.ErrorIs(t, err, io.EOF)
+	require.Equal(t, 5, n)
+
+	all, err := io.ReadAll(io.NewSectionReader(d, 0, d.Size()))
+	require.NoError(t, err)
+	require.Equal(t, plaintext, all)
+}
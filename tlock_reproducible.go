@@ -0,0 +1,318 @@
+package tlock
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	chain "github.com/drand/drand/v2/common"
+	"github.com/drand/drand/v2/crypto"
+	"github.com/drand/kyber"
+	"github.com/drand/kyber/encrypt/ibe"
+	"github.com/drand/kyber/group/mod"
+	"github.com/drand/kyber/pairing"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// ErrReproducibleSeed is returned when the reproducible seed is too short.
+var ErrReproducibleSeed = errors.New("reproducible seed too short")
+
+// ErrNotReproducible is returned when encrypting reproducibly with an option
+// or a suite drawing randomness of its own.
+var ErrNotReproducible = errors.New("can't encrypt reproducibly")
+
+// ErrPlaintextChanged is returned when a seekable plaintext changes between
+// the two reads of a reproducible encryption.
+var ErrPlaintextChanged = errors.New("plaintext changed while encrypting")
+
+// SigmaSuite is implemented by the suites able to encrypt with a given random
+// element rather than drawing one, which reproducible encryption requires.
+type SigmaSuite interface {
+	Suite
+	// EncryptWithSigma encrypts the data towards the identity with the random
+	// element sigma, as long as the data.
+	EncryptWithSigma(publicKey kyber.Point, id []byte, data []byte, sigma []byte) (*ibe.Ciphertext, error)
+}
+
+// These constants define the size of the reproducible seed and of the file
+// keys, which are the ones age generates.
+const (
+	minReproducibleSeedSize = 16
+	fileKeySize             = 16
+)
+
+// reproducibleLabel separates the uses of the reproducible seed.
+const reproducibleLabel = "tlock reproducible"
+
+// WithReproducibleSeed returns a tlock whose Encrypt writes the same bytes
+// each time it encrypts the same plaintext towards the same round with the
+// same options, letting locked artifacts be stored by the digest of their
+// ciphertext. The file key, the payload nonce and the random element of the
+// identity based encryption are derived from the seed, the plaintext and the
+// options rather than drawn, and the header is written in its canonical
+// encoding. The plaintext is read twice when src can seek, hashed and then
+// encrypted, and into memory otherwise. A seekable src must not change in
+// between: Encrypt fails with ErrPlaintextChanged when it does, and what it
+// wrote must then be discarded.
+//
+// As with convergent encryption, anyone holding ciphertexts encrypted with the
+// same seed learns which ones share their plaintext, and anyone holding the
+// seed can confirm a guess of a plaintext before the round is reached. The
+// seed must be kept as confidential as the plaintexts. Passphrases aren't
+// supported, their salt being random.
+func (t Tlock) WithReproducibleSeed(seed []byte) Tlock {
+	t.seed = seed
+	return t
+}
+
+// =============================================================================
+
+// encryptReproducible encrypts src as Encrypt does, deriving the randomness
+// from the seed of the tlock.
+func (t Tlock) encryptReproducible(dst io.Writer, src io.Reader, roundNumber uint64) error {
+	if len(t.seed) < minReproducibleSeedSize {
+		return fmt.Errorf("%w: %d bytes, at least %d are required", ErrReproducibleSeed, len(t.seed), minReproducibleSeedSize)
+	}
+	if t.passphrase != "" {
+		return fmt.Errorf("%w: passphrases use a random salt", ErrNotReproducible)
+	}
+	if t.puzzle > 0 {
+		return fmt.Errorf("%w: time-lock puzzles use random primes", ErrNotReproducible)
+	}
+
+	digest, src, err := plaintextDigest(src)
+	if err != nil {
+		return fmt.Errorf("read: %w", err)
+	}
+
+	r := Recipient{network: t.network, roundNumber: roundNumber, namespace: t.namespace, metadata: t.metadata, sealMetadata: t.sealMetadata}
+	prk, err := t.reproducibleKey(roundNumber, digest)
+	if err != nil {
+		return err
+	}
+	kdf := hkdf.Expand(sha256.New, prk, []byte(reproducibleLabel))
+	fileKey := make([]byte, fileKeySize)
+	nonce := make([]byte, streamNonceSize)
+	r.sigma = make([]byte, fileKeySize)
+	for _, b := range [][]byte{fileKey, nonce, r.sigma} {
+		if _, err := io.ReadFull(kdf, b); err != nil {
+			return err
+		}
+	}
+
+	stanzas, err := r.Wrap(fileKey)
+	if err != nil {
+		return fmt.Errorf("hybrid encrypt: %w", err)
+	}
+	hdr := Header{Stanzas: stanzas}
+	hdr.MAC = headerMAC(fileKey, &hdr)
+
+	bw := bufio.NewWriter(dst)
+	if err := hdr.Marshal(bw); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+	if _, err := bw.Write(nonce); err != nil {
+		return fmt.Errorf("write nonce: %w", err)
+	}
+
+	aead, err := newChaCha20Poly1305(hkdfKey(fileKey, nonce, "payload"), "the age payload")
+	if err != nil {
+		return err
+	}
+	h := sha256.New()
+	br := bufio.NewReaderSize(io.TeeReader(src, h), ChunkSize)
+	chunk := make([]byte, ChunkSize)
+	for index := int64(0); ; index++ {
+		n, err := io.ReadFull(br, chunk)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return fmt.Errorf("read: %w", err)
+		}
+		_, err = br.Peek(1)
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("read: %w", err)
+		}
+		last := err == io.EOF
+		if last && !bytes.Equal(h.Sum(nil), digest) {
+			return ErrPlaintextChanged
+		}
+		if _, err := bw.Write(sealChunk(aead, chunk[:n], index, last)); err != nil {
+			return fmt.Errorf("write: %w", err)
+		}
+		if last {
+			break
+		}
+	}
+
+	return bw.Flush()
+}
+
+// plaintextDigest hashes src and returns a reader of it from the start,
+// seeking back when src can seek and reading it into memory otherwise.
+func plaintextDigest(src io.Reader) ([]byte, io.Reader, error) {
+	h := sha256.New()
+	if rs, ok := src.(io.ReadSeeker); ok {
+		if start, err := rs.Seek(0, io.SeekCurrent); err == nil {
+			if _, err := io.Copy(h, rs); err != nil {
+				return nil, nil, err
+			}
+			if _, err := rs.Seek(start, io.SeekStart); err != nil {
+				return nil, nil, err
+			}
+			return h.Sum(nil), rs, nil
+		}
+	}
+
+	plaintext, err := io.ReadAll(src)
+	if err != nil {
+		return nil, nil, err
+	}
+	h.Write(plaintext)
+	return h.Sum(nil), bytes.NewReader(plaintext), nil
+}
+
+// reproducibleKey derives the key of a reproducible encryption from the seed,
+// the round, the chain, the options shaping the header and the digest of the
+// plaintext, so that no randomness is reused across distinct encryptions.
+func (t Tlock) reproducibleKey(roundNumber uint64, digest []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, t.seed)
+	mac.Write([]byte(reproducibleLabel))
+	mac.Write(binary.BigEndian.AppendUint64(nil, roundNumber))
+	for _, field := range []string{t.network.ChainHash(), t.namespace} {
+		mac.Write(binary.AppendUvarint(nil, uint64(len(field))))
+		mac.Write([]byte(field))
+	}
+	if t.metadata != nil {
+		body, err := marshalMetadata(t.metadata)
+		if err != nil {
+			return nil, err
+		}
+		mac.Write(binary.AppendUvarint(nil, uint64(len(body))))
+		mac.Write(body)
+	}
+	if t.sealMetadata {
+		mac.Write([]byte{1})
+	}
+
+	mac.Write(digest)
+	return mac.Sum(nil), nil
+}
+
+// timeLockWithSigma encrypts the data towards the round as TimeLock does, with
+// the given random element.
+func timeLockWithSigma(scheme crypto.Scheme, publicKey kyber.Point, roundNumber uint64, data []byte, sigma []byte) (*ibe.Ciphertext, error) {
+	if publicKey.Equal(publicKey.Null()) {
+		return nil, ErrInvalidPublicKey
+	}
+	suite, err := suiteFor(scheme.Name)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := suite.(SigmaSuite)
+	if !ok {
+		return nil, fmt.Errorf("%w: the suite of scheme %s draws its own randomness", ErrNotReproducible, scheme.Name)
+	}
+
+	id := scheme.DigestBeacon(&chain.Beacon{Round: roundNumber})
+	return s.EncryptWithSigma(publicKey, id, data, sigma)
+}
+
+// sealChunk encrypts the chunk of the payload with the given index.
+func sealChunk(aead cipher.AEAD, chunk []byte, index int64, last bool) []byte {
+	var nonce [chacha20poly1305.NonceSize]byte
+	for i, n := len(nonce)-2, index; i >= 0; i, n = i-1, n>>8 {
+		nonce[i] = byte(n)
+	}
+	if last {
+		nonce[len(nonce)-1] = lastChunkFlag
+	}
+	return aead.Seal(nil, nonce[:], chunk, nil)
+}
+
+// encryptCCA implements the CCA identity based encryption of kyber with the
+// random element sigma given, in the variant with the master key on G1 when
+// masterOnG1 is set and on G2 otherwise.
+func encryptCCA(s pairing.Suite, masterOnG1 bool, master kyber.Point, id, msg, sigma []byte) (*ibe.Ciphertext, error) {
+	if len(msg) > s.Hash().Size() {
+		return nil, errors.New("plaintext too long for the hash function provided")
+	}
+	if len(sigma) != len(msg) {
+		return nil, errors.New("sigma must be as long as the plaintext")
+	}
+
+	idGroup, uGroup := s.G2(), s.G1()
+	if !masterOnG1 {
+		idGroup, uGroup = s.G1(), s.G2()
+	}
+	hashable, ok := idGroup.Point().(kyber.HashablePoint)
+	if !ok {
+		return nil, errors.New("point needs to implement `kyber.HashablePoint`")
+	}
+	qid := hashable.Hash(id)
+	var gid kyber.Point
+	if masterOnG1 {
+		gid = s.Pair(master, qid)
+	} else {
+		gid = s.Pair(qid, master)
+	}
+
+	r, err := ibeH3(s, sigma, msg)
+	if err != nil {
+		return nil, err
+	}
+	u := uGroup.Point().Mul(r, uGroup.Point().Base())
+
+	rGid := gid.Mul(r, gid)
+	h := s.Hash()
+	h.Write(ibe.H2Tag())
+	if _, err := rGid.MarshalTo(h); err != nil {
+		return nil, fmt.Errorf("marshal gt: %w", err)
+	}
+	v := h.Sum(nil)[:len(msg)]
+	xorInPlace(v, sigma)
+
+	h = s.Hash()
+	h.Write(ibe.H4Tag())
+	h.Write(sigma)
+	w := h.Sum(nil)[:len(msg)]
+	xorInPlace(w, msg)
+
+	return &ibe.Ciphertext{U: u, V: v, W: w}, nil
+}
+
+// ibeH3 hashes sigma and the message to the scalar r, as kyber does.
+func ibeH3(s pairing.Suite, sigma, msg []byte) (kyber.Scalar, error) {
+	h := s.Hash()
+	h.Write(ibe.H3Tag())
+	h.Write(sigma)
+	h.Write(msg)
+	buffer := h.Sum(nil)
+
+	scalar, ok := s.G1().Scalar().(*mod.Int)
+	if !ok {
+		return nil, errors.New("unable to instantiate scalar as a mod.Int")
+	}
+	toMask := scalar.MarshalSize()*8 - scalar.M.BitLen()
+
+	for i := uint16(1); i < 65535; i++ {
+		h.Reset()
+		h.Write(binary.LittleEndian.AppendUint16(nil, i))
+		h.Write(buffer)
+		hashed := h.Sum(nil)
+		if scalar.BO == mod.BigEndian {
+			hashed[0] >>= toMask
+		} else {
+			hashed[len(hashed)-1] >>= toMask
+		}
+		if err := scalar.UnmarshalBinary(hashed); err == nil {
+			return scalar, nil
+		}
+	}
+	return nil, errors.New("rejection sampling failure")
+}
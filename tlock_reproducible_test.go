@@ -0,0 +1,118 @@
+package tlock_test
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/drand/drand/v2/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptReproducible(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	seed := []byte("0123456789abcdef0123456789abcdef")
+	plaintext := make([]byte, 3*tlock.ChunkSize+10)
+	rand.New(rand.NewSource(1)).Read(plaintext)
+
+	unchained := fixedtest.NewKey(crypto.NewPedersenBLSUnchained())
+	networks := map[string]tlock.Network{
+		"sigs on g1": fixedtest.NewNetwork(t, 1000),
+		"unchained":  unchained.Network(t, unchained.SignRound(t, 1000)),
+	}
+
+	for name, network := range networks {
+		t.Run(name, func(t *testing.T) {
+			tl := tlock.New(network).WithReproducibleSeed(seed).WithMetadata(tlock.UserMetadata{"b": "2", "a": "1"})
+			for _, size := range []int{0, 10, tlock.ChunkSize, len(plaintext)} {
+				var first, second bytes.Buffer
+				require.NoError(t, tl.Encrypt(&first, bytes.NewReader(plaintext[:size]), 1000))
+				require.NoError(t, tl.Encrypt(&second, bytes.NewReader(plaintext[:size]), 1000))
+				require.Equal(t, first.Bytes(), second.Bytes())
+
+				var out bytes.Buffer
+				require.NoError(t, tlock.New(network).Decrypt(&out, bytes.NewReader(first.Bytes())))
+				require.True(t, bytes.Equal(plaintext[:size], out.Bytes()))
+
+				// The ciphertext is a valid age file.
+				r, err := age.Decrypt(bytes.NewReader(first.Bytes()), tlock.NewIdentity(network, false))
+				require.NoError(t, err)
+				b, err := io.ReadAll(r)
+				require.NoError(t, err)
+				require.True(t, bytes.Equal(plaintext[:size], b))
+			}
+		})
+	}
+
+	network := fixedtest.NewNetwork(t, 1000)
+	encrypt := func(tl tlock.Tlock, plaintext []byte, roundNumber uint64) []byte {
+		var cipherData bytes.Buffer
+		require.NoError(t, tl.Encrypt(&cipherData, bytes.NewReader(plaintext), roundNumber))
+		return cipherData.Bytes()
+	}
+	tl := tlock.New(network).WithReproducibleSeed(seed)
+	reference := encrypt(tl, plaintext[:10], 1000)
+	require.NotEqual(t, reference, encrypt(tl, plaintext[1:11], 1000))
+	require.NotEqual(t, reference, encrypt(tl, plaintext[:10], 1001))
+	require.NotEqual(t, reference, encrypt(tl.WithNamespace("backups"), plaintext[:10], 1000))
+	require.NotEqual(t, reference, encrypt(tlock.New(network).WithReproducibleSeed([]byte("fedcba9876543210")), plaintext[:10], 1000))
+	require.NotEqual(t, reference, encrypt(tlock.New(network), plaintext[:10], 1000))
+
+	err := tlock.New(network).WithReproducibleSeed([]byte("short")).Encrypt(io.Discard, bytes.NewReader(plaintext), 1000)
+	require.ErrorIs(t, err, tlock.ErrReproducibleSeed)
+	err = tl.WithPassphrase("secret", tlock.KDFParams{Time: 1, Memory: 1024, Threads: 1}).Encrypt(io.Discard, bytes.NewReader(plaintext), 1000)
+	require.ErrorIs(t, err, tlock.ErrNotReproducible)
+}
+
+func TestEncryptReproducibleSeekable(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+	tl := tlock.New(network).WithReproducibleSeed([]byte("0123456789abcdef"))
+	plaintext := make([]byte, 2*tlock.ChunkSize+10)
+	rand.New(rand.NewSource(1)).Read(plaintext)
+
+	// Seekable plaintexts are read twice rather than into memory, and encrypt
+	// to the same bytes.
+	var seekable, piped bytes.Buffer
+	require.NoError(t, tl.Encrypt(&seekable, bytes.NewReader(plaintext), 1000))
+	require.NoError(t, tl.Encrypt(&piped, io.MultiReader(bytes.NewReader(plaintext)), 1000))
+	require.Equal(t, piped.Bytes(), seekable.Bytes())
+
+	changing := changingReader{Reader: bytes.NewReader(plaintext), plaintext: plaintext}
+	err := tl.Encrypt(io.Discard, &changing, 1000)
+	require.ErrorIs(t, err, tlock.ErrPlaintextChanged)
+}
+
+// changingReader changes its plaintext when seeked back to the start.
+type changingReader struct {
+	*bytes.Reader
+	plaintext []byte
+}
+
+func (r *changingReader) Seek(offset int64, whence int) (int64, error) {
+	if offset == 0 && whence == io.SeekStart {
+		r.plaintext[0]++
+	}
+	return r.Reader.Seek(offset, whence)
+}
+
+func TestEncryptConvergentReproducible(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	plaintext := make([]byte, 1024*1024)
+	rand.New(rand.NewSource(1)).Read(plaintext)
+
+	tl := tlock.New(network).WithReproducibleSeed(secret)
+	var first, second bytes.Buffer
+	require.NoError(t, tl.EncryptConvergent(&first, bytes.NewReader(plaintext), 1000, secret))
+	require.NoError(t, tl.EncryptConvergent(&second, bytes.NewReader(plaintext), 1000, secret))
+	require.Equal(t, first.Bytes(), second.Bytes())
+}
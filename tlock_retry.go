@@ -0,0 +1,61 @@
+package tlock
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	chain "github.com/drand/drand/v2/common"
+	dchain "github.com/drand/drand/v2/common/chain"
+)
+
+// retryMinDelay is the first delay between attempts once the time of the
+// round passed, the beacon possibly not being published yet.
+const retryMinDelay = 100 * time.Millisecond
+
+// retryJitterDivisor sets the jitter added when sleeping until a round to a
+// fraction of the period, so consumers don't all hit the network at once.
+const retryJitterDivisor = 10
+
+// RetryUntilUnlocked calls fn until it returns something else than
+// ErrTooEarly, which is returned. It sleeps until the time at which the chain
+// emits the round, then backs off exponentially up to one period while the
+// beacon isn't available yet. It returns the context error when the context
+// is done first.
+func RetryUntilUnlocked(ctx context.Context, fn func() error, info *dchain.Info, roundNumber uint64) error {
+	roundTime := time.Unix(chain.TimeOfRound(info.Period, info.GenesisTime, roundNumber), 0)
+	maxDelay := max(info.Period, retryMinDelay)
+	delay := retryMinDelay
+
+	for {
+		err := fn()
+		if !errors.Is(err, ErrTooEarly) {
+			return err
+		}
+
+		var wait time.Duration
+		if until := time.Until(roundTime); until > 0 {
+			wait = until + jitter(info.Period/retryJitterDivisor)
+		} else {
+			wait = delay + jitter(delay/2)
+			delay = min(2*delay, maxDelay)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// jitter returns a random duration in [0, d).
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d)
+}
@@ -0,0 +1,49 @@
+package tlock_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	dchain "github.com/drand/drand/v2/common/chain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryUntilUnlocked(t *testing.T) {
+	info := &dchain.Info{Period: time.Second, GenesisTime: time.Now().Unix() - 10}
+
+	var calls int
+	err := tlock.RetryUntilUnlocked(context.Background(), func() error {
+		if calls++; calls < 3 {
+			return tlock.ErrTooEarly
+		}
+		return nil
+	}, info, 5)
+	require.NoError(t, err)
+	require.Equal(t, 3, calls)
+
+	errOther := errors.New("other")
+	calls = 0
+	err = tlock.RetryUntilUnlocked(context.Background(), func() error {
+		calls++
+		return errOther
+	}, info, 5)
+	require.ErrorIs(t, err, errOther)
+	require.Equal(t, 1, calls)
+}
+
+func TestRetryUntilUnlockedCanceled(t *testing.T) {
+	info := &dchain.Info{Period: time.Second, GenesisTime: time.Now().Unix()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := tlock.RetryUntilUnlocked(ctx, func() error {
+		return tlock.ErrTooEarly
+	}, info, 1000)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), time.Second)
+}
@@ -0,0 +1,66 @@
+package tlock
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Rewrap rewrites the ciphertext read from src so that it can only be
+// decrypted once the specified round is reached, writing the binary result to
+// dst. The round of the ciphertext must have been reached to recover its file
+// key, which is then wrapped towards the new round while the payload and the
+// metadata are copied unchanged. Since the file key doesn't change, whoever
+// decrypted the original ciphertext can decrypt the rewrapped one too.
+func (t Tlock) Rewrap(dst io.Writer, src io.Reader, roundNumber uint64) error {
+	if err := checkUnchained(t.network); err != nil {
+		return err
+	}
+
+	rr := dearmor(src)
+	hdr, err := ReadHeader(rr)
+	if err != nil {
+		return err
+	}
+
+	id := Identity{network: t.network, trustChainhash: t.trustChainhash, passphrase: t.passphrase, namespace: t.namespace, enforceWindow: t.enforceWindow}
+	fileKey, err := id.Unwrap(hdr.Stanzas)
+	if err != nil {
+		return fmt.Errorf("unwrap: %w", err)
+	}
+	if !hmac.Equal(headerMAC(fileKey, hdr), hdr.MAC) {
+		return errors.New("bad header MAC")
+	}
+
+	r := Recipient{network: t.network, roundNumber: roundNumber, passphrase: t.passphrase, kdf: t.kdf, namespace: t.namespace}
+	stanzas, err := r.Wrap(fileKey)
+	if err != nil {
+		return fmt.Errorf("wrap: %w", err)
+	}
+	if meta := hdr.metadataStanza(); meta != nil {
+		stanzas = append(stanzas, meta)
+	}
+	rewrapped := Header{Stanzas: stanzas}
+	rewrapped.MAC = headerMAC(fileKey, &rewrapped)
+
+	if err := rewrapped.Marshal(dst); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+	if _, err := io.Copy(dst, rr); err != nil {
+		return fmt.Errorf("write payload: %w", err)
+	}
+
+	return nil
+}
+
+// headerMAC computes the MAC of the header with the file key.
+func headerMAC(fileKey []byte, hdr *Header) []byte {
+	var buf bytes.Buffer
+	_ = hdr.MarshalWithoutMAC(&buf)
+	mac := hmac.New(sha256.New, hkdfKey(fileKey, nil, "header"))
+	mac.Write(buf.Bytes())
+	return mac.Sum(nil)
+}
@@ -0,0 +1,44 @@
+package tlock_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRewrap(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	key := fixedtest.NewKey(nil)
+	networkAt := func(roundNumber uint64) *fixed.Network {
+		return key.Network(t, key.SignRound(t, roundNumber))
+	}
+
+	var cipherData bytes.Buffer
+	require.NoError(t, tlock.New(networkAt(1000)).Encrypt(&cipherData, bytes.NewReader(loremBytes), 1000))
+
+	var rewrapped bytes.Buffer
+	require.NoError(t, tlock.New(networkAt(1000)).Rewrap(&rewrapped, bytes.NewReader(cipherData.Bytes()), 2000))
+
+	hdr, err := tlock.ParseHeader(bytes.NewReader(rewrapped.Bytes()))
+	require.NoError(t, err)
+	roundNumber, _, err := hdr.Round()
+	require.NoError(t, err)
+	require.Equal(t, uint64(2000), roundNumber)
+
+	// The beacon of the original round no longer decrypts it.
+	err = tlock.New(networkAt(1000)).Decrypt(&bytes.Buffer{}, bytes.NewReader(rewrapped.Bytes()))
+	require.Error(t, err)
+
+	var plainData bytes.Buffer
+	require.NoError(t, tlock.New(networkAt(2000)).Decrypt(&plainData, bytes.NewReader(rewrapped.Bytes())))
+	require.Equal(t, loremBytes, plainData.Bytes())
+
+	// The file key can't be recovered before the original round.
+	err = tlock.New(networkAt(999)).Rewrap(&bytes.Buffer{}, bytes.NewReader(cipherData.Bytes()), 2000)
+	require.Error(t, err)
+}
@@ -0,0 +1,90 @@
+package tlock
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+
+	dchain "github.com/drand/drand/v2/common/chain"
+)
+
+// RoundInfo describes a round a ciphertext depends on.
+type RoundInfo struct {
+	Round     uint64
+	ChainHash string
+
+	// NotAfter is set for the round closing the decryption window recorded by
+	// the metadata, rather than for a round unlocking the ciphertext.
+	NotAfter bool
+
+	// Earliest, Expected and Latest bound when the round is reached, as
+	// EstimateUnlock computes them. They are zero when the network doesn't
+	// expose the times of its rounds or is of another chain.
+	Earliest time.Time
+	Expected time.Time
+	Latest   time.Time
+}
+
+// Rounds returns the rounds the ciphertext in src depends on, so that tools
+// scheduling its decryption don't have to decrypt it. The ciphertext is read
+// through the reader unwrap returns when it isn't nil, such as NewCMSReader,
+// or else in any of the encodings ParseHeader accepts.
+func Rounds(src io.Reader, unwrap func(io.Reader) io.Reader, network Network) ([]RoundInfo, error) {
+	if unwrap != nil {
+		src = unwrap(src)
+	}
+	hdr, err := ParseHeader(src)
+	if err != nil {
+		return nil, err
+	}
+	return hdr.Rounds(network)
+}
+
+// Rounds returns the rounds of the tlock stanzas of the header, any of which
+// unlocks the ciphertext, then its not-after round if its metadata record one
+// in the clear, each sorted by round. The times of the rounds are estimated
+// from the chain information of the network, which may be nil. The rounds
+// only decryption reveals, such as those of sealed metadata or of the
+// ciphertexts nested in the plaintext, aren't listed.
+func (h *Header) Rounds(network Network) ([]RoundInfo, error) {
+	var rounds []RoundInfo
+	for _, s := range h.Stanzas {
+		if s.Type != "tlock" || len(s.Args) < 2 {
+			continue
+		}
+		roundNumber, err := strconv.ParseUint(s.Args[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse block round: %w", err)
+		}
+		r := RoundInfo{Round: roundNumber, ChainHash: s.Args[1]}
+		if !slices.Contains(rounds, r) {
+			rounds = append(rounds, r)
+		}
+	}
+	if len(rounds) == 0 {
+		return nil, ErrNoTlockStanza
+	}
+	slices.SortFunc(rounds, func(a, b RoundInfo) int {
+		return cmp.Compare(a.Round, b.Round)
+	})
+
+	last, ok, err := h.NotAfter()
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		rounds = append(rounds, RoundInfo{Round: last, ChainHash: rounds[0].ChainHash, NotAfter: true})
+	}
+
+	if n, ok := network.(interface{ Info() *dchain.Info }); ok && n.Info() != nil {
+		for i, r := range rounds {
+			if r.ChainHash == network.ChainHash() {
+				rounds[i].Earliest, rounds[i].Expected, rounds[i].Latest = EstimateUnlock(n.Info(), r.Round)
+			}
+		}
+	}
+	return rounds, nil
+}
@@ -0,0 +1,59 @@
+package tlock_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRounds(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+
+	var cipherData bytes.Buffer
+	require.NoError(t, tlock.New(network).WithNotAfter(2000).Encrypt(&cipherData, strings.NewReader("hello"), 1000))
+	rounds, err := tlock.Rounds(bytes.NewReader(cipherData.Bytes()), nil, network)
+	require.NoError(t, err)
+	require.Len(t, rounds, 2)
+	require.Equal(t, uint64(1000), rounds[0].Round)
+	require.Equal(t, network.ChainHash(), rounds[0].ChainHash)
+	require.False(t, rounds[0].NotAfter)
+	earliest, expected, latest := tlock.EstimateUnlock(network.Info(), 1000)
+	require.Equal(t, earliest, rounds[0].Earliest)
+	require.Equal(t, expected, rounds[0].Expected)
+	require.Equal(t, latest, rounds[0].Latest)
+	require.Equal(t, uint64(2000), rounds[1].Round)
+	require.True(t, rounds[1].NotAfter)
+
+	// Any of the stanzas of a ciphertext encrypted towards several rounds
+	// unlocks it.
+	cipherData.Reset()
+	w, err := age.Encrypt(&cipherData, tlock.NewRecipient(network, 3000), tlock.NewRecipient(network, 1000))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	rounds, err = tlock.Rounds(bytes.NewReader(cipherData.Bytes()), nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, []tlock.RoundInfo{
+		{Round: 1000, ChainHash: network.ChainHash()},
+		{Round: 3000, ChainHash: network.ChainHash()},
+	}, rounds)
+
+	// The ciphertext is read through the unwrapping reader.
+	var cms bytes.Buffer
+	cw := tlock.NewCMSWriter(&cms)
+	require.NoError(t, tlock.New(network).Encrypt(cw, strings.NewReader("hello"), 1000))
+	require.NoError(t, cw.Close())
+	rounds, err = tlock.Rounds(&cms, tlock.NewCMSReader, network)
+	require.NoError(t, err)
+	require.Len(t, rounds, 1)
+	require.Equal(t, uint64(1000), rounds[0].Round)
+
+	_, err = tlock.Rounds(strings.NewReader("age-encryption.org/v1\n--- "+strings.Repeat("A", 43)+"\n"), nil, network)
+	require.ErrorIs(t, err, tlock.ErrNoTlockStanza)
+}
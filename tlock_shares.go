@@ -0,0 +1,329 @@
+package tlock
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// ErrMalformedShare is returned when a share of a ciphertext can't be parsed
+// or fails its checksum.
+var ErrMalformedShare = errors.New("malformed share")
+
+// ErrIncompatibleShares is returned when combining too few shares, or shares
+// of different ciphertexts.
+var ErrIncompatibleShares = errors.New("incompatible shares")
+
+// MaxShares is the largest number of shares a ciphertext splits into.
+const MaxShares = 255
+
+// These constants define the layout of shares: the intro, the ID of the
+// split, the index of the share, the threshold and the number of shares, the
+// share of the ciphertext, and the SHA-256 of what precedes.
+const (
+	shareIntro      = "tlock-share/v1\n"
+	shareIDSize     = 16
+	shareHeaderSize = len(shareIntro) + shareIDSize + 3
+	shareChunkSize  = 64 * 1024
+)
+
+// NewShareWriter returns a writer splitting what is written to it into a
+// share per destination with Shamir's secret sharing over GF(256), so that any
+// threshold of the shares reassemble it with NewShareReader while fewer reveal
+// nothing of it. Storing a share of a ciphertext per storage provider, none
+// of them holds the locked data. The shares are as large as the ciphertext,
+// plus a header and a checksum written once the writer is closed.
+func NewShareWriter(dsts []io.Writer, threshold int) (io.WriteCloser, error) {
+	return newShareWriter(dsts, threshold, rand.Reader)
+}
+
+// NewShareWriter returns a share writer as the function of the same name does,
+// drawing the ID of the split and the coefficients of the shares from the
+// source of WithRandom.
+func (t Tlock) NewShareWriter(dsts []io.Writer, threshold int) (io.WriteCloser, error) {
+	return newShareWriter(dsts, threshold, randomReader(t.random))
+}
+
+// newShareWriter returns the share writer, drawing its randomness from random.
+func newShareWriter(dsts []io.Writer, threshold int, random io.Reader) (io.WriteCloser, error) {
+	if len(dsts) < 2 || len(dsts) > MaxShares {
+		return nil, fmt.Errorf("%d shares, between 2 and %d are required", len(dsts), MaxShares)
+	}
+	if threshold < 2 || threshold > len(dsts) {
+		return nil, fmt.Errorf("threshold %d, between 2 and the %d shares is required", threshold, len(dsts))
+	}
+
+	var id [shareIDSize]byte
+	if _, err := io.ReadFull(random, id[:]); err != nil {
+		return nil, fmt.Errorf("read share id: %w", err)
+	}
+
+	w := shareWriter{
+		dsts:   make([]io.Writer, len(dsts)),
+		hashes: make([]hash.Hash, len(dsts)),
+		bufs:   make([][]byte, len(dsts)),
+		degree: threshold - 1,
+		random: random,
+	}
+	for i, dst := range dsts {
+		w.hashes[i] = sha256.New()
+		w.dsts[i] = io.MultiWriter(dst, w.hashes[i])
+
+		var hdr bytes.Buffer
+		hdr.WriteString(shareIntro)
+		hdr.Write(id[:])
+		hdr.Write([]byte{byte(i + 1), byte(threshold), byte(len(dsts))})
+		if _, err := w.dsts[i].Write(hdr.Bytes()); err != nil {
+			return nil, fmt.Errorf("write share header: %w", err)
+		}
+	}
+	return &w, nil
+}
+
+// NewShareReader returns a reader of what was split into the shares, given in
+// any order. Any threshold of the shares of a split are enough, the others
+// being ignored. The checksums of the shares are verified as their end is
+// read, failing the read with ErrMalformedShare.
+func NewShareReader(srcs []io.Reader) (io.Reader, error) {
+	r := shareReader{}
+	var id []byte
+	var threshold int
+	seen := make(map[byte]bool)
+	for _, src := range srcs {
+		s := shareSource{r: bufio.NewReaderSize(src, shareChunkSize+sha256.Size), h: sha256.New()}
+		hdr := make([]byte, shareHeaderSize)
+		if _, err := io.ReadFull(s.r, hdr); err != nil {
+			return nil, fmt.Errorf("%w: read header: %v", ErrMalformedShare, err)
+		}
+		s.h.Write(hdr)
+		if string(hdr[:len(shareIntro)]) != shareIntro {
+			return nil, fmt.Errorf("%w: not a tlock share", ErrMalformedShare)
+		}
+		hdr = hdr[len(shareIntro):]
+		x, k, n := hdr[shareIDSize], int(hdr[shareIDSize+1]), int(hdr[shareIDSize+2])
+		if x == 0 || int(x) > n || k < 2 || k > n {
+			return nil, fmt.Errorf("%w: share %d, threshold %d of %d", ErrMalformedShare, x, k, n)
+		}
+		switch {
+		case id == nil:
+			id, threshold = hdr[:shareIDSize], k
+		case !bytes.Equal(id, hdr[:shareIDSize]) || k != threshold:
+			return nil, fmt.Errorf("%w: shares of different ciphertexts", ErrIncompatibleShares)
+		}
+		if seen[x] {
+			continue
+		}
+		seen[x] = true
+		if len(r.srcs) < threshold {
+			s.x = x
+			r.srcs = append(r.srcs, &s)
+		}
+	}
+	if id == nil || len(r.srcs) < threshold {
+		return nil, fmt.Errorf("%w: %d distinct shares, %d are required", ErrIncompatibleShares, len(r.srcs), threshold)
+	}
+
+	// The shares are combined by interpolating their polynomials at zero,
+	// with the Lagrange basis of their indexes.
+	r.basis = make([]byte, len(r.srcs))
+	for i, si := range r.srcs {
+		l := byte(1)
+		for j, sj := range r.srcs {
+			if i != j {
+				l = gfMul(l, gfDiv(sj.x, sj.x^si.x))
+			}
+		}
+		r.basis[i] = l
+	}
+	r.bufs = make([][]byte, len(r.srcs))
+	for i := range r.bufs {
+		r.bufs[i] = make([]byte, shareChunkSize)
+	}
+	return &r, nil
+}
+
+// =============================================================================
+
+// shareWriter splits the bytes written into shares.
+type shareWriter struct {
+	dsts   []io.Writer
+	hashes []hash.Hash
+	bufs   [][]byte
+	degree int
+	random io.Reader
+	err    error
+}
+
+// Write splits each byte of p, evaluating at the index of each share a random
+// polynomial of degree threshold - 1 whose constant term is the byte.
+func (w *shareWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	written := 0
+	for len(p) > 0 {
+		chunk := p[:min(len(p), shareChunkSize)]
+		for i := range w.bufs {
+			w.bufs[i] = w.bufs[i][:0]
+		}
+		random := make([]byte, len(chunk)*w.degree)
+		if _, err := io.ReadFull(w.random, random); err != nil {
+			w.err = fmt.Errorf("read random: %w", err)
+			return written, w.err
+		}
+		for k, b := range chunk {
+			coeffs := random[k*w.degree : (k+1)*w.degree]
+			for i := range w.dsts {
+				x := byte(i + 1)
+				// Horner's rule, from the highest degree coefficient.
+				y := byte(0)
+				for c := len(coeffs) - 1; c >= 0; c-- {
+					y = gfMul(y, x) ^ coeffs[c]
+				}
+				w.bufs[i] = append(w.bufs[i], gfMul(y, x)^b)
+			}
+		}
+		clear(random)
+		for i, dst := range w.dsts {
+			if _, err := dst.Write(w.bufs[i]); err != nil {
+				w.err = fmt.Errorf("write share %d: %w", i+1, err)
+				return written, w.err
+			}
+		}
+		written += len(chunk)
+		p = p[len(chunk):]
+	}
+	return written, nil
+}
+
+// Close writes the checksum of each share.
+func (w *shareWriter) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	for i, dst := range w.dsts {
+		if _, err := dst.Write(w.hashes[i].Sum(nil)); err != nil {
+			w.err = fmt.Errorf("write share %d: %w", i+1, err)
+			return w.err
+		}
+	}
+	w.err = errors.New("share writer closed")
+	return nil
+}
+
+// shareSource is a share being read, whose trailing checksum is held back.
+type shareSource struct {
+	r *bufio.Reader
+	h hash.Hash
+	x byte
+}
+
+// read reads up to len(p) bytes of the share, verifying its checksum and
+// returning io.EOF once only the checksum is left.
+func (s *shareSource) read(p []byte) (int, error) {
+	b, err := s.r.Peek(len(p) + sha256.Size)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, err
+	}
+	if len(b) < sha256.Size {
+		return 0, fmt.Errorf("%w: truncated", ErrMalformedShare)
+	}
+	n := copy(p, b[:len(b)-sha256.Size])
+	s.h.Write(p[:n])
+	if _, err := s.r.Discard(n); err != nil {
+		return 0, err
+	}
+	if n > 0 {
+		return n, nil
+	}
+	if subtle.ConstantTimeCompare(s.h.Sum(nil), b) != 1 {
+		return 0, fmt.Errorf("%w: checksum mismatch of share %d", ErrMalformedShare, s.x)
+	}
+	return 0, io.EOF
+}
+
+// shareReader combines the threshold shares read in lockstep.
+type shareReader struct {
+	srcs  []*shareSource
+	basis []byte
+	bufs  [][]byte
+	err   error
+}
+
+func (r *shareReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	size := min(len(p), shareChunkSize)
+
+	n := -1
+	for i, s := range r.srcs {
+		m, err := s.read(r.bufs[i][:size])
+		if err != nil && !errors.Is(err, io.EOF) {
+			r.err = err
+			return 0, err
+		}
+		if n >= 0 && m != n {
+			r.err = fmt.Errorf("%w: shares of different sizes", ErrMalformedShare)
+			return 0, r.err
+		}
+		n = m
+	}
+	if n == 0 {
+		r.err = io.EOF
+		return 0, io.EOF
+	}
+
+	for k := range n {
+		var b byte
+		for i := range r.srcs {
+			b ^= gfMul(r.basis[i], r.bufs[i][k])
+		}
+		p[k] = b
+	}
+	return n, nil
+}
+
+// gfExp and gfLog are the exponentials and logarithms of the generator 3 of
+// GF(256) with the polynomial of AES, x^8 + x^4 + x^3 + x + 1.
+var gfExp, gfLog = func() ([510]byte, [256]byte) {
+	var exp [510]byte
+	var log [256]byte
+	x := byte(1)
+	for i := range 255 {
+		exp[i], exp[i+255] = x, x
+		log[x] = byte(i)
+		// Multiply by 3, that is x * 2 + x.
+		x2 := x << 1
+		if x&0x80 != 0 {
+			x2 ^= 0x1b
+		}
+		x ^= x2
+	}
+	return exp, log
+}()
+
+// gfMul multiplies in GF(256).
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+// gfDiv divides in GF(256), b being non zero.
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+255-int(gfLog[b])]
+}
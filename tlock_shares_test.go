@@ -0,0 +1,123 @@
+package tlock_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShares(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+
+	var cipherData bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&cipherData, bytes.NewReader(dataFile), 1000))
+
+	shares := splitShares(t, cipherData.Bytes(), 5, 3)
+	for _, share := range shares {
+		require.NotContains(t, string(share), string(cipherData.Bytes()[:64]))
+	}
+
+	// Any three shares, in any order, reassemble the ciphertext.
+	for _, pick := range [][]int{{0, 1, 2}, {4, 2, 0}, {3, 1, 4}, {0, 1, 2, 3, 4}} {
+		var srcs []io.Reader
+		for _, i := range pick {
+			srcs = append(srcs, bytes.NewReader(shares[i]))
+		}
+		ciphertext := combineShares(t, srcs)
+		require.Equal(t, cipherData.Bytes(), ciphertext)
+
+		var plainData bytes.Buffer
+		require.NoError(t, tlock.New(network).Decrypt(&plainData, bytes.NewReader(ciphertext)))
+		require.Equal(t, dataFile, plainData.Bytes())
+	}
+
+	// Two shares, or three of which two are the same, are not enough.
+	_, err := tlock.NewShareReader([]io.Reader{bytes.NewReader(shares[0]), bytes.NewReader(shares[1])})
+	require.ErrorIs(t, err, tlock.ErrIncompatibleShares)
+	_, err = tlock.NewShareReader([]io.Reader{bytes.NewReader(shares[0]), bytes.NewReader(shares[1]), bytes.NewReader(shares[1])})
+	require.ErrorIs(t, err, tlock.ErrIncompatibleShares)
+
+	// Shares of different splits don't combine.
+	other := splitShares(t, cipherData.Bytes(), 5, 3)
+	_, err = tlock.NewShareReader([]io.Reader{bytes.NewReader(shares[0]), bytes.NewReader(shares[1]), bytes.NewReader(other[2])})
+	require.ErrorIs(t, err, tlock.ErrIncompatibleShares)
+}
+
+func TestSharesAllRequired(t *testing.T) {
+	data := bytes.Repeat([]byte("tlock"), 30000)
+	shares := splitShares(t, data, 2, 2)
+
+	joined := combineShares(t, []io.Reader{bytes.NewReader(shares[1]), bytes.NewReader(shares[0])})
+	require.Equal(t, data, joined)
+
+	_, err := tlock.NewShareReader([]io.Reader{bytes.NewReader(shares[0])})
+	require.ErrorIs(t, err, tlock.ErrIncompatibleShares)
+}
+
+func TestSharesCorrupted(t *testing.T) {
+	data := bytes.Repeat([]byte{0x42}, 1000)
+	shares := splitShares(t, data, 3, 2)
+
+	corrupted := bytes.Clone(shares[0])
+	corrupted[len(corrupted)/2] ^= 1
+	r, err := tlock.NewShareReader([]io.Reader{bytes.NewReader(corrupted), bytes.NewReader(shares[1])})
+	require.NoError(t, err)
+	_, err = io.ReadAll(r)
+	require.ErrorIs(t, err, tlock.ErrMalformedShare)
+
+	r, err = tlock.NewShareReader([]io.Reader{bytes.NewReader(shares[0][:len(shares[0])-1]), bytes.NewReader(shares[1])})
+	require.NoError(t, err)
+	_, err = io.ReadAll(r)
+	require.ErrorIs(t, err, tlock.ErrMalformedShare)
+
+	_, err = tlock.NewShareReader([]io.Reader{bytes.NewReader([]byte("garbage")), bytes.NewReader(shares[1])})
+	require.ErrorIs(t, err, tlock.ErrMalformedShare)
+}
+
+func TestSharesInvalidCounts(t *testing.T) {
+	for _, c := range []struct{ n, k int }{{1, 1}, {3, 1}, {3, 4}, {256, 2}} {
+		dsts := make([]io.Writer, c.n)
+		for i := range dsts {
+			dsts[i] = io.Discard
+		}
+		_, err := tlock.NewShareWriter(dsts, c.k)
+		require.Error(t, err, "%d of %d", c.k, c.n)
+	}
+}
+
+// =============================================================================
+
+func splitShares(t *testing.T, data []byte, n, k int) [][]byte {
+	t.Helper()
+	bufs := make([]bytes.Buffer, n)
+	dsts := make([]io.Writer, n)
+	for i := range bufs {
+		dsts[i] = &bufs[i]
+	}
+	w, err := tlock.NewShareWriter(dsts, k)
+	require.NoError(t, err)
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	shares := make([][]byte, n)
+	for i := range bufs {
+		shares[i] = bufs[i].Bytes()
+	}
+	return shares
+}
+
+func combineShares(t *testing.T, srcs []io.Reader) []byte {
+	t.Helper()
+	r, err := tlock.NewShareReader(srcs)
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return data
+}
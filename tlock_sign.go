@@ -0,0 +1,107 @@
+package tlock
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrBadSignature is returned when the signature of a ciphertext doesn't
+// verify with the author key.
+var ErrBadSignature = errors.New("invalid ciphertext signature")
+
+// SignaturePEMType is the type of the PEM block of a detached signature.
+const SignaturePEMType = "TLOCK SIGNATURE"
+
+// signatureDomain separates the signed digest from other uses of SHA-256.
+const signatureDomain = "tlock signature v1\n"
+
+// Sign signs the ciphertext in src, which may be binary, age armored, PEM
+// encoded or in a JSON envelope, with the Ed25519 key of its author. It
+// returns the PEM encoded detached signature, covering the header and the
+// hash of each chunk of the payload, which can be verified before the round
+// is reached.
+func Sign(src io.Reader, key ed25519.PrivateKey) ([]byte, error) {
+	digest, err := signatureDigest(src)
+	if err != nil {
+		return nil, err
+	}
+	signature := ed25519.Sign(key, digest)
+	return pem.EncodeToMemory(&pem.Block{Type: SignaturePEMType, Bytes: signature}), nil
+}
+
+// VerifySignature checks the detached signature made by Sign over the
+// ciphertext in src with the public key of its author, returning
+// ErrBadSignature when it doesn't verify.
+func VerifySignature(src io.Reader, signature []byte, key ed25519.PublicKey) error {
+	block, _ := pem.Decode(signature)
+	if block == nil || block.Type != SignaturePEMType || len(block.Bytes) != ed25519.SignatureSize {
+		return fmt.Errorf("%w: malformed signature", ErrBadSignature)
+	}
+
+	digest, err := signatureDigest(src)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(key, digest, block.Bytes) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// =============================================================================
+
+// signatureDigest computes the digest signed for the ciphertext in src. It
+// chains the hash of the header, followed by the payload nonce, and the hash
+// of each chunk of the payload, so that the binary ciphertext is covered
+// without holding it in memory.
+func signatureDigest(src io.Reader) ([]byte, error) {
+	rr := dearmor(src)
+	hdr, err := ReadHeader(rr)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := hdr.Marshal(&buf); err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, streamNonceSize)
+	if _, err := io.ReadFull(rr, nonce); err != nil {
+		return nil, fmt.Errorf("%w: read nonce: %w", ErrMalformedPayload, err)
+	}
+	buf.Write(nonce)
+
+	h := sha256.New()
+	h.Write([]byte(signatureDomain))
+	headerHash := sha256.Sum256(buf.Bytes())
+	h.Write(headerHash[:])
+
+	if err := hashChunks(h, rr); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// hashChunks writes the hash of each encrypted chunk read from src to w.
+func hashChunks(w io.Writer, src *bufio.Reader) error {
+	chunk := make([]byte, encChunkSize)
+	for {
+		n, err := io.ReadFull(src, chunk)
+		if n > 0 {
+			chunkHash := sha256.Sum256(chunk[:n])
+			w.Write(chunkHash[:])
+		}
+		switch {
+		case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+			return nil
+		case err != nil:
+			return fmt.Errorf("read payload: %w", err)
+		}
+	}
+}
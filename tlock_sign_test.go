@@ -0,0 +1,49 @@
+package tlock_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignature(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+	plaintext := bytes.Repeat([]byte("a"), 2*tlock.ChunkSize+10)
+
+	var cipherData bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&cipherData, bytes.NewReader(plaintext), 1000))
+	ciphertext := cipherData.Bytes()
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	signature, err := tlock.Sign(bytes.NewReader(ciphertext), priv)
+	require.NoError(t, err)
+	require.NoError(t, tlock.VerifySignature(bytes.NewReader(ciphertext), signature, pub))
+
+	// The signature covers the binary ciphertext, whatever its encoding.
+	var armored bytes.Buffer
+	w := tlock.NewArmorWriter(&armored)
+	_, err = w.Write(ciphertext)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, tlock.VerifySignature(&armored, signature, pub))
+
+	other, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	require.ErrorIs(t, tlock.VerifySignature(bytes.NewReader(ciphertext), signature, other), tlock.ErrBadSignature)
+
+	tampered := bytes.Clone(ciphertext)
+	tampered[len(tampered)-tlock.ChunkSize] ^= 1
+	require.ErrorIs(t, tlock.VerifySignature(bytes.NewReader(tampered), signature, pub), tlock.ErrBadSignature)
+
+	truncated := ciphertext[:len(ciphertext)-10]
+	require.ErrorIs(t, tlock.VerifySignature(bytes.NewReader(truncated), signature, pub), tlock.ErrBadSignature)
+
+	require.ErrorIs(t, tlock.VerifySignature(bytes.NewReader(ciphertext), []byte("not a signature"), pub), tlock.ErrBadSignature)
+}
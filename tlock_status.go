@@ -0,0 +1,96 @@
+package tlock
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	dchain "github.com/drand/drand/v2/common/chain"
+)
+
+// ErrNoStatus is returned by Status for networks unable to report the latest
+// round of their chain.
+var ErrNoStatus = errors.New("network doesn't report its chain status")
+
+// DegradedThreshold is how far behind its schedule a chain may lag while
+// being reported as degraded rather than halted.
+const DegradedThreshold = time.Minute
+
+// Health summarizes the status of a chain.
+type Health string
+
+// These are the health levels of a chain.
+const (
+	Healthy  Health = "healthy"  // Rounds are published on time.
+	Degraded Health = "degraded" // Rounds are late, up to DegradedThreshold.
+	Halted   Health = "halted"   // Rounds are late by more than DegradedThreshold.
+)
+
+// ChainStatus describes how a chain keeps up with its schedule. Once it
+// isn't healthy, the rounds due in the meantime unlock late, as they are only
+// published when the chain catches up.
+type ChainStatus struct {
+	LatestRound   uint64        // The latest round published.
+	ExpectedRound uint64        // The round due from the wall clock.
+	Behind        time.Duration // How late the latest round is.
+	Health        Health
+}
+
+// StatusReporter is implemented by the networks able to probe their chain
+// by themselves, such as those spanning several relays.
+type StatusReporter interface {
+	Status(ctx context.Context) (ChainStatus, error)
+}
+
+// NewChainStatus returns the status of a chain of the period whose latest
+// round is latest while expected is due. A round behind is tolerated as
+// healthy, relays publishing rounds shortly after their time.
+func NewChainStatus(latest uint64, expected uint64, period time.Duration) ChainStatus {
+	s := ChainStatus{LatestRound: latest, ExpectedRound: expected, Health: Healthy}
+	if latest >= expected {
+		return s
+	}
+
+	lag := expected - latest
+	s.Behind = time.Duration(lag) * period
+	switch {
+	case lag <= 1:
+	case s.Behind <= DegradedThreshold:
+		s.Health = Degraded
+	default:
+		s.Health = Halted
+	}
+	return s
+}
+
+// Status probes the status of the chain of the network. Networks which don't
+// implement StatusReporter must report their latest round, with a
+// LatestRoundContext or LatestRound method, and expose their chain
+// information.
+func Status(ctx context.Context, network Network) (ChainStatus, error) {
+	if n, ok := network.(StatusReporter); ok {
+		return n.Status(ctx)
+	}
+	n, ok := network.(interface{ Info() *dchain.Info })
+	if !ok || n.Info() == nil {
+		return ChainStatus{}, ErrNoStatus
+	}
+
+	var latest uint64
+	var err error
+	switch n := network.(type) {
+	case interface {
+		LatestRoundContext(context.Context) (uint64, error)
+	}:
+		latest, err = n.LatestRoundContext(ctx)
+	case interface{ LatestRound() (uint64, error) }:
+		latest, err = n.LatestRound()
+	default:
+		return ChainStatus{}, ErrNoStatus
+	}
+	if err != nil {
+		return ChainStatus{}, err
+	}
+
+	return NewChainStatus(latest, network.Current(time.Now()), n.Info().Period), nil
+}
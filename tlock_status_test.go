@@ -0,0 +1,40 @@
+package tlock_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewChainStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		latest   uint64
+		expected uint64
+		health   tlock.Health
+		behind   time.Duration
+	}{
+		{name: "on time", latest: 100, expected: 100, health: tlock.Healthy},
+		{name: "ahead of the clock", latest: 101, expected: 100, health: tlock.Healthy},
+		{name: "publication lag", latest: 99, expected: 100, health: tlock.Healthy, behind: 3 * time.Second},
+		{name: "late", latest: 90, expected: 100, health: tlock.Degraded, behind: 30 * time.Second},
+		{name: "halted", latest: 50, expected: 100, health: tlock.Halted, behind: 150 * time.Second},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := tlock.NewChainStatus(test.latest, test.expected, 3*time.Second)
+			require.Equal(t, test.health, s.Health)
+			require.Equal(t, test.behind, s.Behind)
+		})
+	}
+}
+
+func TestStatusWithoutReporter(t *testing.T) {
+	network := fixedtest.NewNetwork(t, 1000)
+	_, err := tlock.Status(context.Background(), network)
+	require.ErrorIs(t, err, tlock.ErrNoStatus)
+}
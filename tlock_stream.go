@@ -0,0 +1,197 @@
+package tlock
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// ErrMalformedPayload is returned when the payload of a ciphertext doesn't
+// have a valid chunk layout.
+var ErrMalformedPayload = errors.New("malformed age payload")
+
+// ErrInvalidResumeOffset is returned when resuming decryption past the end of
+// the plaintext.
+var ErrInvalidResumeOffset = errors.New("resume offset past the end of the plaintext")
+
+// These constants define the age STREAM encryption of the payload.
+const (
+	streamNonceSize = 16
+	streamTagSize   = chacha20poly1305.Overhead
+	encChunkSize    = ChunkSize + streamTagSize
+	lastChunkFlag   = 0x01
+)
+
+// DecryptFrom resumes the decryption of the binary ciphertext in src, written
+// plaintext bytes having already been written to the destination by a previous
+// attempt. Only the chunks from the one holding that offset onwards are read
+// and decrypted.
+func (t Tlock) DecryptFrom(dst io.Writer, src io.ReadSeeker, written int64) error {
+	size, err := src.Seek(0, io.SeekEnd)
+	if err != nil {
+		return fmt.Errorf("seek: %w", err)
+	}
+
+	s, err := t.openStream(&seekReaderAt{rs: src}, size)
+	if err != nil {
+		return fmt.Errorf("hybrid decrypt: %w", err)
+	}
+	if written < 0 || written > s.plaintextSize() {
+		return fmt.Errorf("%w: %d > %d", ErrInvalidResumeOffset, written, s.plaintextSize())
+	}
+	if err := t.checkPlaintextSize(s.plaintextSize()); err != nil {
+		return err
+	}
+
+	cw := countingWriter{w: dst}
+	first, skip := written/ChunkSize, written%ChunkSize
+	for i := first; i < s.chunks(); i++ {
+		plaintext, err := s.chunk(i)
+		if err == nil {
+			if i == first {
+				plaintext = plaintext[skip:]
+			}
+			_, err = cw.Write(plaintext)
+		}
+		if err != nil {
+			return &PartialDecryptionError{
+				Chunks: (written + cw.n) / ChunkSize,
+				Bytes:  written + cw.n,
+				Err:    fmt.Errorf("write: %w", err),
+			}
+		}
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// stream provides random access to the chunks of an age payload.
+type stream struct {
+	src   io.ReaderAt
+	aead  cipher.AEAD
+	start int64 // Offset of the first chunk.
+	size  int64 // Size of all the chunks.
+}
+
+// openStream parses the header of the binary ciphertext of the given size,
+// unwraps the file key and verifies the header MAC, returning the payload.
+func (t Tlock) openStream(src io.ReaderAt, size int64) (*stream, error) {
+	sr := io.NewSectionReader(src, 0, size)
+	br := bufio.NewReader(sr)
+	hdr, err := ReadHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	pos, err := sr.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	headerSize := pos - int64(br.Buffered())
+
+	fileKey, err := t.unwrapFileKey(hdr)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, streamNonceSize)
+	if _, err := src.ReadAt(nonce, headerSize); err != nil {
+		return nil, fmt.Errorf("read nonce: %w", err)
+	}
+	aead, err := newChaCha20Poly1305(hkdfKey(fileKey, nonce, "payload"), "the age payload")
+	if err != nil {
+		return nil, err
+	}
+
+	s := stream{
+		src:   src,
+		aead:  aead,
+		start: headerSize + streamNonceSize,
+		size:  size - headerSize - streamNonceSize,
+	}
+	if last := s.size - (s.chunks()-1)*encChunkSize; s.size < streamTagSize || (s.chunks() > 1 && last == streamTagSize) {
+		return nil, ErrMalformedPayload
+	}
+
+	return &s, nil
+}
+
+// chunks returns the number of chunks of the payload.
+func (s *stream) chunks() int64 {
+	return (s.size + encChunkSize - 1) / encChunkSize
+}
+
+// plaintextSize returns the size of the decrypted payload.
+func (s *stream) plaintextSize() int64 {
+	return s.size - s.chunks()*streamTagSize
+}
+
+// chunk reads and decrypts the chunk with the given index.
+func (s *stream) chunk(index int64) ([]byte, error) {
+	buf := make([]byte, min(encChunkSize, s.size-index*encChunkSize))
+	if _, err := s.src.ReadAt(buf, s.start+index*encChunkSize); err != nil {
+		return nil, fmt.Errorf("read chunk %d: %w", index, err)
+	}
+
+	return openChunk(s.aead, buf, index, index == s.chunks()-1)
+}
+
+// openChunk decrypts the chunk with the given index in place.
+func openChunk(aead cipher.AEAD, buf []byte, index int64, last bool) ([]byte, error) {
+	var nonce [chacha20poly1305.NonceSize]byte
+	for i, n := len(nonce)-2, index; i >= 0; i, n = i-1, n>>8 {
+		nonce[i] = byte(n)
+	}
+	if last {
+		nonce[len(nonce)-1] = lastChunkFlag
+	}
+
+	plaintext, err := aead.Open(buf[:0], nonce[:], buf, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt chunk %d: %w", index, err)
+	}
+	return plaintext, nil
+}
+
+// unwrapFileKey unwraps the file key of the header and verifies the header
+// MAC with it.
+func (t Tlock) unwrapFileKey(hdr *Header) ([]byte, error) {
+	id := Identity{network: t.network, trustChainhash: t.trustChainhash, passphrase: t.passphrase, namespace: t.namespace, enforceWindow: t.enforceWindow, strict: t.strictDecode}
+	fileKey, err := id.Unwrap(hdr.Stanzas)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(headerMAC(fileKey, hdr), hdr.MAC) {
+		return nil, errors.New("bad header MAC")
+	}
+	return fileKey, nil
+}
+
+// hkdfKey derives a 32 bytes key from the file key as age does.
+func hkdfKey(fileKey, salt []byte, info string) []byte {
+	key := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, fileKey, salt, []byte(info)), key); err != nil {
+		panic("hkdf: " + err.Error())
+	}
+	return key
+}
+
+// seekReaderAt implements io.ReaderAt by seeking the underlying reader.
+type seekReaderAt struct {
+	rs io.ReadSeeker
+}
+
+func (r *seekReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if _, err := r.rs.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return io.ReadFull(r.rs, p)
+}
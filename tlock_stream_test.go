@@ -0,0 +1,58 @@
+package tlock_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecryptFrom(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+	plaintext := make([]byte, 3*tlock.ChunkSize+100)
+	for i := range plaintext {
+		plaintext[i] = byte(i * 7)
+	}
+
+	var cipherData bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&cipherData, bytes.NewReader(plaintext), 1000))
+	ciphertext := cipherData.Bytes()
+
+	for _, written := range []int64{0, 1, tlock.ChunkSize, 2*tlock.ChunkSize + 5, int64(len(plaintext))} {
+		var out bytes.Buffer
+		out.Write(plaintext[:written])
+		require.NoError(t, tlock.New(network).DecryptFrom(&out, bytes.NewReader(ciphertext), written))
+		require.Equal(t, plaintext, out.Bytes(), "written %d", written)
+	}
+
+	err := tlock.New(network).DecryptFrom(&bytes.Buffer{}, bytes.NewReader(ciphertext), int64(len(plaintext))+1)
+	require.ErrorIs(t, err, tlock.ErrInvalidResumeOffset)
+
+	// The header MAC is verified, as age would.
+	tampered := bytes.Clone(ciphertext)
+	mac := bytes.Index(tampered, []byte("\n--- ")) + len("\n--- ")
+	if tampered[mac] == 'A' {
+		tampered[mac] = 'B'
+	} else {
+		tampered[mac] = 'A'
+	}
+	err = tlock.New(network).DecryptFrom(&bytes.Buffer{}, bytes.NewReader(tampered), 0)
+	require.ErrorContains(t, err, "bad header MAC")
+}
+
+func TestDecryptFromEmpty(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+
+	var cipherData bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&cipherData, bytes.NewReader(nil), 1000))
+
+	var out bytes.Buffer
+	require.NoError(t, tlock.New(network).DecryptFrom(&out, bytes.NewReader(cipherData.Bytes()), 0))
+	require.Zero(t, out.Len())
+}
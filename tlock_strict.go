@@ -0,0 +1,202 @@
+package tlock
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// ErrNonCanonical is returned when decrypting strictly a ciphertext which
+// isn't encoded exactly as tlock encodes it.
+var ErrNonCanonical = errors.New("non-canonical ciphertext encoding")
+
+// StrictDecode returns a tlock which, rather than leniently accepting what
+// can be made sense of, rejects on decryption the ciphertexts with trailing
+// data, with an encoding other than the one tlock produces, or with
+// duplicate stanzas. This suits services which must treat any ambiguity of
+// the format as an attack, a ciphertext then having a single accepted
+// encoding. In particular:
+//
+//   - the armor must start at its header line and end at its footer line,
+//     without the hint line of NewHintedArmorWriter,
+//   - the PEM blocks and JSON envelopes must be encoded as their writers
+//     encode them, with a round and chain hash matching the ciphertext,
+//   - the stanzas must appear once, with canonical arguments.
+func (t Tlock) StrictDecode() Tlock {
+	t.strictDecode = true
+	return t
+}
+
+// SetStrictDecode sets whether the stanzas are rejected when repeated or
+// when their arguments aren't canonical.
+func (t *Identity) SetStrictDecode(strict bool) {
+	t.strict = strict
+}
+
+// =============================================================================
+
+// dearmor returns a reader over the binary ciphertext in src as the dearmor
+// function does, or as strictDearmor does when the tlock decodes strictly.
+func (t Tlock) dearmor(src io.Reader) (*bufio.Reader, error) {
+	if !t.strictDecode {
+		return dearmor(src), nil
+	}
+	return strictDearmor(src)
+}
+
+// strictDearmor returns a reader over the binary ciphertext in src, removing
+// the age armor, the tlock PEM encoding or the JSON envelope if there is any,
+// which must be in the canonical encoding.
+func strictDearmor(src io.Reader) (*bufio.Reader, error) {
+	rr := bufio.NewReader(src)
+	if start, _ := rr.Peek(len(armor.Header)); string(start) == armor.Header {
+		ar := NewArmorReader(rr).(*armorReader)
+		ar.strict = true
+		return bufio.NewReader(ar), nil
+	}
+	if start, _ := rr.Peek(len(ArmorHintPrefix)); string(start) == ArmorHintPrefix {
+		return nil, fmt.Errorf("%w: hint line before the armor", ErrNonCanonical)
+	}
+
+	pemStart, _ := rr.Peek(len(pemHeader))
+	envelopeStart, _ := rr.Peek(1)
+	var ciphertext []byte
+	var err error
+	switch {
+	case string(pemStart) == pemHeader:
+		ciphertext, err = strictPEM(rr)
+	case string(envelopeStart) == "{":
+		ciphertext, err = strictEnvelope(rr)
+	default:
+		return rr, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bufio.NewReader(bytes.NewReader(ciphertext)), nil
+}
+
+// strictPEM returns the ciphertext of the PEM block in src, which must be
+// all there is, encoded as pem.Encode does, with the headers of the PEM
+// writer only.
+func strictPEM(src io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+	block, rest := pem.Decode(data)
+	if block == nil || block.Type != PEMType {
+		return nil, ErrMalformedPEM
+	}
+	if len(rest) != 0 || !bytes.Equal(pem.EncodeToMemory(block), data) {
+		return nil, fmt.Errorf("%w: pem block", ErrNonCanonical)
+	}
+	for key := range block.Headers {
+		if key != PEMHeaderRound && key != PEMHeaderChainHash && key != PEMHeaderUnlockETA {
+			return nil, fmt.Errorf("%w: pem header %q", ErrNonCanonical, key)
+		}
+	}
+	if err := checkInformative(block.Bytes, block.Headers[PEMHeaderRound], block.Headers[PEMHeaderChainHash]); err != nil {
+		return nil, fmt.Errorf("pem: %w", err)
+	}
+	return block.Bytes, nil
+}
+
+// strictEnvelope returns the ciphertext of the JSON envelope in src, which
+// must be all there is, encoded as the envelope writer encodes it.
+func strictEnvelope(src io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+	var env Envelope
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(&env); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedEnvelope, err)
+	}
+	if env.Version != EnvelopeVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrMalformedEnvelope, env.Version)
+	}
+
+	// Duplicate, unknown or differently cased keys, whitespace and base64
+	// padding bits all change the encoding.
+	var canonical bytes.Buffer
+	if err := json.NewEncoder(&canonical).Encode(env); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(canonical.Bytes(), data) {
+		return nil, fmt.Errorf("%w: json envelope", ErrNonCanonical)
+	}
+	if err := checkInformative(env.Ciphertext, strconv.FormatUint(env.Round, 10), env.ChainHash); err != nil {
+		return nil, fmt.Errorf("json envelope: %w", err)
+	}
+	return env.Ciphertext, nil
+}
+
+// checkInformative checks that the round and chain hash set alongside the
+// ciphertext are those of its header.
+func checkInformative(ciphertext []byte, roundText string, chainHash string) error {
+	hdr, err := ReadHeader(bufio.NewReader(bytes.NewReader(ciphertext)))
+	if err != nil {
+		return err
+	}
+	roundNumber, hdrChainHash, err := hdr.Round()
+	if err != nil {
+		return err
+	}
+	if roundText != strconv.FormatUint(roundNumber, 10) || chainHash != hdrChainHash {
+		return fmt.Errorf("%w: round %s of chain %s, the ciphertext unlocks at round %d of chain %s",
+			ErrNonCanonical, roundText, chainHash, roundNumber, hdrChainHash)
+	}
+	return nil
+}
+
+// checkStanzas checks that the tlock stanzas have canonical arguments and
+// that no stanza is repeated: neither a tlock stanza of the same round and
+// chain hash, nor the metadata or puzzle stanza, nor any identical stanza.
+func checkStanzas(stanzas []*age.Stanza) error {
+	seen := make(map[string]bool)
+	for _, s := range stanzas {
+		key := s.Type + " " + strings.Join(s.Args, " ") + " " + string(s.Body)
+		switch s.Type {
+		case "tlock":
+			if len(s.Args) < 2 || !canonicalUint(s.Args[0]) || !canonicalHex(s.Args[1]) {
+				return fmt.Errorf("%w: tlock stanza arguments %q", ErrNonCanonical, s.Args)
+			}
+			key = s.Type + " " + s.Args[0] + " " + s.Args[1]
+		case puzzleStanzaType:
+			if len(s.Args) < 1 || !canonicalUint(s.Args[0]) {
+				return fmt.Errorf("%w: puzzle stanza arguments %q", ErrNonCanonical, s.Args)
+			}
+			key = s.Type
+		case metadataStanza:
+			key = s.Type
+		}
+		if seen[key] {
+			return fmt.Errorf("%w: duplicate %s stanza", ErrNonCanonical, s.Type)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// canonicalUint reports whether s is a decimal number without leading zeros.
+func canonicalUint(s string) bool {
+	n, err := strconv.ParseUint(s, 10, 64)
+	return err == nil && strconv.FormatUint(n, 10) == s
+}
+
+// canonicalHex reports whether s is lowercase hexadecimal.
+func canonicalHex(s string) bool {
+	b, err := hex.DecodeString(s)
+	return err == nil && len(b) > 0 && hex.EncodeToString(b) == s
+}
@@ -0,0 +1,121 @@
+package tlock_test
+
+import (
+	"bytes"
+	"encoding/pem"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStrictDecodeCanonical(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+	strict := tlock.New(network).StrictDecode()
+
+	var binary bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&binary, bytes.NewReader(dataFile), 1000))
+
+	for name, encode := range map[string]func(io.Writer) io.WriteCloser{
+		"binary":   func(w io.Writer) io.WriteCloser { return nopWriteCloser{w} },
+		"armor":    tlock.NewArmorWriter,
+		"pem":      func(w io.Writer) io.WriteCloser { return tlock.NewPEMWriter(w, network) },
+		"envelope": tlock.NewEnvelopeWriter,
+	} {
+		t.Run(name, func(t *testing.T) {
+			var encoded bytes.Buffer
+			w := encode(&encoded)
+			_, err := w.Write(binary.Bytes())
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+
+			var plainData bytes.Buffer
+			require.NoError(t, strict.Decrypt(&plainData, bytes.NewReader(encoded.Bytes())))
+			require.Equal(t, dataFile, plainData.Bytes())
+		})
+	}
+}
+
+func TestStrictDecodeRejectsAmbiguity(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+
+	var binary, armored, envelope, block bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&binary, bytes.NewReader(dataFile), 1000))
+	for _, w := range []io.WriteCloser{tlock.NewArmorWriter(&armored), tlock.NewEnvelopeWriter(&envelope), tlock.NewPEMWriter(&block, network)} {
+		_, err := w.Write(binary.Bytes())
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+	}
+	hinted := "Unlocks: round 1000, chain 52db9b…\n" + armored.String()
+
+	stanza := regexp.MustCompile(`(?s)-> tlock .*?\n---`).FindString(binary.String())
+	require.NotEmpty(t, stanza)
+	duplicated := strings.Replace(binary.String(), stanza, stanza[:len(stanza)-3]+stanza, 1)
+	zeroPadded := strings.Replace(binary.String(), "-> tlock 1000 ", "-> tlock 01000 ", 1)
+
+	p, _ := pem.Decode(block.Bytes())
+	p.Headers[tlock.PEMHeaderRound] = "999"
+	misleadingPEM := pem.EncodeToMemory(p)
+
+	tests := map[string]struct {
+		ciphertext string
+		lenient    bool
+	}{
+		"armor with trailing whitespace": {armored.String() + "\n", true},
+		"armor with a hint line":         {hinted, true},
+		"armor with crlf line endings":   {strings.ReplaceAll(armored.String(), "\n", "\r\n"), true},
+		"pem with trailing text":         {block.String() + "bye\n", true},
+		"pem with a misleading round":    {string(misleadingPEM), true},
+		"envelope with whitespace":       {strings.Replace(envelope.String(), `,"round"`, `, "round"`, 1), true},
+		"envelope with a duplicate key":  {strings.Replace(envelope.String(), `{"version":1,`, `{"version":1,"version":1,`, 1), true},
+		"envelope with trailing data":    {envelope.String() + "{}", true},
+		"duplicate tlock stanza":         {duplicated, false},
+		"zero padded round":              {zeroPadded, false},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if test.lenient {
+				var plainData bytes.Buffer
+				require.NoError(t, tlock.New(network).Decrypt(&plainData, strings.NewReader(test.ciphertext)))
+				require.Equal(t, dataFile, plainData.Bytes())
+			}
+
+			err := tlock.New(network).StrictDecode().Decrypt(io.Discard, strings.NewReader(test.ciphertext))
+			require.ErrorIs(t, err, tlock.ErrNonCanonical)
+		})
+	}
+}
+
+func TestStrictDecodeIdentity(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+
+	var binary bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&binary, bytes.NewReader(dataFile), 1000))
+	zeroPadded := strings.Replace(binary.String(), "-> tlock 1000 ", "-> tlock 01000 ", 1)
+
+	id := tlock.NewIdentity(network, false)
+	id.SetStrictDecode(true)
+	_, err := age.Decrypt(strings.NewReader(zeroPadded), id)
+	require.ErrorIs(t, err, tlock.ErrNonCanonical)
+
+	r, err := age.Decrypt(bytes.NewReader(binary.Bytes()), id)
+	require.NoError(t, err)
+	plainData, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, dataFile, plainData)
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
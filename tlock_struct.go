@@ -0,0 +1,186 @@
+package tlock
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidStruct is returned when a value can't be encrypted or decrypted
+// field by field.
+var ErrInvalidStruct = errors.New("invalid struct for field encryption")
+
+// structTag is the tag marking the fields to encrypt.
+const structTag = "tlock"
+
+// structField maps a field of a sealed struct to the one of the original
+// struct.
+type structField struct {
+	index  int
+	sealed bool
+	round  uint64
+}
+
+// SealedType returns the struct type EncryptStruct produces for values of the
+// struct type t, so that encrypted messages can be unmarshaled before being
+// decrypted. It has the exported fields of t, in the same order and with the
+// same tags, those tagged with `tlock:""` or `tlock:"round=N"` being byte
+// slices holding their ciphertext.
+func SealedType(t reflect.Type) (reflect.Type, error) {
+	st, _, err := sealedType(t)
+	return st, err
+}
+
+// EncryptStruct returns a pointer to a sibling of the struct v, or of the
+// struct v points to, whose fields tagged with `tlock:"round=N"` are timelock
+// encrypted towards round N and those tagged with `tlock:""` towards the round
+// number. The field values are encoded in JSON before being encrypted, the
+// other exported fields are copied and the unexported ones are left out.
+// Embedded structs aren't supported and nested structs are encrypted as a
+// whole.
+func (t Tlock) EncryptStruct(v any, roundNumber uint64) (any, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if !rv.IsValid() {
+		return nil, fmt.Errorf("%w: nil value", ErrInvalidStruct)
+	}
+	st, fields, err := sealedType(rv.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	out := reflect.New(st).Elem()
+	for i, f := range fields {
+		src := rv.Field(f.index)
+		if !f.sealed {
+			out.Field(i).Set(src)
+			continue
+		}
+
+		name := rv.Type().Field(f.index).Name
+		r := f.round
+		if r == 0 {
+			r = roundNumber
+		}
+		if r == 0 {
+			return nil, fmt.Errorf("%w: no round for field %s", ErrInvalidStruct, name)
+		}
+
+		plaintext, err := json.Marshal(src.Interface())
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", name, err)
+		}
+		var ciphertext bytes.Buffer
+		if err := t.Encrypt(&ciphertext, bytes.NewReader(plaintext), r); err != nil {
+			return nil, fmt.Errorf("field %s: %w", name, err)
+		}
+		out.Field(i).SetBytes(ciphertext.Bytes())
+	}
+
+	return out.Addr().Interface(), nil
+}
+
+// DecryptStruct decrypts the sealed struct src, as returned by EncryptStruct
+// or unmarshaled into a value of its SealedType, into the struct dst points
+// to. Encrypted fields left empty are set to their zero value.
+func (t Tlock) DecryptStruct(dst any, src any) error {
+	dv := reflect.ValueOf(dst)
+	if dv.Kind() != reflect.Pointer || dv.IsNil() {
+		return fmt.Errorf("%w: destination must be a non nil pointer", ErrInvalidStruct)
+	}
+	dv = dv.Elem()
+	st, fields, err := sealedType(dv.Type())
+	if err != nil {
+		return err
+	}
+	sv := reflect.Indirect(reflect.ValueOf(src))
+	if !sv.IsValid() || sv.Type() != st {
+		return fmt.Errorf("%w: source isn't the sealed type of %s", ErrInvalidStruct, dv.Type())
+	}
+
+	for i, f := range fields {
+		field := dv.Field(f.index)
+		if !f.sealed {
+			field.Set(sv.Field(i))
+			continue
+		}
+
+		ciphertext := sv.Field(i).Bytes()
+		if len(ciphertext) == 0 {
+			field.SetZero()
+			continue
+		}
+		name := dv.Type().Field(f.index).Name
+		var plaintext bytes.Buffer
+		if err := t.Decrypt(&plaintext, bytes.NewReader(ciphertext)); err != nil {
+			return fmt.Errorf("field %s: %w", name, err)
+		}
+		if err := json.Unmarshal(plaintext.Bytes(), field.Addr().Interface()); err != nil {
+			return fmt.Errorf("field %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// sealedType builds the sealed type of the struct type t, along with the
+// mapping of its fields.
+func sealedType(t reflect.Type) (reflect.Type, []structField, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("%w: %s is not a struct", ErrInvalidStruct, t)
+	}
+
+	var sealed []reflect.StructField
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		if f.Anonymous {
+			return nil, nil, fmt.Errorf("%w: embedded field %s", ErrInvalidStruct, f.Name)
+		}
+
+		field := structField{index: i}
+		typ := f.Type
+		if tag, ok := f.Tag.Lookup(structTag); ok {
+			roundNumber, err := parseStructTag(tag)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%w: field %s: %w", ErrInvalidStruct, f.Name, err)
+			}
+			field.sealed, field.round = true, roundNumber
+			typ = reflect.TypeOf([]byte(nil))
+		}
+
+		sealed = append(sealed, reflect.StructField{Name: f.Name, Type: typ, Tag: f.Tag})
+		fields = append(fields, field)
+	}
+
+	return reflect.StructOf(sealed), fields, nil
+}
+
+// parseStructTag parses the options of a tlock tag, returning the round it
+// sets or 0.
+func parseStructTag(tag string) (uint64, error) {
+	var roundNumber uint64
+	for _, opt := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(opt, "=")
+		switch key {
+		case "":
+		case "round":
+			r, err := strconv.ParseUint(value, 10, 64)
+			if err != nil || r == 0 {
+				return 0, fmt.Errorf("invalid round %q", value)
+			}
+			roundNumber = r
+		default:
+			return 0, fmt.Errorf("unknown option %q", key)
+		}
+	}
+	return roundNumber, nil
+}
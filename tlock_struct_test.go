@@ -0,0 +1,74 @@
+package tlock_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+type embargoedRelease struct {
+	ID       string            `json:"id"`
+	Title    string            `json:"title" tlock:""`
+	Figures  map[string]int    `json:"figures,omitempty" tlock:"round=1000"`
+	Authors  []string          `json:"authors"`
+	Contacts map[string]string `json:"contacts" tlock:""`
+	internal int
+}
+
+func TestEncryptStruct(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+	in := embargoedRelease{
+		ID:       "q3",
+		Title:    "Quarterly results",
+		Figures:  map[string]int{"revenue": 42},
+		Authors:  []string{"finance"},
+		internal: 7,
+	}
+
+	sealed, err := tlock.New(network).EncryptStruct(&in, 1000)
+	require.NoError(t, err)
+	b, err := json.Marshal(sealed)
+	require.NoError(t, err)
+	require.NotContains(t, string(b), "Quarterly")
+	require.NotContains(t, string(b), "revenue")
+	require.Contains(t, string(b), `"id":"q3"`)
+	require.Contains(t, string(b), `"authors":["finance"]`)
+
+	// The receiving side unmarshals into the sealed type before decrypting.
+	st, err := tlock.SealedType(reflect.TypeOf(embargoedRelease{}))
+	require.NoError(t, err)
+	received := reflect.New(st).Interface()
+	require.NoError(t, json.Unmarshal(b, received))
+
+	var out embargoedRelease
+	require.NoError(t, tlock.New(network).DecryptStruct(&out, received))
+	in.internal = 0
+	require.Equal(t, in, out)
+}
+
+func TestEncryptStructInvalid(t *testing.T) {
+	network := fixedtest.NewNetwork(t, 1000)
+
+	_, err := tlock.New(network).EncryptStruct(&embargoedRelease{}, 0)
+	require.ErrorIs(t, err, tlock.ErrInvalidStruct)
+
+	_, err = tlock.New(network).EncryptStruct("not a struct", 1000)
+	require.ErrorIs(t, err, tlock.ErrInvalidStruct)
+
+	_, err = tlock.New(network).EncryptStruct(struct {
+		Secret string `tlock:"round=soon"`
+	}{}, 1000)
+	require.ErrorIs(t, err, tlock.ErrInvalidStruct)
+
+	var out embargoedRelease
+	err = tlock.New(network).DecryptStruct(&out, struct{ ID string }{})
+	require.ErrorIs(t, err, tlock.ErrInvalidStruct)
+	err = tlock.New(network).DecryptStruct(out, nil)
+	require.ErrorIs(t, err, tlock.ErrInvalidStruct)
+}
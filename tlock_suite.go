@@ -0,0 +1,98 @@
+package tlock
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/drand/drand/v2/crypto"
+	"github.com/drand/kyber"
+	bls "github.com/drand/kyber-bls12381"
+	"github.com/drand/kyber/encrypt/ibe"
+	"github.com/drand/kyber/pairing"
+)
+
+// Suite performs the identity based encryption of a drand scheme. Supporting
+// networks on another curve only takes registering a suite for their scheme.
+type Suite interface {
+	// Encrypt encrypts the data towards the identity.
+	Encrypt(publicKey kyber.Point, id []byte, data []byte) (*ibe.Ciphertext, error)
+	// Decrypt decrypts the ciphertext with the signature of the identity.
+	Decrypt(signature []byte, ciphertext *ibe.Ciphertext) ([]byte, error)
+}
+
+var (
+	suitesMu sync.RWMutex
+	suites   = map[string]Suite{
+		// The ShortSigSchemeID uses the wrong DST for G1, so we keep it for
+		// retro-compatibility.
+		crypto.ShortSigSchemeID:  sigsOnG1Suite{bls.NewBLS12381SuiteWithDST(bls.DefaultDomainG2(), bls.DefaultDomainG2())},
+		crypto.UnchainedSchemeID: sigsOnG2Suite{bls.NewBLS12381Suite()},
+		crypto.SigsOnG1ID:        sigsOnG1Suite{bls.NewBLS12381Suite()},
+	}
+)
+
+// RegisterSuite registers the suite used for the drand scheme with the given
+// ID, replacing any previously registered one.
+func RegisterSuite(schemeID string, suite Suite) {
+	suitesMu.Lock()
+	defer suitesMu.Unlock()
+	suites[schemeID] = suite
+}
+
+// suiteFor returns the suite registered for the drand scheme.
+func suiteFor(schemeID string) (Suite, error) {
+	suitesMu.RLock()
+	defer suitesMu.RUnlock()
+
+	suite, ok := suites[schemeID]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported drand scheme '%s'", ErrNotUnchained, schemeID)
+	}
+	return suite, nil
+}
+
+// =============================================================================
+
+// sigsOnG1Suite is the suite of BLS12-381 schemes with public keys on G2 and
+// signatures on G1.
+type sigsOnG1Suite struct {
+	pairing.Suite
+}
+
+func (s sigsOnG1Suite) Encrypt(publicKey kyber.Point, id []byte, data []byte) (*ibe.Ciphertext, error) {
+	return ibe.EncryptCCAonG2(s.Suite, publicKey, id, data)
+}
+
+func (s sigsOnG1Suite) EncryptWithSigma(publicKey kyber.Point, id []byte, data []byte, sigma []byte) (*ibe.Ciphertext, error) {
+	return encryptCCA(s.Suite, false, publicKey, id, data, sigma)
+}
+
+func (s sigsOnG1Suite) Decrypt(signature []byte, ciphertext *ibe.Ciphertext) ([]byte, error) {
+	var sig bls.KyberG1
+	if err := sig.UnmarshalBinary(signature); err != nil {
+		return nil, fmt.Errorf("unmarshal kyber G1: %w", err)
+	}
+	return ibe.DecryptCCAonG2(s.Suite, &sig, ciphertext)
+}
+
+// sigsOnG2Suite is the suite of BLS12-381 schemes with public keys on G1 and
+// signatures on G2.
+type sigsOnG2Suite struct {
+	pairing.Suite
+}
+
+func (s sigsOnG2Suite) Encrypt(publicKey kyber.Point, id []byte, data []byte) (*ibe.Ciphertext, error) {
+	return ibe.EncryptCCAonG1(s.Suite, publicKey, id, data)
+}
+
+func (s sigsOnG2Suite) EncryptWithSigma(publicKey kyber.Point, id []byte, data []byte, sigma []byte) (*ibe.Ciphertext, error) {
+	return encryptCCA(s.Suite, true, publicKey, id, data, sigma)
+}
+
+func (s sigsOnG2Suite) Decrypt(signature []byte, ciphertext *ibe.Ciphertext) ([]byte, error) {
+	var sig bls.KyberG2
+	if err := sig.UnmarshalBinary(signature); err != nil {
+		return nil, fmt.Errorf("unmarshal kyber G2: %w", err)
+	}
+	return ibe.DecryptCCAonG1(s.Suite, &sig, ciphertext)
+}
@@ -0,0 +1,58 @@
+package tlock_test
+
+import (
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	chain "github.com/drand/drand/v2/common"
+	"github.com/drand/drand/v2/crypto"
+	"github.com/drand/kyber"
+	bls "github.com/drand/kyber-bls12381"
+	"github.com/drand/kyber/encrypt/ibe"
+	"github.com/stretchr/testify/require"
+)
+
+// countingSuite implements the suite of a signatures on G1 scheme while
+// counting its uses.
+type countingSuite struct {
+	calls int
+}
+
+func (s *countingSuite) Encrypt(publicKey kyber.Point, id []byte, data []byte) (*ibe.Ciphertext, error) {
+	s.calls++
+	return ibe.EncryptCCAonG2(bls.NewBLS12381Suite(), publicKey, id, data)
+}
+
+func (s *countingSuite) Decrypt(signature []byte, ciphertext *ibe.Ciphertext) ([]byte, error) {
+	s.calls++
+	var sig bls.KyberG1
+	if err := sig.UnmarshalBinary(signature); err != nil {
+		return nil, err
+	}
+	return ibe.DecryptCCAonG2(bls.NewBLS12381Suite(), &sig, ciphertext)
+}
+
+func TestRegisterSuite(t *testing.T) {
+	sch := crypto.NewPedersenBLSUnchainedG1()
+	sch.Name = "test-custom-scheme"
+	key := fixedtest.NewKey(sch)
+	publicKey := key.PublicKey
+	data := []byte("0123456789abcdef")
+
+	_, err := tlock.TimeLock(*sch, publicKey, 1000, data)
+	require.ErrorContains(t, err, "unsupported drand scheme")
+
+	suite := &countingSuite{}
+	tlock.RegisterSuite(sch.Name, suite)
+
+	ciphertext, err := tlock.TimeLock(*sch, publicKey, 1000, data)
+	require.NoError(t, err)
+
+	sig := key.SignRound(t, 1000)
+	plaintext, err := tlock.TimeUnlock(*sch, publicKey, chain.Beacon{Round: 1000, Signature: sig}, ciphertext)
+
+	require.NoError(t, err)
+	require.Equal(t, data, plaintext)
+	require.Equal(t, 2, suite.calls)
+}
@@ -0,0 +1,86 @@
+package tlock
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// TeeError is returned by the writers of NewTeeWriter when writing to one of
+// their destinations fails, telling which.
+type TeeError struct {
+	Index int
+	Err   error
+}
+
+// Error implements the error interface.
+func (e *TeeError) Error() string {
+	return fmt.Sprintf("output %d: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the error of the destination.
+func (e *TeeError) Unwrap() error {
+	return e.Err
+}
+
+// NewTeeWriter returns a writer duplicating its writes to all the
+// destinations, so that a plaintext is decrypted once and read once while
+// being written locally and uploaded at the same time:
+//
+//	err := tlock.New(network).Decrypt(tlock.NewTeeWriter(file, upload), src)
+//
+// Unlike io.MultiWriter, the destinations are written to concurrently, a
+// slow upload not delaying the local writes by as much, and the failing one
+// is told by a *TeeError. Once one fails, the writes fail without writing to
+// the others.
+func NewTeeWriter(dsts ...io.Writer) io.Writer {
+	return &teeWriter{dsts: dsts}
+}
+
+// =============================================================================
+
+// teeWriter writes to all its destinations.
+type teeWriter struct {
+	dsts []io.Writer
+	err  error
+}
+
+func (w *teeWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	if len(w.dsts) == 1 {
+		return w.write(0, p)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(w.dsts))
+	for i := range w.dsts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = w.write(i, p)
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			w.err = err
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// write writes all of p to the destination of the index.
+func (w *teeWriter) write(i int, p []byte) (int, error) {
+	n, err := w.dsts[i].Write(p)
+	if err == nil && n < len(p) {
+		err = io.ErrShortWrite
+	}
+	if err != nil {
+		return n, &TeeError{Index: i, Err: err}
+	}
+	return n, nil
+}
@@ -0,0 +1,49 @@
+package tlock_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTeeWriter(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1000)
+
+	var cipherData bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&cipherData, bytes.NewReader(dataFile), 1000))
+
+	var local, upload bytes.Buffer
+	require.NoError(t, tlock.New(network).Decrypt(tlock.NewTeeWriter(&local, &upload), bytes.NewReader(cipherData.Bytes())))
+	require.Equal(t, dataFile, local.Bytes())
+	require.Equal(t, dataFile, upload.Bytes())
+
+	// The failing destination is told, and no more is written.
+	errFull := errors.New("full")
+	w := tlock.NewTeeWriter(&local, failingWriter{errFull})
+	_, err := w.Write([]byte("data"))
+	var teeErr *tlock.TeeError
+	require.ErrorAs(t, err, &teeErr)
+	require.Equal(t, 1, teeErr.Index)
+	require.ErrorIs(t, err, errFull)
+
+	local.Reset()
+	_, err = w.Write([]byte("more"))
+	require.ErrorIs(t, err, errFull)
+	require.Zero(t, local.Len())
+}
+
+// =============================================================================
+
+type failingWriter struct {
+	err error
+}
+
+func (w failingWriter) Write([]byte) (int, error) {
+	return 0, w.err
+}
@@ -11,9 +11,13 @@ import (
 	"time"
 
 	chain "github.com/drand/drand/v2/common"
+	dchain "github.com/drand/drand/v2/common/chain"
 	"github.com/drand/drand/v2/crypto"
 	bls "github.com/drand/kyber-bls12381"
+	"github.com/drand/kyber/util/random"
 	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
 	"github.com/JonathanLogan/tlock/networks/http"
 
 	"github.com/stretchr/testify/require"
@@ -35,6 +39,8 @@ const (
 )
 
 func TestEarlyDecryptionWithDuration(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
 	for host, hashes := range map[string][]string{testnetHost: {testnetUnchainedOnG2, testnetQuicknetT},
 		mainnetHost: {mainnetQuicknet}} {
 		for _, hash := range hashes {
@@ -73,6 +79,8 @@ func TestEarlyDecryptionWithDuration(t *testing.T) {
 }
 
 func TestEarlyDecryptionWithRound(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
 	network, err := http.NewNetwork(testnetHost, testnetUnchainedOnG2)
 	require.NoError(t, err)
 
@@ -102,6 +110,8 @@ func TestEarlyDecryptionWithRound(t *testing.T) {
 }
 
 func TestEncryptionWithDuration(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
 	if testing.Short() {
 		t.Skip("skipping live testing in short mode")
 	}
@@ -144,6 +154,8 @@ func TestEncryptionWithDuration(t *testing.T) {
 }
 
 func TestDecryptVariousChainhashes(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
 	dir := "./testdata"
 	prefix := "lorem-"
 
@@ -176,6 +188,8 @@ func TestDecryptVariousChainhashes(t *testing.T) {
 }
 
 func TestDecryptStrict(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
 	dir := "./testdata"
 	prefix := "lorem-"
 
@@ -205,6 +219,8 @@ func TestDecryptStrict(t *testing.T) {
 }
 
 func TestEncryptionWithRound(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
 	if testing.Short() {
 		t.Skip("skipping live testing in short mode")
 	}
@@ -290,7 +306,32 @@ func TestCannotEncryptWithPointAtInfinity(t *testing.T) {
 
 }
 
+// chainedNetwork is a network whose chain information reports a chained
+// scheme.
+type chainedNetwork struct {
+	*fixed.Network
+}
+
+func (n chainedNetwork) Info() *dchain.Info {
+	info := n.Network.Info()
+	info.Scheme = crypto.DefaultSchemeID
+	return info
+}
+
+func TestCannotEncryptWithChainedNetwork(t *testing.T) {
+	_, err := tlock.TimeLock(*crypto.NewPedersenBLSChained(), crypto.NewPedersenBLSChained().KeyGroup.Point().Pick(random.New()), 10, []byte("deadbeef"))
+	require.ErrorIs(t, err, tlock.ErrNotUnchained)
+
+	network := chainedNetwork{fixedtest.NewNetwork(t, 10)}
+	var cipherData bytes.Buffer
+	err = tlock.New(network).Encrypt(&cipherData, strings.NewReader("deadbeef"), 10)
+	require.ErrorIs(t, err, tlock.ErrNotUnchained)
+	require.Zero(t, cipherData.Len())
+}
+
 func TestDecryptText(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
 	cipher := `-----BEGIN AGE ENCRYPTED FILE-----
 YWdlLWVuY3J5cHRpb24ub3JnL3YxCi0+IHRsb2NrIDEyMDQwODgzIDUyZGI5YmE3
 MGUwY2MwZjZlYWY3ODAzZGQwNzQ0N2ExZjU0Nzc3MzVmZDNmNjYxNzkyYmE5NDYw
@@ -338,6 +379,8 @@ UnBmtsw6U2LlKh8iDf0E1PfwDenmKFfQaAGm0WLxdlzP8Q==
 }
 
 func TestInteropWithJS(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
 	t.Run("on Mainnet with G1 sigs", func(t *testing.T) {
 		cipher := `-----BEGIN AGE ENCRYPTED FILE-----
 YWdlLWVuY3J5cHRpb24ub3JnL3YxCi0+IHRsb2NrIDEyMDQxMTI1IDUyZGI5YmE3
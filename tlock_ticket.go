@@ -0,0 +1,236 @@
+package tlock
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	dchain "github.com/drand/drand/v2/common/chain"
+	"github.com/drand/drand/v2/crypto"
+)
+
+// TicketPrefix starts the encoding of decryption tickets, identifying the
+// version of their format.
+const TicketPrefix = "tlock-ticket/v1:"
+
+// ErrMalformedTicket is returned when parsing a ticket which isn't valid: of
+// another format, truncated, or with a beacon whose signature doesn't verify
+// against the public key of its chain.
+var ErrMalformedTicket = errors.New("malformed decryption ticket")
+
+// ErrNotInTicket is returned when decrypting with a ticket a ciphertext it
+// wasn't issued for.
+var ErrNotInTicket = errors.New("ciphertext not covered by the ticket")
+
+// Ticket is the verified beacon of a round along with the chain information
+// verifying it, once the round is reached, which hands the decryption of the
+// ciphertexts locked to the round to workers without network access or relay
+// credentials. Tickets issued for given ciphertexts decrypt those only, as
+// identified by their FileID. This scope is a policy of DecryptWithTicket
+// rather than a cryptographic guarantee, the beacon being public: tickets
+// grant nothing the public beacon of the round doesn't already.
+//
+// Tickets encode to a line of text, a few hundred characters long, fitting
+// in an environment variable or a job description.
+type Ticket struct {
+	Beacon  VerifiedBeacon
+	FileIDs [][]byte
+	info    *dchain.Info
+}
+
+// IssueTicket obtains and verifies the beacon of the round from the network,
+// returning the ticket decrypting the ciphertexts of the file identifiers, or
+// all the ciphertexts of the round without any. The network must expose its
+// chain information, as the http networks do.
+func (t Tlock) IssueTicket(roundNumber uint64, fileIDs ...[]byte) (*Ticket, error) {
+	n, ok := t.network.(interface{ Info() *dchain.Info })
+	if !ok || n.Info() == nil {
+		return nil, errors.New("the network doesn't expose its chain information")
+	}
+	for _, id := range fileIDs {
+		if len(id) != FileIDSize {
+			return nil, fmt.Errorf("invalid file id size %d", len(id))
+		}
+	}
+
+	beacon, err := t.ObtainVerifiedBeacon(roundNumber)
+	if err != nil {
+		return nil, err
+	}
+	return &Ticket{Beacon: beacon, FileIDs: fileIDs, info: n.Info()}, nil
+}
+
+// ParseTicket parses a ticket encoded by String, verifying the beacon against
+// the chain information it carries. The chain hash of the beacon is that of
+// the information: callers pinning a chain compare it.
+func ParseTicket(s string) (*Ticket, error) {
+	encoded, ok := strings.CutPrefix(strings.TrimSpace(s), TicketPrefix)
+	if !ok {
+		return nil, fmt.Errorf("%w: missing %q prefix", ErrMalformedTicket, TicketPrefix)
+	}
+	data, err := base64.RawURLEncoding.Strict().DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedTicket, err)
+	}
+
+	r := ticketReader{data: data}
+	roundNumber := r.uint64()
+	period := r.uint64()
+	genesis := r.uint64()
+	scheme := string(r.bytes())
+	id := string(r.bytes())
+	seed := r.bytes()
+	publicKey := r.bytes()
+	signature := r.bytes()
+	if r.err != nil || len(r.data)%FileIDSize != 0 {
+		return nil, fmt.Errorf("%w: truncated", ErrMalformedTicket)
+	}
+
+	if _, err := suiteFor(scheme); err != nil {
+		return nil, err
+	}
+	sch, err := crypto.SchemeFromName(scheme)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotUnchained, err)
+	}
+	public := sch.KeyGroup.Point()
+	if err := public.UnmarshalBinary(publicKey); err != nil {
+		return nil, fmt.Errorf("%w: public key: %v", ErrMalformedTicket, err)
+	}
+	info := &dchain.Info{
+		PublicKey:   public,
+		ID:          id,
+		Period:      time.Duration(period) * time.Second,
+		Scheme:      scheme,
+		GenesisTime: int64(genesis),
+		GenesisSeed: seed,
+	}
+
+	k := Ticket{
+		Beacon: VerifiedBeacon{Round: roundNumber, ChainHash: info.HashString(), Signature: signature},
+		info:   info,
+	}
+	for ids := r.data; len(ids) > 0; ids = ids[FileIDSize:] {
+		k.FileIDs = append(k.FileIDs, ids[:FileIDSize])
+	}
+	if _, err := k.Network(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedTicket, err)
+	}
+	return &k, nil
+}
+
+// String encodes the ticket in the format ParseTicket reads.
+func (k *Ticket) String() string {
+	publicKey, _ := k.info.PublicKey.MarshalBinary()
+
+	var data []byte
+	data = binary.BigEndian.AppendUint64(data, k.Beacon.Round)
+	data = binary.BigEndian.AppendUint64(data, uint64(k.info.Period/time.Second))
+	data = binary.BigEndian.AppendUint64(data, uint64(k.info.GenesisTime))
+	for _, field := range [][]byte{[]byte(k.info.Scheme), []byte(k.info.ID), k.info.GenesisSeed, publicKey, k.Beacon.Signature} {
+		data = binary.AppendUvarint(data, uint64(len(field)))
+		data = append(data, field...)
+	}
+	for _, id := range k.FileIDs {
+		data = append(data, id...)
+	}
+	return TicketPrefix + base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Covers reports whether the ticket decrypts the ciphertext of the file
+// identifier.
+func (k *Ticket) Covers(fileID []byte) bool {
+	if len(k.FileIDs) == 0 {
+		return true
+	}
+	for _, id := range k.FileIDs {
+		if bytes.Equal(id, fileID) {
+			return true
+		}
+	}
+	return false
+}
+
+// Network returns the network of the chain of the ticket, which only knows
+// the beacon of its round.
+func (k *Ticket) Network() (*TransportNetwork, error) {
+	bundle, err := NewBeaconBundle(k.info)
+	if err != nil {
+		return nil, err
+	}
+	if err := bundle.Add(k.Beacon); err != nil {
+		return nil, err
+	}
+	return NewTransportNetwork(context.Background(), bundle)
+}
+
+// DecryptWithTicket decrypts the source to the destination with the beacon
+// of the ticket, without calling the network, once checked that the ticket
+// covers the ciphertext.
+func (t Tlock) DecryptWithTicket(dst io.Writer, src io.Reader, k *Ticket) error {
+	network, err := k.Network()
+	if err != nil {
+		return err
+	}
+
+	// The file identifier follows the header, which is read again by Decrypt.
+	var head bytes.Buffer
+	br, err := t.dearmor(src)
+	if err != nil {
+		return err
+	}
+	hr := bufio.NewReader(io.TeeReader(br, &head))
+	if _, err := ReadHeader(hr); err != nil {
+		return err
+	}
+	id := make([]byte, FileIDSize)
+	if _, err := io.ReadFull(hr, id); err != nil {
+		return fmt.Errorf("%w: read nonce: %w", ErrMalformedPayload, err)
+	}
+	if !k.Covers(id) {
+		return fmt.Errorf("%w: file id %x", ErrNotInTicket, id)
+	}
+
+	t.network = network
+	return t.Decrypt(dst, io.MultiReader(&head, br))
+}
+
+// =============================================================================
+
+// ticketReader reads the fields of an encoded ticket, recording the first
+// error.
+type ticketReader struct {
+	data []byte
+	err  error
+}
+
+func (r *ticketReader) uint64() uint64 {
+	if r.err != nil || len(r.data) < 8 {
+		r.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	v := binary.BigEndian.Uint64(r.data)
+	r.data = r.data[8:]
+	return v
+}
+
+func (r *ticketReader) bytes() []byte {
+	if r.err != nil {
+		return nil
+	}
+	size, n := binary.Uvarint(r.data)
+	if n <= 0 || uint64(len(r.data)-n) < size {
+		r.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	v := r.data[n : n+int(size)]
+	r.data = r.data[n+int(size):]
+	return v
+}
@@ -0,0 +1,57 @@
+package tlock_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTicket(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	key := fixedtest.NewKey(nil)
+	key.Genesis = time.Now().Add(-300 * time.Second)
+	network := key.BundleNetwork(t, 50)
+
+	var first, second bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&first, bytes.NewReader(loremBytes), 50))
+	require.NoError(t, tlock.New(network).Encrypt(&second, bytes.NewReader(loremBytes), 50))
+	id, err := tlock.FileID(bytes.NewReader(first.Bytes()))
+	require.NoError(t, err)
+
+	issued, err := tlock.New(network).IssueTicket(50, id)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(issued.String(), tlock.TicketPrefix))
+	ticket, err := tlock.ParseTicket(issued.String() + "\n")
+	require.NoError(t, err)
+	require.Equal(t, issued.Beacon, ticket.Beacon)
+	require.Equal(t, [][]byte{id}, ticket.FileIDs)
+
+	// The ticket decrypts the ciphertext it was issued for, and that one only.
+	var plainData bytes.Buffer
+	require.NoError(t, tlock.New(nil).DecryptWithTicket(&plainData, bytes.NewReader(first.Bytes()), ticket))
+	require.Equal(t, loremBytes, plainData.Bytes())
+	err = tlock.New(nil).DecryptWithTicket(io.Discard, bytes.NewReader(second.Bytes()), ticket)
+	require.ErrorIs(t, err, tlock.ErrNotInTicket)
+
+	// Without file identifiers, it decrypts all the ciphertexts of its round.
+	issued, err = tlock.New(network).IssueTicket(50)
+	require.NoError(t, err)
+	ticket, err = tlock.ParseTicket(issued.String())
+	require.NoError(t, err)
+	require.NoError(t, tlock.New(nil).DecryptWithTicket(io.Discard, bytes.NewReader(second.Bytes()), ticket))
+
+	_, err = tlock.New(network).IssueTicket(60)
+	require.ErrorIs(t, err, tlock.ErrTooEarly)
+
+	for _, s := range []string{"", "tlock-beacon/v1:AAAA", tlock.TicketPrefix + "AAAA", issued.String()[:len(issued.String())-4]} {
+		_, err := tlock.ParseTicket(s)
+		require.ErrorIs(t, err, tlock.ErrMalformedTicket, s)
+	}
+}
@@ -0,0 +1,120 @@
+package tlock
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	chain "github.com/drand/drand/v2/common"
+	dchain "github.com/drand/drand/v2/common/chain"
+	"github.com/drand/drand/v2/crypto"
+	"github.com/drand/kyber"
+)
+
+// Transport is the minimal interface of a drand chain, which
+// NewTransportNetwork turns into a Network. Implementing a new way to reach a
+// chain only requires fetching its latest beacon, the beacon of a round and
+// its chain information, the library computing the rounds of the times, the
+// status of the chain and whether rounds are ready to decrypt on top of them.
+type Transport interface {
+	Latest(ctx context.Context) (*chain.Beacon, error)
+	Get(ctx context.Context, roundNumber uint64) (*chain.Beacon, error)
+	ChainInfo(ctx context.Context) (*dchain.Info, error)
+}
+
+// TransportNetwork is the Network of a transport.
+type TransportNetwork struct {
+	transport Transport
+	info      *dchain.Info
+	scheme    crypto.Scheme
+}
+
+// NewTransportNetwork constructs the network of the transport, fetching its
+// chain information once. The chain hash of the network is the hash of that
+// information, which the callers pinning a chain check.
+func NewTransportNetwork(ctx context.Context, transport Transport) (*TransportNetwork, error) {
+	info, err := transport.ChainInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("chain info: %w", err)
+	}
+	if _, err := suiteFor(info.Scheme); err != nil {
+		return nil, err
+	}
+	sch, err := crypto.SchemeFromName(info.Scheme)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotUnchained, err)
+	}
+
+	return &TransportNetwork{
+		transport: transport,
+		info:      info,
+		scheme:    *sch,
+	}, nil
+}
+
+// ChainHash returns the chain hash of the chain information of the transport.
+func (n *TransportNetwork) ChainHash() string {
+	return n.info.HashString()
+}
+
+// Current returns the current round for that network at the given date.
+func (n *TransportNetwork) Current(date time.Time) uint64 {
+	return chain.CurrentRound(date.Unix(), n.info.Period, n.info.GenesisTime)
+}
+
+// RoundNumber returns the round of the network at the given time.
+func (n *TransportNetwork) RoundNumber(t time.Time) uint64 {
+	return n.Current(t)
+}
+
+// PublicKey returns the kyber point needed for encryption and decryption.
+func (n *TransportNetwork) PublicKey() kyber.Point {
+	return n.info.PublicKey
+}
+
+// Scheme returns the drand crypto Scheme used by the network.
+func (n *TransportNetwork) Scheme() crypto.Scheme {
+	return n.scheme
+}
+
+// Info returns the chain information of the network.
+func (n *TransportNetwork) Info() *dchain.Info {
+	return n.info
+}
+
+// Signature fetches the beacon of the round through the transport.
+func (n *TransportNetwork) Signature(roundNumber uint64) ([]byte, error) {
+	b, err := n.transport.Get(context.Background(), roundNumber)
+	if err != nil {
+		return nil, err
+	}
+	if b.Round != roundNumber {
+		return nil, fmt.Errorf("%w: transport returned round %d for round %d", ErrBeaconMismatch, b.Round, roundNumber)
+	}
+	return b.Signature, nil
+}
+
+// LatestRound fetches the latest beacon through the transport, returning its
+// round.
+func (n *TransportNetwork) LatestRound() (uint64, error) {
+	return n.LatestRoundContext(context.Background())
+}
+
+// LatestRoundContext is LatestRound with a context, for probing the status
+// of the chain.
+func (n *TransportNetwork) LatestRoundContext(ctx context.Context) (uint64, error) {
+	b, err := n.transport.Latest(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return b.Round, nil
+}
+
+// SwitchChainHash fails unless the chain hash is the one of the transport,
+// which serves a single chain.
+func (n *TransportNetwork) SwitchChainHash(chainHash string) error {
+	if chainHash != n.ChainHash() {
+		return fmt.Errorf("transport serves chain %s, not %s", n.ChainHash(), chainHash)
+	}
+	return nil
+}
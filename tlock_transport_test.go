@@ -0,0 +1,74 @@
+package tlock_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/JonathanLogan/tlock/networks/http"
+	chain "github.com/drand/drand/v2/common"
+	dchain "github.com/drand/drand/v2/common/chain"
+	"github.com/drand/drand/v2/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+var _ tlock.Transport = (*http.Network)(nil)
+
+// testTransport signs the rounds up to its latest one.
+type testTransport struct {
+	key    *fixedtest.Key
+	info   *dchain.Info
+	latest uint64
+}
+
+func (tr *testTransport) Latest(ctx context.Context) (*chain.Beacon, error) {
+	return tr.Get(ctx, tr.latest)
+}
+
+func (tr *testTransport) Get(_ context.Context, roundNumber uint64) (*chain.Beacon, error) {
+	if roundNumber > tr.latest {
+		return nil, tlock.ErrTooEarly
+	}
+	sig, err := tr.key.Signature(roundNumber)
+	if err != nil {
+		return nil, err
+	}
+	return &chain.Beacon{Round: roundNumber, Signature: sig}, nil
+}
+
+func (tr *testTransport) ChainInfo(context.Context) (*dchain.Info, error) {
+	return tr.info, nil
+}
+
+func TestTransportNetwork(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	// The chain is at round 101 from the time, but only published round 90.
+	key := fixedtest.NewKey(nil)
+	key.Genesis = time.Now().Add(-300 * time.Second)
+	tr := &testTransport{key: key, info: key.Info(), latest: 90}
+	network, err := tlock.NewTransportNetwork(context.Background(), tr)
+	require.NoError(t, err)
+	require.Equal(t, tr.info.HashString(), network.ChainHash())
+	require.Equal(t, uint64(101), network.Current(time.Now()))
+
+	require.True(t, tlock.IsReadyToDecrypt(network, 90))
+	require.False(t, tlock.IsReadyToDecrypt(network, 95))
+	status, err := tlock.Status(context.Background(), network)
+	require.NoError(t, err)
+	require.Equal(t, uint64(90), status.LatestRound)
+
+	var cipherData bytes.Buffer
+	require.NoError(t, tlock.New(network).Encrypt(&cipherData, bytes.NewReader(loremBytes), 80))
+	var plainData bytes.Buffer
+	require.NoError(t, tlock.New(network).Decrypt(&plainData, bytes.NewReader(cipherData.Bytes())))
+	require.Equal(t, loremBytes, plainData.Bytes())
+
+	// Chained schemes can't be used for timelock encryption.
+	tr.info.Scheme = crypto.DefaultSchemeID
+	_, err = tlock.NewTransportNetwork(context.Background(), tr)
+	require.ErrorIs(t, err, tlock.ErrNotUnchained)
+}
@@ -0,0 +1,64 @@
+package tlock_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWindow(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	// The network is at round 101, its genesis being 300 seconds ago.
+	key := fixedtest.NewKey(nil)
+	key.Genesis = time.Now().Add(-300 * time.Second)
+	network := key.Network(t, key.SignRound(t, 50))
+
+	encrypt := func(tl tlock.Tlock) []byte {
+		var cipherData bytes.Buffer
+		require.NoError(t, tl.Encrypt(&cipherData, bytes.NewReader(loremBytes), 50))
+		return cipherData.Bytes()
+	}
+	decrypt := func(tl tlock.Tlock, cipherData []byte) error {
+		var plainData bytes.Buffer
+		if err := tl.Decrypt(&plainData, bytes.NewReader(cipherData)); err != nil {
+			return err
+		}
+		require.Equal(t, loremBytes, plainData.Bytes())
+		return nil
+	}
+
+	for _, seal := range []bool{false, true} {
+		tl := tlock.New(network).WithMetadata(tlock.UserMetadata{"exam": "algebra"})
+		if seal {
+			tl = tlock.New(network).WithSealedMetadata(tlock.UserMetadata{"exam": "algebra"})
+		}
+
+		open := encrypt(tl.WithNotAfter(1000))
+		require.NoError(t, decrypt(tlock.New(network).WithWindowEnforced(), open))
+
+		closed := encrypt(tl.WithNotAfter(60))
+		require.ErrorIs(t, decrypt(tlock.New(network).WithWindowEnforced(), closed), tlock.ErrWindowClosed)
+		require.NoError(t, decrypt(tlock.New(network), closed))
+
+		hdr, err := tlock.ParseHeader(bytes.NewReader(closed))
+		require.NoError(t, err)
+		notAfter, ok, err := hdr.NotAfter()
+		require.NoError(t, err)
+		require.Equal(t, !seal, ok)
+		if !seal {
+			require.Equal(t, uint64(60), notAfter)
+		}
+	}
+
+	// Ciphertexts without a window always decrypt.
+	require.NoError(t, decrypt(tlock.New(network).WithWindowEnforced(), encrypt(tlock.New(network))))
+
+	err := tlock.New(network).WithNotAfter(49).Encrypt(io.Discard, bytes.NewReader(loremBytes), 50)
+	require.ErrorIs(t, err, tlock.ErrInvalidWindow)
+}
@@ -0,0 +1,212 @@
+// Package tlockgrpc implements a gRPC service exposing tlock, letting
+// services written in other languages encrypt and decrypt without shelling
+// out to the tle command. The service is defined in tlock.proto, from which
+// its messages, client and registration are generated.
+package tlockgrpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative tlock.proto
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/policy"
+	dchain "github.com/drand/drand/v2/common/chain"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// TenantMetadataKey is the gRPC metadata key identifying the tenant a request
+// is made for, whose rules are evaluated by the policy of the server, on
+// connections not authenticated by a client certificate.
+const TenantMetadataKey = "tlock-tenant"
+
+// Server implements the service for a network.
+type Server struct {
+	UnimplementedTlockServer
+
+	network tlock.Network
+	policy  *policy.Engine
+}
+
+// NewServer constructs a server backed by the network.
+func NewServer(network tlock.Network) *Server {
+	return &Server{
+		network: network,
+	}
+}
+
+// SetPolicy sets the policy engine evaluating the encryptions of each tenant,
+// which are denied with PermissionDenied when breaking its rules. It must be
+// called before serving.
+//
+// The tenant of a connection authenticated with a verified client
+// certificate, with the TLS credentials of the gRPC server, is the common name
+// of the certificate. Otherwise the client asserts it with WithTenant, and the
+// policy then guards against the mistakes of the tenants rather than against
+// the tenants: whoever may connect may claim any tenant.
+func (s *Server) SetPolicy(p *policy.Engine) {
+	s.policy = p
+}
+
+// WithTenant returns a context making the requests of the client for the
+// tenant.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, TenantMetadataKey, tenant)
+}
+
+// tenantOf returns the tenant a request is made for: the common name of the
+// verified client certificate, or else the tenant set with WithTenant.
+func tenantOf(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.VerifiedChains) > 0 {
+			return info.State.VerifiedChains[0][0].Subject.CommonName
+		}
+	}
+	if values := metadata.ValueFromIncomingContext(ctx, TenantMetadataKey); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Register registers the server with the gRPC server.
+func Register(s grpc.ServiceRegistrar, srv *Server) {
+	RegisterTlockServer(s, srv)
+}
+
+// Encrypt timelock encrypts the plaintext towards the round.
+func (s *Server) Encrypt(ctx context.Context, req *EncryptRequest) (*EncryptResponse, error) {
+	if s.policy != nil {
+		if err := s.policy.Check(tenantOf(ctx), s.network, req.Round); err != nil {
+			return nil, status.Errorf(codes.PermissionDenied, "encrypt: %v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	var dst io.WriteCloser = nopCloser{&buf}
+	if req.Armor {
+		dst = tlock.NewArmorWriter(&buf)
+	}
+
+	t := tlock.New(s.network)
+	switch {
+	case req.Metadata != nil && req.SealMetadata:
+		t = t.WithSealedMetadata(req.Metadata)
+	case req.Metadata != nil:
+		t = t.WithMetadata(req.Metadata)
+	}
+
+	if err := t.Encrypt(dst, bytes.NewReader(req.Plaintext), req.Round); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "encrypt: %v", err)
+	}
+	if err := dst.Close(); err != nil {
+		return nil, status.Errorf(codes.Internal, "close armor: %v", err)
+	}
+
+	return &EncryptResponse{Ciphertext: buf.Bytes()}, nil
+}
+
+// Decrypt decrypts the ciphertext, failing with FailedPrecondition while its
+// round hasn't been reached.
+func (s *Server) Decrypt(_ context.Context, req *DecryptRequest) (*DecryptResponse, error) {
+	// The network is shared by the tenants: a ciphertext naming another
+	// chain mustn't switch it.
+	var buf bytes.Buffer
+	if err := tlock.New(s.network).Strict().Decrypt(&buf, bytes.NewReader(req.Ciphertext)); err != nil {
+		if errors.Is(err, tlock.ErrTooEarly) {
+			return nil, status.Errorf(codes.FailedPrecondition, "decrypt: %v", err)
+		}
+		return nil, status.Errorf(codes.InvalidArgument, "decrypt: %v", err)
+	}
+
+	return &DecryptResponse{Plaintext: buf.Bytes()}, nil
+}
+
+// Inspect reports the round and chain hash the ciphertext is locked to, and
+// the metadata it carries in the clear.
+func (s *Server) Inspect(_ context.Context, req *InspectRequest) (*InspectResponse, error) {
+	hdr, err := tlock.ParseHeader(bytes.NewReader(req.Ciphertext))
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "parse header: %v", err)
+	}
+	roundNumber, chainHash, err := hdr.Round()
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "parse header: %v", err)
+	}
+
+	metadata, sealed, err := hdr.Metadata()
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "parse metadata: %v", err)
+	}
+
+	resp := &InspectResponse{
+		Round:          roundNumber,
+		ChainHash:      chainHash,
+		Unlocked:       tlock.IsReadyToDecrypt(s.network, roundNumber),
+		Metadata:       metadata,
+		MetadataSealed: sealed,
+	}
+	if eta, ok := tlock.RoundTime(s.network, roundNumber); ok {
+		resp.UnlockTime = eta.Unix()
+	}
+
+	return resp, nil
+}
+
+// WaitForRound returns once the network published the round.
+func (s *Server) WaitForRound(ctx context.Context, req *WaitForRoundRequest) (*WaitForRoundResponse, error) {
+	for {
+		if tlock.IsReadyToDecrypt(s.network, req.Round) {
+			return &WaitForRoundResponse{Round: max(req.Round, s.network.Current(time.Now()))}, nil
+		}
+
+		wait := time.Second
+		if eta, ok := tlock.RoundTime(s.network, req.Round); ok && time.Until(eta) > wait {
+			wait = time.Until(eta)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Status reports the chain of the network and its current round.
+func (s *Server) Status(_ context.Context, _ *StatusRequest) (*StatusResponse, error) {
+	resp := &StatusResponse{
+		ChainHash:    s.network.ChainHash(),
+		Scheme:       s.network.Scheme().Name,
+		CurrentRound: s.network.Current(time.Now()),
+	}
+	if n, ok := s.network.(interface{ LatestRound() (uint64, error) }); ok {
+		if latest, err := n.LatestRound(); err == nil {
+			resp.LatestRound = latest
+		}
+	}
+	if n, ok := s.network.(interface{ Info() *dchain.Info }); ok && n.Info() != nil {
+		resp.GenesisTime = n.Info().GenesisTime
+		resp.Period = uint64(n.Info().Period.Seconds())
+	}
+
+	return resp, nil
+}
+
+// =============================================================================
+
+type nopCloser struct {
+	*bytes.Buffer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,149 @@
+package tlockgrpc_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/JonathanLogan/tlock/policy"
+	"github.com/JonathanLogan/tlock/tlockgrpc"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestServer(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1)
+
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	tlockgrpc.Register(s, tlockgrpc.NewServer(network))
+	go func() { _ = s.Serve(lis) }()
+	defer s.Stop()
+
+	cc, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer cc.Close()
+	client := tlockgrpc.NewTlockClient(cc)
+
+	ctx := context.Background()
+	plaintext := []byte("hello from another language")
+
+	enc, err := client.Encrypt(ctx, &tlockgrpc.EncryptRequest{Plaintext: plaintext, Round: 1, Armor: true})
+	require.NoError(t, err)
+	require.Contains(t, string(enc.Ciphertext), "BEGIN AGE ENCRYPTED FILE")
+
+	inspect, err := client.Inspect(ctx, &tlockgrpc.InspectRequest{Ciphertext: enc.Ciphertext})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), inspect.Round)
+	require.Equal(t, network.ChainHash(), inspect.ChainHash)
+	require.True(t, inspect.Unlocked)
+	require.NotZero(t, inspect.UnlockTime)
+
+	dec, err := client.Decrypt(ctx, &tlockgrpc.DecryptRequest{Ciphertext: enc.Ciphertext})
+	require.NoError(t, err)
+	require.Equal(t, plaintext, dec.Plaintext)
+
+	// A ciphertext of another chain doesn't switch the network of the tenants.
+	binary, err := client.Encrypt(ctx, &tlockgrpc.EncryptRequest{Plaintext: plaintext, Round: 1})
+	require.NoError(t, err)
+	other := strings.Repeat("ab", len(network.ChainHash())/2)
+	foreign := bytes.Replace(binary.Ciphertext, []byte(network.ChainHash()), []byte(other), 1)
+	_, err = client.Decrypt(ctx, &tlockgrpc.DecryptRequest{Ciphertext: foreign})
+	require.Error(t, err)
+	require.NotEqual(t, other, network.ChainHash())
+
+	wait, err := client.WaitForRound(ctx, &tlockgrpc.WaitForRoundRequest{Round: 1})
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, wait.Round, uint64(1))
+
+	st, err := client.Status(ctx, &tlockgrpc.StatusRequest{})
+	require.NoError(t, err)
+	require.Equal(t, network.ChainHash(), st.ChainHash)
+	require.Equal(t, uint64(3), st.Period)
+	require.Equal(t, network.Current(time.Now()), st.CurrentRound)
+
+	ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	_, err = client.WaitForRound(ctx, &tlockgrpc.WaitForRoundRequest{Round: 1000})
+	require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+
+	_, err = client.Inspect(context.Background(), &tlockgrpc.InspectRequest{Ciphertext: plaintext})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	metadata := map[string]string{"content-type": "text/plain", "creator": "press office"}
+	enc, err = client.Encrypt(context.Background(), &tlockgrpc.EncryptRequest{Plaintext: plaintext, Round: 1, Metadata: metadata})
+	require.NoError(t, err)
+	inspect, err = client.Inspect(context.Background(), &tlockgrpc.InspectRequest{Ciphertext: enc.Ciphertext})
+	require.NoError(t, err)
+	require.Equal(t, metadata, inspect.Metadata)
+	require.False(t, inspect.MetadataSealed)
+
+	enc, err = client.Encrypt(context.Background(), &tlockgrpc.EncryptRequest{Plaintext: plaintext, Round: 1, Metadata: metadata, SealMetadata: true})
+	require.NoError(t, err)
+	inspect, err = client.Inspect(context.Background(), &tlockgrpc.InspectRequest{Ciphertext: enc.Ciphertext})
+	require.NoError(t, err)
+	require.Empty(t, inspect.Metadata)
+	require.True(t, inspect.MetadataSealed)
+}
+
+func TestServerPolicy(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1)
+	p := policy.New()
+	p.SetTenant("acme", policy.Rules{MaxLockDuration: time.Hour})
+
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	srv := tlockgrpc.NewServer(network)
+	srv.SetPolicy(p)
+	tlockgrpc.Register(s, srv)
+	go func() { _ = s.Serve(lis) }()
+	defer s.Stop()
+
+	cc, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer cc.Close()
+	client := tlockgrpc.NewTlockClient(cc)
+
+	ctx := tlockgrpc.WithTenant(context.Background(), "acme")
+	_, err = client.Encrypt(ctx, &tlockgrpc.EncryptRequest{Plaintext: []byte("hello"), Round: 1})
+	require.NoError(t, err)
+
+	_, err = client.Encrypt(ctx, &tlockgrpc.EncryptRequest{Plaintext: []byte("hello"), Round: 1 << 20})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.Encrypt(context.Background(), &tlockgrpc.EncryptRequest{Plaintext: []byte("hello"), Round: 1})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// A client certificate identifies the tenant, whatever the metadata claims.
+	authenticated := func(commonName string) context.Context {
+		state := tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: commonName}}}}}
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(tlockgrpc.TenantMetadataKey, "acme"))
+		return peer.NewContext(ctx, &peer.Peer{AuthInfo: credentials.TLSInfo{State: state}})
+	}
+	_, err = srv.Encrypt(authenticated("acme"), &tlockgrpc.EncryptRequest{Plaintext: []byte("hello"), Round: 1})
+	require.NoError(t, err)
+	_, err = srv.Encrypt(authenticated("globex"), &tlockgrpc.EncryptRequest{Plaintext: []byte("hello"), Round: 1})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+}
@@ -0,0 +1,289 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: tlock.proto
+
+package tlockgrpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Tlock_Encrypt_FullMethodName      = "/tlock.v1.Tlock/Encrypt"
+	Tlock_Decrypt_FullMethodName      = "/tlock.v1.Tlock/Decrypt"
+	Tlock_Inspect_FullMethodName      = "/tlock.v1.Tlock/Inspect"
+	Tlock_WaitForRound_FullMethodName = "/tlock.v1.Tlock/WaitForRound"
+	Tlock_Status_FullMethodName       = "/tlock.v1.Tlock/Status"
+)
+
+// TlockClient is the client API for Tlock service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Tlock exposes timelock encryption backed by a drand network.
+type TlockClient interface {
+	// Encrypt timelock encrypts the plaintext towards a round.
+	Encrypt(ctx context.Context, in *EncryptRequest, opts ...grpc.CallOption) (*EncryptResponse, error)
+	// Decrypt decrypts a ciphertext whose round has been reached.
+	Decrypt(ctx context.Context, in *DecryptRequest, opts ...grpc.CallOption) (*DecryptResponse, error)
+	// Inspect reports the round and chain a ciphertext is locked to, and its
+	// metadata.
+	Inspect(ctx context.Context, in *InspectRequest, opts ...grpc.CallOption) (*InspectResponse, error)
+	// WaitForRound returns once the network reached the round.
+	WaitForRound(ctx context.Context, in *WaitForRoundRequest, opts ...grpc.CallOption) (*WaitForRoundResponse, error)
+	// Status reports the chain of the network and its current round.
+	Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StatusResponse, error)
+}
+
+type tlockClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTlockClient(cc grpc.ClientConnInterface) TlockClient {
+	return &tlockClient{cc}
+}
+
+func (c *tlockClient) Encrypt(ctx context.Context, in *EncryptRequest, opts ...grpc.CallOption) (*EncryptResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EncryptResponse)
+	err := c.cc.Invoke(ctx, Tlock_Encrypt_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tlockClient) Decrypt(ctx context.Context, in *DecryptRequest, opts ...grpc.CallOption) (*DecryptResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DecryptResponse)
+	err := c.cc.Invoke(ctx, Tlock_Decrypt_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tlockClient) Inspect(ctx context.Context, in *InspectRequest, opts ...grpc.CallOption) (*InspectResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(InspectResponse)
+	err := c.cc.Invoke(ctx, Tlock_Inspect_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tlockClient) WaitForRound(ctx context.Context, in *WaitForRoundRequest, opts ...grpc.CallOption) (*WaitForRoundResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(WaitForRoundResponse)
+	err := c.cc.Invoke(ctx, Tlock_WaitForRound_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tlockClient) Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StatusResponse)
+	err := c.cc.Invoke(ctx, Tlock_Status_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TlockServer is the server API for Tlock service.
+// All implementations must embed UnimplementedTlockServer
+// for forward compatibility.
+//
+// Tlock exposes timelock encryption backed by a drand network.
+type TlockServer interface {
+	// Encrypt timelock encrypts the plaintext towards a round.
+	Encrypt(context.Context, *EncryptRequest) (*EncryptResponse, error)
+	// Decrypt decrypts a ciphertext whose round has been reached.
+	Decrypt(context.Context, *DecryptRequest) (*DecryptResponse, error)
+	// Inspect reports the round and chain a ciphertext is locked to, and its
+	// metadata.
+	Inspect(context.Context, *InspectRequest) (*InspectResponse, error)
+	// WaitForRound returns once the network reached the round.
+	WaitForRound(context.Context, *WaitForRoundRequest) (*WaitForRoundResponse, error)
+	// Status reports the chain of the network and its current round.
+	Status(context.Context, *StatusRequest) (*StatusResponse, error)
+	mustEmbedUnimplementedTlockServer()
+}
+
+// UnimplementedTlockServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedTlockServer struct{}
+
+func (UnimplementedTlockServer) Encrypt(context.Context, *EncryptRequest) (*EncryptResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Encrypt not implemented")
+}
+func (UnimplementedTlockServer) Decrypt(context.Context, *DecryptRequest) (*DecryptResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Decrypt not implemented")
+}
+func (UnimplementedTlockServer) Inspect(context.Context, *InspectRequest) (*InspectResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Inspect not implemented")
+}
+func (UnimplementedTlockServer) WaitForRound(context.Context, *WaitForRoundRequest) (*WaitForRoundResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method WaitForRound not implemented")
+}
+func (UnimplementedTlockServer) Status(context.Context, *StatusRequest) (*StatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Status not implemented")
+}
+func (UnimplementedTlockServer) mustEmbedUnimplementedTlockServer() {}
+func (UnimplementedTlockServer) testEmbeddedByValue()               {}
+
+// UnsafeTlockServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TlockServer will
+// result in compilation errors.
+type UnsafeTlockServer interface {
+	mustEmbedUnimplementedTlockServer()
+}
+
+func RegisterTlockServer(s grpc.ServiceRegistrar, srv TlockServer) {
+	// If the following call pancis, it indicates UnimplementedTlockServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Tlock_ServiceDesc, srv)
+}
+
+func _Tlock_Encrypt_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EncryptRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TlockServer).Encrypt(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tlock_Encrypt_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TlockServer).Encrypt(ctx, req.(*EncryptRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tlock_Decrypt_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DecryptRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TlockServer).Decrypt(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tlock_Decrypt_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TlockServer).Decrypt(ctx, req.(*DecryptRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tlock_Inspect_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InspectRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TlockServer).Inspect(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tlock_Inspect_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TlockServer).Inspect(ctx, req.(*InspectRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tlock_WaitForRound_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WaitForRoundRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TlockServer).WaitForRound(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tlock_WaitForRound_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TlockServer).WaitForRound(ctx, req.(*WaitForRoundRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tlock_Status_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TlockServer).Status(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tlock_Status_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TlockServer).Status(ctx, req.(*StatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Tlock_ServiceDesc is the grpc.ServiceDesc for Tlock service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Tlock_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "tlock.v1.Tlock",
+	HandlerType: (*TlockServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Encrypt",
+			Handler:    _Tlock_Encrypt_Handler,
+		},
+		{
+			MethodName: "Decrypt",
+			Handler:    _Tlock_Decrypt_Handler,
+		},
+		{
+			MethodName: "Inspect",
+			Handler:    _Tlock_Inspect_Handler,
+		},
+		{
+			MethodName: "WaitForRound",
+			Handler:    _Tlock_WaitForRound_Handler,
+		},
+		{
+			MethodName: "Status",
+			Handler:    _Tlock_Status_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "tlock.proto",
+}
@@ -0,0 +1,171 @@
+// Package tlockmail composes emails carrying a timelocked attachment, for
+// letters to the future, and extracts the attachment back from them.
+package tlockmail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+)
+
+// ErrNoAttachment is returned when a message has no timelocked attachment.
+var ErrNoAttachment = errors.New("no timelocked attachment in message")
+
+// ContentType is the media type of the timelocked attachment.
+const ContentType = "application/x-tlock"
+
+// Message holds the headers of the email to compose.
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	Date    time.Time
+}
+
+// Compose encrypts the plaintext towards the round and writes a MIME message
+// with the ciphertext attached under the file name, suffixed with .tle, and
+// a plain text part explaining when and how it can be decrypted.
+func Compose(dst io.Writer, msg Message, network tlock.Network, plaintext io.Reader, roundNumber uint64, filename string) error {
+	var ciphertext bytes.Buffer
+	if err := tlock.New(network).Encrypt(&ciphertext, plaintext, roundNumber); err != nil {
+		return fmt.Errorf("encrypt: %w", err)
+	}
+	filename += ".tle"
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	text, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"8bit"},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(text, instructions(network, roundNumber, filename)); err != nil {
+		return err
+	}
+
+	attachment, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {mime.FormatMediaType(ContentType, map[string]string{"name": filename})},
+		"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": filename})},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return err
+	}
+	if err := writeBase64Lines(attachment, ciphertext.Bytes()); err != nil {
+		return err
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+
+	date := msg.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+
+	var hdr bytes.Buffer
+	fmt.Fprintf(&hdr, "From: %s\r\n", msg.From)
+	fmt.Fprintf(&hdr, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&hdr, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&hdr, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	fmt.Fprintf(&hdr, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&hdr, "Content-Type: %s\r\n\r\n", mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": mw.Boundary()}))
+
+	if _, err := dst.Write(hdr.Bytes()); err != nil {
+		return err
+	}
+	_, err = dst.Write(body.Bytes())
+	return err
+}
+
+// Extract returns the timelocked attachment of the message read from src,
+// along with its file name.
+func Extract(src io.Reader) ([]byte, string, error) {
+	msg, err := mail.ReadMessage(src)
+	if err != nil {
+		return nil, "", fmt.Errorf("read message: %w", err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		return nil, "", ErrNoAttachment
+	}
+
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := mr.NextRawPart()
+		if errors.Is(err, io.EOF) {
+			return nil, "", ErrNoAttachment
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("read part: %w", err)
+		}
+
+		mediaType, _, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if err != nil || mediaType != ContentType {
+			continue
+		}
+
+		var r io.Reader = part
+		if strings.EqualFold(part.Header.Get("Content-Transfer-Encoding"), "base64") {
+			r = base64.NewDecoder(base64.StdEncoding, part)
+		}
+		ciphertext, err := io.ReadAll(r)
+		if err != nil {
+			return nil, "", fmt.Errorf("read attachment: %w", err)
+		}
+		return ciphertext, part.FileName(), nil
+	}
+}
+
+// =============================================================================
+
+// instructions returns the plain text part of the message.
+func instructions(network tlock.Network, roundNumber uint64, filename string) string {
+	when := fmt.Sprintf("once round %d of the drand network is reached", roundNumber)
+	if eta, ok := tlock.RoundTime(network, roundNumber); ok {
+		when = fmt.Sprintf("after %s (round %d of the drand network)", eta.UTC().Format(time.RFC1123), roundNumber)
+	}
+
+	return fmt.Sprintf(`This message carries a timelocked attachment, %s.
+
+Nobody, not even its sender, can decrypt it before its time: it can be
+decrypted %s, using the chain %s.
+
+To decrypt it, save this message and run:
+
+    tle open-email -o %s message.eml
+
+or save the attachment and run:
+
+    tle -d -o %s %s
+
+tle is available at https://github.com/JonathanLogan/tlock.
+`, filename, when, network.ChainHash(), strings.TrimSuffix(filename, ".tle"), strings.TrimSuffix(filename, ".tle"), filename)
+}
+
+// writeBase64Lines writes the data base64 encoded in lines of 76 characters.
+func writeBase64Lines(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 0 {
+		n := min(76, len(encoded))
+		if _, err := io.WriteString(w, encoded[:n]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[n:]
+	}
+	return nil
+}
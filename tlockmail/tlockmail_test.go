@@ -0,0 +1,45 @@
+package tlockmail_test
+
+import (
+	"bytes"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/JonathanLogan/tlock/tlockmail"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComposeExtract(t *testing.T) {
+	fixedtest.SkipInFIPSMode(t)
+
+	network := fixedtest.NewNetwork(t, 1)
+	letter := []byte("Dear future me,\nhow are you?\n")
+
+	var eml bytes.Buffer
+	err := tlockmail.Compose(&eml, tlockmail.Message{
+		From:    "me@example.com",
+		To:      []string{"future-me@example.com"},
+		Subject: "A letter to the future",
+	}, network, bytes.NewReader(letter), 1, "letter.txt")
+	require.NoError(t, err)
+
+	msg, err := mail.ReadMessage(bytes.NewReader(eml.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, "me@example.com", msg.Header.Get("From"))
+	require.Contains(t, eml.String(), "tle open-email")
+	require.NotContains(t, eml.String(), "how are you")
+
+	ciphertext, filename, err := tlockmail.Extract(bytes.NewReader(eml.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, "letter.txt.tle", filename)
+
+	var plaintext bytes.Buffer
+	require.NoError(t, tlock.New(network).Decrypt(&plaintext, bytes.NewReader(ciphertext)))
+	require.Equal(t, letter, plaintext.Bytes())
+
+	_, _, err = tlockmail.Extract(strings.NewReader("Subject: hi\r\n\r\nplain message\r\n"))
+	require.ErrorIs(t, err, tlockmail.ErrNoAttachment)
+}
@@ -0,0 +1,163 @@
+// Package vault exposes the WrapDEK and UnwrapDEK operations and the round
+// watcher in the shape of a HashiCorp Vault secrets engine backend, so a
+// plugin can offer "release this secret at time T" with a thin adapter. The
+// backend is stateless: wrapped keys are returned to the caller and all
+// structures serialize to JSON. It doesn't depend on the Vault SDK, the
+// plugin maps its paths and field data to HandleRequest.
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/watcher"
+)
+
+// These are the operations of the backend, meant to be used as Vault paths.
+const (
+	OperationWrap   = "wrap"
+	OperationUnwrap = "unwrap"
+	OperationStatus = "status"
+)
+
+// ErrUnknownOperation is returned by HandleRequest for unknown operations.
+var ErrUnknownOperation = errors.New("unknown operation")
+
+// WrapRequest is the input of the wrap operation. Either the round or the
+// unlock time must be set.
+type WrapRequest struct {
+	DEK        []byte    `json:"dek"`
+	Round      uint64    `json:"round,omitempty"`
+	UnlockTime time.Time `json:"unlock_time"`
+}
+
+// UnwrapRequest is the input of the unwrap operation.
+type UnwrapRequest struct {
+	Wrapped tlock.WrappedDEK `json:"wrapped"`
+}
+
+// UnwrapResponse is the output of the unwrap operation.
+type UnwrapResponse struct {
+	DEK []byte `json:"dek"`
+}
+
+// StatusRequest is the input of the status operation.
+type StatusRequest struct {
+	Round uint64 `json:"round"`
+}
+
+// StatusResponse is the output of the status operation. The unlock time is
+// zero when the network can't tell it.
+type StatusResponse struct {
+	Round      uint64    `json:"round"`
+	Reached    bool      `json:"reached"`
+	UnlockTime time.Time `json:"unlock_time"`
+}
+
+// Backend implements the operations for a network.
+type Backend struct {
+	network tlock.Network
+	watcher *watcher.Watcher
+}
+
+// New constructs a backend for the network.
+func New(network tlock.Network) *Backend {
+	return &Backend{
+		network: network,
+		watcher: watcher.New(network),
+	}
+}
+
+// Wrap timelocks the key towards the round, or the round of the unlock time.
+func (b *Backend) Wrap(_ context.Context, req WrapRequest) (tlock.WrappedDEK, error) {
+	roundNumber := req.Round
+	switch {
+	case roundNumber != 0 && !req.UnlockTime.IsZero():
+		return tlock.WrappedDEK{}, errors.New("round and unlock_time are mutually exclusive")
+	case roundNumber == 0 && req.UnlockTime.IsZero():
+		return tlock.WrappedDEK{}, errors.New("round or unlock_time is required")
+	case roundNumber == 0:
+		roundNumber = b.network.Current(req.UnlockTime)
+	}
+
+	return tlock.New(b.network).Strict().WrapDEK(req.DEK, roundNumber)
+}
+
+// Unwrap releases the key, failing with tlock.ErrTooEarly before its round.
+func (b *Backend) Unwrap(_ context.Context, req UnwrapRequest) (UnwrapResponse, error) {
+	dek, err := tlock.New(b.network).Strict().UnwrapDEK(req.Wrapped)
+	if err != nil {
+		return UnwrapResponse{}, err
+	}
+	return UnwrapResponse{DEK: dek}, nil
+}
+
+// Status reports whether the round is reached and when it is expected.
+func (b *Backend) Status(_ context.Context, req StatusRequest) (StatusResponse, error) {
+	resp := StatusResponse{
+		Round:   req.Round,
+		Reached: b.watcher.Reached(req.Round),
+	}
+	if eta, ok := tlock.RoundTime(b.network, req.Round); ok {
+		resp.UnlockTime = eta.UTC()
+	}
+	return resp, nil
+}
+
+// Wait blocks until the round is reached, for plugins holding requests
+// until a secret is released.
+func (b *Backend) Wait(ctx context.Context, req StatusRequest) (StatusResponse, error) {
+	if err := b.watcher.Wait(ctx, req.Round); err != nil {
+		return StatusResponse{}, err
+	}
+	return b.Status(ctx, req)
+}
+
+// HandleRequest runs the operation with the raw request data as received by
+// a Vault plugin, returning the response data. Byte fields are base64 encoded
+// strings and times are RFC 3339 strings.
+func (b *Backend) HandleRequest(ctx context.Context, operation string, data map[string]any) (map[string]any, error) {
+	switch operation {
+	case OperationWrap:
+		return handle(ctx, data, b.Wrap)
+	case OperationUnwrap:
+		return handle(ctx, data, b.Unwrap)
+	case OperationStatus:
+		return handle(ctx, data, b.Status)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownOperation, operation)
+	}
+}
+
+// =============================================================================
+
+// handle converts the raw data to the request type of the operation and its
+// response back to raw data.
+func handle[Req, Resp any](ctx context.Context, data map[string]any, op func(context.Context, Req) (Resp, error)) (map[string]any, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("encode request: %w", err)
+	}
+	var req Req
+	if err := json.Unmarshal(b, &req); err != nil {
+		return nil, fmt.Errorf("decode request: %w", err)
+	}
+
+	resp, err := op(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if b, err = json.Marshal(resp); err != nil {
+		return nil, fmt.Errorf("encode response: %w", err)
+	}
+	var out map[string]any
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,53 @@
+package vault_test
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/JonathanLogan/tlock/vault"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleRequest(t *testing.T) {
+	network := fixedtest.NewNetwork(t, 1)
+	b := vault.New(network)
+	ctx := context.Background()
+	dek := []byte("0123456789abcdef")
+
+	wrapped, err := b.HandleRequest(ctx, vault.OperationWrap, map[string]any{
+		"dek":   base64.StdEncoding.EncodeToString(dek),
+		"round": 1,
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, wrapped["round"])
+
+	unwrapped, err := b.HandleRequest(ctx, vault.OperationUnwrap, map[string]any{"wrapped": wrapped})
+	require.NoError(t, err)
+	require.Equal(t, base64.StdEncoding.EncodeToString(dek), unwrapped["dek"])
+
+	status, err := b.HandleRequest(ctx, vault.OperationStatus, map[string]any{"round": 1000})
+	require.NoError(t, err)
+	require.Equal(t, false, status["reached"])
+	require.NotEmpty(t, status["unlock_time"])
+
+	_, err = b.HandleRequest(ctx, vault.OperationWrap, map[string]any{"dek": base64.StdEncoding.EncodeToString(dek)})
+	require.Error(t, err)
+
+	_, err = b.HandleRequest(ctx, "rotate", nil)
+	require.ErrorIs(t, err, vault.ErrUnknownOperation)
+}
+
+func TestWrapUnlockTime(t *testing.T) {
+	network := fixedtest.NewNetwork(t, 1)
+	b := vault.New(network)
+
+	wrapped, err := b.Wrap(context.Background(), vault.WrapRequest{DEK: []byte("key"), UnlockTime: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	require.Greater(t, wrapped.Round, uint64(1000))
+
+	_, err = b.Unwrap(context.Background(), vault.UnwrapRequest{Wrapped: wrapped})
+	require.Error(t, err)
+}
@@ -0,0 +1,98 @@
+// Package watcher notifies when rounds of a drand network are reached, letting
+// services act on ciphertexts as soon as they can be decrypted.
+package watcher
+
+import (
+	"context"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+)
+
+// DefaultInterval is how often the network is checked when the time of a
+// round can't be computed, or passed without the round being published.
+const DefaultInterval = time.Second
+
+// Watcher watches the rounds of a network.
+type Watcher struct {
+	network  tlock.Network
+	interval time.Duration
+	onSlip   func(roundNumber uint64, status tlock.ChainStatus)
+}
+
+// New constructs a watcher for the network.
+func New(network tlock.Network) *Watcher {
+	return &Watcher{
+		network:  network,
+		interval: DefaultInterval,
+	}
+}
+
+// OnSlip sets the handler called when a waited round is due without being
+// published, as the chain is halted or degraded, so that services can alert
+// that its unlock slips. It is called once per wait with the status of the
+// chain, for networks implementing tlock.StatusReporter.
+func (w *Watcher) OnSlip(handler func(roundNumber uint64, status tlock.ChainStatus)) *Watcher {
+	w.onSlip = handler
+	return w
+}
+
+// Reached reports whether the network published the round, which is checked
+// with tlock.IsReadyToDecrypt.
+func (w *Watcher) Reached(roundNumber uint64) bool {
+	return tlock.IsReadyToDecrypt(w.network, roundNumber)
+}
+
+// latest returns the latest round the network published, or its current round
+// when it doesn't tell, as tlock.IsReadyToDecrypt does.
+func (w *Watcher) latest() uint64 {
+	if n, ok := w.network.(interface{ LatestRound() (uint64, error) }); ok {
+		if latest, err := n.LatestRound(); err == nil {
+			return latest
+		}
+	}
+	return w.network.Current(time.Now())
+}
+
+// Wait blocks until the network reached the round or the context is done.
+func (w *Watcher) Wait(ctx context.Context, roundNumber uint64) error {
+	slipped := false
+	for !w.Reached(roundNumber) {
+		wait := w.interval
+		eta, ok := tlock.RoundTime(w.network, roundNumber)
+		if ok && time.Until(eta) > wait {
+			wait = time.Until(eta)
+		}
+
+		// The round is due but not published yet.
+		if ok && !slipped && w.onSlip != nil && time.Since(eta) > w.interval {
+			if status, err := tlock.Status(ctx, w.network); err == nil && status.Health != tlock.Healthy {
+				w.onSlip(roundNumber, status)
+				slipped = true
+			}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return nil
+}
+
+// Subscribe returns a channel receiving the round once the network reached
+// it. The channel is closed after that, or without a value when the context
+// is done first.
+func (w *Watcher) Subscribe(ctx context.Context, roundNumber uint64) <-chan uint64 {
+	ch := make(chan uint64, 1)
+	go func() {
+		defer close(ch)
+		if w.Wait(ctx, roundNumber) == nil {
+			ch <- roundNumber
+		}
+	}()
+	return ch
+}
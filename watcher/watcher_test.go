@@ -0,0 +1,105 @@
+package watcher_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock"
+	"github.com/JonathanLogan/tlock/networks/fixed"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/JonathanLogan/tlock/watcher"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatcher(t *testing.T) {
+	// Round 2 is reached a second from now.
+	key := fixedtest.NewKey(nil)
+	key.Period = time.Second
+	network := key.Network(t, nil)
+	w := watcher.New(network)
+
+	require.True(t, w.Reached(1))
+	require.False(t, w.Reached(100))
+
+	ch := w.Subscribe(context.Background(), 2)
+	select {
+	case roundNumber, ok := <-ch:
+		require.True(t, ok)
+		require.Equal(t, uint64(2), roundNumber)
+	case <-time.After(5 * time.Second):
+		t.Fatal("round 2 not reached")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch = w.Subscribe(ctx, 100)
+	cancel()
+	_, ok := <-ch
+	require.False(t, ok)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, w.Wait(ctx, 100), context.DeadlineExceeded)
+}
+
+// catchingUpNetwork is a network publishing rounds behind their time, as a
+// chain catching up after an outage does.
+type catchingUpNetwork struct {
+	*fixed.Network
+	latest atomic.Uint64
+}
+
+func (n *catchingUpNetwork) LatestRound() (uint64, error) {
+	return n.latest.Load(), nil
+}
+
+func TestWatcherCatchUp(t *testing.T) {
+	// Round 11 is due by the time, but only round 5 was published.
+	key := fixedtest.NewKey(nil)
+	key.Period = time.Second
+	key.Genesis = time.Now().Add(-10 * time.Second)
+	fixedNetwork := key.Network(t, nil)
+	network := &catchingUpNetwork{Network: fixedNetwork}
+	network.latest.Store(5)
+	w := watcher.New(network)
+
+	require.True(t, w.Reached(5))
+	require.False(t, w.Reached(8))
+
+	// The missing rounds are published in a burst.
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		network.latest.Store(11)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	require.NoError(t, w.Wait(ctx, 8))
+	require.Less(t, time.Since(start), 2*watcher.DefaultInterval)
+	require.True(t, w.Reached(11))
+}
+
+func TestWatcherOnSlip(t *testing.T) {
+	// Round 50 is due, but the chain halted at round 5.
+	key := fixedtest.NewKey(nil)
+	key.Period = time.Second
+	key.Genesis = time.Now().Add(-100 * time.Second)
+	fixedNetwork := key.Network(t, nil)
+	network := &catchingUpNetwork{Network: fixedNetwork}
+	network.latest.Store(5)
+
+	var slips []tlock.ChainStatus
+	w := watcher.New(network).OnSlip(func(roundNumber uint64, status tlock.ChainStatus) {
+		require.Equal(t, uint64(50), roundNumber)
+		slips = append(slips, status)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, w.Wait(ctx, 50), context.DeadlineExceeded)
+	require.Len(t, slips, 1)
+	require.Equal(t, tlock.Halted, slips[0].Health)
+	require.Equal(t, uint64(5), slips[0].LatestRound)
+}
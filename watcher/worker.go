@@ -0,0 +1,149 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JonathanLogan/tlock/store"
+)
+
+// DefaultLeaseTTL is how long a worker holds the lease on an item without
+// renewing it. A replica stopping while processing an item delays it by as
+// much, another replica taking it over once the lease expired.
+const DefaultLeaseTTL = 30 * time.Second
+
+// Handler processes an item whose round was reached. It runs at least once
+// per item: when a worker stops before deleting the item, it is processed
+// again.
+type Handler func(ctx context.Context, item store.Item) error
+
+// Worker processes the pending items of a store once their round is reached.
+// Workers of replicas sharing the store coordinate through leases, a single
+// one processing each item.
+type Worker struct {
+	watcher *Watcher
+	store   store.Store
+	leases  store.Leaser
+	owner   string
+	ttl     time.Duration
+	handle  Handler
+	onError func(err error)
+}
+
+// NewWorker constructs a worker processing the items of the store with the
+// handler. The owner identifies the replica in the leases and must be unique
+// among the replicas.
+func NewWorker(w *Watcher, s store.Store, leases store.Leaser, owner string, handle Handler) *Worker {
+	return &Worker{
+		watcher: w,
+		store:   s,
+		leases:  leases,
+		owner:   owner,
+		ttl:     DefaultLeaseTTL,
+		handle:  handle,
+	}
+}
+
+// OnError sets the handler called with the errors of processing the items
+// while running, which are otherwise dropped, so that services can log or
+// alert on them. The items which failed are processed again on the next
+// check.
+func (wk *Worker) OnError(handler func(err error)) *Worker {
+	wk.onError = handler
+	return wk
+}
+
+// Run processes the items until the context is done, checking for items
+// reached at the interval of the watcher. Failing to process items doesn't
+// stop it: the errors are reported to the OnError handler.
+func (wk *Worker) Run(ctx context.Context) error {
+	for {
+		if err := wk.Process(ctx); err != nil && ctx.Err() == nil && wk.onError != nil {
+			wk.onError(err)
+		}
+
+		timer := time.NewTimer(wk.watcher.interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Process processes the items whose round was reached, deleting them from the
+// store once handled. The items leased by other replicas are skipped.
+func (wk *Worker) Process(ctx context.Context) error {
+	items, err := wk.store.List(ctx, wk.watcher.network.Current(time.Now()))
+	if err != nil {
+		return fmt.Errorf("list items: %w", err)
+	}
+
+	// The latest round is fetched once for all the items.
+	latest := wk.watcher.latest()
+	var errs []error
+	for _, item := range items {
+		if item.Round > latest {
+			// Items are ordered by round.
+			break
+		}
+		if err := wk.process(ctx, item.ID); err != nil {
+			errs = append(errs, fmt.Errorf("item %s: %w", item.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// =============================================================================
+
+// process handles the item under its lease.
+func (wk *Worker) process(ctx context.Context, id string) error {
+	key := "item/" + id
+	ok, err := wk.leases.Acquire(ctx, key, wk.owner, wk.ttl)
+	if err != nil {
+		return fmt.Errorf("acquire lease: %w", err)
+	}
+	if !ok {
+		return nil
+	}
+	defer wk.leases.Release(context.WithoutCancel(ctx), key, wk.owner)
+
+	// Another replica may have processed the item since it was listed.
+	item, err := wk.store.Get(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go wk.renew(ctx, cancel, key)
+
+	if err := wk.handle(ctx, item); err != nil {
+		return err
+	}
+	return wk.store.Delete(ctx, id)
+}
+
+// renew keeps the lease on the key until the context is done. Processing is
+// cancelled when the lease is lost.
+func (wk *Worker) renew(ctx context.Context, cancel context.CancelFunc, key string) {
+	ticker := time.NewTicker(wk.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if ok, err := wk.leases.Acquire(ctx, key, wk.owner, wk.ttl); err != nil || !ok {
+				cancel()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package watcher_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/JonathanLogan/tlock/networks/fixed"
+	"github.com/JonathanLogan/tlock/networks/fixed/fixedtest"
+	"github.com/JonathanLogan/tlock/store"
+	"github.com/JonathanLogan/tlock/watcher"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerReplicas(t *testing.T) {
+	// Round 11 is the current one.
+	key := fixedtest.NewKey(nil)
+	key.Period = time.Second
+	key.Genesis = time.Now().Add(-10 * time.Second)
+	network := key.Network(t, nil)
+	w := watcher.New(network)
+
+	ctx := context.Background()
+	s := store.NewMemory()
+	for i := range 20 {
+		require.NoError(t, s.Put(ctx, store.Item{ID: fmt.Sprintf("item%02d", i), Round: uint64(i%10 + 1)}))
+	}
+	require.NoError(t, s.Put(ctx, store.Item{ID: "future", Round: 1000}))
+
+	// An item leased by a stopped replica waits for the lease to expire.
+	ok, err := s.Acquire(ctx, "item/item00", "stopped", time.Hour)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	var mu sync.Mutex
+	handled := make(map[string]int)
+	handle := func(_ context.Context, item store.Item) error {
+		mu.Lock()
+		defer mu.Unlock()
+		handled[item.ID]++
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for i := range 3 {
+		wk := watcher.NewWorker(w, s, s, fmt.Sprintf("replica%d", i), handle)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, wk.Process(ctx))
+		}()
+	}
+	wg.Wait()
+
+	require.Len(t, handled, 19)
+	for id, n := range handled {
+		require.Equal(t, 1, n, id)
+	}
+	items, err := s.List(ctx, 1000)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	require.Equal(t, "item00", items[0].ID)
+	require.Equal(t, "future", items[1].ID)
+}
+
+func TestWorkerHandlerError(t *testing.T) {
+	key := fixedtest.NewKey(nil)
+	key.Period = time.Second
+	key.Genesis = time.Now().Add(-10 * time.Second)
+	network := key.Network(t, nil)
+
+	ctx := context.Background()
+	s := store.NewMemory()
+	require.NoError(t, s.Put(ctx, store.Item{ID: "a", Round: 1}))
+
+	fail := true
+	wk := watcher.NewWorker(watcher.New(network), s, s, "replica", func(context.Context, store.Item) error {
+		if fail {
+			return fmt.Errorf("downstream unavailable")
+		}
+		return nil
+	})
+
+	// The item is kept for another attempt, its lease being released.
+	require.ErrorContains(t, wk.Process(ctx), "downstream unavailable")
+	_, err := s.Get(ctx, "a")
+	require.NoError(t, err)
+
+	fail = false
+	require.NoError(t, wk.Process(ctx))
+	_, err = s.Get(ctx, "a")
+	require.ErrorIs(t, err, store.ErrNotFound)
+}
+
+func TestWorkerRunKeepsGoing(t *testing.T) {
+	key := fixedtest.NewKey(nil)
+	key.Period = time.Second
+	key.Genesis = time.Now().Add(-10 * time.Second)
+	network := key.Network(t, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := store.NewMemory()
+	require.NoError(t, s.Put(ctx, store.Item{ID: "a", Round: 1}))
+
+	var attempts atomic.Int32
+	errs := make(chan error, 10)
+	wk := watcher.NewWorker(watcher.New(network), s, s, "replica", func(context.Context, store.Item) error {
+		if attempts.Add(1) == 1 {
+			return fmt.Errorf("downstream unavailable")
+		}
+		return nil
+	}).OnError(func(err error) { errs <- err })
+
+	done := make(chan error, 1)
+	go func() { done <- wk.Run(ctx) }()
+
+	// The failure is reported, and the item processed again on the next check.
+	require.ErrorContains(t, <-errs, "downstream unavailable")
+	require.Eventually(t, func() bool {
+		_, err := s.Get(ctx, "a")
+		return errors.Is(err, store.ErrNotFound)
+	}, 5*time.Second, 50*time.Millisecond)
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+	require.Empty(t, errs)
+}
+
+// countingNetwork counts the fetches of the latest round, of which round 5 was
+// published.
+type countingNetwork struct {
+	*fixed.Network
+	fetches atomic.Int32
+}
+
+func (n *countingNetwork) LatestRound() (uint64, error) {
+	n.fetches.Add(1)
+	return 5, nil
+}
+
+func TestWorkerFetchesLatestOnce(t *testing.T) {
+	key := fixedtest.NewKey(nil)
+	key.Period = time.Second
+	key.Genesis = time.Now().Add(-10 * time.Second)
+	network := &countingNetwork{Network: key.Network(t, nil)}
+
+	ctx := context.Background()
+	s := store.NewMemory()
+	for i := range 10 {
+		require.NoError(t, s.Put(ctx, store.Item{ID: fmt.Sprintf("item%02d", i), Round: uint64(i + 1)}))
+	}
+
+	var handled []string
+	wk := watcher.NewWorker(watcher.New(network), s, s, "replica", func(_ context.Context, item store.Item) error {
+		handled = append(handled, item.ID)
+		return nil
+	})
+	require.NoError(t, wk.Process(ctx))
+	require.Equal(t, []string{"item00", "item01", "item02", "item03", "item04"}, handled)
+	require.Equal(t, int32(1), network.fetches.Load())
+}